@@ -75,3 +75,144 @@ func TestFlagConflict(t *testing.T) {
 		t.Fatalf("Expected an error parsing flags but none returned")
 	}
 }
+
+func TestInvalidPublishService(t *testing.T) {
+	resetForTesting(func() { t.Fatal("Parsing failed") })
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"cmd", "--http-port", "0", "--https-port", "0", "--publish-service", "namespace/external,internal"}
+
+	_, _, err := parseFlags()
+	if err == nil {
+		t.Fatalf("Expected an error parsing flags but none returned")
+	}
+}
+
+func TestInvalidProfile(t *testing.T) {
+	resetForTesting(func() { t.Fatal("Parsing failed") })
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"cmd", "--http-port", "0", "--https-port", "0", "--profile", "tiny"}
+
+	_, _, err := parseFlags()
+	if err == nil {
+		t.Fatalf("Expected an error parsing flags but none returned")
+	}
+}
+
+func TestInvalidElectionDurations(t *testing.T) {
+	resetForTesting(func() { t.Fatal("Parsing failed") })
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"cmd", "--http-port", "0", "--https-port", "0", "--election-lease-duration", "5s", "--election-renew-deadline", "10s"}
+
+	_, _, err := parseFlags()
+	if err == nil {
+		t.Fatalf("Expected an error parsing flags but none returned")
+	}
+}
+
+func TestInvalidClassConflictPolicy(t *testing.T) {
+	resetForTesting(func() { t.Fatal("Parsing failed") })
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"cmd", "--http-port", "0", "--https-port", "0", "--class-conflict-policy", "reject"}
+
+	_, _, err := parseFlags()
+	if err == nil {
+		t.Fatalf("Expected an error parsing flags but none returned")
+	}
+}
+
+func TestInvalidLogFormat(t *testing.T) {
+	resetForTesting(func() { t.Fatal("Parsing failed") })
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"cmd", "--http-port", "0", "--https-port", "0", "--log-format", "xml"}
+
+	_, _, err := parseFlags()
+	if err == nil {
+		t.Fatalf("Expected an error parsing flags but none returned")
+	}
+}
+
+func TestSyncWatchdogRestartWithoutDeadline(t *testing.T) {
+	resetForTesting(func() { t.Fatal("Parsing failed") })
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"cmd", "--http-port", "0", "--https-port", "0", "--sync-watchdog-deadline", "0", "--sync-watchdog-restart"}
+
+	_, _, err := parseFlags()
+	if err == nil {
+		t.Fatalf("Expected an error parsing flags but none returned")
+	}
+}
+
+func TestInvalidDynamicConfigurationRetryFactor(t *testing.T) {
+	resetForTesting(func() { t.Fatal("Parsing failed") })
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"cmd", "--http-port", "0", "--https-port", "0", "--dynamic-configuration-retry-factor", "0.8"}
+
+	_, _, err := parseFlags()
+	if err == nil {
+		t.Fatalf("Expected an error parsing flags but none returned")
+	}
+}
+
+func TestInvalidInternalBindAddress(t *testing.T) {
+	resetForTesting(func() { t.Fatal("Parsing failed") })
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"cmd", "--http-port", "0", "--https-port", "0", "--internal-bind-address", "internal.example.com"}
+
+	_, _, err := parseFlags()
+	if err == nil {
+		t.Fatalf("Expected an error parsing flags but none returned")
+	}
+}
+
+func TestInvalidRealIPRangesURLs(t *testing.T) {
+	resetForTesting(func() { t.Fatal("Parsing failed") })
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"cmd", "--http-port", "0", "--https-port", "0", "--real-ip-ranges-urls", "https://www.cloudflare.com/ips-v4,ftp://example.com/ips"}
+
+	_, _, err := parseFlags()
+	if err == nil {
+		t.Fatalf("Expected an error parsing flags but none returned")
+	}
+}
+
+func TestClassTemplate(t *testing.T) {
+	templates := "internal=/etc/nginx/template/internal.tmpl, external=/etc/nginx/template/external.tmpl"
+
+	tmpl, err := classTemplate(templates, "internal", "/etc/nginx/template/nginx.tmpl")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tmpl != "/etc/nginx/template/internal.tmpl" {
+		t.Errorf("Expected the template of the class but got %v", tmpl)
+	}
+
+	tmpl, err = classTemplate(templates, "nginx", "/etc/nginx/template/nginx.tmpl")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tmpl != "/etc/nginx/template/nginx.tmpl" {
+		t.Errorf("Expected the default template but got %v", tmpl)
+	}
+
+	if _, err := classTemplate("internal", "internal", "/etc/nginx/template/nginx.tmpl"); err == nil {
+		t.Errorf("Expected an error with an invalid pair")
+	}
+}
@@ -19,17 +19,25 @@ package main
 import (
 	"flag"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/golang/glog"
 	"github.com/spf13/pflag"
 
 	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 
 	"k8s.io/ingress-nginx/internal/ingress/annotations/class"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
 	"k8s.io/ingress-nginx/internal/ingress/controller"
 	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
+	"k8s.io/ingress-nginx/internal/ingress/status"
+	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/internal/log"
 	ing_net "k8s.io/ingress-nginx/internal/net"
 )
 
@@ -55,14 +63,33 @@ requests to the first port of this Service.`)
 The class of an Ingress object is set using the annotation "kubernetes.io/ingress.class".
 All ingress classes are satisfied if this parameter is left empty.`)
 
+		templatePath = flags.String("template", "/etc/nginx/template/nginx.tmpl",
+			`Path of the template used to render the NGINX configuration. The key custom-template of the
+configuration ConfigMap takes precedence.`)
+
+		classTemplates = flags.String("class-templates", "",
+			`Comma separated list of class=path pairs with the template used instead of the one of the template
+parameter by the controllers of each ingress class, so controllers of several classes can share the same
+arguments, like "internal=/etc/nginx/template/internal.tmpl,external=/etc/nginx/template/external.tmpl".`)
+
 		configMap = flags.String("configmap", "",
 			`Name of the ConfigMap containing custom global configurations for the controller.`)
 
+		denylistConfigMap = flags.String("denylist-configmap", "",
+			`Name of the ConfigMap containing the IPv4 and IPv6 addresses and networks blocked in all the
+servers, in the form "namespace/name". Changes are applied without reloading NGINX.`)
+
+		hostRedirectConfigMap = flags.String("host-redirect-configmap", "",
+			`Name of the ConfigMap containing the hosts redirected to other hosts, in the form
+"namespace/name". Each key is the old host and the value the new host, optionally followed by the
+status code (301, 302, 307 or 308) and "drop-path". Changes are applied without reloading NGINX.`)
+
 		publishSvc = flags.String("publish-service", "",
 			`Service fronting the Ingress controller.
 Takes the form "namespace/name". When used together with update-status, the
 controller mirrors the address of this service's endpoints to the load-balancer
-status of all Ingress objects it satisfies.`)
+status of all Ingress objects it satisfies. Accepts a comma separated list of
+Services, like an internal and an external load balancer, whose addresses are merged.`)
 
 		resyncPeriod = flags.Duration("sync-period", 0,
 			`Period at which the controller forces the repopulation of its local object stores. Disabled by default.`)
@@ -72,9 +99,125 @@ status of all Ingress objects it satisfies.`)
 This includes Ingresses, Services and all configuration resources. All
 namespaces are watched if this parameter is left empty.`)
 
+		watchNamespaceSelector = flags.String("watch-namespace-selector", "",
+			`Label selector of the namespaces whose Ingresses are watched, like "ingress=public". Namespaces are
+added or removed when their labels change. May be used together with watch-namespace. Requires permission
+to list and watch namespaces.`)
+
 		profiling = flags.Bool("profiling", true,
 			`Enable profiling via web interface host:port/debug/pprof/`)
 
+		namespaceMetrics = flags.Bool("enable-namespace-metrics", false,
+			`Expose the metrics of the Ingresses located in a namespace via web interface host:port/metrics/namespaces/<namespace>.
+Requests must contain a bearer token of a user or service account allowed to get Ingresses in that namespace.`)
+
+		annotationBundle = flags.Bool("enable-annotation-bundle", false,
+			`Expose the annotations of the Ingresses via web interface host:port/annotations, and their validation in host:port/annotations/validate.
+Requests must contain a bearer token of a user or service account allowed to list Ingresses to export the annotations,
+or to create Ingresses in the namespaces of the bundle to validate it.`)
+
+		canaryAPI = flags.Bool("enable-canary-api", false,
+			`Expose the weight of the canary Ingresses via web interface host:port/canaries/<namespace>/<name>, to read it (GET) and change it (PUT).
+Requests must contain a bearer token of a user or service account allowed to get or patch the Ingress.`)
+
+		routingOverrides = flags.Bool("enable-routing-overrides", false,
+			`Expose the temporary routing overrides via web interface host:port/routing-overrides, to list (GET), install (POST) and remove (DELETE) them.
+Requests must contain a bearer token of a user or service account allowed to list Ingresses to read the overrides,
+or to patch all the Ingresses of the host and get the Service receiving the requests to change them.`)
+
+		maxmindLicenseKey = flags.String("maxmind-license-key", "",
+			`MaxMind license key used to download the GeoIP2 databases (GeoLite2-City and GeoLite2-ASN).
+The databases are downloaded on start and refreshed periodically, replacing the ones included in the image.`)
+
+		maxmindRefreshPeriod = flags.Duration("maxmind-refresh-period", 24*time.Hour,
+			`Time between downloads of the GeoIP2 databases. Requires the maxmind-license-key parameter.`)
+
+		chargebackLabel = flags.String("chargeback-label", "",
+			`Aggregate the bytes received and sent in the requests by this label in the metrics
+nginx_ingress_controller_chargeback_request_bytes and nginx_ingress_controller_chargeback_response_bytes.
+Use "namespace" to aggregate by the namespace of the Ingress or the name of an Ingress annotation, like
+"example.com/billing-id", to aggregate by its value. Requests to Ingresses without the annotation are not counted.`)
+
+		classConflictPolicy = flags.String("class-conflict-policy", controller.ClassConflictWarn,
+			`Handling of Ingresses with a host and path also defined in an Ingress of other class.
+Use "warn" to create a Warning event on the Ingresses and expose their number in the metric
+nginx_ingress_controller_ingress_class_conflicts, "skip" to also ignore them or "ignore" to disable the check.`)
+
+		duplicatePathPolicy = flags.String("duplicate-path-policy", controller.DuplicatePathFirstWins,
+			`Handling of a host and path defined in several Ingresses, which are reported with a Warning event.
+Use "first-wins" to configure the path with the oldest Ingress, "reject" to ignore the newer Ingresses or
+"merge" to use the backend of the oldest Ingress and the annotations of all of them, where the annotations
+of the older Ingresses take precedence.`)
+
+		endpointWeightAnnotation = flags.String("endpoint-weight-annotation", "",
+			`Name of the Pod annotation with the weight of its endpoints in the load balancing, like
+"ingress.kubernetes.io/endpoint-weight". The value must be an integer; endpoints with weight 0 receive no
+requests unless all the endpoints of the backend have weight 0. Enabling this option watches the Pods.`)
+
+		externalNameResolvePeriod = flags.Duration("external-name-resolve-period", 30*time.Second,
+			`Time between checks of the resolution of the hostnames of ExternalName Services. Services are
+added to the configuration when their hostname starts resolving and removed when it stops, without reloading NGINX.
+Use 0 to disable the checks.`)
+
+		realIPRangesURLs = flags.String("real-ip-ranges-urls", "",
+			`Comma separated list of URLs with the IP ranges published by a CDN, like
+https://www.cloudflare.com/ips-v4. The ranges are added to the proxy-real-ip-cidr of the configuration
+and fetched again periodically, reloading NGINX when they change. The URLs return the ranges as text
+separated by spaces, commas or new lines. For a JSON document the fragment of the URL is the key of the
+list of ranges, like https://d7uri8nf7uskq.cloudfront.net/tools/list-cloudfront-ips#CLOUDFRONT_GLOBAL_IP_LIST.
+Every range is trusted to send the client address: a URL listing ranges anyone can use, like all
+the addresses of a cloud provider, allows clients to spoof their address.`)
+
+		realIPRangesSyncPeriod = flags.Duration("real-ip-ranges-sync-period", 12*time.Hour,
+			`Time between fetches of the IP ranges of real-ip-ranges-urls.`)
+
+		publishEffectiveConfig = flags.Bool("publish-effective-configuration", false,
+			`Write a summary of the configuration applied to each Ingress, like the backend protocol, the TLS
+Secret used for each host, the canary weight and the paths served by other Ingresses, in the annotation
+nginx.ingress.kubernetes.io/effective-configuration of the Ingress. The annotation is written by the leader of
+the status update, in each update of the status. Requires update-status.`)
+
+		configTestWorkers = flags.Int("config-test-workers", 1,
+			`Number of workers used to test the NGINX configuration with "nginx -t" before a reload. Each
+worker reuses the same temporal file and a configuration identical to the last valid one is not tested again.`)
+
+		reloadConcurrency = flags.Int("reload-concurrency", 0,
+			`Maximum number of instances of the controller reloading NGINX at the same time, like the pods of a
+DaemonSet, to reload in waves instead of all at once. The instances coordinate using Lease objects named
+"<election-id>-reload-<n>" in their namespace. 0 disables the coordination.`)
+
+		reloadCoordinationTimeout = flags.Duration("reload-coordination-timeout", 1*time.Minute,
+			`Maximum time a reload waits for other instances to finish their reloads when reload-concurrency is
+set. After this time NGINX is reloaded anyway.`)
+
+		websocketReloadThreshold = flags.Int("websocket-reload-threshold", 0,
+			`Number of active WebSocket connections in the locations with the annotation websocket-heavy above
+which the reloads of NGINX are deferred, to avoid closing them on every configuration change. The endpoints
+are still updated dynamically. 0 never defers the reloads.`)
+
+		websocketReloadMaxDelay = flags.Duration("websocket-reload-max-delay", 15*time.Minute,
+			`Maximum time a reload is deferred when websocket-reload-threshold is set. After this time NGINX is
+reloaded anyway.`)
+
+		listPageSize = flags.Int64("list-page-size", 0,
+			`Number of objects read in each request of the initial list of Ingresses, Services, Endpoints and Pods,
+to reduce the load of the API server and the memory used at startup in clusters with many
+objects. 0 lists all the objects in a single request served from the cache of the API server.`)
+
+		shardIndex = flags.Int("shard-index", 0,
+			`Index of the shard of the controller, from 0 to shard-count - 1. The controller only configures the
+hostnames of its shard and updates the status of their Ingresses.`)
+
+		shardCount = flags.Int("shard-count", 1,
+			`Number of controller deployments the hostnames are split between, by a consistent hash of each hostname.
+Each deployment must use a different shard-index and the same shard-count. The rules without a hostname and
+the default backends are configured by all the shards. 1 disables the sharding.`)
+
+		shutdownGracePeriod = flags.Duration("shutdown-grace-period", 0,
+			`Time NGINX keeps serving traffic after receiving SIGTERM. During this period the health checks fail so
+the load balancers stop sending new connections, then NGINX stops accepting connections and waits up to
+worker-shutdown-timeout for the requests in progress. 0 stops NGINX immediately.`)
+
 		defSSLCertificate = flags.String("default-ssl-certificate", "",
 			`Secret containing a SSL certificate to be used by the default HTTPS server (catch-all).
 Takes the form "namespace/name".`)
@@ -93,6 +236,21 @@ Requires setting the publish-service parameter to a valid Service reference.`)
 		electionID = flags.String("election-id", "ingress-controller-leader",
 			`Election id to use for Ingress status updates.`)
 
+		electionLeaseDuration = flags.Duration("election-lease-duration", status.DefaultLeaseDuration,
+			`Time non-leader instances wait before trying to acquire the leadership of Ingress status updates.
+Lower values reduce the time it takes to elect a new leader when the current one is gone.`)
+
+		electionRenewDeadline = flags.Duration("election-renew-deadline", status.DefaultRenewDeadline,
+			`Time the leader retries refreshing the leadership before giving up.
+Must be lower than election-lease-duration.`)
+
+		electionRetryPeriod = flags.Duration("election-retry-period", status.DefaultRetryPeriod,
+			`Time between attempts to acquire or renew the leadership.`)
+
+		electionLockType = flags.String("election-lock-type", status.ElectionLockConfigMaps,
+			`Kind of object used to store the leader election record of Ingress status updates.
+One of "configmaps" or "leases" (coordination.k8s.io Lease objects, requires Kubernetes 1.12 or newer).`)
+
 		forceIsolation = flags.Bool("force-namespace-isolation", false,
 			`Force namespace isolation.
 Prevents Ingress objects from referencing Secrets and ConfigMaps located in a
@@ -105,6 +263,11 @@ Requires the update-status parameter.`)
 		sortBackends = flags.Bool("sort-backends", false,
 			`Sort servers inside NGINX upstreams.`)
 
+		deterministicEndpointOrder = flags.Bool("deterministic-endpoint-order", false,
+			`Shuffle the servers of each NGINX upstream always in the same order for the same endpoints, so the
+configuration only changes when the endpoints change. The order is different for each Service, unlike
+sort-backends, which takes precedence.`)
+
 		useNodeInternalIP = flags.Bool("report-node-internal-ip-address", false,
 			`Set the load-balancer status of Ingress objects to internal Node addresses instead of external.
 Requires the update-status parameter.`)
@@ -112,6 +275,11 @@ Requires the update-status parameter.`)
 		showVersion = flags.Bool("version", false,
 			`Show release information about the NGINX Ingress controller and exit.`)
 
+		preflight = flags.Bool("preflight", false,
+			`Check the kernel settings, the limit of open files, the availability of the ports, the NGINX
+modules and the permissions of the directories used by the controller, print a JSON report and
+exit. The exit code is 1 if a check found a problem that prevents the controller from working.`)
+
 		enableSSLPassthrough = flags.Bool("enable-ssl-passthrough", false,
 			`Enable SSL Passthrough.`)
 
@@ -124,23 +292,88 @@ A valid certificate chain is required to enable OCSP stapling. Certificates
 uploaded to Kubernetes must have the "Authority Information Access" X.509 v3
 extension for this to succeed.`)
 
+		outboundProxy = flags.String("outbound-proxy", "",
+			`URL of the HTTP proxy used by the controller to reach servers outside the cluster, i.e. http://proxy.example.com:3128.
+It is used to download the intermediate CA certificates during the SSL chain completion, the GeoIP2 databases
+and the IP ranges of --real-ip-ranges-urls.
+Requests sent by NGINX, like the external authentication, and DNS resolutions do not use it.
+If empty, the environment variables HTTP_PROXY, HTTPS_PROXY and NO_PROXY are used.`)
+
+		outboundNoProxy = flags.String("outbound-no-proxy", "",
+			`Comma separated list of hosts, domains and CIDRs that are contacted without
+the proxy defined in --outbound-proxy.`)
+
+		sslClockSkewLeeway = flags.Duration("ssl-clock-skew-leeway", 5*time.Minute,
+			`Time a SSL certificate is considered valid before the beginning of its validity period.
+Certificates are usually used right after they are issued and the clocks of the nodes
+and the certificate authority can differ.`)
+
 		syncRateLimit = flags.Float32("sync-rate-limit", 0.3,
 			`Define the sync frequency upper limit`)
 
+		dynamicConfigurationRetries = flags.Int("dynamic-configuration-retries", 5,
+			`Number of times a failed update of the configuration applied without reloads, like the endpoints of
+the backends, is retried in a sync before the sync fails and is queued again.`)
+
+		dynamicConfigurationRetryDelay = flags.Duration("dynamic-configuration-retry-delay", 1*time.Second,
+			`Time before the first retry of a failed update of the dynamic configuration.`)
+
+		dynamicConfigurationRetryFactor = flags.Float64("dynamic-configuration-retry-factor", 1.5,
+			`Factor multiplying the time between retries of the dynamic configuration after each retry. Must be 1 or greater.`)
+
+		dynamicConfigurationFailuresBeforeReload = flags.Int("dynamic-configuration-failures-before-reload", 3,
+			`Number of consecutive syncs that fail to update the dynamic configuration after which NGINX is reloaded,
+to recover from errors in the Lua workers. 0 never reloads NGINX because of these failures.`)
+
+		syncWatchdogDeadline = flags.Duration("sync-watchdog-deadline", 10*time.Minute,
+			`Maximum time a sync of the configuration can run, like a hung test of the configuration or a blocked
+update of the dynamic configuration. Longer syncs are reported in the log with the stack traces of all the
+goroutines and counted in the metric nginx_ingress_controller_sync_watchdog_timeouts. 0 disables the watchdog.`)
+
+		syncWatchdogRestart = flags.Bool("sync-watchdog-restart", false,
+			`Exit the controller, to be restarted by Kubernetes, when a sync runs longer than sync-watchdog-deadline.`)
+
 		publishStatusAddress = flags.String("publish-status-address", "",
 			`Customized address to set as the load-balancer status of Ingress objects this controller satisfies.
-Requires the update-status parameter.`)
+Accepts a comma separated list of IP addresses or hostnames. Requires the update-status parameter.`)
 
 		dynamicCertificatesEnabled = flags.Bool("enable-dynamic-certificates", false,
 			`Dynamically update SSL certificates instead of reloading NGINX.
 Feature backed by OpenResty Lua libraries. Requires that OCSP stapling is not enabled`)
 
+		logFormat = flags.String("log-format", log.TextFormat,
+			`Format of the logs of the controller written to stderr. Valid values are "text",
+the format of glog, and "json", a JSON object in each line. The Kubernetes client
+libraries still log through glog, in its text format, to the same stderr.`)
+
+		logSyslogAddress = flags.String("log-syslog-address", "",
+			`Address of a syslog server that receives a copy of the logs of the controller,
+like udp://10.0.0.1:514 or tcp://logs.example.com:601. The logs are sent in the background:
+they are discarded while the server is unavailable or too slow.`)
+
+		profile = flags.String("profile", ngx_config.DefaultProfile,
+			`Set of defaults used for the configuration of NGINX, values defined in the
+configuration ConfigMap take precedence. Valid values are "default" and "low-memory".
+The low-memory profile shrinks the Lua shared dictionaries, disables the collection
+of request metrics and reduces the number of worker processes, for small edge
+devices like Raspberry Pi clusters.`)
+
 		httpPort      = flags.Int("http-port", 80, `Port to use for servicing HTTP traffic.`)
 		httpsPort     = flags.Int("https-port", 443, `Port to use for servicing HTTPS traffic.`)
 		statusPort    = flags.Int("status-port", 18080, `Port to use for exposing NGINX status pages.`)
 		sslProxyPort  = flags.Int("ssl-passthrough-proxy-port", 442, `Port to use internally for SSL Passthrough.`)
 		defServerPort = flags.Int("default-server-port", 8181, `Port to use for exposing the default server (catch-all).`)
 		healthzPort   = flags.Int("healthz-port", 10254, "Port to use for the healthz endpoint.")
+
+		internalHTTPPort = flags.Int("internal-http-port", 0,
+			`Port to use for servicing the HTTP traffic of the Ingresses with the annotation
+internal, which do not listen on the HTTP and HTTPS ports. Zero disables the port.`)
+		internalHTTPSPort = flags.Int("internal-https-port", 0,
+			`Port to use for servicing the HTTPS traffic of the Ingresses with the annotation
+internal. Zero disables the port.`)
+		internalBindAddress = flags.String("internal-bind-address", "",
+			`IP address of the internal ports, like the address of a node interface not
+reachable from the internet. All the addresses by default.`)
 	)
 
 	flag.Set("logtostderr", "true")
@@ -152,8 +385,13 @@ Feature backed by OpenResty Lua libraries. Requires that OCSP stapling is not en
 	// https://github.com/kubernetes/kubernetes/issues/17162
 	flag.CommandLine.Parse([]string{})
 
+	err := configureLogging(*logFormat, *logSyslogAddress)
+	if err != nil {
+		return false, nil, err
+	}
+
 	pflag.VisitAll(func(flag *pflag.Flag) {
-		glog.V(2).Infof("FLAG: --%s=%q", flag.Name, flag.Value)
+		log.V(2).Infof("FLAG: --%s=%q", flag.Name, flag.Value)
 	})
 
 	if *showVersion {
@@ -161,10 +399,10 @@ Feature backed by OpenResty Lua libraries. Requires that OCSP stapling is not en
 	}
 
 	if *ingressClass != "" {
-		glog.Infof("Watching for Ingress class: %s", *ingressClass)
+		log.Infof("Watching for Ingress class: %s", *ingressClass)
 
 		if *ingressClass != class.DefaultClass {
-			glog.Warningf("Only Ingresses with class %q will be processed by this Ingress controller", *ingressClass)
+			log.Warningf("Only Ingresses with class %q will be processed by this Ingress controller", *ingressClass)
 		}
 
 		class.IngressClass = *ingressClass
@@ -172,62 +410,264 @@ Feature backed by OpenResty Lua libraries. Requires that OCSP stapling is not en
 
 	parser.AnnotationsPrefix = *annotationsPrefix
 
-	// check port collisions
-	if !ing_net.IsPortAvailable(*httpPort) {
-		return false, nil, fmt.Errorf("Port %v is already in use. Please check the flag --http-port", *httpPort)
+	template, err := classTemplate(*classTemplates, class.IngressClass, *templatePath)
+	if err != nil {
+		return false, nil, err
 	}
 
-	if !ing_net.IsPortAvailable(*httpsPort) {
-		return false, nil, fmt.Errorf("Port %v is already in use. Please check the flag --https-port", *httpsPort)
-	}
+	// check port collisions, reported by the preflight checks instead
+	if !*preflight {
+		if !ing_net.IsPortAvailable(*httpPort) {
+			return false, nil, fmt.Errorf("Port %v is already in use. Please check the flag --http-port", *httpPort)
+		}
 
-	if !ing_net.IsPortAvailable(*statusPort) {
-		return false, nil, fmt.Errorf("Port %v is already in use. Please check the flag --status-port", *statusPort)
-	}
+		if !ing_net.IsPortAvailable(*httpsPort) {
+			return false, nil, fmt.Errorf("Port %v is already in use. Please check the flag --https-port", *httpsPort)
+		}
 
-	if !ing_net.IsPortAvailable(*defServerPort) {
-		return false, nil, fmt.Errorf("Port %v is already in use. Please check the flag --default-server-port", *defServerPort)
-	}
+		if !ing_net.IsPortAvailable(*statusPort) {
+			return false, nil, fmt.Errorf("Port %v is already in use. Please check the flag --status-port", *statusPort)
+		}
+
+		if !ing_net.IsPortAvailable(*defServerPort) {
+			return false, nil, fmt.Errorf("Port %v is already in use. Please check the flag --default-server-port", *defServerPort)
+		}
+
+		if *enableSSLPassthrough && !ing_net.IsPortAvailable(*sslProxyPort) {
+			return false, nil, fmt.Errorf("Port %v is already in use. Please check the flag --ssl-passthrough-proxy-port", *sslProxyPort)
+		}
+
+		if *internalHTTPPort > 0 && !ing_net.IsPortAvailable(*internalHTTPPort) {
+			return false, nil, fmt.Errorf("Port %v is already in use. Please check the flag --internal-http-port", *internalHTTPPort)
+		}
 
-	if *enableSSLPassthrough && !ing_net.IsPortAvailable(*sslProxyPort) {
-		return false, nil, fmt.Errorf("Port %v is already in use. Please check the flag --ssl-passthrough-proxy-port", *sslProxyPort)
+		if *internalHTTPSPort > 0 && !ing_net.IsPortAvailable(*internalHTTPSPort) {
+			return false, nil, fmt.Errorf("Port %v is already in use. Please check the flag --internal-https-port", *internalHTTPSPort)
+		}
 	}
 
 	if !*enableSSLChainCompletion {
-		glog.Warningf("SSL certificate chain completion is disabled (--enable-ssl-chain-completion=false)")
+		log.Warningf("SSL certificate chain completion is disabled (--enable-ssl-chain-completion=false)")
 	}
 
 	if *enableSSLChainCompletion && *dynamicCertificatesEnabled {
 		return false, nil, fmt.Errorf(`SSL certificate chain completion cannot be enabled when dynamic certificates functionality is enabled. Please check the flags --enable-ssl-chain-completion`)
 	}
 
+	var outboundProxyURL *url.URL
+	if *outboundProxy != "" {
+		proxyURL, err := url.Parse(*outboundProxy)
+		if err != nil || proxyURL.Host == "" || (proxyURL.Scheme != "http" && proxyURL.Scheme != "https") {
+			return false, nil, fmt.Errorf("Flag --outbound-proxy must be a valid http or https URL")
+		}
+		outboundProxyURL = proxyURL
+	}
+
+	var ipRangesURLs []string
+	for _, rangesURL := range strings.Split(*realIPRangesURLs, ",") {
+		rangesURL = strings.TrimSpace(rangesURL)
+		if rangesURL == "" {
+			continue
+		}
+
+		u, err := url.Parse(rangesURL)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return false, nil, fmt.Errorf("Flag --real-ip-ranges-urls contains an invalid URL %q, it must be a http or https URL", rangesURL)
+		}
+		ipRangesURLs = append(ipRangesURLs, rangesURL)
+	}
+
+	if *publishEffectiveConfig && !*updateStatus {
+		return false, nil, fmt.Errorf("Flag --publish-effective-configuration requires --update-status")
+	}
+
+	if len(ipRangesURLs) > 0 && *realIPRangesSyncPeriod <= 0 {
+		return false, nil, fmt.Errorf("Flag --real-ip-ranges-sync-period must be greater than zero")
+	}
+
+	if *internalBindAddress != "" && net.ParseIP(*internalBindAddress) == nil {
+		return false, nil, fmt.Errorf("Flag --internal-bind-address must be an IP address")
+	}
+
+	if !ngx_config.IsValidProfile(*profile) {
+		return false, nil, fmt.Errorf("Flag --profile must be one of \"%v\" or \"%v\"", ngx_config.DefaultProfile, ngx_config.LowMemoryProfile)
+	}
+
+	if *maxmindLicenseKey != "" && *maxmindRefreshPeriod <= 0 {
+		return false, nil, fmt.Errorf("Flag --maxmind-refresh-period must be greater than zero")
+	}
+
+	if err := status.ValidateElectionDurations(*electionLeaseDuration, *electionRenewDeadline, *electionRetryPeriod); err != nil {
+		return false, nil, fmt.Errorf("Invalid leader election flags (--election-lease-duration, --election-renew-deadline or --election-retry-period): %v", err)
+	}
+
+	if !status.IsValidElectionLockType(*electionLockType) {
+		return false, nil, fmt.Errorf("Flag --election-lock-type must be one of %q or %q", status.ElectionLockConfigMaps, status.ElectionLockLeases)
+	}
+
+	switch *classConflictPolicy {
+	case controller.ClassConflictIgnore, controller.ClassConflictWarn, controller.ClassConflictSkip:
+	default:
+		return false, nil, fmt.Errorf("Flag --class-conflict-policy must be one of %q, %q or %q",
+			controller.ClassConflictIgnore, controller.ClassConflictWarn, controller.ClassConflictSkip)
+	}
+
+	if *configTestWorkers < 1 {
+		return false, nil, fmt.Errorf("Flag --config-test-workers must be greater than zero")
+	}
+
+	if *reloadConcurrency < 0 {
+		return false, nil, fmt.Errorf("Flag --reload-concurrency must be zero or greater")
+	}
+
+	if *reloadConcurrency > 0 && *reloadCoordinationTimeout <= 0 {
+		return false, nil, fmt.Errorf("Flag --reload-coordination-timeout must be greater than zero")
+	}
+
+	if *websocketReloadThreshold < 0 {
+		return false, nil, fmt.Errorf("Flag --websocket-reload-threshold must be zero or greater")
+	}
+
+	if *websocketReloadThreshold > 0 && *websocketReloadMaxDelay <= 0 {
+		return false, nil, fmt.Errorf("Flag --websocket-reload-max-delay must be greater than zero")
+	}
+
+	if *listPageSize < 0 {
+		return false, nil, fmt.Errorf("Flag --list-page-size must be zero or greater")
+	}
+
+	if *shardCount < 1 {
+		return false, nil, fmt.Errorf("Flag --shard-count must be 1 or greater")
+	}
+
+	if *shardIndex < 0 || *shardIndex >= *shardCount {
+		return false, nil, fmt.Errorf("Flag --shard-index must be between 0 and --shard-count - 1")
+	}
+
+	if *shutdownGracePeriod < 0 {
+		return false, nil, fmt.Errorf("Flag --shutdown-grace-period must be zero or greater")
+	}
+
+	if *dynamicConfigurationRetries < 0 {
+		return false, nil, fmt.Errorf("Flag --dynamic-configuration-retries must be zero or greater")
+	}
+
+	if *dynamicConfigurationRetryDelay < 0 {
+		return false, nil, fmt.Errorf("Flag --dynamic-configuration-retry-delay must be zero or greater")
+	}
+
+	if *dynamicConfigurationRetryFactor < 1 {
+		return false, nil, fmt.Errorf("Flag --dynamic-configuration-retry-factor must be 1 or greater")
+	}
+
+	if *dynamicConfigurationFailuresBeforeReload < 0 {
+		return false, nil, fmt.Errorf("Flag --dynamic-configuration-failures-before-reload must be zero or greater")
+	}
+
+	if *syncWatchdogDeadline < 0 {
+		return false, nil, fmt.Errorf("Flag --sync-watchdog-deadline must be zero or greater")
+	}
+
+	if *syncWatchdogRestart && *syncWatchdogDeadline == 0 {
+		return false, nil, fmt.Errorf("Flag --sync-watchdog-restart requires a --sync-watchdog-deadline greater than zero")
+	}
+
+	switch *duplicatePathPolicy {
+	case controller.DuplicatePathFirstWins, controller.DuplicatePathReject, controller.DuplicatePathMerge:
+	default:
+		return false, nil, fmt.Errorf("Flag --duplicate-path-policy must be one of %q, %q or %q",
+			controller.DuplicatePathFirstWins, controller.DuplicatePathReject, controller.DuplicatePathMerge)
+	}
+
+	var namespaceSelector labels.Selector
+	if *watchNamespaceSelector != "" {
+		namespaceSelector, err = labels.Parse(*watchNamespaceSelector)
+		if err != nil {
+			return false, nil, fmt.Errorf("Flag --watch-namespace-selector is not a valid label selector: %v", err)
+		}
+	}
+
 	if *publishSvc != "" && *publishStatusAddress != "" {
 		return false, nil, fmt.Errorf("Flags --publish-service and --publish-status-address are mutually exclusive")
 	}
 
+	for _, svc := range strings.Split(*publishSvc, ",") {
+		svc = strings.TrimSpace(svc)
+		if svc == "" {
+			continue
+		}
+
+		if _, _, err := k8s.ParseNameNS(svc); err != nil {
+			return false, nil, fmt.Errorf("Flag --publish-service contains an invalid Service reference: %v", err)
+		}
+	}
+
 	config := &controller.Configuration{
-		APIServerHost:              *apiserverHost,
-		KubeConfigFile:             *kubeConfigFile,
-		UpdateStatus:               *updateStatus,
-		ElectionID:                 *electionID,
-		EnableProfiling:            *profiling,
-		EnableSSLPassthrough:       *enableSSLPassthrough,
-		EnableSSLChainCompletion:   *enableSSLChainCompletion,
-		ResyncPeriod:               *resyncPeriod,
-		DefaultService:             *defaultSvc,
-		Namespace:                  *watchNamespace,
-		ConfigMapName:              *configMap,
-		DefaultSSLCertificate:      *defSSLCertificate,
-		DefaultHealthzURL:          *defHealthzURL,
-		HealthCheckTimeout:         *healthCheckTimeout,
-		PublishService:             *publishSvc,
-		PublishStatusAddress:       *publishStatusAddress,
-		ForceNamespaceIsolation:    *forceIsolation,
-		UpdateStatusOnShutdown:     *updateStatusOnShutdown,
-		SortBackends:               *sortBackends,
-		UseNodeInternalIP:          *useNodeInternalIP,
-		SyncRateLimit:              *syncRateLimit,
-		DynamicCertificatesEnabled: *dynamicCertificatesEnabled,
+		APIServerHost:                            *apiserverHost,
+		KubeConfigFile:                           *kubeConfigFile,
+		UpdateStatus:                             *updateStatus,
+		ElectionID:                               *electionID,
+		ElectionLeaseDuration:                    *electionLeaseDuration,
+		ElectionRenewDeadline:                    *electionRenewDeadline,
+		ElectionRetryPeriod:                      *electionRetryPeriod,
+		ElectionLockType:                         *electionLockType,
+		EnableProfiling:                          *profiling,
+		EnableNamespaceMetrics:                   *namespaceMetrics,
+		EnableAnnotationBundle:                   *annotationBundle,
+		EnableCanaryAPI:                          *canaryAPI,
+		EnableRoutingOverrides:                   *routingOverrides,
+		ChargebackLabel:                          *chargebackLabel,
+		ClassConflictPolicy:                      *classConflictPolicy,
+		DuplicatePathPolicy:                      *duplicatePathPolicy,
+		EndpointWeightAnnotation:                 *endpointWeightAnnotation,
+		ExternalNameResolvePeriod:                *externalNameResolvePeriod,
+		RealIPRangesURLs:                         ipRangesURLs,
+		RealIPRangesSyncPeriod:                   *realIPRangesSyncPeriod,
+		PublishEffectiveConfig:                   *publishEffectiveConfig,
+		ConfigTestWorkers:                        *configTestWorkers,
+		ReloadConcurrency:                        *reloadConcurrency,
+		ReloadCoordinationTimeout:                *reloadCoordinationTimeout,
+		WebSocketReloadThreshold:                 *websocketReloadThreshold,
+		WebSocketReloadMaxDelay:                  *websocketReloadMaxDelay,
+		ShutdownGracePeriod:                      *shutdownGracePeriod,
+		ListPageSize:                             *listPageSize,
+		ShardIndex:                               *shardIndex,
+		ShardCount:                               *shardCount,
+		MaxmindLicenseKey:                        *maxmindLicenseKey,
+		MaxmindRefreshPeriod:                     *maxmindRefreshPeriod,
+		EnableSSLPassthrough:                     *enableSSLPassthrough,
+		EnableSSLChainCompletion:                 *enableSSLChainCompletion,
+		OutboundProxy:                            outboundProxyURL,
+		OutboundNoProxy:                          strings.Split(*outboundNoProxy, ","),
+		ResyncPeriod:                             *resyncPeriod,
+		DefaultService:                           *defaultSvc,
+		Namespace:                                *watchNamespace,
+		NamespaceSelector:                        namespaceSelector,
+		ConfigMapName:                            *configMap,
+		DenylistConfigMap:                        *denylistConfigMap,
+		HostRedirectConfigMap:                    *hostRedirectConfigMap,
+		DefaultSSLCertificate:                    *defSSLCertificate,
+		DefaultHealthzURL:                        *defHealthzURL,
+		HealthCheckTimeout:                       *healthCheckTimeout,
+		PublishService:                           *publishSvc,
+		PublishStatusAddress:                     *publishStatusAddress,
+		ForceNamespaceIsolation:                  *forceIsolation,
+		UpdateStatusOnShutdown:                   *updateStatusOnShutdown,
+		SortBackends:                             *sortBackends,
+		DeterministicEndpointOrder:               *deterministicEndpointOrder,
+		UseNodeInternalIP:                        *useNodeInternalIP,
+		SyncRateLimit:                            *syncRateLimit,
+		DynamicConfigurationRetries:              *dynamicConfigurationRetries,
+		DynamicConfigurationRetryDelay:           *dynamicConfigurationRetryDelay,
+		DynamicConfigurationRetryFactor:          *dynamicConfigurationRetryFactor,
+		DynamicConfigurationFailuresBeforeReload: *dynamicConfigurationFailuresBeforeReload,
+		TemplatePath:                             template,
+		SyncWatchdogDeadline:                     *syncWatchdogDeadline,
+		SyncWatchdogRestart:                      *syncWatchdogRestart,
+		SSLClockSkewLeeway:                       *sslClockSkewLeeway,
+		DynamicCertificatesEnabled:               *dynamicCertificatesEnabled,
+		Profile:                                  *profile,
+		Preflight:                                *preflight,
 		ListenPorts: &ngx_config.ListenPorts{
 			Default:  *defServerPort,
 			Health:   *healthzPort,
@@ -235,8 +675,66 @@ Feature backed by OpenResty Lua libraries. Requires that OCSP stapling is not en
 			HTTPS:    *httpsPort,
 			SSLProxy: *sslProxyPort,
 			Status:   *statusPort,
+
+			Internal:        *internalHTTPPort,
+			InternalHTTPS:   *internalHTTPSPort,
+			InternalAddress: *internalBindAddress,
 		},
 	}
 
 	return false, config, nil
 }
+
+// classTemplate returns the template used by the controller of an ingress
+// class, defined in a list of class=path pairs or the default template
+func classTemplate(templates, ingressClass, defaultTemplate string) (string, error) {
+	template := defaultTemplate
+	for _, pair := range strings.Split(templates, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return "", fmt.Errorf("Flag --class-templates contains an invalid pair %q, the format is class=path", pair)
+		}
+
+		if parts[0] == ingressClass {
+			template = parts[1]
+		}
+	}
+
+	return template, nil
+}
+
+// configureLogging configures the sinks of the logs of the controller and
+// their verbosity, defined by the flag -v shared with the libraries that
+// use glog
+func configureLogging(format, syslogAddress string) error {
+	sink, err := log.NewSink(format, os.Stderr)
+	if err != nil {
+		return fmt.Errorf("Flag --log-format: %v", err)
+	}
+
+	sinks := []log.Sink{sink}
+	if syslogAddress != "" {
+		sink, err := log.NewSyslogSink(syslogAddress, "nginx-ingress-controller")
+		if err != nil {
+			return fmt.Errorf("Flag --log-syslog-address: %v", err)
+		}
+
+		sinks = append(sinks, sink)
+	}
+
+	log.SetSinks(sinks...)
+
+	if v := flag.Lookup("v"); v != nil {
+		level, err := strconv.Atoi(v.Value.String())
+		if err == nil {
+			log.SetVerbosity(level)
+		}
+	}
+
+	return nil
+}
@@ -28,11 +28,13 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/golang/glog"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
 	discovery "k8s.io/apimachinery/pkg/version"
 	"k8s.io/apiserver/pkg/server/healthz"
@@ -40,10 +42,13 @@ import (
 	"k8s.io/client-go/tools/clientcmd"
 
 	"k8s.io/ingress-nginx/internal/file"
+	"k8s.io/ingress-nginx/internal/ingress"
 	"k8s.io/ingress-nginx/internal/ingress/controller"
 	"k8s.io/ingress-nginx/internal/ingress/metric"
 	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/internal/log"
 	"k8s.io/ingress-nginx/internal/net/ssl"
+	"k8s.io/ingress-nginx/internal/preflight"
 	"k8s.io/ingress-nginx/version"
 )
 
@@ -61,22 +66,27 @@ const (
 func main() {
 	rand.Seed(time.Now().UnixNano())
 
+	showVersion, conf, err := parseFlags()
+	if err == nil && conf != nil && conf.Preflight {
+		// only the report is printed to keep the output machine-readable
+		os.Exit(runPreflight(conf))
+	}
+
 	fmt.Println(version.String())
 
-	showVersion, conf, err := parseFlags()
 	if showVersion {
 		os.Exit(0)
 	}
 
 	if err != nil {
-		glog.Fatal(err)
+		log.Fatal(err)
 	}
 
 	nginxVersion()
 
 	fs, err := file.NewLocalFS()
 	if err != nil {
-		glog.Fatal(err)
+		log.Fatal(err)
 	}
 
 	kubeClient, err := createApiserverClient(conf.APIServerHost, conf.KubeConfigFile)
@@ -87,24 +97,24 @@ func main() {
 	if len(conf.DefaultService) > 0 {
 		defSvcNs, defSvcName, err := k8s.ParseNameNS(conf.DefaultService)
 		if err != nil {
-			glog.Fatal(err)
+			log.Fatal(err)
 		}
 
 		_, err = kubeClient.CoreV1().Services(defSvcNs).Get(defSvcName, metav1.GetOptions{})
 		if err != nil {
 			// TODO (antoineco): compare with error types from k8s.io/apimachinery/pkg/api/errors
 			if strings.Contains(err.Error(), "cannot get services in the namespace") {
-				glog.Fatal("✖ The cluster seems to be running with a restrictive Authorization mode and the Ingress controller does not have the required permissions to operate normally.")
+				log.Fatal("✖ The cluster seems to be running with a restrictive Authorization mode and the Ingress controller does not have the required permissions to operate normally.")
 			}
-			glog.Fatalf("No service with name %v found: %v", conf.DefaultService, err)
+			log.Fatalf("No service with name %v found: %v", conf.DefaultService, err)
 		}
-		glog.Infof("Validated %v as the default backend.", conf.DefaultService)
+		log.Infof("Validated %v as the default backend.", conf.DefaultService)
 	}
 
 	if conf.Namespace != "" {
 		_, err = kubeClient.CoreV1().Namespaces().Get(conf.Namespace, metav1.GetOptions{})
 		if err != nil {
-			glog.Fatalf("No namespace with name %v found: %v", conf.Namespace, err)
+			log.Fatalf("No namespace with name %v found: %v", conf.Namespace, err)
 		}
 	}
 
@@ -112,7 +122,7 @@ func main() {
 	defCert, defKey := ssl.GetFakeSSLCert()
 	c, err := ssl.AddOrUpdateCertAndKey(fakeCertificate, defCert, defKey, []byte{}, fs)
 	if err != nil {
-		glog.Fatalf("Error generating self-signed certificate: %v", err)
+		log.Fatalf("Error generating self-signed certificate: %v", err)
 	}
 
 	conf.FakeCertificatePath = c.PemFileName
@@ -130,7 +140,7 @@ func main() {
 
 	mc, err := metric.NewCollector(conf.ListenPorts.Status, reg)
 	if err != nil {
-		glog.Fatalf("Error creating prometheus collector:  %v", err)
+		log.Fatalf("Error creating prometheus collector:  %v", err)
 	}
 	mc.Start()
 
@@ -147,6 +157,18 @@ func main() {
 
 	registerHealthz(ngx, mux)
 	registerMetrics(reg, mux)
+	if conf.EnableAnnotationBundle {
+		registerAnnotationBundle(ngx, kubeClient, mux)
+	}
+	if conf.EnableNamespaceMetrics {
+		registerNamespaceMetrics(reg, kubeClient, mux)
+	}
+	if conf.EnableCanaryAPI {
+		registerCanaryAPI(ngx, kubeClient, mux)
+	}
+	if conf.EnableRoutingOverrides {
+		registerRoutingOverrides(ngx, kubeClient, mux)
+	}
 	registerHandlers(mux)
 
 	go startHTTPServer(conf.ListenPorts.Health, mux)
@@ -156,22 +178,62 @@ func main() {
 
 type exiter func(code int)
 
+// runPreflight prints the report of the preflight checks and returns
+// the exit code, 1 if a check found a blocker
+func runPreflight(conf *controller.Configuration) int {
+	ports := map[string]int{
+		"http-port":           conf.ListenPorts.HTTP,
+		"https-port":          conf.ListenPorts.HTTPS,
+		"status-port":         conf.ListenPorts.Status,
+		"healthz-port":        conf.ListenPorts.Health,
+		"default-server-port": conf.ListenPorts.Default,
+	}
+	if conf.EnableSSLPassthrough {
+		ports["ssl-passthrough-proxy-port"] = conf.ListenPorts.SSLProxy
+	}
+	if conf.ListenPorts.Internal > 0 {
+		ports["internal-http-port"] = conf.ListenPorts.Internal
+	}
+	if conf.ListenPorts.InternalHTTPS > 0 {
+		ports["internal-https-port"] = conf.ListenPorts.InternalHTTPS
+	}
+
+	report := preflight.Run(preflight.Config{
+		Ports:        ports,
+		WritableDirs: []string{"/etc/nginx", ingress.DefaultSSLDirectory, os.TempDir()},
+	})
+
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		log.Errorf("Error encoding the preflight report: %v", err)
+		return 1
+	}
+
+	fmt.Println(string(b))
+
+	if report.Blockers > 0 {
+		return 1
+	}
+
+	return 0
+}
+
 func handleSigterm(ngx *controller.NGINXController, exit exiter) {
 	signalChan := make(chan os.Signal, 1)
 	signal.Notify(signalChan, syscall.SIGTERM)
 	<-signalChan
-	glog.Info("Received SIGTERM, shutting down")
+	log.Info("Received SIGTERM, shutting down")
 
 	exitCode := 0
 	if err := ngx.Stop(); err != nil {
-		glog.Infof("Error during shutdown: %v", err)
+		log.Infof("Error during shutdown: %v", err)
 		exitCode = 1
 	}
 
-	glog.Info("Handled quit, awaiting Pod deletion")
+	log.Info("Handled quit, awaiting Pod deletion")
 	time.Sleep(10 * time.Second)
 
-	glog.Infof("Exiting with %v", exitCode)
+	log.Infof("Exiting with %v", exitCode)
 	exit(exitCode)
 }
 
@@ -193,7 +255,7 @@ func createApiserverClient(apiserverHost, kubeConfig string) (*kubernetes.Client
 	cfg.Burst = defaultBurst
 	cfg.ContentType = "application/vnd.kubernetes.protobuf"
 
-	glog.Infof("Creating API client for %s", cfg.Host)
+	log.Infof("Creating API client for %s", cfg.Host)
 
 	client, err := kubernetes.NewForConfig(cfg)
 	if err != nil {
@@ -213,7 +275,7 @@ func createApiserverClient(apiserverHost, kubeConfig string) (*kubernetes.Client
 
 	var lastErr error
 	retries := 0
-	glog.V(2).Info("Trying to discover Kubernetes version")
+	log.V(2).Info("Trying to discover Kubernetes version")
 	err = wait.ExponentialBackoff(defaultRetry, func() (bool, error) {
 		v, err = client.Discovery().ServerVersion()
 
@@ -222,7 +284,7 @@ func createApiserverClient(apiserverHost, kubeConfig string) (*kubernetes.Client
 		}
 
 		lastErr = err
-		glog.V(2).Infof("Unexpected error discovering Kubernetes version (attempt %v): %v", retries, err)
+		log.V(2).Infof("Unexpected error discovering Kubernetes version (attempt %v): %v", retries, err)
 		retries++
 		return false, nil
 	})
@@ -234,10 +296,10 @@ func createApiserverClient(apiserverHost, kubeConfig string) (*kubernetes.Client
 
 	// this should not happen, warn the user
 	if retries > 0 {
-		glog.Warningf("Initial connection to the Kubernetes API server was retried %d times.", retries)
+		log.Warningf("Initial connection to the Kubernetes API server was retried %d times.", retries)
 	}
 
-	glog.Infof("Running in Kubernetes cluster version v%v.%v (%v) - git (%v) commit %v - platform %v",
+	log.Infof("Running in Kubernetes cluster version v%v.%v (%v) - git (%v) commit %v - platform %v",
 		v.Major, v.Minor, v.GitVersion, v.GitTreeState, v.GitCommit, v.Platform)
 
 	return client, nil
@@ -245,7 +307,7 @@ func createApiserverClient(apiserverHost, kubeConfig string) (*kubernetes.Client
 
 // Handler for fatal init errors. Prints a verbose error message and exits.
 func handleFatalInitError(err error) {
-	glog.Fatalf("Error while initiating a connection to the Kubernetes API server. "+
+	log.Fatalf("Error while initiating a connection to the Kubernetes API server. "+
 		"This could mean the cluster is misconfigured (e.g. it has invalid API server certificates "+
 		"or Service Accounts configuration). Reason: %s\n"+
 		"Refer to the troubleshooting guide for more information: "+
@@ -263,7 +325,7 @@ func registerHandlers(mux *http.ServeMux) {
 	mux.HandleFunc("/stop", func(w http.ResponseWriter, r *http.Request) {
 		err := syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
 		if err != nil {
-			glog.Errorf("Unexpected error: %v", err)
+			log.Errorf("Unexpected error: %v", err)
 		}
 	})
 }
@@ -276,6 +338,301 @@ func registerHealthz(ic *controller.NGINXController, mux *http.ServeMux) {
 	)
 }
 
+// registerAnnotationBundle exposes the export of the annotations of the
+// Ingresses (GET /annotations) and the validation of a bundle of
+// annotations (POST /annotations/validate). Requests must contain a bearer
+// token allowed to list Ingresses to export the annotations, or to create
+// Ingresses in the namespaces of the bundle to validate it.
+func registerAnnotationBundle(ic *controller.NGINXController, client kubernetes.Interface, mux *http.ServeMux) {
+	mux.HandleFunc("/annotations", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if !authorizeRequest(w, r, client, authorizationv1.ResourceAttributes{
+			Verb:     "list",
+			Group:    "extensions",
+			Resource: "ingresses",
+		}) {
+			return
+		}
+
+		b, err := json.Marshal(ic.ExportAnnotations())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(b)
+	})
+
+	mux.HandleFunc("/annotations/validate", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		bundle := &controller.AnnotationBundle{}
+		if err := json.NewDecoder(r.Body).Decode(bundle); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid annotation bundle: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		namespaces := sets.NewString()
+		for _, ia := range bundle.Ingresses {
+			namespaces.Insert(ia.Namespace)
+		}
+
+		attrs := make([]authorizationv1.ResourceAttributes, 0, namespaces.Len())
+		for _, namespace := range namespaces.List() {
+			attrs = append(attrs, authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      "create",
+				Group:     "extensions",
+				Resource:  "ingresses",
+			})
+		}
+
+		if !authorizeRequest(w, r, client, attrs...) {
+			return
+		}
+
+		status := http.StatusOK
+		if !ic.ValidateAnnotations(bundle) {
+			status = http.StatusUnprocessableEntity
+		}
+
+		b, err := json.Marshal(bundle)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write(b)
+	})
+}
+
+// registerRoutingOverrides exposes the temporary routing overrides:
+// list (GET), install (POST) and removal (DELETE with the host parameter).
+// Requests must contain a bearer token allowed to list Ingresses to read
+// the overrides, and to patch all the Ingresses of the host, and get the
+// Service receiving the requests, to change them.
+func registerRoutingOverrides(ic *controller.NGINXController, client kubernetes.Interface, mux *http.ServeMux) {
+	// hostAttributes returns the permissions required to change the routing of a host
+	hostAttributes := func(w http.ResponseWriter, host string) ([]authorizationv1.ResourceAttributes, bool) {
+		keys := ic.HostIngresses(host)
+		if len(keys) == 0 {
+			http.Error(w, fmt.Sprintf("No Ingress defines the host %q", host), http.StatusNotFound)
+			return nil, false
+		}
+
+		attrs := make([]authorizationv1.ResourceAttributes, 0, len(keys))
+		for _, key := range keys {
+			namespace, name, _ := k8s.ParseNameNS(key)
+			attrs = append(attrs, authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Name:      name,
+				Verb:      "patch",
+				Group:     "extensions",
+				Resource:  "ingresses",
+			})
+		}
+
+		return attrs, true
+	}
+
+	mux.HandleFunc("/routing-overrides", func(w http.ResponseWriter, r *http.Request) {
+		var res interface{}
+
+		switch r.Method {
+		case http.MethodGet:
+			if !authorizeRequest(w, r, client, authorizationv1.ResourceAttributes{
+				Verb:     "list",
+				Group:    "extensions",
+				Resource: "ingresses",
+			}) {
+				return
+			}
+
+			res = ic.RoutingOverrides()
+		case http.MethodPost:
+			req := struct {
+				controller.RoutingOverride
+				Duration string `json:"duration"`
+			}{}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, fmt.Sprintf("Invalid routing override: %v", err), http.StatusBadRequest)
+				return
+			}
+
+			svcNamespace, svcName, err := k8s.ParseNameNS(req.Service)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Invalid Service: %v", err), http.StatusBadRequest)
+				return
+			}
+
+			attrs, ok := hostAttributes(w, req.Host)
+			if !ok {
+				return
+			}
+
+			attrs = append(attrs, authorizationv1.ResourceAttributes{
+				Namespace: svcNamespace,
+				Name:      svcName,
+				Verb:      "get",
+				Resource:  "services",
+			})
+			if !authorizeRequest(w, r, client, attrs...) {
+				return
+			}
+
+			d, err := time.ParseDuration(req.Duration)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Invalid duration: %v", err), http.StatusBadRequest)
+				return
+			}
+
+			o, err := ic.AddRoutingOverride(req.RoutingOverride, d)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			res = o
+		case http.MethodDelete:
+			host := r.URL.Query().Get("host")
+
+			attrs, ok := hostAttributes(w, host)
+			if !ok {
+				return
+			}
+
+			if !authorizeRequest(w, r, client, attrs...) {
+				return
+			}
+
+			if !ic.DeleteRoutingOverride(host) {
+				http.Error(w, fmt.Sprintf("No routing override for host %q", host), http.StatusNotFound)
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+			return
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		b, err := json.Marshal(res)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(b)
+	})
+}
+
+// authorizeRequest checks the bearer token of a request is allowed to
+// access all the resources, writing the error in the response otherwise
+func authorizeRequest(w http.ResponseWriter, r *http.Request, client kubernetes.Interface, attrs ...authorizationv1.ResourceAttributes) bool {
+	token := k8s.BearerToken(r)
+	if token == "" {
+		http.Error(w, "a bearer token is required", http.StatusUnauthorized)
+		return false
+	}
+
+	for _, a := range attrs {
+		allowed, err := k8s.Authorize(client, token, a)
+		if err != nil {
+			log.Errorf("Unexpected error authorizing access to %v %v/%v: %v", a.Resource, a.Namespace, a.Name, err)
+			http.Error(w, "unexpected error authorizing the request", http.StatusInternalServerError)
+			return false
+		}
+
+		if !allowed {
+			http.Error(w, "access denied", http.StatusForbidden)
+			return false
+		}
+	}
+
+	return true
+}
+
+// registerCanaryAPI exposes the weight of the canary Ingresses in
+// /canaries/<namespace>/<name>: read (GET) and change (PUT). Requests must
+// contain a bearer token allowed to get or patch the Ingress.
+func registerCanaryAPI(ic *controller.NGINXController, client kubernetes.Interface, mux *http.ServeMux) {
+	mux.HandleFunc("/canaries/", func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/canaries/"), "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			http.NotFound(w, r)
+			return
+		}
+		namespace, name := parts[0], parts[1]
+
+		var verb string
+		switch r.Method {
+		case http.MethodGet:
+			verb = "get"
+		case http.MethodPut:
+			verb = "patch"
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if !authorizeRequest(w, r, client, authorizationv1.ResourceAttributes{
+			Namespace: namespace,
+			Name:      name,
+			Verb:      verb,
+			Group:     "extensions",
+			Resource:  "ingresses",
+		}) {
+			return
+		}
+
+		var res interface{}
+		var err error
+		if r.Method == http.MethodPut {
+			cw := controller.CanaryWeight{}
+			if err := json.NewDecoder(r.Body).Decode(&cw); err != nil {
+				http.Error(w, fmt.Sprintf("Invalid canary weight: %v", err), http.StatusBadRequest)
+				return
+			}
+
+			err = ic.SetCanaryWeight(namespace, name, cw)
+			res = cw
+		} else {
+			res, err = ic.GetCanaryWeight(namespace, name)
+		}
+
+		if err != nil {
+			code := http.StatusInternalServerError
+			if status, ok := err.(apierrors.APIStatus); ok {
+				code = int(status.Status().Code)
+			}
+
+			http.Error(w, err.Error(), code)
+			return
+		}
+
+		b, err := json.Marshal(res)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(b)
+	})
+}
+
 func registerMetrics(reg *prometheus.Registry, mux *http.ServeMux) {
 	mux.Handle(
 		"/metrics",
@@ -287,6 +644,10 @@ func registerMetrics(reg *prometheus.Registry, mux *http.ServeMux) {
 
 }
 
+func registerNamespaceMetrics(reg *prometheus.Registry, client kubernetes.Interface, mux *http.ServeMux) {
+	mux.Handle(metric.NamespaceMetricsPath, metric.NewNamespaceHandler(reg, client))
+}
+
 func registerProfiler(mux *http.ServeMux) {
 	mux.HandleFunc("/debug/pprof/", pprof.Index)
 	mux.HandleFunc("/debug/pprof/heap", pprof.Index)
@@ -309,5 +670,5 @@ func startHTTPServer(port int, mux *http.ServeMux) {
 		WriteTimeout:      300 * time.Second,
 		IdleTimeout:       120 * time.Second,
 	}
-	glog.Fatal(server.ListenAndServe())
+	log.Fatal(server.ListenAndServe())
 }
@@ -20,13 +20,13 @@ import (
 	"os"
 	"os/exec"
 
-	"github.com/golang/glog"
+	"k8s.io/ingress-nginx/internal/log"
 )
 
 func nginxVersion() {
 	flag := "-v"
 
-	if glog.V(2) {
+	if log.V(2) {
 		flag = "-V"
 	}
 
@@ -316,6 +316,12 @@ make install
 cd "$BUILD_PATH/lua-resty-cookie-0.1.0"
 make install
 
+# used to decompress the dynamic configuration posted by the controller
+luarocks install lua-ffi-zlib 0.4-0
+
+# install lua-resty-openidc and its dependencies (lua-resty-http, lua-resty-session and lua-resty-jwt)
+luarocks install lua-resty-openidc 1.6.1-1
+
 # build and install lua-resty-waf with dependencies
 /install_lua_resty_waf.sh
 
@@ -19,8 +19,9 @@ package file
 import (
 	"crypto/sha1"
 	"encoding/hex"
-	"github.com/golang/glog"
 	"io/ioutil"
+
+	"k8s.io/ingress-nginx/internal/log"
 )
 
 // SHA1 returns the SHA1 of a file.
@@ -28,7 +29,7 @@ func SHA1(filename string) string {
 	hasher := sha1.New()
 	s, err := ioutil.ReadFile(filename)
 	if err != nil {
-		glog.Errorf("Error reading file %v", err)
+		log.Errorf("Error reading file %v", err)
 		return ""
 	}
 
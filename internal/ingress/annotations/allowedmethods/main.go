@@ -0,0 +1,96 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package allowedmethods
+
+import (
+	"regexp"
+	"strings"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+var methodRegex = regexp.MustCompile(`^[A-Z]+$`)
+
+// Config contains the HTTP methods allowed in a location
+type Config struct {
+	Methods []string `json:"methods,omitempty"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if len(c1.Methods) != len(c2.Methods) {
+		return false
+	}
+	for i := range c1.Methods {
+		if c1.Methods[i] != c2.Methods[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+type allowedMethods struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new allowed methods annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return allowedMethods{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+// used to restrict the HTTP methods accepted in a location.
+// Multiple methods can be specified using commas as separator
+// e.g. `GET,HEAD,POST`
+// Requests using any other method are rejected with a 405 status code.
+func (a allowedMethods) Parse(ing *extensions.Ingress) (interface{}, error) {
+	val, err := parser.GetStringAnnotation("allowed-methods", ing)
+	if err != nil {
+		return nil, err
+	}
+
+	methods := sets.NewString()
+	for _, m := range strings.Split(val, ",") {
+		m = strings.ToUpper(strings.TrimSpace(m))
+		if m == "" {
+			continue
+		}
+
+		if !methodRegex.MatchString(m) {
+			return nil, ing_errors.NewInvalidAnnotationContent("allowed-methods", val)
+		}
+
+		methods.Insert(m)
+	}
+
+	if methods.Len() == 0 {
+		return nil, ing_errors.NewInvalidAnnotationContent("allowed-methods", val)
+	}
+
+	return &Config{Methods: methods.List()}, nil
+}
@@ -17,44 +17,70 @@ limitations under the License.
 package annotations
 
 import (
-	"github.com/golang/glog"
+	"fmt"
+
 	"github.com/imdario/mergo"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/canary"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/sslcipher"
+	ing_log "k8s.io/ingress-nginx/internal/log"
 
 	apiv1 "k8s.io/api/core/v1"
 	extensions "k8s.io/api/extensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"k8s.io/ingress-nginx/internal/ingress/annotations/alias"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/allowedmethods"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/auth"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/authoidc"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/authreq"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/authtls"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/backendprotocol"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/blocking"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/brotli"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/clientbodybuffersize"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/compression"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/connection"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/connectproxy"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/cors"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/defaultbackend"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/fastcgi"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/geoipfilter"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/headerrouting"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/hsts"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/includenotready"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/influxdb"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/internalonly"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/ipwhitelist"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/loadbalancing"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/locationpriority"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/log"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/luarestywaf"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/noauthlocations"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/opentracing"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/portinredirect"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/proxy"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/proxycache"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/proxyprotocol"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/proxyssl"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/queryrouting"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/ratelimit"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/realip"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/redirect"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/rewrite"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/satisfy"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/secureupstream"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/serversnippet"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/serviceupstream"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/sessionaffinity"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/sizemetrics"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/snippet"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/sslpassthrough"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/upstreamhashby"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/upstreamvhost"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/websocket"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/xforwardedprefix"
+	"k8s.io/ingress-nginx/internal/ingress/defaults"
 	"k8s.io/ingress-nginx/internal/ingress/errors"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
@@ -78,12 +104,15 @@ type Ingress struct {
 	Denied               error
 	ExternalAuth         authreq.Config
 	Proxy                proxy.Config
+	ProxyCache           proxycache.Config
+	ProxySSL             proxyssl.Config
 	RateLimit            ratelimit.Config
 	Redirect             redirect.Config
 	Rewrite              rewrite.Config
 	SecureUpstream       secureupstream.Config
 	ServerSnippet        string
 	ServiceUpstream      bool
+	IncludeNotReady      bool
 	SessionAffinity      sessionaffinity.Config
 	SSLPassthrough       bool
 	UsePortInRedirects   bool
@@ -96,11 +125,32 @@ type Ingress struct {
 	Logs                 log.Config
 	LuaRestyWAF          luarestywaf.Config
 	InfluxDB             influxdb.Config
+	Brotli               brotli.Config
+	Satisfy              string
+	Priority             int
+	AuthOIDC             authoidc.Config
+	QueryRouting         queryrouting.Config
+	HeaderRouting        headerrouting.Config
+	AllowedMethods       allowedmethods.Config
+	Blocking             blocking.Config
+	GeoIPFilter          geoipfilter.Config
+	ConnectProxy         connectproxy.Config
+	Compression          compression.Config
+	FastCGI              fastcgi.Config
+	Opentracing          opentracing.Config
+	SizeMetrics          bool
+	WebSocketHeavy       bool
+	NoAuthLocations      []string
+	Internal             bool
+	ProxyProtocol        string
+	RealIP               realip.Config
+	HSTS                 hsts.Config
 }
 
 // Extractor defines the annotation parsers to be used in the extraction of annotations
 type Extractor struct {
 	annotations map[string]parser.IngressAnnotation
+	resolver    resolver.Resolver
 }
 
 // NewAnnotationExtractor creates a new annotations extractor
@@ -118,12 +168,15 @@ func NewAnnotationExtractor(cfg resolver.Resolver) Extractor {
 			"DefaultBackend":       defaultbackend.NewParser(cfg),
 			"ExternalAuth":         authreq.NewParser(cfg),
 			"Proxy":                proxy.NewParser(cfg),
+			"ProxyCache":           proxycache.NewParser(cfg),
+			"ProxySSL":             proxyssl.NewParser(cfg),
 			"RateLimit":            ratelimit.NewParser(cfg),
 			"Redirect":             redirect.NewParser(cfg),
 			"Rewrite":              rewrite.NewParser(cfg),
 			"SecureUpstream":       secureupstream.NewParser(cfg),
 			"ServerSnippet":        serversnippet.NewParser(cfg),
 			"ServiceUpstream":      serviceupstream.NewParser(cfg),
+			"IncludeNotReady":      includenotready.NewParser(cfg),
 			"SessionAffinity":      sessionaffinity.NewParser(cfg),
 			"SSLPassthrough":       sslpassthrough.NewParser(cfg),
 			"UsePortInRedirects":   portinredirect.NewParser(cfg),
@@ -137,7 +190,28 @@ func NewAnnotationExtractor(cfg resolver.Resolver) Extractor {
 			"LuaRestyWAF":          luarestywaf.NewParser(cfg),
 			"InfluxDB":             influxdb.NewParser(cfg),
 			"BackendProtocol":      backendprotocol.NewParser(cfg),
+			"Brotli":               brotli.NewParser(cfg),
+			"Satisfy":              satisfy.NewParser(cfg),
+			"Priority":             locationpriority.NewParser(cfg),
+			"AuthOIDC":             authoidc.NewParser(cfg),
+			"QueryRouting":         queryrouting.NewParser(cfg),
+			"HeaderRouting":        headerrouting.NewParser(cfg),
+			"AllowedMethods":       allowedmethods.NewParser(cfg),
+			"Blocking":             blocking.NewParser(cfg),
+			"GeoIPFilter":          geoipfilter.NewParser(cfg),
+			"ConnectProxy":         connectproxy.NewParser(cfg),
+			"Compression":          compression.NewParser(cfg),
+			"FastCGI":              fastcgi.NewParser(cfg),
+			"Opentracing":          opentracing.NewParser(cfg),
+			"SizeMetrics":          sizemetrics.NewParser(cfg),
+			"WebSocketHeavy":       websocket.NewParser(cfg),
+			"NoAuthLocations":      noauthlocations.NewParser(cfg),
+			"Internal":             internalonly.NewParser(cfg),
+			"ProxyProtocol":        proxyprotocol.NewParser(cfg),
+			"RealIP":               realip.NewParser(cfg),
+			"HSTS":                 hsts.NewParser(cfg),
 		},
+		cfg,
 	}
 }
 
@@ -150,7 +224,7 @@ func (e Extractor) Extract(ing *extensions.Ingress) *Ingress {
 	data := make(map[string]interface{})
 	for name, annotationParser := range e.annotations {
 		val, err := annotationParser.Parse(ing)
-		glog.V(5).Infof("annotation %v in Ingress %v/%v: %v", name, ing.GetNamespace(), ing.GetName(), val)
+		ing_log.V(5).Infof("annotation %v in Ingress %v/%v: %v", name, ing.GetNamespace(), ing.GetName(), val)
 		if err != nil {
 			if errors.IsMissingAnnotations(err) {
 				continue
@@ -171,11 +245,11 @@ func (e Extractor) Extract(ing *extensions.Ingress) *Ingress {
 			_, alreadyDenied := data[DeniedKeyName]
 			if !alreadyDenied {
 				data[DeniedKeyName] = err
-				glog.Errorf("error reading %v annotation in Ingress %v/%v: %v", name, ing.GetNamespace(), ing.GetName(), err)
+				ing_log.Errorf("error reading %v annotation in Ingress %v/%v: %v", name, ing.GetNamespace(), ing.GetName(), err)
 				continue
 			}
 
-			glog.V(5).Infof("error reading %v annotation in Ingress %v/%v: %v", name, ing.GetNamespace(), ing.GetName(), err)
+			ing_log.V(5).Infof("error reading %v annotation in Ingress %v/%v: %v", name, ing.GetNamespace(), ing.GetName(), err)
 		}
 
 		if val != nil {
@@ -185,8 +259,76 @@ func (e Extractor) Extract(ing *extensions.Ingress) *Ingress {
 
 	err := mergo.MapWithOverwrite(pia, data)
 	if err != nil {
-		glog.Errorf("unexpected error merging extracted annotations: %v", err)
+		ing_log.Errorf("unexpected error merging extracted annotations: %v", err)
+	}
+
+	if !e.resolver.GetDefaultBackend().AllowSnippetAnnotations {
+		removeSnippets(pia)
 	}
 
 	return pia
 }
+
+// Validate parses the annotations of an Ingress and returns the errors
+// found, indexed by the name of the parser. The objects referenced by the
+// annotations, like Secrets or Services, are not resolved: the errors of
+// the parsers that need one of them are ignored.
+func (e Extractor) Validate(ing *extensions.Ingress) map[string]error {
+	r := &unresolvedReferences{backend: e.resolver.GetDefaultBackend()}
+	v := NewAnnotationExtractor(r)
+
+	errs := make(map[string]error)
+	for name, annotationParser := range v.annotations {
+		r.referenced = false
+		_, err := annotationParser.Parse(ing)
+		if err != nil && !errors.IsMissingAnnotations(err) && !r.referenced {
+			errs[name] = err
+		}
+	}
+
+	return errs
+}
+
+// unresolvedReferences is a resolver that only returns the default backend
+// and records if a parser tried to resolve a referenced object
+type unresolvedReferences struct {
+	backend    defaults.Backend
+	referenced bool
+}
+
+func (r *unresolvedReferences) GetDefaultBackend() defaults.Backend {
+	return r.backend
+}
+
+func (r *unresolvedReferences) GetSecret(name string) (*apiv1.Secret, error) {
+	r.referenced = true
+	return nil, fmt.Errorf("secret %v is not resolved", name)
+}
+
+func (r *unresolvedReferences) GetAuthCertificate(name string) (*resolver.AuthSSLCert, error) {
+	r.referenced = true
+	return nil, fmt.Errorf("secret %v is not resolved", name)
+}
+
+func (r *unresolvedReferences) GetService(name string) (*apiv1.Service, error) {
+	r.referenced = true
+	return nil, fmt.Errorf("service %v is not resolved", name)
+}
+
+func (r *unresolvedReferences) GetConfigMap(name string) (*apiv1.ConfigMap, error) {
+	r.referenced = true
+	return nil, fmt.Errorf("configmap %v is not resolved", name)
+}
+
+// removeSnippets discards the custom configuration
+// snippets defined in the annotations of an Ingress
+func removeSnippets(pia *Ingress) {
+	if pia.ConfigurationSnippet == "" && pia.ServerSnippet == "" && pia.ExternalAuth.AuthSnippet == "" {
+		return
+	}
+
+	ing_log.Warningf("snippet annotations are not allowed, ignoring the snippets defined in Ingress %v/%v", pia.Namespace, pia.Name)
+	pia.ConfigurationSnippet = ""
+	pia.ServerSnippet = ""
+	pia.ExternalAuth.AuthSnippet = ""
+}
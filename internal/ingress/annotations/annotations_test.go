@@ -271,6 +271,71 @@ func TestCors(t *testing.T) {
 	}
 }
 
+type mockSnippetCfg struct {
+	mockCfg
+}
+
+func (m mockSnippetCfg) GetDefaultBackend() defaults.Backend {
+	return defaults.Backend{AllowSnippetAnnotations: true}
+}
+
+func TestSnippets(t *testing.T) {
+	ing := buildIngress()
+	ing.SetAnnotations(map[string]string{
+		parser.GetAnnotationWithPrefix("configuration-snippet"): "more_set_headers \"Location: foo\";",
+		parser.GetAnnotationWithPrefix("server-snippet"):        "set $foo bar;",
+		parser.GetAnnotationWithPrefix("auth-url"):              "http://auth.example.com/verify",
+		parser.GetAnnotationWithPrefix("auth-snippet"):          "proxy_set_header X-Tenant $host;",
+	})
+
+	fooAnns := []struct {
+		cfg     resolver.Resolver
+		allowed bool
+	}{
+		{mockSnippetCfg{}, true},
+		{mockCfg{}, false},
+	}
+
+	for _, foo := range fooAnns {
+		r := NewAnnotationExtractor(foo.cfg).Extract(ing)
+
+		if r.ExternalAuth.URL == "" {
+			t.Errorf("expected the external authentication to be configured")
+		}
+
+		snippets := []string{r.ConfigurationSnippet, r.ServerSnippet, r.ExternalAuth.AuthSnippet}
+		for _, snippet := range snippets {
+			if (snippet != "") != foo.allowed {
+				t.Errorf("expected snippets allowed to be %v but snippet \"%v\" was returned", foo.allowed, snippet)
+			}
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	ec := NewAnnotationExtractor(mockCfg{})
+
+	ing := buildIngress()
+	ing.SetAnnotations(map[string]string{
+		annotationUpstreamHashBy:                          "$request_uri",
+		parser.GetAnnotationWithPrefix("allowed-methods"): "GET,P@ST",
+		// the referenced objects are not resolved
+		parser.GetAnnotationWithPrefix("auth-type"):       "basic",
+		parser.GetAnnotationWithPrefix("auth-secret"):     "missing",
+		parser.GetAnnotationWithPrefix("auth-tls-secret"): "default/missing",
+		parser.GetAnnotationWithPrefix("default-backend"): "missing",
+	})
+
+	errs := ec.Validate(ing)
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error but got %v", errs)
+	}
+
+	if _, ok := errs["AllowedMethods"]; !ok {
+		t.Errorf("expected an error in the AllowedMethods annotation but got %v", errs)
+	}
+}
+
 /*
 func TestMergeLocationAnnotations(t *testing.T) {
 	// initial parameters
@@ -0,0 +1,173 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authoidc
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+const (
+	defaultScope        = "openid email profile"
+	defaultRedirectPath = "/oauth2/callback"
+	defaultLogoutPath   = "/oauth2/logout"
+)
+
+var (
+	pathRegex  = regexp.MustCompile(`^/[a-zA-Z0-9_\-/.]*$`)
+	scopeRegex = regexp.MustCompile(`^[a-zA-Z0-9_\-:./ ]+$`)
+)
+
+// Config returns the OpenID Connect authentication configuration for an Ingress rule
+type Config struct {
+	// Discovery is the URL of the OpenID Connect discovery document
+	Discovery string `json:"discovery"`
+	// ClientID, ClientSecret and SessionSecret are never serialized nor
+	// written in nginx.conf. They are sent to NGINX through the dynamic
+	// configuration and read by Lua using the name of the secret.
+	ClientID     string `json:"-"`
+	ClientSecret string `json:"-"`
+	// SessionSecret is used to encrypt the session cookie. It must be the
+	// same in all the replicas of the ingress controller
+	SessionSecret string `json:"-"`
+	Scope         string `json:"scope"`
+	RedirectPath  string `json:"redirectPath"`
+	LogoutPath    string `json:"logoutPath"`
+	// Secret is the namespace/name of the secret that contains the credentials
+	Secret string `json:"secret"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if c1.Discovery != c2.Discovery {
+		return false
+	}
+	if c1.ClientID != c2.ClientID {
+		return false
+	}
+	if c1.ClientSecret != c2.ClientSecret {
+		return false
+	}
+	if c1.SessionSecret != c2.SessionSecret {
+		return false
+	}
+	if c1.Scope != c2.Scope {
+		return false
+	}
+	if c1.RedirectPath != c2.RedirectPath {
+		return false
+	}
+	if c1.LogoutPath != c2.LogoutPath {
+		return false
+	}
+	if c1.Secret != c2.Secret {
+		return false
+	}
+
+	return true
+}
+
+type authOIDC struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new OpenID Connect authentication annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return authOIDC{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+// used to authenticate users using the authorization code flow
+// of an OpenID Connect provider
+func (a authOIDC) Parse(ing *extensions.Ingress) (interface{}, error) {
+	discovery, err := parser.GetStringAnnotation("auth-oidc-discovery", ing)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(discovery)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") || strings.ContainsAny(discovery, "'\"\\") {
+		return nil, ing_errors.NewLocationDenied("invalid OpenID Connect discovery URL")
+	}
+
+	s, err := parser.GetStringAnnotation("auth-oidc-secret", ing)
+	if err != nil {
+		return nil, ing_errors.LocationDenied{
+			Reason: errors.Wrap(err, "error reading secret name from annotation"),
+		}
+	}
+
+	name := fmt.Sprintf("%v/%v", ing.Namespace, s)
+	secret, err := a.r.GetSecret(name)
+	if err != nil {
+		return nil, ing_errors.LocationDenied{
+			Reason: errors.Wrapf(err, "unexpected error reading secret %v", name),
+		}
+	}
+
+	values := map[string]string{}
+	for _, key := range []string{"client-id", "client-secret", "session-secret"} {
+		val, ok := secret.Data[key]
+		if !ok || len(val) == 0 {
+			return nil, ing_errors.LocationDenied{
+				Reason: errors.Errorf("the secret %v does not contain a key with value %v", name, key),
+			}
+		}
+		values[key] = string(val)
+	}
+
+	scope, err := parser.GetStringAnnotation("auth-oidc-scope", ing)
+	if err != nil || !scopeRegex.MatchString(scope) {
+		scope = defaultScope
+	}
+
+	redirectPath, err := parser.GetStringAnnotation("auth-oidc-redirect-path", ing)
+	if err != nil || !pathRegex.MatchString(redirectPath) {
+		redirectPath = defaultRedirectPath
+	}
+
+	logoutPath, err := parser.GetStringAnnotation("auth-oidc-logout-path", ing)
+	if err != nil || !pathRegex.MatchString(logoutPath) {
+		logoutPath = defaultLogoutPath
+	}
+
+	return &Config{
+		Discovery:     discovery,
+		ClientID:      values["client-id"],
+		ClientSecret:  values["client-secret"],
+		SessionSecret: values["session-secret"],
+		Scope:         scope,
+		RedirectPath:  redirectPath,
+		LogoutPath:    logoutPath,
+		Secret:        name,
+	}, nil
+}
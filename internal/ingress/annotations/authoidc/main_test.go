@@ -0,0 +1,171 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authoidc
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+type mockSecret struct {
+	resolver.Mock
+}
+
+func (m mockSecret) GetSecret(name string) (*api.Secret, error) {
+	switch name {
+	case "default/oidc":
+		return &api.Secret{
+			ObjectMeta: meta_v1.ObjectMeta{
+				Namespace: api.NamespaceDefault,
+				Name:      "oidc",
+			},
+			Data: map[string][]byte{
+				"client-id":      []byte("app"),
+				"client-secret":  []byte("s3cr3t"),
+				"session-secret": []byte("0123456789"),
+			},
+		}, nil
+	case "default/incomplete":
+		return &api.Secret{
+			ObjectMeta: meta_v1.ObjectMeta{
+				Namespace: api.NamespaceDefault,
+				Name:      "incomplete",
+			},
+			Data: map[string][]byte{
+				"client-id": []byte("app"),
+			},
+		}, nil
+	}
+
+	return nil, errors.Errorf("there is no secret with name %v", name)
+}
+
+func TestParse(t *testing.T) {
+	discovery := parser.GetAnnotationWithPrefix("auth-oidc-discovery")
+	secret := parser.GetAnnotationWithPrefix("auth-oidc-secret")
+	scope := parser.GetAnnotationWithPrefix("auth-oidc-scope")
+	redirect := parser.GetAnnotationWithPrefix("auth-oidc-redirect-path")
+	logout := parser.GetAnnotationWithPrefix("auth-oidc-logout-path")
+
+	ap := NewParser(mockSecret{})
+	if ap == nil {
+		t.Fatalf("expected a parser.IngressAnnotation but returned nil")
+	}
+
+	url := "https://accounts.example.com/.well-known/openid-configuration"
+
+	testCases := []struct {
+		title       string
+		annotations map[string]string
+		expected    *Config
+		expErr      bool
+	}{
+		{"without annotations", map[string]string{}, nil, true},
+		{"invalid discovery URL", map[string]string{discovery: "accounts", secret: "oidc"}, nil, true},
+		{"without secret", map[string]string{discovery: url}, nil, true},
+		{"missing secret", map[string]string{discovery: url, secret: "missing"}, nil, true},
+		{"incomplete secret", map[string]string{discovery: url, secret: "incomplete"}, nil, true},
+		{"defaults", map[string]string{discovery: url, secret: "oidc"}, &Config{
+			Discovery:     url,
+			ClientID:      "app",
+			ClientSecret:  "s3cr3t",
+			SessionSecret: "0123456789",
+			Scope:         defaultScope,
+			RedirectPath:  defaultRedirectPath,
+			LogoutPath:    defaultLogoutPath,
+			Secret:        "default/oidc",
+		}, false},
+		{"custom values", map[string]string{discovery: url, secret: "oidc", scope: "openid groups", redirect: "/callback", logout: "/bye"}, &Config{
+			Discovery:     url,
+			ClientID:      "app",
+			ClientSecret:  "s3cr3t",
+			SessionSecret: "0123456789",
+			Scope:         "openid groups",
+			RedirectPath:  "/callback",
+			LogoutPath:    "/bye",
+			Secret:        "default/oidc",
+		}, false},
+		{"invalid paths", map[string]string{discovery: url, secret: "oidc", redirect: "callback", logout: "/bye;"}, &Config{
+			Discovery:     url,
+			ClientID:      "app",
+			ClientSecret:  "s3cr3t",
+			SessionSecret: "0123456789",
+			Scope:         defaultScope,
+			RedirectPath:  defaultRedirectPath,
+			LogoutPath:    defaultLogoutPath,
+			Secret:        "default/oidc",
+		}, false},
+	}
+
+	ing := &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: extensions.IngressSpec{},
+	}
+
+	for _, testCase := range testCases {
+		ing.SetAnnotations(testCase.annotations)
+		i, err := ap.Parse(ing)
+		if testCase.expErr {
+			if err == nil {
+				t.Errorf("%v: expected error but returned nil", testCase.title)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", testCase.title, err)
+			continue
+		}
+
+		c, _ := i.(*Config)
+		if !c.Equal(testCase.expected) {
+			t.Errorf("%v: expected %v but returned %v", testCase.title, testCase.expected, c)
+		}
+	}
+}
+
+func TestConfigJSONWithoutCredentials(t *testing.T) {
+	b, err := json.Marshal(&Config{
+		Discovery:     "https://accounts.example.com",
+		ClientID:      "app",
+		ClientSecret:  "s3cr3t",
+		SessionSecret: "0123456789",
+		Secret:        "default/oidc",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, credential := range []string{"app", "s3cr3t", "0123456789"} {
+		if strings.Contains(string(b), credential) {
+			t.Errorf("expected the credentials to not be serialized but returned %s", b)
+		}
+	}
+}
@@ -37,6 +37,13 @@ type Config struct {
 	Method          string   `json:"method"`
 	ResponseHeaders []string `json:"responseHeaders,omitempty"`
 	RequestRedirect string   `json:"requestRedirect"`
+	// AuthCacheKey is the key used to cache the responses of the authentication service.
+	// An empty value disables the cache.
+	AuthCacheKey      string   `json:"authCacheKey"`
+	AuthCacheDuration []string `json:"authCacheDuration"`
+	// AuthSnippet contains custom configuration for the location that
+	// sends the subrequest to the authentication service
+	AuthSnippet string `json:"authSnippet"`
 }
 
 // Equal tests for equality between two Config types
@@ -74,13 +81,32 @@ func (e1 *Config) Equal(e2 *Config) bool {
 	if e1.RequestRedirect != e2.RequestRedirect {
 		return false
 	}
+	if e1.AuthCacheKey != e2.AuthCacheKey {
+		return false
+	}
+	if len(e1.AuthCacheDuration) != len(e2.AuthCacheDuration) {
+		return false
+	}
+	for i := range e1.AuthCacheDuration {
+		if e1.AuthCacheDuration[i] != e2.AuthCacheDuration[i] {
+			return false
+		}
+	}
+	if e1.AuthSnippet != e2.AuthSnippet {
+		return false
+	}
 
 	return true
 }
 
+// defaultAuthCacheDuration caches successful and unauthorized responses
+const defaultAuthCacheDuration = "200 202 401 5m"
+
 var (
 	methods      = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "CONNECT", "OPTIONS", "TRACE"}
 	headerRegexp = regexp.MustCompile(`^[a-zA-Z\d\-_]+$`)
+	// http://nginx.org/en/docs/http/ngx_http_proxy_module.html#proxy_cache_valid
+	durationRegexp = regexp.MustCompile(`^((any|[1-5][0-9]{2})\s+)*[0-9]+[smhd]?$`)
 )
 
 func validMethod(method string) bool {
@@ -100,6 +126,28 @@ func validHeader(header string) bool {
 	return headerRegexp.Match([]byte(header))
 }
 
+// validCacheKey checks the key can be safely used inside a quoted NGINX string
+func validCacheKey(key string) bool {
+	return !strings.ContainsAny(key, "'\\\n")
+}
+
+// parseCacheDuration parses a comma separated list of
+// response codes and durations, i.e. 200 202 10m, 401 30s
+func parseCacheDuration(value string) ([]string, bool) {
+	durations := []string{}
+	for _, d := range strings.Split(value, ",") {
+		d = strings.Join(strings.Fields(d), " ")
+		if d == "" {
+			continue
+		}
+		if !durationRegexp.MatchString(d) {
+			return nil, false
+		}
+		durations = append(durations, d)
+	}
+	return durations, true
+}
+
 type authReq struct {
 	r resolver.Resolver
 }
@@ -160,12 +208,37 @@ func (a authReq) Parse(ing *extensions.Ingress) (interface{}, error) {
 
 	requestRedirect, _ := parser.GetStringAnnotation("auth-request-redirect", ing)
 
+	cacheKey, _ := parser.GetStringAnnotation("auth-cache-key", ing)
+	cacheKey = strings.TrimSpace(cacheKey)
+	if !validCacheKey(cacheKey) {
+		return nil, ing_errors.NewLocationDenied("invalid auth cache key")
+	}
+
+	cacheDuration := []string{}
+	if cacheKey != "" {
+		dstr, err := parser.GetStringAnnotation("auth-cache-duration", ing)
+		if err != nil || strings.TrimSpace(dstr) == "" {
+			dstr = defaultAuthCacheDuration
+		}
+
+		var ok bool
+		cacheDuration, ok = parseCacheDuration(dstr)
+		if !ok {
+			return nil, ing_errors.NewLocationDenied("invalid auth cache duration")
+		}
+	}
+
+	authSnippet, _ := parser.GetStringAnnotation("auth-snippet", ing)
+
 	return &Config{
-		URL:             urlString,
-		Host:            authURL.Hostname(),
-		SigninURL:       signIn,
-		Method:          authMethod,
-		ResponseHeaders: responseHeaders,
-		RequestRedirect: requestRedirect,
+		URL:               urlString,
+		Host:              authURL.Hostname(),
+		SigninURL:         signIn,
+		Method:            authMethod,
+		ResponseHeaders:   responseHeaders,
+		RequestRedirect:   requestRedirect,
+		AuthCacheKey:      cacheKey,
+		AuthCacheDuration: cacheDuration,
+		AuthSnippet:       authSnippet,
 	}, nil
 }
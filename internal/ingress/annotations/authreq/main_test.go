@@ -168,3 +168,76 @@ func TestHeaderAnnotations(t *testing.T) {
 		}
 	}
 }
+
+func TestCacheAnnotations(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	ing.SetAnnotations(data)
+
+	tests := []struct {
+		title             string
+		authCacheKey      string
+		authCacheDuration string
+		expectedKey       string
+		expectedDuration  []string
+		expErr            bool
+	}{
+		{"no cache", "", "200 5m", "", []string{}, false},
+		{"default duration", "$http_authorization", "", "$http_authorization", []string{"200 202 401 5m"}, false},
+		{"custom durations", "$remote_user$http_authorization", "200  202 10m, 401 30s", "$remote_user$http_authorization", []string{"200 202 10m", "401 30s"}, false},
+		{"invalid duration", "$http_authorization", "10m 200", "", nil, true},
+		{"invalid key", "$http_authorization'", "", "", nil, true},
+	}
+
+	for _, test := range tests {
+		data[parser.GetAnnotationWithPrefix("auth-url")] = "http://foo.com/external-auth"
+		data[parser.GetAnnotationWithPrefix("auth-cache-key")] = test.authCacheKey
+		data[parser.GetAnnotationWithPrefix("auth-cache-duration")] = test.authCacheDuration
+
+		i, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if test.expErr {
+			if err == nil {
+				t.Errorf("%v: expected error but retuned nil", test.title)
+			}
+			continue
+		}
+
+		u, ok := i.(*Config)
+		if !ok {
+			t.Errorf("%v: expected an External type", test.title)
+			continue
+		}
+
+		if u.AuthCacheKey != test.expectedKey {
+			t.Errorf("%v: expected \"%v\" but \"%v\" was returned", test.title, test.expectedKey, u.AuthCacheKey)
+		}
+		if !reflect.DeepEqual(u.AuthCacheDuration, test.expectedDuration) {
+			t.Errorf("%v: expected \"%v\" but \"%v\" was returned", test.title, test.expectedDuration, u.AuthCacheDuration)
+		}
+	}
+}
+
+func TestAuthSnippetAnnotation(t *testing.T) {
+	ing := buildIngress()
+
+	snippet := "proxy_set_header X-Tenant $host;"
+	ing.SetAnnotations(map[string]string{
+		parser.GetAnnotationWithPrefix("auth-url"):     "http://foo.com/external-auth",
+		parser.GetAnnotationWithPrefix("auth-snippet"): snippet,
+	})
+
+	i, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	u, ok := i.(*Config)
+	if !ok {
+		t.Fatalf("expected an External type")
+	}
+
+	if u.AuthSnippet != snippet {
+		t.Errorf("expected \"%v\" but \"%v\" was returned", snippet, u.AuthSnippet)
+	}
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package blocking
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+var headerRegex = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+
+// Rule denies the requests where the value of a NGINX variable
+// matches a regular expression (case insensitive)
+type Rule struct {
+	Variable string `json:"variable"`
+	Regex    string `json:"regex"`
+}
+
+// Config contains the rules used to deny requests in a server
+type Config struct {
+	Rules []Rule `json:"rules,omitempty"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if len(c1.Rules) != len(c2.Rules) {
+		return false
+	}
+	for i := range c1.Rules {
+		if c1.Rules[i] != c2.Rules[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Merge adds the rules of c2 not present in c1
+func (c1 *Config) Merge(c2 Config) {
+	for _, r2 := range c2.Rules {
+		found := false
+		for _, r1 := range c1.Rules {
+			if r1 == r2 {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			c1.Rules = append(c1.Rules, r2)
+		}
+	}
+}
+
+type blocking struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new request blocking annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return blocking{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+// used to deny requests with specific user agents, referers or
+// header values. Each annotation contains a list of regular
+// expressions, one per line. The values of block-header-values
+// use the format <header name>: <regular expression>
+func (a blocking) Parse(ing *extensions.Ingress) (interface{}, error) {
+	config := &Config{}
+
+	for _, a := range []struct {
+		annotation string
+		variable   string
+	}{
+		{"block-user-agents", "$http_user_agent"},
+		{"block-referers", "$http_referer"},
+	} {
+		annotation, variable := a.annotation, a.variable
+		val, err := parser.GetStringAnnotation(annotation, ing)
+		if err != nil {
+			continue
+		}
+
+		for _, regex := range lines(val) {
+			if !isValidRegex(regex) {
+				return nil, ing_errors.NewInvalidAnnotationContent(annotation, val)
+			}
+
+			config.Rules = append(config.Rules, Rule{Variable: variable, Regex: regex})
+		}
+	}
+
+	val, err := parser.GetStringAnnotation("block-header-values", ing)
+	if err == nil {
+		for _, line := range lines(val) {
+			parts := strings.SplitN(line, ":", 2)
+			if len(parts) != 2 {
+				return nil, ing_errors.NewInvalidAnnotationContent("block-header-values", val)
+			}
+
+			header := strings.TrimSpace(parts[0])
+			regex := strings.TrimSpace(parts[1])
+			if !headerRegex.MatchString(header) || !isValidRegex(regex) {
+				return nil, ing_errors.NewInvalidAnnotationContent("block-header-values", val)
+			}
+
+			config.Rules = append(config.Rules, Rule{Variable: headerVariable(header), Regex: regex})
+		}
+	}
+
+	if len(config.Rules) == 0 {
+		return nil, ing_errors.ErrMissingAnnotations
+	}
+
+	return config, nil
+}
+
+// lines returns the non empty lines of a string
+func lines(val string) []string {
+	var l []string
+	for _, line := range strings.Split(val, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			l = append(l, line)
+		}
+	}
+
+	return l
+}
+
+// isValidRegex checks the regular expression can be used
+// between double quotes in the NGINX configuration
+func isValidRegex(regex string) bool {
+	if strings.ContainsAny(regex, "\"\r\n") || strings.HasSuffix(regex, `\`) {
+		return false
+	}
+
+	_, err := regexp.Compile(regex)
+	return err == nil
+}
+
+// headerVariable returns the NGINX variable that contains the value of a header
+func headerVariable(header string) string {
+	return fmt.Sprintf("$http_%v", strings.Replace(strings.ToLower(header), "-", "_", -1))
+}
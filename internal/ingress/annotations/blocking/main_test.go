@@ -0,0 +1,97 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package blocking
+
+import (
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func TestParse(t *testing.T) {
+	userAgents := parser.GetAnnotationWithPrefix("block-user-agents")
+	referers := parser.GetAnnotationWithPrefix("block-referers")
+	headers := parser.GetAnnotationWithPrefix("block-header-values")
+
+	ap := NewParser(&resolver.Mock{})
+	if ap == nil {
+		t.Fatalf("expected a parser.IngressAnnotation but returned nil")
+	}
+
+	testCases := []struct {
+		annotations map[string]string
+		expected    *Config
+	}{
+		{map[string]string{}, nil},
+		{map[string]string{userAgents: "\n  \n"}, nil},
+		{map[string]string{userAgents: `bad"bot`}, nil},
+		{map[string]string{userAgents: "(bot"}, nil},
+		{map[string]string{headers: "X-Token"}, nil},
+		{map[string]string{headers: "X_Token: abc"}, nil},
+		{map[string]string{userAgents: "badbot\n  ^curl/.*$ \n", referers: "spam\\.example\\.com"}, &Config{
+			Rules: []Rule{
+				{Variable: "$http_user_agent", Regex: "badbot"},
+				{Variable: "$http_user_agent", Regex: "^curl/.*$"},
+				{Variable: "$http_referer", Regex: "spam\\.example\\.com"},
+			},
+		}},
+		{map[string]string{headers: "X-Forwarded-Host: ^evil\nAccept-Language: ^xx"}, &Config{
+			Rules: []Rule{
+				{Variable: "$http_x_forwarded_host", Regex: "^evil"},
+				{Variable: "$http_accept_language", Regex: "^xx"},
+			},
+		}},
+	}
+
+	ing := &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: extensions.IngressSpec{},
+	}
+
+	for _, testCase := range testCases {
+		ing.SetAnnotations(testCase.annotations)
+		i, _ := ap.Parse(ing)
+		p, _ := i.(*Config)
+
+		if !p.Equal(testCase.expected) {
+			t.Errorf("expected %v but returned %v, annotations: %s", testCase.expected, p, testCase.annotations)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	c := &Config{Rules: []Rule{{Variable: "$http_user_agent", Regex: "badbot"}}}
+	c.Merge(Config{Rules: []Rule{
+		{Variable: "$http_user_agent", Regex: "badbot"},
+		{Variable: "$http_referer", Regex: "spam"},
+	}})
+
+	expected := &Config{Rules: []Rule{
+		{Variable: "$http_user_agent", Regex: "badbot"},
+		{Variable: "$http_referer", Regex: "spam"},
+	}}
+	if !c.Equal(expected) {
+		t.Errorf("expected %v but returned %v", expected, c)
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package brotli
+
+import (
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+	"k8s.io/ingress-nginx/internal/log"
+)
+
+// Config contains the brotli compression configuration of a location
+type Config struct {
+	Enabled bool   `json:"enabled"`
+	Level   int    `json:"level"`
+	Types   string `json:"types"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if c1.Enabled != c2.Enabled {
+		return false
+	}
+	if c1.Level != c2.Level {
+		return false
+	}
+	if c1.Types != c2.Types {
+		return false
+	}
+
+	return true
+}
+
+type brotli struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new brotli compression annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return brotli{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+// used to configure brotli compression in the location/s
+func (a brotli) Parse(ing *extensions.Ingress) (interface{}, error) {
+	defBackend := a.r.GetDefaultBackend()
+
+	enabled, err := parser.GetBoolAnnotation("enable-brotli", ing)
+	if err != nil {
+		enabled = defBackend.EnableBrotli
+	}
+
+	level, err := parser.GetIntAnnotation("brotli-level", ing)
+	if err != nil {
+		level = defBackend.BrotliLevel
+	}
+
+	if level < 0 || level > 11 {
+		log.Warningf("%v is not a valid brotli compression level (0-11). Using the default", level)
+		level = defBackend.BrotliLevel
+	}
+
+	types, err := parser.GetStringAnnotation("brotli-types", ing)
+	if err != nil || types == "" {
+		types = defBackend.BrotliTypes
+	}
+
+	return &Config{
+		Enabled: enabled,
+		Level:   level,
+		Types:   types,
+	}, nil
+}
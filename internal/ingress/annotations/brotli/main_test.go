@@ -0,0 +1,81 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package brotli
+
+import (
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/defaults"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+type mockBackend struct {
+	resolver.Mock
+}
+
+func (m mockBackend) GetDefaultBackend() defaults.Backend {
+	return defaults.Backend{
+		EnableBrotli: false,
+		BrotliLevel:  4,
+		BrotliTypes:  "text/html",
+	}
+}
+
+func TestParse(t *testing.T) {
+	enable := parser.GetAnnotationWithPrefix("enable-brotli")
+	level := parser.GetAnnotationWithPrefix("brotli-level")
+	types := parser.GetAnnotationWithPrefix("brotli-types")
+
+	ap := NewParser(mockBackend{})
+	if ap == nil {
+		t.Fatalf("expected a parser.IngressAnnotation but returned nil")
+	}
+
+	testCases := []struct {
+		annotations map[string]string
+		expected    *Config
+	}{
+		{nil, &Config{Enabled: false, Level: 4, Types: "text/html"}},
+		{map[string]string{enable: "true"}, &Config{Enabled: true, Level: 4, Types: "text/html"}},
+		{map[string]string{enable: "true", level: "11", types: "application/json"}, &Config{Enabled: true, Level: 11, Types: "application/json"}},
+		{map[string]string{enable: "true", level: "12"}, &Config{Enabled: true, Level: 4, Types: "text/html"}},
+		{map[string]string{enable: "false"}, &Config{Enabled: false, Level: 4, Types: "text/html"}},
+	}
+
+	ing := &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: extensions.IngressSpec{},
+	}
+
+	for _, testCase := range testCases {
+		ing.SetAnnotations(testCase.annotations)
+		i, _ := ap.Parse(ing)
+		p, _ := i.(*Config)
+
+		if !p.Equal(testCase.expected) {
+			t.Errorf("expected %v but returned %v, annotations: %s", testCase.expected, p, testCase.annotations)
+		}
+	}
+}
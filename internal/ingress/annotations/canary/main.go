@@ -17,6 +17,8 @@ limitations under the License.
 package canary
 
 import (
+	"math"
+
 	extensions "k8s.io/api/extensions/v1beta1"
 
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
@@ -24,6 +26,10 @@ import (
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
+// defaultWeightTotal is the total of the weights of the canaries by default,
+// so the weights are a percentage of the requests
+const defaultWeightTotal = 100
+
 type canary struct {
 	r resolver.Resolver
 }
@@ -31,9 +37,23 @@ type canary struct {
 // Config returns the configuration rules for setting up the Canary
 type Config struct {
 	Enabled bool
-	Weight  int
-	Header  string
-	Cookie  string
+	// Weight of the requests routed to the canary, relative to WeightTotal.
+	// It is rounded to two decimals.
+	Weight      float64
+	WeightTotal int
+	Header      string
+	Cookie      string
+	// Shadow indicates the canary receives a copy of the requests routed
+	// to it, discarding its responses, instead of serving them
+	Shadow bool
+}
+
+// Percentage returns the percentage of the requests routed to the canary by weight
+func (c Config) Percentage() float64 {
+	if c.WeightTotal <= 0 {
+		return c.Weight
+	}
+	return c.Weight * 100 / float64(c.WeightTotal)
 }
 
 // NewParser parses the ingress for canary related annotations
@@ -52,10 +72,24 @@ func (c canary) Parse(ing *extensions.Ingress) (interface{}, error) {
 		config.Enabled = false
 	}
 
-	config.Weight, err = parser.GetIntAnnotation("canary-weight", ing)
+	config.Weight, err = parser.GetFloatAnnotation("canary-weight", ing)
 	if err != nil {
 		config.Weight = 0
 	}
+	config.Weight = math.Round(config.Weight*100) / 100
+
+	config.WeightTotal, err = parser.GetIntAnnotation("canary-weight-total", ing)
+	if err != nil {
+		config.WeightTotal = defaultWeightTotal
+	}
+
+	if config.WeightTotal <= 0 {
+		return nil, errors.NewInvalidAnnotationContent("canary-weight-total", config.WeightTotal)
+	}
+
+	if config.Weight < 0 || config.Weight > float64(config.WeightTotal) {
+		return nil, errors.NewInvalidAnnotationConfiguration("canary-weight", "must be between 0 and canary-weight-total")
+	}
 
 	config.Header, err = parser.GetStringAnnotation("canary-by-header", ing)
 	if err != nil {
@@ -67,7 +101,12 @@ func (c canary) Parse(ing *extensions.Ingress) (interface{}, error) {
 		config.Cookie = ""
 	}
 
-	if !config.Enabled && (config.Weight > 0 || len(config.Header) > 0 || len(config.Cookie) > 0) {
+	config.Shadow, err = parser.GetBoolAnnotation("canary-shadow", ing)
+	if err != nil {
+		config.Shadow = false
+	}
+
+	if !config.Enabled && (config.Weight > 0 || len(config.Header) > 0 || len(config.Cookie) > 0 || config.Shadow) {
 		return nil, errors.NewInvalidAnnotationConfiguration("canary", "configured but not enabled")
 	}
 
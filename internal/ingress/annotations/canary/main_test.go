@@ -72,7 +72,7 @@ func TestAnnotations(t *testing.T) {
 	tests := []struct {
 		title         string
 		canaryEnabled bool
-		canaryWeight  int
+		canaryWeight  float64
 		canaryHeader  string
 		canaryCookie  string
 		expErr        bool
@@ -89,7 +89,7 @@ func TestAnnotations(t *testing.T) {
 
 	for _, test := range tests {
 		data[parser.GetAnnotationWithPrefix("canary")] = strconv.FormatBool(test.canaryEnabled)
-		data[parser.GetAnnotationWithPrefix("canary-weight")] = strconv.Itoa(test.canaryWeight)
+		data[parser.GetAnnotationWithPrefix("canary-weight")] = strconv.FormatFloat(test.canaryWeight, 'f', -1, 64)
 		data[parser.GetAnnotationWithPrefix("canary-by-header")] = test.canaryHeader
 		data[parser.GetAnnotationWithPrefix("canary-by-cookie")] = test.canaryCookie
 
@@ -124,3 +124,102 @@ func TestAnnotations(t *testing.T) {
 		}
 	}
 }
+
+func TestWeightTotal(t *testing.T) {
+	ing := buildIngress()
+
+	tests := []struct {
+		title       string
+		weight      string
+		weightTotal string
+		expWeight   float64
+		expTotal    int
+		expErr      bool
+	}{
+		{"default total", "20", "", 20, 100, false},
+		{"fractional weight", "0.1", "", 0.1, 100, false},
+		{"weight rounded to two decimals", "0.015", "", 0.02, 100, false},
+		{"custom total", "1", "10000", 1, 10000, false},
+		{"weight above the total", "101", "", 0, 0, true},
+		{"negative weight", "-1", "", 0, 0, true},
+		{"invalid total", "1", "0", 0, 0, true},
+	}
+
+	for _, test := range tests {
+		data := map[string]string{
+			parser.GetAnnotationWithPrefix("canary"):        "true",
+			parser.GetAnnotationWithPrefix("canary-weight"): test.weight,
+		}
+		if test.weightTotal != "" {
+			data[parser.GetAnnotationWithPrefix("canary-weight-total")] = test.weightTotal
+		}
+		ing.SetAnnotations(data)
+
+		i, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if test.expErr {
+			if err == nil {
+				t.Errorf("%v: expected error but returned nil", test.title)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: expected nil but returned error %v", test.title, err)
+			continue
+		}
+
+		canaryConfig := i.(*Config)
+		if canaryConfig.Weight != test.expWeight {
+			t.Errorf("%v: expected weight %v but %v was returned", test.title, test.expWeight, canaryConfig.Weight)
+		}
+		if canaryConfig.WeightTotal != test.expTotal {
+			t.Errorf("%v: expected total %v but %v was returned", test.title, test.expTotal, canaryConfig.WeightTotal)
+		}
+	}
+
+	if p := (Config{Weight: 1, WeightTotal: 10000}).Percentage(); p != 0.01 {
+		t.Errorf("expected a percentage of 0.01 but %v was returned", p)
+	}
+}
+
+func TestShadow(t *testing.T) {
+	ing := buildIngress()
+
+	tests := []struct {
+		title     string
+		enabled   string
+		shadow    string
+		expShadow bool
+		expErr    bool
+	}{
+		{"no shadow", "true", "", false, false},
+		{"shadow canary", "true", "true", true, false},
+		{"shadow without canary", "false", "true", false, true},
+	}
+
+	for _, test := range tests {
+		data := map[string]string{
+			parser.GetAnnotationWithPrefix("canary"):        test.enabled,
+			parser.GetAnnotationWithPrefix("canary-weight"): "0",
+		}
+		if test.shadow != "" {
+			data[parser.GetAnnotationWithPrefix("canary-shadow")] = test.shadow
+		}
+		ing.SetAnnotations(data)
+
+		i, err := NewParser(&resolver.Mock{}).Parse(ing)
+		if test.expErr {
+			if err == nil {
+				t.Errorf("%v: expected error but returned nil", test.title)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: expected nil but returned error %v", test.title, err)
+			continue
+		}
+
+		if shadow := i.(*Config).Shadow; shadow != test.expShadow {
+			t.Errorf("%v: expected shadow %v but %v was returned", test.title, test.expShadow, shadow)
+		}
+	}
+}
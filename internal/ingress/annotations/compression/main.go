@@ -0,0 +1,91 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package compression
+
+import (
+	"strings"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+	"k8s.io/ingress-nginx/internal/log"
+)
+
+// Config contains the exclusions of the gzip and brotli compression of a location
+type Config struct {
+	// ExcludeTypes contains the MIME types never compressed in the location
+	ExcludeTypes []string `json:"excludeTypes,omitempty"`
+	// MinLength is the minimum length of the compressed responses.
+	// 0 uses the global value
+	MinLength int `json:"minLength"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if len(c1.ExcludeTypes) != len(c2.ExcludeTypes) {
+		return false
+	}
+	for i, t := range c1.ExcludeTypes {
+		if t != c2.ExcludeTypes[i] {
+			return false
+		}
+	}
+	if c1.MinLength != c2.MinLength {
+		return false
+	}
+
+	return true
+}
+
+type compression struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new compression exclusions annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return compression{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+// used to exclude responses from the compression in the location/s
+func (a compression) Parse(ing *extensions.Ingress) (interface{}, error) {
+	config := &Config{}
+
+	types, err := parser.GetStringAnnotation("compression-exclude-types", ing)
+	if err == nil {
+		config.ExcludeTypes = strings.FieldsFunc(strings.ToLower(types), func(r rune) bool {
+			return r == ',' || r == ' '
+		})
+	}
+
+	minLength, err := parser.GetIntAnnotation("compression-min-length", ing)
+	if err == nil {
+		if minLength < 0 {
+			log.Warningf("%v is not a valid compression minimum length. Using the default", minLength)
+		} else {
+			config.MinLength = minLength
+		}
+	}
+
+	return config, nil
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package connectproxy
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"net"
+	"strconv"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+// SocketDirectory contains the unix sockets used by NGINX
+// to reach the upstreams through a forward proxy
+var SocketDirectory = "/tmp/nginx-connect"
+
+// Config contains the address of the forward proxy used
+// to reach the upstream servers of a location
+type Config struct {
+	Address string `json:"address,omitempty"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+
+	return c1.Address == c2.Address
+}
+
+// SocketPath returns the unix socket that tunnels the connections
+// to the endpoints of a backend through a forward proxy
+func SocketPath(backend, proxy string) string {
+	hash := sha1.Sum([]byte(backend + "|" + proxy))
+	return fmt.Sprintf("%v/%x.sock", SocketDirectory, hash[:8])
+}
+
+type connectProxy struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new forward proxy annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return connectProxy{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+// used to indicate the upstream servers must be reached through
+// a forward proxy that supports the HTTP CONNECT method.
+// The value uses the format <host>:<port>
+func (a connectProxy) Parse(ing *extensions.Ingress) (interface{}, error) {
+	val, err := parser.GetStringAnnotation("upstream-connect-proxy", ing)
+	if err != nil {
+		return nil, err
+	}
+
+	host, port, err := net.SplitHostPort(val)
+	if err != nil || host == "" {
+		return nil, ing_errors.NewInvalidAnnotationContent("upstream-connect-proxy", val)
+	}
+
+	p, err := strconv.Atoi(port)
+	if err != nil || p < 1 || p > 65535 {
+		return nil, ing_errors.NewInvalidAnnotationContent("upstream-connect-proxy", val)
+	}
+
+	return &Config{Address: val}, nil
+}
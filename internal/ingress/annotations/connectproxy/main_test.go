@@ -0,0 +1,80 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package connectproxy
+
+import (
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func TestParse(t *testing.T) {
+	annotation := parser.GetAnnotationWithPrefix("upstream-connect-proxy")
+
+	ap := NewParser(&resolver.Mock{})
+	if ap == nil {
+		t.Fatalf("expected a parser.IngressAnnotation but returned nil")
+	}
+
+	testCases := []struct {
+		annotations map[string]string
+		expected    *Config
+	}{
+		{map[string]string{}, nil},
+		{map[string]string{annotation: "proxy.corp"}, nil},
+		{map[string]string{annotation: ":3128"}, nil},
+		{map[string]string{annotation: "proxy.corp:http"}, nil},
+		{map[string]string{annotation: "proxy.corp:70000"}, nil},
+		{map[string]string{annotation: "proxy.corp:3128"}, &Config{Address: "proxy.corp:3128"}},
+		{map[string]string{annotation: "[2001:db8::1]:3128"}, &Config{Address: "[2001:db8::1]:3128"}},
+	}
+
+	ing := &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: extensions.IngressSpec{},
+	}
+
+	for _, testCase := range testCases {
+		ing.SetAnnotations(testCase.annotations)
+		i, _ := ap.Parse(ing)
+		p, _ := i.(*Config)
+
+		if !p.Equal(testCase.expected) {
+			t.Errorf("expected %v but returned %v, annotations: %s", testCase.expected, p, testCase.annotations)
+		}
+	}
+}
+
+func TestSocketPath(t *testing.T) {
+	s1 := SocketPath("default-app-80", "proxy.corp:3128")
+	s2 := SocketPath("default-app-80", "proxy.corp:8080")
+
+	if s1 == s2 {
+		t.Errorf("expected different sockets for different proxies but returned %v", s1)
+	}
+
+	if s1 != SocketPath("default-app-80", "proxy.corp:3128") {
+		t.Errorf("expected the same socket for the same backend and proxy")
+	}
+}
@@ -18,10 +18,12 @@ package cors
 
 import (
 	"regexp"
+	"strings"
 
 	extensions "k8s.io/api/extensions/v1beta1"
 
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
@@ -43,6 +45,9 @@ var (
 	// Headers must contain valid values only (X-HEADER12, X-ABC)
 	// May contain or not spaces between each Header
 	corsHeadersRegex = regexp.MustCompile(`^([A-Za-z0-9\-\_]+,?\s?)+$`)
+	// Origin regex must not contain quotes, spaces or semicolons, used to
+	// write it in the NGINX configuration
+	corsOriginRegexRegex = regexp.MustCompile(`^[^"'\s;]+$`)
 )
 
 type cors struct {
@@ -51,14 +56,26 @@ type cors struct {
 
 // Config contains the Cors configuration to be used in the Ingress
 type Config struct {
-	CorsEnabled          bool   `json:"corsEnabled"`
-	CorsAllowOrigin      string `json:"corsAllowOrigin"`
+	CorsEnabled     bool   `json:"corsEnabled"`
+	CorsAllowOrigin string `json:"corsAllowOrigin"`
+	// CorsAllowOrigins are the origins allowed when cors-allow-origin
+	// contains more than one origin
+	CorsAllowOrigins []string `json:"corsAllowOrigins,omitempty"`
+	// CorsAllowOriginRegex is a regular expression matching other allowed origins
+	CorsAllowOriginRegex string `json:"corsAllowOriginRegex,omitempty"`
 	CorsAllowMethods     string `json:"corsAllowMethods"`
 	CorsAllowHeaders     string `json:"corsAllowHeaders"`
+	CorsExposeHeaders    string `json:"corsExposeHeaders,omitempty"`
 	CorsAllowCredentials bool   `json:"corsAllowCredentials"`
 	CorsMaxAge           int    `json:"corsMaxAge"`
 }
 
+// MatchesOrigin returns true if the allowed origin depends on the header
+// Origin of the request, because several origins or a regex are allowed
+func (c Config) MatchesOrigin() bool {
+	return len(c.CorsAllowOrigins) > 0 || c.CorsAllowOriginRegex != ""
+}
+
 // NewParser creates a new CORS annotation parser
 func NewParser(r resolver.Resolver) parser.IngressAnnotation {
 	return cors{r}
@@ -87,6 +104,20 @@ func (c1 *Config) Equal(c2 *Config) bool {
 	if c1.CorsAllowOrigin != c2.CorsAllowOrigin {
 		return false
 	}
+	if len(c1.CorsAllowOrigins) != len(c2.CorsAllowOrigins) {
+		return false
+	}
+	for i, origin := range c1.CorsAllowOrigins {
+		if origin != c2.CorsAllowOrigins[i] {
+			return false
+		}
+	}
+	if c1.CorsAllowOriginRegex != c2.CorsAllowOriginRegex {
+		return false
+	}
+	if c1.CorsExposeHeaders != c2.CorsExposeHeaders {
+		return false
+	}
 	if c1.CorsEnabled != c2.CorsEnabled {
 		return false
 	}
@@ -103,8 +134,46 @@ func (c cors) Parse(ing *extensions.Ingress) (interface{}, error) {
 	}
 
 	corsalloworigin, err := parser.GetStringAnnotation("cors-allow-origin", ing)
-	if err != nil || corsalloworigin == "" || !corsOriginRegex.MatchString(corsalloworigin) {
+	if err != nil {
+		corsalloworigin = ""
+	}
+
+	var corsalloworigins []string
+	for _, origin := range strings.Split(corsalloworigin, ",") {
+		origin = strings.TrimSpace(origin)
+		if origin == "" || !corsOriginRegex.MatchString(origin) || origin == "*" {
+			corsalloworigins = nil
+			break
+		}
+		corsalloworigins = append(corsalloworigins, origin)
+	}
+
+	switch len(corsalloworigins) {
+	case 0:
 		corsalloworigin = "*"
+	case 1:
+		corsalloworigin = corsalloworigins[0]
+		corsalloworigins = nil
+	default:
+		corsalloworigin = strings.Join(corsalloworigins, ",")
+	}
+
+	corsalloworiginregex, err := parser.GetStringAnnotation("cors-allow-origin-regex", ing)
+	if err != nil {
+		corsalloworiginregex = ""
+	}
+
+	if corsalloworiginregex != "" {
+		if !corsOriginRegexRegex.MatchString(corsalloworiginregex) {
+			return nil, ing_errors.NewInvalidAnnotationContent("cors-allow-origin-regex", corsalloworiginregex)
+		}
+		if _, err := regexp.Compile(corsalloworiginregex); err != nil {
+			return nil, ing_errors.NewInvalidAnnotationContent("cors-allow-origin-regex", corsalloworiginregex)
+		}
+		if corsalloworigin == "*" {
+			// only the origins matching the regex are allowed
+			corsalloworigin = ""
+		}
 	}
 
 	corsallowheaders, err := parser.GetStringAnnotation("cors-allow-headers", ing)
@@ -112,6 +181,11 @@ func (c cors) Parse(ing *extensions.Ingress) (interface{}, error) {
 		corsallowheaders = defaultCorsHeaders
 	}
 
+	corsexposeheaders, err := parser.GetStringAnnotation("cors-expose-headers", ing)
+	if err != nil || !corsHeadersRegex.MatchString(corsexposeheaders) {
+		corsexposeheaders = ""
+	}
+
 	corsallowmethods, err := parser.GetStringAnnotation("cors-allow-methods", ing)
 	if err != nil || corsallowmethods == "" || !corsMethodsRegex.MatchString(corsallowmethods) {
 		corsallowmethods = defaultCorsMethods
@@ -130,7 +204,10 @@ func (c cors) Parse(ing *extensions.Ingress) (interface{}, error) {
 	return &Config{
 		CorsEnabled:          corsenabled,
 		CorsAllowOrigin:      corsalloworigin,
+		CorsAllowOrigins:     corsalloworigins,
+		CorsAllowOriginRegex: corsalloworiginregex,
 		CorsAllowHeaders:     corsallowheaders,
+		CorsExposeHeaders:    corsexposeheaders,
 		CorsAllowMethods:     corsallowmethods,
 		CorsAllowCredentials: corsallowcredentials,
 		CorsMaxAge:           corsmaxage,
@@ -159,3 +159,71 @@ func TestIngressCorsConfigInvalid(t *testing.T) {
 		t.Errorf("expected %v but returned %v", defaultCorsMaxAge, nginxCors.CorsMaxAge)
 	}
 }
+
+func TestIngressCorsMultipleOrigins(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("enable-cors")] = "true"
+	data[parser.GetAnnotationWithPrefix("cors-allow-origin")] = "https://a.test.com, https://b.test.com:4443"
+	data[parser.GetAnnotationWithPrefix("cors-expose-headers")] = "X-Request-Id, X-Total"
+	ing.SetAnnotations(data)
+
+	corst, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Errorf("error parsing annotations: %v", err)
+	}
+
+	nginxCors, ok := corst.(*Config)
+	if !ok {
+		t.Fatalf("expected a Config type but returned %t", corst)
+	}
+
+	if len(nginxCors.CorsAllowOrigins) != 2 || nginxCors.CorsAllowOrigins[0] != "https://a.test.com" || nginxCors.CorsAllowOrigins[1] != "https://b.test.com:4443" {
+		t.Errorf("expected the origins https://a.test.com and https://b.test.com:4443 but returned %v", nginxCors.CorsAllowOrigins)
+	}
+
+	if !nginxCors.MatchesOrigin() {
+		t.Errorf("expected the configuration to match the origin of the requests")
+	}
+
+	if nginxCors.CorsExposeHeaders != "X-Request-Id, X-Total" {
+		t.Errorf("expected X-Request-Id, X-Total but returned %v", nginxCors.CorsExposeHeaders)
+	}
+}
+
+func TestIngressCorsOriginRegex(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("enable-cors")] = "true"
+	data[parser.GetAnnotationWithPrefix("cors-allow-origin-regex")] = `^https://[a-z0-9-]+\.test\.com$`
+	ing.SetAnnotations(data)
+
+	corst, err := NewParser(&resolver.Mock{}).Parse(ing)
+	if err != nil {
+		t.Errorf("error parsing annotations: %v", err)
+	}
+
+	nginxCors, ok := corst.(*Config)
+	if !ok {
+		t.Fatalf("expected a Config type but returned %t", corst)
+	}
+
+	if nginxCors.CorsAllowOrigin != "" {
+		t.Errorf("expected no origin allowed besides the regex but returned %v", nginxCors.CorsAllowOrigin)
+	}
+
+	if !nginxCors.MatchesOrigin() {
+		t.Errorf("expected the configuration to match the origin of the requests")
+	}
+
+	for _, regex := range []string{`^https://(.*`, `https://a.com'; return 200`} {
+		data[parser.GetAnnotationWithPrefix("cors-allow-origin-regex")] = regex
+		ing.SetAnnotations(data)
+
+		if _, err := NewParser(&resolver.Mock{}).Parse(ing); err == nil {
+			t.Errorf("expected an error parsing the regex %q", regex)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fastcgi
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+// Config contains the FastCGI configuration of the locations using the
+// FCGI backend protocol
+type Config struct {
+	// Index is the file name appended to the URIs ending with a slash,
+	// in the SCRIPT_FILENAME parameter
+	Index string `json:"index"`
+	// Params contains the FastCGI parameters sent to the backend, read
+	// from the ConfigMap referenced in the annotation
+	Params map[string]string `json:"params"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if c1.Index != c2.Index {
+		return false
+	}
+	if len(c1.Params) != len(c2.Params) {
+		return false
+	}
+
+	return reflect.DeepEqual(c1.Params, c2.Params)
+}
+
+type fastcgi struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new FastCGI annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return fastcgi{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+// used to configure the FastCGI backends
+func (a fastcgi) Parse(ing *extensions.Ingress) (interface{}, error) {
+	config := &Config{}
+
+	index, err := parser.GetStringAnnotation("fastcgi-index", ing)
+	if err == nil {
+		if strings.ContainsAny(index, " ;\"'{}$") {
+			return nil, ing_errors.NewInvalidAnnotationContent("fastcgi-index", index)
+		}
+		config.Index = index
+	}
+
+	name, err := parser.GetStringAnnotation("fastcgi-params-configmap", ing)
+	if err != nil {
+		return config, nil
+	}
+
+	config.Params, err = a.configMapParams(name, ing.Namespace)
+	if err != nil {
+		return nil, ing_errors.LocationDenied{
+			Reason: err,
+		}
+	}
+
+	return config, nil
+}
+
+// configMapParams returns the FastCGI parameters of a ConfigMap, where the
+// keys are the names of the parameters. The ConfigMap is searched in the
+// namespace of the Ingress if the name does not contain a namespace.
+func (a fastcgi) configMapParams(name, namespace string) (map[string]string, error) {
+	if !strings.Contains(name, "/") {
+		name = fmt.Sprintf("%v/%v", namespace, name)
+	}
+
+	cm, err := a.r.GetConfigMap(name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unexpected error reading ConfigMap %v", name)
+	}
+	if cm == nil {
+		return nil, errors.Errorf("ConfigMap %v does not exist", name)
+	}
+
+	for k, v := range cm.Data {
+		// the values are written between double quotes in nginx.conf
+		if !validParamName(k) || strings.ContainsAny(v, "\"\\\r\n") {
+			return nil, errors.Errorf("invalid FastCGI parameter %q in ConfigMap %v", k, name)
+		}
+	}
+
+	return cm.Data, nil
+}
+
+// validParamName returns true if the name of a FastCGI parameter only
+// contains letters, digits and underscores
+func validParamName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for _, r := range name {
+		if !(r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')) {
+			return false
+		}
+	}
+
+	return true
+}
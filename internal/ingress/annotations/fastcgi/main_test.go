@@ -0,0 +1,113 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fastcgi
+
+import (
+	"fmt"
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+type mockConfigMap struct {
+	resolver.Mock
+}
+
+// GetConfigMap returns a ConfigMap with FastCGI parameters
+func (m mockConfigMap) GetConfigMap(name string) (*api.ConfigMap, error) {
+	switch name {
+	case "default/fcgi-params":
+		return &api.ConfigMap{
+			Data: map[string]string{
+				"SCRIPT_FILENAME": "/example/index.php",
+				"HTTP_PROXY":      "",
+			},
+		}, nil
+	case "other/invalid-params":
+		return &api.ConfigMap{
+			Data: map[string]string{
+				"SCRIPT_FILENAME": "/example/\"index.php",
+			},
+		}, nil
+	}
+
+	return nil, fmt.Errorf("configmap %v not found", name)
+}
+
+func buildIngress() *extensions.Ingress {
+	return &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+	}
+}
+
+func TestParse(t *testing.T) {
+	ap := NewParser(mockConfigMap{})
+
+	tests := []struct {
+		annotations map[string]string
+		expected    *Config
+		denied      bool
+	}{
+		{map[string]string{}, &Config{}, false},
+		{map[string]string{"fastcgi-index": "index.php"}, &Config{Index: "index.php"}, false},
+		{map[string]string{"fastcgi-index": "index.php; deny all"}, nil, false},
+		{map[string]string{"fastcgi-params-configmap": "fcgi-params"}, &Config{
+			Params: map[string]string{"SCRIPT_FILENAME": "/example/index.php", "HTTP_PROXY": ""},
+		}, false},
+		{map[string]string{"fastcgi-params-configmap": "default/fcgi-params"}, &Config{
+			Params: map[string]string{"SCRIPT_FILENAME": "/example/index.php", "HTTP_PROXY": ""},
+		}, false},
+		{map[string]string{"fastcgi-params-configmap": "missing"}, nil, true},
+		{map[string]string{"fastcgi-params-configmap": "other/invalid-params"}, nil, true},
+	}
+
+	for _, test := range tests {
+		ing := buildIngress()
+		data := map[string]string{}
+		for k, v := range test.annotations {
+			data[parser.GetAnnotationWithPrefix(k)] = v
+		}
+		ing.SetAnnotations(data)
+
+		i, err := ap.Parse(ing)
+		if test.expected == nil {
+			if err == nil {
+				t.Errorf("%v: expected an error but none returned", test.annotations)
+			} else if errors.IsLocationDenied(err) != test.denied {
+				t.Errorf("%v: expected a denied location to be %v but the error is %v", test.annotations, test.denied, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.annotations, err)
+			continue
+		}
+		if c := i.(*Config); !c.Equal(test.expected) {
+			t.Errorf("%v: expected %v but %v returned", test.annotations, test.expected, c)
+		}
+	}
+}
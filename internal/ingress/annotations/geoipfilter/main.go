@@ -0,0 +1,147 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package geoipfilter
+
+import (
+	"regexp"
+	"strings"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+var (
+	countryRegex = regexp.MustCompile(`^[A-Z]{2}$`)
+	asnRegex     = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// Config contains the countries and autonomous systems allowed or
+// denied to access a location, using the GeoIP2 databases
+type Config struct {
+	AllowCountries []string `json:"allowCountries,omitempty"`
+	DenyCountries  []string `json:"denyCountries,omitempty"`
+	AllowASNs      []string `json:"allowASNs,omitempty"`
+	DenyASNs       []string `json:"denyASNs,omitempty"`
+}
+
+// Enabled returns true if the access to the location is filtered
+func (c Config) Enabled() bool {
+	return len(c.AllowCountries) > 0 || len(c.DenyCountries) > 0 ||
+		len(c.AllowASNs) > 0 || len(c.DenyASNs) > 0
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+
+	return equalLists(c1.AllowCountries, c2.AllowCountries) &&
+		equalLists(c1.DenyCountries, c2.DenyCountries) &&
+		equalLists(c1.AllowASNs, c2.AllowASNs) &&
+		equalLists(c1.DenyASNs, c2.DenyASNs)
+}
+
+func equalLists(l1, l2 []string) bool {
+	if len(l1) != len(l2) {
+		return false
+	}
+	for i := range l1 {
+		if l1[i] != l2[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+type geoipFilter struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new GeoIP2 access control annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return geoipFilter{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+// used to filter the access to a location by the country code
+// (ISO 3166-1) or the autonomous system number of the client.
+// Multiple values can specified using commas as separator
+// e.g. `US,CA` or `AS15169,13335`
+func (a geoipFilter) Parse(ing *extensions.Ingress) (interface{}, error) {
+	config := &Config{}
+
+	var err error
+	config.AllowCountries, err = parseList("allow-countries", countryRegex, "", ing)
+	if err != nil {
+		return nil, err
+	}
+	config.DenyCountries, err = parseList("deny-countries", countryRegex, "", ing)
+	if err != nil {
+		return nil, err
+	}
+	config.AllowASNs, err = parseList("allow-asns", asnRegex, "AS", ing)
+	if err != nil {
+		return nil, err
+	}
+	config.DenyASNs, err = parseList("deny-asns", asnRegex, "AS", ing)
+	if err != nil {
+		return nil, err
+	}
+
+	if !config.Enabled() {
+		return nil, ing_errors.ErrMissingAnnotations
+	}
+
+	return config, nil
+}
+
+// parseList returns the sorted values of a comma separated annotation,
+// removing the optional prefix of the values
+func parseList(name string, valid *regexp.Regexp, prefix string, ing *extensions.Ingress) ([]string, error) {
+	val, err := parser.GetStringAnnotation(name, ing)
+	if err != nil {
+		return nil, nil
+	}
+
+	values := sets.NewString()
+	for _, v := range strings.Split(val, ",") {
+		v = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(v)), prefix)
+		if v == "" {
+			continue
+		}
+
+		if !valid.MatchString(v) {
+			return nil, ing_errors.NewInvalidAnnotationContent(name, val)
+		}
+
+		values.Insert(v)
+	}
+
+	if values.Len() == 0 {
+		return nil, nil
+	}
+
+	return values.List(), nil
+}
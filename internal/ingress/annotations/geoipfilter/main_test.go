@@ -0,0 +1,73 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package geoipfilter
+
+import (
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func TestParse(t *testing.T) {
+	allowCountries := parser.GetAnnotationWithPrefix("allow-countries")
+	denyCountries := parser.GetAnnotationWithPrefix("deny-countries")
+	allowASNs := parser.GetAnnotationWithPrefix("allow-asns")
+	denyASNs := parser.GetAnnotationWithPrefix("deny-asns")
+
+	ap := NewParser(&resolver.Mock{})
+	if ap == nil {
+		t.Fatalf("expected a parser.IngressAnnotation but returned nil")
+	}
+
+	testCases := []struct {
+		annotations map[string]string
+		expected    *Config
+	}{
+		{map[string]string{}, nil},
+		{map[string]string{allowCountries: " , "}, nil},
+		{map[string]string{allowCountries: "USA"}, nil},
+		{map[string]string{denyASNs: "AS-1"}, nil},
+		{map[string]string{allowCountries: "us, ca,US"}, &Config{AllowCountries: []string{"CA", "US"}}},
+		{map[string]string{denyCountries: "xx", allowASNs: "AS15169, 13335"}, &Config{
+			DenyCountries: []string{"XX"},
+			AllowASNs:     []string{"13335", "15169"},
+		}},
+		{map[string]string{denyASNs: "as64512"}, &Config{DenyASNs: []string{"64512"}}},
+	}
+
+	ing := &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: extensions.IngressSpec{},
+	}
+
+	for _, testCase := range testCases {
+		ing.SetAnnotations(testCase.annotations)
+		i, _ := ap.Parse(ing)
+		p, _ := i.(*Config)
+
+		if !p.Equal(testCase.expected) {
+			t.Errorf("expected %v but returned %v, annotations: %s", testCase.expected, p, testCase.annotations)
+		}
+	}
+}
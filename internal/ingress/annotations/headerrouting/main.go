@@ -0,0 +1,142 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package headerrouting
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+const patternPrefix = "~"
+
+var (
+	headerRegex  = regexp.MustCompile(`^[a-zA-Z0-9\-]+$`)
+	valueRegex   = regexp.MustCompile(`^[a-zA-Z0-9_\-.:@]+$`)
+	serviceRegex = regexp.MustCompile(`^([a-z0-9]([-a-z0-9]*[a-z0-9])?):([a-zA-Z0-9\-]+)$`)
+	numericRegex = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// Route maps a value or a pattern of the header to a Service
+type Route struct {
+	// Value contains the exact value of the header
+	Value string `json:"value,omitempty"`
+	// Pattern contains a regular expression the header must match
+	Pattern     string             `json:"pattern,omitempty"`
+	ServiceName string             `json:"serviceName"`
+	ServicePort intstr.IntOrString `json:"servicePort"`
+}
+
+// Config returns the configuration used to route requests
+// to different Services depending on the value of a header
+type Config struct {
+	Header string  `json:"header"`
+	Routes []Route `json:"routes"`
+}
+
+type headerRouting struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new header routing annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return headerRouting{r}
+}
+
+// Parse parses the annotations contained in the ingress rule used to
+// route requests to a different Service depending on the value of a
+// header. Values prefixed with ~ are regular expressions, i.e.
+// header-routing-name: X-Tenant
+// header-routing-map: acme=tenant-acme:80, ~^beta-=tenant-beta:http
+func (a headerRouting) Parse(ing *extensions.Ingress) (interface{}, error) {
+	header, err := parser.GetStringAnnotation("header-routing-name", ing)
+	if err != nil {
+		return nil, err
+	}
+
+	if !headerRegex.MatchString(header) {
+		return nil, errors.NewInvalidAnnotationContent("header-routing-name", header)
+	}
+
+	routesMap, err := parser.GetStringAnnotation("header-routing-map", ing)
+	if err != nil {
+		return nil, errors.NewInvalidAnnotationConfiguration("header-routing-name", "header-routing-map is required")
+	}
+
+	config := &Config{
+		Header: header,
+		Routes: []Route{},
+	}
+
+	for _, entry := range strings.Split(routesMap, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		// the service cannot contain the character = but the pattern can.
+		// Patterns cannot contain commas because they separate the entries.
+		idx := strings.LastIndex(entry, "=")
+		if idx <= 0 {
+			return nil, errors.NewInvalidAnnotationContent("header-routing-map", entry)
+		}
+
+		value := strings.TrimSpace(entry[:idx])
+		m := serviceRegex.FindStringSubmatch(strings.TrimSpace(entry[idx+1:]))
+		if m == nil {
+			return nil, errors.NewInvalidAnnotationContent("header-routing-map", entry)
+		}
+
+		port := intstr.FromString(m[3])
+		if numericRegex.MatchString(m[3]) {
+			port = intstr.Parse(m[3])
+		}
+
+		route := Route{
+			ServiceName: m[1],
+			ServicePort: port,
+		}
+
+		if strings.HasPrefix(value, patternPrefix) {
+			pattern := strings.TrimPrefix(value, patternPrefix)
+			if _, err := regexp.Compile(pattern); err != nil || pattern == "" {
+				return nil, errors.NewInvalidAnnotationConfiguration("header-routing-map", fmt.Sprintf("invalid pattern %v", pattern))
+			}
+			route.Pattern = pattern
+		} else {
+			if !valueRegex.MatchString(value) {
+				return nil, errors.NewInvalidAnnotationContent("header-routing-map", entry)
+			}
+			route.Value = value
+		}
+
+		config.Routes = append(config.Routes, route)
+	}
+
+	if len(config.Routes) == 0 {
+		return nil, errors.NewInvalidAnnotationConfiguration("header-routing-map", "no routes defined")
+	}
+
+	return config, nil
+}
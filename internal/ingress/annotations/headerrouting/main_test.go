@@ -0,0 +1,90 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package headerrouting
+
+import (
+	"reflect"
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func TestParse(t *testing.T) {
+	header := parser.GetAnnotationWithPrefix("header-routing-name")
+	routes := parser.GetAnnotationWithPrefix("header-routing-map")
+
+	ap := NewParser(&resolver.Mock{})
+	if ap == nil {
+		t.Fatalf("expected a parser.IngressAnnotation but returned nil")
+	}
+
+	testCases := []struct {
+		title       string
+		annotations map[string]string
+		expected    *Config
+		expErr      bool
+	}{
+		{"without annotations", map[string]string{}, nil, true},
+		{"without map", map[string]string{header: "X-Tenant"}, nil, true},
+		{"invalid header", map[string]string{header: "X Tenant", routes: "acme=svc:80"}, nil, true},
+		{"invalid route", map[string]string{header: "X-Tenant", routes: "acme"}, nil, true},
+		{"invalid value", map[string]string{header: "X-Tenant", routes: "ac'me=svc:80"}, nil, true},
+		{"invalid pattern", map[string]string{header: "X-Tenant", routes: "~beta-(=svc:80"}, nil, true},
+		{"empty map", map[string]string{header: "X-Tenant", routes: " , "}, nil, true},
+		{"valid", map[string]string{header: "X-Tenant", routes: "acme=tenant-acme:80, ~^beta-[a-z]+$=tenant-beta:http"}, &Config{
+			Header: "X-Tenant",
+			Routes: []Route{
+				{Value: "acme", ServiceName: "tenant-acme", ServicePort: intstr.FromInt(80)},
+				{Pattern: "^beta-[a-z]+$", ServiceName: "tenant-beta", ServicePort: intstr.FromString("http")},
+			},
+		}, false},
+	}
+
+	ing := &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: extensions.IngressSpec{},
+	}
+
+	for _, testCase := range testCases {
+		ing.SetAnnotations(testCase.annotations)
+		i, err := ap.Parse(ing)
+		if testCase.expErr {
+			if err == nil {
+				t.Errorf("%v: expected error but returned nil", testCase.title)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", testCase.title, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(i, testCase.expected) {
+			t.Errorf("%v: expected %v but returned %v", testCase.title, testCase.expected, i)
+		}
+	}
+}
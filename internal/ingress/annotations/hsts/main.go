@@ -0,0 +1,105 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hsts
+
+import (
+	"strconv"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+	"k8s.io/ingress-nginx/internal/log"
+)
+
+// Config contains the HTTP Strict Transport Security configuration of a server
+type Config struct {
+	Enabled           bool   `json:"enabled"`
+	MaxAge            string `json:"maxAge"`
+	IncludeSubdomains bool   `json:"includeSubdomains"`
+	Preload           bool   `json:"preload"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if c1.Enabled != c2.Enabled {
+		return false
+	}
+	if c1.MaxAge != c2.MaxAge {
+		return false
+	}
+	if c1.IncludeSubdomains != c2.IncludeSubdomains {
+		return false
+	}
+	if c1.Preload != c2.Preload {
+		return false
+	}
+
+	return true
+}
+
+type hsts struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new HSTS annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return hsts{r}
+}
+
+// Parse parses the annotations contained in the ingress rule used to
+// configure the header Strict-Transport-Security of the server/s. The
+// annotations not set use the values of the configuration ConfigMap.
+func (a hsts) Parse(ing *extensions.Ingress) (interface{}, error) {
+	defBackend := a.r.GetDefaultBackend()
+
+	enabled, err := parser.GetBoolAnnotation("hsts", ing)
+	if err != nil {
+		enabled = defBackend.HSTS
+	}
+
+	maxAge, err := parser.GetStringAnnotation("hsts-max-age", ing)
+	if err != nil {
+		maxAge = defBackend.HSTSMaxAge
+	} else if seconds, err := strconv.Atoi(maxAge); err != nil || seconds < 0 {
+		log.Warningf("%v is not a valid HSTS max-age (seconds). Using the default", maxAge)
+		maxAge = defBackend.HSTSMaxAge
+	}
+
+	includeSubdomains, err := parser.GetBoolAnnotation("hsts-include-subdomains", ing)
+	if err != nil {
+		includeSubdomains = defBackend.HSTSIncludeSubdomains
+	}
+
+	preload, err := parser.GetBoolAnnotation("hsts-preload", ing)
+	if err != nil {
+		preload = defBackend.HSTSPreload
+	}
+
+	return &Config{
+		Enabled:           enabled,
+		MaxAge:            maxAge,
+		IncludeSubdomains: includeSubdomains,
+		Preload:           preload,
+	}, nil
+}
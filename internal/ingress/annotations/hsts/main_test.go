@@ -0,0 +1,83 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hsts
+
+import (
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/defaults"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+type mockBackend struct {
+	resolver.Mock
+}
+
+func (m mockBackend) GetDefaultBackend() defaults.Backend {
+	return defaults.Backend{
+		HSTS:                  true,
+		HSTSMaxAge:            "15724800",
+		HSTSIncludeSubdomains: true,
+		HSTSPreload:           false,
+	}
+}
+
+func TestParse(t *testing.T) {
+	enable := parser.GetAnnotationWithPrefix("hsts")
+	maxAge := parser.GetAnnotationWithPrefix("hsts-max-age")
+	subdomains := parser.GetAnnotationWithPrefix("hsts-include-subdomains")
+	preload := parser.GetAnnotationWithPrefix("hsts-preload")
+
+	ap := NewParser(mockBackend{})
+	if ap == nil {
+		t.Fatalf("expected a parser.IngressAnnotation but returned nil")
+	}
+
+	testCases := []struct {
+		annotations map[string]string
+		expected    *Config
+	}{
+		{nil, &Config{Enabled: true, MaxAge: "15724800", IncludeSubdomains: true}},
+		{map[string]string{enable: "false"}, &Config{Enabled: false, MaxAge: "15724800", IncludeSubdomains: true}},
+		{map[string]string{maxAge: "63072000", subdomains: "true", preload: "true"}, &Config{Enabled: true, MaxAge: "63072000", IncludeSubdomains: true, Preload: true}},
+		{map[string]string{maxAge: "1y", subdomains: "false"}, &Config{Enabled: true, MaxAge: "15724800", IncludeSubdomains: false}},
+		{map[string]string{maxAge: "-1"}, &Config{Enabled: true, MaxAge: "15724800", IncludeSubdomains: true}},
+	}
+
+	ing := &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: extensions.IngressSpec{},
+	}
+
+	for _, testCase := range testCases {
+		ing.SetAnnotations(testCase.annotations)
+		i, _ := ap.Parse(ing)
+		p, _ := i.(*Config)
+
+		if !p.Equal(testCase.expected) {
+			t.Errorf("expected %v but returned %v, annotations: %s", testCase.expected, p, testCase.annotations)
+		}
+	}
+}
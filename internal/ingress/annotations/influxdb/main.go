@@ -90,6 +90,9 @@ func (e1 *Config) Equal(e2 *Config) bool {
 	if e1.InfluxDBEnabled != e2.InfluxDBEnabled {
 		return false
 	}
+	if e1.InfluxDBMeasurement != e2.InfluxDBMeasurement {
+		return false
+	}
 	if e1.InfluxDBPort != e2.InfluxDBPort {
 		return false
 	}
@@ -17,8 +17,10 @@ limitations under the License.
 package ipwhitelist
 
 import (
+	"fmt"
 	"sort"
 	"strings"
+	"unicode"
 
 	"github.com/pkg/errors"
 
@@ -77,17 +79,35 @@ func NewParser(r resolver.Resolver) parser.IngressAnnotation {
 // rule used to limit access to certain client addresses or networks.
 // Multiple ranges can specified using commas as separator
 // e.g. `18.0.0.0/8,56.0.0.0/8`
+// The ranges can also be read from the values of a ConfigMap referenced
+// with the annotation whitelist-source-range-configmap, which allows to
+// manage large lists in a central location.
 func (a ipwhitelist) Parse(ing *extensions.Ingress) (interface{}, error) {
 	defBackend := a.r.GetDefaultBackend()
 	sort.Strings(defBackend.WhitelistSourceRange)
 
 	val, err := parser.GetStringAnnotation("whitelist-source-range", ing)
+	cmName, cmErr := parser.GetStringAnnotation("whitelist-source-range-configmap", ing)
 	// A missing annotation is not a problem, just use the default
-	if err == ing_errors.ErrMissingAnnotations {
+	if err == ing_errors.ErrMissingAnnotations && cmErr == ing_errors.ErrMissingAnnotations {
 		return &SourceRange{CIDR: defBackend.WhitelistSourceRange}, nil
 	}
 
-	values := strings.Split(val, ",")
+	values := []string{}
+	if err == nil {
+		values = append(values, strings.Split(val, ",")...)
+	}
+
+	if cmName != "" {
+		cmValues, err := a.configMapValues(cmName, ing.Namespace)
+		if err != nil {
+			return &SourceRange{CIDR: defBackend.WhitelistSourceRange}, ing_errors.LocationDenied{
+				Reason: err,
+			}
+		}
+		values = append(values, cmValues...)
+	}
+
 	ipnets, ips, err := net.ParseIPNets(values...)
 	if err != nil && len(ips) == 0 {
 		return &SourceRange{CIDR: defBackend.WhitelistSourceRange}, ing_errors.LocationDenied{
@@ -107,3 +127,34 @@ func (a ipwhitelist) Parse(ing *extensions.Ingress) (interface{}, error) {
 
 	return &SourceRange{cidrs}, nil
 }
+
+// configMapValues returns the ranges contained in all the keys of a
+// ConfigMap, separated by commas, spaces or new lines. The ConfigMap
+// is searched in the namespace of the Ingress if the name does not
+// contain a namespace.
+func (a ipwhitelist) configMapValues(name, namespace string) ([]string, error) {
+	if !strings.Contains(name, "/") {
+		name = fmt.Sprintf("%v/%v", namespace, name)
+	}
+
+	cm, err := a.r.GetConfigMap(name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unexpected error reading ConfigMap %v", name)
+	}
+	if cm == nil {
+		return nil, errors.Errorf("ConfigMap %v does not exist", name)
+	}
+
+	values := []string{}
+	for _, data := range cm.Data {
+		values = append(values, strings.FieldsFunc(data, func(r rune) bool {
+			return r == ',' || unicode.IsSpace(r)
+		})...)
+	}
+
+	if len(values) == 0 {
+		return nil, errors.Errorf("ConfigMap %v does not contain IP addresses or networks", name)
+	}
+
+	return values, nil
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package ipwhitelist
 
 import (
+	"fmt"
 	"testing"
 
 	api "k8s.io/api/core/v1"
@@ -202,3 +203,81 @@ func strsEquals(a, b []string) bool {
 	}
 	return true
 }
+
+type mockConfigMap struct {
+	resolver.Mock
+}
+
+// GetConfigMap returns a ConfigMap with a list of networks
+func (m mockConfigMap) GetConfigMap(name string) (*api.ConfigMap, error) {
+	if name != "default/allowlist" {
+		return nil, fmt.Errorf("configmap %v not found", name)
+	}
+
+	return &api.ConfigMap{
+		Data: map[string]string{
+			"office": "10.0.0.0/24, 10.0.1.0/24",
+			"vpn":    "192.168.0.0/16\n172.16.0.1\n",
+		},
+	}, nil
+}
+
+func TestParseAnnotationsWithConfigMap(t *testing.T) {
+	ing := buildIngress()
+
+	tests := map[string]struct {
+		net        string
+		configMap  string
+		expectCidr []string
+		expectErr  bool
+	}{
+		"test configmap in the namespace of the ingress": {
+			configMap:  "allowlist",
+			expectCidr: []string{"10.0.0.0/24", "10.0.1.0/24", "172.16.0.1", "192.168.0.0/16"},
+		},
+		"test configmap with namespace": {
+			configMap:  "default/allowlist",
+			expectCidr: []string{"10.0.0.0/24", "10.0.1.0/24", "172.16.0.1", "192.168.0.0/16"},
+		},
+		"test configmap merged with annotation": {
+			net:        "2.2.2.2/32",
+			configMap:  "allowlist",
+			expectCidr: []string{"10.0.0.0/24", "10.0.1.0/24", "172.16.0.1", "192.168.0.0/16", "2.2.2.2/32"},
+		},
+		"test missing configmap": {
+			net:       "2.2.2.2/32",
+			configMap: "other/allowlist",
+			expectErr: true,
+		},
+	}
+
+	for testName, test := range tests {
+		data := map[string]string{}
+		if test.net != "" {
+			data[parser.GetAnnotationWithPrefix("whitelist-source-range")] = test.net
+		}
+		data[parser.GetAnnotationWithPrefix("whitelist-source-range-configmap")] = test.configMap
+		ing.SetAnnotations(data)
+
+		i, err := NewParser(mockConfigMap{}).Parse(ing)
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("%v:expected error but none returned", testName)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v:unexpected error: %v", testName, err)
+			continue
+		}
+
+		sr, ok := i.(*SourceRange)
+		if !ok {
+			t.Errorf("%v:expected a SourceRange type", testName)
+			continue
+		}
+		if !strsEquals(sr.CIDR, test.expectCidr) {
+			t.Errorf("%v:expected %v CIDR but %v returned", testName, test.expectCidr, sr.CIDR)
+		}
+	}
+}
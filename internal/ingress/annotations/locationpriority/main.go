@@ -0,0 +1,46 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package locationpriority
+
+import (
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+type locationPriority struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new location priority annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return locationPriority{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+// used to indicate the precedence of the locations of the ingress
+// when the paths of different ingresses in the same host overlap.
+// Locations with a higher priority are rendered first.
+func (a locationPriority) Parse(ing *extensions.Ingress) (interface{}, error) {
+	priority, err := parser.GetIntAnnotation("location-priority", ing)
+	if err != nil {
+		return 0, nil
+	}
+
+	return priority, nil
+}
@@ -17,10 +17,13 @@ limitations under the License.
 package log
 
 import (
+	"strings"
+
 	extensions "k8s.io/api/extensions/v1beta1"
 
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
+	ing_log "k8s.io/ingress-nginx/internal/log"
 )
 
 type log struct {
@@ -31,6 +34,9 @@ type log struct {
 type Config struct {
 	Access  bool `json:"accessLog"`
 	Rewrite bool `json:"rewriteLog"`
+	// Format is a custom log format for the access log of the location.
+	// An empty value uses the global upstreaminfo format.
+	Format string `json:"accessLogFormat"`
 }
 
 // Equal tests for equality between two Config types
@@ -43,6 +49,10 @@ func (bd1 *Config) Equal(bd2 *Config) bool {
 		return false
 	}
 
+	if bd1.Format != bd2.Format {
+		return false
+	}
+
 	return true
 }
 
@@ -64,5 +74,16 @@ func (l log) Parse(ing *extensions.Ingress) (interface{}, error) {
 		rewriteEnabled = false
 	}
 
-	return &Config{Access: accessEnabled, Rewrite: rewriteEnabled}, nil
+	format, err := parser.GetStringAnnotation("access-log-format", ing)
+	if err != nil {
+		format = ""
+	}
+	// the format is rendered inside single quotes in the log_format directive
+	if strings.ContainsAny(format, "'\n") {
+		ing_log.Warningf("ignoring access-log-format annotation in ingress %v/%v: the format cannot contain single quotes or new lines",
+			ing.Namespace, ing.Name)
+		format = ""
+	}
+
+	return &Config{Access: accessEnabled, Rewrite: rewriteEnabled, Format: format}, nil
 }
@@ -97,3 +97,30 @@ func TestIngressRewriteLogConfig(t *testing.T) {
 		t.Errorf("expected rewrite log to be enabled but it is disabled")
 	}
 }
+
+func TestIngressAccessLogFormat(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("access-log-format")] = "$remote_addr $status $request_time"
+	ing.SetAnnotations(data)
+
+	log, _ := NewParser(&resolver.Mock{}).Parse(ing)
+	nginxLogs, ok := log.(*Config)
+	if !ok {
+		t.Errorf("expected a Config type")
+	}
+
+	if nginxLogs.Format != "$remote_addr $status $request_time" {
+		t.Errorf("expected a custom access log format but %v was returned", nginxLogs.Format)
+	}
+
+	data[parser.GetAnnotationWithPrefix("access-log-format")] = "$remote_addr ';"
+	ing.SetAnnotations(data)
+
+	log, _ = NewParser(&resolver.Mock{}).Parse(ing)
+	nginxLogs = log.(*Config)
+	if nginxLogs.Format != "" {
+		t.Errorf("expected a format with single quotes to be ignored but %v was returned", nginxLogs.Format)
+	}
+}
@@ -0,0 +1,209 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package annotations
+
+import (
+	"sort"
+	"strings"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+)
+
+// EffectiveConfigAnnotation is the name, without the prefix, of the
+// annotation written by the controller with the summary of the
+// configuration applied to an Ingress
+const EffectiveConfigAnnotation = "effective-configuration"
+
+// knownAnnotations contains the names, without the prefix, of the
+// annotations read by the parsers
+var knownAnnotations = sets.NewString(
+	"access-log-format",
+	"add-base-url",
+	"affinity",
+	"allow-asns",
+	"allow-countries",
+	"allowed-methods",
+	"app-root",
+	"auth-cache-duration",
+	"auth-cache-key",
+	"auth-method",
+	"auth-oidc-discovery",
+	"auth-oidc-logout-path",
+	"auth-oidc-redirect-path",
+	"auth-oidc-scope",
+	"auth-oidc-secret",
+	"auth-realm",
+	"auth-request-redirect",
+	"auth-response-headers",
+	"auth-secret",
+	"auth-signin",
+	"auth-snippet",
+	"auth-tls-error-page",
+	"auth-tls-pass-certificate-to-upstream",
+	"auth-tls-secret",
+	"auth-tls-verify-client",
+	"auth-tls-verify-depth",
+	"auth-type",
+	"auth-url",
+	"backend-protocol",
+	"base-url-scheme",
+	"block-header-values",
+	"block-referers",
+	"block-user-agents",
+	"brotli-level",
+	"brotli-types",
+	"canary",
+	"canary-by-cookie",
+	"canary-by-header",
+	"canary-shadow",
+	"canary-weight",
+	"canary-weight-total",
+	"client-body-buffer-size",
+	"compression-exclude-types",
+	"compression-min-length",
+	"configuration-snippet",
+	"connection-proxy-header",
+	"cors-allow-credentials",
+	"cors-allow-headers",
+	"cors-allow-methods",
+	"cors-allow-origin",
+	"cors-allow-origin-regex",
+	"cors-expose-headers",
+	"cors-max-age",
+	"default-backend",
+	"deny-asns",
+	"deny-countries",
+	"enable-access-log",
+	"enable-brotli",
+	"enable-cors",
+	"enable-influxdb",
+	"enable-opentracing",
+	"enable-rewrite-log",
+	"enable-size-metrics",
+	"fastcgi-index",
+	"fastcgi-params-configmap",
+	"force-ssl-redirect",
+	"forwarded-for-header",
+	"from-to-www-redirect",
+	"header-routing-map",
+	"header-routing-name",
+	"hsts",
+	"hsts-include-subdomains",
+	"hsts-max-age",
+	"hsts-preload",
+	"influxdb-host",
+	"influxdb-measurement",
+	"influxdb-port",
+	"influxdb-server-name",
+	"internal",
+	"limit-connections",
+	"limit-rate",
+	"limit-rate-after",
+	"limit-rpm",
+	"limit-rps",
+	"limit-rps-burst-multiplier",
+	"limit-whitelist",
+	"load-balance",
+	"location-priority",
+	"lua-resty-waf",
+	"lua-resty-waf-allow-unknown-content-types",
+	"lua-resty-waf-debug",
+	"lua-resty-waf-extra-rules",
+	"lua-resty-waf-ignore-rulesets",
+	"lua-resty-waf-process-multipart-body",
+	"lua-resty-waf-score-threshold",
+	"no-auth-locations",
+	"permanent-redirect",
+	"permanent-redirect-code",
+	"proxy-bind",
+	"proxy-body-size",
+	"proxy-buffer-size",
+	"proxy-buffering",
+	"proxy-cache-bypass",
+	"proxy-cache-compressed",
+	"proxy-cache-key",
+	"proxy-cache-valid",
+	"proxy-cache-zone",
+	"proxy-connect-timeout",
+	"proxy-cookie-domain",
+	"proxy-cookie-path",
+	"proxy-next-upstream",
+	"proxy-next-upstream-budget",
+	"proxy-next-upstream-timeout",
+	"proxy-next-upstream-tries",
+	"proxy-protocol-to-backend",
+	"proxy-read-timeout",
+	"proxy-real-ip-cidr",
+	"proxy-redirect-from",
+	"proxy-redirect-to",
+	"proxy-request-buffering",
+	"proxy-send-timeout",
+	"proxy-ssl-name",
+	"proxy-ssl-protocols",
+	"proxy-ssl-secret",
+	"proxy-ssl-server-name",
+	"proxy-ssl-session-reuse",
+	"proxy-ssl-verify",
+	"query-routing-map",
+	"query-routing-param",
+	"rewrite-target",
+	"satisfy",
+	"secure-verify-ca-secret",
+	"server-alias",
+	"server-snippet",
+	"service-upstream",
+	"service-upstream-include-not-ready",
+	"session-cookie-hash",
+	"session-cookie-name",
+	"ssl-ciphers",
+	"ssl-passthrough",
+	"ssl-redirect",
+	"temporal-redirect",
+	"temporal-redirect-code",
+	"upstream-connect-proxy",
+	"upstream-hash-by",
+	"upstream-vhost",
+	"use-port-in-redirects",
+	"use-regex",
+	"websocket-heavy",
+	"whitelist-source-range",
+	"whitelist-source-range-configmap",
+	"x-forwarded-prefix",
+	EffectiveConfigAnnotation,
+)
+
+// UnknownAnnotations returns the names, without the prefix, of the
+// annotations of an Ingress with the prefix of the controller that are
+// not read by any parser, like misspelled annotations, sorted by name.
+func UnknownAnnotations(ing *extensions.Ingress) []string {
+	prefix := parser.GetAnnotationWithPrefix("")
+
+	var unknown []string
+	for k := range ing.GetAnnotations() {
+		name := strings.TrimPrefix(k, prefix)
+		if name == k || knownAnnotations.Has(name) {
+			continue
+		}
+		unknown = append(unknown, name)
+	}
+
+	sort.Strings(unknown)
+	return unknown
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package annotations
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ing_parser "k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+)
+
+// TestKnownAnnotations checks that the names of the annotations read by the
+// parsers with a literal are in the list of known annotations
+func TestKnownAnnotations(t *testing.T) {
+	getters := map[string]bool{
+		"GetAnnotationWithPrefix": true,
+		"GetBoolAnnotation":       true,
+		"GetFloatAnnotation":      true,
+		"GetIntAnnotation":        true,
+		"GetStringAnnotation":     true,
+	}
+
+	files, err := filepath.Glob("*/*.go")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if filepath.Dir(file) == "parser" || strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			t.Fatalf("unexpected error parsing %v: %v", file, err)
+		}
+
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) == 0 {
+				return true
+			}
+
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok || !getters[sel.Sel.Name] {
+				return true
+			}
+			if x, ok := sel.X.(*ast.Ident); !ok || x.Name != "parser" {
+				return true
+			}
+
+			lit, ok := call.Args[0].(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				return true
+			}
+
+			name, _ := strconv.Unquote(lit.Value)
+			if !knownAnnotations.Has(name) {
+				t.Errorf("%v: annotation %q is not in the list of known annotations", fset.Position(lit.Pos()), name)
+			}
+			return true
+		})
+	}
+}
+
+func TestUnknownAnnotations(t *testing.T) {
+	ing := &extensions.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				ing_parser.GetAnnotationWithPrefix("rewrite-target"): "/",
+				ing_parser.GetAnnotationWithPrefix("rewrite-taget"):  "/",
+				ing_parser.GetAnnotationWithPrefix("enable-cros"):    "true",
+				"kubernetes.io/ingress.class":                        "nginx",
+			},
+		},
+	}
+
+	unknown := UnknownAnnotations(ing)
+	expected := []string{"enable-cros", "rewrite-taget"}
+	if !reflect.DeepEqual(unknown, expected) {
+		t.Errorf("expected %v but got %v", expected, unknown)
+	}
+}
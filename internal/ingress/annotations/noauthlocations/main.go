@@ -0,0 +1,67 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package noauthlocations
+
+import (
+	"strings"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+type noAuthLocations struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new parser of the annotation with the paths of an
+// Ingress exempt from its authentication annotations
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return noAuthLocations{r}
+}
+
+// Parse parses the annotation no-auth-locations, a comma-separated list of
+// paths like `/healthz,/webhooks`. The paths are sorted and must start with /
+func (a noAuthLocations) Parse(ing *extensions.Ingress) (interface{}, error) {
+	val, err := parser.GetStringAnnotation("no-auth-locations", ing)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := sets.NewString()
+	for _, p := range strings.Split(val, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+
+		if !strings.HasPrefix(p, "/") || strings.ContainsAny(p, " ;{}") {
+			return nil, ing_errors.NewInvalidAnnotationContent("no-auth-locations", val)
+		}
+
+		paths.Insert(p)
+	}
+
+	if paths.Len() == 0 {
+		return nil, ing_errors.NewInvalidAnnotationContent("no-auth-locations", val)
+	}
+
+	return paths.List(), nil
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package noauthlocations
+
+import (
+	"reflect"
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func TestParse(t *testing.T) {
+	annotation := parser.GetAnnotationWithPrefix("no-auth-locations")
+	ap := NewParser(&resolver.Mock{})
+
+	testCases := []struct {
+		annotations map[string]string
+		expected    []string
+	}{
+		{map[string]string{}, nil},
+		{map[string]string{annotation: ""}, nil},
+		{map[string]string{annotation: " , "}, nil},
+		{map[string]string{annotation: "healthz"}, nil},
+		{map[string]string{annotation: "/healthz;return 200"}, nil},
+		{map[string]string{annotation: "/healthz"}, []string{"/healthz"}},
+		{map[string]string{annotation: "/webhooks, /healthz,/healthz"}, []string{"/healthz", "/webhooks"}},
+	}
+
+	ing := &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+	}
+
+	for _, testCase := range testCases {
+		ing.SetAnnotations(testCase.annotations)
+		i, _ := ap.Parse(ing)
+		paths, _ := i.([]string)
+		if !reflect.DeepEqual(paths, testCase.expected) {
+			t.Errorf("expected %v but returned %v, annotations: %s", testCase.expected, paths, testCase.annotations)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package opentracing
+
+import (
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+// Config contains the opentracing configuration of a location
+type Config struct {
+	// Disabled indicates that the requests of the location are not traced
+	// even if opentracing is enabled in the configuration ConfigMap
+	Disabled bool `json:"disabled"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if c1.Disabled != c2.Disabled {
+		return false
+	}
+
+	return true
+}
+
+type opentracing struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new opentracing annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return opentracing{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+// used to disable the tracing of the requests in the location/s
+func (a opentracing) Parse(ing *extensions.Ingress) (interface{}, error) {
+	enabled, err := parser.GetBoolAnnotation("enable-opentracing", ing)
+	if err != nil {
+		enabled = true
+	}
+
+	return &Config{Disabled: !enabled}, nil
+}
@@ -17,29 +17,40 @@ limitations under the License.
 package proxy
 
 import (
+	"net"
+	"regexp"
+	"strings"
+
 	extensions "k8s.io/api/extensions/v1beta1"
 
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
+	"k8s.io/ingress-nginx/internal/log"
 )
 
 // Config returns the proxy timeout to use in the upstream server/s
 type Config struct {
-	BodySize          string `json:"bodySize"`
-	ConnectTimeout    int    `json:"connectTimeout"`
-	SendTimeout       int    `json:"sendTimeout"`
-	ReadTimeout       int    `json:"readTimeout"`
-	BufferSize        string `json:"bufferSize"`
-	CookieDomain      string `json:"cookieDomain"`
-	CookiePath        string `json:"cookiePath"`
-	NextUpstream      string `json:"nextUpstream"`
-	NextUpstreamTries int    `json:"nextUpstreamTries"`
-	ProxyRedirectFrom string `json:"proxyRedirectFrom"`
-	ProxyRedirectTo   string `json:"proxyRedirectTo"`
-	RequestBuffering  string `json:"requestBuffering"`
-	ProxyBuffering    string `json:"proxyBuffering"`
+	BodySize            string `json:"bodySize"`
+	ConnectTimeout      int    `json:"connectTimeout"`
+	SendTimeout         int    `json:"sendTimeout"`
+	ReadTimeout         int    `json:"readTimeout"`
+	BufferSize          string `json:"bufferSize"`
+	CookieDomain        string `json:"cookieDomain"`
+	CookiePath          string `json:"cookiePath"`
+	NextUpstream        string `json:"nextUpstream"`
+	NextUpstreamTries   int    `json:"nextUpstreamTries"`
+	NextUpstreamTimeout int    `json:"nextUpstreamTimeout"`
+	NextUpstreamBudget  int    `json:"nextUpstreamBudget"`
+	ProxyRedirectFrom   string `json:"proxyRedirectFrom"`
+	ProxyRedirectTo     string `json:"proxyRedirectTo"`
+	RequestBuffering    string `json:"requestBuffering"`
+	ProxyBuffering      string `json:"proxyBuffering"`
+	ProxyBind           string `json:"proxyBind"`
+	SSLSessionReuse     bool   `json:"sslSessionReuse"`
 }
 
+var variableRegex = regexp.MustCompile(`^\$[a-zA-Z0-9_]+$`)
+
 // Equal tests for equality between two Configuration types
 func (l1 *Config) Equal(l2 *Config) bool {
 	if l1 == l2 {
@@ -75,6 +86,12 @@ func (l1 *Config) Equal(l2 *Config) bool {
 	if l1.NextUpstreamTries != l2.NextUpstreamTries {
 		return false
 	}
+	if l1.NextUpstreamTimeout != l2.NextUpstreamTimeout {
+		return false
+	}
+	if l1.NextUpstreamBudget != l2.NextUpstreamBudget {
+		return false
+	}
 	if l1.RequestBuffering != l2.RequestBuffering {
 		return false
 	}
@@ -87,6 +104,12 @@ func (l1 *Config) Equal(l2 *Config) bool {
 	if l1.ProxyBuffering != l2.ProxyBuffering {
 		return false
 	}
+	if l1.ProxyBind != l2.ProxyBind {
+		return false
+	}
+	if l1.SSLSessionReuse != l2.SSLSessionReuse {
+		return false
+	}
 
 	return true
 }
@@ -150,6 +173,16 @@ func (a proxy) Parse(ing *extensions.Ingress) (interface{}, error) {
 		nut = defBackend.ProxyNextUpstreamTries
 	}
 
+	nuto, err := parser.GetIntAnnotation("proxy-next-upstream-timeout", ing)
+	if err != nil {
+		nuto = defBackend.ProxyNextUpstreamTimeout
+	}
+
+	nub, err := parser.GetIntAnnotation("proxy-next-upstream-budget", ing)
+	if err != nil || nub < 0 || nub > 100 {
+		nub = defBackend.ProxyNextUpstreamBudget
+	}
+
 	rb, err := parser.GetStringAnnotation("proxy-request-buffering", ing)
 	if err != nil || rb == "" {
 		rb = defBackend.ProxyRequestBuffering
@@ -170,5 +203,39 @@ func (a proxy) Parse(ing *extensions.Ingress) (interface{}, error) {
 		pb = defBackend.ProxyBuffering
 	}
 
-	return &Config{bs, ct, st, rt, bufs, cd, cp, nu, nut, prf, prt, rb, pb}, nil
+	pbi, err := parser.GetStringAnnotation("proxy-bind", ing)
+	if err != nil || pbi == "" {
+		pbi = defBackend.ProxyBind
+	} else if !isValidProxyBind(pbi) {
+		log.Warningf("%v is not a valid value for the proxy-bind annotation. Using the default value", pbi)
+		pbi = defBackend.ProxyBind
+	}
+
+	sr, err := parser.GetBoolAnnotation("proxy-ssl-session-reuse", ing)
+	if err != nil {
+		sr = defBackend.ProxySSLSessionReuse
+	}
+
+	return &Config{bs, ct, st, rt, bufs, cd, cp, nu, nut, nuto, nub, prf, prt, rb, pb, pbi, sr}, nil
+}
+
+// isValidProxyBind checks the value contains an IP address or a variable,
+// optionally followed by the transparent parameter, or is the value off
+// http://nginx.org/en/docs/http/ngx_http_proxy_module.html#proxy_bind
+func isValidProxyBind(val string) bool {
+	fields := strings.Fields(val)
+	switch len(fields) {
+	case 1:
+		if fields[0] == "off" {
+			return true
+		}
+	case 2:
+		if fields[1] != "transparent" {
+			return false
+		}
+	default:
+		return false
+	}
+
+	return net.ParseIP(fields[0]) != nil || variableRegex.MatchString(fields[0])
 }
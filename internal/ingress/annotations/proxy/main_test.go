@@ -79,6 +79,8 @@ func (m mockBackend) GetDefaultBackend() defaults.Backend {
 		ProxyNextUpstreamTries: 3,
 		ProxyRequestBuffering:  "on",
 		ProxyBuffering:         "off",
+		ProxyBind:              "10.0.0.1",
+		ProxySSLSessionReuse:   true,
 	}
 }
 
@@ -93,8 +95,12 @@ func TestProxy(t *testing.T) {
 	data[parser.GetAnnotationWithPrefix("proxy-body-size")] = "2k"
 	data[parser.GetAnnotationWithPrefix("proxy-next-upstream")] = "off"
 	data[parser.GetAnnotationWithPrefix("proxy-next-upstream-tries")] = "3"
+	data[parser.GetAnnotationWithPrefix("proxy-next-upstream-timeout")] = "30"
+	data[parser.GetAnnotationWithPrefix("proxy-next-upstream-budget")] = "20"
 	data[parser.GetAnnotationWithPrefix("proxy-request-buffering")] = "off"
 	data[parser.GetAnnotationWithPrefix("proxy-buffering")] = "on"
+	data[parser.GetAnnotationWithPrefix("proxy-bind")] = "$remote_addr transparent"
+	data[parser.GetAnnotationWithPrefix("proxy-ssl-session-reuse")] = "false"
 	ing.SetAnnotations(data)
 
 	i, err := NewParser(mockBackend{}).Parse(ing)
@@ -126,12 +132,24 @@ func TestProxy(t *testing.T) {
 	if p.NextUpstreamTries != 3 {
 		t.Errorf("expected 3 as next-upstream-tries but returned %v", p.NextUpstreamTries)
 	}
+	if p.NextUpstreamTimeout != 30 {
+		t.Errorf("expected 30 as next-upstream-timeout but returned %v", p.NextUpstreamTimeout)
+	}
+	if p.NextUpstreamBudget != 20 {
+		t.Errorf("expected 20 as next-upstream-budget but returned %v", p.NextUpstreamBudget)
+	}
 	if p.RequestBuffering != "off" {
 		t.Errorf("expected off as request-buffering but returned %v", p.RequestBuffering)
 	}
 	if p.ProxyBuffering != "on" {
 		t.Errorf("expected on as proxy-buffering but returned %v", p.ProxyBuffering)
 	}
+	if p.ProxyBind != "$remote_addr transparent" {
+		t.Errorf("expected $remote_addr transparent as proxy-bind but returned %v", p.ProxyBind)
+	}
+	if p.SSLSessionReuse {
+		t.Errorf("expected false as proxy-ssl-session-reuse but returned %v", p.SSLSessionReuse)
+	}
 }
 
 func TestProxyWithNoAnnotation(t *testing.T) {
@@ -172,4 +190,41 @@ func TestProxyWithNoAnnotation(t *testing.T) {
 	if p.RequestBuffering != "on" {
 		t.Errorf("expected on as request-buffering but returned %v", p.RequestBuffering)
 	}
+	if p.ProxyBind != "10.0.0.1" {
+		t.Errorf("expected 10.0.0.1 as proxy-bind but returned %v", p.ProxyBind)
+	}
+	if !p.SSLSessionReuse {
+		t.Errorf("expected true as proxy-ssl-session-reuse but returned %v", p.SSLSessionReuse)
+	}
+}
+
+func TestProxyBind(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected string
+	}{
+		{"off", "off"},
+		{"192.168.0.10", "192.168.0.10"},
+		{"2001:db8::1 transparent", "2001:db8::1 transparent"},
+		{"$remote_addr transparent", "$remote_addr transparent"},
+		{"$remote_addr permanent", "10.0.0.1"},
+		{"off transparent", "10.0.0.1"},
+		{"example.com", "10.0.0.1"},
+		{"10.0.0.2; return 200", "10.0.0.1"},
+	}
+
+	ing := buildIngress()
+	for _, tc := range testCases {
+		ing.SetAnnotations(map[string]string{parser.GetAnnotationWithPrefix("proxy-bind"): tc.value})
+
+		i, err := NewParser(mockBackend{}).Parse(ing)
+		if err != nil {
+			t.Fatalf("unexpected error parsing a valid")
+		}
+
+		p := i.(*Config)
+		if p.ProxyBind != tc.expected {
+			t.Errorf("expected %v as proxy-bind for %q but returned %v", tc.expected, tc.value, p.ProxyBind)
+		}
+	}
 }
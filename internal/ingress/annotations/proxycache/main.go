@@ -0,0 +1,149 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxycache
+
+import (
+	"regexp"
+	"strings"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+const defaultCacheKey = "$scheme$host$request_uri"
+
+var (
+	zoneRegex  = regexp.MustCompile(`^[a-zA-Z0-9_\-]+$`)
+	validRegex = regexp.MustCompile(`^((any|[1-5][0-9]{2})\s+)*[0-9]+[smhd]?$`)
+	// the key and the conditions are written in nginx.conf, so they only
+	// accept variables and characters that cannot end the directive
+	keyRegex    = regexp.MustCompile(`^[a-zA-Z0-9_$.:/?=&@\-]+$`)
+	bypassRegex = regexp.MustCompile(`^\$?[a-zA-Z0-9_]+(\s+\$?[a-zA-Z0-9_]+)*$`)
+)
+
+// Config contains the configuration to cache responses from the upstream
+// servers in one of the zones defined in the configuration configmap
+type Config struct {
+	// Zone is the name of the proxy_cache_path zone used to store responses
+	Zone string `json:"zone"`
+	// Valid contains the list of caching times, e.g. "200 302 10m"
+	Valid []string `json:"valid,omitempty"`
+	// Key defines the key used to store the response in the cache
+	Key string `json:"key"`
+	// Bypass contains the conditions under which the response will not be
+	// taken from the cache
+	Bypass string `json:"bypass"`
+	// Compressed stores a variant of the responses for each compression
+	// method (br, gzip or none) requested by the clients
+	Compressed bool `json:"compressed"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if c1.Zone != c2.Zone {
+		return false
+	}
+	if len(c1.Valid) != len(c2.Valid) {
+		return false
+	}
+	for i, v := range c1.Valid {
+		if v != c2.Valid[i] {
+			return false
+		}
+	}
+	if c1.Key != c2.Key {
+		return false
+	}
+	if c1.Bypass != c2.Bypass {
+		return false
+	}
+	if c1.Compressed != c2.Compressed {
+		return false
+	}
+
+	return true
+}
+
+type proxyCache struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new proxy cache annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return proxyCache{r}
+}
+
+// Parse parses the annotations contained in the ingress
+// rule used to enable caching of responses in the ingress controller
+func (a proxyCache) Parse(ing *extensions.Ingress) (interface{}, error) {
+	zone, err := parser.GetStringAnnotation("proxy-cache-zone", ing)
+	if err != nil {
+		return nil, err
+	}
+
+	if !zoneRegex.MatchString(zone) {
+		return nil, ing_errors.NewInvalidAnnotationContent("proxy-cache-zone", zone)
+	}
+
+	valid := []string{}
+	val, _ := parser.GetStringAnnotation("proxy-cache-valid", ing)
+	for _, v := range strings.Split(val, ",") {
+		v = strings.Join(strings.Fields(v), " ")
+		if v == "" {
+			continue
+		}
+
+		if !validRegex.MatchString(v) {
+			return nil, ing_errors.NewInvalidAnnotationContent("proxy-cache-valid", val)
+		}
+
+		valid = append(valid, v)
+	}
+
+	key, _ := parser.GetStringAnnotation("proxy-cache-key", ing)
+	if key == "" {
+		key = defaultCacheKey
+	}
+
+	if !keyRegex.MatchString(key) {
+		return nil, ing_errors.NewInvalidAnnotationContent("proxy-cache-key", key)
+	}
+
+	bypass, _ := parser.GetStringAnnotation("proxy-cache-bypass", ing)
+	if bypass != "" && !bypassRegex.MatchString(bypass) {
+		return nil, ing_errors.NewInvalidAnnotationContent("proxy-cache-bypass", bypass)
+	}
+
+	compressed, _ := parser.GetBoolAnnotation("proxy-cache-compressed", ing)
+
+	return &Config{
+		Zone:       zone,
+		Valid:      valid,
+		Key:        key,
+		Bypass:     bypass,
+		Compressed: compressed,
+	}, nil
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxycache
+
+import (
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func TestParse(t *testing.T) {
+	zone := parser.GetAnnotationWithPrefix("proxy-cache-zone")
+	valid := parser.GetAnnotationWithPrefix("proxy-cache-valid")
+	key := parser.GetAnnotationWithPrefix("proxy-cache-key")
+	bypass := parser.GetAnnotationWithPrefix("proxy-cache-bypass")
+	compressed := parser.GetAnnotationWithPrefix("proxy-cache-compressed")
+
+	ap := NewParser(&resolver.Mock{})
+	if ap == nil {
+		t.Fatalf("expected a parser.IngressAnnotation but returned nil")
+	}
+
+	testCases := []struct {
+		annotations map[string]string
+		expected    *Config
+	}{
+		{map[string]string{}, nil},
+		{map[string]string{zone: "static files"}, nil},
+		{map[string]string{zone: "static"}, &Config{Zone: "static", Valid: []string{}, Key: defaultCacheKey}},
+		{map[string]string{zone: "static", valid: "200  302 10m, 404 1m,any 30s"}, &Config{
+			Zone:  "static",
+			Valid: []string{"200 302 10m", "404 1m", "any 30s"},
+			Key:   defaultCacheKey,
+		}},
+		{map[string]string{zone: "static", valid: "10m 200"}, nil},
+		{map[string]string{zone: "static", key: "$host$uri", bypass: "$http_pragma $cookie_nocache"}, &Config{
+			Zone:   "static",
+			Valid:  []string{},
+			Key:    "$host$uri",
+			Bypass: "$http_pragma $cookie_nocache",
+		}},
+		{map[string]string{zone: "static", key: "$host$uri\"; return 200 \"owned"}, nil},
+		{map[string]string{zone: "static", key: "$host; allow all"}, nil},
+		{map[string]string{zone: "static", key: "$host${uri}"}, nil},
+		{map[string]string{zone: "static", key: "$host\n$uri"}, nil},
+		{map[string]string{zone: "static", bypass: "$http_pragma; return 200"}, nil},
+		{map[string]string{zone: "static", bypass: "\"$cookie_nocache\""}, nil},
+		{map[string]string{zone: "static", bypass: "$arg_nocache }"}, nil},
+		{map[string]string{zone: "static", key: "$scheme://$host:$server_port$request_uri", bypass: "$arg_nocache 1"}, &Config{
+			Zone:   "static",
+			Valid:  []string{},
+			Key:    "$scheme://$host:$server_port$request_uri",
+			Bypass: "$arg_nocache 1",
+		}},
+		{map[string]string{zone: "static", compressed: "true"}, &Config{
+			Zone:       "static",
+			Valid:      []string{},
+			Key:        defaultCacheKey,
+			Compressed: true,
+		}},
+	}
+
+	ing := &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: extensions.IngressSpec{},
+	}
+
+	for _, testCase := range testCases {
+		ing.SetAnnotations(testCase.annotations)
+		i, _ := ap.Parse(ing)
+		p, _ := i.(*Config)
+
+		if !p.Equal(testCase.expected) {
+			t.Errorf("expected %v but returned %v, annotations: %s", testCase.expected, p, testCase.annotations)
+		}
+	}
+}
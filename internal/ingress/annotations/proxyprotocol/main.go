@@ -0,0 +1,54 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxyprotocol
+
+import (
+	"strings"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+	"k8s.io/ingress-nginx/internal/log"
+)
+
+type proxyProtocol struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new parser of the annotation that sends the PROXY
+// protocol to the endpoints of the SSL passthrough servers
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return proxyProtocol{r}
+}
+
+// Parse parses the annotation proxy-protocol-to-backend, the version of the
+// PROXY protocol, v1 or v2. It returns an empty string when it is not set.
+func (a proxyProtocol) Parse(ing *extensions.Ingress) (interface{}, error) {
+	version, err := parser.GetStringAnnotation("proxy-protocol-to-backend", ing)
+	if err != nil {
+		return "", nil
+	}
+
+	version = strings.TrimSpace(strings.ToLower(version))
+	if version != "v1" && version != "v2" {
+		log.Warningf("%v is not a valid value for the proxy-protocol-to-backend annotation, valid values are v1 and v2", version)
+		return "", nil
+	}
+
+	return version, nil
+}
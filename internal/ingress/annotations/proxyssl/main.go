@@ -0,0 +1,204 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxyssl
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	apiv1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+var (
+	validProtocols = regexp.MustCompile(`^(SSLv2|SSLv3|TLSv1|TLSv1\.1|TLSv1\.2|TLSv1\.3)$`)
+	validName      = regexp.MustCompile(`^[A-Za-z0-9\-\.\*\$_]+$`)
+	validHostname  = regexp.MustCompile(`^[A-Za-z0-9\-\.]+$`)
+)
+
+// ServerName configures the server name sent with SNI to the servers
+// of a backend using HTTPS or GRPCS
+type ServerName struct {
+	// Enabled indicates if the server name is sent
+	Enabled bool `json:"enabled"`
+	// Name is the server name sent, the Host header sent to the
+	// backend when it is empty
+	Name string `json:"name,omitempty"`
+}
+
+// Equal tests for equality between two ServerName types
+func (sn1 *ServerName) Equal(sn2 *ServerName) bool {
+	if sn1 == sn2 {
+		return true
+	}
+	if sn1 == nil || sn2 == nil {
+		return false
+	}
+
+	return sn1.Enabled == sn2.Enabled && sn1.Name == sn2.Name
+}
+
+// Config contains the TLS configuration of the connections to HTTPS and
+// GRPCS upstream servers
+type Config struct {
+	// AuthSSLCert is the Secret with the trusted CA certificates, ca.crt,
+	// and optionally the client certificate, tls.crt and tls.key
+	resolver.AuthSSLCert
+	// ClientCertificate indicates if the client certificate of the Secret
+	// is presented to the upstream servers
+	ClientCertificate bool `json:"clientCertificate"`
+	// Verify indicates if the certificate of the upstream servers is
+	// verified with the CA certificates of the Secret
+	Verify bool `json:"verify"`
+	// Name is the server name used to verify the certificate of the upstream
+	// servers and sent with SNI, the host of the upstream by default
+	Name string `json:"name"`
+	// Protocols are the TLS protocols enabled, separated by spaces
+	Protocols string `json:"protocols"`
+	// ServerName is the SNI of the backend of the location, used by all
+	// the locations sending requests to the backend
+	ServerName ServerName `json:"serverName"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if !(&c1.AuthSSLCert).Equal(&c2.AuthSSLCert) {
+		return false
+	}
+	if c1.ClientCertificate != c2.ClientCertificate {
+		return false
+	}
+	if c1.Verify != c2.Verify {
+		return false
+	}
+	if c1.Name != c2.Name {
+		return false
+	}
+	if c1.Protocols != c2.Protocols {
+		return false
+	}
+	if !(&c1.ServerName).Equal(&c2.ServerName) {
+		return false
+	}
+
+	return true
+}
+
+type proxySSL struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new proxy SSL annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return proxySSL{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+// used to configure the TLS connections to the upstream servers
+func (a proxySSL) Parse(ing *extensions.Ingress) (interface{}, error) {
+	config := &Config{}
+
+	secret, err := parser.GetStringAnnotation("proxy-ssl-secret", ing)
+	if err == nil {
+		if !strings.Contains(secret, "/") {
+			secret = fmt.Sprintf("%v/%v", ing.Namespace, secret)
+		}
+
+		config.ClientCertificate, err = a.hasClientCertificate(secret)
+		if err != nil {
+			return nil, ing_errors.LocationDenied{Reason: err}
+		}
+
+		cert, err := a.r.GetAuthCertificate(secret)
+		if err != nil {
+			return nil, ing_errors.LocationDenied{Reason: errors.Wrap(err, "error obtaining certificate")}
+		}
+		if cert.CAFileName == "" {
+			return nil, ing_errors.LocationDenied{Reason: errors.Errorf("secret %v does not contain CA certificates in ca.crt", secret)}
+		}
+		config.AuthSSLCert = *cert
+	}
+
+	config.Verify, err = parser.GetBoolAnnotation("proxy-ssl-verify", ing)
+	if err == nil && config.Verify && config.CAFileName == "" {
+		// the upstream servers cannot be trusted without CA certificates
+		return nil, ing_errors.NewLocationDenied("proxy-ssl-verify requires the CA certificates of the annotation proxy-ssl-secret")
+	}
+
+	name, err := parser.GetStringAnnotation("proxy-ssl-name", ing)
+	if err == nil {
+		if !validName.MatchString(name) {
+			return nil, ing_errors.NewInvalidAnnotationContent("proxy-ssl-name", name)
+		}
+		config.Name = name
+	}
+
+	protocols, err := parser.GetStringAnnotation("proxy-ssl-protocols", ing)
+	if err == nil {
+		fields := strings.Fields(protocols)
+		for _, protocol := range fields {
+			if !validProtocols.MatchString(protocol) {
+				return nil, ing_errors.NewInvalidAnnotationContent("proxy-ssl-protocols", protocols)
+			}
+		}
+		config.Protocols = strings.Join(fields, " ")
+	}
+
+	serverName, err := parser.GetStringAnnotation("proxy-ssl-server-name", ing)
+	if err == nil {
+		switch {
+		case serverName == "true":
+			config.ServerName.Enabled = true
+		case serverName == "false":
+		case validHostname.MatchString(serverName):
+			config.ServerName = ServerName{Enabled: true, Name: serverName}
+		default:
+			return nil, ing_errors.NewInvalidAnnotationContent("proxy-ssl-server-name", serverName)
+		}
+	}
+
+	return config, nil
+}
+
+// hasClientCertificate returns true if a Secret contains a client
+// certificate with its key besides the CA certificates
+func (a proxySSL) hasClientCertificate(name string) (bool, error) {
+	secret, err := a.r.GetSecret(name)
+	if err != nil {
+		return false, errors.Wrapf(err, "unexpected error reading secret %v", name)
+	}
+	if secret == nil {
+		return false, errors.Errorf("secret %v does not exist", name)
+	}
+
+	_, okcert := secret.Data[apiv1.TLSCertKey]
+	_, okkey := secret.Data[apiv1.TLSPrivateKeyKey]
+
+	return okcert && okkey, nil
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxyssl
+
+import (
+	"fmt"
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+type mockSecret struct {
+	resolver.Mock
+}
+
+// GetSecret returns a Secret with a client certificate and one with
+// only CA certificates
+func (m mockSecret) GetSecret(name string) (*api.Secret, error) {
+	switch name {
+	case "default/client":
+		return &api.Secret{
+			Data: map[string][]byte{"tls.crt": []byte("crt"), "tls.key": []byte("key"), "ca.crt": []byte("ca")},
+		}, nil
+	case "other/ca":
+		return &api.Secret{
+			Data: map[string][]byte{"ca.crt": []byte("ca")},
+		}, nil
+	}
+
+	return nil, fmt.Errorf("secret %v not found", name)
+}
+
+func (m mockSecret) GetAuthCertificate(name string) (*resolver.AuthSSLCert, error) {
+	if _, err := m.GetSecret(name); err != nil {
+		return nil, err
+	}
+
+	return &resolver.AuthSSLCert{
+		Secret:     name,
+		CAFileName: "/etc/ingress-controller/ssl/" + name + ".pem",
+		PemSHA:     "123",
+	}, nil
+}
+
+func buildIngress() *extensions.Ingress {
+	return &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+	}
+}
+
+func TestParse(t *testing.T) {
+	ap := NewParser(mockSecret{})
+
+	clientCert := resolver.AuthSSLCert{
+		Secret:     "default/client",
+		CAFileName: "/etc/ingress-controller/ssl/default/client.pem",
+		PemSHA:     "123",
+	}
+	caCert := resolver.AuthSSLCert{
+		Secret:     "other/ca",
+		CAFileName: "/etc/ingress-controller/ssl/other/ca.pem",
+		PemSHA:     "123",
+	}
+
+	tests := []struct {
+		annotations map[string]string
+		expected    *Config
+		denied      bool
+	}{
+		{map[string]string{}, &Config{}, false},
+		{map[string]string{"proxy-ssl-secret": "client"}, &Config{AuthSSLCert: clientCert, ClientCertificate: true}, false},
+		{map[string]string{"proxy-ssl-secret": "other/ca", "proxy-ssl-verify": "true"}, &Config{AuthSSLCert: caCert, Verify: true}, false},
+		{map[string]string{"proxy-ssl-secret": "missing"}, nil, true},
+		{map[string]string{"proxy-ssl-verify": "true"}, nil, true},
+		{map[string]string{"proxy-ssl-name": "backend.example.com"}, &Config{Name: "backend.example.com"}, false},
+		{map[string]string{"proxy-ssl-name": "$host"}, &Config{Name: "$host"}, false},
+		{map[string]string{"proxy-ssl-name": "backend; deny all"}, nil, false},
+		{map[string]string{"proxy-ssl-protocols": " TLSv1.2   TLSv1.3 "}, &Config{Protocols: "TLSv1.2 TLSv1.3"}, false},
+		{map[string]string{"proxy-ssl-protocols": "TLSv1.2 TLSv2"}, nil, false},
+		{map[string]string{"proxy-ssl-server-name": "true"}, &Config{ServerName: ServerName{Enabled: true}}, false},
+		{map[string]string{"proxy-ssl-server-name": "false"}, &Config{}, false},
+		{map[string]string{"proxy-ssl-server-name": "lb.example.com"}, &Config{ServerName: ServerName{Enabled: true, Name: "lb.example.com"}}, false},
+		{map[string]string{"proxy-ssl-server-name": "$host"}, nil, false},
+	}
+
+	for _, test := range tests {
+		ing := buildIngress()
+		data := map[string]string{}
+		for k, v := range test.annotations {
+			data[parser.GetAnnotationWithPrefix(k)] = v
+		}
+		ing.SetAnnotations(data)
+
+		i, err := ap.Parse(ing)
+		if test.expected == nil {
+			if err == nil {
+				t.Errorf("%v: expected an error but none returned", test.annotations)
+			} else if errors.IsLocationDenied(err) != test.denied {
+				t.Errorf("%v: expected a denied location to be %v but the error is %v", test.annotations, test.denied, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.annotations, err)
+			continue
+		}
+		if c := i.(*Config); !c.Equal(test.expected) {
+			t.Errorf("%v: expected %v but %v returned", test.annotations, test.expected, c)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queryrouting
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+var (
+	paramRegex   = regexp.MustCompile(`^[a-zA-Z0-9_\-]+$`)
+	routeRegex   = regexp.MustCompile(`^([a-zA-Z0-9_\-.~]+)=([a-z0-9]([-a-z0-9]*[a-z0-9])?):([a-zA-Z0-9\-]+)$`)
+	numericRegex = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// Route maps a value of the query parameter to a Service
+type Route struct {
+	Value       string             `json:"value"`
+	ServiceName string             `json:"serviceName"`
+	ServicePort intstr.IntOrString `json:"servicePort"`
+}
+
+// Config returns the configuration used to route requests
+// to different Services depending on a query parameter
+type Config struct {
+	Param  string  `json:"param"`
+	Routes []Route `json:"routes"`
+}
+
+type queryRouting struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new query routing annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return queryRouting{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+// used to route requests to a different Service when the query
+// parameter contains one of the values of the map, i.e.
+// query-routing-param: version
+// query-routing-map: v2=service-v2:80, v3=service-v3:http
+func (a queryRouting) Parse(ing *extensions.Ingress) (interface{}, error) {
+	param, err := parser.GetStringAnnotation("query-routing-param", ing)
+	if err != nil {
+		return nil, err
+	}
+
+	if !paramRegex.MatchString(param) {
+		return nil, errors.NewInvalidAnnotationContent("query-routing-param", param)
+	}
+
+	routesMap, err := parser.GetStringAnnotation("query-routing-map", ing)
+	if err != nil {
+		return nil, errors.NewInvalidAnnotationConfiguration("query-routing-param", "query-routing-map is required")
+	}
+
+	config := &Config{
+		Param:  param,
+		Routes: []Route{},
+	}
+
+	values := map[string]bool{}
+	for _, route := range strings.Split(routesMap, ",") {
+		route = strings.TrimSpace(route)
+		if route == "" {
+			continue
+		}
+
+		m := routeRegex.FindStringSubmatch(route)
+		if m == nil {
+			return nil, errors.NewInvalidAnnotationContent("query-routing-map", route)
+		}
+
+		if values[m[1]] {
+			return nil, errors.NewInvalidAnnotationConfiguration("query-routing-map", fmt.Sprintf("duplicated value %v", m[1]))
+		}
+		values[m[1]] = true
+
+		port := intstr.FromString(m[4])
+		if numericRegex.MatchString(m[4]) {
+			port = intstr.Parse(m[4])
+		}
+
+		config.Routes = append(config.Routes, Route{
+			Value:       m[1],
+			ServiceName: m[2],
+			ServicePort: port,
+		})
+	}
+
+	if len(config.Routes) == 0 {
+		return nil, errors.NewInvalidAnnotationConfiguration("query-routing-map", "no routes defined")
+	}
+
+	return config, nil
+}
@@ -30,11 +30,12 @@ import (
 )
 
 const (
-	// allow 5 times the specified limit as burst
+	// allow 5 times the specified limit as burst, unless the
+	// limit-rps-burst-multiplier annotation is present
 	defBurst = 5
 
 	// 1MB -> 16 thousand 64-byte states or about 8 thousand 128-byte states
-	// default is 5MB
+	// default is 5MB, unless the limit-conn-zone-size ConfigMap key is present
 	defSharedSize = 5
 )
 
@@ -166,6 +167,16 @@ func (a ratelimit) Parse(ing *extensions.Ingress) (interface{}, error) {
 		lra = defBackend.LimitRateAfter
 	}
 
+	sharedSize := defBackend.LimitConnZoneSize
+	if sharedSize <= 0 {
+		sharedSize = defSharedSize
+	}
+
+	burstMultiplier, err := parser.GetIntAnnotation("limit-rps-burst-multiplier", ing)
+	if err != nil || burstMultiplier <= 0 {
+		burstMultiplier = defBurst
+	}
+
 	rpm, _ := parser.GetIntAnnotation("limit-rpm", ing)
 	rps, _ := parser.GetIntAnnotation("limit-rps", ing)
 	conn, _ := parser.GetIntAnnotation("limit-connections", ing)
@@ -194,19 +205,19 @@ func (a ratelimit) Parse(ing *extensions.Ingress) (interface{}, error) {
 			Name:       fmt.Sprintf("%v_conn", zoneName),
 			Limit:      conn,
 			Burst:      conn * defBurst,
-			SharedSize: defSharedSize,
+			SharedSize: sharedSize,
 		},
 		RPS: Zone{
 			Name:       fmt.Sprintf("%v_rps", zoneName),
 			Limit:      rps,
-			Burst:      rps * defBurst,
-			SharedSize: defSharedSize,
+			Burst:      rps * burstMultiplier,
+			SharedSize: sharedSize,
 		},
 		RPM: Zone{
 			Name:       fmt.Sprintf("%v_rpm", zoneName),
 			Limit:      rpm,
-			Burst:      rpm * defBurst,
-			SharedSize: defSharedSize,
+			Burst:      rpm * burstMultiplier,
+			SharedSize: sharedSize,
 		},
 		LimitRate:      lr,
 		LimitRateAfter: lra,
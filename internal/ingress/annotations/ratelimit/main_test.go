@@ -130,3 +130,54 @@ func TestBadRateLimiting(t *testing.T) {
 		t.Errorf("expected 10 in limit by limitrate but %v was returend", rateLimit.LimitRate)
 	}
 }
+
+type mockZoneSizeBackend struct {
+	resolver.Mock
+}
+
+func (m mockZoneSizeBackend) GetDefaultBackend() defaults.Backend {
+	return defaults.Backend{
+		LimitConnZoneSize: 10,
+	}
+}
+
+func TestRateLimitBurstMultiplier(t *testing.T) {
+	ing := buildIngress()
+
+	data := map[string]string{}
+	data[parser.GetAnnotationWithPrefix("limit-connections")] = "5"
+	data[parser.GetAnnotationWithPrefix("limit-rps")] = "100"
+	data[parser.GetAnnotationWithPrefix("limit-rpm")] = "10"
+	data[parser.GetAnnotationWithPrefix("limit-rps-burst-multiplier")] = "2"
+	ing.SetAnnotations(data)
+
+	i, err := NewParser(mockZoneSizeBackend{}).Parse(ing)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	rateLimit, ok := i.(*Config)
+	if !ok {
+		t.Errorf("expected a RateLimit type")
+	}
+	if rateLimit.RPS.Burst != 200 {
+		t.Errorf("expected 200 in burst by rps but %v was returned", rateLimit.RPS.Burst)
+	}
+	if rateLimit.RPM.Burst != 20 {
+		t.Errorf("expected 20 in burst by rpm but %v was returned", rateLimit.RPM.Burst)
+	}
+	if rateLimit.Connections.SharedSize != 10 {
+		t.Errorf("expected 10 in the size of the connections zone but %v was returned", rateLimit.Connections.SharedSize)
+	}
+
+	data[parser.GetAnnotationWithPrefix("limit-rps-burst-multiplier")] = "0"
+	ing.SetAnnotations(data)
+
+	i, _ = NewParser(mockBackend{}).Parse(ing)
+	rateLimit = i.(*Config)
+	if rateLimit.RPS.Burst != 500 {
+		t.Errorf("expected 500 in burst by rps but %v was returned", rateLimit.RPS.Burst)
+	}
+	if rateLimit.RPS.SharedSize != defSharedSize {
+		t.Errorf("expected %v in the size of the rps zone but %v was returned", defSharedSize, rateLimit.RPS.SharedSize)
+	}
+}
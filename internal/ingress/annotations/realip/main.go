@@ -0,0 +1,115 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package realip
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ing_errors "k8s.io/ingress-nginx/internal/ingress/errors"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+	"k8s.io/ingress-nginx/internal/net"
+)
+
+var headerRegex = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+
+// Config contains the addresses of the proxies trusted to send the client
+// address of a server and the header where they send it
+type Config struct {
+	CIDRs  []string `json:"cidrs,omitempty"`
+	Header string   `json:"header,omitempty"`
+}
+
+// Equal tests for equality between two Config types
+func (c1 *Config) Equal(c2 *Config) bool {
+	if c1 == c2 {
+		return true
+	}
+	if c1 == nil || c2 == nil {
+		return false
+	}
+	if c1.Header != c2.Header {
+		return false
+	}
+	if len(c1.CIDRs) != len(c2.CIDRs) {
+		return false
+	}
+	for i := range c1.CIDRs {
+		if c1.CIDRs[i] != c2.CIDRs[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// IsEmpty returns true if the server uses the global configuration
+func (c Config) IsEmpty() bool {
+	return len(c.CIDRs) == 0 && c.Header == ""
+}
+
+type realIP struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new parser of the annotations that override the
+// proxy-real-ip-cidr and forwarded-for-header of the ConfigMap in a server
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return realIP{r}
+}
+
+// Parse parses the annotations proxy-real-ip-cidr, a comma separated list
+// of addresses and networks like `173.245.48.0/20,2400:cb00::/32`, and
+// forwarded-for-header, the header with the client address
+func (a realIP) Parse(ing *extensions.Ingress) (interface{}, error) {
+	cidrs, cidrsErr := parser.GetStringAnnotation("proxy-real-ip-cidr", ing)
+	header, headerErr := parser.GetStringAnnotation("forwarded-for-header", ing)
+	if cidrsErr != nil && headerErr != nil {
+		return nil, ing_errors.ErrMissingAnnotations
+	}
+
+	config := &Config{}
+
+	if cidrsErr == nil {
+		ipnets, ips, err := net.ParseIPNets(strings.Split(cidrs, ",")...)
+		if err != nil {
+			return nil, ing_errors.NewInvalidAnnotationContent("proxy-real-ip-cidr", cidrs)
+		}
+
+		for k := range ipnets {
+			config.CIDRs = append(config.CIDRs, k)
+		}
+		for k := range ips {
+			config.CIDRs = append(config.CIDRs, k)
+		}
+		sort.Strings(config.CIDRs)
+	}
+
+	if headerErr == nil {
+		header = strings.TrimSpace(header)
+		if !headerRegex.MatchString(header) {
+			return nil, ing_errors.NewInvalidAnnotationContent("forwarded-for-header", header)
+		}
+		config.Header = header
+	}
+
+	return config, nil
+}
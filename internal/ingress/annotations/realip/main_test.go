@@ -0,0 +1,75 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package realip
+
+import (
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func TestParse(t *testing.T) {
+	cidrs := parser.GetAnnotationWithPrefix("proxy-real-ip-cidr")
+	header := parser.GetAnnotationWithPrefix("forwarded-for-header")
+
+	ap := NewParser(&resolver.Mock{})
+	if ap == nil {
+		t.Fatalf("expected a parser.IngressAnnotation but returned nil")
+	}
+
+	testCases := []struct {
+		annotations map[string]string
+		expected    *Config
+	}{
+		{map[string]string{}, nil},
+		{map[string]string{cidrs: "10.0.0.0/33"}, nil},
+		{map[string]string{header: "CF Connecting IP"}, nil},
+		{map[string]string{cidrs: "2400:cb00::/32, 173.245.48.0/20,10.0.0.1"}, &Config{
+			CIDRs: []string{"10.0.0.1", "173.245.48.0/20", "2400:cb00::/32"},
+		}},
+		{map[string]string{header: " CF-Connecting-IP "}, &Config{
+			Header: "CF-Connecting-IP",
+		}},
+		{map[string]string{cidrs: "172.16.0.0/12", header: "X-Forwarded-For"}, &Config{
+			CIDRs:  []string{"172.16.0.0/12"},
+			Header: "X-Forwarded-For",
+		}},
+	}
+
+	ing := &extensions.Ingress{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      "foo",
+			Namespace: api.NamespaceDefault,
+		},
+		Spec: extensions.IngressSpec{},
+	}
+
+	for _, testCase := range testCases {
+		ing.SetAnnotations(testCase.annotations)
+		i, _ := ap.Parse(ing)
+		p, _ := i.(*Config)
+
+		if !p.Equal(testCase.expected) {
+			t.Errorf("expected %v but returned %v, annotations: %s", testCase.expected, p, testCase.annotations)
+		}
+	}
+}
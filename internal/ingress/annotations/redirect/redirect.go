@@ -30,6 +30,11 @@ import (
 
 const defaultPermanentRedirectCode = http.StatusMovedPermanently
 
+const defaultTemporalRedirectCode = http.StatusFound
+
+// validTemporalRedirectCodes are the status codes of temporary redirects
+var validTemporalRedirectCodes = []int{http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect}
+
 // Config returns the redirect configuration for an Ingress rule
 type Config struct {
 	URL       string `json:"url"`
@@ -63,9 +68,18 @@ func (r redirect) Parse(ing *extensions.Ingress) (interface{}, error) {
 			return nil, err
 		}
 
+		trc, err := parser.GetIntAnnotation("temporal-redirect-code", ing)
+		if err != nil && !errors.IsMissingAnnotations(err) {
+			return nil, err
+		}
+
+		if !isValidTemporalRedirectCode(trc) {
+			trc = defaultTemporalRedirectCode
+		}
+
 		return &Config{
 			URL:       tr,
-			Code:      http.StatusFound,
+			Code:      trc,
 			FromToWWW: r3w,
 		}, nil
 	}
@@ -115,6 +129,15 @@ func (r1 *Config) Equal(r2 *Config) bool {
 	return true
 }
 
+func isValidTemporalRedirectCode(code int) bool {
+	for _, c := range validTemporalRedirectCodes {
+		if code == c {
+			return true
+		}
+	}
+	return false
+}
+
 func isValidURL(s string) error {
 	u, err := url.Parse(s)
 	if err != nil {
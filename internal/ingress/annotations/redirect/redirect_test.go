@@ -99,3 +99,47 @@ func TestPermanentRedirectWithCustomCode(t *testing.T) {
 		})
 	}
 }
+
+func TestTemporalRedirectWithCustomCode(t *testing.T) {
+	rp := NewParser(resolver.Mock{})
+	if rp == nil {
+		t.Fatalf("Expected a parser.IngressAnnotation but returned nil")
+	}
+
+	testCases := map[string]struct {
+		input        string
+		expectOutput int
+	}{
+		"default code":   {"", defaultTemporalRedirectCode},
+		"valid code":     {strconv.Itoa(http.StatusTemporaryRedirect), http.StatusTemporaryRedirect},
+		"permanent code": {strconv.Itoa(http.StatusMovedPermanently), defaultTemporalRedirectCode},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			ing := new(extensions.Ingress)
+
+			data := make(map[string]string, 2)
+			data[parser.GetAnnotationWithPrefix("temporal-redirect")] = defRedirectURL
+			if tc.input != "" {
+				data[parser.GetAnnotationWithPrefix("temporal-redirect-code")] = tc.input
+			}
+			ing.SetAnnotations(data)
+
+			i, err := rp.Parse(ing)
+			if err != nil {
+				t.Errorf("Unexpected error with ingress: %v", err)
+			}
+			redirect, ok := i.(*Config)
+			if !ok {
+				t.Fatalf("Expected a redirect Config type")
+			}
+			if redirect.URL != defRedirectURL {
+				t.Errorf("Expected %v as redirect but returned %s", defRedirectURL, redirect.URL)
+			}
+			if redirect.Code != tc.expectOutput {
+				t.Errorf("Expected %v as redirect to have a code %d but had %d", defRedirectURL, tc.expectOutput, redirect.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sizemetrics
+
+import (
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+type sizeMetrics struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new parser of the annotation that enables the
+// metrics of the size of the request and response bodies
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return sizeMetrics{r}
+}
+
+// Parse parses the annotation enable-size-metrics
+func (sm sizeMetrics) Parse(ing *extensions.Ingress) (interface{}, error) {
+	return parser.GetBoolAnnotation("enable-size-metrics", ing)
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"k8s.io/ingress-nginx/internal/ingress"
+)
+
+// oidcCredentials are the credentials of an OpenID Connect client. They are
+// sent to NGINX through the dynamic configuration, instead of being written
+// in nginx.conf, and read by Lua using the name of their secret.
+type oidcCredentials struct {
+	ClientID      string `json:"client_id"`
+	ClientSecret  string `json:"client_secret"`
+	SessionSecret string `json:"session_secret"`
+}
+
+// buildOIDCCredentials returns the credentials used by the locations
+// with OpenID Connect authentication, indexed by secret
+func buildOIDCCredentials(servers []*ingress.Server) map[string]oidcCredentials {
+	credentials := map[string]oidcCredentials{}
+	for _, server := range servers {
+		for _, location := range server.Locations {
+			cfg := location.AuthOIDC
+			if cfg.Discovery == "" {
+				continue
+			}
+
+			credentials[cfg.Secret] = oidcCredentials{
+				ClientID:      cfg.ClientID,
+				ClientSecret:  cfg.ClientSecret,
+				SessionSecret: cfg.SessionSecret,
+			}
+		}
+	}
+
+	return credentials
+}
+
+// configureOIDCCredentials POSTs the credentials of the OpenID Connect
+// clients to an internal HTTP endpoint handled by Lua
+func configureOIDCCredentials(credentials map[string]oidcCredentials) error {
+	return post("/configuration/oidc", credentials)
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/authoidc"
+)
+
+func TestBuildOIDCCredentials(t *testing.T) {
+	servers := []*ingress.Server{
+		{
+			Hostname: "foo.bar",
+			Locations: []*ingress.Location{
+				{Path: "/"},
+				{Path: "/app", AuthOIDC: authoidc.Config{
+					Discovery:     "https://accounts.example.com",
+					ClientID:      "app",
+					ClientSecret:  "s3cr3t",
+					SessionSecret: "0123456789",
+					Secret:        "default/oidc",
+				}},
+			},
+		},
+	}
+
+	expected := map[string]oidcCredentials{
+		"default/oidc": {ClientID: "app", ClientSecret: "s3cr3t", SessionSecret: "0123456789"},
+	}
+
+	credentials := buildOIDCCredentials(servers)
+	if !reflect.DeepEqual(credentials, expected) {
+		t.Errorf("expected the credentials %v but returned %v", expected, credentials)
+	}
+}
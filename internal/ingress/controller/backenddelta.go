@@ -0,0 +1,105 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+)
+
+// postedBackends are the backends posted to NGINX, encoded in JSON and
+// indexed by name, and the checksum of all of them
+type postedBackends struct {
+	checksum string
+	backends map[string][]byte
+	// oidcCredentials indicates credentials of OpenID Connect clients
+	// were posted with the backends
+	oidcCredentials bool
+}
+
+// encodeBackends encodes a list of backends in JSON. The checksum is the
+// MD5 sum of the encoded list, also returned.
+func encodeBackends(backends []*ingress.Backend) (*postedBackends, []byte, error) {
+	posted := &postedBackends{
+		backends: make(map[string][]byte, len(backends)),
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, backend := range backends {
+		data, err := json.Marshal(backend)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(data)
+		posted.backends[backend.Name] = data
+	}
+	buf.WriteByte(']')
+
+	posted.checksum = fmt.Sprintf("%x", md5.Sum(buf.Bytes()))
+	return posted, buf.Bytes(), nil
+}
+
+// backendsDelta is the change of the backends posted to NGINX, applied
+// by Lua only when its backends have the checksum Base
+type backendsDelta struct {
+	Base     string            `json:"base"`
+	Checksum string            `json:"checksum"`
+	Update   []json.RawMessage `json:"update"`
+	Remove   []string          `json:"remove"`
+}
+
+// deltaFrom returns the backends added, changed and removed since
+// the previous backends were posted
+func (p *postedBackends) deltaFrom(previous *postedBackends) *backendsDelta {
+	delta := &backendsDelta{
+		Base:     previous.checksum,
+		Checksum: p.checksum,
+		Update:   []json.RawMessage{},
+		Remove:   []string{},
+	}
+
+	names := make([]string, 0, len(p.backends))
+	for name := range p.backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !bytes.Equal(previous.backends[name], p.backends[name]) {
+			delta.Update = append(delta.Update, p.backends[name])
+		}
+	}
+
+	for name := range previous.backends {
+		if _, ok := p.backends[name]; !ok {
+			delta.Remove = append(delta.Remove, name)
+		}
+	}
+	sort.Strings(delta.Remove)
+
+	return delta
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+)
+
+func TestBackendsDelta(t *testing.T) {
+	previous, buf, err := encodeBackends([]*ingress.Backend{
+		{Name: "a"},
+		{Name: "b", Endpoints: []ingress.Endpoint{{Address: "10.0.0.1", Port: "80"}}},
+		{Name: "c"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var decoded []*ingress.Backend
+	if err := json.Unmarshal(buf, &decoded); err != nil || len(decoded) != 3 {
+		t.Errorf("expected a JSON list of backends but returned %s", buf)
+	}
+	if previous.checksum != fmt.Sprintf("%x", md5.Sum(buf)) {
+		t.Errorf("expected the checksum of the encoded backends but %v returned", previous.checksum)
+	}
+
+	posted, _, err := encodeBackends([]*ingress.Backend{
+		{Name: "a"},
+		{Name: "b", Endpoints: []ingress.Endpoint{{Address: "10.0.0.2", Port: "80"}}},
+		{Name: "d"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	delta := posted.deltaFrom(previous)
+	if delta.Base != previous.checksum || delta.Checksum != posted.checksum {
+		t.Errorf("expected the delta from %v to %v but returned %v to %v", previous.checksum, posted.checksum, delta.Base, delta.Checksum)
+	}
+
+	var updated []string
+	for _, data := range delta.Update {
+		b := &ingress.Backend{}
+		json.Unmarshal(data, b)
+		updated = append(updated, b.Name)
+	}
+	if !reflect.DeepEqual(updated, []string{"b", "d"}) {
+		t.Errorf("expected the backends b and d to be updated but returned %v", updated)
+	}
+	if !reflect.DeepEqual(delta.Remove, []string{"c"}) {
+		t.Errorf("expected the backend c to be removed but returned %v", delta.Remove)
+	}
+}
+
+func TestConfigureDynamicallyDelta(t *testing.T) {
+	var paths []string
+	conflict := true
+	stop := newConfigurationServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		paths = append(paths, r.URL.Path)
+
+		if r.URL.Path == "/configuration/backends/delta" && conflict {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer stop()
+
+	cfg := &ingress.Configuration{Backends: []*ingress.Backend{{Name: "a"}}}
+	previous, err := configureDynamically(cfg, nil, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// NGINX lost the backends
+	if _, err := configureDynamically(cfg, previous, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	conflict = false
+	if _, err := configureDynamically(cfg, previous, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"/configuration/backends",
+		"/configuration/backends/delta",
+		"/configuration/backends",
+		"/configuration/backends/delta",
+	}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected the requests %v but returned %v", expected, paths)
+	}
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+)
+
+// AnnotationBundle contains the annotations of the Ingresses handled by the
+// controller. The names of the annotations do not include the prefix.
+type AnnotationBundle struct {
+	Ingresses []IngressAnnotations `json:"ingresses"`
+}
+
+// IngressAnnotations contains the annotations of an Ingress
+type IngressAnnotations struct {
+	Namespace   string            `json:"namespace"`
+	Name        string            `json:"name"`
+	Annotations map[string]string `json:"annotations"`
+	// Effective contains the values of the parsed annotations applied to
+	// the Ingress, including the defaults, indexed by the name of the parser
+	// +optional
+	Effective map[string]interface{} `json:"effective,omitempty"`
+	// Unknown contains the names of the annotations with the prefix of the
+	// controller not read by any parser, like misspelled annotations
+	// +optional
+	Unknown []string `json:"unknown,omitempty"`
+	// Errors contains the errors found in the validation of the annotations,
+	// indexed by the name of the parser
+	// +optional
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// ExportAnnotations returns the annotations with the prefix of the controller
+// defined in the Ingresses handled by the controller, and their effective
+// values, sorted by namespace and name.
+func (n *NGINXController) ExportAnnotations() *AnnotationBundle {
+	prefix := parser.GetAnnotationWithPrefix("")
+
+	bundle := &AnnotationBundle{
+		Ingresses: []IngressAnnotations{},
+	}
+
+	for _, ing := range n.store.ListIngresses() {
+		anns := make(map[string]string)
+		for k, v := range ing.Annotations {
+			name := strings.TrimPrefix(k, prefix)
+			// the effective configuration is written by the controller
+			if name == k || name == annotations.EffectiveConfigAnnotation {
+				continue
+			}
+
+			anns[name] = v
+		}
+
+		if len(anns) == 0 {
+			continue
+		}
+
+		ia := IngressAnnotations{
+			Namespace:   ing.Namespace,
+			Name:        ing.Name,
+			Annotations: anns,
+			Unknown:     annotations.UnknownAnnotations(ing),
+		}
+
+		pia, err := n.store.GetIngressAnnotations(fmt.Sprintf("%v/%v", ing.Namespace, ing.Name))
+		if err == nil {
+			ia.Effective = effectiveAnnotations(pia)
+		}
+
+		bundle.Ingresses = append(bundle.Ingresses, ia)
+	}
+
+	sort.SliceStable(bundle.Ingresses, func(i, j int) bool {
+		if bundle.Ingresses[i].Namespace != bundle.Ingresses[j].Namespace {
+			return bundle.Ingresses[i].Namespace < bundle.Ingresses[j].Namespace
+		}
+		return bundle.Ingresses[i].Name < bundle.Ingresses[j].Name
+	})
+
+	return bundle
+}
+
+// effectiveAnnotations returns the parsed annotations that are not empty,
+// indexed by the name of the parser
+func effectiveAnnotations(pia *annotations.Ingress) map[string]interface{} {
+	values := make(map[string]interface{})
+
+	v := reflect.ValueOf(*pia)
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.Anonymous || field.Name == annotations.DeniedKeyName {
+			continue
+		}
+
+		value := v.Field(i)
+		if reflect.DeepEqual(value.Interface(), reflect.Zero(field.Type).Interface()) {
+			continue
+		}
+
+		values[field.Name] = value.Interface()
+	}
+
+	return values
+}
+
+// ValidateAnnotations parses the annotations of each Ingress of a bundle,
+// without resolving the objects they reference, and sets the errors and the
+// unknown annotations found. Returns true if the annotations are valid and
+// known.
+func (n *NGINXController) ValidateAnnotations(bundle *AnnotationBundle) bool {
+	valid := true
+	for i, ia := range bundle.Ingresses {
+		ing := &extensions.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   ia.Namespace,
+				Name:        ia.Name,
+				Annotations: make(map[string]string, len(ia.Annotations)),
+			},
+		}
+
+		for k, v := range ia.Annotations {
+			ing.Annotations[parser.GetAnnotationWithPrefix(k)] = v
+		}
+
+		bundle.Ingresses[i].Unknown = annotations.UnknownAnnotations(ing)
+		if len(bundle.Ingresses[i].Unknown) > 0 {
+			valid = false
+		}
+
+		errs := n.annotations.Validate(ing)
+		if len(errs) == 0 {
+			bundle.Ingresses[i].Errors = nil
+			continue
+		}
+
+		valid = false
+		bundle.Ingresses[i].Errors = make(map[string]string, len(errs))
+		for name, err := range errs {
+			bundle.Ingresses[i].Errors[name] = fmt.Sprintf("%v", err)
+		}
+	}
+
+	return valid
+}
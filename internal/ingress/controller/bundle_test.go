@@ -0,0 +1,142 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/rewrite"
+	"k8s.io/ingress-nginx/internal/ingress/controller/store"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+type fakeIngressStore struct {
+	store.Storer
+	ingresses   []*extensions.Ingress
+	annotations map[string]*annotations.Ingress
+}
+
+func (fis fakeIngressStore) ListIngresses() []*extensions.Ingress {
+	return fis.ingresses
+}
+
+func (fis fakeIngressStore) GetIngressAnnotations(key string) (*annotations.Ingress, error) {
+	pia, ok := fis.annotations[key]
+	if !ok {
+		return nil, fmt.Errorf("ingress %v was not found", key)
+	}
+	return pia, nil
+}
+
+func TestExportAnnotations(t *testing.T) {
+	n := &NGINXController{
+		store: fakeIngressStore{
+			ingresses: []*extensions.Ingress{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "web",
+						Namespace: "shop",
+						Annotations: map[string]string{
+							"nginx.ingress.kubernetes.io/rewrite-target": "/",
+							"nginx.ingress.kubernetes.io/ssl-redirct":    "false",
+							"kubernetes.io/ingress.class":                "nginx",
+						},
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "api",
+						Namespace: "shop",
+						Annotations: map[string]string{
+							"nginx.ingress.kubernetes.io/enable-cors": "true",
+						},
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "plain",
+						Namespace: "default",
+					},
+				},
+			},
+			annotations: map[string]*annotations.Ingress{
+				"shop/web": {
+					Rewrite: rewrite.Config{Target: "/", SSLRedirect: true},
+				},
+			},
+		},
+	}
+
+	expected := &AnnotationBundle{
+		Ingresses: []IngressAnnotations{
+			{
+				Namespace:   "shop",
+				Name:        "api",
+				Annotations: map[string]string{"enable-cors": "true"},
+			},
+			{
+				Namespace:   "shop",
+				Name:        "web",
+				Annotations: map[string]string{"rewrite-target": "/", "ssl-redirct": "false"},
+				Effective: map[string]interface{}{
+					"Rewrite": rewrite.Config{Target: "/", SSLRedirect: true},
+				},
+				Unknown: []string{"ssl-redirct"},
+			},
+		},
+	}
+
+	bundle := n.ExportAnnotations()
+	if !reflect.DeepEqual(bundle, expected) {
+		t.Errorf("expected %v but got %v", expected, bundle)
+	}
+}
+
+func TestValidateAnnotations(t *testing.T) {
+	n := &NGINXController{
+		annotations: annotations.NewAnnotationExtractor(resolver.Mock{}),
+	}
+
+	bundle := &AnnotationBundle{
+		Ingresses: []IngressAnnotations{
+			{Namespace: "shop", Name: "api", Annotations: map[string]string{"enable-cors": "true"}},
+			{Namespace: "shop", Name: "web", Annotations: map[string]string{"allowed-methods": "GET,P@ST"}},
+			{Namespace: "shop", Name: "www", Annotations: map[string]string{"enable-cros": "true"}},
+		},
+	}
+
+	if n.ValidateAnnotations(bundle) {
+		t.Fatalf("expected the bundle to be invalid")
+	}
+
+	if len(bundle.Ingresses[0].Errors) != 0 {
+		t.Errorf("expected no errors in Ingress shop/api but got %v", bundle.Ingresses[0].Errors)
+	}
+
+	if _, ok := bundle.Ingresses[1].Errors["AllowedMethods"]; !ok {
+		t.Errorf("expected an error in the AllowedMethods annotation but got %v", bundle.Ingresses[1].Errors)
+	}
+
+	if len(bundle.Ingresses[2].Errors) != 0 || !reflect.DeepEqual(bundle.Ingresses[2].Unknown, []string{"enable-cros"}) {
+		t.Errorf("expected the unknown annotation enable-cros in Ingress shop/www but got %v", bundle.Ingresses[2])
+	}
+}
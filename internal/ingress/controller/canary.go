@@ -0,0 +1,97 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+
+	apiv1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/internal/log"
+)
+
+// defaultWeightTotal is the total weight of the traffic shaping policies
+// without canary-weight-total
+const defaultWeightTotal = 100
+
+// weightPercentage returns the percentage of the requests routed to an
+// alternative backend by the weight of its traffic shaping policy
+func weightPercentage(policy ingress.TrafficShapingPolicy) float64 {
+	total := policy.WeightTotal
+	if total <= 0 {
+		total = defaultWeightTotal
+	}
+
+	return policy.Weight * 100 / float64(total)
+}
+
+// addAlternativeBackend adds an alternative backend to a backend. An
+// alternative backend with weight is not added when the alternative backends
+// already added route by weight, together with it, more than all the
+// requests of the backend. The shadow backends, which receive a copy of the
+// requests, are added up apart from the ones serving the requests. The
+// Ingresses are processed from the oldest to the newest, so the canaries of
+// the newest Ingresses are the ones dropped.
+func addAlternativeBackend(backend, alternative *ingress.Backend, upstreams map[string]*ingress.Backend) error {
+	weight := weightPercentage(alternative.TrafficShapingPolicy)
+
+	total := weight
+	for _, name := range backend.AlternativeBackends {
+		if name == alternative.Name {
+			return nil
+		}
+
+		other, ok := upstreams[name]
+		if ok && other.TrafficShapingPolicy.Shadow == alternative.TrafficShapingPolicy.Shadow {
+			total += weightPercentage(other.TrafficShapingPolicy)
+		}
+	}
+
+	// tolerate the rounding of the weights, like 33.33 + 33.33 + 33.34
+	if weight > 0 && total > 100.000001 {
+		return fmt.Errorf("the canaries of backend %v would receive %.2f%% of its requests, the weight of the canary %v is too high",
+			backend.Name, total, alternative.Name)
+	}
+
+	backend.AlternativeBackends = append(backend.AlternativeBackends, alternative.Name)
+	return nil
+}
+
+// reportCanaryConflicts reports the canaries of an Ingress that were not
+// configured. The conflicts already reported in the previous
+// synchronization are only logged once.
+func (n *NGINXController) reportCanaryConflicts(ing *extensions.Ingress, conflicts []error, reported sets.String) {
+	ingKey := k8s.MetaNamespaceKey(ing)
+
+	for _, conflict := range conflicts {
+		msg := conflict.Error()
+		key := fmt.Sprintf("%v|%v", ingKey, msg)
+		reported.Insert(key)
+		if n.canaryConflicts.Has(key) {
+			continue
+		}
+
+		log.Warningf("Ingress %v: %v", ingKey, msg)
+		if n.recorder != nil {
+			n.recorder.Event(ing, apiv1.EventTypeWarning, "CanaryConflict", msg)
+		}
+	}
+}
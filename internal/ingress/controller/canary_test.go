@@ -0,0 +1,80 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+)
+
+func TestAddAlternativeBackend(t *testing.T) {
+	upstreams := map[string]*ingress.Backend{
+		"default-web-80": {Name: "default-web-80"},
+		"default-web-canary-a-80": {
+			Name:                 "default-web-canary-a-80",
+			NoServer:             true,
+			TrafficShapingPolicy: ingress.TrafficShapingPolicy{Weight: 60},
+		},
+		"default-web-canary-b-80": {
+			Name:                 "default-web-canary-b-80",
+			NoServer:             true,
+			TrafficShapingPolicy: ingress.TrafficShapingPolicy{Weight: 4000, WeightTotal: 10000},
+		},
+		"default-web-canary-c-80": {
+			Name:                 "default-web-canary-c-80",
+			NoServer:             true,
+			TrafficShapingPolicy: ingress.TrafficShapingPolicy{Weight: 0.01},
+		},
+		"default-web-shadow-80": {
+			Name:                 "default-web-shadow-80",
+			NoServer:             true,
+			TrafficShapingPolicy: ingress.TrafficShapingPolicy{Weight: 50, Shadow: true},
+		},
+		"default-web-header-80": {
+			Name:                 "default-web-header-80",
+			NoServer:             true,
+			TrafficShapingPolicy: ingress.TrafficShapingPolicy{Header: "X-Canary"},
+		},
+	}
+	backend := upstreams["default-web-80"]
+
+	for _, name := range []string{"default-web-canary-a-80", "default-web-canary-b-80", "default-web-canary-a-80", "default-web-header-80"} {
+		if err := addAlternativeBackend(backend, upstreams[name], upstreams); err != nil {
+			t.Errorf("unexpected error adding the alternative backend %v: %v", name, err)
+		}
+	}
+
+	if err := addAlternativeBackend(backend, upstreams["default-web-canary-c-80"], upstreams); err == nil {
+		t.Errorf("expected an error adding an alternative backend above the total weight")
+	}
+
+	// the shadow backends do not add up with the ones serving the requests
+	if err := addAlternativeBackend(backend, upstreams["default-web-shadow-80"], upstreams); err != nil {
+		t.Errorf("unexpected error adding the shadow backend: %v", err)
+	}
+
+	expected := []string{"default-web-canary-a-80", "default-web-canary-b-80", "default-web-header-80", "default-web-shadow-80"}
+	if len(backend.AlternativeBackends) != len(expected) {
+		t.Fatalf("expected the alternative backends %v but %v returned", expected, backend.AlternativeBackends)
+	}
+	for i, name := range expected {
+		if backend.AlternativeBackends[i] != name {
+			t.Errorf("expected the alternative backends %v but %v returned", expected, backend.AlternativeBackends)
+		}
+	}
+}
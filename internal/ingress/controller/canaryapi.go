@@ -0,0 +1,113 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/canary"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/log"
+)
+
+// CanaryWeight is the weight of a canary Ingress, read and changed at
+// runtime through the canary API
+type CanaryWeight struct {
+	// Weight of the requests routed to the canary, relative to WeightTotal
+	Weight float64 `json:"weight"`
+	// WeightTotal is the total of the weight, 100 if it is not set
+	// +optional
+	WeightTotal int `json:"weightTotal,omitempty"`
+}
+
+// canaryConfig returns the canary configuration of an Ingress. The errors
+// are API errors, like the ones returned by the API server.
+func (n *NGINXController) canaryConfig(namespace, name string) (*canary.Config, error) {
+	anns, err := n.store.GetIngressAnnotations(fmt.Sprintf("%v/%v", namespace, name))
+	if err != nil {
+		return nil, apierrors.NewNotFound(extensions.Resource("ingresses"), name)
+	}
+
+	if !anns.Canary.Enabled {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("Ingress %v/%v is not a canary", namespace, name))
+	}
+
+	return &anns.Canary, nil
+}
+
+// GetCanaryWeight returns the weight of a canary Ingress
+func (n *NGINXController) GetCanaryWeight(namespace, name string) (*CanaryWeight, error) {
+	cfg, err := n.canaryConfig(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CanaryWeight{
+		Weight:      cfg.Weight,
+		WeightTotal: cfg.WeightTotal,
+	}, nil
+}
+
+// SetCanaryWeight writes the weight of a canary Ingress in its annotations
+// canary-weight and canary-weight-total, applied like any change of the
+// Ingress. A weight without total removes the annotation canary-weight-total.
+func (n *NGINXController) SetCanaryWeight(namespace, name string, cw CanaryWeight) error {
+	if _, err := n.canaryConfig(namespace, name); err != nil {
+		return err
+	}
+
+	total := cw.WeightTotal
+	if total == 0 {
+		total = 100
+	}
+
+	if total < 0 {
+		return apierrors.NewBadRequest(fmt.Sprintf("invalid weight total %v, it must be greater than 0", cw.WeightTotal))
+	}
+
+	if cw.Weight < 0 || cw.Weight > float64(total) {
+		return apierrors.NewBadRequest(fmt.Sprintf("invalid weight %v, it must be between 0 and %v", cw.Weight, total))
+	}
+
+	// a null value removes the annotation in a merge patch
+	values := map[string]interface{}{
+		parser.GetAnnotationWithPrefix("canary-weight"):       strconv.FormatFloat(cw.Weight, 'f', -1, 64),
+		parser.GetAnnotationWithPrefix("canary-weight-total"): nil,
+	}
+	if cw.WeightTotal > 0 {
+		values[parser.GetAnnotationWithPrefix("canary-weight-total")] = strconv.Itoa(cw.WeightTotal)
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": values,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Setting the weight of canary Ingress %v/%v to %v of %v", namespace, name, cw.Weight, total)
+	_, err = n.cfg.Client.ExtensionsV1beta1().Ingresses(namespace).Patch(name, types.MergePatchType, patch)
+	return err
+}
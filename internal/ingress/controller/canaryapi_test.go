@@ -0,0 +1,106 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/canary"
+)
+
+func TestSetCanaryWeight(t *testing.T) {
+	canaryIng := newEffectiveConfigIngress("canary", "/")
+	canaryIng.Annotations = map[string]string{
+		"nginx.ingress.kubernetes.io/canary":              "true",
+		"nginx.ingress.kubernetes.io/canary-weight":       "10",
+		"nginx.ingress.kubernetes.io/canary-weight-total": "1000",
+	}
+	web := newEffectiveConfigIngress("web", "/")
+
+	client := fake.NewSimpleClientset(canaryIng, web)
+	n := &NGINXController{
+		cfg: &Configuration{Client: client},
+		store: fakeAnnotationsStore{
+			annotations: map[string]*annotations.Ingress{
+				"default/canary": {Canary: canary.Config{Enabled: true, Weight: 10, WeightTotal: 1000}},
+				"default/web":    {},
+			},
+		},
+	}
+
+	cw, err := n.GetCanaryWeight("default", "canary")
+	if err != nil {
+		t.Fatalf("unexpected error getting the canary weight: %v", err)
+	}
+	if cw.Weight != 10 || cw.WeightTotal != 1000 {
+		t.Errorf("expected a weight of 10 of 1000 but %v of %v returned", cw.Weight, cw.WeightTotal)
+	}
+
+	if err := n.SetCanaryWeight("default", "canary", CanaryWeight{Weight: 12.5}); err != nil {
+		t.Fatalf("unexpected error setting the canary weight: %v", err)
+	}
+
+	ing, err := client.ExtensionsV1beta1().Ingresses("default").Get("canary", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error getting the Ingress: %v", err)
+	}
+	if v := ing.Annotations["nginx.ingress.kubernetes.io/canary-weight"]; v != "12.5" {
+		t.Errorf("expected the canary-weight annotation 12.5 but %q returned", v)
+	}
+
+	// the fake client merges the patch into the annotations without removing any
+	var patch string
+	for _, action := range client.Actions() {
+		if pa, ok := action.(k8stesting.PatchAction); ok {
+			patch = string(pa.GetPatch())
+		}
+	}
+	if !strings.Contains(patch, `"nginx.ingress.kubernetes.io/canary-weight-total":null`) {
+		t.Errorf("expected a patch removing the canary-weight-total annotation but %v returned", patch)
+	}
+	if v := ing.Annotations["nginx.ingress.kubernetes.io/canary"]; v != "true" {
+		t.Errorf("expected the canary annotation kept but %q returned", v)
+	}
+
+	testCases := []struct {
+		name   string
+		ing    string
+		weight CanaryWeight
+		check  func(error) bool
+	}{
+		{"not found", "other", CanaryWeight{Weight: 10}, apierrors.IsNotFound},
+		{"not a canary", "web", CanaryWeight{Weight: 10}, apierrors.IsBadRequest},
+		{"weight above the default total", "canary", CanaryWeight{Weight: 101}, apierrors.IsBadRequest},
+		{"weight above the total", "canary", CanaryWeight{Weight: 11, WeightTotal: 10}, apierrors.IsBadRequest},
+		{"negative weight", "canary", CanaryWeight{Weight: -1}, apierrors.IsBadRequest},
+		{"negative total", "canary", CanaryWeight{Weight: 1, WeightTotal: -10}, apierrors.IsBadRequest},
+	}
+
+	for _, tc := range testCases {
+		err := n.SetCanaryWeight("default", tc.ing, tc.weight)
+		if !tc.check(err) {
+			t.Errorf("%v: unexpected error setting the canary weight: %v", tc.name, err)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"fmt"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+)
+
+// chargebackNamespaceLabel aggregates the chargeback metrics
+// using the namespace of the Ingresses
+const chargebackNamespaceLabel = "namespace"
+
+// chargebackIDs returns the value of the chargeback label of each Ingress,
+// indexed by <namespace>/<name>. The label is the namespace of the Ingress
+// or the value of the annotation with the name of the label.
+func chargebackIDs(ings []*extensions.Ingress, label string) map[string]string {
+	ids := make(map[string]string, len(ings))
+	for _, ing := range ings {
+		key := fmt.Sprintf("%v/%v", ing.Namespace, ing.Name)
+
+		if label == chargebackNamespaceLabel {
+			ids[key] = ing.Namespace
+			continue
+		}
+
+		if id := ing.Annotations[label]; id != "" {
+			ids[key] = id
+		}
+	}
+
+	return ids
+}
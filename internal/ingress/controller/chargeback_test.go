@@ -0,0 +1,58 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestChargebackIDs(t *testing.T) {
+	ings := []*extensions.Ingress{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "web",
+				Namespace:   "shop",
+				Annotations: map[string]string{"example.com/billing-id": "team-a"},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "api",
+				Namespace: "shop",
+			},
+		},
+	}
+
+	testCases := []struct {
+		label    string
+		expected map[string]string
+	}{
+		{"namespace", map[string]string{"shop/web": "shop", "shop/api": "shop"}},
+		{"example.com/billing-id", map[string]string{"shop/web": "team-a"}},
+		{"example.com/cost-center", map[string]string{}},
+	}
+
+	for _, tc := range testCases {
+		ids := chargebackIDs(ings, tc.label)
+		if !reflect.DeepEqual(ids, tc.expected) {
+			t.Errorf("expected %v for label %v but returned %v", tc.expected, tc.label, ids)
+		}
+	}
+}
@@ -18,6 +18,7 @@ package controller
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"strconv"
 	"strings"
@@ -36,6 +37,9 @@ func (n NGINXController) Name() string {
 
 // Check returns if the nginx healthz endpoint is returning ok (status code 200)
 func (n *NGINXController) Check(_ *http.Request) error {
+	if n.isShuttingDown {
+		return fmt.Errorf("ingress controller is shutting down")
+	}
 
 	url := fmt.Sprintf("http://127.0.0.1:%v%v", n.cfg.ListenPorts.Status, ngxHealthPath)
 	timeout := n.cfg.HealthCheckTimeout
@@ -58,6 +62,11 @@ func (n *NGINXController) Check(_ *http.Request) error {
 		return fmt.Errorf("dynamic load balancer not started")
 	}
 
+	err = n.checkDynamicConfiguration(timeout)
+	if err != nil {
+		return err
+	}
+
 	// check the nginx master process is running
 	fs, err := proc.NewFS("/proc")
 	if err != nil {
@@ -76,22 +85,60 @@ func (n *NGINXController) Check(_ *http.Request) error {
 	return err
 }
 
+// checkDynamicConfiguration verifies the backends used by NGINX are the
+// last ones posted by the controller. NGINX and the controller diverge
+// when a dynamic reconfiguration fails or the shared dictionaries are lost.
+func (n *NGINXController) checkDynamicConfiguration(timeout time.Duration) error {
+	expected, _ := n.dynamicChecksum.Load().(string)
+	if expected == "" {
+		// the backends were not posted yet
+		return nil
+	}
+
+	client := configurationClient(timeout * time.Second)
+	statusCode, body, err := getBody(client, configurationURL("/configuration/checksum"))
+	if err != nil {
+		return err
+	}
+
+	if statusCode != 200 {
+		return fmt.Errorf("unexpected status code %v reading the dynamic configuration checksum", statusCode)
+	}
+
+	if body != expected {
+		return fmt.Errorf("dynamic configuration diverged: NGINX reports checksum %q, expected %q", body, expected)
+	}
+
+	return nil
+}
+
 func simpleGet(url string, timeout time.Duration) (int, error) {
 	client := &http.Client{
 		Timeout:   timeout * time.Second,
 		Transport: &http.Transport{DisableKeepAlives: true},
 	}
 
+	statusCode, _, err := getBody(client, url)
+	return statusCode, err
+}
+
+// getBody returns the status code and the body of a GET request
+func getBody(client *http.Client, url string) (int, string, error) {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return -1, err
+		return -1, "", err
 	}
 
 	res, err := client.Do(req)
 	if err != nil {
-		return -1, err
+		return -1, "", err
 	}
 	defer res.Body.Close()
 
-	return res.StatusCode, nil
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return -1, "", err
+	}
+
+	return res.StatusCode, string(body), nil
 }
@@ -34,11 +34,16 @@ import (
 func TestNginxCheck(t *testing.T) {
 	mux := http.NewServeMux()
 
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		fmt.Fprintf(w, "ok")
-	}))
+	})
+
+	server := httptest.NewServer(handler)
 	defer server.Close()
+	// the dynamic configuration is read through the Unix socket
+	stop := newConfigurationServer(t, handler)
+	defer stop()
 	// port to be used in the check
 	p := server.Listener.Addr().(*net.TCPAddr).Port
 
@@ -93,6 +98,29 @@ func TestNginxCheck(t *testing.T) {
 		}
 	})
 
+	t.Run("shutting down", func(t *testing.T) {
+		n.isShuttingDown = true
+		defer func() { n.isShuttingDown = false }()
+		if err := callHealthz(true, mux); err == nil {
+			t.Error("expected an error but none returned")
+		}
+	})
+
+	t.Run("dynamic configuration diverged", func(t *testing.T) {
+		n.dynamicChecksum.Store("abc")
+		if err := callHealthz(true, mux); err == nil {
+			t.Error("expected an error but none returned")
+		}
+	})
+
+	t.Run("dynamic configuration up to date", func(t *testing.T) {
+		// the test server returns "ok" as checksum
+		n.dynamicChecksum.Store("ok")
+		if err := callHealthz(false, mux); err != nil {
+			t.Error(err)
+		}
+	})
+
 	// pollute pid file
 	pidFile.Write([]byte(fmt.Sprint("999999")))
 	pidFile.Close()
@@ -0,0 +1,115 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+
+	"github.com/mitchellh/hashstructure"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+)
+
+// serverChecksum is the checksum of a server, reused by the next syncs
+// while the server does not change
+type serverChecksum struct {
+	server *ingress.Server
+	sum    uint64
+}
+
+// backendChecksum is the checksum of a backend, reused by the next syncs
+// while the backend does not change
+type backendChecksum struct {
+	backend *ingress.Backend
+	sum     uint64
+}
+
+// configChecksums keeps the checksums of the servers and backends of the
+// last configuration, indexed by hostname and name. Hashing each object is
+// much more expensive than comparing it, so only the objects that changed
+// since the last sync are hashed again. Only used in syncIngress.
+type configChecksums struct {
+	servers  map[string]serverChecksum
+	backends map[string]backendChecksum
+}
+
+// checksum returns the checksum of a configuration, combining the
+// checksums of its backends and servers in order with the one of the
+// rest of the configuration
+func (c *configChecksums) checksum(pcfg *ingress.Configuration) uint64 {
+	backends := make(map[string]backendChecksum, len(pcfg.Backends))
+	servers := make(map[string]serverChecksum, len(pcfg.Servers))
+
+	h := fnv.New64a()
+	sum := make([]byte, 8)
+	write := func(v uint64) {
+		binary.BigEndian.PutUint64(sum, v)
+		h.Write(sum)
+	}
+
+	write(uint64(len(pcfg.Backends)))
+	for _, backend := range pcfg.Backends {
+		cached, ok := c.backends[backend.Name]
+		if !ok || !equalExceptEndpoints(cached.backend, backend) {
+			cached.sum = hashObject(backend)
+		}
+
+		backends[backend.Name] = backendChecksum{backend: backend, sum: cached.sum}
+		write(cached.sum)
+	}
+
+	write(uint64(len(pcfg.Servers)))
+	for _, server := range pcfg.Servers {
+		cached, ok := c.servers[server.Hostname]
+		if !ok || !cached.server.Equal(server) {
+			cached.sum = hashObject(server)
+		}
+
+		servers[server.Hostname] = serverChecksum{server: server, sum: cached.sum}
+		write(cached.sum)
+	}
+
+	write(hashObject(&ingress.Configuration{
+		PassthroughBackends:   pcfg.PassthroughBackends,
+		BackendConfigChecksum: pcfg.BackendConfigChecksum,
+		RealIPRanges:          pcfg.RealIPRanges,
+	}))
+
+	// the objects removed from the configuration are forgotten
+	c.backends = backends
+	c.servers = servers
+
+	return h.Sum64()
+}
+
+// equalExceptEndpoints returns true if two backends only differ in their
+// endpoints, which are not part of the checksum of a backend (see
+// Backend.HashInclude) and change much more often than the rest
+func equalExceptEndpoints(b1, b2 *ingress.Backend) bool {
+	b := *b2
+	b.Endpoints = b1.Endpoints
+	return b1.Equal(&b)
+}
+
+// hashObject returns the checksum of an object encoded like in JSON
+func hashObject(obj interface{}) uint64 {
+	hash, _ := hashstructure.Hash(obj, &hashstructure.HashOptions{
+		TagName: "json",
+	})
+	return hash
+}
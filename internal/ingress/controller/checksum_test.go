@@ -0,0 +1,92 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+)
+
+func TestConfigChecksums(t *testing.T) {
+	newConfig := func(address string) *ingress.Configuration {
+		return &ingress.Configuration{
+			Backends: []*ingress.Backend{
+				{Name: "a", Endpoints: []ingress.Endpoint{{Address: address, Port: "80"}}},
+				{Name: "b"},
+			},
+			Servers: []*ingress.Server{
+				{Hostname: "foo.bar", Locations: []*ingress.Location{{Path: "/", Backend: "a"}}},
+			},
+			BackendConfigChecksum: "1",
+		}
+	}
+
+	c := &configChecksums{}
+	pcfg := newConfig("10.0.0.1")
+	sum := c.checksum(pcfg)
+
+	if c.checksum(newConfig("10.0.0.1")) != sum {
+		t.Errorf("expected the same checksum for the same configuration")
+	}
+
+	c.backends["b"] = backendChecksum{backend: pcfg.Backends[1], sum: 42}
+	c.checksum(newConfig("10.0.0.1"))
+	if c.backends["b"].sum != 42 {
+		t.Errorf("expected the checksum of the unchanged backend to be reused")
+	}
+	c.backends["b"] = backendChecksum{backend: pcfg.Backends[1], sum: hashObject(pcfg.Backends[1])}
+
+	changed := newConfig("10.0.0.2")
+	if c.checksum(changed) != sum {
+		t.Errorf("expected the same checksum when only an endpoint changes")
+	}
+	if c.backends["a"].backend != changed.Backends[0] {
+		t.Errorf("expected the backend with a new endpoint to replace the previous one")
+	}
+
+	changed = newConfig("10.0.0.1")
+	changed.Backends[0].LoadBalancing = "ewma"
+	if c.checksum(changed) == sum {
+		t.Errorf("expected a different checksum when a backend changes")
+	}
+	if c.checksum(newConfig("10.0.0.1")) != sum {
+		t.Errorf("expected the first checksum when the backend is restored")
+	}
+
+	changed = newConfig("10.0.0.1")
+	changed.Servers[0].Locations[0].Backend = "b"
+	if c.checksum(changed) == sum {
+		t.Errorf("expected a different checksum when a server changes")
+	}
+
+	changed = newConfig("10.0.0.1")
+	changed.BackendConfigChecksum = "2"
+	if c.checksum(changed) == sum {
+		t.Errorf("expected a different checksum when the backend configuration changes")
+	}
+
+	changed = newConfig("10.0.0.1")
+	changed.Backends = changed.Backends[:1]
+	changed.Servers = nil
+	if c.checksum(changed) == sum {
+		t.Errorf("expected a different checksum when objects are removed")
+	}
+	if _, ok := c.backends["b"]; ok || len(c.servers) != 0 {
+		t.Errorf("expected the checksums of the removed objects to be forgotten")
+	}
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"fmt"
+
+	apiv1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations/class"
+	"k8s.io/ingress-nginx/internal/log"
+)
+
+const (
+	// ClassConflictIgnore does not check the Ingresses of other classes
+	ClassConflictIgnore = "ignore"
+	// ClassConflictWarn reports the Ingresses with a host and path also
+	// defined in an Ingress of other class
+	ClassConflictWarn = "warn"
+	// ClassConflictSkip reports and does not configure the Ingresses with
+	// a host and path also defined in an Ingress of other class
+	ClassConflictSkip = "skip"
+)
+
+// classConflict is a host and path defined in an Ingress handled by the
+// controller and in an Ingress of other class
+type classConflict struct {
+	ingress *extensions.Ingress
+	other   *extensions.Ingress
+	host    string
+	path    string
+}
+
+func (c classConflict) String() string {
+	return fmt.Sprintf("%v/%v|%v/%v|%v%v", c.ingress.Namespace, c.ingress.Name,
+		c.other.Namespace, c.other.Name, c.host, c.path)
+}
+
+// hostPath is a path of a rule of an Ingress
+type hostPath struct {
+	host string
+	path string
+}
+
+// ingressPaths returns the host and path pairs defined in the rules of an Ingress
+func ingressPaths(ing *extensions.Ingress) []hostPath {
+	var paths []hostPath
+	for _, rule := range ing.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+
+		for _, path := range rule.HTTP.Paths {
+			p := path.Path
+			if p == "" {
+				p = "/"
+			}
+
+			paths = append(paths, hostPath{rule.Host, p})
+		}
+	}
+
+	return paths
+}
+
+// classConflicts returns the host and paths of the Ingresses ings also
+// defined in the Ingresses of other classes
+func classConflicts(ings, others []*extensions.Ingress) []classConflict {
+	claimed := make(map[hostPath]*extensions.Ingress)
+	for _, other := range others {
+		for _, hp := range ingressPaths(other) {
+			if _, ok := claimed[hp]; !ok {
+				claimed[hp] = other
+			}
+		}
+	}
+
+	var conflicts []classConflict
+	for _, ing := range ings {
+		for _, hp := range ingressPaths(ing) {
+			other, ok := claimed[hp]
+			if !ok {
+				continue
+			}
+
+			conflicts = append(conflicts, classConflict{
+				ingress: ing,
+				other:   other,
+				host:    hp.host,
+				path:    hp.path,
+			})
+		}
+	}
+
+	return conflicts
+}
+
+// checkClassConflicts reports the Ingresses with a host and path also defined
+// in an Ingress of other class and returns the Ingresses to configure, according
+// to the class conflict policy.
+func (n *NGINXController) checkClassConflicts(ings []*extensions.Ingress) []*extensions.Ingress {
+	policy := n.cfg.ClassConflictPolicy
+	if policy != ClassConflictWarn && policy != ClassConflictSkip {
+		return ings
+	}
+
+	conflicts := classConflicts(ings, n.store.ListIngressesOfOtherClasses())
+
+	reported := sets.NewString()
+	skip := sets.NewString()
+	for _, c := range conflicts {
+		key := c.String()
+		reported.Insert(key)
+		skip.Insert(fmt.Sprintf("%v/%v", c.ingress.Namespace, c.ingress.Name))
+
+		if n.classConflicts.Has(key) {
+			continue
+		}
+
+		msg := fmt.Sprintf("Host %q and path %q are also defined in Ingress %v/%v with class %q",
+			c.host, c.path, c.other.Namespace, c.other.Name, c.other.Annotations[class.IngressKey])
+		log.Warningf("Ingress %v/%v: %v", c.ingress.Namespace, c.ingress.Name, msg)
+		if n.recorder != nil {
+			n.recorder.Event(c.ingress, apiv1.EventTypeWarning, "ClassConflict", msg)
+		}
+	}
+
+	n.classConflicts = reported
+	n.metricCollector.SetClassConflicts(skip.Len())
+
+	if policy != ClassConflictSkip || skip.Len() == 0 {
+		return ings
+	}
+
+	var filtered []*extensions.Ingress
+	for _, ing := range ings {
+		key := fmt.Sprintf("%v/%v", ing.Namespace, ing.Name)
+		if skip.Has(key) {
+			log.Warningf("Ignoring Ingress %v because of class conflicts", key)
+			continue
+		}
+
+		filtered = append(filtered, ing)
+	}
+
+	return filtered
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"testing"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newRulesIngress(name, class string, rules map[string][]string) *extensions.Ingress {
+	ing := &extensions.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "default",
+			Annotations: map[string]string{},
+		},
+	}
+
+	if class != "" {
+		ing.Annotations["kubernetes.io/ingress.class"] = class
+	}
+
+	for host, paths := range rules {
+		rule := extensions.IngressRule{
+			Host: host,
+			IngressRuleValue: extensions.IngressRuleValue{
+				HTTP: &extensions.HTTPIngressRuleValue{},
+			},
+		}
+
+		for _, path := range paths {
+			rule.HTTP.Paths = append(rule.HTTP.Paths, extensions.HTTPIngressPath{Path: path})
+		}
+
+		ing.Spec.Rules = append(ing.Spec.Rules, rule)
+	}
+
+	return ing
+}
+
+func TestClassConflicts(t *testing.T) {
+	ings := []*extensions.Ingress{
+		newRulesIngress("web", "", map[string][]string{"foo.bar": {"", "/api"}}),
+		newRulesIngress("docs", "nginx", map[string][]string{"docs.foo.bar": {"/"}}),
+	}
+
+	others := []*extensions.Ingress{
+		newRulesIngress("gce-web", "gce", map[string][]string{"foo.bar": {"/"}}),
+		newRulesIngress("gce-api", "gce", map[string][]string{"foo.bar": {"/v2"}}),
+		newRulesIngress("gce-docs", "gce", map[string][]string{"docs.bar": {"/"}}),
+	}
+
+	conflicts := classConflicts(ings, others)
+	if len(conflicts) != 1 {
+		t.Fatalf("expected 1 conflict but got %v", len(conflicts))
+	}
+
+	c := conflicts[0]
+	if c.ingress.Name != "web" || c.other.Name != "gce-web" || c.host != "foo.bar" || c.path != "/" {
+		t.Errorf("unexpected conflict %v", c)
+	}
+
+	if conflicts := classConflicts(ings, nil); len(conflicts) != 0 {
+		t.Errorf("expected no conflicts but got %v", conflicts)
+	}
+}
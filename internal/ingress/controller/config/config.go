@@ -18,15 +18,16 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"strconv"
+	"strings"
 	"time"
 
-	"github.com/golang/glog"
-
 	apiv1 "k8s.io/api/core/v1"
 
 	"k8s.io/ingress-nginx/internal/ingress"
 	"k8s.io/ingress-nginx/internal/ingress/defaults"
+	"k8s.io/ingress-nginx/internal/log"
 	"k8s.io/ingress-nginx/internal/runtime"
 )
 
@@ -54,6 +55,9 @@ const (
 
 	logFormatStream = `[$time_local] $protocol $status $bytes_sent $bytes_received $session_time`
 
+	// SyslogScheme is the prefix of the log targets that send the logs to a syslog server
+	SyslogScheme = "syslog://"
+
 	// http://nginx.org/en/docs/http/ngx_http_ssl_module.html#ssl_buffer_size
 	// Sets the size of the buffer used for sending data.
 	// 4k helps NGINX to improve TLS Time To First Byte (TTTFB)
@@ -111,6 +115,18 @@ type Configuration struct {
 	// By default error logs go to /var/log/nginx/error.log
 	ErrorLogPath string `json:"error-log-path,omitempty"`
 
+	// LogRotateMaxSize is the size in megabytes of the access or error log
+	// file that triggers a rotation. By default this is disabled (0)
+	LogRotateMaxSize int `json:"log-rotate-max-size"`
+
+	// LogRotateMaxAge is the time in hours after which the access and error
+	// log files are rotated. By default this is disabled (0)
+	LogRotateMaxAge int `json:"log-rotate-max-age"`
+
+	// LogRotateMaxFiles is the number of rotated files kept for each log
+	// file. Older files are removed
+	LogRotateMaxFiles int `json:"log-rotate-max-files"`
+
 	// EnableDynamicTLSRecords enables dynamic TLS record sizes
 	// https://blog.cloudflare.com/optimizing-tls-over-tcp-to-reduce-latency
 	// By default this is enabled
@@ -151,6 +167,14 @@ type Configuration struct {
 	// DisableIpv6 disable listening on ipv6 address
 	DisableIpv6 bool `json:"disable-ipv6,omitempty"`
 
+	// ListenIPFamily sets the IP families of the HTTP and HTTPS listeners: auto
+	// (IPv4, plus IPv6 when enabled), dual, ipv4 or ipv6
+	ListenIPFamily string `json:"listen-ip-family,omitempty"`
+
+	// StatusListenIPFamily sets the IP families of the status and default server
+	// listeners. ListenIPFamily is used when empty.
+	StatusListenIPFamily string `json:"status-listen-ip-family,omitempty"`
+
 	// EnableUnderscoresInHeaders enables underscores in header names
 	// http://nginx.org/en/docs/http/ngx_http_core_module.html#underscores_in_headers
 	// By default this is disabled
@@ -184,23 +208,6 @@ type Configuration struct {
 	// and the need of establishing a new connection.
 	HTTP2MaxRequests int `json:"http2-max-requests,omitempty"`
 
-	// Enables or disables the header HSTS in servers running SSL
-	HSTS bool `json:"hsts,omitempty"`
-
-	// Enables or disables the use of HSTS in all the subdomains of the servername
-	// Default: true
-	HSTSIncludeSubdomains bool `json:"hsts-include-subdomains,omitempty"`
-
-	// HTTP Strict Transport Security (often abbreviated as HSTS) is a security feature (HTTP header)
-	// that tell browsers that it should only be communicated with using HTTPS, instead of using HTTP.
-	// https://developer.mozilla.org/en-US/docs/Web/Security/HTTP_strict_transport_security
-	// max-age is the time, in seconds, that the browser should remember that this site is only to be
-	// accessed using HTTPS.
-	HSTSMaxAge string `json:"hsts-max-age,omitempty"`
-
-	// Enables or disables the preload attribute in HSTS feature
-	HSTSPreload bool `json:"hsts-preload,omitempty"`
-
 	// Time during which a keep-alive client connection will stay open on the server side.
 	// The zero value disables keep-alive client connections
 	// http://nginx.org/en/docs/http/ngx_http_core_module.html#keepalive_timeout
@@ -228,6 +235,21 @@ type Configuration struct {
 	// http://nginx.org/en/docs/http/ngx_http_log_module.html#log_format
 	LogFormatStream string `json:"log-format-stream,omitempty"`
 
+	// LogFormatJSON emits the upstream access log as a JSON object containing the
+	// fields in the list (in order). When defined it takes precedence over LogFormatUpstream.
+	// The value is a comma-separated list of predefined field names (see LogFormatJSONFields)
+	// or name:$variable pairs, e.g. "time,request_id,status,latency:$upstream_response_time"
+	LogFormatJSON []LogFormatJSONField `json:"log-format-json,omitempty"`
+
+	// ServiceLabels is the list of labels of the Services exposed in the NGINX
+	// variables $service_label_<name> of each location, to use them in the log
+	// format or custom templates. See LabelVariable for the name of the variables.
+	ServiceLabels []string `json:"service-labels,omitempty"`
+
+	// IngressLabels is the list of labels of the Ingresses exposed in the NGINX
+	// variables $ingress_label_<name> of each location
+	IngressLabels []string `json:"ingress-labels,omitempty"`
+
 	// If disabled, a worker process will accept one new connection at a time.
 	// Otherwise, a worker process will accept all new connections at a time.
 	// http://nginx.org/en/docs/ngx_core_module.html#multi_accept
@@ -339,6 +361,10 @@ type Configuration struct {
 	// Example '60s'
 	ProxyProtocolHeaderTimeout time.Duration `json:"proxy-protocol-header-timeout,omitempty"`
 
+	// ProxyProtocolListeners are the listeners, http and https, that expect the
+	// PROXY protocol, version 1 or 2, when use-proxy-protocol is enabled
+	ProxyProtocolListeners []string `json:"proxy-protocol-listeners,omitempty"`
+
 	// Enables or disables the use of the nginx module that compresses responses using the "gzip" method
 	// http://nginx.org/en/docs/http/ngx_http_gzip_module.html
 	UseGzip bool `json:"use-gzip,omitempty"`
@@ -351,16 +377,6 @@ type Configuration struct {
 	// By default this is disabled
 	UseGeoIP2 bool `json:"use-geoip2,omitempty"`
 
-	// Enables or disables the use of the NGINX Brotli Module for compression
-	// https://github.com/google/ngx_brotli
-	EnableBrotli bool `json:"enable-brotli,omitempty"`
-
-	// Brotli Compression Level that will be used
-	BrotliLevel int `json:"brotli-level,omitempty"`
-
-	// MIME Types that will be compressed on-the-fly using Brotli module
-	BrotliTypes string `json:"brotli-types,omitempty"`
-
 	// Enables or disables the HTTP/2 support in secure connections
 	// http://nginx.org/en/docs/http/ngx_http_v2_module.html
 	// Default: true
@@ -403,6 +419,11 @@ type Configuration struct {
 	// http://nginx.org/en/docs/http/ngx_http_upstream_module.html#keepalive_timeout
 	UpstreamKeepaliveTimeout int `json:"upstream-keepalive-timeout,omitempty"`
 
+	// Time in seconds the endpoints removed from a backend with cookie session affinity
+	// keep receiving the requests of the existing sessions. New sessions are not
+	// assigned to them. 0 disables the drain of the endpoints
+	EndpointDrainPeriod int `json:"endpoint-drain-period,omitempty"`
+
 	// Sets the maximum number of requests that can be served through one keepalive connection.
 	// After the maximum number of requests is made, the connection is closed.
 	// http://nginx.org/en/docs/http/ngx_http_upstream_module.html#keepalive_requests
@@ -500,6 +521,21 @@ type Configuration struct {
 	// LocationSnippet adds custom configuration to all the locations in the nginx configuration
 	LocationSnippet string `json:"location-snippet"`
 
+	// HTTPSnippetFiles are files included in the http section of the nginx configuration
+	HTTPSnippetFiles []string `json:"http-snippet-files"`
+
+	// ServerSnippetDir is a directory with .conf files included in all the servers
+	// of the nginx configuration
+	ServerSnippetDir string `json:"server-snippet-dir"`
+
+	// StreamSnippetDir is a directory with .conf files included in the stream section
+	// of the nginx configuration
+	StreamSnippetDir string `json:"stream-snippet-dir"`
+
+	// CustomTemplate is the path of a template file used to render the nginx
+	// configuration instead of the template of the controller
+	CustomTemplate string `json:"custom-template"`
+
 	// HTTPRedirectCode sets the HTTP status code to be used in redirects.
 	// Supported codes are 301,302,307 and 308
 	// Default: 308
@@ -516,6 +552,12 @@ type Configuration struct {
 	// Default: empty
 	HideHeaders []string `json:"hide-headers"`
 
+	// RetryAfterUnavailable sets the value in seconds of the header Retry-After in the
+	// responses with status code 503, like the ones returned while a backend is not
+	// available after a reload or a change of its endpoints. 0 disables the header
+	// Default: 0
+	RetryAfterUnavailable int `json:"retry-after-unavailable"`
+
 	// LimitReqStatusCode Sets the status code to return in response to rejected requests.
 	// http://nginx.org/en/docs/http/ngx_http_limit_req_module.html#limit_req_status
 	// Default: 503
@@ -527,6 +569,27 @@ type Configuration struct {
 	SyslogHost string `json:"syslog-host"`
 	// SyslogPort port
 	SyslogPort int `json:"syslog-port"`
+	// SyslogFacility is the facility of the messages sent to syslog
+	// http://nginx.org/en/docs/syslog.html
+	SyslogFacility string `json:"syslog-facility"`
+	// SyslogTag is the tag of the messages sent to syslog
+	SyslogTag string `json:"syslog-tag"`
+	// SyslogSeverity is the severity of the access log messages sent to syslog
+	SyslogSeverity string `json:"syslog-severity"`
+
+	// AccessLogTarget sends the access logs to a remote syslog server
+	// instead of AccessLogPath, using the format syslog://host[:port]
+	AccessLogTarget string `json:"access-log-target"`
+	// ErrorLogTarget sends the error logs to a remote syslog server
+	// instead of ErrorLogPath, using the format syslog://host[:port]
+	ErrorLogTarget string `json:"error-log-target"`
+
+	// AccessLogBuffer is the size of the buffer used to write the access
+	// logs to a file. By default the logs are not buffered
+	// http://nginx.org/en/docs/http/ngx_http_log_module.html#access_log
+	AccessLogBuffer string `json:"access-log-buffer"`
+	// AccessLogFlush is the maximum time the access logs stay in the buffer
+	AccessLogFlush string `json:"access-log-flush"`
 
 	// NoTLSRedirectLocations is a comma-separated list of locations
 	// that should not get redirected to TLS
@@ -556,6 +619,75 @@ type Configuration struct {
 
 	// Block all requests with given Referer headers
 	BlockReferers []string `json:"block-referers"`
+
+	// StaleConfigurationHeader sets the name of a response header added to all
+	// the responses when the running configuration is known to be stale (the
+	// API server is unreachable or the reload or dynamic reconfiguration failed).
+	// The value of the header contains the reasons. By default this is disabled
+	StaleConfigurationHeader string `json:"stale-configuration-header"`
+
+	// ProxyCacheZones defines the zones available to cache responses from
+	// the upstream servers using the proxy-cache-zone annotation.
+	// The value is a comma-separated list of name:size pairs, e.g. "static:10m,api:50m"
+	// http://nginx.org/en/docs/http/ngx_http_proxy_module.html#proxy_cache_path
+	ProxyCacheZones []ProxyCacheZone `json:"proxy-cache-zones"`
+
+	// ProxyCachePath sets the directory where the cache zones are stored.
+	// Each zone uses a subdirectory with the name of the zone
+	// Default: /tmp/nginx-cache
+	ProxyCachePath string `json:"proxy-cache-path"`
+
+	// ProxyCacheMaxSize sets the maximum size of each cache zone
+	// Default: 1g
+	ProxyCacheMaxSize string `json:"proxy-cache-max-size"`
+
+	// ProxyCacheInactive sets the time after which cached data that is not
+	// accessed is removed from the cache
+	// Default: 10m
+	ProxyCacheInactive string `json:"proxy-cache-inactive"`
+}
+
+// ProxyCacheZone describes a shared memory zone used to cache responses
+type ProxyCacheZone struct {
+	// Name of the zone
+	Name string `json:"name"`
+	// Size of the shared memory zone that contains the cache keys
+	Size string `json:"size"`
+}
+
+// LogFormatJSONField describes a field of the JSON access log
+type LogFormatJSONField struct {
+	// Name of the key in the JSON object
+	Name string `json:"name"`
+	// Variable is the NGINX variable used as value
+	Variable string `json:"variable"`
+}
+
+// LogFormatJSONFields contains the fields that can be used by name in the
+// log-format-json setting. The namespace, ingress and service fields are
+// resolved by the controller for each location.
+var LogFormatJSONFields = map[string]string{
+	"time":             "$time_iso8601",
+	"remote_addr":      "$the_real_ip",
+	"remote_user":      "$remote_user",
+	"request_id":       "$req_id",
+	"request":          "$request",
+	"method":           "$request_method",
+	"host":             "$host",
+	"path":             "$uri",
+	"status":           "$status",
+	"bytes_sent":       "$body_bytes_sent",
+	"request_length":   "$request_length",
+	"request_time":     "$request_time",
+	"referer":          "$http_referer",
+	"user_agent":       "$http_user_agent",
+	"upstream_name":    "$proxy_upstream_name",
+	"upstream_addr":    "$upstream_addr",
+	"upstream_status":  "$upstream_status",
+	"upstream_latency": "$upstream_response_time",
+	"namespace":        "$namespace",
+	"ingress":          "$ingress_name",
+	"service":          "$service_name",
 }
 
 // NewDefault returns the default nginx configuration
@@ -566,7 +698,7 @@ func NewDefault() Configuration {
 	defNginxStatusIpv4Whitelist := make([]string, 0)
 	defNginxStatusIpv6Whitelist := make([]string, 0)
 
-	defIPCIDR = append(defIPCIDR, "0.0.0.0/0")
+	defIPCIDR = append(defIPCIDR, "0.0.0.0/0", "::/0")
 	defNginxStatusIpv4Whitelist = append(defNginxStatusIpv4Whitelist, "127.0.0.1")
 	defNginxStatusIpv6Whitelist = append(defNginxStatusIpv6Whitelist, "::1")
 	defProxyDeadlineDuration := time.Duration(5) * time.Second
@@ -576,11 +708,10 @@ func NewDefault() Configuration {
 		AccessLogPath:              "/var/log/nginx/access.log",
 		WorkerCpuAffinity:          "",
 		ErrorLogPath:               "/var/log/nginx/error.log",
+		LogRotateMaxFiles:          5,
 		BlockCIDRs:                 defBlockEntity,
 		BlockUserAgents:            defBlockEntity,
 		BlockReferers:              defBlockEntity,
-		BrotliLevel:                4,
-		BrotliTypes:                brotliTypes,
 		ClientHeaderBufferSize:     "1k",
 		ClientHeaderTimeout:        60,
 		ClientBodyBufferSize:       "8k",
@@ -597,10 +728,6 @@ func NewDefault() Configuration {
 		HTTP2MaxHeaderSize:         "16k",
 		HTTP2MaxRequests:           1000,
 		HTTPRedirectCode:           308,
-		HSTS:                       true,
-		HSTSIncludeSubdomains:      true,
-		HSTSMaxAge:                 hstsMaxAge,
-		HSTSPreload:                false,
 		IgnoreInvalidHeaders:       true,
 		GzipLevel:                  5,
 		GzipTypes:                  gzipTypes,
@@ -617,11 +744,13 @@ func NewDefault() Configuration {
 		NginxStatusIpv6Whitelist:   defNginxStatusIpv6Whitelist,
 		ProxyRealIPCIDR:            defIPCIDR,
 		ProxyProtocolHeaderTimeout: defProxyDeadlineDuration,
+		ProxyProtocolListeners:     []string{HTTPListener, HTTPSListener},
 		ServerNameHashMaxSize:      1024,
 		ProxyHeadersHashMaxSize:    512,
 		ProxyHeadersHashBucketSize: 64,
 		ProxyStreamResponses:       1,
 		ReusePort:                  true,
+		ListenIPFamily:             AutoIPFamily,
 		ShowServerTokens:           true,
 		SSLBufferSize:              sslBufferSize,
 		SSLCiphers:                 sslCiphers,
@@ -631,7 +760,6 @@ func NewDefault() Configuration {
 		SSLSessionCacheSize:        sslSessionCacheSize,
 		SSLSessionTickets:          true,
 		SSLSessionTimeout:          sslSessionTimeout,
-		EnableBrotli:               false,
 		UseGzip:                    true,
 		UseGeoIP:                   true,
 		UseGeoIP2:                  false,
@@ -642,26 +770,40 @@ func NewDefault() Configuration {
 		VariablesHashMaxSize:       2048,
 		UseHTTP2:                   true,
 		ProxyStreamTimeout:         "600s",
+		ProxyCacheZones:            []ProxyCacheZone{},
+		ProxyCachePath:             "/tmp/nginx-cache",
+		ProxyCacheMaxSize:          "1g",
+		ProxyCacheInactive:         "10m",
 		Backend: defaults.Backend{
-			ProxyBodySize:          bodySize,
-			ProxyConnectTimeout:    5,
-			ProxyReadTimeout:       60,
-			ProxySendTimeout:       60,
-			ProxyBufferSize:        "4k",
-			ProxyCookieDomain:      "off",
-			ProxyCookiePath:        "off",
-			ProxyNextUpstream:      "error timeout",
-			ProxyNextUpstreamTries: 3,
-			ProxyRequestBuffering:  "on",
-			ProxyRedirectFrom:      "off",
-			ProxyRedirectTo:        "off",
-			SSLRedirect:            true,
-			CustomHTTPErrors:       []int{},
-			WhitelistSourceRange:   []string{},
-			SkipAccessLogURLs:      []string{},
-			LimitRate:              0,
-			LimitRateAfter:         0,
-			ProxyBuffering:         "off",
+			ProxyBodySize:           bodySize,
+			ProxyConnectTimeout:     5,
+			ProxyReadTimeout:        60,
+			ProxySendTimeout:        60,
+			ProxyBufferSize:         "4k",
+			ProxyCookieDomain:       "off",
+			ProxyCookiePath:         "off",
+			ProxyNextUpstream:       "error timeout",
+			ProxyNextUpstreamTries:  3,
+			ProxyRequestBuffering:   "on",
+			ProxyRedirectFrom:       "off",
+			ProxyRedirectTo:         "off",
+			SSLRedirect:             true,
+			AllowSnippetAnnotations: true,
+			CustomHTTPErrors:        []int{},
+			WhitelistSourceRange:    []string{},
+			SkipAccessLogURLs:       []string{},
+			LimitRate:               0,
+			LimitRateAfter:          0,
+			LimitConnZoneSize:       5,
+			ProxyBuffering:          "off",
+			ProxySSLSessionReuse:    true,
+			EnableBrotli:            false,
+			BrotliLevel:             4,
+			BrotliTypes:             brotliTypes,
+			HSTS:                    true,
+			HSTSIncludeSubdomains:   true,
+			HSTSMaxAge:              hstsMaxAge,
+			HSTSPreload:             false,
 		},
 		UpstreamKeepaliveConnections: 32,
 		UpstreamKeepaliveTimeout:     60,
@@ -678,21 +820,82 @@ func NewDefault() Configuration {
 		JaegerSamplerParam:           "1",
 		LimitReqStatusCode:           503,
 		SyslogPort:                   514,
+		SyslogFacility:               "local7",
+		SyslogTag:                    "nginx",
+		SyslogSeverity:               "info",
 		NoTLSRedirectLocations:       "/.well-known/acme-challenge",
 		NoAuthLocations:              "/.well-known/acme-challenge",
 	}
 
-	if glog.V(5) {
+	if log.V(5) {
 		cfg.ErrorLogLevel = "debug"
 	}
 
 	return cfg
 }
 
+const (
+	// ServiceLabelPrefix is the prefix of the variables with Service labels
+	ServiceLabelPrefix = "service_label_"
+	// IngressLabelPrefix is the prefix of the variables with Ingress labels
+	IngressLabelPrefix = "ingress_label_"
+)
+
+// LabelVariable returns the name of the NGINX variable, without $, with the
+// value of a label. The characters of the label not valid in variables are
+// replaced with _, i.e. app.kubernetes.io/part-of is exposed in the variable
+// service_label_app_kubernetes_io_part_of.
+func LabelVariable(prefix, label string) string {
+	return prefix + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		default:
+			return '_'
+		}
+	}, label)
+}
+
+// StatusIPFamily returns the IP family of the status and default server
+// listeners, the one of the HTTP and HTTPS listeners by default
+func (cfg Configuration) StatusIPFamily() string {
+	if cfg.StatusListenIPFamily != "" {
+		return cfg.StatusListenIPFamily
+	}
+	return cfg.ListenIPFamily
+}
+
+// ProxyProtocolOn returns true if a listener, HTTPListener or HTTPSListener,
+// expects the PROXY protocol
+func (cfg Configuration) ProxyProtocolOn(listener string) bool {
+	if !cfg.UseProxyProtocol {
+		return false
+	}
+
+	for _, l := range cfg.ProxyProtocolListeners {
+		if l == listener {
+			return true
+		}
+	}
+	return false
+}
+
 // BuildLogFormatUpstream format the log_format upstream using
 // proxy_protocol_addr as remote client address if UseProxyProtocol
 // is enabled.
+// When LogFormatJSON is defined the log format is a JSON object
+// with one key for each field.
 func (cfg Configuration) BuildLogFormatUpstream() string {
+	if len(cfg.LogFormatJSON) > 0 {
+		fields := make([]string, 0, len(cfg.LogFormatJSON))
+		for _, field := range cfg.LogFormatJSON {
+			fields = append(fields, fmt.Sprintf(`"%v": "%v"`, field.Name, field.Variable))
+		}
+		return fmt.Sprintf("{ %v }", strings.Join(fields, ", "))
+	}
+
 	if cfg.LogFormatUpstream == logFormatUpstream {
 		return fmt.Sprintf(cfg.LogFormatUpstream, "$the_real_ip")
 	}
@@ -700,6 +903,55 @@ func (cfg Configuration) BuildLogFormatUpstream() string {
 	return cfg.LogFormatUpstream
 }
 
+// BuildAccessLog returns the destination, format and buffer options
+// of the access logs used in the access_log directive.
+func (cfg Configuration) BuildAccessLog(format string) string {
+	target := cfg.AccessLogTarget
+	if target == "" && cfg.EnableSyslog {
+		target = fmt.Sprintf("%v%v", SyslogScheme, net.JoinHostPort(cfg.SyslogHost, strconv.Itoa(cfg.SyslogPort)))
+	}
+
+	if target != "" {
+		return fmt.Sprintf("%v,severity=%v %v", cfg.buildSyslogDestination(target), cfg.SyslogSeverity, format)
+	}
+
+	if cfg.AccessLogBuffer == "" {
+		return fmt.Sprintf("%v %v", cfg.AccessLogPath, format)
+	}
+
+	if cfg.AccessLogFlush == "" {
+		return fmt.Sprintf("%v %v buffer=%v", cfg.AccessLogPath, format, cfg.AccessLogBuffer)
+	}
+
+	return fmt.Sprintf("%v %v buffer=%v flush=%v", cfg.AccessLogPath, format, cfg.AccessLogBuffer, cfg.AccessLogFlush)
+}
+
+// BuildErrorLogDestination returns the destination of the error logs
+// used in the error_log directive.
+func (cfg Configuration) BuildErrorLogDestination() string {
+	target := cfg.ErrorLogTarget
+	if target == "" && cfg.EnableSyslog {
+		target = fmt.Sprintf("%v%v", SyslogScheme, net.JoinHostPort(cfg.SyslogHost, strconv.Itoa(cfg.SyslogPort)))
+	}
+
+	if target != "" {
+		return cfg.buildSyslogDestination(target)
+	}
+
+	return cfg.ErrorLogPath
+}
+
+// buildSyslogDestination returns the syslog destination for a
+// target syslog://host[:port]. The default port is 514.
+func (cfg Configuration) buildSyslogDestination(target string) string {
+	server := strings.TrimPrefix(target, SyslogScheme)
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(strings.Trim(server, "[]"), "514")
+	}
+
+	return fmt.Sprintf("syslog:server=%v,facility=%v,tag=%v", server, cfg.SyslogFacility, cfg.SyslogTag)
+}
+
 // TemplateConfig contains the nginx configuration to render the file nginx.conf
 type TemplateConfig struct {
 	ProxySetHeaders            map[string]string
@@ -710,16 +962,98 @@ type TemplateConfig struct {
 	PassthroughBackends        []*ingress.SSLPassthroughBackend
 	Servers                    []*ingress.Server
 	HealthzURI                 string
+	ShutdownMarker             string
+	ConfigSocket               string
 	CustomErrors               bool
 	Cfg                        Configuration
 	IsIPV6Enabled              bool
+	HTTPListen                 ListenIPFamily
+	StatusListen               ListenIPFamily
 	IsSSLPassthroughEnabled    bool
 	NginxStatusIpv4Whitelist   []string
 	NginxStatusIpv6Whitelist   []string
-	RedirectServers            map[string]string
+	RedirectServers            []*RedirectServer
 	ListenPorts                *ListenPorts
 	PublishService             *apiv1.Service
 	DynamicCertificatesEnabled bool
+	DenylistEnabled            bool
+	HostRedirectsEnabled       bool
+	Profile                    string
+	EnableRequestMetrics       bool
+	SnippetFiles               SnippetFiles
+}
+
+// RedirectServer is a server created to redirect the requests sent to a host
+// to the same host with or without the prefix www
+type RedirectServer struct {
+	// From is the host redirected
+	From string
+	// To is the host of the server with the annotation from-to-www-redirect
+	To string
+	// SSLCert is the certificate of the server of To, also used to serve From
+	SSLCert ingress.SSLCert
+	// SSLRedirect indicates if the server of To redirects HTTP to HTTPS, so
+	// the requests sent to From over HTTP are redirected to HTTPS directly
+	SSLRedirect bool
+	// UsePortInRedirects indicates if the redirects must specify the port
+	UsePortInRedirects bool
+}
+
+// SnippetFiles contains the content of the files included in the sections of
+// the NGINX configuration, defined by http-snippet-files, server-snippet-dir
+// and stream-snippet-dir
+type SnippetFiles struct {
+	HTTP   string
+	Server string
+	Stream string
+}
+
+const (
+	// AutoIPFamily listens on IPv4, plus IPv6 when enabled in the host
+	AutoIPFamily = "auto"
+	// DualIPFamily listens on IPv4 and IPv6
+	DualIPFamily = "dual"
+	// IPv4IPFamily only listens on IPv4
+	IPv4IPFamily = "ipv4"
+	// IPv6IPFamily only listens on IPv6
+	IPv6IPFamily = "ipv6"
+)
+
+const (
+	// HTTPListener is the listener of the HTTP port
+	HTTPListener = "http"
+	// HTTPSListener is the listener of the HTTPS port
+	HTTPSListener = "https"
+)
+
+// IsValidIPFamily returns true if family is a valid value of listen-ip-family
+func IsValidIPFamily(family string) bool {
+	switch family {
+	case AutoIPFamily, DualIPFamily, IPv4IPFamily, IPv6IPFamily:
+		return true
+	}
+	return false
+}
+
+// ListenIPFamily indicates the IP families of the addresses of a listener
+type ListenIPFamily struct {
+	IPv4 bool
+	IPv6 bool
+}
+
+// NewListenIPFamily returns the IP families of a listener configured with
+// family. ipv6Enabled is used by the auto family.
+func NewListenIPFamily(family string, ipv6Enabled bool) ListenIPFamily {
+	switch family {
+	case DualIPFamily:
+		return ListenIPFamily{IPv4: true, IPv6: true}
+	case IPv4IPFamily:
+		return ListenIPFamily{IPv4: true}
+	case IPv6IPFamily:
+		return ListenIPFamily{IPv6: true}
+	default:
+		return ListenIPFamily{IPv4: true, IPv6: ipv6Enabled}
+	}
 }
 
 // ListenPorts describe the ports required to run the
@@ -731,4 +1065,15 @@ type ListenPorts struct {
 	Health   int
 	Default  int
 	SSLProxy int
+	// Internal and InternalHTTPS are the ports of the servers with the
+	// annotation internal, bound to InternalAddress (all the addresses
+	// when empty). Zero disables the port.
+	Internal        int
+	InternalHTTPS   int
+	InternalAddress string
+}
+
+// HasInternal returns true if an internal port is configured
+func (lp *ListenPorts) HasInternal() bool {
+	return lp != nil && (lp.Internal > 0 || lp.InternalHTTPS > 0)
 }
@@ -44,3 +44,124 @@ func TestBuildLogFormatUpstream(t *testing.T) {
 		}
 	}
 }
+
+func TestBuildLogFormatUpstreamJSON(t *testing.T) {
+	cfg := NewDefault()
+	cfg.LogFormatJSON = []LogFormatJSONField{
+		{Name: "time", Variable: "$time_iso8601"},
+		{Name: "request_id", Variable: "$req_id"},
+		{Name: "latency", Variable: "$upstream_response_time"},
+	}
+
+	expected := `{ "time": "$time_iso8601", "request_id": "$req_id", "latency": "$upstream_response_time" }`
+	if result := cfg.BuildLogFormatUpstream(); result != expected {
+		t.Errorf("expected %v but return %v", expected, result)
+	}
+}
+
+func TestBuildAccessLog(t *testing.T) {
+	testCases := []struct {
+		name     string
+		modify   func(*Configuration)
+		expected string
+		errorLog string
+	}{
+		{"file", func(cfg *Configuration) {}, "/var/log/nginx/access.log upstreaminfo", "/var/log/nginx/error.log"},
+		{"buffered file", func(cfg *Configuration) {
+			cfg.AccessLogBuffer = "32k"
+			cfg.AccessLogFlush = "5s"
+		}, "/var/log/nginx/access.log upstreaminfo buffer=32k flush=5s", "/var/log/nginx/error.log"},
+		{"enable-syslog", func(cfg *Configuration) {
+			cfg.EnableSyslog = true
+			cfg.SyslogHost = "10.0.0.1"
+		}, "syslog:server=10.0.0.1:514,facility=local7,tag=nginx,severity=info upstreaminfo",
+			"syslog:server=10.0.0.1:514,facility=local7,tag=nginx"},
+		{"targets", func(cfg *Configuration) {
+			cfg.AccessLogTarget = "syslog://logs.example.com:5140"
+			cfg.ErrorLogTarget = "syslog://[fd00::1]"
+			cfg.AccessLogBuffer = "32k"
+			cfg.SyslogFacility = "local0"
+			cfg.SyslogTag = "ingress"
+			cfg.SyslogSeverity = "notice"
+		}, "syslog:server=logs.example.com:5140,facility=local0,tag=ingress,severity=notice upstreaminfo",
+			"syslog:server=[fd00::1]:514,facility=local0,tag=ingress"},
+	}
+
+	for _, tc := range testCases {
+		cfg := NewDefault()
+		tc.modify(&cfg)
+		if result := cfg.BuildAccessLog("upstreaminfo"); result != tc.expected {
+			t.Errorf("%v: expected %v but returned %v", tc.name, tc.expected, result)
+		}
+		if result := cfg.BuildErrorLogDestination(); result != tc.errorLog {
+			t.Errorf("%v: expected %v but returned %v", tc.name, tc.errorLog, result)
+		}
+	}
+}
+
+func TestLabelVariable(t *testing.T) {
+	for label, expected := range map[string]string{
+		"team":                      "service_label_team",
+		"Team":                      "service_label_team",
+		"app.kubernetes.io/part-of": "service_label_app_kubernetes_io_part_of",
+		"cost_center":               "service_label_cost_center",
+	} {
+		if actual := LabelVariable(ServiceLabelPrefix, label); actual != expected {
+			t.Errorf("%v: expected '%v' but returned '%v'", label, expected, actual)
+		}
+	}
+}
+
+func TestNewListenIPFamily(t *testing.T) {
+	testCases := []struct {
+		family      string
+		ipv6Enabled bool
+		expected    ListenIPFamily
+	}{
+		{AutoIPFamily, true, ListenIPFamily{IPv4: true, IPv6: true}},
+		{AutoIPFamily, false, ListenIPFamily{IPv4: true}},
+		{DualIPFamily, false, ListenIPFamily{IPv4: true, IPv6: true}},
+		{IPv4IPFamily, true, ListenIPFamily{IPv4: true}},
+		{IPv6IPFamily, false, ListenIPFamily{IPv6: true}},
+	}
+
+	for _, tc := range testCases {
+		if actual := NewListenIPFamily(tc.family, tc.ipv6Enabled); actual != tc.expected {
+			t.Errorf("%v (IPv6 enabled %v): expected %+v but returned %+v", tc.family, tc.ipv6Enabled, tc.expected, actual)
+		}
+	}
+}
+
+func TestStatusIPFamily(t *testing.T) {
+	cfg := NewDefault()
+	cfg.ListenIPFamily = IPv6IPFamily
+
+	if family := cfg.StatusIPFamily(); family != IPv6IPFamily {
+		t.Errorf("expected the IP family of the HTTP listeners but returned %v", family)
+	}
+
+	cfg.StatusListenIPFamily = DualIPFamily
+	if family := cfg.StatusIPFamily(); family != DualIPFamily {
+		t.Errorf("expected %v but returned %v", DualIPFamily, family)
+	}
+}
+
+func TestProxyProtocolOn(t *testing.T) {
+	cfg := NewDefault()
+	if cfg.ProxyProtocolOn(HTTPListener) || cfg.ProxyProtocolOn(HTTPSListener) {
+		t.Errorf("expected the PROXY protocol to be disabled by default")
+	}
+
+	cfg.UseProxyProtocol = true
+	if !cfg.ProxyProtocolOn(HTTPListener) || !cfg.ProxyProtocolOn(HTTPSListener) {
+		t.Errorf("expected the PROXY protocol to be enabled in all the listeners")
+	}
+
+	cfg.ProxyProtocolListeners = []string{HTTPSListener}
+	if cfg.ProxyProtocolOn(HTTPListener) {
+		t.Errorf("expected the PROXY protocol to be disabled in the listener %v", HTTPListener)
+	}
+	if !cfg.ProxyProtocolOn(HTTPSListener) {
+		t.Errorf("expected the PROXY protocol to be enabled in the listener %v", HTTPSListener)
+	}
+}
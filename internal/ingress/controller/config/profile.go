@@ -0,0 +1,62 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+const (
+	// DefaultProfile uses the default values of the configuration
+	DefaultProfile = "default"
+
+	// LowMemoryProfile reduces the memory used by NGINX and the Lua
+	// subsystem to run in small edge devices like Raspberry Pi clusters
+	LowMemoryProfile = "low-memory"
+)
+
+// profiles contains the ConfigMap values used as defaults by each
+// profile. Values defined in the ConfigMap take precedence.
+var profiles = map[string]map[string]string{
+	DefaultProfile: {},
+	LowMemoryProfile: {
+		"worker-processes":               "1",
+		"max-worker-connections":         "1024",
+		"upstream-keepalive-connections": "8",
+		"ssl-session-cache-size":         "1m",
+		"disable-lua-resty-waf":          "true",
+		"use-geoip":                      "false",
+		"use-geoip2":                     "false",
+	},
+}
+
+// IsValidProfile checks the name of a configuration profile
+func IsValidProfile(profile string) bool {
+	_, ok := profiles[profile]
+	return ok
+}
+
+// ApplyProfile merges the values of a ConfigMap with
+// the defaults of a configuration profile
+func ApplyProfile(profile string, data map[string]string) map[string]string {
+	merged := map[string]string{}
+	for k, v := range profiles[profile] {
+		merged[k] = v
+	}
+
+	for k, v := range data {
+		merged[k] = v
+	}
+
+	return merged
+}
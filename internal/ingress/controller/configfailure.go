@@ -0,0 +1,126 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+
+	"k8s.io/ingress-nginx/internal/file"
+	"k8s.io/ingress-nginx/internal/log"
+)
+
+// failedConfigRetryPeriod is the time a configuration that failed is
+// not tried again, unless the reload is forced
+const failedConfigRetryPeriod = 5 * time.Minute
+
+var (
+	// lastGoodCfgPath is the copy of the last NGINX configuration file
+	// loaded successfully, restored when a reload fails
+	lastGoodCfgPath = "/etc/nginx/nginx.conf.last-good"
+
+	// failedCfgDiffPath contains the changes of the last configuration that
+	// could not be applied. The configuration contains secrets, so the changes
+	// are only readable in the pod and never included in the events.
+	failedCfgDiffPath = "/etc/nginx/nginx.conf.failed.diff"
+)
+
+// failedConfig is a configuration that could not be rendered,
+// tested or loaded by NGINX
+type failedConfig struct {
+	hash uint64
+	time time.Time
+}
+
+// isRecentFailure returns true if the configuration with a hash failed
+// less than failedConfigRetryPeriod ago
+func (f *failedConfig) isRecentFailure(hash uint64, now time.Time) bool {
+	return f != nil && f.hash == hash && now.Sub(f.time) < failedConfigRetryPeriod
+}
+
+// saveLastGoodConfig keeps a copy of a configuration loaded by NGINX
+func saveLastGoodConfig(content []byte) error {
+	return ioutil.WriteFile(lastGoodCfgPath, content, file.ReadWriteByUser)
+}
+
+// restoreLastGoodConfig replaces a configuration file with the last
+// configuration loaded successfully
+func restoreLastGoodConfig(path string) error {
+	content, err := ioutil.ReadFile(lastGoodCfgPath)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, content, file.ReadWriteByUser)
+}
+
+// configDiff returns the changes of content from a configuration file,
+// in unified format
+func configDiff(path string, content []byte) (string, error) {
+	tmpfile, err := ioutil.TempFile("", "new-nginx-cfg")
+	if err != nil {
+		return "", err
+	}
+	defer tmpfile.Close()
+	defer os.Remove(tmpfile.Name())
+
+	err = ioutil.WriteFile(tmpfile.Name(), content, file.ReadWriteByUser)
+	if err != nil {
+		return "", err
+	}
+
+	// diff exits with code 1 when the files are different
+	out, _ := exec.Command("diff", "-u", path, tmpfile.Name()).CombinedOutput()
+	return string(out), nil
+}
+
+// writeConfigDiff writes in failedCfgDiffPath the changes of content from
+// a configuration file
+func writeConfigDiff(path string, content []byte) error {
+	diff, err := configDiff(path, content)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(failedCfgDiffPath, []byte(diff), file.ReadWriteByUser)
+}
+
+// reportConfigFailure emits a ConfigRenderFailed event on the controller pod
+// with the error. The changes from the running configuration, if the
+// configuration was rendered, are written in failedCfgDiffPath. NGINX keeps
+// running the previous configuration.
+func (n *NGINXController) reportConfigFailure(err error, content []byte) {
+	msg := fmt.Sprintf("The NGINX configuration could not be applied, the previous configuration is kept: %v", err)
+	if content != nil {
+		if derr := writeConfigDiff(cfgPath, content); derr != nil {
+			log.Warningf("Error writing the changes of the NGINX configuration: %v", derr)
+		} else {
+			msg = fmt.Sprintf("%v. The changes are in %v in the controller pod", msg, failedCfgDiffPath)
+		}
+	}
+
+	if n.recorder != nil {
+		if pod := n.controllerPod(); pod != nil {
+			n.recorder.Event(pod, apiv1.EventTypeWarning, "ConfigRenderFailed", msg)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsRecentFailure(t *testing.T) {
+	now := time.Now()
+
+	var none *failedConfig
+	if none.isRecentFailure(1, now) {
+		t.Errorf("expected no recent failure without a failed configuration")
+	}
+
+	f := &failedConfig{hash: 1, time: now}
+	if !f.isRecentFailure(1, now.Add(time.Minute)) {
+		t.Errorf("expected a recent failure of the same configuration")
+	}
+	if f.isRecentFailure(2, now.Add(time.Minute)) {
+		t.Errorf("expected no recent failure of other configuration")
+	}
+	if f.isRecentFailure(1, now.Add(failedConfigRetryPeriod)) {
+		t.Errorf("expected the configuration to be tried again after %v", failedConfigRetryPeriod)
+	}
+}
+
+func TestRestoreLastGoodConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nginx-cfg")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	defaultPath := lastGoodCfgPath
+	defer func() { lastGoodCfgPath = defaultPath }()
+	lastGoodCfgPath = filepath.Join(dir, "nginx.conf.last-good")
+
+	path := filepath.Join(dir, "nginx.conf")
+	if err := restoreLastGoodConfig(path); err == nil {
+		t.Errorf("expected an error without a last good configuration")
+	}
+
+	if err := saveLastGoodConfig([]byte("events {}\n")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ioutil.WriteFile(path, []byte("invalid\n"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := restoreLastGoodConfig(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(content) != "events {}\n" {
+		t.Errorf("expected the last good configuration but got %q", content)
+	}
+
+	defaultDiffPath := failedCfgDiffPath
+	defer func() { failedCfgDiffPath = defaultDiffPath }()
+	failedCfgDiffPath = filepath.Join(dir, "nginx.conf.failed.diff")
+
+	if err := writeConfigDiff(path, []byte("events {}\nhttp {}\n")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fi, err := os.Stat(failedCfgDiffPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fi.Mode().Perm()&0007 != 0 {
+		t.Errorf("expected the diff to not be readable by other users but its mode is %v", fi.Mode())
+	}
+
+	diff, err := ioutil.ReadFile(failedCfgDiffPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(diff), "+http {}") {
+		t.Errorf("expected the diff to contain the new line but got:\n%s", diff)
+	}
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ngxConfigSocket is the Unix socket of the NGINX server with the internal
+// endpoint /configuration, used to configure NGINX without reloads. Its
+// directory is only accessible by the user running the controller and NGINX,
+// so other processes, like pods using the host network, cannot reach it.
+var ngxConfigSocket = "/tmp/nginx-config/configuration.sock"
+
+// ngxConfigHost is the host of the requests sent through ngxConfigSocket
+const ngxConfigHost = "nginx-config"
+
+// createConfigSocketDirectory creates the directory of ngxConfigSocket
+// restricted to the user running the controller
+func createConfigSocketDirectory() error {
+	dir := filepath.Dir(ngxConfigSocket)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	// the directory may exist from a previous execution
+	return os.Chmod(dir, 0700)
+}
+
+// configurationURL returns the URL of a path of the internal endpoint
+// /configuration, like /configuration/backends
+func configurationURL(path string) string {
+	return "http://" + ngxConfigHost + path
+}
+
+// configurationClient returns an HTTP client that sends the requests
+// through ngxConfigSocket. A timeout of zero means no timeout.
+func configurationClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", ngxConfigSocket)
+			},
+			DisableKeepAlives: true,
+		},
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newConfigurationServer starts a server listening on a temporary
+// ngxConfigSocket, which decompresses the bodies of the requests for the
+// handler. The returned function stops it and restores the socket.
+func newConfigurationServer(t *testing.T, handler http.Handler) func() {
+	dir, err := ioutil.TempDir("", "nginx-config")
+	if err != nil {
+		t.Fatalf("unexpected error creating temporary directory: %v", err)
+	}
+
+	defaultSocket := ngxConfigSocket
+	ngxConfigSocket = filepath.Join(dir, "configuration.sock")
+
+	if err := createConfigSocketDirectory(); err != nil {
+		t.Fatalf("unexpected error creating the socket directory: %v", err)
+	}
+
+	listener, err := net.Listen("unix", ngxConfigSocket)
+	if err != nil {
+		t.Fatalf("unexpected error listening on %v: %v", ngxConfigSocket, err)
+	}
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Errorf("unexpected error reading the compressed body: %v", err)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			r.Body = gz
+		}
+
+		handler.ServeHTTP(w, r)
+	}))
+	ts.Listener = listener
+	ts.Start()
+
+	return func() {
+		ts.Close()
+		ngxConfigSocket = defaultSocket
+		os.RemoveAll(dir)
+	}
+}
+
+func TestConfigSocket(t *testing.T) {
+	stop := newConfigurationServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != ngxConfigHost || r.URL.Path != "/configuration/stale" || r.Header.Get("Content-Encoding") != "gzip" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer stop()
+
+	fi, err := os.Stat(filepath.Dir(ngxConfigSocket))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fi.Mode().Perm() != 0700 {
+		t.Errorf("expected the socket directory to be accessible only by its owner but its mode is %v", fi.Mode())
+	}
+
+	if err := post("/configuration/stale", []string{}); err != nil {
+		t.Errorf("unexpected error posting through the socket: %v", err)
+	}
+}
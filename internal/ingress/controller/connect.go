@@ -0,0 +1,223 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/connectproxy"
+	"k8s.io/ingress-nginx/internal/log"
+)
+
+const connectTimeout = 10 * time.Second
+
+// connectTunnel listens in a unix socket used by NGINX as upstream and
+// forwards the connections to the endpoints of a backend using a tunnel
+// established with the HTTP CONNECT method in a forward proxy.
+type connectTunnel struct {
+	proxy    string
+	listener net.Listener
+
+	lock    sync.Mutex
+	targets []string
+	next    int
+}
+
+// target returns the next endpoint of the backend (round robin)
+func (t *connectTunnel) target() string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if len(t.targets) == 0 {
+		return ""
+	}
+
+	t.next = (t.next + 1) % len(t.targets)
+	return t.targets[t.next]
+}
+
+func (t *connectTunnel) setTargets(targets []string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.targets = targets
+}
+
+func (t *connectTunnel) run() {
+	for {
+		conn, err := t.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go t.handle(conn)
+	}
+}
+
+func (t *connectTunnel) handle(conn net.Conn) {
+	defer conn.Close()
+
+	target := t.target()
+	if target == "" {
+		log.Warningf("There are no endpoints to tunnel through the proxy %v", t.proxy)
+		return
+	}
+
+	upstream, err := dialConnect(t.proxy, target)
+	if err != nil {
+		log.Warningf("Error connecting to %v through the proxy %v: %v", target, t.proxy, err)
+		return
+	}
+	defer upstream.Close()
+
+	pipe(upstream, conn)
+}
+
+// dialConnect opens a tunnel to the target address using the HTTP CONNECT method
+func dialConnect(proxy, target string) (net.Conn, error) {
+	conn, err := net.DialTimeout("tcp", proxy, connectTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Now().Add(connectTimeout))
+
+	req, _ := http.NewRequest(http.MethodConnect, "http://"+target, nil)
+	req.Host = target
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected status code %v", resp.StatusCode)
+	}
+
+	conn.SetDeadline(time.Time{})
+
+	return &bufferedConn{conn, br}, nil
+}
+
+// bufferedConn returns the data already read in the
+// response of the proxy before reading the connection
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// syncConnectTunnels starts the tunnels used by the locations that reach
+// the upstream servers through a forward proxy, updates the endpoints of
+// the running ones and stops the ones not used anymore.
+func (n *NGINXController) syncConnectTunnels(pcfg *ingress.Configuration) {
+	endpoints := make(map[string][]string, len(pcfg.Backends))
+	for _, backend := range pcfg.Backends {
+		for _, ep := range backend.Endpoints {
+			endpoints[backend.Name] = append(endpoints[backend.Name], net.JoinHostPort(ep.Address, ep.Port))
+		}
+	}
+
+	if n.tunnels == nil {
+		n.tunnels = make(map[string]*connectTunnel)
+	}
+
+	used := make(map[string]bool)
+	for _, server := range pcfg.Servers {
+		for _, loc := range server.Locations {
+			proxy := loc.ConnectProxy.Address
+			if proxy == "" {
+				continue
+			}
+
+			socket := connectproxy.SocketPath(loc.Backend, proxy)
+			used[socket] = true
+
+			t, ok := n.tunnels[socket]
+			if !ok {
+				var err error
+				t, err = newConnectTunnel(socket, proxy)
+				if err != nil {
+					log.Errorf("Unexpected error creating tunnel to backend %v through the proxy %v: %v", loc.Backend, proxy, err)
+					continue
+				}
+
+				log.Infof("Tunneling connections to backend %v through the proxy %v", loc.Backend, proxy)
+				n.tunnels[socket] = t
+			}
+
+			t.setTargets(endpoints[loc.Backend])
+		}
+	}
+
+	for socket, t := range n.tunnels {
+		if used[socket] {
+			continue
+		}
+
+		log.Infof("Removing tunnel through the proxy %v (%v)", t.proxy, socket)
+		t.listener.Close()
+		delete(n.tunnels, socket)
+	}
+}
+
+func newConnectTunnel(socket, proxy string) (*connectTunnel, error) {
+	err := os.MkdirAll(connectproxy.SocketDirectory, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	// remove the socket of a previous execution
+	os.Remove(socket)
+
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+
+	// NGINX workers do not run as root
+	err = os.Chmod(socket, 0777)
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	t := &connectTunnel{
+		proxy:    proxy,
+		listener: listener,
+	}
+	go t.run()
+
+	return t, nil
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/connectproxy"
+)
+
+// newFakeConnectProxy returns the address of a forward proxy that accepts
+// tunnels only to the allowed target and echoes the data received
+func newFakeConnectProxy(t *testing.T, allowed string) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error creating listener: %v", err)
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func(conn net.Conn) {
+				defer conn.Close()
+
+				br := bufio.NewReader(conn)
+				req, err := http.ReadRequest(br)
+				if err != nil {
+					return
+				}
+
+				if req.Method != http.MethodConnect || req.Host != allowed {
+					io.WriteString(conn, "HTTP/1.1 403 Forbidden\r\n\r\n")
+					return
+				}
+
+				io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+				io.Copy(conn, br)
+			}(conn)
+		}
+	}()
+
+	return l
+}
+
+func TestDialConnect(t *testing.T) {
+	proxy := newFakeConnectProxy(t, "10.0.0.1:80")
+	defer proxy.Close()
+
+	if _, err := dialConnect(proxy.Addr().String(), "10.0.0.2:80"); err == nil {
+		t.Errorf("expected an error connecting to a target not allowed")
+	}
+
+	conn, err := dialConnect(proxy.Addr().String(), "10.0.0.1:80")
+	if err != nil {
+		t.Fatalf("unexpected error connecting through the proxy: %v", err)
+	}
+	defer conn.Close()
+
+	io.WriteString(conn, "ping")
+	b := make([]byte, 4)
+	if _, err := io.ReadFull(conn, b); err != nil || string(b) != "ping" {
+		t.Errorf("expected ping but returned %q (error: %v)", b, err)
+	}
+}
+
+func TestSyncConnectTunnels(t *testing.T) {
+	dir, err := ioutil.TempDir("", "connect")
+	if err != nil {
+		t.Fatalf("unexpected error creating temporal directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	oldDir := connectproxy.SocketDirectory
+	defer func() { connectproxy.SocketDirectory = oldDir }()
+	connectproxy.SocketDirectory = dir
+
+	proxy := newFakeConnectProxy(t, "10.0.0.1:80")
+	defer proxy.Close()
+
+	pcfg := &ingress.Configuration{
+		Backends: []*ingress.Backend{
+			{
+				Name:      "default-app-80",
+				Endpoints: []ingress.Endpoint{{Address: "10.0.0.1", Port: "80"}},
+			},
+		},
+		Servers: []*ingress.Server{
+			{
+				Hostname: "example.com",
+				Locations: []*ingress.Location{
+					{
+						Path:         "/",
+						Backend:      "default-app-80",
+						ConnectProxy: connectproxy.Config{Address: proxy.Addr().String()},
+					},
+				},
+			},
+		},
+	}
+
+	n := &NGINXController{}
+	n.syncConnectTunnels(pcfg)
+
+	socket := connectproxy.SocketPath("default-app-80", proxy.Addr().String())
+	if len(n.tunnels) != 1 || n.tunnels[socket] == nil {
+		t.Fatalf("expected a tunnel listening in %v but returned %v", socket, n.tunnels)
+	}
+
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatalf("unexpected error connecting to the tunnel: %v", err)
+	}
+
+	io.WriteString(conn, "ping")
+	b := make([]byte, 4)
+	if _, err := io.ReadFull(conn, b); err != nil || string(b) != "ping" {
+		t.Errorf("expected ping but returned %q (error: %v)", b, err)
+	}
+	conn.Close()
+
+	pcfg.Servers[0].Locations[0].ConnectProxy = connectproxy.Config{}
+	n.syncConnectTunnels(pcfg)
+
+	if len(n.tunnels) != 0 {
+		t.Errorf("expected no tunnels but returned %v", n.tunnels)
+	}
+}
@@ -18,25 +18,31 @@ package controller
 
 import (
 	"fmt"
-	"math/rand"
+	"net/url"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 
-	"github.com/golang/glog"
-	"github.com/mitchellh/hashstructure"
-
 	apiv1 "k8s.io/api/core/v1"
 	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
-	"k8s.io/apimachinery/pkg/util/wait"
 	clientset "k8s.io/client-go/kubernetes"
 
 	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/annotations"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/brotli"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/headerrouting"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/hsts"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/proxy"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/queryrouting"
 	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
 	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/internal/log"
+	"k8s.io/ingress-nginx/internal/net/ssl"
+	"k8s.io/ingress-nginx/internal/task"
 )
 
 const (
@@ -57,6 +63,9 @@ type Configuration struct {
 	DefaultService string
 
 	Namespace string
+	// NamespaceSelector restricts the watched namespaces to the ones
+	// matching it. All the namespaces are watched when nil
+	NamespaceSelector labels.Selector
 
 	ForceNamespaceIsolation bool
 
@@ -71,9 +80,16 @@ type Configuration struct {
 	UpdateStatus           bool
 	UseNodeInternalIP      bool
 	ElectionID             string
+	ElectionLeaseDuration  time.Duration
+	ElectionRenewDeadline  time.Duration
+	ElectionRetryPeriod    time.Duration
+	ElectionLockType       string
 	UpdateStatusOnShutdown bool
 
 	SortBackends bool
+	// DeterministicEndpointOrder shuffles the endpoints of each Service
+	// port always in the same order when they are not sorted
+	DeterministicEndpointOrder bool
 
 	ListenPorts *ngx_config.ListenPorts
 
@@ -81,19 +97,140 @@ type Configuration struct {
 
 	EnableProfiling bool
 
+	EnableNamespaceMetrics bool
+
+	// EnableAnnotationBundle exposes the export and the validation of the
+	// annotations of the Ingresses through the status port
+	EnableAnnotationBundle bool
+
+	// EnableCanaryAPI exposes the weight of the canary Ingresses through
+	// the status port, writing the changes in the Ingress annotations
+	EnableCanaryAPI bool
+
+	// EnableRoutingOverrides exposes the temporary routing overrides
+	// through the status port
+	EnableRoutingOverrides bool
+
+	ChargebackLabel string
+
+	// ClassConflictPolicy defines how to handle the Ingresses with a host
+	// and path also defined in an Ingress of other class
+	ClassConflictPolicy string
+
+	// EndpointWeightAnnotation is the Pod annotation with the weight of its endpoints
+	EndpointWeightAnnotation string
+
+	MaxmindLicenseKey    string
+	MaxmindRefreshPeriod time.Duration
+
+	// ExternalNameResolvePeriod is the time between checks of the resolution
+	// of the hostnames of ExternalName Services
+	ExternalNameResolvePeriod time.Duration
+
+	// RealIPRangesURLs are the URLs of the IP ranges published by CDNs,
+	// trusted to send the client address
+	RealIPRangesURLs []string
+	// RealIPRangesSyncPeriod is the time between fetches of RealIPRangesURLs
+	RealIPRangesSyncPeriod time.Duration
+
+	// PublishEffectiveConfig writes a summary of the configuration
+	// applied to each Ingress in one of its annotations. The leader of
+	// the status update writes it.
+	PublishEffectiveConfig bool
+
+	// ConfigTestWorkers is the number of workers used to
+	// test the NGINX configuration files
+	ConfigTestWorkers int
+
+	// ReloadConcurrency is the maximum number of instances reloading NGINX
+	// at the same time. Zero disables the coordination of the reloads
+	ReloadConcurrency int
+	// ReloadCoordinationTimeout is the maximum time a reload waits for
+	// other instances to finish their reloads
+	ReloadCoordinationTimeout time.Duration
+
+	// WebSocketReloadThreshold is the number of active WebSocket connections
+	// above which the reloads are deferred. Zero never defers the reloads
+	WebSocketReloadThreshold int
+	// WebSocketReloadMaxDelay is the maximum time a reload is deferred
+	WebSocketReloadMaxDelay time.Duration
+
+	// ListPageSize is the number of objects read in each request of the
+	// initial list of the resources. Zero lists them in a single request
+	ListPageSize int64
+
+	// ShutdownGracePeriod is the time NGINX keeps serving traffic after
+	// the health checks start failing during a shutdown
+	ShutdownGracePeriod time.Duration
+
+	// Preflight indicates the controller only checks the system is able
+	// to run it and exits
+	Preflight bool
+
+	// ShardIndex and ShardCount split the hostnames between ShardCount
+	// controllers. This one configures the hostnames of the shard ShardIndex
+	ShardIndex int
+	ShardCount int
+
+	// DuplicatePathPolicy defines how to handle the Ingresses with a host
+	// and path already defined in an older Ingress
+	DuplicatePathPolicy string
+
 	EnableSSLChainCompletion bool
 
+	// OutboundProxy is the URL of the HTTP proxy used by the controller to
+	// reach servers outside the cluster, except the hosts of OutboundNoProxy
+	OutboundProxy   *url.URL
+	OutboundNoProxy []string
+
 	FakeCertificatePath string
 	FakeCertificateSHA  string
 
 	SyncRateLimit float32
 
+	// DynamicConfigurationRetries is the number of times a failed
+	// dynamic configuration is retried in a sync
+	DynamicConfigurationRetries int
+	// DynamicConfigurationRetryDelay is the time before the first retry
+	DynamicConfigurationRetryDelay time.Duration
+	// DynamicConfigurationRetryFactor multiplies the time between
+	// retries after each retry
+	DynamicConfigurationRetryFactor float64
+	// DynamicConfigurationFailuresBeforeReload is the number of consecutive
+	// syncs with a failed dynamic configuration after which NGINX is
+	// reloaded. Zero never reloads NGINX because of these failures
+	DynamicConfigurationFailuresBeforeReload int
+
+	// SyncWatchdogDeadline is the maximum time a sync can run before it is
+	// reported as stuck. Zero disables the watchdog
+	SyncWatchdogDeadline time.Duration
+	// SyncWatchdogRestart exits the controller when a sync is stuck
+	SyncWatchdogRestart bool
+
 	DynamicCertificatesEnabled bool
+
+	// DenylistConfigMap is the ConfigMap that contains the networks blocked in all the servers
+	DenylistConfigMap string
+
+	// HostRedirectConfigMap is the ConfigMap that contains the hosts redirected to other hosts
+	HostRedirectConfigMap string
+
+	// SSLClockSkewLeeway is the time a certificate is considered valid before its validity period starts
+	SSLClockSkewLeeway time.Duration
+
+	// TemplatePath is the path of the template used to render the
+	// NGINX configuration
+	TemplatePath string
+
+	// Profile contains the name of the set of defaults used for the configuration
+	Profile string
 }
 
 // GetPublishService returns the Service used to set the load-balancer status of Ingresses.
+// When several Services are configured the first one is returned.
 func (n NGINXController) GetPublishService() *apiv1.Service {
-	s, err := n.store.GetService(n.cfg.PublishService)
+	svcName := strings.TrimSpace(strings.Split(n.cfg.PublishService, ",")[0])
+	s, err := n.store.GetService(svcName)
 	if err != nil {
 		return nil
 	}
@@ -105,20 +242,40 @@ func (n NGINXController) GetPublishService() *apiv1.Service {
 // configuration file and passes the resulting data structures to the backend
 // (OnUpdate) when a reload is deemed necessary.
 func (n *NGINXController) syncIngress(interface{}) error {
+	start := time.Now()
 	n.syncRateLimiter.Accept()
+	n.metricCollector.ObserveSyncRateLimiterWait(time.Since(start))
+	start = time.Now()
 
 	if n.syncQueue.IsShuttingDown() {
 		return nil
 	}
 
-	// sort Ingresses using the ResourceVersion field
+	n.syncWatchdog.start(start)
+	defer n.syncWatchdog.done()
+
+	// sort Ingresses using the CreationTimestamp and ResourceVersion fields
 	ings := n.store.ListIngresses()
 	sort.SliceStable(ings, func(i, j int) bool {
+		it := ings[i].CreationTimestamp
+		jt := ings[j].CreationTimestamp
+		if !it.Equal(&jt) {
+			return it.Before(&jt)
+		}
+
 		ir := ings[i].ResourceVersion
 		jr := ings[j].ResourceVersion
 		return ir < jr
 	})
 
+	ings = n.checkClassConflicts(ings)
+	ings = n.checkDuplicatePaths(ings)
+	ings = n.filterShardIngresses(ings)
+
+	if n.cfg.ChargebackLabel != "" {
+		n.metricCollector.SetChargebackIDs(chargebackIDs(ings, n.cfg.ChargebackLabel))
+	}
+
 	upstreams, servers := n.getBackendServers(ings)
 	var passUpstreams []*ingress.SSLPassthroughBackend
 
@@ -130,19 +287,28 @@ func (n *NGINXController) syncIngress(interface{}) error {
 		}
 
 		if !server.SSLPassthrough {
+			if server.ProxyProtocol != "" {
+				log.Warningf("Ignoring PROXY protocol to the backend of server %q without SSL Passthrough", server.Hostname)
+			}
+			continue
+		}
+
+		if server.Internal {
+			log.Warningf("Ignoring SSL Passthrough for internal server %q", server.Hostname)
 			continue
 		}
 
 		for _, loc := range server.Locations {
 			if loc.Path != rootLocation {
-				glog.Warningf("Ignoring SSL Passthrough for location %q in server %q", loc.Path, server.Hostname)
+				log.Warningf("Ignoring SSL Passthrough for location %q in server %q", loc.Path, server.Hostname)
 				continue
 			}
 			passUpstreams = append(passUpstreams, &ingress.SSLPassthroughBackend{
-				Backend:  loc.Backend,
-				Hostname: server.Hostname,
-				Service:  loc.Service,
-				Port:     loc.Port,
+				Backend:       loc.Backend,
+				Hostname:      server.Hostname,
+				Service:       loc.Service,
+				Port:          loc.Port,
+				ProxyProtocol: server.ProxyProtocol,
 			})
 			break
 		}
@@ -153,66 +319,122 @@ func (n *NGINXController) syncIngress(interface{}) error {
 		Servers:               servers,
 		PassthroughBackends:   passUpstreams,
 		BackendConfigChecksum: n.store.GetBackendConfiguration().Checksum,
+		RealIPRanges:          n.realIPRanges.get(),
 	}
 
-	if n.runningConfig.Equal(pcfg) {
-		glog.V(3).Infof("No configuration change detected, skipping backend reload.")
+	n.syncConnectTunnels(pcfg)
+	n.metricCollector.ObserveSyncDuration("build", time.Since(start))
+
+	if !n.isForceReload() && !n.overrides.isChanged() && n.runningConfig.Equal(pcfg) {
+		log.V(3).Infof("No configuration change detected, skipping backend reload.")
 		return nil
 	}
 
-	if !n.IsDynamicConfigurationEnough(pcfg) {
-		glog.Infof("Configuration changes detected, backend reload required.")
+	reload := n.isForceReload() || !n.IsDynamicConfigurationEnough(pcfg)
+	deferred := reload && n.deferReload()
 
-		hash, _ := hashstructure.Hash(pcfg, &hashstructure.HashOptions{
-			TagName: "json",
-		})
+	if reload && !deferred {
+		log.Infof("Configuration changes detected, backend reload required.")
 
+		hash := n.checksums.checksum(pcfg)
 		pcfg.ConfigurationChecksum = fmt.Sprintf("%v", hash)
 
+		if !n.isForceReload() && n.failedConfig.isRecentFailure(hash, time.Now()) {
+			log.V(2).Infof("Skipping reload, the configuration %v failed less than %v ago", hash, failedConfigRetryPeriod)
+			return nil
+		}
+
+		release := func() {}
+		if n.reloadCoordinator != nil {
+			start := time.Now()
+			release = n.reloadCoordinator.acquire()
+			n.metricCollector.ObserveSyncDuration("reload-wait", time.Since(start))
+		}
+
+		start := time.Now()
 		err := n.OnUpdate(*pcfg)
+		n.metricCollector.ObserveSyncDuration("reload", time.Since(start))
+		release()
 		if err != nil {
 			n.metricCollector.IncReloadErrorCount()
 			n.metricCollector.ConfigSuccess(hash, false)
-			glog.Errorf("Unexpected failure reloading the backend:\n%v", err)
+			n.setStale(staleReasonReload, true)
+			n.failedConfig = &failedConfig{hash: hash, time: time.Now()}
+			log.Errorf("Unexpected failure reloading the backend:\n%v", err)
 			return err
 		}
 
+		n.setStale(staleReasonReload, false)
+		n.setForceReload(false)
+		n.failedConfig = nil
+		n.reloads.add(time.Now())
+		n.reloadDeferredSince = time.Time{}
+
 		n.metricCollector.SetHosts(hosts)
 
-		glog.Infof("Backend successfully reloaded.")
+		log.Infof("Backend successfully reloaded.")
 		n.metricCollector.ConfigSuccess(hash, true)
 		n.metricCollector.IncReloadCount()
 		n.metricCollector.SetSSLExpireTime(servers)
 	}
 
-	retry := wait.Backoff{
-		Steps:    15,
-		Duration: 1 * time.Second,
-		Factor:   0.8,
-		Jitter:   0.1,
+	// routing overrides are only part of the dynamic configuration
+	n.overrides.setChanged(false)
+	dcfg := n.applyRoutingOverrides(pcfg)
+	if deferred {
+		dcfg = withRunningBackends(dcfg, n.runningConfig)
 	}
 
-	err := wait.ExponentialBackoff(retry, func() (bool, error) {
-		err := configureDynamically(pcfg, n.cfg.ListenPorts.Status, n.cfg.DynamicCertificatesEnabled)
-		if err == nil {
-			glog.V(2).Infof("Dynamic reconfiguration succeeded.")
-			return true, nil
+	start = time.Now()
+	err := retryDynamically(n.dynamicConfigurationBackoff(), func() error {
+		posted, err := configureDynamically(dcfg, n.postedBackends, n.cfg.DynamicCertificatesEnabled)
+		if err != nil {
+			return err
 		}
 
-		glog.Warningf("Dynamic reconfiguration failed: %v", err)
-		return false, err
+		log.V(2).Infof("Dynamic reconfiguration succeeded.")
+		n.postedBackends = posted
+		n.dynamicChecksum.Store(posted.checksum)
+		return nil
 	})
+	n.metricCollector.ObserveSyncDuration("dynamic-configuration", time.Since(start))
+	if err != nil {
+		n.overrides.setChanged(true)
+		n.setStale(staleReasonDynamic, true)
+		n.dynamicConfigurationFailed()
+		log.Errorf("Unexpected failure reconfiguring NGINX:\n%v", err)
+		return err
+	}
+
+	// the checksum reported by Lua confirms NGINX uses the posted backends
+	err = n.checkDynamicConfiguration(n.cfg.HealthCheckTimeout)
 	if err != nil {
-		glog.Errorf("Unexpected failure reconfiguring NGINX:\n%v", err)
+		// the next sync posts all the backends
+		n.postedBackends = nil
+		n.setStale(staleReasonDynamic, true)
+		n.dynamicConfigurationFailed()
+		log.Errorf("Unexpected failure verifying the dynamic configuration of NGINX: %v", err)
 		return err
 	}
 
+	n.dynamicFailures = 0
+	n.setStale(staleReasonDynamic, false)
+	n.setStale(staleReasonReloadDeferred, deferred)
+	if deferred {
+		// the running configuration is kept to reload NGINX in the next sync
+		return nil
+	}
+
 	ri := getRemovedIngresses(n.runningConfig, pcfg)
 	re := getRemovedHosts(n.runningConfig, pcfg)
 	n.metricCollector.RemoveMetrics(ri, re)
 
 	n.runningConfig = pcfg
 
+	if n.cfg.PublishEffectiveConfig {
+		n.effectiveConfigSource.Store(pcfg)
+	}
+
 	return nil
 }
 
@@ -231,14 +453,14 @@ func (n *NGINXController) getDefaultUpstream() *ingress.Backend {
 
 	svc, err := n.store.GetService(svcKey)
 	if err != nil {
-		glog.Warningf("Error getting default backend %q: %v", svcKey, err)
+		log.Warningf("Error getting default backend %q: %v", svcKey, err)
 		upstream.Endpoints = append(upstream.Endpoints, n.DefaultEndpoint())
 		return upstream
 	}
 
-	endps := getEndpoints(svc, &svc.Spec.Ports[0], apiv1.ProtocolTCP, n.store.GetServiceEndpoints)
+	endps := getEndpoints(svc, &svc.Spec.Ports[0], apiv1.ProtocolTCP, false, n.store.GetServiceEndpoints)
 	if len(endps) == 0 {
-		glog.Warningf("Service %q does not have any active Endpoint", svcKey)
+		log.Warningf("Service %q does not have any active Endpoint", svcKey)
 		endps = []ingress.Endpoint{n.DefaultEndpoint()}
 	}
 
@@ -252,15 +474,21 @@ func (n *NGINXController) getDefaultUpstream() *ingress.Backend {
 // service name and port are the same.
 func (n *NGINXController) getBackendServers(ingresses []*extensions.Ingress) ([]*ingress.Backend, []*ingress.Server) {
 	du := n.getDefaultUpstream()
-	upstreams := n.createUpstreams(ingresses, du)
-	servers := n.createServers(ingresses, upstreams, du)
+	resolved := n.resolveIngresses(ingresses)
+	upstreams := n.createUpstreams(ingresses, du, resolved)
+	servers := n.createServers(ingresses, upstreams, du, resolved)
+
+	// annotations of the locations defined in several Ingresses
+	merged := make(map[*ingress.Location]map[string]string)
+
+	canaryConflicts := sets.NewString()
 
 	for _, ing := range ingresses {
 		ingKey := k8s.MetaNamespaceKey(ing)
 
-		anns, err := n.store.GetIngressAnnotations(ingKey)
+		anns, err := resolved.ingressAnnotations(ingKey)
 		if err != nil {
-			glog.Errorf("Error getting Ingress annotations %q: %v", ingKey, err)
+			log.Errorf("Error getting Ingress annotations %q: %v", ingKey, err)
 		}
 
 		for _, rule := range ing.Spec.Rules {
@@ -276,7 +504,7 @@ func (n *NGINXController) getBackendServers(ingresses []*extensions.Ingress) ([]
 
 			if rule.HTTP == nil &&
 				host != defServerName {
-				glog.V(3).Infof("Ingress %q does not contain any HTTP rule, using default backend", ingKey)
+				log.V(3).Infof("Ingress %q does not contain any HTTP rule, using default backend", ingKey)
 				continue
 			}
 
@@ -287,16 +515,16 @@ func (n *NGINXController) getBackendServers(ingresses []*extensions.Ingress) ([]
 			if server.CertificateAuth.CAFileName == "" {
 				server.CertificateAuth = anns.CertificateAuth
 				if server.CertificateAuth.Secret != "" && server.CertificateAuth.CAFileName == "" {
-					glog.V(3).Infof("Secret %q has no 'ca.crt' key, mutual authentication disabled for Ingress %q",
+					log.V(3).Infof("Secret %q has no 'ca.crt' key, mutual authentication disabled for Ingress %q",
 						server.CertificateAuth.Secret, ingKey)
 				}
 			} else {
-				glog.V(3).Infof("Server %q is already configured for mutual authentication (Ingress %q)",
+				log.V(3).Infof("Server %q is already configured for mutual authentication (Ingress %q)",
 					server.Hostname, ingKey)
 			}
 
 			if rule.HTTP == nil {
-				glog.V(3).Infof("Ingress %q does not contain any HTTP rule, using default backend", ingKey)
+				log.V(3).Infof("Ingress %q does not contain any HTTP rule, using default backend", ingKey)
 				continue
 			}
 
@@ -315,18 +543,31 @@ func (n *NGINXController) getBackendServers(ingresses []*extensions.Ingress) ([]
 					nginxPath = path.Path
 				}
 
+				if ingress.IsInternalPath(nginxPath) {
+					log.Warningf("Ignoring path %q of Ingress %q: the path is reserved for internal locations", nginxPath, ingKey)
+					continue
+				}
+
 				addLoc := true
 				for _, loc := range server.Locations {
 					if loc.Path == nginxPath {
 						addLoc = false
 
 						if !loc.IsDefBackend {
-							glog.V(3).Infof("Location %q already configured for server %q with upstream %q (Ingress %q)",
+							if n.cfg.DuplicatePathPolicy == DuplicatePathMerge && loc.Ingress != nil &&
+								k8s.MetaNamespaceKey(loc.Ingress) != ingKey {
+								log.V(3).Infof("Merging annotations of Ingress %q into location %q for server %q",
+									ingKey, loc.Path, server.Hostname)
+								n.mergeLocationAnnotations(loc, ing, merged)
+								break
+							}
+
+							log.V(3).Infof("Location %q already configured for server %q with upstream %q (Ingress %q)",
 								loc.Path, server.Hostname, loc.Backend, ingKey)
 							break
 						}
 
-						glog.V(3).Infof("Replacing location %q for server %q with upstream %q to use upstream %q (Ingress %q)",
+						log.V(3).Infof("Replacing location %q for server %q with upstream %q to use upstream %q (Ingress %q)",
 							loc.Path, server.Hostname, loc.Backend, ups.Name, ingKey)
 
 						loc.Backend = ups.Name
@@ -334,26 +575,7 @@ func (n *NGINXController) getBackendServers(ingresses []*extensions.Ingress) ([]
 						loc.Port = ups.Port
 						loc.Service = ups.Service
 						loc.Ingress = ing
-						loc.BasicDigestAuth = anns.BasicDigestAuth
-						loc.ClientBodyBufferSize = anns.ClientBodyBufferSize
-						loc.ConfigurationSnippet = anns.ConfigurationSnippet
-						loc.CorsConfig = anns.CorsConfig
-						loc.ExternalAuth = anns.ExternalAuth
-						loc.Proxy = anns.Proxy
-						loc.RateLimit = anns.RateLimit
-						loc.Redirect = anns.Redirect
-						loc.Rewrite = anns.Rewrite
-						loc.UpstreamVhost = anns.UpstreamVhost
-						loc.Whitelist = anns.Whitelist
-						loc.Denied = anns.Denied
-						loc.XForwardedPrefix = anns.XForwardedPrefix
-						loc.UsePortInRedirects = anns.UsePortInRedirects
-						loc.Connection = anns.Connection
-						loc.Logs = anns.Logs
-						loc.LuaRestyWAF = anns.LuaRestyWAF
-						loc.InfluxDB = anns.InfluxDB
-						loc.DefaultBackend = anns.DefaultBackend
-						loc.BackendProtocol = anns.BackendProtocol
+						setLocationAnnotations(loc, anns)
 
 						if loc.Redirect.FromToWWW {
 							server.RedirectFromToWWW = true
@@ -364,7 +586,7 @@ func (n *NGINXController) getBackendServers(ingresses []*extensions.Ingress) ([]
 
 				// new location
 				if addLoc {
-					glog.V(3).Infof("Adding location %q for server %q with upstream %q (Ingress %q)",
+					log.V(3).Infof("Adding location %q for server %q with upstream %q (Ingress %q)",
 						nginxPath, server.Hostname, ups.Name, ingKey)
 
 					loc := &ingress.Location{
@@ -380,6 +602,7 @@ func (n *NGINXController) getBackendServers(ingresses []*extensions.Ingress) ([]
 						CorsConfig:           anns.CorsConfig,
 						ExternalAuth:         anns.ExternalAuth,
 						Proxy:                anns.Proxy,
+						ProxyCache:           anns.ProxyCache,
 						RateLimit:            anns.RateLimit,
 						Redirect:             anns.Redirect,
 						Rewrite:              anns.Rewrite,
@@ -394,6 +617,19 @@ func (n *NGINXController) getBackendServers(ingresses []*extensions.Ingress) ([]
 						InfluxDB:             anns.InfluxDB,
 						DefaultBackend:       anns.DefaultBackend,
 						BackendProtocol:      anns.BackendProtocol,
+						Brotli:               anns.Brotli,
+						Satisfy:              anns.Satisfy,
+						Priority:             anns.Priority,
+						AuthOIDC:             anns.AuthOIDC,
+						AllowedMethods:       anns.AllowedMethods,
+						GeoIPFilter:          anns.GeoIPFilter,
+						ConnectProxy:         anns.ConnectProxy,
+						Compression:          anns.Compression,
+						FastCGI:              anns.FastCGI,
+						ProxySSL:             anns.ProxySSL,
+						Opentracing:          anns.Opentracing,
+						SizeMetrics:          anns.SizeMetrics,
+						WebSocketHeavy:       anns.WebSocketHeavy,
 					}
 
 					if loc.Redirect.FromToWWW {
@@ -418,14 +654,21 @@ func (n *NGINXController) getBackendServers(ingresses []*extensions.Ingress) ([]
 					locs[host] = append(locs[host], path.Path)
 				}
 			}
+
+			if len(anns.NoAuthLocations) > 0 {
+				addNoAuthLocations(server, ingKey, anns.NoAuthLocations)
+			}
 		}
 
 		if anns.Canary.Enabled {
-			glog.Infof("Canary ingress %v detected. Finding eligible backends to merge into.", ing.Name)
-			mergeAlternativeBackends(ing, upstreams, servers)
+			log.Infof("Canary ingress %v detected. Finding eligible backends to merge into.", ing.Name)
+			conflicts := mergeAlternativeBackends(ing, upstreams, servers)
+			n.reportCanaryConflicts(ing, conflicts, canaryConflicts)
 		}
 	}
 
+	n.canaryConflicts = canaryConflicts
+
 	aUpstreams := make([]*ingress.Backend, 0, len(upstreams))
 
 	for _, upstream := range upstreams {
@@ -434,20 +677,20 @@ func (n *NGINXController) getBackendServers(ingresses []*extensions.Ingress) ([]
 			for _, location := range server.Locations {
 				if upstream.Name == location.Backend {
 					if len(upstream.Endpoints) == 0 {
-						glog.V(3).Infof("Upstream %q has no active Endpoint", upstream.Name)
+						log.V(3).Infof("Upstream %q has no active Endpoint", upstream.Name)
 
 						location.Backend = "" // for nginx.tmpl checking
 
 						// check if the location contains endpoints and a custom default backend
 						if location.DefaultBackend != nil {
 							sp := location.DefaultBackend.Spec.Ports[0]
-							endps := getEndpoints(location.DefaultBackend, &sp, apiv1.ProtocolTCP, n.store.GetServiceEndpoints)
+							endps := getEndpoints(location.DefaultBackend, &sp, apiv1.ProtocolTCP, false, n.store.GetServiceEndpoints)
 							if len(endps) > 0 {
-								glog.V(3).Infof("Using custom default backend for location %q in server %q (Service \"%v/%v\")",
+								log.V(3).Infof("Using custom default backend for location %q in server %q (Service \"%v/%v\")",
 									location.Path, server.Hostname, location.DefaultBackend.Namespace, location.DefaultBackend.Name)
 
 								nb := upstream.DeepCopy()
-								name := fmt.Sprintf("custom-default-backend-%v", upstream.Name)
+								name := customDefaultBackendPrefix + upstream.Name
 								nb.Name = name
 								nb.Endpoints = endps
 								aUpstreams = append(aUpstreams, nb)
@@ -459,7 +702,7 @@ func (n *NGINXController) getBackendServers(ingresses []*extensions.Ingress) ([]
 					if server.SSLPassthrough {
 						if location.Path == rootLocation {
 							if location.Backend == defUpstreamName {
-								glog.Warningf("Server %q has no default backend, ignoring SSL Passthrough.", server.Hostname)
+								log.Warningf("Server %q has no default backend, ignoring SSL Passthrough.", server.Hostname)
 								continue
 							}
 							isHTTPSfrom = append(isHTTPSfrom, server)
@@ -474,23 +717,37 @@ func (n *NGINXController) getBackendServers(ingresses []*extensions.Ingress) ([]
 		}
 	}
 
+	cfg := n.store.GetBackendConfiguration()
+	drainPeriod := time.Duration(cfg.EndpointDrainPeriod) * time.Second
+	now := time.Now()
+
 	// create the list of upstreams and skip those without Endpoints
 	for _, upstream := range upstreams {
+		if n.drainer.drain(upstream, drainPeriod, now) {
+			// sync again to remove the endpoints at the end of the drain period
+			time.AfterFunc(drainPeriod, func() {
+				n.syncQueue.EnqueueTask(task.GetDummyObject("endpoint-drain"))
+			})
+		}
+
 		if len(upstream.Endpoints) == 0 {
 			continue
 		}
+		n.setEndpointWeights(upstream)
 		aUpstreams = append(aUpstreams, upstream)
 	}
 
+	n.drainer.prune(sets.StringKeySet(upstreams))
+
 	aServers := make([]*ingress.Server, 0, len(servers))
 	for _, value := range servers {
-		sort.SliceStable(value.Locations, func(i, j int) bool {
-			return value.Locations[i].Path > value.Locations[j].Path
-		})
+		if value.Internal && !n.cfg.ListenPorts.HasInternal() {
+			log.Warningf("Ignoring internal server %q, the internal ports are not configured (flags --internal-http-port and --internal-https-port)", value.Hostname)
+			continue
+		}
 
-		sort.SliceStable(value.Locations, func(i, j int) bool {
-			return len(value.Locations[i].Path) > len(value.Locations[j].Path)
-		})
+		sortLocations(value.Locations)
+		setLocationLabels(value.Locations, cfg)
 		aServers = append(aServers, value)
 	}
 
@@ -505,25 +762,82 @@ func (n *NGINXController) getBackendServers(ingresses []*extensions.Ingress) ([]
 	return aUpstreams, aServers
 }
 
+// sortLocations sorts the locations of a server by priority (location-priority
+// annotation) and then by path length, longest first. NGINX checks regular
+// expressions in the order they are defined, which means the priority makes
+// explicit which of the overlapping paths of different Ingresses is used.
+func sortLocations(locations []*ingress.Location) {
+	sort.SliceStable(locations, func(i, j int) bool {
+		return locations[i].Path > locations[j].Path
+	})
+
+	sort.SliceStable(locations, func(i, j int) bool {
+		return len(locations[i].Path) > len(locations[j].Path)
+	})
+
+	sort.SliceStable(locations, func(i, j int) bool {
+		return locations[i].Priority > locations[j].Priority
+	})
+}
+
+// setLocationAnnotations sets the configuration of a location defined by
+// the annotations of an Ingress
+func setLocationAnnotations(loc *ingress.Location, anns *annotations.Ingress) {
+	loc.BasicDigestAuth = anns.BasicDigestAuth
+	loc.ClientBodyBufferSize = anns.ClientBodyBufferSize
+	loc.ConfigurationSnippet = anns.ConfigurationSnippet
+	loc.CorsConfig = anns.CorsConfig
+	loc.ExternalAuth = anns.ExternalAuth
+	loc.Proxy = anns.Proxy
+	loc.ProxyCache = anns.ProxyCache
+	loc.RateLimit = anns.RateLimit
+	loc.Redirect = anns.Redirect
+	loc.Rewrite = anns.Rewrite
+	loc.UpstreamVhost = anns.UpstreamVhost
+	loc.Whitelist = anns.Whitelist
+	loc.Denied = anns.Denied
+	loc.XForwardedPrefix = anns.XForwardedPrefix
+	loc.UsePortInRedirects = anns.UsePortInRedirects
+	loc.Connection = anns.Connection
+	loc.Logs = anns.Logs
+	loc.LuaRestyWAF = anns.LuaRestyWAF
+	loc.InfluxDB = anns.InfluxDB
+	loc.DefaultBackend = anns.DefaultBackend
+	loc.BackendProtocol = anns.BackendProtocol
+	loc.Brotli = anns.Brotli
+	loc.Satisfy = anns.Satisfy
+	loc.Priority = anns.Priority
+	loc.AuthOIDC = anns.AuthOIDC
+	loc.AllowedMethods = anns.AllowedMethods
+	loc.GeoIPFilter = anns.GeoIPFilter
+	loc.ConnectProxy = anns.ConnectProxy
+	loc.Compression = anns.Compression
+	loc.FastCGI = anns.FastCGI
+	loc.ProxySSL = anns.ProxySSL
+	loc.Opentracing = anns.Opentracing
+	loc.SizeMetrics = anns.SizeMetrics
+	loc.WebSocketHeavy = anns.WebSocketHeavy
+}
+
 // createUpstreams creates the NGINX upstreams (Endpoints) for each Service
 // referenced in Ingress rules.
-func (n *NGINXController) createUpstreams(data []*extensions.Ingress, du *ingress.Backend) map[string]*ingress.Backend {
+func (n *NGINXController) createUpstreams(data []*extensions.Ingress, du *ingress.Backend, resolved *syncResolution) map[string]*ingress.Backend {
 	upstreams := make(map[string]*ingress.Backend)
 	upstreams[defUpstreamName] = du
 
 	for _, ing := range data {
 		ingKey := k8s.MetaNamespaceKey(ing)
 
-		anns, err := n.store.GetIngressAnnotations(ingKey)
+		anns, err := resolved.ingressAnnotations(ingKey)
 		if err != nil {
-			glog.Errorf("Error getting Ingress annotations %q: %v", ingKey, err)
+			log.Errorf("Error getting Ingress annotations %q: %v", ingKey, err)
 		}
 
 		var defBackend string
 		if ing.Spec.Backend != nil {
 			defBackend = upstreamName(ing.Namespace, ing.Spec.Backend.ServiceName, ing.Spec.Backend.ServicePort)
 
-			glog.V(3).Infof("Creating upstream %q", defBackend)
+			log.V(3).Infof("Creating upstream %q", defBackend)
 			upstreams[defBackend] = newUpstream(defBackend)
 			if upstreams[defBackend].SecureCACert.Secret == "" {
 				upstreams[defBackend].SecureCACert = anns.SecureUpstream.CACert
@@ -534,6 +848,12 @@ func (n *NGINXController) createUpstreams(data []*extensions.Ingress, du *ingres
 			if upstreams[defBackend].LoadBalancing == "" {
 				upstreams[defBackend].LoadBalancing = anns.LoadBalancing
 			}
+			if upstreams[defBackend].NextUpstreamBudget == 0 {
+				upstreams[defBackend].NextUpstreamBudget = anns.Proxy.NextUpstreamBudget
+			}
+			if !upstreams[defBackend].SSLServerName.Enabled {
+				upstreams[defBackend].SSLServerName = anns.ProxySSL.ServerName
+			}
 
 			svcKey := fmt.Sprintf("%v/%v", ing.Namespace, ing.Spec.Backend.ServiceName)
 
@@ -541,7 +861,7 @@ func (n *NGINXController) createUpstreams(data []*extensions.Ingress, du *ingres
 			if anns.ServiceUpstream {
 				endpoint, err := n.getServiceClusterEndpoint(svcKey, ing.Spec.Backend)
 				if err != nil {
-					glog.Errorf("Failed to determine a suitable ClusterIP Endpoint for Service %q: %v", svcKey, err)
+					log.Errorf("Failed to determine a suitable ClusterIP Endpoint for Service %q: %v", svcKey, err)
 				} else {
 					upstreams[defBackend].Endpoints = []ingress.Endpoint{endpoint}
 				}
@@ -551,22 +871,35 @@ func (n *NGINXController) createUpstreams(data []*extensions.Ingress, du *ingres
 			if anns.Canary.Enabled {
 				upstreams[defBackend].NoServer = true
 				upstreams[defBackend].TrafficShapingPolicy = ingress.TrafficShapingPolicy{
-					Weight: anns.Canary.Weight,
-					Header: anns.Canary.Header,
-					Cookie: anns.Canary.Cookie,
+					Weight:        anns.Canary.Weight,
+					WeightTotal:   anns.Canary.WeightTotal,
+					Header:        anns.Canary.Header,
+					Cookie:        anns.Canary.Cookie,
+					UpstreamVhost: anns.UpstreamVhost,
+					Shadow:        anns.Canary.Shadow,
 				}
 			}
 
 			if len(upstreams[defBackend].Endpoints) == 0 {
-				endps, err := n.serviceEndpoints(svcKey, ing.Spec.Backend.ServicePort.String())
+				endps, err := resolved.serviceEndpoints(svcKey, ing.Spec.Backend.ServicePort.String(), anns.BackendProtocol, anns.IncludeNotReady)
 				upstreams[defBackend].Endpoints = append(upstreams[defBackend].Endpoints, endps...)
 				if err != nil {
-					glog.Warningf("Error creating upstream %q: %v", defBackend, err)
+					log.Warningf("Error creating upstream %q: %v", defBackend, err)
 				}
 			}
 
 		}
 
+		// the custom default backend is used for the paths not defined in the
+		// Ingress rules when the Ingress does not define a backend
+		if ing.Spec.Backend == nil && hasCustomDefaultBackend(anns) {
+			name := customDefaultBackendName(anns.DefaultBackend)
+			if _, ok := upstreams[name]; !ok {
+				log.V(3).Infof("Creating upstream %q", name)
+				upstreams[name] = n.newCustomDefaultBackend(anns.DefaultBackend)
+			}
+		}
+
 		for _, rule := range ing.Spec.Rules {
 			if rule.HTTP == nil {
 				continue
@@ -579,7 +912,7 @@ func (n *NGINXController) createUpstreams(data []*extensions.Ingress, du *ingres
 					continue
 				}
 
-				glog.V(3).Infof("Creating upstream %q", name)
+				log.V(3).Infof("Creating upstream %q", name)
 				upstreams[name] = newUpstream(name)
 				upstreams[name].Port = path.Backend.ServicePort
 
@@ -595,13 +928,21 @@ func (n *NGINXController) createUpstreams(data []*extensions.Ingress, du *ingres
 					upstreams[name].LoadBalancing = anns.LoadBalancing
 				}
 
+				if upstreams[name].NextUpstreamBudget == 0 {
+					upstreams[name].NextUpstreamBudget = anns.Proxy.NextUpstreamBudget
+				}
+
+				if !upstreams[name].SSLServerName.Enabled {
+					upstreams[name].SSLServerName = anns.ProxySSL.ServerName
+				}
+
 				svcKey := fmt.Sprintf("%v/%v", ing.Namespace, path.Backend.ServiceName)
 
 				// add the service ClusterIP as a single Endpoint instead of individual Endpoints
 				if anns.ServiceUpstream {
 					endpoint, err := n.getServiceClusterEndpoint(svcKey, &path.Backend)
 					if err != nil {
-						glog.Errorf("Failed to determine a suitable ClusterIP Endpoint for Service %q: %v", svcKey, err)
+						log.Errorf("Failed to determine a suitable ClusterIP Endpoint for Service %q: %v", svcKey, err)
 					} else {
 						upstreams[name].Endpoints = []ingress.Endpoint{endpoint}
 					}
@@ -611,16 +952,24 @@ func (n *NGINXController) createUpstreams(data []*extensions.Ingress, du *ingres
 				if anns.Canary.Enabled {
 					upstreams[name].NoServer = true
 					upstreams[name].TrafficShapingPolicy = ingress.TrafficShapingPolicy{
-						Weight: anns.Canary.Weight,
-						Header: anns.Canary.Header,
-						Cookie: anns.Canary.Cookie,
+						Weight:        anns.Canary.Weight,
+						WeightTotal:   anns.Canary.WeightTotal,
+						Header:        anns.Canary.Header,
+						Cookie:        anns.Canary.Cookie,
+						UpstreamVhost: anns.UpstreamVhost,
+						Shadow:        anns.Canary.Shadow,
 					}
 				}
 
 				if len(upstreams[name].Endpoints) == 0 {
-					endp, err := n.serviceEndpoints(svcKey, path.Backend.ServicePort.String())
+					endp, err := resolved.serviceEndpoints(svcKey, path.Backend.ServicePort.String(), anns.BackendProtocol, anns.IncludeNotReady)
 					if err != nil {
-						glog.Warningf("Error obtaining Endpoints for Service %q: %v", svcKey, err)
+						if anns.Redirect.URL != "" {
+							// the requests are redirected, the Service is not required
+							log.V(3).Infof("Error obtaining Endpoints for Service %q of Ingress %q with redirect: %v", svcKey, ingKey, err)
+							continue
+						}
+						log.Warningf("Error obtaining Endpoints for Service %q: %v", svcKey, err)
 						continue
 					}
 					upstreams[name].Endpoints = endp
@@ -628,7 +977,7 @@ func (n *NGINXController) createUpstreams(data []*extensions.Ingress, du *ingres
 
 				s, err := n.store.GetService(svcKey)
 				if err != nil {
-					glog.Warningf("Error obtaining Service %q: %v", svcKey, err)
+					log.Warningf("Error obtaining Service %q: %v", svcKey, err)
 					continue
 				}
 
@@ -637,9 +986,145 @@ func (n *NGINXController) createUpstreams(data []*extensions.Ingress, du *ingres
 		}
 	}
 
+	// the query routing is configured once all the upstreams referenced
+	// in Ingress rules exist, so they are created using their annotations
+	for _, ing := range data {
+		ingKey := k8s.MetaNamespaceKey(ing)
+
+		anns, err := resolved.ingressAnnotations(ingKey)
+		if err != nil {
+			continue
+		}
+
+		if anns.QueryRouting.Param != "" {
+			n.configureQueryRouting(ing, anns.QueryRouting, upstreams)
+		}
+
+		if anns.HeaderRouting.Header != "" {
+			n.configureHeaderRouting(ing, anns.HeaderRouting, upstreams)
+		}
+	}
+
 	return upstreams
 }
 
+// configureQueryRouting creates the upstreams of the Services referenced in
+// the query-routing-map annotation and configures the backends of the Ingress
+// to route requests to them depending on the value of the query parameter.
+// The backends are shared with other Ingresses using the same Service and
+// port, so the policy is indexed by the key of the Ingress and only applies
+// to the locations of this Ingress.
+func (n *NGINXController) configureQueryRouting(ing *extensions.Ingress, cfg queryrouting.Config, upstreams map[string]*ingress.Backend) {
+	backends := map[string]string{}
+	for _, route := range cfg.Routes {
+		name := upstreamName(ing.Namespace, route.ServiceName, route.ServicePort)
+
+		if _, ok := upstreams[name]; !ok {
+			ups, err := n.newServiceUpstream(name, ing.Namespace, route.ServiceName, route.ServicePort)
+			if err != nil {
+				log.Warningf("Error creating upstream %q: %v", name, err)
+				continue
+			}
+			upstreams[name] = ups
+		}
+
+		backends[route.Value] = name
+	}
+
+	policy := ingress.QueryRoutingPolicy{
+		Param:    cfg.Param,
+		Backends: backends,
+	}
+
+	key := k8s.MetaNamespaceKey(ing)
+	for _, name := range ingressUpstreamNames(ing) {
+		if ups, ok := upstreams[name]; ok {
+			if ups.QueryRoutingPolicies == nil {
+				ups.QueryRoutingPolicies = map[string]ingress.QueryRoutingPolicy{}
+			}
+			ups.QueryRoutingPolicies[key] = policy
+		}
+	}
+}
+
+// configureHeaderRouting creates an alternative backend for each entry of the
+// header-routing-map annotation and adds them to the backends of the Ingress.
+// Alternative backends are not shared with other Ingresses because the
+// traffic shaping policy is specific to the Ingress.
+func (n *NGINXController) configureHeaderRouting(ing *extensions.Ingress, cfg headerrouting.Config, upstreams map[string]*ingress.Backend) {
+	alternatives := []string{}
+	for i, route := range cfg.Routes {
+		name := fmt.Sprintf("%v-%v-header-%v-%v-%v", ing.Namespace, ing.Name, i, route.ServiceName, route.ServicePort.String())
+
+		ups, err := n.newServiceUpstream(name, ing.Namespace, route.ServiceName, route.ServicePort)
+		if err != nil {
+			log.Warningf("Error creating upstream %q: %v", name, err)
+			continue
+		}
+
+		ups.NoServer = true
+		ups.TrafficShapingPolicy = ingress.TrafficShapingPolicy{
+			Header:        cfg.Header,
+			HeaderValue:   route.Value,
+			HeaderPattern: route.Pattern,
+		}
+
+		upstreams[name] = ups
+		alternatives = append(alternatives, name)
+	}
+
+	for _, name := range ingressUpstreamNames(ing) {
+		if ups, ok := upstreams[name]; ok && !ups.NoServer {
+			ups.AlternativeBackends = append(ups.AlternativeBackends, alternatives...)
+		}
+	}
+}
+
+// newServiceUpstream returns an upstream with the Endpoints of a Service
+func (n *NGINXController) newServiceUpstream(name, namespace, service string, port intstr.IntOrString) (*ingress.Backend, error) {
+	svcKey := fmt.Sprintf("%v/%v", namespace, service)
+
+	endps, err := n.serviceEndpoints(svcKey, port.String(), "", false)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := n.store.GetService(svcKey)
+	if err != nil {
+		return nil, err
+	}
+
+	log.V(3).Infof("Creating upstream %q", name)
+	ups := newUpstream(name)
+	ups.Port = port
+	ups.Endpoints = endps
+	ups.Service = s
+
+	return ups, nil
+}
+
+// ingressUpstreamNames returns the names of the upstreams
+// referenced in the default backend and rules of an Ingress
+func ingressUpstreamNames(ing *extensions.Ingress) []string {
+	names := sets.NewString()
+
+	if ing.Spec.Backend != nil {
+		names.Insert(upstreamName(ing.Namespace, ing.Spec.Backend.ServiceName, ing.Spec.Backend.ServicePort))
+	}
+
+	for _, rule := range ing.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+
+		for _, path := range rule.HTTP.Paths {
+			names.Insert(upstreamName(ing.Namespace, path.Backend.ServiceName, path.Backend.ServicePort))
+		}
+	}
+
+	return names.List()
+}
+
 // getServiceClusterEndpoint returns an Endpoint corresponding to the ClusterIP
 // field of a Service.
 func (n *NGINXController) getServiceClusterEndpoint(svcKey string, backend *extensions.IngressBackend) (endpoint ingress.Endpoint, err error) {
@@ -676,7 +1161,8 @@ func (n *NGINXController) getServiceClusterEndpoint(svcKey string, backend *exte
 }
 
 // serviceEndpoints returns the upstream servers (Endpoints) associated with a Service.
-func (n *NGINXController) serviceEndpoints(svcKey, backendPort string) ([]ingress.Endpoint, error) {
+// The backend protocol of the Ingress chooses between several ports with the same number.
+func (n *NGINXController) serviceEndpoints(svcKey, backendPort, backendProtocol string, includeNotReady bool) ([]ingress.Endpoint, error) {
 	svc, err := n.store.GetService(svcKey)
 
 	var upstreams []ingress.Endpoint
@@ -684,39 +1170,30 @@ func (n *NGINXController) serviceEndpoints(svcKey, backendPort string) ([]ingres
 		return upstreams, err
 	}
 
-	glog.V(3).Infof("Obtaining ports information for Service %q", svcKey)
-	for _, servicePort := range svc.Spec.Ports {
-		// targetPort could be a string, use either the port name or number (int)
-		if strconv.Itoa(int(servicePort.Port)) == backendPort ||
-			servicePort.TargetPort.String() == backendPort ||
-			servicePort.Name == backendPort {
-
-			endps := getEndpoints(svc, &servicePort, apiv1.ProtocolTCP, n.store.GetServiceEndpoints)
-			if len(endps) == 0 {
-				glog.Warningf("Service %q does not have any active Endpoint.", svcKey)
-			}
+	log.V(3).Infof("Obtaining ports information for Service %q", svcKey)
+	// targetPort could be a string, use either the port name or number (int)
+	servicePort, choice := selectServicePort(svc, backendPort, backendProtocol)
+	if choice != "" {
+		n.reportServicePortChoice(svc, backendPort, choice)
+	}
 
-			if n.cfg.SortBackends {
-				sort.SliceStable(endps, func(i, j int) bool {
-					iName := endps[i].Address
-					jName := endps[j].Address
-					if iName != jName {
-						return iName < jName
-					}
+	if servicePort != nil {
+		endps := getEndpoints(svc, servicePort, apiv1.ProtocolTCP, includeNotReady, n.store.GetServiceEndpoints)
+		if len(endps) == 0 {
+			log.Warningf("Service %q does not have any active Endpoint.", svcKey)
+		}
 
-					return endps[i].Port < endps[j].Port
-				})
-			}
-			upstreams = append(upstreams, endps...)
-			break
+		if n.cfg.SortBackends {
+			sortEndpoints(endps)
 		}
+		upstreams = append(upstreams, endps...)
 	}
 
 	// Ingress with an ExternalName Service and no port defined for that Service
 	if len(svc.Spec.Ports) == 0 && svc.Spec.Type == apiv1.ServiceTypeExternalName {
 		externalPort, err := strconv.Atoi(backendPort)
 		if err != nil {
-			glog.Warningf("Only numeric ports are allowed in ExternalName Services: %q is not a valid port number.", backendPort)
+			log.Warningf("Only numeric ports are allowed in ExternalName Services: %q is not a valid port number.", backendPort)
 			return upstreams, nil
 		}
 
@@ -725,9 +1202,9 @@ func (n *NGINXController) serviceEndpoints(svcKey, backendPort string) ([]ingres
 			Port:       int32(externalPort),
 			TargetPort: intstr.FromString(backendPort),
 		}
-		endps := getEndpoints(svc, &servicePort, apiv1.ProtocolTCP, n.store.GetServiceEndpoints)
+		endps := getEndpoints(svc, &servicePort, apiv1.ProtocolTCP, false, n.store.GetServiceEndpoints)
 		if len(endps) == 0 {
-			glog.Warningf("Service %q does not have any active Endpoint.", svcKey)
+			log.Warningf("Service %q does not have any active Endpoint.", svcKey)
 			return upstreams, nil
 		}
 
@@ -736,11 +1213,7 @@ func (n *NGINXController) serviceEndpoints(svcKey, backendPort string) ([]ingres
 	}
 
 	if !n.cfg.SortBackends {
-		rand.Seed(time.Now().UnixNano())
-		for i := range upstreams {
-			j := rand.Intn(i + 1)
-			upstreams[i], upstreams[j] = upstreams[j], upstreams[i]
-		}
+		n.shuffleEndpoints(svcKey, backendPort, upstreams)
 	}
 
 	return upstreams, nil
@@ -751,25 +1224,43 @@ func (n *NGINXController) serviceEndpoints(svcKey, backendPort string) ([]ingres
 // one root location, which uses a default backend if left unspecified.
 func (n *NGINXController) createServers(data []*extensions.Ingress,
 	upstreams map[string]*ingress.Backend,
-	du *ingress.Backend) map[string]*ingress.Server {
+	du *ingress.Backend,
+	resolved *syncResolution) map[string]*ingress.Server {
 
 	servers := make(map[string]*ingress.Server, len(data))
 	aliases := make(map[string]string, len(data))
 
 	bdef := n.store.GetDefaultBackend()
 	ngxProxy := proxy.Config{
-		BodySize:          bdef.ProxyBodySize,
-		ConnectTimeout:    bdef.ProxyConnectTimeout,
-		SendTimeout:       bdef.ProxySendTimeout,
-		ReadTimeout:       bdef.ProxyReadTimeout,
-		BufferSize:        bdef.ProxyBufferSize,
-		CookieDomain:      bdef.ProxyCookieDomain,
-		CookiePath:        bdef.ProxyCookiePath,
-		NextUpstream:      bdef.ProxyNextUpstream,
-		NextUpstreamTries: bdef.ProxyNextUpstreamTries,
-		RequestBuffering:  bdef.ProxyRequestBuffering,
-		ProxyRedirectFrom: bdef.ProxyRedirectFrom,
-		ProxyBuffering:    bdef.ProxyBuffering,
+		BodySize:            bdef.ProxyBodySize,
+		ConnectTimeout:      bdef.ProxyConnectTimeout,
+		SendTimeout:         bdef.ProxySendTimeout,
+		ReadTimeout:         bdef.ProxyReadTimeout,
+		BufferSize:          bdef.ProxyBufferSize,
+		CookieDomain:        bdef.ProxyCookieDomain,
+		CookiePath:          bdef.ProxyCookiePath,
+		NextUpstream:        bdef.ProxyNextUpstream,
+		NextUpstreamTries:   bdef.ProxyNextUpstreamTries,
+		NextUpstreamTimeout: bdef.ProxyNextUpstreamTimeout,
+		NextUpstreamBudget:  bdef.ProxyNextUpstreamBudget,
+		RequestBuffering:    bdef.ProxyRequestBuffering,
+		ProxyRedirectFrom:   bdef.ProxyRedirectFrom,
+		ProxyBuffering:      bdef.ProxyBuffering,
+		ProxyBind:           bdef.ProxyBind,
+		SSLSessionReuse:     bdef.ProxySSLSessionReuse,
+	}
+
+	ngxBrotli := brotli.Config{
+		Enabled: bdef.EnableBrotli,
+		Level:   bdef.BrotliLevel,
+		Types:   bdef.BrotliTypes,
+	}
+
+	ngxHSTS := hsts.Config{
+		Enabled:           bdef.HSTS,
+		MaxAge:            bdef.HSTSMaxAge,
+		IncludeSubdomains: bdef.HSTSIncludeSubdomains,
+		Preload:           bdef.HSTSPreload,
 	}
 
 	// generated on Start() with createDefaultSSLCertificate()
@@ -796,17 +1287,20 @@ func (n *NGINXController) createServers(data []*extensions.Ingress,
 				IsDefBackend: true,
 				Backend:      du.Name,
 				Proxy:        ngxProxy,
+				Brotli:       ngxBrotli,
 				Service:      du.Service,
 			},
-		}}
+		},
+		HSTS: ngxHSTS,
+	}
 
 	// initialize all other servers
 	for _, ing := range data {
 		ingKey := k8s.MetaNamespaceKey(ing)
 
-		anns, err := n.store.GetIngressAnnotations(ingKey)
+		anns, err := resolved.ingressAnnotations(ingKey)
 		if err != nil {
-			glog.Errorf("Error getting Ingress annotations %q: %v", ingKey, err)
+			log.Errorf("Error getting Ingress annotations %q: %v", ingKey, err)
 		}
 
 		// default upstream name
@@ -822,7 +1316,7 @@ func (n *NGINXController) createServers(data []*extensions.Ingress,
 				// special "catch all" case, Ingress with a backend but no rule
 				defLoc := servers[defServerName].Locations[0]
 				if defLoc.IsDefBackend && len(ing.Spec.Rules) == 0 {
-					glog.Infof("Ingress %q defines a backend but no rule. Using it to configure the catch-all server %q",
+					log.Infof("Ingress %q defines a backend but no rule. Using it to configure the catch-all server %q",
 						ingKey, defServerName)
 
 					defLoc.IsDefBackend = false
@@ -831,25 +1325,9 @@ func (n *NGINXController) createServers(data []*extensions.Ingress,
 					defLoc.Ingress = ing
 
 					// customize using Ingress annotations
-					defLoc.Logs = anns.Logs
-					defLoc.BasicDigestAuth = anns.BasicDigestAuth
-					defLoc.ClientBodyBufferSize = anns.ClientBodyBufferSize
-					defLoc.ConfigurationSnippet = anns.ConfigurationSnippet
-					defLoc.CorsConfig = anns.CorsConfig
-					defLoc.ExternalAuth = anns.ExternalAuth
-					defLoc.Proxy = anns.Proxy
-					defLoc.RateLimit = anns.RateLimit
-					// TODO: Redirect and rewrite can affect the catch all behavior, skip for now
-					// defLoc.Redirect = anns.Redirect
-					// defLoc.Rewrite = anns.Rewrite
-					defLoc.UpstreamVhost = anns.UpstreamVhost
-					defLoc.Whitelist = anns.Whitelist
-					defLoc.Denied = anns.Denied
-					defLoc.LuaRestyWAF = anns.LuaRestyWAF
-					defLoc.InfluxDB = anns.InfluxDB
-					defLoc.BackendProtocol = anns.BackendProtocol
+					setDefaultLocationAnnotations(defLoc, anns)
 				} else {
-					glog.V(3).Infof("Ingress %q defines both a backend and rules. Using its backend as default upstream for all its rules.",
+					log.V(3).Infof("Ingress %q defines both a backend and rules. Using its backend as default upstream for all its rules.",
 						ingKey)
 				}
 			}
@@ -873,12 +1351,32 @@ func (n *NGINXController) createServers(data []*extensions.Ingress,
 						IsDefBackend: true,
 						Backend:      un,
 						Proxy:        ngxProxy,
+						Brotli:       ngxBrotli,
 						Service:      &apiv1.Service{},
 					},
 				},
 				SSLPassthrough: anns.SSLPassthrough,
+				ProxyProtocol:  anns.ProxyProtocol,
+				HSTS:           anns.HSTS,
 				SSLCiphers:     anns.SSLCiphers,
 			}
+
+			// the paths not defined in the Ingress rules use the custom
+			// default backend, configured with the Ingress annotations
+			if un == du.Name && hasCustomDefaultBackend(anns) {
+				name := customDefaultBackendName(anns.DefaultBackend)
+				if _, ok := upstreams[name]; ok {
+					log.V(3).Infof("Using custom default backend for the paths of server %q not defined in Ingress %q (Service \"%v/%v\")",
+						host, ingKey, anns.DefaultBackend.Namespace, anns.DefaultBackend.Name)
+
+					defLoc := servers[host].Locations[0]
+					defLoc.Backend = name
+					defLoc.Service = anns.DefaultBackend
+					defLoc.Ingress = ing
+					defLoc.DefaultBackend = anns.DefaultBackend
+					setDefaultLocationAnnotations(defLoc, anns)
+				}
+			}
 		}
 	}
 
@@ -886,9 +1384,9 @@ func (n *NGINXController) createServers(data []*extensions.Ingress,
 	for _, ing := range data {
 		ingKey := k8s.MetaNamespaceKey(ing)
 
-		anns, err := n.store.GetIngressAnnotations(ingKey)
+		anns, err := resolved.ingressAnnotations(ingKey)
 		if err != nil {
-			glog.Errorf("Error getting Ingress annotations %q: %v", ingKey, err)
+			log.Errorf("Error getting Ingress annotations %q: %v", ingKey, err)
 		}
 
 		for _, rule := range ing.Spec.Rules {
@@ -904,7 +1402,7 @@ func (n *NGINXController) createServers(data []*extensions.Ingress,
 						aliases["Alias"] = host
 					}
 				} else {
-					glog.Warningf("Aliases already configured for server %q, skipping (Ingress %q)",
+					log.Warningf("Aliases already configured for server %q, skipping (Ingress %q)",
 						host, ingKey)
 				}
 			}
@@ -913,11 +1411,33 @@ func (n *NGINXController) createServers(data []*extensions.Ingress,
 				if servers[host].ServerSnippet == "" {
 					servers[host].ServerSnippet = anns.ServerSnippet
 				} else {
-					glog.Warningf("Server snippet already configured for server %q, skipping (Ingress %q)",
+					log.Warningf("Server snippet already configured for server %q, skipping (Ingress %q)",
 						host, ingKey)
 				}
 			}
 
+			if !anns.RealIP.IsEmpty() {
+				if servers[host].RealIP.IsEmpty() {
+					servers[host].RealIP = anns.RealIP
+				} else if !(&servers[host].RealIP).Equal(&anns.RealIP) {
+					log.Warningf("Real IP configuration already configured for server %q, skipping (Ingress %q)",
+						host, ingKey)
+				}
+			}
+
+			// the rules to deny requests of all the Ingresses of the server are combined
+			servers[host].Blocking.Merge(anns.Blocking)
+
+			// the server is internal if any of its Ingresses is internal, to
+			// never expose it in the public ports by mistake
+			if anns.Internal {
+				if host == defServerName {
+					log.Warningf("Ignoring annotation internal for the catch-all server (Ingress %q)", ingKey)
+				} else {
+					servers[host].Internal = true
+				}
+			}
+
 			// only add SSL ciphers if the server does not have them previously configured
 			if servers[host].SSLCiphers == "" && anns.SSLCiphers != "" {
 				servers[host].SSLCiphers = anns.SSLCiphers
@@ -929,14 +1449,14 @@ func (n *NGINXController) createServers(data []*extensions.Ingress,
 			}
 
 			if len(ing.Spec.TLS) == 0 {
-				glog.V(3).Infof("Ingress %q does not contains a TLS section.", ingKey)
+				log.V(3).Infof("Ingress %q does not contains a TLS section.", ingKey)
 				continue
 			}
 
 			tlsSecretName := extractTLSSecretName(host, ing, n.store.GetLocalSSLCert)
 
 			if tlsSecretName == "" {
-				glog.V(3).Infof("Host %q is listed in the TLS section but secretName is empty. Using default certificate.", host)
+				log.V(3).Infof("Host %q is listed in the TLS section but secretName is empty. Using default certificate.", host)
 				servers[host].SSLCert.PemFileName = defaultPemFileName
 				servers[host].SSLCert.PemSHA = defaultPemSHA
 				continue
@@ -945,7 +1465,7 @@ func (n *NGINXController) createServers(data []*extensions.Ingress,
 			secrKey := fmt.Sprintf("%v/%v", ing.Namespace, tlsSecretName)
 			cert, err := n.store.GetLocalSSLCert(secrKey)
 			if err != nil {
-				glog.Warningf("Error getting SSL certificate %q: %v. Using default certificate", secrKey, err)
+				log.Warningf("Error getting SSL certificate %q: %v. Using default certificate", secrKey, err)
 				servers[host].SSLCert.PemFileName = defaultPemFileName
 				servers[host].SSLCert.PemSHA = defaultPemSHA
 				continue
@@ -953,15 +1473,15 @@ func (n *NGINXController) createServers(data []*extensions.Ingress,
 
 			err = cert.Certificate.VerifyHostname(host)
 			if err != nil {
-				glog.Warningf("Unexpected error validating SSL certificate %q for server %q: %v", secrKey, host, err)
-				glog.Warning("Validating certificate against DNS names. This will be deprecated in a future version.")
+				log.Warningf("Unexpected error validating SSL certificate %q for server %q: %v", secrKey, host, err)
+				log.Warning("Validating certificate against DNS names. This will be deprecated in a future version.")
 				// check the Common Name field
 				// https://github.com/golang/go/issues/22922
 				err := verifyHostname(host, cert.Certificate)
 				if err != nil {
-					glog.Warningf("SSL certificate %q does not contain a Common Name or Subject Alternative Name for server %q: %v",
+					log.Warningf("SSL certificate %q does not contain a Common Name or Subject Alternative Name for server %q: %v",
 						secrKey, host, err)
-					glog.Warningf("Using default certificate")
+					log.Warningf("Using default certificate")
 					servers[host].SSLCert.PemFileName = defaultPemFileName
 					servers[host].SSLCert.PemSHA = defaultPemSHA
 					continue
@@ -976,15 +1496,17 @@ func (n *NGINXController) createServers(data []*extensions.Ingress,
 
 			servers[host].SSLCert = *cert
 
-			if cert.ExpireTime.Before(time.Now().Add(240 * time.Hour)) {
-				glog.Warningf("SSL certificate for server %q is about to expire (%v)", host, cert.ExpireTime)
+			if ssl.IsNotYetValid(cert.Certificate, time.Now()) {
+				n.reportNotYetValidCertificate(ing, secrKey, cert)
+			} else if cert.ExpireTime.Before(time.Now().Add(240 * time.Hour)) {
+				log.Warningf("SSL certificate for server %q is about to expire (%v)", host, cert.ExpireTime)
 			}
 		}
 	}
 
 	for alias, host := range aliases {
 		if _, ok := servers[alias]; ok {
-			glog.Warningf("Conflicting hostname (%v) and alias (%v). Removing alias to avoid conflicts.", host, alias)
+			log.Warningf("Conflicting hostname (%v) and alias (%v). Removing alias to avoid conflicts.", host, alias)
 			servers[host].Alias = ""
 		}
 	}
@@ -992,12 +1514,36 @@ func (n *NGINXController) createServers(data []*extensions.Ingress,
 	return servers
 }
 
+// reportNotYetValidCertificate warns about a certificate whose validity
+// period has not started yet, usually because the clocks of the nodes are
+// skewed. The warning and the Event are emitted only once per certificate.
+func (n *NGINXController) reportNotYetValidCertificate(ing *extensions.Ingress, secrKey string, cert *ingress.SSLCert) {
+	if n.notYetValidCerts == nil {
+		n.notYetValidCerts = sets.NewString()
+	}
+
+	key := fmt.Sprintf("%v/%v", secrKey, cert.PemSHA)
+	if n.notYetValidCerts.Has(key) {
+		return
+	}
+	n.notYetValidCerts.Insert(key)
+
+	msg := fmt.Sprintf("SSL certificate %q is not valid until %v", secrKey, cert.Certificate.NotBefore)
+	log.Warning(msg)
+	if n.recorder != nil {
+		n.recorder.Event(ing, apiv1.EventTypeWarning, "CertificateNotYetValid", msg)
+	}
+}
+
 // Compares an Ingress of a potential alternative backend's rules with each existing server and finds matching host + path pairs.
 // If a match is found, we know that this server should back the alternative backend and add the alternative backend
 // to a backend's alternative list.
 // If no match is found, then the serverless backend is deleted.
+// The alternative backends not added because of their weight are returned.
 func mergeAlternativeBackends(ing *extensions.Ingress, upstreams map[string]*ingress.Backend,
-	servers map[string]*ingress.Server) {
+	servers map[string]*ingress.Server) []error {
+
+	var conflicts []error
 
 	// merge catch-all alternative backends
 	if ing.Spec.Backend != nil {
@@ -1007,11 +1553,14 @@ func mergeAlternativeBackends(ing *extensions.Ingress, upstreams map[string]*ing
 
 		defLoc := servers[defServerName].Locations[0]
 
-		glog.Infof("matching backend %v found for alternative backend %v",
+		log.Infof("matching backend %v found for alternative backend %v",
 			upstreams[defLoc.Backend].Name, ups.Name)
 
-		upstreams[defLoc.Backend].AlternativeBackends =
-			append(upstreams[defLoc.Backend].AlternativeBackends, ups.Name)
+		if err := addAlternativeBackend(upstreams[defLoc.Backend], ups, upstreams); err != nil {
+			conflicts = append(conflicts, err)
+		} else if ups.TrafficShapingPolicy.Shadow {
+			defLoc.Shadow = true
+		}
 	}
 
 	for _, rule := range ing.Spec.Rules {
@@ -1021,6 +1570,7 @@ func mergeAlternativeBackends(ing *extensions.Ingress, upstreams map[string]*ing
 			ups := upstreams[upsName]
 
 			merged := false
+			conflicted := false
 
 			server := servers[rule.Host]
 
@@ -1031,22 +1581,33 @@ func mergeAlternativeBackends(ing *extensions.Ingress, upstreams map[string]*ing
 				}
 
 				if location.Path == path.Path && !upstreams[location.Backend].NoServer {
-					glog.Infof("matching backend %v found for alternative backend %v",
+					log.Infof("matching backend %v found for alternative backend %v",
 						upstreams[location.Backend].Name, ups.Name)
 
-					upstreams[location.Backend].AlternativeBackends =
-						append(upstreams[location.Backend].AlternativeBackends, ups.Name)
+					if err := addAlternativeBackend(upstreams[location.Backend], ups, upstreams); err != nil {
+						conflicts = append(conflicts, err)
+						conflicted = true
+						continue
+					}
+
+					if ups.TrafficShapingPolicy.Shadow {
+						location.Shadow = true
+					}
 
 					merged = true
 				}
 			}
 
 			if !merged {
-				glog.Warningf("unable to find real backend for alternative backend %v. Deleting.", ups.Name)
+				if !conflicted {
+					log.Warningf("unable to find real backend for alternative backend %v. Deleting.", ups.Name)
+				}
 				delete(upstreams, ups.Name)
 			}
 		}
 	}
+
+	return conflicts
 }
 
 // extractTLSSecretName returns the name of the Secret containing a SSL
@@ -1077,7 +1638,7 @@ func extractTLSSecretName(host string, ing *extensions.Ingress,
 
 		cert, err := getLocalSSLCert(secrKey)
 		if err != nil {
-			glog.Warningf("Error getting SSL certificate %q: %v", secrKey, err)
+			log.Warningf("Error getting SSL certificate %q: %v", secrKey, err)
 			continue
 		}
 
@@ -1089,7 +1650,7 @@ func extractTLSSecretName(host string, ing *extensions.Ingress,
 		if err != nil {
 			continue
 		}
-		glog.V(3).Infof("Found SSL certificate matching host %q: %q", host, secrKey)
+		log.V(3).Infof("Found SSL certificate matching host %q: %q", host, secrKey)
 		return tls.SecretName
 	}
 
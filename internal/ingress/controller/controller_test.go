@@ -21,11 +21,13 @@ import (
 	"crypto/x509/pkix"
 	"encoding/asn1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"reflect"
 	"testing"
 
 	extensions "k8s.io/api/extensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/queryrouting"
 )
 
 func TestMergeAlternativeBackends(t *testing.T) {
@@ -348,3 +350,112 @@ func fakeX509Cert(dnsNames []string) *x509.Certificate {
 		},
 	}
 }
+
+func TestSortLocations(t *testing.T) {
+	locations := []*ingress.Location{
+		{Path: "/"},
+		{Path: "/api/v1"},
+		{Path: "/api", Priority: 10},
+		{Path: "/static"},
+		{Path: "/low", Priority: -1},
+	}
+
+	sortLocations(locations)
+
+	expected := []string{"/api", "/static", "/api/v1", "/", "/low"}
+	for i, path := range expected {
+		if locations[i].Path != path {
+			t.Errorf("expected location %v to be %v but %v was returned", i, path, locations[i].Path)
+		}
+	}
+}
+
+func TestIngressUpstreamNames(t *testing.T) {
+	backend := extensions.IngressBackend{
+		ServiceName: "http-svc",
+		ServicePort: intstr.FromInt(80),
+	}
+
+	ing := &extensions.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example",
+			Namespace: "default",
+		},
+		Spec: extensions.IngressSpec{
+			Backend: &extensions.IngressBackend{
+				ServiceName: "default-svc",
+				ServicePort: intstr.FromString("http"),
+			},
+			Rules: []extensions.IngressRule{
+				{
+					Host: "example.com",
+				},
+				{
+					Host: "foo.bar",
+					IngressRuleValue: extensions.IngressRuleValue{
+						HTTP: &extensions.HTTPIngressRuleValue{
+							Paths: []extensions.HTTPIngressPath{
+								{Path: "/", Backend: backend},
+								{Path: "/api", Backend: backend},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	names := ingressUpstreamNames(ing)
+
+	expected := []string{"default-default-svc-http", "default-http-svc-80"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v but %v was returned", expected, names)
+	}
+}
+
+func TestConfigureQueryRouting(t *testing.T) {
+	newIngress := func(name string) *extensions.Ingress {
+		return &extensions.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+			Spec: extensions.IngressSpec{
+				Backend: &extensions.IngressBackend{
+					ServiceName: "http-svc",
+					ServicePort: intstr.FromInt(80),
+				},
+			},
+		}
+	}
+
+	upstreams := map[string]*ingress.Backend{
+		"default-http-svc-80": {Name: "default-http-svc-80"},
+		"default-api-v2-80":   {Name: "default-api-v2-80"},
+	}
+
+	n := &NGINXController{}
+	n.configureQueryRouting(newIngress("api"), queryrouting.Config{
+		Param: "version",
+		Routes: []queryrouting.Route{
+			{Value: "v2", ServiceName: "api-v2", ServicePort: intstr.FromInt(80)},
+		},
+	}, upstreams)
+	n.configureQueryRouting(newIngress("web"), queryrouting.Config{
+		Param: "release",
+	}, upstreams)
+
+	expected := map[string]ingress.QueryRoutingPolicy{
+		"default/api": {Param: "version", Backends: map[string]string{"v2": "default-api-v2-80"}},
+		"default/web": {Param: "release", Backends: map[string]string{}},
+	}
+
+	policies := upstreams["default-http-svc-80"].QueryRoutingPolicies
+	if !reflect.DeepEqual(policies, expected) {
+		t.Errorf("expected %v but %v was returned", expected, policies)
+	}
+
+	if upstreams["default-api-v2-80"].QueryRoutingPolicies != nil {
+		t.Errorf("expected no query routing policy in the routed backend")
+	}
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"k8s.io/ingress-nginx/internal/file"
+	ngx_template "k8s.io/ingress-nginx/internal/ingress/controller/template"
+	"k8s.io/ingress-nginx/internal/log"
+	"k8s.io/ingress-nginx/internal/watch"
+)
+
+// templateCache contains the templates of the custom-template key of the
+// configuration ConfigMap, indexed by path. Each file is watched to load
+// its changes independently of the other templates.
+type templateCache struct {
+	sync.Mutex
+
+	fs    file.Filesystem
+	funcs *ngx_template.FuncRegistry
+
+	// watchFiles indicates if the files are watched
+	watchFiles bool
+
+	// onChange is called after loading a change of a template
+	onChange func(path string)
+
+	templates map[string]*ngx_template.Template
+	watchers  map[string]watch.FileWatcher
+}
+
+func newTemplateCache(fs file.Filesystem, funcs *ngx_template.FuncRegistry, watchFiles bool, onChange func(path string)) *templateCache {
+	return &templateCache{
+		fs:         fs,
+		funcs:      funcs,
+		watchFiles: watchFiles,
+		onChange:   onChange,
+		templates:  make(map[string]*ngx_template.Template),
+		watchers:   make(map[string]watch.FileWatcher),
+	}
+}
+
+// get returns the template of a file, loaded the first time it is used
+func (c *templateCache) get(path string) (*ngx_template.Template, error) {
+	if !filepath.IsAbs(path) {
+		return nil, fmt.Errorf("invalid custom-template %q: it must be an absolute path", path)
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if t, ok := c.templates[path]; ok {
+		return t, nil
+	}
+
+	t, err := ngx_template.NewTemplateWithFuncs(path, c.fs, c.funcs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid custom-template %q: %v", path, err)
+	}
+	c.templates[path] = t
+
+	if c.watchFiles {
+		fw, err := watch.NewFileWatcher(path, func() { c.reload(path) })
+		if err != nil {
+			log.Warningf("Error creating file watcher for %v: %v", path, err)
+		} else {
+			c.watchers[path] = fw
+		}
+	}
+
+	log.Infof("Custom NGINX configuration template %v loaded.", path)
+	return t, nil
+}
+
+// reload loads the changes of a template file. A template with errors
+// is reported and the previous version is kept.
+func (c *templateCache) reload(path string) {
+	t, err := ngx_template.NewTemplateWithFuncs(path, c.fs, c.funcs)
+	if err != nil {
+		log.Errorf("Error loading custom NGINX configuration template %v, the previous version is kept: %v", path, err)
+		return
+	}
+
+	c.Lock()
+	c.templates[path] = t
+	c.Unlock()
+
+	log.Infof("Custom NGINX configuration template %v reloaded.", path)
+	c.onChange(path)
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/ingress-nginx/internal/file"
+	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
+	ngx_template "k8s.io/ingress-nginx/internal/ingress/controller/template"
+)
+
+func TestTemplateCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "templates")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	fs, err := file.NewLocalFS()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	path := filepath.Join(dir, "internal.tmpl")
+	if err := ioutil.WriteFile(path, []byte("profile {{ .Profile }}"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	changed := ""
+	c := newTemplateCache(fs, ngx_template.NewFuncRegistry(), false, func(p string) { changed = p })
+
+	if _, err := c.get("internal.tmpl"); err == nil {
+		t.Errorf("expected an error with a relative path")
+	}
+	if _, err := c.get(filepath.Join(dir, "missing.tmpl")); err == nil {
+		t.Errorf("expected an error with a missing template")
+	}
+
+	tmpl, err := c.get(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	content, err := tmpl.Write(ngx_config.TemplateConfig{Profile: "default"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(content) != "profile default" {
+		t.Errorf("expected the rendered custom template but got %q", content)
+	}
+
+	// an invalid change keeps the previous version
+	if err := ioutil.WriteFile(path, []byte("profile {{ .Profile "), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.reload(path)
+	if changed != "" {
+		t.Errorf("expected no change with an invalid template")
+	}
+	if cached, _ := c.get(path); cached != tmpl {
+		t.Errorf("expected the previous version of the template")
+	}
+
+	if err := ioutil.WriteFile(path, []byte("class template"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.reload(path)
+	if changed != path {
+		t.Errorf("expected a change of %v but got %q", path, changed)
+	}
+	tmpl, _ = c.get(path)
+	content, err = tmpl.Write(ngx_config.TemplateConfig{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(content) != "class template" {
+		t.Errorf("expected the new version of the template but got %q", content)
+	}
+}
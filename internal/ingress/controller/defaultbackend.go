@@ -0,0 +1,91 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/annotations"
+)
+
+// customDefaultBackendPrefix is the prefix of the upstreams of the Services
+// in the annotation default-backend. The requests sent to them contain the
+// headers used by the default backend to render custom errors.
+const customDefaultBackendPrefix = "custom-default-backend-"
+
+// hasCustomDefaultBackend returns true if the annotation default-backend
+// of an Ingress references a Service with ports
+func hasCustomDefaultBackend(anns *annotations.Ingress) bool {
+	return anns.DefaultBackend != nil && len(anns.DefaultBackend.Spec.Ports) > 0
+}
+
+// customDefaultBackendName returns the name of the upstream of the Service
+// in the annotation default-backend, used for the paths of the servers not
+// defined in the Ingress rules
+func customDefaultBackendName(svc *apiv1.Service) string {
+	return customDefaultBackendPrefix + upstreamName(svc.Namespace, svc.Name, intstr.FromInt(int(svc.Spec.Ports[0].Port)))
+}
+
+// newCustomDefaultBackend returns the upstream of the Service in the
+// annotation default-backend, using its first port
+func (n *NGINXController) newCustomDefaultBackend(svc *apiv1.Service) *ingress.Backend {
+	sp := svc.Spec.Ports[0]
+	upstream := newUpstream(customDefaultBackendName(svc))
+	upstream.Service = svc
+	upstream.Port = intstr.FromInt(int(sp.Port))
+	upstream.Endpoints = getEndpoints(svc, &sp, apiv1.ProtocolTCP, false, n.store.GetServiceEndpoints)
+
+	return upstream
+}
+
+// setDefaultLocationAnnotations configures a location of a default backend
+// with the annotations of the Ingress defining the backend
+func setDefaultLocationAnnotations(loc *ingress.Location, anns *annotations.Ingress) {
+	loc.Logs = anns.Logs
+	loc.BasicDigestAuth = anns.BasicDigestAuth
+	loc.ClientBodyBufferSize = anns.ClientBodyBufferSize
+	loc.ConfigurationSnippet = anns.ConfigurationSnippet
+	loc.CorsConfig = anns.CorsConfig
+	loc.ExternalAuth = anns.ExternalAuth
+	loc.Proxy = anns.Proxy
+	loc.ProxyCache = anns.ProxyCache
+	loc.RateLimit = anns.RateLimit
+	// TODO: Redirect and rewrite can affect the catch all behavior, skip for now
+	// loc.Redirect = anns.Redirect
+	// loc.Rewrite = anns.Rewrite
+	loc.UpstreamVhost = anns.UpstreamVhost
+	loc.Whitelist = anns.Whitelist
+	loc.Denied = anns.Denied
+	loc.LuaRestyWAF = anns.LuaRestyWAF
+	loc.InfluxDB = anns.InfluxDB
+	loc.BackendProtocol = anns.BackendProtocol
+	loc.Brotli = anns.Brotli
+	loc.Satisfy = anns.Satisfy
+	loc.Priority = anns.Priority
+	loc.AuthOIDC = anns.AuthOIDC
+	loc.AllowedMethods = anns.AllowedMethods
+	loc.GeoIPFilter = anns.GeoIPFilter
+	loc.ConnectProxy = anns.ConnectProxy
+	loc.Compression = anns.Compression
+	loc.FastCGI = anns.FastCGI
+	loc.ProxySSL = anns.ProxySSL
+	loc.Opentracing = anns.Opentracing
+	loc.SizeMetrics = anns.SizeMetrics
+	loc.WebSocketHeavy = anns.WebSocketHeavy
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/annotations"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/cors"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/redirect"
+)
+
+func TestCustomDefaultBackend(t *testing.T) {
+	anns := &annotations.Ingress{}
+	if hasCustomDefaultBackend(anns) {
+		t.Errorf("expected no custom default backend without the annotation")
+	}
+
+	anns.DefaultBackend = &apiv1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "errors"},
+	}
+	if hasCustomDefaultBackend(anns) {
+		t.Errorf("expected no custom default backend for a Service without ports")
+	}
+
+	anns.DefaultBackend.Spec.Ports = []apiv1.ServicePort{{Port: 8080}}
+	if !hasCustomDefaultBackend(anns) {
+		t.Errorf("expected a custom default backend")
+	}
+
+	if name := customDefaultBackendName(anns.DefaultBackend); name != "custom-default-backend-default-errors-8080" {
+		t.Errorf("unexpected name of the custom default backend: %v", name)
+	}
+}
+
+func TestSetDefaultLocationAnnotations(t *testing.T) {
+	anns := &annotations.Ingress{
+		CorsConfig: cors.Config{CorsEnabled: true},
+		Redirect:   redirect.Config{URL: "https://example.com", Code: 301},
+	}
+
+	loc := &ingress.Location{}
+	setDefaultLocationAnnotations(loc, anns)
+
+	if !loc.CorsConfig.CorsEnabled {
+		t.Errorf("expected the location of the default backend to use the CORS configuration of the Ingress")
+	}
+	if loc.Redirect.URL != "" {
+		t.Errorf("expected the location of the default backend to ignore the redirect of the Ingress")
+	}
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/ingress-nginx/internal/log"
+)
+
+// denylistSyncPeriod defines how often the content of the denylist ConfigMap is checked
+const denylistSyncPeriod = 2 * time.Second
+
+// syncDenylist reads the networks defined in the denylist ConfigMap and
+// sends them to NGINX when they differ from the list NGINX reports, which
+// is empty after NGINX is restarted. Blocking a network does not require
+// a reload.
+func (n *NGINXController) syncDenylist() {
+	networks := []string{}
+
+	cm, err := n.store.GetConfigMap(n.cfg.DenylistConfigMap)
+	if err != nil {
+		log.V(3).Infof("Denylist ConfigMap %v not found, no networks are blocked: %v", n.cfg.DenylistConfigMap, err)
+	} else {
+		var invalid []string
+		networks, invalid = parseDenylist(cm)
+		n.reportInvalidDenylist(cm, invalid)
+	}
+
+	current, err := runningDenylist()
+	if err != nil {
+		log.Warningf("Unexpected error reading the denylist: %v", err)
+		return
+	}
+
+	if current != nil && reflect.DeepEqual(current, networks) {
+		return
+	}
+
+	err = configureDenylist(networks)
+	if err != nil {
+		log.Warningf("Unexpected error configuring the denylist: %v", err)
+		return
+	}
+
+	log.Infof("Denylist updated (%v networks blocked)", len(networks))
+}
+
+// parseDenylist returns the sorted list of unique IPv4 and IPv6 addresses
+// and networks contained in the values of a ConfigMap, separated by commas,
+// spaces or new lines, and the sorted list of the invalid entries.
+func parseDenylist(cm *apiv1.ConfigMap) ([]string, []string) {
+	networks := sets.NewString()
+	invalid := sets.NewString()
+	for _, data := range cm.Data {
+		values := strings.FieldsFunc(data, func(r rune) bool {
+			return r == ',' || unicode.IsSpace(r)
+		})
+
+		for _, value := range values {
+			ip, ipnet, err := net.ParseCIDR(value)
+			if err != nil {
+				ip = net.ParseIP(value)
+			}
+
+			if ip == nil {
+				invalid.Insert(value)
+				continue
+			}
+
+			if ipnet != nil {
+				networks.Insert(ipnet.String())
+			} else {
+				networks.Insert(ip.String())
+			}
+		}
+	}
+
+	return networks.List(), invalid.List()
+}
+
+// reportInvalidDenylist reports the invalid entries of the denylist
+// ConfigMap with a warning event. The entries already reported in the
+// previous synchronization are not reported again.
+func (n *NGINXController) reportInvalidDenylist(cm *apiv1.ConfigMap, invalid []string) {
+	reported := sets.NewString(invalid...)
+	for _, value := range invalid {
+		if n.denylistInvalid.Has(value) {
+			continue
+		}
+
+		msg := fmt.Sprintf("Ignoring invalid IP address or network %q in the denylist", value)
+		log.Warningf("%v (ConfigMap %v/%v)", msg, cm.Namespace, cm.Name)
+		if n.recorder != nil {
+			n.recorder.Event(cm, apiv1.EventTypeWarning, "InvalidDenylistEntry", msg)
+		}
+	}
+
+	n.denylistInvalid = reported
+}
+
+// runningDenylist returns the list of blocked networks configured in NGINX,
+// or nil if the list was not configured since NGINX started.
+func runningDenylist() ([]string, error) {
+	client := configurationClient(5 * time.Second)
+	statusCode, body, err := getBody(client, configurationURL("/configuration/denylist"))
+	if err != nil {
+		return nil, err
+	}
+
+	if statusCode == http.StatusNotFound {
+		return nil, nil
+	}
+
+	if statusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %v reading the denylist", statusCode)
+	}
+
+	networks := []string{}
+	if err := json.Unmarshal([]byte(body), &networks); err != nil {
+		return nil, fmt.Errorf("invalid denylist %q: %v", body, err)
+	}
+
+	return networks, nil
+}
+
+// configureDenylist POSTs the list of blocked networks to an internal
+// HTTP endpoint handled by Lua.
+func configureDenylist(networks []string) error {
+	return post("/configuration/denylist", networks)
+}
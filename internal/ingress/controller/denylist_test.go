@@ -0,0 +1,141 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/record"
+
+	"k8s.io/ingress-nginx/internal/ingress/controller/store"
+)
+
+type fakeConfigMapStore struct {
+	store.Storer
+	configMap *apiv1.ConfigMap
+}
+
+func (fcs fakeConfigMapStore) GetConfigMap(string) (*apiv1.ConfigMap, error) {
+	return fcs.configMap, nil
+}
+
+func TestParseDenylist(t *testing.T) {
+	cm := &apiv1.ConfigMap{
+		Data: map[string]string{
+			"incident-42": "10.0.0.0/8, 192.168.1.1\n172.16.0.0/12",
+			"scanners":    "192.168.1.1 2001:db8::/32 invalid 10.1.2.3/32",
+		},
+	}
+
+	expected := []string{"10.0.0.0/8", "10.1.2.3/32", "172.16.0.0/12", "192.168.1.1", "2001:db8::/32"}
+	networks, invalid := parseDenylist(cm)
+	if !reflect.DeepEqual(networks, expected) {
+		t.Errorf("expected %v but %v was returned", expected, networks)
+	}
+	if expected := []string{"invalid"}; !reflect.DeepEqual(invalid, expected) {
+		t.Errorf("expected the invalid entries %v but %v was returned", expected, invalid)
+	}
+
+	networks, _ = parseDenylist(&apiv1.ConfigMap{})
+	if len(networks) != 0 {
+		t.Errorf("expected an empty denylist but %v was returned", networks)
+	}
+}
+
+func TestReportInvalidDenylist(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	n := &NGINXController{
+		recorder:        recorder,
+		denylistInvalid: sets.NewString(),
+	}
+
+	cm := &apiv1.ConfigMap{}
+	n.reportInvalidDenylist(cm, []string{"invalid"})
+	n.reportInvalidDenylist(cm, []string{"invalid", "10.0.0.300"})
+
+	if len(recorder.Events) != 2 {
+		t.Fatalf("expected 2 events but %v were recorded", len(recorder.Events))
+	}
+	if event := <-recorder.Events; event != `Warning InvalidDenylistEntry Ignoring invalid IP address or network "invalid" in the denylist` {
+		t.Errorf("unexpected event %v", event)
+	}
+}
+
+func TestSyncDenylist(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		denylist string
+		posts    int
+	)
+
+	stop := newConfigurationServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.URL.Path != "/configuration/denylist" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if r.Method == "GET" {
+			if denylist == "" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(denylist))
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		denylist = string(body)
+		posts++
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer stop()
+
+	n := &NGINXController{
+		cfg: &Configuration{DenylistConfigMap: "default/denylist"},
+		store: fakeConfigMapStore{
+			configMap: &apiv1.ConfigMap{Data: map[string]string{"incident-42": "10.0.0.0/8"}},
+		},
+	}
+
+	n.syncDenylist()
+	n.syncDenylist()
+	if posts != 1 {
+		t.Errorf("expected the denylist to be posted once but it was posted %v times", posts)
+	}
+	if denylist != `["10.0.0.0/8"]` {
+		t.Errorf("unexpected denylist %v", denylist)
+	}
+
+	// NGINX was restarted and lost the denylist
+	mu.Lock()
+	denylist = ""
+	mu.Unlock()
+
+	n.syncDenylist()
+	if posts != 2 {
+		t.Errorf("expected the denylist to be posted again after a restart but it was posted %v times", posts)
+	}
+}
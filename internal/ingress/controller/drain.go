@@ -0,0 +1,120 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+)
+
+// drainingEndpoint is an endpoint removed from an upstream that receives
+// requests with session affinity until the end of the drain period
+type drainingEndpoint struct {
+	endpoint ingress.Endpoint
+	until    time.Time
+}
+
+// endpointDrainer keeps the endpoints removed from the upstreams with
+// cookie session affinity during a drain period, only for the sessions
+// that already exist.
+type endpointDrainer struct {
+	// active contains the endpoints of each upstream in the last sync,
+	// indexed by address:port
+	active map[string]map[string]ingress.Endpoint
+	// draining contains the draining endpoints of each upstream
+	draining map[string]map[string]drainingEndpoint
+}
+
+func newEndpointDrainer() *endpointDrainer {
+	return &endpointDrainer{
+		active:   make(map[string]map[string]ingress.Endpoint),
+		draining: make(map[string]map[string]drainingEndpoint),
+	}
+}
+
+// drain adds to the endpoints of an upstream the ones removed from it in
+// the last drain period. Returns true if an endpoint started draining.
+func (ed *endpointDrainer) drain(upstream *ingress.Backend, period time.Duration, now time.Time) bool {
+	current := make(map[string]ingress.Endpoint, len(upstream.Endpoints))
+	for _, ep := range upstream.Endpoints {
+		current[ep.Address+":"+ep.Port] = ep
+	}
+
+	previous := ed.active[upstream.Name]
+	ed.active[upstream.Name] = current
+
+	if period <= 0 || upstream.SessionAffinity.AffinityType != "cookie" {
+		delete(ed.draining, upstream.Name)
+		return false
+	}
+
+	draining, ok := ed.draining[upstream.Name]
+	if !ok {
+		draining = make(map[string]drainingEndpoint)
+		ed.draining[upstream.Name] = draining
+	}
+
+	started := false
+	for key, ep := range previous {
+		if _, ok := current[key]; ok {
+			continue
+		}
+
+		if _, ok := draining[key]; ok {
+			continue
+		}
+
+		draining[key] = drainingEndpoint{
+			endpoint: ep,
+			until:    now.Add(period),
+		}
+		started = true
+	}
+
+	keys := make([]string, 0, len(draining))
+	for key := range draining {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		de := draining[key]
+		if _, ok := current[key]; ok || !now.Before(de.until) {
+			delete(draining, key)
+			continue
+		}
+
+		ep := de.endpoint
+		ep.Draining = true
+		upstream.Endpoints = append(upstream.Endpoints, ep)
+	}
+
+	return started
+}
+
+// prune removes the state of the upstreams not present in the configuration
+func (ed *endpointDrainer) prune(upstreams sets.String) {
+	for name := range ed.active {
+		if !upstreams.Has(name) {
+			delete(ed.active, name)
+			delete(ed.draining, name)
+		}
+	}
+}
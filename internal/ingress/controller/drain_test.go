@@ -0,0 +1,102 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+)
+
+func newDrainUpstream(addresses ...string) *ingress.Backend {
+	upstream := &ingress.Backend{
+		Name: "default-app-80",
+		SessionAffinity: ingress.SessionAffinityConfig{
+			AffinityType: "cookie",
+		},
+	}
+
+	for _, address := range addresses {
+		upstream.Endpoints = append(upstream.Endpoints, ingress.Endpoint{Address: address, Port: "8080"})
+	}
+
+	return upstream
+}
+
+func TestEndpointDrainer(t *testing.T) {
+	ed := newEndpointDrainer()
+	period := 30 * time.Second
+	now := time.Now()
+
+	if ed.drain(newDrainUpstream("10.0.0.1", "10.0.0.2"), period, now) {
+		t.Errorf("expected no draining endpoints in the first sync")
+	}
+
+	upstream := newDrainUpstream("10.0.0.1")
+	if !ed.drain(upstream, period, now.Add(time.Second)) {
+		t.Errorf("expected the removed endpoint to start draining")
+	}
+
+	expected := []ingress.Endpoint{
+		{Address: "10.0.0.1", Port: "8080"},
+		{Address: "10.0.0.2", Port: "8080", Draining: true},
+	}
+	if len(upstream.Endpoints) != len(expected) {
+		t.Fatalf("expected endpoints %v but got %v", expected, upstream.Endpoints)
+	}
+	for i := range expected {
+		if !(&expected[i]).Equal(&upstream.Endpoints[i]) {
+			t.Errorf("expected endpoint %v but got %v", expected[i], upstream.Endpoints[i])
+		}
+	}
+
+	upstream = newDrainUpstream("10.0.0.1")
+	if ed.drain(upstream, period, now.Add(10*time.Second)) {
+		t.Errorf("expected no new draining endpoints")
+	}
+	if len(upstream.Endpoints) != 2 {
+		t.Errorf("expected the endpoint to be draining but got %v", upstream.Endpoints)
+	}
+
+	upstream = newDrainUpstream("10.0.0.1")
+	ed.drain(upstream, period, now.Add(time.Minute))
+	if len(upstream.Endpoints) != 1 {
+		t.Errorf("expected the drain period to be over but got %v", upstream.Endpoints)
+	}
+
+	ed.prune(sets.NewString())
+	if len(ed.active) != 0 || len(ed.draining) != 0 {
+		t.Errorf("expected no state after removing the upstreams")
+	}
+}
+
+func TestEndpointDrainerWithoutAffinity(t *testing.T) {
+	ed := newEndpointDrainer()
+	now := time.Now()
+
+	upstream := newDrainUpstream("10.0.0.1", "10.0.0.2")
+	upstream.SessionAffinity.AffinityType = ""
+	ed.drain(upstream, time.Minute, now)
+
+	upstream = newDrainUpstream("10.0.0.1")
+	upstream.SessionAffinity.AffinityType = ""
+	if ed.drain(upstream, time.Minute, now) || len(upstream.Endpoints) != 1 {
+		t.Errorf("expected no draining endpoints without session affinity but got %v", upstream.Endpoints)
+	}
+}
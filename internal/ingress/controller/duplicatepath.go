@@ -0,0 +1,146 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/internal/log"
+)
+
+const (
+	// DuplicatePathFirstWins configures a host and path defined in several
+	// Ingresses with the oldest Ingress
+	DuplicatePathFirstWins = "first-wins"
+	// DuplicatePathReject does not configure the Ingresses with a host and
+	// path already defined in an older Ingress
+	DuplicatePathReject = "reject"
+	// DuplicatePathMerge configures a host and path defined in several
+	// Ingresses with the backend of the oldest Ingress and the annotations
+	// of all of them, where the annotations of the older Ingresses take
+	// precedence
+	DuplicatePathMerge = "merge"
+)
+
+// isCanary returns true if the Ingress defines a canary backend, which
+// shares the host and path of other Ingress by design
+func (n *NGINXController) isCanary(ing *extensions.Ingress) bool {
+	anns, err := n.store.GetIngressAnnotations(k8s.MetaNamespaceKey(ing))
+	return err == nil && anns.Canary.Enabled
+}
+
+// checkDuplicatePaths reports the Ingresses with a host and path already
+// defined in an older Ingress and returns the Ingresses to configure,
+// according to the duplicate path policy. The Ingresses must be sorted
+// from the oldest to the newest.
+func (n *NGINXController) checkDuplicatePaths(ings []*extensions.Ingress) []*extensions.Ingress {
+	policy := n.cfg.DuplicatePathPolicy
+
+	claimed := make(map[hostPath]*extensions.Ingress)
+	reported := sets.NewString()
+	configured := make([]*extensions.Ingress, 0, len(ings))
+
+	for _, ing := range ings {
+		if n.isCanary(ing) {
+			configured = append(configured, ing)
+			continue
+		}
+
+		ingKey := k8s.MetaNamespaceKey(ing)
+		paths := ingressPaths(ing)
+
+		var msgs []string
+		for _, hp := range paths {
+			other, ok := claimed[hp]
+			if !ok || k8s.MetaNamespaceKey(other) == ingKey {
+				continue
+			}
+
+			msg := fmt.Sprintf("Host %q and path %q are already defined in Ingress %v/%v", hp.host, hp.path, other.Namespace, other.Name)
+			switch policy {
+			case DuplicatePathReject:
+				msg += ", the Ingress is not configured"
+			case DuplicatePathMerge:
+				msg += ", the annotations are merged into its location"
+			default:
+				msg += ", the path is not configured"
+			}
+			msgs = append(msgs, msg)
+		}
+
+		for _, msg := range msgs {
+			key := fmt.Sprintf("%v|%v", ingKey, msg)
+			reported.Insert(key)
+			if n.duplicatePaths.Has(key) {
+				continue
+			}
+
+			log.Warningf("Ingress %v: %v", ingKey, msg)
+			if n.recorder != nil {
+				n.recorder.Event(ing, apiv1.EventTypeWarning, "DuplicatePath", msg)
+			}
+		}
+
+		if len(msgs) > 0 && policy == DuplicatePathReject {
+			continue
+		}
+
+		for _, hp := range paths {
+			if _, ok := claimed[hp]; !ok {
+				claimed[hp] = ing
+			}
+		}
+		configured = append(configured, ing)
+	}
+
+	n.duplicatePaths = reported
+	return configured
+}
+
+// mergeLocationAnnotations merges the annotations of an Ingress into the
+// annotations used to configure a location of an older Ingress. The
+// annotations already merged into each location are kept in merged.
+func (n *NGINXController) mergeLocationAnnotations(loc *ingress.Location, ing *extensions.Ingress,
+	merged map[*ingress.Location]map[string]string) {
+
+	anns, ok := merged[loc]
+	if !ok {
+		anns = make(map[string]string, len(loc.Ingress.Annotations))
+		for k, v := range loc.Ingress.Annotations {
+			anns[k] = v
+		}
+		merged[loc] = anns
+	}
+
+	prefix := parser.GetAnnotationWithPrefix("")
+	for k, v := range ing.Annotations {
+		if _, ok := anns[k]; !ok && strings.HasPrefix(k, prefix) {
+			anns[k] = v
+		}
+	}
+
+	owner := loc.Ingress.DeepCopy()
+	owner.Annotations = anns
+	setLocationAnnotations(loc, n.annotations.Extract(owner))
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"testing"
+	"time"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/annotations"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/canary"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
+)
+
+func TestCheckDuplicatePaths(t *testing.T) {
+	web := newEffectiveConfigIngress("web", "/", "/api")
+	api := newEffectiveConfigIngress("api", "/api")
+	other := newEffectiveConfigIngress("other", "/other")
+	canaryIng := newEffectiveConfigIngress("canary", "/")
+
+	fakeStore := fakeAnnotationsStore{
+		annotations: map[string]*annotations.Ingress{
+			"default/web":    {},
+			"default/api":    {},
+			"default/other":  {},
+			"default/canary": {Canary: canary.Config{Enabled: true}},
+		},
+	}
+	ings := []*extensions.Ingress{web, api, other, canaryIng}
+
+	testCases := []struct {
+		policy   string
+		expected []*extensions.Ingress
+	}{
+		{DuplicatePathFirstWins, []*extensions.Ingress{web, api, other, canaryIng}},
+		{DuplicatePathMerge, []*extensions.Ingress{web, api, other, canaryIng}},
+		{DuplicatePathReject, []*extensions.Ingress{web, other, canaryIng}},
+	}
+
+	for _, tc := range testCases {
+		n := &NGINXController{
+			cfg:   &Configuration{DuplicatePathPolicy: tc.policy},
+			store: fakeStore,
+		}
+
+		configured := n.checkDuplicatePaths(ings)
+		if len(configured) != len(tc.expected) {
+			t.Fatalf("expected %v Ingresses configured with policy %v but %v returned", len(tc.expected), tc.policy, len(configured))
+		}
+		for i := range configured {
+			if configured[i] != tc.expected[i] {
+				t.Errorf("expected Ingress %v configured with policy %v but %v returned", tc.expected[i].Name, tc.policy, configured[i].Name)
+			}
+		}
+
+		if n.duplicatePaths.Len() != 1 {
+			t.Errorf("expected 1 duplicate path reported with policy %v but %v returned", tc.policy, n.duplicatePaths.List())
+		}
+	}
+}
+
+func TestMergeLocationAnnotations(t *testing.T) {
+	owner := newEffectiveConfigIngress("web", "/")
+	owner.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Hour))
+	owner.Annotations = map[string]string{
+		"nginx.ingress.kubernetes.io/rewrite-target": "/web",
+	}
+
+	ing := newEffectiveConfigIngress("api", "/")
+	ing.Annotations = map[string]string{
+		"nginx.ingress.kubernetes.io/rewrite-target": "/api",
+		"nginx.ingress.kubernetes.io/enable-cors":    "true",
+		"kubernetes.io/ingress.class":                "nginx",
+	}
+
+	n := &NGINXController{
+		annotations: annotations.NewAnnotationExtractor(resolver.Mock{}),
+	}
+
+	loc := &ingress.Location{Path: "/", Ingress: owner}
+	merged := make(map[*ingress.Location]map[string]string)
+	n.mergeLocationAnnotations(loc, ing, merged)
+
+	if loc.Rewrite.Target != "/web" {
+		t.Errorf("expected /web as rewrite target but %v returned", loc.Rewrite.Target)
+	}
+	if !loc.CorsConfig.CorsEnabled {
+		t.Errorf("expected CORS enabled by the merged annotations")
+	}
+	if _, ok := merged[loc]["kubernetes.io/ingress.class"]; ok {
+		t.Errorf("expected only annotations with the prefix of the controller to be merged")
+	}
+	if len(owner.Annotations) != 1 {
+		t.Errorf("expected the annotations of the Ingress not to be modified but %v returned", owner.Annotations)
+	}
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"k8s.io/ingress-nginx/internal/log"
+)
+
+// retryDynamically calls configure until it succeeds, waiting between the
+// attempts as defined by the backoff. The error returned is the one of the
+// last attempt.
+func retryDynamically(backoff wait.Backoff, configure func() error) error {
+	var lastErr error
+	err := wait.ExponentialBackoff(backoff, func() (bool, error) {
+		lastErr = configure()
+		if lastErr == nil {
+			return true, nil
+		}
+
+		log.Warningf("Dynamic reconfiguration failed: %v", lastErr)
+		return false, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return fmt.Errorf("%v attempts failed, last error: %v", backoff.Steps, lastErr)
+	}
+
+	return err
+}
+
+// dynamicConfigurationBackoff returns the backoff of the attempts to
+// configure NGINX dynamically in a sync
+func (n *NGINXController) dynamicConfigurationBackoff() wait.Backoff {
+	return wait.Backoff{
+		Steps:    n.cfg.DynamicConfigurationRetries + 1,
+		Duration: n.cfg.DynamicConfigurationRetryDelay,
+		Factor:   n.cfg.DynamicConfigurationRetryFactor,
+		Jitter:   0.1,
+	}
+}
+
+// dynamicConfigurationFailed counts a sync that could not configure NGINX
+// dynamically. After DynamicConfigurationFailuresBeforeReload consecutive
+// failures the next sync reloads NGINX, which restarts the Lua workers.
+func (n *NGINXController) dynamicConfigurationFailed() {
+	n.dynamicFailures++
+
+	limit := n.cfg.DynamicConfigurationFailuresBeforeReload
+	if limit <= 0 || n.dynamicFailures < limit {
+		return
+	}
+
+	log.Warningf("The dynamic configuration failed in %v consecutive syncs, the next sync reloads NGINX", n.dynamicFailures)
+	n.dynamicFailures = 0
+	n.postedBackends = nil
+	n.setForceReload(true)
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestRetryDynamically(t *testing.T) {
+	backoff := wait.Backoff{Steps: 3, Duration: time.Millisecond, Factor: 1}
+
+	attempts := 0
+	err := retryDynamically(backoff, func() error {
+		attempts++
+		if attempts < 3 {
+			return fmt.Errorf("attempt %v", attempts)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts but got %v", attempts)
+	}
+
+	attempts = 0
+	err = retryDynamically(backoff, func() error {
+		attempts++
+		return fmt.Errorf("attempt %v", attempts)
+	})
+	if err == nil || !strings.Contains(err.Error(), "attempt 3") {
+		t.Errorf("expected the error of the last attempt but got %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts but got %v", attempts)
+	}
+}
+
+func TestDynamicConfigurationFailed(t *testing.T) {
+	n := &NGINXController{
+		cfg: &Configuration{
+			DynamicConfigurationFailuresBeforeReload: 2,
+		},
+		postedBackends: &postedBackends{checksum: "a"},
+	}
+
+	n.dynamicConfigurationFailed()
+	if n.isForceReload() {
+		t.Errorf("expected no reload after the first failure")
+	}
+
+	n.dynamicConfigurationFailed()
+	if !n.isForceReload() {
+		t.Errorf("expected a reload after two consecutive failures")
+	}
+	if n.postedBackends != nil {
+		t.Errorf("expected the full list of backends to be posted after the reload")
+	}
+	if n.dynamicFailures != 0 {
+		t.Errorf("expected the failures to be counted again after the reload but got %v", n.dynamicFailures)
+	}
+
+	n.setForceReload(false)
+	n.cfg.DynamicConfigurationFailuresBeforeReload = 0
+	for i := 0; i < 5; i++ {
+		n.dynamicConfigurationFailed()
+	}
+	if n.isForceReload() {
+		t.Errorf("expected no reload when the limit is zero")
+	}
+}
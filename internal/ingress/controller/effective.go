@@ -0,0 +1,194 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	pool "gopkg.in/go-playground/pool.v3"
+	extensions "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/annotations"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	"k8s.io/ingress-nginx/internal/log"
+)
+
+// EffectiveConfig summarizes the configuration applied to an Ingress
+type EffectiveConfig struct {
+	BackendProtocol string `json:"backendProtocol,omitempty"`
+	// TLSSecrets contains the Secret with the certificate used for each
+	// host of the Ingress
+	// +optional
+	TLSSecrets map[string]string `json:"tlsSecrets,omitempty"`
+	// CanaryWeight is the percentage of the requests routed to the canary
+	// by weight
+	// +optional
+	CanaryWeight *float64 `json:"canaryWeight,omitempty"`
+	// Notes contains the differences between the Ingress and the applied
+	// configuration, like paths served by other Ingresses
+	// +optional
+	Notes []string `json:"notes,omitempty"`
+}
+
+// effectiveConfig returns the summary of the configuration applied to an Ingress
+func (n *NGINXController) effectiveConfig(ing *extensions.Ingress, pcfg *ingress.Configuration) *EffectiveConfig {
+	ingKey := fmt.Sprintf("%v/%v", ing.Namespace, ing.Name)
+	ec := &EffectiveConfig{}
+
+	anns, err := n.store.GetIngressAnnotations(ingKey)
+	if err != nil {
+		ec.Notes = append(ec.Notes, fmt.Sprintf("annotations not applied: %v", err))
+	} else {
+		ec.BackendProtocol = anns.BackendProtocol
+		if anns.Canary.Enabled {
+			weight := anns.Canary.Percentage()
+			ec.CanaryWeight = &weight
+		}
+	}
+
+	servers := make(map[string]*ingress.Server, len(pcfg.Servers))
+	owners := make(map[hostPath]*extensions.Ingress)
+	for _, server := range pcfg.Servers {
+		servers[server.Hostname] = server
+		for _, loc := range server.Locations {
+			if loc.Ingress != nil {
+				owners[hostPath{server.Hostname, loc.Path}] = loc.Ingress
+			}
+		}
+	}
+
+	for _, hp := range ingressPaths(ing) {
+		host := hp.host
+		if host == "" {
+			host = defServerName
+		}
+
+		owner, ok := owners[hostPath{host, hp.path}]
+		switch {
+		case !ok:
+			ec.Notes = append(ec.Notes, fmt.Sprintf("path %v%v is not configured", hp.host, hp.path))
+		case owner.Namespace != ing.Namespace || owner.Name != ing.Name:
+			ec.Notes = append(ec.Notes, fmt.Sprintf("path %v%v is served by Ingress %v/%v",
+				hp.host, hp.path, owner.Namespace, owner.Name))
+		}
+	}
+
+	for _, tls := range ing.Spec.TLS {
+		for _, host := range tls.Hosts {
+			server, ok := servers[host]
+			if !ok {
+				continue
+			}
+
+			secretName := extractTLSSecretName(host, ing, n.store.GetLocalSSLCert)
+			if secretName == "" {
+				ec.Notes = append(ec.Notes, fmt.Sprintf("host %v uses the default certificate", host))
+				continue
+			}
+
+			secretKey := fmt.Sprintf("%v/%v", ing.Namespace, secretName)
+			cert, err := n.store.GetLocalSSLCert(secretKey)
+			if err != nil || cert.Certificate != server.SSLCert.Certificate {
+				ec.Notes = append(ec.Notes, fmt.Sprintf("host %v does not use the certificate of Secret %v", host, secretKey))
+				continue
+			}
+
+			if ec.TLSSecrets == nil {
+				ec.TLSSecrets = make(map[string]string)
+			}
+			ec.TLSSecrets[host] = secretKey
+		}
+	}
+
+	for _, c := range n.classConflicts.List() {
+		parts := strings.Split(c, "|")
+		if len(parts) == 3 && parts[0] == ingKey {
+			ec.Notes = append(ec.Notes, fmt.Sprintf("path %v is also defined in Ingress %v", parts[2], parts[1]))
+		}
+	}
+
+	sort.Strings(ec.Notes)
+	return ec
+}
+
+// publishEffectiveConfigurations writes the summary of the running
+// configuration applied to each Ingress in the effective configuration
+// annotation, when it changes. Only invoked by the leader of the status update.
+func (n *NGINXController) publishEffectiveConfigurations() {
+	pcfg, ok := n.effectiveConfigSource.Load().(*ingress.Configuration)
+	if !ok {
+		return
+	}
+
+	key := parser.GetAnnotationWithPrefix(annotations.EffectiveConfigAnnotation)
+
+	p := pool.NewLimited(10)
+	defer p.Close()
+
+	batch := p.Batch()
+
+	for _, ing := range n.store.ListIngresses() {
+		b, err := json.Marshal(n.effectiveConfig(ing, pcfg))
+		if err != nil {
+			log.Errorf("Error encoding the effective configuration of Ingress %v/%v: %v", ing.Namespace, ing.Name, err)
+			continue
+		}
+
+		value := string(b)
+		if ing.Annotations[key] == value {
+			continue
+		}
+
+		batch.Queue(n.runEffectiveConfigUpdate(ing, key, value))
+	}
+
+	batch.QueueComplete()
+	batch.WaitAll()
+}
+
+func (n *NGINXController) runEffectiveConfigUpdate(ing *extensions.Ingress, key, value string) pool.WorkFunc {
+	return func(wu pool.WorkUnit) (interface{}, error) {
+		if wu.IsCancelled() {
+			return nil, nil
+		}
+
+		ingClient := n.cfg.Client.ExtensionsV1beta1().Ingresses(ing.Namespace)
+
+		currIng, err := ingClient.Get(ing.Name, metav1.GetOptions{})
+		if err != nil {
+			log.Warningf("Error getting Ingress %v/%v: %v", ing.Namespace, ing.Name, err)
+			return nil, nil
+		}
+
+		if currIng.Annotations == nil {
+			currIng.Annotations = make(map[string]string)
+		}
+		currIng.Annotations[key] = value
+
+		log.V(2).Infof("Updating effective configuration of Ingress %v/%v to %v", ing.Namespace, ing.Name, value)
+		_, err = ingClient.Update(currIng)
+		if err != nil {
+			log.Warningf("Error updating effective configuration of Ingress %v/%v: %v", ing.Namespace, ing.Name, err)
+		}
+
+		return true, nil
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/annotations"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/canary"
+	"k8s.io/ingress-nginx/internal/ingress/controller/store"
+)
+
+type fakeAnnotationsStore struct {
+	store.Storer
+	annotations map[string]*annotations.Ingress
+}
+
+func (fas fakeAnnotationsStore) GetIngressAnnotations(key string) (*annotations.Ingress, error) {
+	anns, ok := fas.annotations[key]
+	if !ok {
+		return nil, fmt.Errorf("annotations of Ingress %v not found", key)
+	}
+	return anns, nil
+}
+
+func (fas fakeAnnotationsStore) GetLocalSSLCert(key string) (*ingress.SSLCert, error) {
+	return nil, fmt.Errorf("secret %v not found", key)
+}
+
+func newEffectiveConfigIngress(name string, paths ...string) *extensions.Ingress {
+	ing := &extensions.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: extensions.IngressSpec{
+			Rules: []extensions.IngressRule{{
+				Host: "example.com",
+				IngressRuleValue: extensions.IngressRuleValue{
+					HTTP: &extensions.HTTPIngressRuleValue{},
+				},
+			}},
+		},
+	}
+
+	for _, path := range paths {
+		ing.Spec.Rules[0].HTTP.Paths = append(ing.Spec.Rules[0].HTTP.Paths, extensions.HTTPIngressPath{
+			Path: path,
+			Backend: extensions.IngressBackend{
+				ServiceName: "http-svc",
+				ServicePort: intstr.FromInt(80),
+			},
+		})
+	}
+
+	return ing
+}
+
+func TestEffectiveConfig(t *testing.T) {
+	web := newEffectiveConfigIngress("web", "/", "/api")
+	api := newEffectiveConfigIngress("api", "/api")
+	web.Spec.TLS = []extensions.IngressTLS{{Hosts: []string{"example.com"}}}
+
+	n := &NGINXController{
+		store: fakeAnnotationsStore{
+			annotations: map[string]*annotations.Ingress{
+				"default/web": {BackendProtocol: "HTTPS"},
+				"default/api": {Canary: canary.Config{Enabled: true, Weight: 10}},
+			},
+		},
+	}
+
+	pcfg := &ingress.Configuration{
+		Servers: []*ingress.Server{{
+			Hostname: "example.com",
+			Locations: []*ingress.Location{
+				{Path: "/", Ingress: web},
+				{Path: "/api", Ingress: api},
+			},
+		}},
+	}
+
+	weight := 10.0
+	testCases := []struct {
+		ing      *extensions.Ingress
+		expected *EffectiveConfig
+	}{
+		{
+			web,
+			&EffectiveConfig{
+				BackendProtocol: "HTTPS",
+				Notes: []string{
+					"host example.com uses the default certificate",
+					"path example.com/api is served by Ingress default/api",
+				},
+			},
+		},
+		{
+			api,
+			&EffectiveConfig{CanaryWeight: &weight},
+		},
+		{
+			newEffectiveConfigIngress("other", "/other"),
+			&EffectiveConfig{
+				Notes: []string{
+					"annotations not applied: annotations of Ingress default/other not found",
+					"path example.com/other is not configured",
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		ec := n.effectiveConfig(tc.ing, pcfg)
+		if !reflect.DeepEqual(ec, tc.expected) {
+			t.Errorf("expected %+v as effective configuration of Ingress %v but returned %+v", tc.expected, tc.ing.Name, ec)
+		}
+	}
+}
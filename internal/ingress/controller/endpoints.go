@@ -22,16 +22,16 @@ import (
 	"reflect"
 	"strconv"
 
-	"github.com/golang/glog"
-
 	corev1 "k8s.io/api/core/v1"
 
 	"k8s.io/ingress-nginx/internal/ingress"
 	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/internal/log"
 )
 
 // getEndpoints returns a list of Endpoint structs for a given service/target port combination.
-func getEndpoints(s *corev1.Service, port *corev1.ServicePort, proto corev1.Protocol,
+// The addresses of the Pods that are not ready are only included if includeNotReady is true.
+func getEndpoints(s *corev1.Service, port *corev1.ServicePort, proto corev1.Protocol, includeNotReady bool,
 	getServiceEndpoints func(string) (*corev1.Endpoints, error)) []ingress.Endpoint {
 
 	upsServers := []ingress.Endpoint{}
@@ -48,18 +48,18 @@ func getEndpoints(s *corev1.Service, port *corev1.ServicePort, proto corev1.Prot
 
 	// ExternalName services
 	if s.Spec.Type == corev1.ServiceTypeExternalName {
-		glog.V(3).Infof("Ingress using Service %q of type ExternalName.", svcKey)
+		log.V(3).Infof("Ingress using Service %q of type ExternalName.", svcKey)
 
 		targetPort := port.TargetPort.IntValue()
 		if targetPort <= 0 {
-			glog.Errorf("ExternalName Service %q has an invalid port (%v)", svcKey, targetPort)
+			log.Errorf("ExternalName Service %q has an invalid port (%v)", svcKey, targetPort)
 			return upsServers
 		}
 
 		if net.ParseIP(s.Spec.ExternalName) == nil {
-			_, err := net.LookupHost(s.Spec.ExternalName)
+			_, err := lookupHost(s.Spec.ExternalName)
 			if err != nil {
-				glog.Errorf("Error resolving host %q: %v", s.Spec.ExternalName, err)
+				log.Errorf("Error resolving host %q: %v", s.Spec.ExternalName, err)
 				return upsServers
 			}
 		}
@@ -70,10 +70,10 @@ func getEndpoints(s *corev1.Service, port *corev1.ServicePort, proto corev1.Prot
 		})
 	}
 
-	glog.V(3).Infof("Getting Endpoints for Service %q and port %v", svcKey, port.String())
+	log.V(3).Infof("Getting Endpoints for Service %q and port %v", svcKey, port.String())
 	ep, err := getServiceEndpoints(svcKey)
 	if err != nil {
-		glog.Warningf("Error obtaining Endpoints for Service %q: %v", svcKey, err)
+		log.Warningf("Error obtaining Endpoints for Service %q: %v", svcKey, err)
 		return upsServers
 	}
 
@@ -97,7 +97,12 @@ func getEndpoints(s *corev1.Service, port *corev1.ServicePort, proto corev1.Prot
 				continue
 			}
 
-			for _, epAddress := range ss.Addresses {
+			addresses := ss.Addresses
+			if includeNotReady {
+				addresses = append(addresses[:len(addresses):len(addresses)], ss.NotReadyAddresses...)
+			}
+
+			for _, epAddress := range addresses {
 				ep := net.JoinHostPort(epAddress.IP, strconv.Itoa(int(targetPort)))
 				if _, exists := processedUpstreamServers[ep]; exists {
 					continue
@@ -113,6 +118,6 @@ func getEndpoints(s *corev1.Service, port *corev1.ServicePort, proto corev1.Prot
 		}
 	}
 
-	glog.V(3).Infof("Endpoints found for Service %q: %v", svcKey, upsServers)
+	log.V(3).Infof("Endpoints found for Service %q: %v", svcKey, upsServers)
 	return upsServers
 }
@@ -385,10 +385,55 @@ func TestGetEndpoints(t *testing.T) {
 
 	for _, testCase := range tests {
 		t.Run(testCase.name, func(t *testing.T) {
-			result := getEndpoints(testCase.svc, testCase.port, testCase.proto, testCase.fn)
+			result := getEndpoints(testCase.svc, testCase.port, testCase.proto, false, testCase.fn)
 			if len(testCase.result) != len(result) {
 				t.Errorf("Expected %d Endpoints but got %d", len(testCase.result), len(result))
 			}
 		})
 	}
 }
+
+func TestGetEndpointsIncludeNotReady(t *testing.T) {
+	svc := &corev1.Service{
+		Spec: corev1.ServiceSpec{
+			Type:      corev1.ServiceTypeClusterIP,
+			ClusterIP: "1.1.1.1",
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "default",
+					TargetPort: intstr.FromInt(80),
+				},
+			},
+		},
+	}
+
+	fn := func(string) (*corev1.Endpoints, error) {
+		return &corev1.Endpoints{
+			Subsets: []corev1.EndpointSubset{
+				{
+					Addresses: []corev1.EndpointAddress{
+						{IP: "1.1.1.1"},
+					},
+					NotReadyAddresses: []corev1.EndpointAddress{
+						{IP: "1.1.1.2"},
+					},
+					Ports: []corev1.EndpointPort{
+						{
+							Protocol: corev1.ProtocolTCP,
+							Port:     80,
+							Name:     "default",
+						},
+					},
+				},
+			},
+		}, nil
+	}
+
+	if result := getEndpoints(svc, &svc.Spec.Ports[0], corev1.ProtocolTCP, false, fn); len(result) != 1 {
+		t.Errorf("Expected 1 Endpoint but got %v", result)
+	}
+
+	if result := getEndpoints(svc, &svc.Spec.Ports[0], corev1.ProtocolTCP, true, fn); len(result) != 2 {
+		t.Errorf("Expected 2 Endpoints but got %v", result)
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+
+	apiv1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/ingress-nginx/internal/log"
+	"k8s.io/ingress-nginx/internal/task"
+)
+
+// lookupHost resolves the hostname of ExternalName Services
+var lookupHost = net.LookupHost
+
+// ingressServices returns the names of the Services used in an Ingress
+func ingressServices(ing *extensions.Ingress) sets.String {
+	services := sets.NewString()
+	if ing.Spec.Backend != nil {
+		services.Insert(ing.Spec.Backend.ServiceName)
+	}
+
+	for _, rule := range ing.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+
+		for _, path := range rule.HTTP.Paths {
+			services.Insert(path.Backend.ServiceName)
+		}
+	}
+
+	return services
+}
+
+// resolveExternalNames resolves the hostnames of the ExternalName Services
+// used in the Ingresses and syncs the configuration when a hostname starts
+// or stops resolving. The Services with a hostname that cannot be resolved
+// are not part of the configuration, while NGINX resolves the hostnames of
+// the others again after the TTL of the DNS records expires.
+func (n *NGINXController) resolveExternalNames() {
+	resolved := make(map[string]bool)
+	for _, ing := range n.store.ListIngresses() {
+		for _, name := range ingressServices(ing).List() {
+			svc, err := n.store.GetService(fmt.Sprintf("%v/%v", ing.Namespace, name))
+			if err != nil || svc.Spec.Type != apiv1.ServiceTypeExternalName {
+				continue
+			}
+
+			host := svc.Spec.ExternalName
+			if _, ok := resolved[host]; ok || net.ParseIP(host) != nil {
+				continue
+			}
+
+			_, err = lookupHost(host)
+			if err != nil {
+				log.V(3).Infof("Error resolving host %q: %v", host, err)
+			}
+			resolved[host] = err == nil
+		}
+	}
+
+	previous := n.externalNames
+	n.externalNames = resolved
+	if previous == nil || reflect.DeepEqual(previous, resolved) {
+		return
+	}
+
+	log.Infof("Resolution of ExternalName Services changed")
+	n.syncQueue.EnqueueTask(task.GetDummyObject("external-name-resolution"))
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"fmt"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/task"
+)
+
+type fakeExternalNameStore struct {
+	fakeServiceStore
+	ingresses []*extensions.Ingress
+}
+
+func (fes fakeExternalNameStore) ListIngresses() []*extensions.Ingress {
+	return fes.ingresses
+}
+
+func TestResolveExternalNames(t *testing.T) {
+	defer func(fn func(string) ([]string, error)) { lookupHost = fn }(lookupHost)
+
+	resolves := true
+	lookupHost = func(host string) ([]string, error) {
+		if !resolves {
+			return nil, fmt.Errorf("no such host %v", host)
+		}
+		return []string{"10.0.0.1"}, nil
+	}
+
+	n := &NGINXController{
+		store: fakeExternalNameStore{
+			fakeServiceStore: fakeServiceStore{
+				service: &apiv1.Service{
+					ObjectMeta: metav1.ObjectMeta{Name: "external", Namespace: "default"},
+					Spec: apiv1.ServiceSpec{
+						Type:         apiv1.ServiceTypeExternalName,
+						ExternalName: "example.com",
+					},
+				},
+			},
+			ingresses: []*extensions.Ingress{{
+				ObjectMeta: metav1.ObjectMeta{Name: "ing", Namespace: "default"},
+				Spec: extensions.IngressSpec{
+					Backend: &extensions.IngressBackend{ServiceName: "external"},
+				},
+			}},
+		},
+	}
+	n.syncQueue = task.NewTaskQueue(func(interface{}) error { return nil })
+
+	n.resolveExternalNames()
+	if !n.externalNames["example.com"] {
+		t.Errorf("expected example.com to be resolved")
+	}
+
+	resolves = false
+	n.resolveExternalNames()
+	if n.externalNames["example.com"] {
+		t.Errorf("expected example.com not to be resolved")
+	}
+}
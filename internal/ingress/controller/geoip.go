@@ -0,0 +1,58 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"sync/atomic"
+
+	"k8s.io/ingress-nginx/internal/log"
+	"k8s.io/ingress-nginx/internal/net/geoip"
+	"k8s.io/ingress-nginx/internal/task"
+)
+
+// updateGeoIPDatabases downloads the GeoIP2 databases from MaxMind and
+// reloads NGINX when they change, because NGINX only reads them on start.
+func (n *NGINXController) updateGeoIPDatabases() {
+	changed, err := geoip.DownloadDatabases(n.cfg.MaxmindLicenseKey)
+	if err != nil {
+		log.Warningf("Unexpected error updating GeoIP2 databases: %v", err)
+		return
+	}
+
+	if !changed {
+		log.V(3).Infof("GeoIP2 databases did not change")
+		return
+	}
+
+	log.Infof("GeoIP2 databases updated")
+	n.setForceReload(true)
+	n.syncQueue.EnqueueTask(task.GetDummyObject("geoip-update"))
+}
+
+// setForceReload indicates if the next sync must reload NGINX
+func (n *NGINXController) setForceReload(force bool) {
+	if force {
+		atomic.StoreInt32(&n.forceReload, 1)
+		return
+	}
+
+	atomic.StoreInt32(&n.forceReload, 0)
+}
+
+// isForceReload returns true if NGINX must be reloaded in the next sync
+func (n *NGINXController) isForceReload() bool {
+	return atomic.LoadInt32(&n.forceReload) != 0
+}
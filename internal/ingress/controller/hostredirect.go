@@ -0,0 +1,143 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"k8s.io/ingress-nginx/internal/log"
+)
+
+// hostRedirectSyncPeriod defines how often the content of the host redirect ConfigMap is checked
+const hostRedirectSyncPeriod = 2 * time.Second
+
+// dropPath is the option of a host redirect that sends all the requests to the root path
+const dropPath = "drop-path"
+
+var validHostRedirectCodes = sets.NewInt(301, 302, 307, 308)
+
+// hostRedirect defines the redirection of all the requests sent to a host
+type hostRedirect struct {
+	// Host is the host used in the Location header
+	Host string `json:"host"`
+	// Code is the HTTP status code of the redirection
+	Code int `json:"code"`
+	// PreservePath keeps the path and query string of the request
+	PreservePath bool `json:"preservePath"`
+}
+
+// syncHostRedirects reads the redirections defined in the host redirect
+// ConfigMap and sends them to NGINX when they change. Updating the
+// redirections does not require a reload.
+func (n *NGINXController) syncHostRedirects() {
+	redirects := map[string]hostRedirect{}
+
+	cm, err := n.store.GetConfigMap(n.cfg.HostRedirectConfigMap)
+	if err != nil {
+		log.V(3).Infof("Host redirect ConfigMap %v not found, no hosts are redirected: %v", n.cfg.HostRedirectConfigMap, err)
+	} else {
+		redirects = parseHostRedirects(cm)
+	}
+
+	if n.hostRedirects != nil && reflect.DeepEqual(n.hostRedirects, redirects) {
+		return
+	}
+
+	err = configureHostRedirects(redirects)
+	if err != nil {
+		log.Warningf("Unexpected error configuring the host redirects: %v", err)
+		return
+	}
+
+	log.Infof("Host redirects updated (%v hosts)", len(redirects))
+	n.hostRedirects = redirects
+}
+
+// parseHostRedirects returns the redirections defined in a ConfigMap. Each
+// key is the old host and the value contains the new host, optionally
+// followed by the status code (301 by default) and the drop-path option
+// to redirect all the requests to the root path. Invalid entries are ignored.
+func parseHostRedirects(cm *apiv1.ConfigMap) map[string]hostRedirect {
+	redirects := map[string]hostRedirect{}
+	for key, value := range cm.Data {
+		from := strings.ToLower(key)
+		if errs := validation.IsDNS1123Subdomain(from); len(errs) > 0 {
+			log.Warningf("Ignoring invalid host %q in host redirect ConfigMap %v/%v: %v", key, cm.Namespace, cm.Name, strings.Join(errs, ", "))
+			continue
+		}
+
+		redirect, err := parseHostRedirect(value)
+		if err != nil {
+			log.Warningf("Ignoring invalid redirect of host %q in host redirect ConfigMap %v/%v: %v", key, cm.Namespace, cm.Name, err)
+			continue
+		}
+
+		if redirect.Host == from {
+			log.Warningf("Ignoring redirect of host %q to itself in host redirect ConfigMap %v/%v", key, cm.Namespace, cm.Name)
+			continue
+		}
+
+		redirects[from] = redirect
+	}
+
+	return redirects
+}
+
+func parseHostRedirect(value string) (hostRedirect, error) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return hostRedirect{}, fmt.Errorf("the new host is missing")
+	}
+
+	redirect := hostRedirect{
+		Host:         strings.ToLower(fields[0]),
+		Code:         301,
+		PreservePath: true,
+	}
+
+	if errs := validation.IsDNS1123Subdomain(redirect.Host); len(errs) > 0 {
+		return redirect, fmt.Errorf("invalid host %q: %v", fields[0], strings.Join(errs, ", "))
+	}
+
+	for _, field := range fields[1:] {
+		if field == dropPath {
+			redirect.PreservePath = false
+			continue
+		}
+
+		code, err := strconv.Atoi(field)
+		if err != nil || !validHostRedirectCodes.Has(code) {
+			return redirect, fmt.Errorf("invalid option %q (expected one of %v or %v)", field, validHostRedirectCodes.List(), dropPath)
+		}
+		redirect.Code = code
+	}
+
+	return redirect, nil
+}
+
+// configureHostRedirects POSTs the host redirects to an internal
+// HTTP endpoint handled by Lua.
+func configureHostRedirects(redirects map[string]hostRedirect) error {
+	return post("/configuration/host-redirects", redirects)
+}
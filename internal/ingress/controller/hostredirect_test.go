@@ -0,0 +1,52 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+func TestParseHostRedirects(t *testing.T) {
+	cm := &apiv1.ConfigMap{
+		Data: map[string]string{
+			"old.example.com":    "new.example.com",
+			"Legacy.example.com": "www.example.com 308 drop-path",
+			"shop.example.com":   "store.example.com 302",
+			"loop.example.com":   "loop.example.com",
+			"bad-code.com":       "new.example.com 200",
+			"bad-host.com":       "https://new.example.com",
+			"empty.example.com":  "",
+		},
+	}
+
+	expected := map[string]hostRedirect{
+		"old.example.com":    {Host: "new.example.com", Code: 301, PreservePath: true},
+		"legacy.example.com": {Host: "www.example.com", Code: 308, PreservePath: false},
+		"shop.example.com":   {Host: "store.example.com", Code: 302, PreservePath: true},
+	}
+	redirects := parseHostRedirects(cm)
+	if !reflect.DeepEqual(redirects, expected) {
+		t.Errorf("expected %v but %v was returned", expected, redirects)
+	}
+
+	redirects = parseHostRedirects(&apiv1.ConfigMap{})
+	if len(redirects) != 0 {
+		t.Errorf("expected no host redirects but %v was returned", redirects)
+	}
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/controller/config"
+)
+
+// setLocationLabels copies to the locations the labels of their Service and
+// Ingress listed in the service-labels and ingress-labels settings. Only those
+// labels are copied, so changes of other labels do not reload NGINX.
+func setLocationLabels(locations []*ingress.Location, cfg config.Configuration) {
+	for _, loc := range locations {
+		loc.ServiceLabels = nil
+		if loc.Service != nil {
+			loc.ServiceLabels = selectLabels(loc.Service.Labels, cfg.ServiceLabels)
+		}
+
+		loc.IngressLabels = nil
+		if loc.Ingress != nil {
+			loc.IngressLabels = selectLabels(loc.Ingress.Labels, cfg.IngressLabels)
+		}
+	}
+}
+
+// selectLabels returns the labels with one of the keys, or nil if none is set
+func selectLabels(labels map[string]string, keys []string) map[string]string {
+	var selected map[string]string
+	for _, key := range keys {
+		value, ok := labels[key]
+		if !ok {
+			continue
+		}
+
+		if selected == nil {
+			selected = make(map[string]string, len(keys))
+		}
+		selected[key] = value
+	}
+
+	return selected
+}
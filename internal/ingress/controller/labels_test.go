@@ -0,0 +1,67 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/controller/config"
+)
+
+func TestSetLocationLabels(t *testing.T) {
+	cfg := config.Configuration{
+		ServiceLabels: []string{"team", "tier"},
+		IngressLabels: []string{"owner"},
+	}
+
+	svc := &apiv1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{"team": "payments", "app": "api"},
+		},
+	}
+	ing := &extensions.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{"team": "web"},
+		},
+	}
+
+	locations := []*ingress.Location{
+		{Path: "/", Service: svc, Ingress: ing},
+		{Path: "/default", IngressLabels: map[string]string{"owner": "stale"}},
+	}
+
+	setLocationLabels(locations, cfg)
+
+	expected := map[string]string{"team": "payments"}
+	if !reflect.DeepEqual(locations[0].ServiceLabels, expected) {
+		t.Errorf("expected %v but %v was returned", expected, locations[0].ServiceLabels)
+	}
+	if locations[0].IngressLabels != nil {
+		t.Errorf("expected no Ingress labels but %v was returned", locations[0].IngressLabels)
+	}
+
+	if locations[1].ServiceLabels != nil || locations[1].IngressLabels != nil {
+		t.Errorf("expected no labels for a location without Service and Ingress but %v and %v were returned",
+			locations[1].ServiceLabels, locations[1].IngressLabels)
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/ingress-nginx/internal/log"
+)
+
+const logRotateCheckPeriod = time.Minute
+
+// rotateLogs rotates the access and error log files when they exceed the
+// size or age defined in the configuration and tells NGINX to reopen them.
+// Logs that are not regular files (like the default symlinks to /dev/stdout
+// and /dev/stderr) are never rotated.
+func (n *NGINXController) rotateLogs() {
+	cfg := n.store.GetBackendConfiguration()
+	if cfg.LogRotateMaxSize <= 0 && cfg.LogRotateMaxAge <= 0 {
+		return
+	}
+
+	if n.logRotations == nil {
+		n.logRotations = map[string]time.Time{}
+	}
+
+	now := time.Now()
+	maxSize := int64(cfg.LogRotateMaxSize) * 1024 * 1024
+	maxAge := time.Duration(cfg.LogRotateMaxAge) * time.Hour
+
+	reopen := false
+	for _, path := range sets.NewString(cfg.AccessLogPath, cfg.ErrorLogPath).List() {
+		since, ok := n.logRotations[path]
+		if !ok {
+			since = now
+			n.logRotations[path] = now
+		}
+
+		rotated, err := rotateLog(path, maxSize, maxAge, cfg.LogRotateMaxFiles, since, now)
+		if err != nil {
+			log.Warningf("Error rotating log file %v: %v", path, err)
+			continue
+		}
+
+		if rotated {
+			log.Infof("Log file %v rotated", path)
+			n.logRotations[path] = now
+			reopen = true
+		}
+	}
+
+	if !reopen {
+		return
+	}
+
+	o, err := nginxExecCommand("-s", "reopen").CombinedOutput()
+	if err != nil {
+		log.Errorf("Error reopening the NGINX log files: %v\n%v", err, string(o))
+	}
+}
+
+// rotateLog renames the file in path to path.1 (after moving the older
+// copies to path.2, path.3...) if it is bigger than maxSize bytes or it was
+// last rotated more than maxAge ago. Only maxFiles copies are kept.
+// Zero values disable the corresponding limit.
+func rotateLog(path string, maxSize int64, maxAge time.Duration, maxFiles int, since, now time.Time) (bool, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if !info.Mode().IsRegular() || info.Size() == 0 {
+		return false, nil
+	}
+
+	bySize := maxSize > 0 && info.Size() >= maxSize
+	byAge := maxAge > 0 && now.Sub(since) >= maxAge
+	if !bySize && !byAge {
+		return false, nil
+	}
+
+	if maxFiles <= 0 {
+		return true, os.Remove(path)
+	}
+
+	err = os.Remove(fmt.Sprintf("%v.%v", path, maxFiles))
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+
+	for i := maxFiles - 1; i > 0; i-- {
+		err = os.Rename(fmt.Sprintf("%v.%v", path, i), fmt.Sprintf("%v.%v", path, i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return false, err
+		}
+	}
+
+	return true, os.Rename(path, fmt.Sprintf("%v.1", path))
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRotateLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logrotate")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "access.log")
+	write := func(content string) {
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	read := func(name string) string {
+		content, err := ioutil.ReadFile(name)
+		if err != nil {
+			return ""
+		}
+		return string(content)
+	}
+
+	now := time.Now()
+
+	rotated, err := rotateLog(path, 10, 0, 2, now, now)
+	if err != nil || rotated {
+		t.Errorf("expected a missing file to be ignored (rotated %v, error %v)", rotated, err)
+	}
+
+	write("first")
+	rotated, err = rotateLog(path, 10, time.Hour, 2, now, now)
+	if err != nil || rotated {
+		t.Errorf("expected a small and recent file not to be rotated (rotated %v, error %v)", rotated, err)
+	}
+
+	write("first log file")
+	rotated, err = rotateLog(path, 10, 0, 2, now, now)
+	if err != nil || !rotated {
+		t.Fatalf("expected the file to be rotated by size (rotated %v, error %v)", rotated, err)
+	}
+	if read(path+".1") != "first log file" {
+		t.Errorf("expected the content of the log file in %v.1", path)
+	}
+
+	write("second")
+	rotated, err = rotateLog(path, 0, time.Hour, 2, now.Add(-2*time.Hour), now)
+	if err != nil || !rotated {
+		t.Fatalf("expected the file to be rotated by age (rotated %v, error %v)", rotated, err)
+	}
+
+	write("third")
+	rotated, err = rotateLog(path, 0, time.Hour, 2, now.Add(-2*time.Hour), now)
+	if err != nil || !rotated {
+		t.Fatalf("expected the file to be rotated by age (rotated %v, error %v)", rotated, err)
+	}
+
+	if read(path+".1") != "third" || read(path+".2") != "second" {
+		t.Errorf("expected the newest copies in %v.1 and %v.2", path, path)
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected only two copies of the log file")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the log file to be moved")
+	}
+
+	link := filepath.Join(dir, "error.log")
+	if err := os.Symlink(path+".1", link); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rotated, err = rotateLog(link, 1, 0, 2, now, now)
+	if err != nil || rotated {
+		t.Errorf("expected a symlink not to be rotated (rotated %v, error %v)", rotated, err)
+	}
+}
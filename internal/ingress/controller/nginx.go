@@ -18,6 +18,7 @@ package controller
 
 import (
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -30,15 +31,15 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"text/template"
 	"time"
 
-	"github.com/golang/glog"
-
-	proxyproto "github.com/armon/go-proxyproto"
 	"github.com/eapache/channels"
 	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes/scheme"
 	v1core "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/record"
@@ -55,8 +56,11 @@ import (
 	ngx_template "k8s.io/ingress-nginx/internal/ingress/controller/template"
 	"k8s.io/ingress-nginx/internal/ingress/metric"
 	"k8s.io/ingress-nginx/internal/ingress/status"
+	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/internal/log"
 	ing_net "k8s.io/ingress-nginx/internal/net"
 	"k8s.io/ingress-nginx/internal/net/dns"
+	"k8s.io/ingress-nginx/internal/net/proxy"
 	"k8s.io/ingress-nginx/internal/net/ssl"
 	"k8s.io/ingress-nginx/internal/task"
 	"k8s.io/ingress-nginx/internal/watch"
@@ -64,23 +68,23 @@ import (
 
 const (
 	ngxHealthPath = "/healthz"
-)
 
-var (
-	tmplPath = "/etc/nginx/template/nginx.tmpl"
+	// ngxShutdownMarker is the file created during the shutdown grace
+	// period to make the NGINX health checks fail
+	ngxShutdownMarker = "/tmp/nginx-shutdown"
 )
 
 // NewNGINXController creates a new NGINX Ingress controller.
 func NewNGINXController(config *Configuration, mc metric.Collector, fs file.Filesystem) *NGINXController {
 	eventBroadcaster := record.NewBroadcaster()
-	eventBroadcaster.StartLogging(glog.Infof)
+	eventBroadcaster.StartLogging(log.Infof)
 	eventBroadcaster.StartRecordingToSink(&v1core.EventSinkImpl{
 		Interface: config.Client.CoreV1().Events(config.Namespace),
 	})
 
 	h, err := dns.GetSystemNameServers()
 	if err != nil {
-		glog.Warningf("Error reading system nameservers: %v", err)
+		log.Warningf("Error reading system nameservers: %v", err)
 	}
 
 	n := &NGINXController{
@@ -99,6 +103,8 @@ func NewNGINXController(config *Configuration, mc metric.Collector, fs file.File
 
 		stopLock: &sync.Mutex{},
 
+		servicePortChoicesLock: &sync.Mutex{},
+
 		fileSystem: fs,
 
 		runningConfig: new(ingress.Configuration),
@@ -106,44 +112,95 @@ func NewNGINXController(config *Configuration, mc metric.Collector, fs file.File
 		Proxy: &TCPProxy{},
 
 		metricCollector: mc,
+
+		stale: &staleConfiguration{
+			reasons: sets.NewString(),
+		},
+
+		drainer: newEndpointDrainer(),
+
+		overrides: newRoutingOverrides(),
+
+		realIPRanges: &realIPRanges{},
+
+		reloads: &reloadHistory{},
+
+		syncWatchdog: &syncWatchdog{},
+
+		snippetFiles: &snippetFileWatch{},
+	}
+
+	ssl.SetClockSkewLeeway(config.SSLClockSkewLeeway)
+
+	if config.OutboundProxy != nil {
+		proxy.Set(config.OutboundProxy, config.OutboundNoProxy)
 	}
 
 	n.store = store.New(
 		config.EnableSSLChainCompletion,
 		config.Namespace,
+		config.NamespaceSelector,
 		config.ConfigMapName,
 		config.DefaultSSLCertificate,
 		config.ResyncPeriod,
 		config.Client,
 		fs,
 		n.updateCh,
-		config.DynamicCertificatesEnabled)
+		config.DynamicCertificatesEnabled,
+		config.Profile,
+		config.EndpointWeightAnnotation,
+		config.ListPageSize)
 
 	n.syncQueue = task.NewTaskQueue(n.syncIngress)
+	n.syncQueue.SetObserver(n.metricCollector)
 
 	n.annotations = annotations.NewAnnotationExtractor(n.store)
 
 	if config.UpdateStatus {
+		electionID := config.ElectionID
+		if config.ShardCount > 1 {
+			// each shard elects the leader updating the status of its Ingresses
+			electionID = fmt.Sprintf("%v-shard-%v", config.ElectionID, config.ShardIndex)
+		}
+
 		n.syncStatus = status.NewStatusSyncer(status.Config{
 			Client:                 config.Client,
 			PublishService:         config.PublishService,
 			PublishStatusAddress:   config.PublishStatusAddress,
-			IngressLister:          n.store,
-			ElectionID:             config.ElectionID,
+			IngressLister:          shardIngressLister{n},
+			ElectionID:             electionID,
+			ElectionLockType:       config.ElectionLockType,
 			IngressClass:           class.IngressClass,
 			DefaultIngressClass:    class.DefaultClass,
 			UpdateStatusOnShutdown: config.UpdateStatusOnShutdown,
 			UseNodeInternalIP:      config.UseNodeInternalIP,
+			LeaseDuration:          config.ElectionLeaseDuration,
+			RenewDeadline:          config.ElectionRenewDeadline,
+			RetryPeriod:            config.ElectionRetryPeriod,
+			OnLeaderChange: func(leader bool) {
+				n.metricCollector.SetLeader(leader)
+			},
+			OnNewLeader: func(identity string) {
+				n.metricCollector.IncLeaderChanges()
+				n.metricCollector.SetLeaderIdentity(identity)
+			},
+			OnSync: func() {
+				if config.PublishEffectiveConfig {
+					n.publishEffectiveConfigurations()
+				}
+			},
 		})
 	} else {
-		glog.Warning("Update of Ingress status is disabled (flag --update-status)")
+		log.Warning("Update of Ingress status is disabled (flag --update-status)")
 	}
 
+	n.templateFuncs = n.newTemplateFuncs()
+
 	onTemplateChange := func() {
-		template, err := ngx_template.NewTemplate(tmplPath, fs)
+		template, err := ngx_template.NewTemplateWithFuncs(config.TemplatePath, fs, n.templateFuncs)
 		if err != nil {
 			// this error is different from the rest because it must be clear why nginx is not working
-			glog.Errorf(`
+			log.Errorf(`
 -------------------------------------------------------------------------------
 Error loading new template: %v
 -------------------------------------------------------------------------------
@@ -152,25 +209,52 @@ Error loading new template: %v
 		}
 
 		n.t = template
-		glog.Info("New NGINX configuration template loaded.")
+		log.Info("New NGINX configuration template loaded.")
 		n.syncQueue.EnqueueTask(task.GetDummyObject("template-change"))
 	}
 
-	ngxTpl, err := ngx_template.NewTemplate(tmplPath, fs)
+	ngxTpl, err := ngx_template.NewTemplateWithFuncs(config.TemplatePath, fs, n.templateFuncs)
 	if err != nil {
-		glog.Fatalf("Invalid NGINX configuration template: %v", err)
+		log.Fatalf("Invalid NGINX configuration template: %v", err)
 	}
 
 	n.t = ngxTpl
 
-	if _, ok := fs.(filesystem.DefaultFs); !ok {
+	n.validator, err = newConfigValidator(config.ConfigTestWorkers, func(cfg string) ([]byte, error) {
+		return nginxTestCommand(cfg).CombinedOutput()
+	})
+	if err != nil {
+		log.Fatalf("Error creating the NGINX configuration validator: %v", err)
+	}
+
+	if config.ReloadConcurrency > 0 {
+		pod, err := k8s.GetPodDetails(config.Client)
+		if err != nil {
+			log.Warningf("Reloads are not coordinated: %v", err)
+		} else {
+			n.reloadCoordinator = newReloadCoordinator(config.Client.CoordinationV1beta1(), pod.Namespace,
+				fmt.Sprintf("%v-reload", config.ElectionID), pod.Name, config.ReloadConcurrency, config.ReloadCoordinationTimeout)
+		}
+	}
+
+	_, watchFiles := fs.(filesystem.DefaultFs)
+	n.customTemplates = newTemplateCache(fs, n.templateFuncs, watchFiles, func(path string) {
+		if n.store.GetBackendConfiguration().CustomTemplate != path {
+			return
+		}
+
+		n.setForceReload(true)
+		n.syncQueue.EnqueueTask(task.GetDummyObject("template-change"))
+	})
+
+	if !watchFiles {
 		// do not setup watchers on tests
 		return n
 	}
 
-	_, err = watch.NewFileWatcher(tmplPath, onTemplateChange)
+	_, err = watch.NewFileWatcher(config.TemplatePath, onTemplateChange)
 	if err != nil {
-		glog.Fatalf("Error creating file watcher for %v: %v", tmplPath, err)
+		log.Fatalf("Error creating file watcher for %v: %v", config.TemplatePath, err)
 	}
 
 	filesToWatch := []string{}
@@ -188,16 +272,16 @@ Error loading new template: %v
 	})
 
 	if err != nil {
-		glog.Fatalf("Error creating file watchers: %v", err)
+		log.Fatalf("Error creating file watchers: %v", err)
 	}
 
 	for _, f := range filesToWatch {
 		_, err = watch.NewFileWatcher(f, func() {
-			glog.Infof("File %v changed. Reloading NGINX", f)
+			log.Infof("File %v changed. Reloading NGINX", f)
 			n.syncQueue.EnqueueTask(task.GetDummyObject("file-change"))
 		})
 		if err != nil {
-			glog.Fatalf("Error creating file watcher for %v: %v", f, err)
+			log.Fatalf("Error creating file watcher for %v: %v", f, err)
 		}
 	}
 
@@ -234,6 +318,21 @@ type NGINXController struct {
 
 	t *ngx_template.Template
 
+	// templateFuncs contains the functions available in the
+	// templates in addition to the built-in ones
+	templateFuncs *ngx_template.FuncRegistry
+
+	// customTemplates contains the templates of the custom-template
+	// key of the configuration ConfigMap
+	customTemplates *templateCache
+
+	// validator tests the NGINX configuration before a reload
+	validator *configValidator
+
+	// reloadCoordinator limits the instances reloading NGINX at the
+	// same time. Nil if the reloads are not coordinated
+	reloadCoordinator *reloadCoordinator
+
 	resolver []net.IP
 
 	isIPV6Enabled bool
@@ -247,11 +346,126 @@ type NGINXController struct {
 	fileSystem filesystem.Filesystem
 
 	metricCollector metric.Collector
+
+	// stale contains the reasons why the running configuration could be outdated
+	stale *staleConfiguration
+
+	// denylistInvalid contains the invalid entries of the denylist
+	// ConfigMap already reported
+	denylistInvalid sets.String
+
+	// hostRedirects contains the host redirects configured in NGINX
+	hostRedirects map[string]hostRedirect
+
+	// notYetValidCerts contains the SSL certificates already reported as not yet valid
+	notYetValidCerts sets.String
+
+	// dynamicChecksum contains the checksum of the last backends posted
+	// to NGINX, compared with the one reported by Lua in the health check
+	dynamicChecksum atomic.Value
+
+	// effectiveConfigSource contains the running configuration summarized in the
+	// effective configuration annotation of the Ingresses by the leader
+	effectiveConfigSource atomic.Value
+
+	// postedBackends are the last backends posted to NGINX, used to send
+	// only the changes in the next sync. Only used in syncIngress
+	postedBackends *postedBackends
+
+	// dynamicFailures is the number of consecutive syncs that failed
+	// to configure NGINX dynamically. Only used in syncIngress
+	dynamicFailures int
+
+	// failedConfig is the last configuration that could not be applied,
+	// not tried again for a while. Only used in syncIngress
+	failedConfig *failedConfig
+
+	// checksums are the checksums of the servers and backends of the
+	// last configuration that required a reload. Only used in syncIngress
+	checksums configChecksums
+
+	// snippetFiles watches the snippet files included in the configuration
+	snippetFiles *snippetFileWatch
+
+	// forceReload indicates the next sync must reload NGINX even
+	// if the configuration did not change
+	forceReload int32
+
+	// tunnels contains the tunnels through forward proxies used to
+	// reach upstream servers, indexed by the unix socket of the tunnel.
+	// Only modified in syncIngress
+	tunnels map[string]*connectTunnel
+
+	// classConflicts contains the class conflicts already reported
+	classConflicts sets.String
+
+	// duplicatePaths contains the duplicate paths already reported
+	duplicatePaths sets.String
+
+	// canaryConflicts contains the canaries not configured already reported
+	canaryConflicts sets.String
+
+	// servicePortChoices contains the choices already reported between
+	// several Service ports matching the port of an Ingress backend, indexed
+	// by Service and backend port. Only used in syncIngress, concurrently
+	servicePortChoices     map[string]string
+	servicePortChoicesLock *sync.Mutex
+
+	// drainer keeps the endpoints removed from upstreams with session
+	// affinity during the drain period. Only used in syncIngress
+	drainer *endpointDrainer
+
+	// overrides contains the temporary routing overrides
+	overrides *routingOverrides
+
+	// externalNames indicates if the hostnames of the ExternalName Services
+	// were resolved in the last check. Only used in resolveExternalNames
+	externalNames map[string]bool
+
+	// realIPRanges contains the IP ranges published by CDNs
+	realIPRanges *realIPRanges
+
+	// logRotations contains the time of the last rotation of each
+	// log file. Only used in rotateLogs
+	logRotations map[string]time.Time
+
+	// reloads contains the time of the last reloads of NGINX
+	reloads *reloadHistory
+
+	// reloadDeferredSince is the time since the reloads are deferred because
+	// of the active WebSocket connections. Only used in syncIngress
+	reloadDeferredSince time.Time
+
+	// reloadRetry enqueues a sync to retry a deferred reload
+	reloadRetry *time.Timer
+
+	// lingeringWorkers contains the PIDs of the NGINX workers already reported
+	// as shutting down for longer than worker-shutdown-timeout. Only used
+	// in checkLingeringWorkers
+	lingeringWorkers sets.Int
+
+	// syncWatchdog keeps the start time of the sync in progress, checked
+	// periodically to report the syncs that do not complete
+	syncWatchdog *syncWatchdog
 }
 
 // Start starts a new NGINX master process running in the foreground.
 func (n *NGINXController) Start() {
-	glog.Infof("Starting NGINX Ingress controller")
+	log.Infof("Starting NGINX Ingress controller")
+
+	// remove the marker left by a previous shutdown of the container
+	if err := os.Remove(ngxShutdownMarker); err != nil && !os.IsNotExist(err) {
+		log.Warningf("Error removing shutdown marker %v: %v", ngxShutdownMarker, err)
+	}
+
+	if err := createConfigSocketDirectory(); err != nil {
+		log.Fatalf("Error creating the directory of the configuration socket %v: %v", ngxConfigSocket, err)
+	}
+
+	// NGINX cannot listen on the socket left by a previous execution
+	if err := os.Remove(ngxConfigSocket); err != nil && !os.IsNotExist(err) {
+		log.Warningf("Error removing configuration socket %v: %v", ngxConfigSocket, err)
+	}
 
 	n.store.Run(n.stopCh)
 
@@ -272,10 +486,44 @@ func (n *NGINXController) Start() {
 		n.setupSSLProxy()
 	}
 
-	glog.Info("Starting NGINX process")
+	if n.cfg.MaxmindLicenseKey != "" {
+		// the databases must be available before NGINX starts
+		n.updateGeoIPDatabases()
+		go wait.PollUntil(n.cfg.MaxmindRefreshPeriod, func() (bool, error) {
+			n.updateGeoIPDatabases()
+			return false, nil
+		}, n.stopCh)
+	}
+
+	log.Info("Starting NGINX process")
 	n.start(cmd)
 
 	go n.syncQueue.Run(time.Second, n.stopCh)
+	go wait.Until(n.checkAPIServer, 30*time.Second, n.stopCh)
+
+	if n.cfg.DenylistConfigMap != "" {
+		go wait.Until(n.syncDenylist, denylistSyncPeriod, n.stopCh)
+	}
+
+	if n.cfg.HostRedirectConfigMap != "" {
+		go wait.Until(n.syncHostRedirects, hostRedirectSyncPeriod, n.stopCh)
+	}
+
+	go wait.Until(n.rotateLogs, logRotateCheckPeriod, n.stopCh)
+	go wait.Until(n.checkLingeringWorkers, workerCheckPeriod, n.stopCh)
+
+	if n.cfg.SyncWatchdogDeadline > 0 {
+		go wait.Until(n.checkSyncWatchdog, syncWatchdogPeriod(n.cfg.SyncWatchdogDeadline), n.stopCh)
+	}
+
+	if n.cfg.ExternalNameResolvePeriod > 0 {
+		go wait.Until(n.resolveExternalNames, n.cfg.ExternalNameResolvePeriod, n.stopCh)
+	}
+
+	if len(n.cfg.RealIPRangesURLs) > 0 {
+		go wait.Until(n.syncRealIPRanges, n.cfg.RealIPRangesSyncPeriod, n.stopCh)
+	}
+
 	// force initial sync
 	n.syncQueue.EnqueueTask(task.GetDummyObject("initial-sync"))
 
@@ -292,6 +540,7 @@ func (n *NGINXController) Start() {
 			// issues because of this behavior.
 			// To avoid this issue we restart nginx in case of errors.
 			if process.IsRespawnIfRequired(err) {
+				n.metricCollector.IncNGINXRestartCount()
 				process.WaitUntilPortIsAvailable(n.cfg.ListenPorts.HTTP)
 				// release command resources
 				cmd.Process.Release()
@@ -302,13 +551,15 @@ func (n *NGINXController) Start() {
 					Pgid:    0,
 				}
 				n.start(cmd)
+				// the new process does not know the reasons of the stale configuration
+				n.stale.resend()
 			}
 		case event := <-n.updateCh.Out():
 			if n.isShuttingDown {
 				break
 			}
 			if evt, ok := event.(store.Event); ok {
-				glog.V(3).Infof("Event %v received - object %v", evt.Type, evt.Obj)
+				log.V(3).Infof("Event %v received - object %v", evt.Type, evt.Obj)
 				if evt.Type == store.ConfigurationEvent {
 					// TODO: is this necessary? Consider removing this special case
 					n.syncQueue.EnqueueTask(task.GetDummyObject("configmap-change"))
@@ -317,7 +568,7 @@ func (n *NGINXController) Start() {
 
 				n.syncQueue.EnqueueSkippableTask(evt.Obj)
 			} else {
-				glog.Warningf("Unexpected event type received %T", event)
+				log.Warningf("Unexpected event type received %T", event)
 			}
 		case <-n.stopCh:
 			break
@@ -336,7 +587,9 @@ func (n *NGINXController) Stop() error {
 		return fmt.Errorf("shutdown already in progress")
 	}
 
-	glog.Infof("Shutting down controller queues")
+	n.waitForDeregistration()
+
+	log.Infof("Shutting down controller queues")
 	close(n.stopCh)
 	go n.syncQueue.Shutdown()
 	if n.syncStatus != nil {
@@ -344,7 +597,7 @@ func (n *NGINXController) Stop() error {
 	}
 
 	// send stop signal to NGINX
-	glog.Info("Stopping NGINX process")
+	log.Info("Stopping NGINX process")
 	cmd := nginxExecCommand("-s", "quit")
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -357,7 +610,7 @@ func (n *NGINXController) Stop() error {
 	timer := time.NewTicker(time.Second * 1)
 	for range timer.C {
 		if !process.IsNginxRunning() {
-			glog.Info("NGINX process has stopped")
+			log.Info("NGINX process has stopped")
 			timer.Stop()
 			break
 		}
@@ -366,11 +619,28 @@ func (n *NGINXController) Stop() error {
 	return nil
 }
 
+// waitForDeregistration makes the health checks fail and waits for the
+// shutdown grace period, leaving the load balancers time to stop sending
+// new connections to this instance before NGINX is stopped
+func (n *NGINXController) waitForDeregistration() {
+	if n.cfg.ShutdownGracePeriod <= 0 {
+		return
+	}
+
+	err := ioutil.WriteFile(ngxShutdownMarker, []byte{}, file.ReadWriteByUser)
+	if err != nil {
+		log.Warningf("Error creating shutdown marker %v: %v", ngxShutdownMarker, err)
+	}
+
+	log.Infof("Waiting %v for the load balancers to deregister the instance", n.cfg.ShutdownGracePeriod)
+	time.Sleep(n.cfg.ShutdownGracePeriod)
+}
+
 func (n *NGINXController) start(cmd *exec.Cmd) {
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	if err := cmd.Start(); err != nil {
-		glog.Fatalf("NGINX error: %v", err)
+		log.Fatalf("NGINX error: %v", err)
 		n.ngxErrCh <- err
 		return
 	}
@@ -389,37 +659,6 @@ func (n NGINXController) DefaultEndpoint() ingress.Endpoint {
 	}
 }
 
-// testTemplate checks if the NGINX configuration inside the byte array is valid
-// running the command "nginx -t" using a temporal file.
-func (n NGINXController) testTemplate(cfg []byte) error {
-	if len(cfg) == 0 {
-		return fmt.Errorf("invalid NGINX configuration (empty)")
-	}
-	tmpfile, err := ioutil.TempFile("", "nginx-cfg")
-	if err != nil {
-		return err
-	}
-	defer tmpfile.Close()
-	err = ioutil.WriteFile(tmpfile.Name(), cfg, file.ReadWriteByUser)
-	if err != nil {
-		return err
-	}
-	out, err := nginxTestCommand(tmpfile.Name()).CombinedOutput()
-	if err != nil {
-		// this error is different from the rest because it must be clear why nginx is not working
-		oe := fmt.Sprintf(`
--------------------------------------------------------------------------------
-Error: %v
-%v
--------------------------------------------------------------------------------
-`, err, string(out))
-		return errors.New(oe)
-	}
-
-	os.Remove(tmpfile.Name())
-	return nil
-}
-
 // OnUpdate is called by the synchronization loop whenever configuration
 // changes were detected. The received backend Configuration is merged with the
 // configuration ConfigMap before generating the final configuration file.
@@ -433,7 +672,7 @@ func (n *NGINXController) OnUpdate(ingressCfg ingress.Configuration) error {
 		for _, pb := range ingressCfg.PassthroughBackends {
 			svc := pb.Service
 			if svc == nil {
-				glog.Warningf("Missing Service for SSL Passthrough backend %q", pb.Backend)
+				log.Warningf("Missing Service for SSL Passthrough backend %q", pb.Backend)
 				continue
 			}
 			port, err := strconv.Atoi(pb.Port.String())
@@ -453,12 +692,11 @@ func (n *NGINXController) OnUpdate(ingressCfg ingress.Configuration) error {
 				}
 			}
 
-			// TODO: Allow PassthroughBackends to specify they support proxy-protocol
 			servers = append(servers, &TCPServer{
 				Hostname:      pb.Hostname,
 				IP:            svc.Spec.ClusterIP,
 				Port:          port,
-				ProxyProtocol: false,
+				ProxyProtocol: pb.ProxyProtocol,
 			})
 		}
 
@@ -473,54 +711,39 @@ func (n *NGINXController) OnUpdate(ingressCfg ingress.Configuration) error {
 	// https://trac.nginx.org/nginx/ticket/631
 	var longestName int
 	var serverNameBytes int
-	redirectServers := make(map[string]string)
 	for _, srv := range ingressCfg.Servers {
 		if longestName < len(srv.Hostname) {
 			longestName = len(srv.Hostname)
 		}
 		serverNameBytes += len(srv.Hostname)
-		if srv.RedirectFromToWWW {
-			var n string
-			if strings.HasPrefix(srv.Hostname, "www.") {
-				n = strings.TrimPrefix(srv.Hostname, "www.")
-			} else {
-				n = fmt.Sprintf("www.%v", srv.Hostname)
-			}
-			glog.V(3).Infof("Creating redirect from %q to %q", srv.Hostname, n)
-			if _, ok := redirectServers[n]; !ok {
-				found := false
-				for _, esrv := range ingressCfg.Servers {
-					if esrv.Hostname == n {
-						found = true
-						break
-					}
-				}
-				if !found {
-					redirectServers[n] = srv.Hostname
-				}
-			}
+	}
+	redirectServers := buildRedirectServers(ingressCfg.Servers)
+	for _, redirect := range redirectServers {
+		if longestName < len(redirect.From) {
+			longestName = len(redirect.From)
 		}
+		serverNameBytes += len(redirect.From)
 	}
 	if cfg.ServerNameHashBucketSize == 0 {
 		nameHashBucketSize := nginxHashBucketSize(longestName)
-		glog.V(3).Infof("Adjusting ServerNameHashBucketSize variable to %d", nameHashBucketSize)
+		log.V(3).Infof("Adjusting ServerNameHashBucketSize variable to %d", nameHashBucketSize)
 		cfg.ServerNameHashBucketSize = nameHashBucketSize
 	}
 	serverNameHashMaxSize := nextPowerOf2(serverNameBytes)
 	if cfg.ServerNameHashMaxSize < serverNameHashMaxSize {
-		glog.V(3).Infof("Adjusting ServerNameHashMaxSize variable to %d", serverNameHashMaxSize)
+		log.V(3).Infof("Adjusting ServerNameHashMaxSize variable to %d", serverNameHashMaxSize)
 		cfg.ServerNameHashMaxSize = serverNameHashMaxSize
 	}
 
 	// the limit of open files is per worker process
 	// and we leave some room to avoid consuming all the FDs available
 	wp, err := strconv.Atoi(cfg.WorkerProcesses)
-	glog.V(3).Infof("Number of worker processes: %d", wp)
+	log.V(3).Infof("Number of worker processes: %d", wp)
 	if err != nil {
 		wp = 1
 	}
 	maxOpenFiles := (sysctlFSFileMax() / wp) - 1024
-	glog.V(2).Infof("Maximum number of open file descriptors: %d", maxOpenFiles)
+	log.V(2).Infof("Maximum number of open file descriptors: %d", maxOpenFiles)
 	if maxOpenFiles < 1024 {
 		// this means the value of RLIMIT_NOFILE is too low.
 		maxOpenFiles = 1024
@@ -530,7 +753,7 @@ func (n *NGINXController) OnUpdate(ingressCfg ingress.Configuration) error {
 	if cfg.ProxySetHeaders != "" {
 		cmap, err := n.store.GetConfigMap(cfg.ProxySetHeaders)
 		if err != nil {
-			glog.Warningf("Error reading ConfigMap %q from local store: %v", cfg.ProxySetHeaders, err)
+			log.Warningf("Error reading ConfigMap %q from local store: %v", cfg.ProxySetHeaders, err)
 		}
 
 		setHeaders = cmap.Data
@@ -540,7 +763,7 @@ func (n *NGINXController) OnUpdate(ingressCfg ingress.Configuration) error {
 	if cfg.AddHeaders != "" {
 		cmap, err := n.store.GetConfigMap(cfg.AddHeaders)
 		if err != nil {
-			glog.Warningf("Error reading ConfigMap %q from local store: %v", cfg.AddHeaders, err)
+			log.Warningf("Error reading ConfigMap %q from local store: %v", cfg.AddHeaders, err)
 		}
 
 		addHeaders = cmap.Data
@@ -552,7 +775,7 @@ func (n *NGINXController) OnUpdate(ingressCfg ingress.Configuration) error {
 
 		secret, err := n.store.GetSecret(secretName)
 		if err != nil {
-			glog.Warningf("Error reading Secret %q from local store: %v", secretName, err)
+			log.Warningf("Error reading Secret %q from local store: %v", secretName, err)
 		}
 
 		nsSecName := strings.Replace(secretName, "/", "-", -1)
@@ -561,7 +784,7 @@ func (n *NGINXController) OnUpdate(ingressCfg ingress.Configuration) error {
 		if ok {
 			pemFileName, err := ssl.AddOrUpdateDHParam(nsSecName, dh, n.fileSystem)
 			if err != nil {
-				glog.Warningf("Error adding or updating dhparam file %v: %v", nsSecName, err)
+				log.Warningf("Error adding or updating dhparam file %v: %v", nsSecName, err)
 			} else {
 				sslDHParam = pemFileName
 			}
@@ -570,6 +793,21 @@ func (n *NGINXController) OnUpdate(ingressCfg ingress.Configuration) error {
 
 	cfg.SSLDHParam = sslDHParam
 
+	if _, ok := n.fileSystem.(filesystem.DefaultFs); ok {
+		n.snippetFiles.watch(snippetDirs(cfg), n.checkSnippetFiles)
+	}
+
+	snippetFiles, err := readSnippetFiles(cfg)
+	if err != nil {
+		n.reportConfigFailure(err, nil)
+		return err
+	}
+
+	// the IP ranges published by CDNs are trusted like the ones of the configuration
+	if len(ingressCfg.RealIPRanges) > 0 {
+		cfg.ProxyRealIPCIDR = append(append([]string{}, cfg.ProxyRealIPCIDR...), ingressCfg.RealIPRanges...)
+	}
+
 	tc := ngx_config.TemplateConfig{
 		ProxySetHeaders:            setHeaders,
 		AddHeaders:                 addHeaders,
@@ -579,9 +817,13 @@ func (n *NGINXController) OnUpdate(ingressCfg ingress.Configuration) error {
 		PassthroughBackends:        ingressCfg.PassthroughBackends,
 		Servers:                    ingressCfg.Servers,
 		HealthzURI:                 ngxHealthPath,
+		ShutdownMarker:             ngxShutdownMarker,
+		ConfigSocket:               ngxConfigSocket,
 		CustomErrors:               len(cfg.CustomHTTPErrors) > 0,
 		Cfg:                        cfg,
 		IsIPV6Enabled:              n.isIPV6Enabled && !cfg.DisableIpv6,
+		HTTPListen:                 ngx_config.NewListenIPFamily(cfg.ListenIPFamily, n.isIPV6Enabled && !cfg.DisableIpv6),
+		StatusListen:               ngx_config.NewListenIPFamily(cfg.StatusIPFamily(), n.isIPV6Enabled && !cfg.DisableIpv6),
 		NginxStatusIpv4Whitelist:   cfg.NginxStatusIpv4Whitelist,
 		NginxStatusIpv6Whitelist:   cfg.NginxStatusIpv6Whitelist,
 		RedirectServers:            redirectServers,
@@ -589,12 +831,27 @@ func (n *NGINXController) OnUpdate(ingressCfg ingress.Configuration) error {
 		ListenPorts:                n.cfg.ListenPorts,
 		PublishService:             n.GetPublishService(),
 		DynamicCertificatesEnabled: n.cfg.DynamicCertificatesEnabled,
+		DenylistEnabled:            n.cfg.DenylistConfigMap != "",
+		HostRedirectsEnabled:       n.cfg.HostRedirectConfigMap != "",
+		Profile:                    n.cfg.Profile,
+		EnableRequestMetrics:       n.cfg.Profile != ngx_config.LowMemoryProfile,
+		SnippetFiles:               snippetFiles,
 	}
 
 	tc.Cfg.Checksum = ingressCfg.ConfigurationChecksum
 
-	content, err := n.t.Write(tc)
+	tmpl := n.t
+	if cfg.CustomTemplate != "" {
+		tmpl, err = n.customTemplates.get(cfg.CustomTemplate)
+		if err != nil {
+			n.reportConfigFailure(err, nil)
+			return err
+		}
+	}
+
+	content, err := tmpl.Write(tc)
 	if err != nil {
+		n.reportConfigFailure(err, nil)
 		return err
 	}
 
@@ -605,32 +862,21 @@ func (n *NGINXController) OnUpdate(ingressCfg ingress.Configuration) error {
 		}
 	}
 
-	err = n.testTemplate(content)
+	err = n.validator.Validate(content)
 	if err != nil {
+		n.reportConfigFailure(err, content)
 		return err
 	}
 
-	if glog.V(2) {
+	if log.V(2) {
 		src, _ := ioutil.ReadFile(cfgPath)
 		if !bytes.Equal(src, content) {
-			tmpfile, err := ioutil.TempFile("", "new-nginx-cfg")
+			diff, err := configDiff(cfgPath, content)
 			if err != nil {
 				return err
 			}
-			defer tmpfile.Close()
-			err = ioutil.WriteFile(tmpfile.Name(), content, file.ReadWriteByUser)
-			if err != nil {
-				return err
-			}
-
-			// TODO: executing diff can return exit code != 0
-			diffOutput, _ := exec.Command("diff", "-u", cfgPath, tmpfile.Name()).CombinedOutput()
-
-			glog.Infof("NGINX configuration diff:\n%v", string(diffOutput))
 
-			// we do not defer the deletion of temp files in order
-			// to keep them around for inspection in case of error
-			os.Remove(tmpfile.Name())
+			log.Infof("NGINX configuration diff:\n%v", diff)
 		}
 	}
 
@@ -641,9 +887,22 @@ func (n *NGINXController) OnUpdate(ingressCfg ingress.Configuration) error {
 
 	o, err := nginxExecCommand("-s", "reload").CombinedOutput()
 	if err != nil {
-		return fmt.Errorf("%v\n%v", err, string(o))
+		// NGINX uses the last configuration loaded successfully
+		if rerr := restoreLastGoodConfig(cfgPath); rerr != nil {
+			log.Warningf("Error restoring the last valid NGINX configuration: %v", rerr)
+		}
+
+		err = fmt.Errorf("%v\n%v", err, string(o))
+		n.reportConfigFailure(err, content)
+		return err
+	}
+
+	if err := saveLastGoodConfig(content); err != nil {
+		log.Warningf("Error saving a copy of the NGINX configuration: %v", err)
 	}
 
+	n.snippetFiles.setLoaded(snippetFiles)
+
 	return nil
 }
 
@@ -673,48 +932,50 @@ func nextPowerOf2(v int) int {
 }
 
 func (n *NGINXController) setupSSLProxy() {
-	cfg := n.store.GetBackendConfiguration()
 	sslPort := n.cfg.ListenPorts.HTTPS
 	proxyPort := n.cfg.ListenPorts.SSLProxy
 
-	glog.Info("Starting TLS proxy for SSL Passthrough")
+	log.Info("Starting TLS proxy for SSL Passthrough")
 	n.Proxy = &TCPProxy{
 		Default: &TCPServer{
 			Hostname:      "localhost",
 			IP:            "127.0.0.1",
 			Port:          proxyPort,
-			ProxyProtocol: true,
+			ProxyProtocol: proxyProtocolV1,
 		},
 	}
 
 	listener, err := net.Listen("tcp", fmt.Sprintf(":%v", sslPort))
 	if err != nil {
-		glog.Fatalf("%v", err)
+		log.Fatalf("%v", err)
 	}
 
-	proxyList := &proxyproto.Listener{Listener: listener, ProxyHeaderTimeout: cfg.ProxyProtocolHeaderTimeout}
-
 	// accept TCP connections on the configured HTTPS port
 	go func() {
 		for {
-			var conn net.Conn
-			var err error
-
-			if n.store.GetBackendConfiguration().UseProxyProtocol {
-				// wrap the listener in order to decode Proxy
-				// Protocol before handling the connection
-				conn, err = proxyList.Accept()
-			} else {
-				conn, err = listener.Accept()
-			}
-
+			conn, err := listener.Accept()
 			if err != nil {
-				glog.Warningf("Error accepting TCP connection: %v", err)
+				log.Warningf("Error accepting TCP connection: %v", err)
 				continue
 			}
 
-			glog.V(3).Infof("Handling connection from remote address %s to local %s", conn.RemoteAddr(), conn.LocalAddr())
-			go n.Proxy.Handle(conn)
+			go func() {
+				cfg := n.store.GetBackendConfiguration()
+				if cfg.ProxyProtocolOn(ngx_config.HTTPSListener) {
+					// decode the PROXY protocol, v1 or v2, before
+					// handling the connection
+					ppConn, err := newProxyProtocolConn(conn, cfg.ProxyProtocolHeaderTimeout)
+					if err != nil {
+						log.Warningf("Error reading PROXY protocol header from %v: %v", conn.RemoteAddr(), err)
+						conn.Close()
+						return
+					}
+					conn = ppConn
+				}
+
+				log.V(3).Infof("Handling connection from remote address %s to local %s", conn.RemoteAddr(), conn.LocalAddr())
+				n.Proxy.Handle(conn)
+			}()
 		}
 	}()
 }
@@ -749,8 +1010,11 @@ func (n *NGINXController) IsDynamicConfigurationEnough(pcfg *ingress.Configurati
 }
 
 // configureDynamically encodes new Backends in JSON format and POSTs the
-// payload to an internal HTTP endpoint handled by Lua.
-func configureDynamically(pcfg *ingress.Configuration, port int, isDynamicCertificatesEnabled bool) error {
+// payload to an internal HTTP endpoint handled by Lua. When the backends
+// posted before are known only the backends added, changed and removed
+// are sent, unless NGINX does not have them anymore. Returns the posted
+// backends.
+func configureDynamically(pcfg *ingress.Configuration, previous *postedBackends, isDynamicCertificatesEnabled bool) (*postedBackends, error) {
 	backends := make([]*ingress.Backend, len(pcfg.Backends))
 
 	for i, backend := range pcfg.Backends {
@@ -769,13 +1033,16 @@ func configureDynamically(pcfg *ingress.Configuration, port int, isDynamicCertif
 			NoServer:             backend.NoServer,
 			TrafficShapingPolicy: backend.TrafficShapingPolicy,
 			AlternativeBackends:  backend.AlternativeBackends,
+			QueryRoutingPolicies: backend.QueryRoutingPolicies,
 		}
 
 		var endpoints []ingress.Endpoint
 		for _, endpoint := range backend.Endpoints {
 			endpoints = append(endpoints, ingress.Endpoint{
-				Address: endpoint.Address,
-				Port:    endpoint.Port,
+				Address:  endpoint.Address,
+				Port:     endpoint.Port,
+				Weight:   endpoint.Weight,
+				Draining: endpoint.Draining,
 			})
 		}
 
@@ -783,25 +1050,47 @@ func configureDynamically(pcfg *ingress.Configuration, port int, isDynamicCertif
 		backends[i] = luaBackend
 	}
 
-	url := fmt.Sprintf("http://localhost:%d/configuration/backends", port)
-	err := post(url, backends)
+	posted, buf, err := encodeBackends(backends)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	err = errConfigurationConflict
+	if previous != nil {
+		delta := posted.deltaFrom(previous)
+		log.V(2).Infof("Posting %v changed and %v removed backends", len(delta.Update), len(delta.Remove))
+		err = post("/configuration/backends/delta", delta)
+	}
+	if err == errConfigurationConflict {
+		err = post("/configuration/backends", json.RawMessage(buf))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the credentials are only posted while some location uses them
+	credentials := buildOIDCCredentials(pcfg.Servers)
+	if len(credentials) > 0 || (previous != nil && previous.oidcCredentials) {
+		err = configureOIDCCredentials(credentials)
+		if err != nil {
+			return nil, err
+		}
 	}
+	posted.oidcCredentials = len(credentials) > 0
 
 	if isDynamicCertificatesEnabled {
-		err = configureCertificates(pcfg, port)
+		err = configureCertificates(pcfg)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	return nil
+	return posted, nil
 }
 
 // configureCertificates JSON encodes certificates and POSTs it to an internal HTTP endpoint
 // that is handled by Lua
-func configureCertificates(pcfg *ingress.Configuration, port int) error {
+func configureCertificates(pcfg *ingress.Configuration) error {
 	var servers []*ingress.Server
 
 	for _, server := range pcfg.Servers {
@@ -813,8 +1102,16 @@ func configureCertificates(pcfg *ingress.Configuration, port int) error {
 		})
 	}
 
-	url := fmt.Sprintf("http://localhost:%d/configuration/servers", port)
-	err := post(url, servers)
+	for _, redirect := range buildRedirectServers(pcfg.Servers) {
+		servers = append(servers, &ingress.Server{
+			Hostname: redirect.From,
+			SSLCert: ingress.SSLCert{
+				PemCertKey: redirect.SSLCert.PemCertKey,
+			},
+		})
+	}
+
+	err := post("/configuration/servers", servers)
 	if err != nil {
 		return err
 	}
@@ -822,25 +1119,54 @@ func configureCertificates(pcfg *ingress.Configuration, port int) error {
 	return nil
 }
 
-func post(url string, data interface{}) error {
+// errConfigurationConflict is returned by post when NGINX rejects a change
+// of its configuration based on another one
+var errConfigurationConflict = errors.New("the configuration of NGINX changed")
+
+// post sends data in JSON format, compressed with gzip, to a path of the
+// internal endpoint /configuration through the Unix socket of NGINX
+func post(path string, data interface{}) error {
 	buf, err := json.Marshal(data)
 	if err != nil {
 		return err
 	}
 
-	glog.V(2).Infof("Posting to %s", url)
+	var body bytes.Buffer
+	gz, err := gzip.NewWriterLevel(&body, gzip.BestSpeed)
+	if err != nil {
+		return err
+	}
+	if _, err := gz.Write(buf); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	log.V(2).Infof("Posting %v bytes (%v compressed) to %s", len(buf), body.Len(), path)
 
-	resp, err := http.Post(url, "application/json", bytes.NewReader(buf))
+	req, err := http.NewRequest("POST", configurationURL(path), &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+
+	resp, err := configurationClient(0).Do(req)
 	if err != nil {
 		return err
 	}
 
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
-			glog.Warningf("Error while closing response body:\n%v", err)
+			log.Warningf("Error while closing response body:\n%v", err)
 		}
 	}()
 
+	if resp.StatusCode == http.StatusConflict {
+		return errConfigurationConflict
+	}
+
 	if resp.StatusCode != http.StatusCreated {
 		return fmt.Errorf("unexpected error code: %d", resp.StatusCode)
 	}
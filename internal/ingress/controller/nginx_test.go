@@ -17,11 +17,11 @@ limitations under the License.
 package controller
 
 import (
+	"crypto/md5"
+	"fmt"
 	"io"
 	"io/ioutil"
-	"net"
 	"net/http"
-	"net/http/httptest"
 	"strings"
 	"testing"
 
@@ -182,7 +182,8 @@ func TestConfigureDynamically(t *testing.T) {
 		Servers:  servers,
 	}
 
-	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	var posted []byte
+	stop := newConfigurationServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusCreated)
 
 		if r.Method != "POST" {
@@ -193,6 +194,7 @@ func TestConfigureDynamically(t *testing.T) {
 		if err != nil && err != io.EOF {
 			t.Fatal(err)
 		}
+		posted = b
 		body := string(b)
 		if strings.Contains(body, "target") {
 			t.Errorf("unexpected target reference in JSON content: %v", body)
@@ -203,13 +205,15 @@ func TestConfigureDynamically(t *testing.T) {
 		}
 
 	}))
+	defer stop()
 
-	port := ts.Listener.Addr().(*net.TCPAddr).Port
-	defer ts.Close()
-
-	err := configureDynamically(commonConfig, port, false)
+	p, err := configureDynamically(commonConfig, nil, false)
 	if err != nil {
-		t.Errorf("unexpected error posting dynamic configuration: %v", err)
+		t.Fatalf("unexpected error posting dynamic configuration: %v", err)
+	}
+
+	if p.checksum != fmt.Sprintf("%x", md5.Sum(posted)) {
+		t.Errorf("expected the checksum of the posted backends but %v returned", p.checksum)
 	}
 
 	if commonConfig.Backends[0].Endpoints[0].Target != target {
@@ -230,7 +234,7 @@ func TestConfigureCertificates(t *testing.T) {
 		Servers: servers,
 	}
 
-	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	stop := newConfigurationServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusCreated)
 
 		if r.Method != "POST" {
@@ -257,11 +261,9 @@ func TestConfigureCertificates(t *testing.T) {
 			}
 		}
 	}))
+	defer stop()
 
-	port := ts.Listener.Addr().(*net.TCPAddr).Port
-	defer ts.Close()
-
-	err := configureCertificates(commonConfig, port)
+	err := configureCertificates(commonConfig)
 	if err != nil {
 		t.Errorf("unexpected error posting dynamic certificate configuration: %v", err)
 	}
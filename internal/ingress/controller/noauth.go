@@ -0,0 +1,75 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strings"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/auth"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/authoidc"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/authreq"
+	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/internal/log"
+)
+
+// addNoAuthLocations adds to a server the locations of the paths of an
+// Ingress exempt from its authentication annotations (no-auth-locations).
+// Each path is configured like the location with the longest path that is a
+// prefix of it, without basic, external and OIDC auth, when that location is
+// defined in the Ingress.
+func addNoAuthLocations(server *ingress.Server, ingKey string, paths []string) {
+	for _, path := range paths {
+		var parent *ingress.Location
+		for _, loc := range server.Locations {
+			if strings.HasPrefix(path, loc.Path) && (parent == nil || len(loc.Path) > len(parent.Path)) {
+				parent = loc
+			}
+		}
+
+		if parent == nil || parent.Ingress == nil || k8s.MetaNamespaceKey(parent.Ingress) != ingKey {
+			log.V(3).Infof("The path %q of server %q is not configured by Ingress %q, ignoring it in no-auth-locations",
+				path, server.Hostname, ingKey)
+			continue
+		}
+
+		if parent.Path == path {
+			clearLocationAuth(parent)
+			continue
+		}
+
+		log.V(3).Infof("Adding location %q without authentication for server %q (Ingress %q)",
+			path, server.Hostname, ingKey)
+
+		loc := *parent
+		loc.Path = path
+		if loc.Rewrite.Target != "" {
+			// keep the rewrite of the requests matching the parent location
+			loc.Rewrite.Target = strings.TrimSuffix(loc.Rewrite.Target, "/") + strings.TrimPrefix(path, strings.TrimSuffix(parent.Path, "/"))
+		}
+		clearLocationAuth(&loc)
+
+		server.Locations = append(server.Locations, &loc)
+	}
+}
+
+// clearLocationAuth removes the authentication of a location
+func clearLocationAuth(loc *ingress.Location) {
+	loc.BasicDigestAuth = auth.Config{}
+	loc.ExternalAuth = authreq.Config{}
+	loc.AuthOIDC = authoidc.Config{}
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/auth"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/authreq"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/rewrite"
+)
+
+func TestAddNoAuthLocations(t *testing.T) {
+	web := newEffectiveConfigIngress("web", "/", "/api")
+	other := newEffectiveConfigIngress("other", "/other")
+
+	secured := func(path string) *ingress.Location {
+		return &ingress.Location{
+			Path:            path,
+			Ingress:         web,
+			Backend:         "default-web-80",
+			BasicDigestAuth: auth.Config{Type: "basic", Realm: "web"},
+			ExternalAuth:    authreq.Config{URL: "http://auth.default.svc/verify"},
+		}
+	}
+
+	root := secured("/")
+	api := secured("/api")
+	api.Rewrite = rewrite.Config{Target: "/"}
+	server := &ingress.Server{
+		Hostname: "foo.bar",
+		Locations: []*ingress.Location{
+			root,
+			api,
+			{Path: "/other", Ingress: other, Backend: "default-other-80"},
+		},
+	}
+
+	addNoAuthLocations(server, "default/web", []string{"/api", "/api/webhooks", "/healthz", "/other", "/other/healthz"})
+
+	locations := map[string]*ingress.Location{}
+	for _, loc := range server.Locations {
+		locations[loc.Path] = loc
+	}
+
+	if len(server.Locations) != 5 {
+		t.Fatalf("expected 5 locations but %v returned", len(server.Locations))
+	}
+
+	if api.BasicDigestAuth.Type != "" || api.ExternalAuth.URL != "" {
+		t.Errorf("expected the authentication of the location /api to be removed")
+	}
+	if root.BasicDigestAuth.Type == "" || root.ExternalAuth.URL == "" {
+		t.Errorf("expected the authentication of the location / to be kept")
+	}
+
+	healthz, ok := locations["/healthz"]
+	if !ok {
+		t.Fatalf("expected a location /healthz")
+	}
+	if healthz.Backend != "default-web-80" || healthz.BasicDigestAuth.Type != "" || healthz.ExternalAuth.URL != "" {
+		t.Errorf("expected the location /healthz to use the backend of / without authentication but returned %+v", healthz)
+	}
+
+	webhooks, ok := locations["/api/webhooks"]
+	if !ok {
+		t.Fatalf("expected a location /api/webhooks")
+	}
+	if webhooks.Rewrite.Target != "/webhooks" {
+		t.Errorf("expected the rewrite target /webhooks but %v returned", webhooks.Rewrite.Target)
+	}
+
+	if _, ok := locations["/other/healthz"]; ok {
+		t.Errorf("unexpected location for a path of another Ingress")
+	}
+}
@@ -0,0 +1,271 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/log"
+	"k8s.io/ingress-nginx/internal/task"
+)
+
+// maxRoutingOverrideDuration is the maximum time a routing override is active
+const maxRoutingOverrideDuration = 24 * time.Hour
+
+// RoutingOverride sends a percentage of the requests of a host to a Service
+// until it expires. Routing overrides are only part of the dynamic
+// configuration of NGINX and are not persisted.
+type RoutingOverride struct {
+	// Host is the hostname of the server
+	Host string `json:"host"`
+	// Service is the Service, in the form namespace/name, receiving the requests
+	Service string `json:"service"`
+	// Port is the number or the name of the port of the Service
+	Port intstr.IntOrString `json:"port"`
+	// Weight (0-100) of the requests sent to the Service
+	Weight int `json:"weight"`
+	// Expires is the time the override is removed
+	Expires time.Time `json:"expires"`
+}
+
+// routingOverrides contains the active routing overrides indexed by host
+type routingOverrides struct {
+	mu        sync.Mutex
+	overrides map[string]RoutingOverride
+	// changed indicates the overrides changed since the last dynamic configuration
+	changed int32
+}
+
+func newRoutingOverrides() *routingOverrides {
+	return &routingOverrides{
+		overrides: make(map[string]RoutingOverride),
+	}
+}
+
+// active returns the overrides not expired, sorted by host
+func (ro *routingOverrides) active(now time.Time) []RoutingOverride {
+	ro.mu.Lock()
+	defer ro.mu.Unlock()
+
+	overrides := []RoutingOverride{}
+	for host, o := range ro.overrides {
+		if !now.Before(o.Expires) {
+			delete(ro.overrides, host)
+			continue
+		}
+		overrides = append(overrides, o)
+	}
+
+	sort.SliceStable(overrides, func(i, j int) bool {
+		return overrides[i].Host < overrides[j].Host
+	})
+
+	return overrides
+}
+
+func (ro *routingOverrides) setChanged(changed bool) {
+	if changed {
+		atomic.StoreInt32(&ro.changed, 1)
+		return
+	}
+
+	atomic.StoreInt32(&ro.changed, 0)
+}
+
+func (ro *routingOverrides) isChanged() bool {
+	return atomic.LoadInt32(&ro.changed) == 1
+}
+
+// AddRoutingOverride installs a routing override for the host during the
+// given time, replacing the previous override of the host
+func (n *NGINXController) AddRoutingOverride(o RoutingOverride, d time.Duration) (*RoutingOverride, error) {
+	if o.Host == "" {
+		return nil, fmt.Errorf("the host of the routing override is required")
+	}
+
+	if len(n.HostIngresses(o.Host)) == 0 {
+		return nil, fmt.Errorf("no Ingress defines the host %q", o.Host)
+	}
+
+	if _, err := n.store.GetService(o.Service); err != nil {
+		return nil, fmt.Errorf("error obtaining Service %q: %v", o.Service, err)
+	}
+
+	if o.Weight < 0 || o.Weight > 100 {
+		return nil, fmt.Errorf("the weight of the routing override must be between 0 and 100")
+	}
+
+	if d <= 0 || d > maxRoutingOverrideDuration {
+		return nil, fmt.Errorf("the duration of the routing override must be greater than 0 and lower than %v", maxRoutingOverrideDuration)
+	}
+
+	o.Expires = time.Now().Add(d)
+
+	n.overrides.mu.Lock()
+	n.overrides.overrides[o.Host] = o
+	n.overrides.mu.Unlock()
+
+	log.Infof("Sending %v%% of the requests of host %q to Service %v until %v", o.Weight, o.Host, o.Service, o.Expires)
+
+	n.overrides.setChanged(true)
+	n.syncQueue.EnqueueTask(task.GetDummyObject("routing-override"))
+
+	time.AfterFunc(d, func() {
+		n.overrides.setChanged(true)
+		n.syncQueue.EnqueueTask(task.GetDummyObject("routing-override-expired"))
+	})
+
+	return &o, nil
+}
+
+// DeleteRoutingOverride removes the routing override of a host.
+// Returns false if the host has no routing override.
+func (n *NGINXController) DeleteRoutingOverride(host string) bool {
+	n.overrides.mu.Lock()
+	_, ok := n.overrides.overrides[host]
+	delete(n.overrides.overrides, host)
+	n.overrides.mu.Unlock()
+
+	if ok {
+		n.overrides.setChanged(true)
+		n.syncQueue.EnqueueTask(task.GetDummyObject("routing-override"))
+	}
+
+	return ok
+}
+
+// HostIngresses returns the Ingresses with a rule for a host, in the form
+// namespace/name. Changing the routing of the host requires permission on
+// all of them.
+func (n *NGINXController) HostIngresses(host string) []string {
+	var keys []string
+	for _, ing := range n.store.ListIngresses() {
+		for _, rule := range ing.Spec.Rules {
+			if rule.Host == host {
+				keys = append(keys, fmt.Sprintf("%v/%v", ing.Namespace, ing.Name))
+				break
+			}
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// RoutingOverrides returns the active routing overrides
+func (n *NGINXController) RoutingOverrides() []RoutingOverride {
+	return n.overrides.active(time.Now())
+}
+
+// applyRoutingOverrides returns a copy of the configuration with an alternative
+// backend for each active routing override, added to the backends of the
+// locations of the host. The alternative backend is restricted to the
+// requests of the host because these backends can also serve other hosts.
+func (n *NGINXController) applyRoutingOverrides(pcfg *ingress.Configuration) *ingress.Configuration {
+	overrides := n.overrides.active(time.Now())
+	if len(overrides) == 0 {
+		return pcfg
+	}
+
+	backends := make(map[string]*ingress.Backend, len(pcfg.Backends))
+	for _, b := range pcfg.Backends {
+		backends[b.Name] = b
+	}
+
+	cfg := *pcfg
+	var extra []*ingress.Backend
+	for _, o := range overrides {
+		name := fmt.Sprintf("override-%v-%v", o.Host, o.Service)
+		ob := n.overrideBackend(name, o)
+		if ob == nil {
+			continue
+		}
+
+		for _, server := range pcfg.Servers {
+			if server.Hostname != o.Host {
+				continue
+			}
+
+			for _, loc := range server.Locations {
+				b, ok := backends[loc.Backend]
+				if !ok {
+					continue
+				}
+
+				// copy the backend to avoid modifying the configuration
+				nb := *b
+				nb.AlternativeBackends = append([]string{name}, b.AlternativeBackends...)
+				backends[loc.Backend] = &nb
+			}
+		}
+
+		extra = append(extra, ob)
+	}
+
+	cfg.Backends = make([]*ingress.Backend, 0, len(pcfg.Backends)+len(extra))
+	for _, b := range pcfg.Backends {
+		cfg.Backends = append(cfg.Backends, backends[b.Name])
+	}
+	cfg.Backends = append(cfg.Backends, extra...)
+
+	return &cfg
+}
+
+// overrideBackend returns the backend of a routing override
+func (n *NGINXController) overrideBackend(name string, o RoutingOverride) *ingress.Backend {
+	svc, err := n.store.GetService(o.Service)
+	if err != nil {
+		log.Warningf("Error obtaining Service %q of the routing override of host %q: %v", o.Service, o.Host, err)
+		return nil
+	}
+
+	for _, sp := range svc.Spec.Ports {
+		if (o.Port.Type == intstr.Int && sp.Port != o.Port.IntVal) ||
+			(o.Port.Type == intstr.String && sp.Name != o.Port.StrVal) {
+			continue
+		}
+
+		endps := getEndpoints(svc, &sp, apiv1.ProtocolTCP, false, n.store.GetServiceEndpoints)
+		if len(endps) == 0 {
+			log.Warningf("Service %q of the routing override of host %q does not have any active Endpoint", o.Service, o.Host)
+			return nil
+		}
+
+		return &ingress.Backend{
+			Name:      name,
+			Service:   svc,
+			Port:      o.Port,
+			Endpoints: endps,
+			NoServer:  true,
+			TrafficShapingPolicy: ingress.TrafficShapingPolicy{
+				Weight: float64(o.Weight),
+				// the backends of the locations can be shared with other hosts
+				Host: o.Host,
+			},
+		}
+	}
+
+	log.Warningf("Service %q of the routing override of host %q does not have the port %v", o.Service, o.Host, o.Port.String())
+	return nil
+}
@@ -0,0 +1,221 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/controller/store"
+)
+
+type fakeServiceStore struct {
+	store.Storer
+	service   *apiv1.Service
+	endpoints *apiv1.Endpoints
+}
+
+func (fss fakeServiceStore) GetService(string) (*apiv1.Service, error) {
+	return fss.service, nil
+}
+
+func (fss fakeServiceStore) GetServiceEndpoints(string) (*apiv1.Endpoints, error) {
+	return fss.endpoints, nil
+}
+
+func TestApplyRoutingOverrides(t *testing.T) {
+	n := &NGINXController{
+		store: fakeServiceStore{
+			service: &apiv1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "experiment", Namespace: "default"},
+				Spec: apiv1.ServiceSpec{
+					Ports: []apiv1.ServicePort{{Name: "http", Port: 80, Protocol: apiv1.ProtocolTCP}},
+				},
+			},
+			endpoints: &apiv1.Endpoints{
+				Subsets: []apiv1.EndpointSubset{{
+					Addresses: []apiv1.EndpointAddress{{IP: "10.0.0.1"}},
+					Ports:     []apiv1.EndpointPort{{Name: "http", Port: 8080, Protocol: apiv1.ProtocolTCP}},
+				}},
+			},
+		},
+		overrides: newRoutingOverrides(),
+	}
+
+	pcfg := &ingress.Configuration{
+		Backends: []*ingress.Backend{
+			{Name: "default-app-80"},
+			{Name: "default-other-80"},
+		},
+		Servers: []*ingress.Server{
+			{
+				Hostname:  "foo.bar",
+				Locations: []*ingress.Location{{Path: "/", Backend: "default-app-80"}},
+			},
+			{
+				Hostname:  "other.bar",
+				Locations: []*ingress.Location{{Path: "/", Backend: "default-other-80"}},
+			},
+		},
+	}
+
+	if cfg := n.applyRoutingOverrides(pcfg); cfg != pcfg {
+		t.Errorf("expected the same configuration without routing overrides")
+	}
+
+	n.overrides.overrides["foo.bar"] = RoutingOverride{
+		Host:    "foo.bar",
+		Service: "default/experiment",
+		Port:    intstr.FromInt(80),
+		Weight:  5,
+		Expires: time.Now().Add(time.Minute),
+	}
+	n.overrides.overrides["expired.bar"] = RoutingOverride{
+		Host:    "expired.bar",
+		Service: "default/experiment",
+		Port:    intstr.FromString("http"),
+		Weight:  5,
+		Expires: time.Now().Add(-time.Minute),
+	}
+
+	cfg := n.applyRoutingOverrides(pcfg)
+	if len(cfg.Backends) != 3 {
+		t.Fatalf("expected 3 backends but got %v", len(cfg.Backends))
+	}
+
+	name := "override-foo.bar-default/experiment"
+	if alt := cfg.Backends[0].AlternativeBackends; len(alt) != 1 || alt[0] != name {
+		t.Errorf("expected the alternative backend %v but got %v", name, alt)
+	}
+
+	if alt := cfg.Backends[1].AlternativeBackends; len(alt) != 0 {
+		t.Errorf("expected no alternative backends but got %v", alt)
+	}
+
+	if len(pcfg.Backends[0].AlternativeBackends) != 0 {
+		t.Errorf("expected the original configuration to be unchanged")
+	}
+
+	ob := cfg.Backends[2]
+	if ob.Name != name || ob.TrafficShapingPolicy.Weight != 5 || !ob.NoServer || len(ob.Endpoints) != 1 {
+		t.Errorf("unexpected backend of the routing override %+v", ob)
+	}
+
+	if overrides := n.RoutingOverrides(); len(overrides) != 1 {
+		t.Errorf("expected the expired routing override to be removed but got %v", overrides)
+	}
+}
+
+func TestApplyRoutingOverridesSharedBackend(t *testing.T) {
+	n := &NGINXController{
+		store: fakeServiceStore{
+			service: &apiv1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "experiment", Namespace: "default"},
+				Spec: apiv1.ServiceSpec{
+					Ports: []apiv1.ServicePort{{Name: "http", Port: 80, Protocol: apiv1.ProtocolTCP}},
+				},
+			},
+			endpoints: &apiv1.Endpoints{
+				Subsets: []apiv1.EndpointSubset{{
+					Addresses: []apiv1.EndpointAddress{{IP: "10.0.0.1"}},
+					Ports:     []apiv1.EndpointPort{{Name: "http", Port: 8080, Protocol: apiv1.ProtocolTCP}},
+				}},
+			},
+		},
+		overrides: newRoutingOverrides(),
+	}
+
+	// both hosts use the same Service
+	pcfg := &ingress.Configuration{
+		Backends: []*ingress.Backend{{Name: "default-app-80"}},
+		Servers: []*ingress.Server{
+			{
+				Hostname:  "foo.bar",
+				Locations: []*ingress.Location{{Path: "/", Backend: "default-app-80"}},
+			},
+			{
+				Hostname:  "other.bar",
+				Locations: []*ingress.Location{{Path: "/", Backend: "default-app-80"}},
+			},
+		},
+	}
+
+	n.overrides.overrides["foo.bar"] = RoutingOverride{
+		Host:    "foo.bar",
+		Service: "default/experiment",
+		Port:    intstr.FromInt(80),
+		Weight:  5,
+		Expires: time.Now().Add(time.Minute),
+	}
+
+	cfg := n.applyRoutingOverrides(pcfg)
+	if len(cfg.Backends) != 2 {
+		t.Fatalf("expected 2 backends but got %v", len(cfg.Backends))
+	}
+
+	ob := cfg.Backends[1]
+	if ob.TrafficShapingPolicy.Host != "foo.bar" {
+		t.Errorf("expected the routing override to be restricted to the host foo.bar but got %q", ob.TrafficShapingPolicy.Host)
+	}
+
+	for _, server := range cfg.Servers {
+		if server.Locations[0].Backend != "default-app-80" {
+			t.Errorf("expected the locations of %v to keep their backend", server.Hostname)
+		}
+	}
+}
+
+func TestHostIngresses(t *testing.T) {
+	newIngress := func(namespace, name string, hosts ...string) *extensions.Ingress {
+		ing := &extensions.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		}
+		for _, host := range hosts {
+			ing.Spec.Rules = append(ing.Spec.Rules, extensions.IngressRule{Host: host})
+		}
+		return ing
+	}
+
+	n := &NGINXController{
+		store: fakeIngressStore{
+			ingresses: []*extensions.Ingress{
+				newIngress("shop", "web", "foo.bar", "www.foo.bar"),
+				newIngress("other", "api", "foo.bar"),
+				newIngress("shop", "admin", "admin.foo.bar"),
+			},
+		},
+	}
+
+	expected := []string{"other/api", "shop/web"}
+	if keys := n.HostIngresses("foo.bar"); !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expected the Ingresses %v but got %v", expected, keys)
+	}
+
+	if keys := n.HostIngresses("unknown.bar"); len(keys) != 0 {
+		t.Errorf("expected no Ingresses for an unknown host but got %v", keys)
+	}
+
+	if _, err := n.AddRoutingOverride(RoutingOverride{Host: "unknown.bar"}, time.Minute); err == nil {
+		t.Errorf("expected an error adding a routing override for a host without Ingresses")
+	}
+}
@@ -24,9 +24,10 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/golang/glog"
 	ps "github.com/mitchellh/go-ps"
 	"github.com/ncabatoff/process-exporter/proc"
+
+	"k8s.io/ingress-nginx/internal/log"
 )
 
 // IsRespawnIfRequired checks if error type is exec.ExitError or not
@@ -37,7 +38,7 @@ func IsRespawnIfRequired(err error) bool {
 	}
 
 	waitStatus := exitError.Sys().(syscall.WaitStatus)
-	glog.Warningf(`
+	log.Warningf(`
 -------------------------------------------------------------------------------
 NGINX master process died (%v): %v
 -------------------------------------------------------------------------------
@@ -58,7 +59,7 @@ func WaitUntilPortIsAvailable(port int) {
 		// kill nginx worker processes
 		fs, err := proc.NewFS("/proc")
 		if err != nil {
-			glog.Errorf("unexpected error reading /proc information: %v", err)
+			log.Errorf("unexpected error reading /proc information: %v", err)
 			continue
 		}
 
@@ -66,14 +67,14 @@ func WaitUntilPortIsAvailable(port int) {
 		for _, p := range procs {
 			pn, err := p.Comm()
 			if err != nil {
-				glog.Errorf("unexpected error obtaining process information: %v", err)
+				log.Errorf("unexpected error obtaining process information: %v", err)
 				continue
 			}
 
 			if pn == "nginx" {
 				osp, err := os.FindProcess(p.PID)
 				if err != nil {
-					glog.Errorf("unexpected error obtaining process information: %v", err)
+					log.Errorf("unexpected error obtaining process information: %v", err)
 					continue
 				}
 				osp.Signal(syscall.SIGQUIT)
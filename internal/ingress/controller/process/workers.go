@@ -0,0 +1,97 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/procfs"
+)
+
+// workerCmdline is the prefix of the command line of the NGINX worker
+// processes. Workers of a previous configuration append "is shutting down"
+var workerCmdline = "nginx: worker process"
+
+// Worker contains the state of a NGINX worker process
+type Worker struct {
+	PID int
+	// ShuttingDown indicates the worker belongs to a previous configuration
+	// and waits for its connections to finish
+	ShuttingDown bool
+	CPUSeconds   float64
+	// ResidentMemory is the resident memory in bytes
+	ResidentMemory int
+	// OpenSockets is the number of sockets open, including the listening sockets
+	OpenSockets int
+	StartTime   time.Time
+}
+
+// ListWorkers returns the state of the NGINX worker processes read from
+// /proc. Processes that terminate while they are read are ignored.
+func ListWorkers() ([]Worker, error) {
+	fs, err := procfs.NewFS("/proc")
+	if err != nil {
+		return nil, err
+	}
+
+	procs, err := fs.AllProcs()
+	if err != nil {
+		return nil, err
+	}
+
+	var workers []Worker
+	for _, proc := range procs {
+		cmdline, err := proc.CmdLine()
+		if err != nil || len(cmdline) == 0 || !strings.HasPrefix(cmdline[0], workerCmdline) {
+			continue
+		}
+
+		stat, err := proc.NewStat()
+		if err != nil {
+			continue
+		}
+
+		startTime, err := stat.StartTime()
+		if err != nil {
+			continue
+		}
+
+		targets, err := proc.FileDescriptorTargets()
+		if err != nil {
+			continue
+		}
+
+		sockets := 0
+		for _, target := range targets {
+			if strings.HasPrefix(target, "socket:") {
+				sockets++
+			}
+		}
+
+		workers = append(workers, Worker{
+			PID:            proc.PID,
+			ShuttingDown:   strings.HasSuffix(cmdline[0], "is shutting down"),
+			CPUSeconds:     stat.CPUTime(),
+			ResidentMemory: stat.ResidentMemory(),
+			OpenSockets:    sockets,
+			StartTime:      time.Unix(0, int64(startTime*float64(time.Second))),
+		})
+	}
+
+	return workers, nil
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestListWorkers(t *testing.T) {
+	active := &exec.Cmd{Path: "/bin/sleep", Args: []string{"nginx: worker process", "1000000"}}
+	shuttingDown := &exec.Cmd{Path: "/bin/sleep", Args: []string{"nginx: worker process is shutting down", "1000000"}}
+
+	for _, cmd := range []*exec.Cmd{active, shuttingDown} {
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("unexpected error creating dummy process: %v", err)
+		}
+		go cmd.Wait()
+		defer cmd.Process.Kill()
+	}
+
+	workers, err := ListWorkers()
+	if err != nil {
+		t.Fatalf("unexpected error reading the worker processes: %v", err)
+	}
+
+	expected := map[int]bool{
+		active.Process.Pid:       false,
+		shuttingDown.Process.Pid: true,
+	}
+	for _, w := range workers {
+		sd, ok := expected[w.PID]
+		if !ok {
+			continue
+		}
+
+		if w.ShuttingDown != sd {
+			t.Errorf("expected worker %v shutting down to be %v but returned %v", w.PID, sd, w.ShuttingDown)
+		}
+		if time.Since(w.StartTime) > time.Minute || time.Since(w.StartTime) < -time.Minute {
+			t.Errorf("unexpected start time %v of worker %v", w.StartTime, w.PID)
+		}
+		delete(expected, w.PID)
+	}
+
+	if len(expected) != 0 {
+		t.Errorf("expected the workers %v to be returned", expected)
+	}
+}
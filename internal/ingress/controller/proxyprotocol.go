@@ -0,0 +1,259 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// proxyProtocolV1 is the text version of the PROXY protocol
+	proxyProtocolV1 = "v1"
+	// proxyProtocolV2 is the binary version of the PROXY protocol
+	proxyProtocolV2 = "v2"
+
+	// proxyProtocolV1MaxLength is the maximum length of a v1 header
+	proxyProtocolV1MaxLength = 107
+)
+
+var (
+	proxyProtocolV1Prefix    = []byte("PROXY ")
+	proxyProtocolV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+)
+
+// proxyProtocolConn is a connection starting with a PROXY protocol header,
+// whose addresses replace the ones of the connection
+type proxyProtocolConn struct {
+	net.Conn
+	r   *bufio.Reader
+	src net.Addr
+	dst net.Addr
+}
+
+// newProxyProtocolConn reads the PROXY protocol header, version 1 or 2, of a
+// connection. Connections without a header are returned unchanged, like
+// the ones without data after the timeout (zero means no timeout).
+func newProxyProtocolConn(conn net.Conn, timeout time.Duration) (net.Conn, error) {
+	if timeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(timeout))
+		defer conn.SetReadDeadline(time.Time{})
+	}
+
+	r := bufio.NewReader(conn)
+	src, dst, err := readProxyProtocolHeader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &proxyProtocolConn{Conn: conn, r: r}
+	if src != nil && dst != nil {
+		c.src = src
+		c.dst = dst
+	}
+
+	return c, nil
+}
+
+func (c *proxyProtocolConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// RemoteAddr returns the source address of the PROXY protocol header
+func (c *proxyProtocolConn) RemoteAddr() net.Addr {
+	if c.src != nil {
+		return c.src
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// LocalAddr returns the destination address of the PROXY protocol header
+func (c *proxyProtocolConn) LocalAddr() net.Addr {
+	if c.dst != nil {
+		return c.dst
+	}
+	return c.Conn.LocalAddr()
+}
+
+// readProxyProtocolHeader reads the PROXY protocol header at the start of r
+// and returns its addresses. The addresses are nil when there is no header or
+// it does not have them, like the v1 UNKNOWN or the v2 LOCAL headers.
+func readProxyProtocolHeader(r *bufio.Reader) (*net.TCPAddr, *net.TCPAddr, error) {
+	start, _ := r.Peek(len(proxyProtocolV2Signature))
+
+	switch {
+	case bytes.Equal(start, proxyProtocolV2Signature):
+		return readProxyProtocolV2(r)
+	case bytes.HasPrefix(start, proxyProtocolV1Prefix):
+		return readProxyProtocolV1(r)
+	default:
+		return nil, nil, nil
+	}
+}
+
+// readProxyProtocolV1 reads a header like PROXY TCP4 10.0.0.1 10.0.0.2 51000 443
+func readProxyProtocolV1(r *bufio.Reader) (*net.TCPAddr, *net.TCPAddr, error) {
+	var line []byte
+	for !bytes.HasSuffix(line, []byte("\r\n")) {
+		if len(line) >= proxyProtocolV1MaxLength {
+			return nil, nil, fmt.Errorf("PROXY protocol v1 header longer than %v bytes", proxyProtocolV1MaxLength)
+		}
+
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, nil, err
+		}
+		line = append(line, b)
+	}
+
+	parts := strings.Split(strings.TrimSuffix(string(line), "\r\n"), " ")
+	if len(parts) >= 2 && parts[1] == "UNKNOWN" {
+		return nil, nil, nil
+	}
+
+	if len(parts) != 6 || (parts[1] != "TCP4" && parts[1] != "TCP6") {
+		return nil, nil, fmt.Errorf("invalid PROXY protocol v1 header %q", line)
+	}
+
+	src, err := parseProxyProtocolV1Address(parts[2], parts[4])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dst, err := parseProxyProtocolV1Address(parts[3], parts[5])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return src, dst, nil
+}
+
+func parseProxyProtocolV1Address(address, port string) (*net.TCPAddr, error) {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address %q in PROXY protocol v1 header", address)
+	}
+
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port %q in PROXY protocol v1 header", port)
+	}
+
+	return &net.TCPAddr{IP: ip, Port: int(p)}, nil
+}
+
+// readProxyProtocolV2 reads a binary header, a signature followed by the
+// version and command, the address family, the length of the addresses
+// and the addresses
+func readProxyProtocolV2(r *bufio.Reader) (*net.TCPAddr, *net.TCPAddr, error) {
+	header := make([]byte, len(proxyProtocolV2Signature)+4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, nil, err
+	}
+
+	versionCommand := header[12]
+	family := header[13]
+	length := binary.BigEndian.Uint16(header[14:])
+
+	addresses := make([]byte, length)
+	if _, err := io.ReadFull(r, addresses); err != nil {
+		return nil, nil, err
+	}
+
+	if versionCommand>>4 != 2 {
+		return nil, nil, fmt.Errorf("invalid PROXY protocol version %v", versionCommand>>4)
+	}
+
+	switch versionCommand & 0x0F {
+	case 0x0:
+		// LOCAL, like health checks of the proxy
+		return nil, nil, nil
+	case 0x1:
+		// PROXY
+	default:
+		return nil, nil, fmt.Errorf("invalid PROXY protocol v2 command %v", versionCommand&0x0F)
+	}
+
+	var size int
+	switch family >> 4 {
+	case 0x1:
+		size = net.IPv4len
+	case 0x2:
+		size = net.IPv6len
+	default:
+		// UNSPEC and Unix sockets
+		return nil, nil, nil
+	}
+
+	if len(addresses) < 2*size+4 {
+		return nil, nil, fmt.Errorf("PROXY protocol v2 addresses too short (%v bytes)", len(addresses))
+	}
+
+	src := &net.TCPAddr{
+		IP:   net.IP(addresses[:size]),
+		Port: int(binary.BigEndian.Uint16(addresses[2*size:])),
+	}
+	dst := &net.TCPAddr{
+		IP:   net.IP(addresses[size : 2*size]),
+		Port: int(binary.BigEndian.Uint16(addresses[2*size+2:])),
+	}
+
+	return src, dst, nil
+}
+
+// proxyProtocolHeader returns the PROXY protocol header, proxyProtocolV1 or
+// proxyProtocolV2, of a connection from src to dst
+func proxyProtocolHeader(version string, src, dst *net.TCPAddr) []byte {
+	srcIP, dstIP := src.IP.To4(), dst.IP.To4()
+	ipv4 := srcIP != nil && dstIP != nil
+	if !ipv4 {
+		srcIP, dstIP = src.IP.To16(), dst.IP.To16()
+	}
+
+	if version != proxyProtocolV2 {
+		protocol := "TCP4"
+		if !ipv4 {
+			protocol = "TCP6"
+		}
+		return []byte(fmt.Sprintf("PROXY %s %s %s %d %d\r\n", protocol, srcIP, dstIP, src.Port, dst.Port))
+	}
+
+	// version 2 and PROXY command, TCP over IPv4 or IPv6
+	family := byte(0x11)
+	if !ipv4 {
+		family = 0x21
+	}
+
+	var buf bytes.Buffer
+	buf.Write(proxyProtocolV2Signature)
+	buf.WriteByte(0x21)
+	buf.WriteByte(family)
+	binary.Write(&buf, binary.BigEndian, uint16(2*len(srcIP)+4))
+	buf.Write(srcIP)
+	buf.Write(dstIP)
+	binary.Write(&buf, binary.BigEndian, uint16(src.Port))
+	binary.Write(&buf, binary.BigEndian, uint16(dst.Port))
+
+	return buf.Bytes()
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestReadProxyProtocolHeader(t *testing.T) {
+	src4 := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 51000}
+	dst4 := &net.TCPAddr{IP: net.ParseIP("10.0.0.2"), Port: 443}
+	src6 := &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 51000}
+	dst6 := &net.TCPAddr{IP: net.ParseIP("2001:db8::2"), Port: 443}
+
+	local := append(append([]byte{}, proxyProtocolV2Signature...), 0x20, 0x00, 0x00, 0x00)
+
+	testCases := []struct {
+		name   string
+		data   []byte
+		src    *net.TCPAddr
+		dst    *net.TCPAddr
+		err    bool
+		remain string
+	}{
+		{"v1 IPv4", []byte("PROXY TCP4 10.0.0.1 10.0.0.2 51000 443\r\nhello"), src4, dst4, false, "hello"},
+		{"v1 IPv6", []byte("PROXY TCP6 2001:db8::1 2001:db8::2 51000 443\r\nhello"), src6, dst6, false, "hello"},
+		{"v1 unknown", []byte("PROXY UNKNOWN\r\nhello"), nil, nil, false, "hello"},
+		{"v1 invalid port", []byte("PROXY TCP4 10.0.0.1 10.0.0.2 51000 65536\r\nhello"), nil, nil, true, ""},
+		{"v1 too long", []byte("PROXY " + strings.Repeat("A", 200) + "\r\n"), nil, nil, true, ""},
+		{"v2 IPv4", append(proxyProtocolHeader(proxyProtocolV2, src4, dst4), "hello"...), src4, dst4, false, "hello"},
+		{"v2 IPv6", append(proxyProtocolHeader(proxyProtocolV2, src6, dst6), "hello"...), src6, dst6, false, "hello"},
+		{"v2 local", append(local, "hello"...), nil, nil, false, "hello"},
+		{"without header", []byte("hello"), nil, nil, false, "hello"},
+	}
+
+	for _, tc := range testCases {
+		r := bufio.NewReader(bytes.NewReader(tc.data))
+		src, dst, err := readProxyProtocolHeader(r)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%v: expected an error", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tc.name, err)
+			continue
+		}
+
+		if (tc.src == nil) != (src == nil) || (src != nil && (!src.IP.Equal(tc.src.IP) || src.Port != tc.src.Port)) {
+			t.Errorf("%v: expected source address %v but returned %v", tc.name, tc.src, src)
+		}
+		if (tc.dst == nil) != (dst == nil) || (dst != nil && (!dst.IP.Equal(tc.dst.IP) || dst.Port != tc.dst.Port)) {
+			t.Errorf("%v: expected destination address %v but returned %v", tc.name, tc.dst, dst)
+		}
+
+		remain, _ := ioutil.ReadAll(r)
+		if string(remain) != tc.remain {
+			t.Errorf("%v: expected %q after the header but %q was returned", tc.name, tc.remain, remain)
+		}
+	}
+}
+
+func TestProxyProtocolHeaderV1(t *testing.T) {
+	src := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 51000}
+	dst := &net.TCPAddr{IP: net.ParseIP("10.0.0.2"), Port: 443}
+
+	expected := "PROXY TCP4 10.0.0.1 10.0.0.2 51000 443\r\n"
+	if header := string(proxyProtocolHeader(proxyProtocolV1, src, dst)); header != expected {
+		t.Errorf("expected %q but returned %q", expected, header)
+	}
+}
+
+func TestProxyProtocolConn(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go func() {
+		src := &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 51000}
+		dst := &net.TCPAddr{IP: net.ParseIP("2001:db8::2"), Port: 443}
+		client.Write(append(proxyProtocolHeader(proxyProtocolV2, src, dst), "hello"...))
+		client.Close()
+	}()
+
+	conn, err := newProxyProtocolConn(server, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	if addr := conn.RemoteAddr().String(); addr != "[2001:db8::1]:51000" {
+		t.Errorf("expected the source address of the header but returned %v", addr)
+	}
+	if addr := conn.LocalAddr().String(); addr != "[2001:db8::2]:443" {
+		t.Errorf("expected the destination address of the header but returned %v", addr)
+	}
+
+	data, _ := ioutil.ReadAll(conn)
+	if string(data) != "hello" {
+		t.Errorf("expected the data after the header but %q was returned", data)
+	}
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/ingress-nginx/internal/log"
+	"k8s.io/ingress-nginx/internal/net/proxy"
+	"k8s.io/ingress-nginx/internal/task"
+)
+
+// maxIPRangesSize is the maximum size of the IP ranges published by a CDN
+const maxIPRangesSize = 1 << 20
+
+// ipRangesClient fetches the IP ranges published by CDNs
+var ipRangesClient = proxy.NewClient(30 * time.Second)
+
+// realIPRanges contains the IP ranges fetched from the URLs of
+// RealIPRangesURLs, trusted to send the client address
+type realIPRanges struct {
+	mu     sync.Mutex
+	ranges []string
+}
+
+// get returns the ranges, nil when r is nil
+func (r *realIPRanges) get() []string {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.ranges
+}
+
+// set replaces the ranges and returns true if they changed
+func (r *realIPRanges) set(ranges []string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if reflect.DeepEqual(r.ranges, ranges) {
+		return false
+	}
+
+	r.ranges = ranges
+	return true
+}
+
+// syncRealIPRanges fetches the IP ranges published by the CDNs and syncs
+// the configuration when they change. The previous ranges are kept when
+// any of the URLs cannot be fetched, to not distrust the addresses of a
+// CDN because of a temporary error.
+func (n *NGINXController) syncRealIPRanges() {
+	ranges := sets.NewString()
+	for _, url := range n.cfg.RealIPRangesURLs {
+		r, err := fetchIPRanges(url)
+		if err != nil {
+			log.Warningf("Error fetching the IP ranges of %v, keeping the previous ranges: %v", url, err)
+			return
+		}
+
+		ranges.Insert(r...)
+	}
+
+	if !n.realIPRanges.set(ranges.List()) {
+		return
+	}
+
+	log.Infof("IP ranges trusted to send the client address changed (%v ranges)", ranges.Len())
+	n.syncQueue.EnqueueTask(task.GetDummyObject("real-ip-ranges"))
+}
+
+// fetchIPRanges returns the IP ranges published in a URL. The fragment
+// of the URL is the key of the ranges in a JSON document.
+func fetchIPRanges(rangesURL string) ([]string, error) {
+	u, err := url.Parse(rangesURL)
+	if err != nil {
+		return nil, err
+	}
+
+	key := u.Fragment
+	u.Fragment = ""
+
+	resp, err := ipRangesClient.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %v", resp.StatusCode)
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxIPRangesSize))
+	if err != nil {
+		return nil, err
+	}
+
+	ranges, err := parseIPRanges(data, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("no IP ranges found")
+	}
+
+	return ranges, nil
+}
+
+// parseIPRanges returns the IP addresses and networks of a text, separated
+// by spaces, commas or new lines. When key is not empty the data is a JSON
+// object and only the list of strings in key is used: documents like the
+// ranges of a cloud provider also contain the ranges of services that can
+// be used by anyone, which must not be trusted to send the client address.
+func parseIPRanges(data []byte, key string) ([]string, error) {
+	var tokens []string
+
+	if key != "" {
+		doc := map[string]json.RawMessage{}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("invalid JSON document: %v", err)
+		}
+
+		value, ok := doc[key]
+		if !ok {
+			return nil, fmt.Errorf("key %q not found in the JSON document", key)
+		}
+
+		if err := json.Unmarshal(value, &tokens); err != nil {
+			return nil, fmt.Errorf("the value of key %q is not a list of strings: %v", key, err)
+		}
+	} else {
+		text := strings.TrimSpace(string(data))
+		if strings.HasPrefix(text, "{") || strings.HasPrefix(text, "[") {
+			return nil, fmt.Errorf("JSON documents require the key of the ranges in the fragment of the URL")
+		}
+
+		tokens = strings.FieldsFunc(text, func(r rune) bool {
+			return unicode.IsSpace(r) || r == ','
+		})
+	}
+
+	ranges := sets.NewString()
+	for _, token := range tokens {
+		token = strings.TrimSpace(token)
+		if _, ipnet, err := net.ParseCIDR(token); err == nil {
+			ranges.Insert(ipnet.String())
+		} else if ip := net.ParseIP(token); ip != nil {
+			ranges.Insert(ip.String())
+		}
+	}
+
+	return ranges.List(), nil
+}
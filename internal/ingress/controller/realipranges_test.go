@@ -0,0 +1,114 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"k8s.io/ingress-nginx/internal/task"
+)
+
+func TestParseIPRanges(t *testing.T) {
+	cloudfront := `{"CLOUDFRONT_GLOBAL_IP_LIST": ["120.52.22.96/27", "205.251.249.0/24"], "CLOUDFRONT_REGIONAL_EDGE_IP_LIST": ["13.113.196.64/26"]}`
+
+	testCases := []struct {
+		name     string
+		data     string
+		key      string
+		expected []string
+		err      bool
+	}{
+		{"text", "173.245.48.0/20\n103.21.244.0/22\n\n2400:cb00::/32\n", "", []string{"103.21.244.0/22", "173.245.48.0/20", "2400:cb00::/32"}, false},
+		{"commas", "10.0.0.1, 10.0.1.0/24,invalid", "", []string{"10.0.0.1", "10.0.1.0/24"}, false},
+		{"json key", cloudfront, "CLOUDFRONT_GLOBAL_IP_LIST", []string{"120.52.22.96/27", "205.251.249.0/24"}, false},
+		{"json without key", cloudfront, "", nil, true},
+		{"json unknown key", cloudfront, "EC2", nil, true},
+		{"json key without list", `{"prefixes": [{"ip_prefix": "3.5.140.0/22", "service": "EC2"}]}`, "prefixes", nil, true},
+		{"html", "<html>not found</html>", "", []string{}, false},
+	}
+
+	for _, tc := range testCases {
+		ranges, err := parseIPRanges([]byte(tc.data), tc.key)
+		if (err != nil) != tc.err {
+			t.Errorf("%v: unexpected error %v", tc.name, err)
+			continue
+		}
+		if !tc.err && !reflect.DeepEqual(ranges, tc.expected) {
+			t.Errorf("%v: expected %v but returned %v", tc.name, tc.expected, ranges)
+		}
+	}
+}
+
+func TestSyncRealIPRanges(t *testing.T) {
+	ranges := "173.245.48.0/20"
+	available := true
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !available {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, ranges)
+	}))
+	defer ts.Close()
+
+	n := &NGINXController{
+		cfg:          &Configuration{RealIPRangesURLs: []string{ts.URL}},
+		realIPRanges: &realIPRanges{},
+	}
+	n.syncQueue = task.NewTaskQueue(func(interface{}) error { return nil })
+
+	n.syncRealIPRanges()
+	if expected := []string{"173.245.48.0/20"}; !reflect.DeepEqual(n.realIPRanges.get(), expected) {
+		t.Errorf("expected %v but returned %v", expected, n.realIPRanges.get())
+	}
+
+	available = false
+	n.syncRealIPRanges()
+	if expected := []string{"173.245.48.0/20"}; !reflect.DeepEqual(n.realIPRanges.get(), expected) {
+		t.Errorf("expected the previous ranges to be kept after an error but returned %v", n.realIPRanges.get())
+	}
+
+	available = true
+	ranges = "173.245.48.0/20\n2400:cb00::/32"
+	n.syncRealIPRanges()
+	if expected := []string{"173.245.48.0/20", "2400:cb00::/32"}; !reflect.DeepEqual(n.realIPRanges.get(), expected) {
+		t.Errorf("expected %v but returned %v", expected, n.realIPRanges.get())
+	}
+}
+
+func TestFetchIPRangesKey(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"CLOUDFRONT_GLOBAL_IP_LIST": ["120.52.22.96/27"], "EC2": ["3.5.140.0/22"]}`)
+	}))
+	defer ts.Close()
+
+	ranges, err := fetchIPRanges(ts.URL + "/ips#CLOUDFRONT_GLOBAL_IP_LIST")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"120.52.22.96/27"}; !reflect.DeepEqual(ranges, expected) {
+		t.Errorf("expected %v but returned %v", expected, ranges)
+	}
+
+	if _, err := fetchIPRanges(ts.URL + "/ips"); err == nil {
+		t.Errorf("expected an error fetching a JSON document without key")
+	}
+}
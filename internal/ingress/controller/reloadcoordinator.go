@@ -0,0 +1,195 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	coordination "k8s.io/api/coordination/v1beta1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coordinationclient "k8s.io/client-go/kubernetes/typed/coordination/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/log"
+)
+
+const (
+	// reloadSlotDuration is the time after which a slot not released,
+	// for instance because the instance holding it was killed, is free
+	reloadSlotDuration = 30 * time.Second
+	// reloadSlotHold is the time a slot is kept after the reload, so the
+	// new NGINX workers are started before other instances reload
+	reloadSlotHold = 5 * time.Second
+	// reloadSlotRetryPeriod is the time between attempts to acquire a slot
+	reloadSlotRetryPeriod = 2 * time.Second
+)
+
+// reloadCoordinator limits the number of instances of the ingress
+// controller reloading NGINX at the same time, like the pods of a
+// DaemonSet receiving the same change, using a Lease object per slot.
+// Errors talking to the API server never block a reload.
+type reloadCoordinator struct {
+	client    coordinationclient.LeasesGetter
+	namespace string
+	prefix    string
+	identity  string
+	slots     int
+	timeout   time.Duration
+
+	now func() time.Time
+}
+
+func newReloadCoordinator(client coordinationclient.LeasesGetter, namespace, prefix, identity string, slots int, timeout time.Duration) *reloadCoordinator {
+	return &reloadCoordinator{
+		client:    client,
+		namespace: namespace,
+		prefix:    prefix,
+		identity:  identity,
+		slots:     slots,
+		timeout:   timeout,
+		now:       time.Now,
+	}
+}
+
+// acquire waits until a reload slot is available or the timeout expires
+// and returns the function that releases the slot after the reload
+func (rc *reloadCoordinator) acquire() func() {
+	deadline := rc.now().Add(rc.timeout)
+
+	for {
+		slot, err := rc.tryAcquire()
+		if err != nil {
+			log.Warningf("Error acquiring a reload slot, reloading without coordination: %v", err)
+			return func() {}
+		}
+
+		if slot != "" {
+			log.V(2).Infof("Acquired reload slot %q", slot)
+			return func() {
+				time.AfterFunc(reloadSlotHold, func() { rc.release(slot) })
+			}
+		}
+
+		if !rc.now().Before(deadline) {
+			log.Warningf("Timeout waiting for a reload slot after %v, reloading without coordination", rc.timeout)
+			return func() {}
+		}
+
+		log.V(2).Infof("All the reload slots are in use, waiting %v", reloadSlotRetryPeriod)
+		time.Sleep(reloadSlotRetryPeriod)
+	}
+}
+
+// tryAcquire tries to acquire any of the slots, starting by a random one
+// to spread the instances. Returns the name of the acquired slot or an
+// empty string if all the slots are in use.
+func (rc *reloadCoordinator) tryAcquire() (string, error) {
+	first := rand.Intn(rc.slots)
+	for i := 0; i < rc.slots; i++ {
+		name := fmt.Sprintf("%v-%v", rc.prefix, (first+i)%rc.slots)
+
+		acquired, err := rc.tryAcquireSlot(name)
+		if err != nil {
+			return "", err
+		}
+
+		if acquired {
+			return name, nil
+		}
+	}
+
+	return "", nil
+}
+
+// tryAcquireSlot takes the Lease of a slot if it does not exist, is not
+// held or the holder did not renew it before it expired. Conflicts with
+// other instances updating the same Lease are not errors.
+func (rc *reloadCoordinator) tryAcquireSlot(name string) (bool, error) {
+	now := metav1.NewMicroTime(rc.now())
+	duration := int32(reloadSlotDuration.Seconds())
+	spec := coordination.LeaseSpec{
+		HolderIdentity:       &rc.identity,
+		LeaseDurationSeconds: &duration,
+		AcquireTime:          &now,
+		RenewTime:            &now,
+	}
+
+	lease, err := rc.client.Leases(rc.namespace).Get(name, metav1.GetOptions{})
+	if k8sErrors.IsNotFound(err) {
+		_, err = rc.client.Leases(rc.namespace).Create(&coordination.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: rc.namespace},
+			Spec:       spec,
+		})
+		if k8sErrors.IsAlreadyExists(err) {
+			return false, nil
+		}
+
+		return err == nil, err
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if isReloadSlotHeld(lease, rc.identity, now.Time) {
+		return false, nil
+	}
+
+	lease.Spec = spec
+	_, err = rc.client.Leases(rc.namespace).Update(lease)
+	if k8sErrors.IsConflict(err) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// release frees a slot if it is still held by this instance
+func (rc *reloadCoordinator) release(name string) {
+	lease, err := rc.client.Leases(rc.namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		log.Warningf("Error releasing reload slot %q: %v", name, err)
+		return
+	}
+
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != rc.identity {
+		return
+	}
+
+	lease.Spec.HolderIdentity = nil
+	_, err = rc.client.Leases(rc.namespace).Update(lease)
+	if err != nil {
+		log.Warningf("Error releasing reload slot %q: %v", name, err)
+	}
+}
+
+// isReloadSlotHeld returns true if the Lease of a slot is held by other
+// instance and did not expire
+func isReloadSlotHeld(lease *coordination.Lease, identity string, now time.Time) bool {
+	spec := lease.Spec
+	if spec.HolderIdentity == nil || *spec.HolderIdentity == "" || *spec.HolderIdentity == identity {
+		return false
+	}
+
+	if spec.RenewTime == nil || spec.LeaseDurationSeconds == nil {
+		return false
+	}
+
+	expires := spec.RenewTime.Add(time.Duration(*spec.LeaseDurationSeconds) * time.Second)
+	return now.Before(expires)
+}
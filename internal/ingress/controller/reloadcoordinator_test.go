@@ -0,0 +1,98 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestReloadCoordinator(t *testing.T) {
+	client := testclient.NewSimpleClientset().CoordinationV1beta1()
+
+	a := newReloadCoordinator(client, "default", "leader-reload", "pod-a", 1, time.Minute)
+	b := newReloadCoordinator(client, "default", "leader-reload", "pod-b", 1, 0)
+
+	slot, err := a.tryAcquire()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if slot != "leader-reload-0" {
+		t.Fatalf("expected slot leader-reload-0 but %q returned", slot)
+	}
+
+	slot, err = b.tryAcquire()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if slot != "" {
+		t.Errorf("expected no slot available but %q returned", slot)
+	}
+
+	// the holder can acquire the slot again
+	slot, _ = a.tryAcquire()
+	if slot != "leader-reload-0" {
+		t.Errorf("expected the holder to acquire the slot again but %q returned", slot)
+	}
+
+	// acquire does not block after the timeout
+	b.acquire()()
+
+	a.release("leader-reload-0")
+
+	slot, _ = b.tryAcquire()
+	if slot != "leader-reload-0" {
+		t.Errorf("expected slot leader-reload-0 after the release but %q returned", slot)
+	}
+
+	// the slot expires if the holder does not release it
+	a.now = func() time.Time { return time.Now().Add(reloadSlotDuration + time.Second) }
+	slot, _ = a.tryAcquire()
+	if slot != "leader-reload-0" {
+		t.Errorf("expected an expired slot to be acquired but %q returned", slot)
+	}
+
+	// releasing a slot held by other instance does nothing
+	b.release("leader-reload-0")
+	slot, _ = b.tryAcquire()
+	if slot != "" {
+		t.Errorf("expected no slot available but %q returned", slot)
+	}
+}
+
+func TestReloadCoordinatorSlots(t *testing.T) {
+	client := testclient.NewSimpleClientset().CoordinationV1beta1()
+
+	acquired := map[string]bool{}
+	for _, identity := range []string{"pod-a", "pod-b", "pod-c"} {
+		rc := newReloadCoordinator(client, "default", "leader-reload", identity, 2, 0)
+		slot, err := rc.tryAcquire()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if slot != "" {
+			acquired[slot] = true
+		}
+	}
+
+	if len(acquired) != 2 || !acquired["leader-reload-0"] || !acquired["leader-reload-1"] {
+		t.Errorf("expected the two slots to be acquired but returned %v", acquired)
+	}
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/annotations"
+	"k8s.io/ingress-nginx/internal/k8s"
+)
+
+// syncWorkers is the maximum number of goroutines resolving the
+// annotations and the endpoints of the Ingresses in each sync
+var syncWorkers = runtime.NumCPU()
+
+// parallelize calls work with the indexes from 0 to count-1 in up to
+// workers goroutines and waits until all of them finish
+func parallelize(workers, count int, work func(i int)) {
+	if workers > count {
+		workers = count
+	}
+
+	indexes := make(chan int, count)
+	for i := 0; i < count; i++ {
+		indexes <- i
+	}
+	close(indexes)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				work(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// endpointsKey identifies the endpoints of a Service port read for the
+// backends of the Ingresses
+type endpointsKey struct {
+	svcKey          string
+	backendPort     string
+	backendProtocol string
+	includeNotReady bool
+}
+
+type resolvedAnnotations struct {
+	anns *annotations.Ingress
+	err  error
+}
+
+type resolvedEndpoints struct {
+	endpoints []ingress.Endpoint
+	err       error
+}
+
+// syncResolution contains the annotations and the endpoints of the
+// Ingresses of a sync, resolved concurrently before building the
+// configuration, which reads them several times
+type syncResolution struct {
+	n           *NGINXController
+	annotations map[string]resolvedAnnotations
+	endpoints   map[endpointsKey]resolvedEndpoints
+}
+
+// resolveIngresses reads the annotations of the Ingresses and then the
+// endpoints of their backends, each one once, in syncWorkers goroutines
+func (n *NGINXController) resolveIngresses(ings []*extensions.Ingress) *syncResolution {
+	r := &syncResolution{
+		n:           n,
+		annotations: make(map[string]resolvedAnnotations, len(ings)),
+		endpoints:   make(map[endpointsKey]resolvedEndpoints),
+	}
+
+	anns := make([]resolvedAnnotations, len(ings))
+	parallelize(syncWorkers, len(ings), func(i int) {
+		a, err := n.store.GetIngressAnnotations(k8s.MetaNamespaceKey(ings[i]))
+		anns[i] = resolvedAnnotations{a, err}
+	})
+
+	var keys []endpointsKey
+	seen := make(map[endpointsKey]bool)
+	addKey := func(ing *extensions.Ingress, backend *extensions.IngressBackend, a *annotations.Ingress) {
+		key := endpointsKey{
+			svcKey:          fmt.Sprintf("%v/%v", ing.Namespace, backend.ServiceName),
+			backendPort:     backend.ServicePort.String(),
+			backendProtocol: a.BackendProtocol,
+			includeNotReady: a.IncludeNotReady,
+		}
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	for i, ing := range ings {
+		r.annotations[k8s.MetaNamespaceKey(ing)] = anns[i]
+
+		a := anns[i].anns
+		// the backends using the ClusterIP of the Service do not
+		// need its endpoints
+		if a == nil || a.ServiceUpstream {
+			continue
+		}
+
+		if ing.Spec.Backend != nil {
+			addKey(ing, ing.Spec.Backend, a)
+		}
+
+		for _, rule := range ing.Spec.Rules {
+			if rule.HTTP == nil {
+				continue
+			}
+
+			for i := range rule.HTTP.Paths {
+				addKey(ing, &rule.HTTP.Paths[i].Backend, a)
+			}
+		}
+	}
+
+	endps := make([]resolvedEndpoints, len(keys))
+	parallelize(syncWorkers, len(keys), func(i int) {
+		key := keys[i]
+		e, err := n.serviceEndpoints(key.svcKey, key.backendPort, key.backendProtocol, key.includeNotReady)
+		endps[i] = resolvedEndpoints{e, err}
+	})
+
+	for i, key := range keys {
+		r.endpoints[key] = endps[i]
+	}
+
+	return r
+}
+
+// ingressAnnotations returns the annotations of the Ingress matching key
+func (r *syncResolution) ingressAnnotations(key string) (*annotations.Ingress, error) {
+	if a, ok := r.annotations[key]; ok {
+		return a.anns, a.err
+	}
+
+	return r.n.store.GetIngressAnnotations(key)
+}
+
+// serviceEndpoints returns the endpoints of a Service port like the
+// serviceEndpoints method of the controller. Each call returns a copy.
+func (r *syncResolution) serviceEndpoints(svcKey, backendPort, backendProtocol string, includeNotReady bool) ([]ingress.Endpoint, error) {
+	e, ok := r.endpoints[endpointsKey{svcKey, backendPort, backendProtocol, includeNotReady}]
+	if !ok {
+		return r.n.serviceEndpoints(svcKey, backendPort, backendProtocol, includeNotReady)
+	}
+
+	return append([]ingress.Endpoint(nil), e.endpoints...), e.err
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"sync/atomic"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"k8s.io/ingress-nginx/internal/ingress/annotations"
+)
+
+// countingServiceStore counts the reads of the Service
+type countingServiceStore struct {
+	fakeServiceStore
+	annotations map[string]*annotations.Ingress
+	services    *int32
+}
+
+func (s countingServiceStore) GetService(key string) (*apiv1.Service, error) {
+	atomic.AddInt32(s.services, 1)
+	return s.fakeServiceStore.GetService(key)
+}
+
+func (s countingServiceStore) GetIngressAnnotations(key string) (*annotations.Ingress, error) {
+	return fakeAnnotationsStore{annotations: s.annotations}.GetIngressAnnotations(key)
+}
+
+func TestParallelize(t *testing.T) {
+	for _, workers := range []int{1, 4, 100} {
+		done := make([]int32, 50)
+		parallelize(workers, len(done), func(i int) {
+			atomic.AddInt32(&done[i], 1)
+		})
+
+		for i, d := range done {
+			if d != 1 {
+				t.Errorf("expected index %v done once with %v workers but it was done %v times", i, workers, d)
+			}
+		}
+	}
+
+	parallelize(4, 0, func(i int) {
+		t.Errorf("unexpected call without work")
+	})
+}
+
+func TestResolveIngresses(t *testing.T) {
+	backend := extensions.IngressBackend{ServiceName: "app", ServicePort: intstr.FromInt(80)}
+	newIngress := func(name string) *extensions.Ingress {
+		return &extensions.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: extensions.IngressSpec{
+				Backend: &backend,
+				Rules: []extensions.IngressRule{{
+					Host: "foo.bar",
+					IngressRuleValue: extensions.IngressRuleValue{
+						HTTP: &extensions.HTTPIngressRuleValue{
+							Paths: []extensions.HTTPIngressPath{{Path: "/", Backend: backend}},
+						},
+					},
+				}},
+			},
+		}
+	}
+
+	var services int32
+	n := &NGINXController{
+		cfg: &Configuration{SortBackends: true},
+		store: countingServiceStore{
+			annotations: map[string]*annotations.Ingress{
+				"default/web":              {},
+				"default/api":              {},
+				"default/service-upstream": {ServiceUpstream: true},
+			},
+			fakeServiceStore: fakeServiceStore{
+				service: &apiv1.Service{
+					ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+					Spec: apiv1.ServiceSpec{
+						Ports: []apiv1.ServicePort{{Port: 80, Protocol: apiv1.ProtocolTCP}},
+					},
+				},
+				endpoints: &apiv1.Endpoints{
+					Subsets: []apiv1.EndpointSubset{{
+						Addresses: []apiv1.EndpointAddress{{IP: "10.0.0.1"}, {IP: "10.0.0.2"}},
+						Ports:     []apiv1.EndpointPort{{Port: 8080, Protocol: apiv1.ProtocolTCP}},
+					}},
+				},
+			},
+			services: &services,
+		},
+	}
+
+	r := n.resolveIngresses([]*extensions.Ingress{newIngress("web"), newIngress("api"), newIngress("service-upstream")})
+
+	if len(r.annotations) != 3 {
+		t.Errorf("expected the annotations of 3 Ingresses but %v returned", len(r.annotations))
+	}
+	anns, err := r.ingressAnnotations("default/service-upstream")
+	if err != nil || !anns.ServiceUpstream {
+		t.Errorf("expected the annotations of the Ingress default/service-upstream but %v returned (%v)", anns, err)
+	}
+
+	// the same Service port of all the backends is read once
+	if len(r.endpoints) != 1 || services != 1 {
+		t.Errorf("expected the endpoints of a Service port read once but %v read %v times", len(r.endpoints), services)
+	}
+
+	endps, err := r.serviceEndpoints("default/app", "80", "", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(endps) != 2 || endps[0].Address != "10.0.0.1" {
+		t.Errorf("expected the 2 endpoints of the Service but %v returned", endps)
+	}
+	if services != 1 {
+		t.Errorf("expected the resolved endpoints without reading the Service again")
+	}
+
+	endps[0].Address = "10.0.0.3"
+	if endps, _ := r.serviceEndpoints("default/app", "80", "", false); endps[0].Address != "10.0.0.1" {
+		t.Errorf("expected a copy of the resolved endpoints in each call")
+	}
+
+	if _, err := r.serviceEndpoints("default/app", "80", "HTTPS", false); err != nil || services != 2 {
+		t.Errorf("expected the endpoints not resolved read from the store")
+	}
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+
+	"k8s.io/ingress-nginx/internal/log"
+)
+
+// appProtocolsAnnotation is the annotation of a Service with the application
+// protocol of its ports, as a JSON object indexed by port name, like
+// {"https-port":"HTTPS"}. The ServicePort of this API version has no
+// appProtocol field.
+const appProtocolsAnnotation = "service.alpha.kubernetes.io/app-protocols"
+
+// servicePortMatch is a port of a Service referenced by an Ingress backend
+type servicePortMatch struct {
+	port *apiv1.ServicePort
+	// byTargetPort indicates the backend port is the target port and
+	// not the port or the name of the Service port
+	byTargetPort bool
+	appProtocol  string
+}
+
+func (m servicePortMatch) String() string {
+	if m.port.Name == "" {
+		return fmt.Sprintf("%v/%v", m.port.Port, m.port.Protocol)
+	}
+
+	return fmt.Sprintf("%q (%v/%v)", m.port.Name, m.port.Port, m.port.Protocol)
+}
+
+// appProtocols returns the application protocol of the ports of a Service
+// defined in the annotation app-protocols
+func appProtocols(svc *apiv1.Service) map[string]string {
+	val, ok := svc.Annotations[appProtocolsAnnotation]
+	if !ok {
+		return nil
+	}
+
+	protocols := map[string]string{}
+	if err := json.Unmarshal([]byte(val), &protocols); err != nil {
+		log.Warningf("Invalid annotation %v in Service %v/%v: %v", appProtocolsAnnotation, svc.Namespace, svc.Name, err)
+		return nil
+	}
+
+	return protocols
+}
+
+// selectServicePort returns the port of a Service referenced by the port
+// of an Ingress backend, a number or a name, and the reason of the choice
+// when several ports match it. The rules are applied in order until one
+// port remains:
+//
+// 1. a match of the port or the name of the Service port over the target port
+// 2. the TCP protocol
+// 3. the application protocol equal to the backend protocol of the Ingress
+// 4. the first port in the Service
+func selectServicePort(svc *apiv1.Service, backendPort, backendProtocol string) (*apiv1.ServicePort, string) {
+	protocols := appProtocols(svc)
+
+	var matches []servicePortMatch
+	for i := range svc.Spec.Ports {
+		sp := &svc.Spec.Ports[i]
+		m := servicePortMatch{port: sp, appProtocol: protocols[sp.Name]}
+		switch {
+		case strconv.Itoa(int(sp.Port)) == backendPort || sp.Name == backendPort:
+		case sp.TargetPort.String() == backendPort:
+			m.byTargetPort = true
+		default:
+			continue
+		}
+
+		matches = append(matches, m)
+	}
+
+	if len(matches) == 0 {
+		return nil, ""
+	}
+	if len(matches) == 1 {
+		return matches[0].port, ""
+	}
+
+	candidates := matches
+	reason := "it is the first one in the Service"
+
+	rules := []struct {
+		reason string
+		match  func(servicePortMatch) bool
+	}{
+		{
+			"its port or name is the port of the Ingress backend",
+			func(m servicePortMatch) bool { return !m.byTargetPort },
+		},
+		{
+			"its protocol is TCP",
+			func(m servicePortMatch) bool { return m.port.Protocol == "" || m.port.Protocol == apiv1.ProtocolTCP },
+		},
+		{
+			fmt.Sprintf("its application protocol is %v", backendProtocol),
+			func(m servicePortMatch) bool {
+				return backendProtocol != "" && strings.EqualFold(m.appProtocol, backendProtocol)
+			},
+		},
+	}
+
+	for _, rule := range rules {
+		var filtered []servicePortMatch
+		for _, m := range candidates {
+			if rule.match(m) {
+				filtered = append(filtered, m)
+			}
+		}
+
+		if len(filtered) == 0 {
+			continue
+		}
+
+		candidates = filtered
+		if len(candidates) == 1 {
+			reason = rule.reason
+			break
+		}
+	}
+
+	var others []string
+	for _, m := range matches {
+		if m.port != candidates[0].port {
+			others = append(others, m.String())
+		}
+	}
+
+	return candidates[0].port, fmt.Sprintf("Port %v matches several ports of the Service, using %v instead of %v because %v",
+		backendPort, candidates[0], strings.Join(others, ", "), reason)
+}
+
+// reportServicePortChoice logs and records an event in the Service when
+// the choice of the port referenced by an Ingress backend changes
+func (n *NGINXController) reportServicePortChoice(svc *apiv1.Service, backendPort, msg string) {
+	key := fmt.Sprintf("%v/%v|%v", svc.Namespace, svc.Name, backendPort)
+
+	n.servicePortChoicesLock.Lock()
+	defer n.servicePortChoicesLock.Unlock()
+
+	if n.servicePortChoices[key] == msg {
+		return
+	}
+
+	if n.servicePortChoices == nil {
+		n.servicePortChoices = map[string]string{}
+	}
+	n.servicePortChoices[key] = msg
+
+	log.Warningf("Service %v/%v: %v", svc.Namespace, svc.Name, msg)
+	if n.recorder != nil {
+		n.recorder.Event(svc, apiv1.EventTypeNormal, "ServicePortSelected", msg)
+	}
+}
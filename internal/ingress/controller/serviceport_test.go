@@ -0,0 +1,135 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestSelectServicePort(t *testing.T) {
+	testCases := map[string]struct {
+		ports           []apiv1.ServicePort
+		annotations     map[string]string
+		backendPort     string
+		backendProtocol string
+		expected        string
+		reason          string
+	}{
+		"no matching port": {
+			ports:       []apiv1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}},
+			backendPort: "81",
+		},
+		"single matching port": {
+			ports:       []apiv1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}},
+			backendPort: "8080",
+			expected:    "http",
+		},
+		"port number over target port": {
+			ports: []apiv1.ServicePort{
+				{Name: "metrics", Port: 9090, TargetPort: intstr.FromInt(80), Protocol: apiv1.ProtocolTCP},
+				{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080), Protocol: apiv1.ProtocolTCP},
+			},
+			backendPort: "80",
+			expected:    "http",
+			reason:      "its port or name is the port of the Ingress backend",
+		},
+		"TCP over UDP": {
+			ports: []apiv1.ServicePort{
+				{Name: "dns-udp", Port: 53, TargetPort: intstr.FromInt(53), Protocol: apiv1.ProtocolUDP},
+				{Name: "dns-tcp", Port: 53, TargetPort: intstr.FromInt(53), Protocol: apiv1.ProtocolTCP},
+			},
+			backendPort: "53",
+			expected:    "dns-tcp",
+			reason:      "its protocol is TCP",
+		},
+		"application protocol": {
+			ports: []apiv1.ServicePort{
+				{Name: "http", Port: 8443, TargetPort: intstr.FromInt(8080), Protocol: apiv1.ProtocolTCP},
+				{Name: "https", Port: 9443, TargetPort: intstr.FromInt(8443), Protocol: apiv1.ProtocolTCP},
+			},
+			annotations:     map[string]string{appProtocolsAnnotation: `{"http":"HTTP","https":"HTTPS"}`},
+			backendPort:     "8443",
+			backendProtocol: "HTTPS",
+			expected:        "http",
+			reason:          "its port or name is the port of the Ingress backend",
+		},
+		"application protocol tiebreaker": {
+			ports: []apiv1.ServicePort{
+				{Name: "http", Port: 8080, TargetPort: intstr.FromInt(80), Protocol: apiv1.ProtocolTCP},
+				{Name: "https", Port: 8443, TargetPort: intstr.FromInt(80), Protocol: apiv1.ProtocolTCP},
+			},
+			annotations:     map[string]string{appProtocolsAnnotation: `{"http":"HTTP","https":"HTTPS"}`},
+			backendPort:     "80",
+			backendProtocol: "HTTPS",
+			expected:        "https",
+			reason:          "its application protocol is HTTPS",
+		},
+		"first port": {
+			ports: []apiv1.ServicePort{
+				{Name: "a", Port: 8080, TargetPort: intstr.FromInt(80), Protocol: apiv1.ProtocolTCP},
+				{Name: "b", Port: 8081, TargetPort: intstr.FromInt(80), Protocol: apiv1.ProtocolTCP},
+			},
+			annotations:     map[string]string{appProtocolsAnnotation: `invalid`},
+			backendPort:     "80",
+			backendProtocol: "HTTP",
+			expected:        "a",
+			reason:          "it is the first one in the Service",
+		},
+	}
+
+	for name, tc := range testCases {
+		svc := &apiv1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "svc", Namespace: "default", Annotations: tc.annotations},
+			Spec:       apiv1.ServiceSpec{Ports: tc.ports},
+		}
+
+		port, msg := selectServicePort(svc, tc.backendPort, tc.backendProtocol)
+		if tc.expected == "" {
+			if port != nil {
+				t.Errorf("%v: expected no port but %v returned", name, port.Name)
+			}
+			continue
+		}
+
+		if port == nil || port.Name != tc.expected {
+			t.Errorf("%v: expected port %v but %v returned", name, tc.expected, port)
+		}
+		if tc.reason == "" && msg != "" {
+			t.Errorf("%v: unexpected choice %q", name, msg)
+		}
+		if !strings.HasSuffix(msg, tc.reason) {
+			t.Errorf("%v: expected the reason %q but returned %q", name, tc.reason, msg)
+		}
+	}
+}
+
+func TestReportServicePortChoice(t *testing.T) {
+	n := &NGINXController{servicePortChoicesLock: &sync.Mutex{}}
+	svc := &apiv1.Service{ObjectMeta: metav1.ObjectMeta{Name: "svc", Namespace: "default"}}
+
+	n.reportServicePortChoice(svc, "80", "choice")
+	n.reportServicePortChoice(svc, "80", "choice")
+	if len(n.servicePortChoices) != 1 || n.servicePortChoices["default/svc|80"] != "choice" {
+		t.Errorf("expected the choice to be reported once but returned %v", n.servicePortChoices)
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"hash/fnv"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+)
+
+// shardOf returns the shard, from 0 to count-1, owning a hostname. It is
+// the jump consistent hash of the FNV-1a hash of the hostname, so adding a
+// shard only moves 1/count of the hostnames to it.
+func shardOf(hostname string, count int) int {
+	h := fnv.New64a()
+	h.Write([]byte(hostname))
+	key := h.Sum64()
+
+	var b, j int64 = -1, 0
+	for j < int64(count) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+
+	return int(b)
+}
+
+// ownsHost returns true if the shard of the controller configures a
+// hostname. All the shards configure the rules without a hostname.
+func (n *NGINXController) ownsHost(hostname string) bool {
+	if n.cfg.ShardCount <= 1 || hostname == "" {
+		return true
+	}
+
+	return shardOf(hostname, n.cfg.ShardCount) == n.cfg.ShardIndex
+}
+
+// filterShardIngresses returns the Ingresses with the rules of the
+// hostnames owned by the shard of the controller. The Ingresses with rules
+// of other shards are copied without them, and the ones without rules or
+// default backend left are not returned.
+func (n *NGINXController) filterShardIngresses(ings []*extensions.Ingress) []*extensions.Ingress {
+	if n.cfg.ShardCount <= 1 {
+		return ings
+	}
+
+	owned := make([]*extensions.Ingress, 0, len(ings))
+	for _, ing := range ings {
+		var rules []extensions.IngressRule
+		for _, rule := range ing.Spec.Rules {
+			if n.ownsHost(rule.Host) {
+				rules = append(rules, rule)
+			}
+		}
+
+		if len(rules) == len(ing.Spec.Rules) {
+			owned = append(owned, ing)
+			continue
+		}
+
+		if len(rules) == 0 && ing.Spec.Backend == nil {
+			continue
+		}
+
+		ing = ing.DeepCopy()
+		ing.Spec.Rules = rules
+		owned = append(owned, ing)
+	}
+
+	return owned
+}
+
+// shardIngressLister lists the Ingresses whose status is updated by the
+// shard of the controller. Each Ingress is updated by a single shard, the
+// owner of its lowest hostname, to not publish the addresses of several
+// shards in turns. The Ingresses without hostnames are updated by the
+// first shard.
+type shardIngressLister struct {
+	n *NGINXController
+}
+
+// ListIngresses returns the Ingresses of the shard, all of them when the
+// hostnames are not sharded
+func (l shardIngressLister) ListIngresses() []*extensions.Ingress {
+	if l.n.cfg.ShardCount <= 1 {
+		return l.n.store.ListIngresses()
+	}
+
+	var ings []*extensions.Ingress
+	for _, ing := range l.n.store.ListIngresses() {
+		lowest := ""
+		for _, rule := range ing.Spec.Rules {
+			if rule.Host != "" && (lowest == "" || rule.Host < lowest) {
+				lowest = rule.Host
+			}
+		}
+
+		if (lowest != "" && l.n.ownsHost(lowest)) || (lowest == "" && l.n.cfg.ShardIndex == 0) {
+			ings = append(ings, ing)
+		}
+	}
+
+	return ings
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	extensions "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestShardOf(t *testing.T) {
+	counts := make([]int, 4)
+	for i := 0; i < 10000; i++ {
+		host := fmt.Sprintf("host-%v.example.com", i)
+
+		shard := shardOf(host, 4)
+		if shard < 0 || shard >= 4 {
+			t.Fatalf("expected a shard between 0 and 3 for %v but %v returned", host, shard)
+		}
+		if shardOf(host, 4) != shard {
+			t.Fatalf("expected the same shard for %v", host)
+		}
+		counts[shard]++
+
+		// a new shard only takes hostnames from the other shards
+		if moved := shardOf(host, 5); moved != shard && moved != 4 {
+			t.Errorf("expected %v to stay in shard %v or move to shard 4 but %v returned", host, shard, moved)
+		}
+	}
+
+	for shard, count := range counts {
+		if count < 2000 || count > 3000 {
+			t.Errorf("expected about 2500 hostnames in shard %v but %v returned", shard, count)
+		}
+	}
+
+	if shard := shardOf("example.com", 1); shard != 0 {
+		t.Errorf("expected shard 0 with a single shard but %v returned", shard)
+	}
+}
+
+func TestFilterShardIngresses(t *testing.T) {
+	// find two hostnames of different shards
+	owned, other := "", ""
+	for i := 0; owned == "" || other == ""; i++ {
+		host := fmt.Sprintf("host-%v.example.com", i)
+		if shardOf(host, 2) == 1 {
+			owned = host
+		} else {
+			other = host
+		}
+	}
+
+	newIngress := func(name string, hosts ...string) *extensions.Ingress {
+		ing := &extensions.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		}
+		for _, host := range hosts {
+			ing.Spec.Rules = append(ing.Spec.Rules, extensions.IngressRule{Host: host})
+		}
+		return ing
+	}
+
+	ownedIng := newIngress("owned", owned)
+	otherIng := newIngress("other", other)
+	mixedIng := newIngress("mixed", owned, other)
+	catchAllIng := newIngress("catch-all", "")
+	defaultBackendIng := newIngress("default-backend", other)
+	defaultBackendIng.Spec.Backend = &extensions.IngressBackend{ServiceName: "default"}
+
+	n := &NGINXController{cfg: &Configuration{ShardIndex: 1, ShardCount: 2}}
+	ings := n.filterShardIngresses([]*extensions.Ingress{ownedIng, otherIng, mixedIng, catchAllIng, defaultBackendIng})
+
+	if len(ings) != 4 {
+		t.Fatalf("expected 4 Ingresses but %v returned", len(ings))
+	}
+	if ings[0] != ownedIng {
+		t.Errorf("expected the Ingress with an owned hostname unchanged")
+	}
+	if len(ings[1].Spec.Rules) != 1 || ings[1].Spec.Rules[0].Host != owned {
+		t.Errorf("expected only the rule of %v in the mixed Ingress but %v returned", owned, ings[1].Spec.Rules)
+	}
+	if len(mixedIng.Spec.Rules) != 2 {
+		t.Errorf("expected the mixed Ingress in the store unchanged")
+	}
+	if ings[2] != catchAllIng {
+		t.Errorf("expected the Ingress without hostnames unchanged")
+	}
+	if len(ings[3].Spec.Rules) != 0 || ings[3].Spec.Backend == nil {
+		t.Errorf("expected only the default backend of the Ingress of another shard")
+	}
+
+	n.cfg.ShardCount = 1
+	if ings := n.filterShardIngresses([]*extensions.Ingress{ownedIng, otherIng}); len(ings) != 2 {
+		t.Errorf("expected 2 Ingresses without sharding but %v returned", len(ings))
+	}
+}
+
+func TestShardIngressLister(t *testing.T) {
+	// find two hostnames of different shards
+	hosts := map[int]string{}
+	for i := 0; len(hosts) < 2; i++ {
+		host := fmt.Sprintf("host-%v.example.com", i)
+		if _, ok := hosts[shardOf(host, 2)]; !ok {
+			hosts[shardOf(host, 2)] = host
+		}
+	}
+
+	mixed := &extensions.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "mixed", Namespace: "default"},
+		Spec: extensions.IngressSpec{
+			Rules: []extensions.IngressRule{{Host: hosts[0]}, {Host: hosts[1]}},
+		},
+	}
+	catchAll := &extensions.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "catch-all", Namespace: "default"},
+		Spec: extensions.IngressSpec{
+			Rules: []extensions.IngressRule{{Host: ""}},
+		},
+	}
+
+	lowest := hosts[0]
+	if hosts[1] < lowest {
+		lowest = hosts[1]
+	}
+
+	listed := map[string][]int{}
+	for index := 0; index < 2; index++ {
+		n := &NGINXController{
+			cfg:   &Configuration{ShardIndex: index, ShardCount: 2},
+			store: fakeIngressStore{ingresses: []*extensions.Ingress{mixed, catchAll}},
+		}
+
+		for _, ing := range (shardIngressLister{n}).ListIngresses() {
+			listed[ing.Name] = append(listed[ing.Name], index)
+		}
+	}
+
+	if expected := []int{shardOf(lowest, 2)}; !reflect.DeepEqual(listed["mixed"], expected) {
+		t.Errorf("expected the Ingress with hostnames of several shards listed by the shard %v but got %v", expected, listed["mixed"])
+	}
+	if expected := []int{0}; !reflect.DeepEqual(listed["catch-all"], expected) {
+		t.Errorf("expected the Ingress without hostnames listed by the first shard but got %v", listed["catch-all"])
+	}
+}
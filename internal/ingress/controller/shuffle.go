@@ -0,0 +1,82 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+)
+
+// lockedSource is a math/rand source safe for concurrent use, like the
+// source of the top-level functions of math/rand
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+}
+
+// endpointsRand shuffles the endpoints of the upstreams. It is seeded once
+// and used by the concurrent syncs of the endpoints.
+var endpointsRand = rand.New(&lockedSource{src: rand.NewSource(time.Now().UnixNano())})
+
+// sortEndpoints sorts endpoints by address and port
+func sortEndpoints(endps []ingress.Endpoint) {
+	sort.SliceStable(endps, func(i, j int) bool {
+		iName := endps[i].Address
+		jName := endps[j].Address
+		if iName != jName {
+			return iName < jName
+		}
+
+		return endps[i].Port < endps[j].Port
+	})
+}
+
+// shuffleEndpoints shuffles the endpoints of a Service port. With a
+// deterministic endpoint order the same endpoints are always shuffled in
+// the same order, seeded by the Service and the port, so the configuration
+// only changes when they change.
+func (n *NGINXController) shuffleEndpoints(svcKey, backendPort string, endps []ingress.Endpoint) {
+	r := endpointsRand
+	if n.cfg.DeterministicEndpointOrder {
+		sortEndpoints(endps)
+
+		h := fnv.New64a()
+		h.Write([]byte(svcKey + "|" + backendPort))
+		r = rand.New(rand.NewSource(int64(h.Sum64())))
+	}
+
+	r.Shuffle(len(endps), func(i, j int) {
+		endps[i], endps[j] = endps[j], endps[i]
+	})
+}
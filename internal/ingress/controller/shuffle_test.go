@@ -0,0 +1,85 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+)
+
+func newShuffleEndpoints(count int) []ingress.Endpoint {
+	endps := make([]ingress.Endpoint, count)
+	for i := range endps {
+		endps[i] = ingress.Endpoint{Address: fmt.Sprintf("10.0.0.%v", i), Port: "8080"}
+	}
+	return endps
+}
+
+func TestShuffleEndpointsDeterministic(t *testing.T) {
+	n := &NGINXController{cfg: &Configuration{DeterministicEndpointOrder: true}}
+
+	first := newShuffleEndpoints(20)
+	n.shuffleEndpoints("default/app", "80", first)
+
+	// the order of the endpoints read does not change the result
+	reversed := newShuffleEndpoints(20)
+	for i, j := 0, len(reversed)-1; i < j; i, j = i+1, j-1 {
+		reversed[i], reversed[j] = reversed[j], reversed[i]
+	}
+	n.shuffleEndpoints("default/app", "80", reversed)
+
+	if !reflect.DeepEqual(first, reversed) {
+		t.Errorf("expected the same order for the same endpoints but %v and %v returned", first, reversed)
+	}
+
+	sorted := newShuffleEndpoints(20)
+	sortEndpoints(sorted)
+	if reflect.DeepEqual(first, sorted) {
+		t.Errorf("expected the endpoints shuffled")
+	}
+
+	other := newShuffleEndpoints(20)
+	n.shuffleEndpoints("default/other", "80", other)
+	if reflect.DeepEqual(first, other) {
+		t.Errorf("expected a different order for another Service")
+	}
+}
+
+func TestShuffleEndpointsConcurrent(t *testing.T) {
+	n := &NGINXController{cfg: &Configuration{}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			endps := newShuffleEndpoints(10)
+			n.shuffleEndpoints("default/app", "80", endps)
+
+			sortEndpoints(endps)
+			if !reflect.DeepEqual(endps, newShuffleEndpoints(10)) {
+				t.Errorf("expected the same endpoints after shuffling them but %v returned", endps)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
+	"k8s.io/ingress-nginx/internal/log"
+	"k8s.io/ingress-nginx/internal/task"
+	"k8s.io/ingress-nginx/internal/watch"
+)
+
+// snippetFileWatch keeps the content of the snippet files included in the
+// last configuration loaded by NGINX and watches their directories
+type snippetFileWatch struct {
+	sync.Mutex
+
+	// loaded is the content included in the last configuration loaded by NGINX
+	loaded ngx_config.SnippetFiles
+
+	// watchers contains the watchers of the directories with snippet files
+	watchers map[string]watch.FileWatcher
+}
+
+// setLoaded records the content of the snippet files loaded by NGINX
+func (w *snippetFileWatch) setLoaded(files ngx_config.SnippetFiles) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.loaded = files
+}
+
+// isLoaded returns true if NGINX uses the content of the snippet files
+func (w *snippetFileWatch) isLoaded(files ngx_config.SnippetFiles) bool {
+	w.Lock()
+	defer w.Unlock()
+
+	return w.loaded == files
+}
+
+// watch replaces the watched directories. It calls onChange when a file
+// in one of the directories is created or written.
+func (w *snippetFileWatch) watch(dirs []string, onChange func()) {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.watchers == nil {
+		w.watchers = make(map[string]watch.FileWatcher)
+	}
+
+	current := make(map[string]bool)
+	for _, dir := range dirs {
+		current[dir] = true
+		if _, ok := w.watchers[dir]; ok {
+			continue
+		}
+
+		// the trailing slash matches the events of all the files in the directory
+		fw, err := watch.NewFileWatcher(dir+"/", onChange)
+		if err != nil {
+			log.Warningf("Error watching the snippet files in %v: %v", dir, err)
+			continue
+		}
+		w.watchers[dir] = fw
+	}
+
+	for dir, fw := range w.watchers {
+		if current[dir] {
+			continue
+		}
+
+		fw.Close()
+		delete(w.watchers, dir)
+	}
+}
+
+// readSnippetFiles reads the files defined by http-snippet-files and the .conf
+// files in server-snippet-dir and stream-snippet-dir, sorted by name
+func readSnippetFiles(cfg ngx_config.Configuration) (ngx_config.SnippetFiles, error) {
+	var files ngx_config.SnippetFiles
+
+	var buf bytes.Buffer
+	for _, f := range cfg.HTTPSnippetFiles {
+		content, err := ioutil.ReadFile(f)
+		if err != nil {
+			return files, fmt.Errorf("error reading http-snippet-files: %v", err)
+		}
+		writeSnippet(&buf, f, content)
+	}
+	files.HTTP = buf.String()
+
+	server, err := readSnippetDir(cfg.ServerSnippetDir)
+	if err != nil {
+		return files, fmt.Errorf("error reading server-snippet-dir: %v", err)
+	}
+	files.Server = server
+
+	stream, err := readSnippetDir(cfg.StreamSnippetDir)
+	if err != nil {
+		return files, fmt.Errorf("error reading stream-snippet-dir: %v", err)
+	}
+	files.Stream = stream
+
+	return files, nil
+}
+
+// readSnippetDir concatenates the .conf files in a directory, sorted by name
+func readSnippetDir(dir string) (string, error) {
+	if dir == "" {
+		return "", nil
+	}
+
+	if !filepath.IsAbs(dir) {
+		return "", fmt.Errorf("%v is not an absolute path", dir)
+	}
+
+	names, err := filepath.Glob(filepath.Join(dir, "*.conf"))
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		content, err := ioutil.ReadFile(name)
+		if err != nil {
+			return "", err
+		}
+		writeSnippet(&buf, name, content)
+	}
+
+	return buf.String(), nil
+}
+
+// writeSnippet writes the content of a snippet file preceded by its name
+func writeSnippet(buf *bytes.Buffer, name string, content []byte) {
+	fmt.Fprintf(buf, "# %v\n", name)
+	buf.Write(content)
+	if !bytes.HasSuffix(content, []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+}
+
+// snippetDirs returns the directories with the snippet files of a configuration
+func snippetDirs(cfg ngx_config.Configuration) []string {
+	dirs := make(map[string]bool)
+	for _, f := range cfg.HTTPSnippetFiles {
+		dirs[filepath.Dir(f)] = true
+	}
+	for _, dir := range []string{cfg.ServerSnippetDir, cfg.StreamSnippetDir} {
+		if dir != "" {
+			dirs[strings.TrimSuffix(dir, "/")] = true
+		}
+	}
+
+	list := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		list = append(list, dir)
+	}
+	sort.Strings(list)
+
+	return list
+}
+
+// checkSnippetFiles reloads NGINX when the content of the snippet files
+// changed since the last reload
+func (n *NGINXController) checkSnippetFiles() {
+	files, err := readSnippetFiles(n.store.GetBackendConfiguration())
+	if err != nil {
+		log.Warningf("Error reading the snippet files: %v", err)
+		return
+	}
+
+	if n.snippetFiles.isLoaded(files) {
+		return
+	}
+
+	log.Infof("Snippet files changed. Reloading NGINX")
+	n.setForceReload(true)
+	n.syncQueue.EnqueueTask(task.GetDummyObject("snippet-file-change"))
+}
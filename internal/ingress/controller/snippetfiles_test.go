@@ -0,0 +1,93 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
+)
+
+func TestReadSnippetFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snippets")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	serverDir := filepath.Join(dir, "server")
+	if err := os.Mkdir(serverDir, 0755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	files := map[string]string{
+		filepath.Join(dir, "maps.conf"):           "map $host $tenant { default none; }",
+		filepath.Join(serverDir, "b-cache.conf"):  "expires 1h;\n",
+		filepath.Join(serverDir, "a-limits.conf"): "limit_req zone=default;\n",
+		filepath.Join(serverDir, "README"):        "not included",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	cfg := ngx_config.Configuration{
+		HTTPSnippetFiles: []string{filepath.Join(dir, "maps.conf")},
+		ServerSnippetDir: serverDir,
+	}
+
+	snippets, err := readSnippetFiles(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := ngx_config.SnippetFiles{
+		HTTP: "# " + filepath.Join(dir, "maps.conf") + "\nmap $host $tenant { default none; }\n",
+		Server: "# " + filepath.Join(serverDir, "a-limits.conf") + "\nlimit_req zone=default;\n" +
+			"# " + filepath.Join(serverDir, "b-cache.conf") + "\nexpires 1h;\n",
+	}
+	if snippets != expected {
+		t.Errorf("expected %+v but got %+v", expected, snippets)
+	}
+
+	expectedDirs := []string{dir, serverDir}
+	if dirs := snippetDirs(cfg); !reflect.DeepEqual(dirs, expectedDirs) {
+		t.Errorf("expected the directories %v but got %v", expectedDirs, dirs)
+	}
+
+	w := &snippetFileWatch{}
+	w.setLoaded(snippets)
+	if !w.isLoaded(snippets) {
+		t.Errorf("expected the snippet files to be loaded")
+	}
+
+	cfg.HTTPSnippetFiles = append(cfg.HTTPSnippetFiles, filepath.Join(dir, "missing.conf"))
+	if _, err := readSnippetFiles(cfg); err == nil {
+		t.Errorf("expected an error reading a missing snippet file")
+	}
+
+	cfg.HTTPSnippetFiles = nil
+	cfg.StreamSnippetDir = "relative"
+	if _, err := readSnippetFiles(cfg); err == nil {
+		t.Errorf("expected an error with a relative snippet directory")
+	}
+}
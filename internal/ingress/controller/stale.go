@@ -0,0 +1,128 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/ingress-nginx/internal/log"
+)
+
+const (
+	// staleReasonReload indicates the last reload of NGINX failed
+	staleReasonReload = "reload-failed"
+	// staleReasonDynamic indicates the last dynamic reconfiguration failed
+	staleReasonDynamic = "dynamic-configuration-failed"
+	// staleReasonAPIServer indicates the API server cannot be reached
+	staleReasonAPIServer = "apiserver-unreachable"
+	// staleReasonReloadDeferred indicates a reload of NGINX is deferred
+	// because of the active WebSocket connections
+	staleReasonReloadDeferred = "reload-deferred"
+)
+
+// staleConfiguration contains the reasons why the configuration running
+// in NGINX could be outdated compared with the state of the cluster.
+type staleConfiguration struct {
+	lock    sync.Mutex
+	reasons sets.String
+	// version is incremented each time the reasons change
+	version int
+	// sentVersion is the version of the reasons NGINX received
+	sentVersion int
+
+	// sendLock serializes the requests sending the reasons to NGINX
+	sendLock sync.Mutex
+}
+
+// setStale adds or removes a reason to consider the running configuration
+// stale. Changes are reported to the metric collector and to NGINX, which
+// can inject the reasons in a response header.
+func (n *NGINXController) setStale(reason string, stale bool) {
+	n.stale.lock.Lock()
+	changed := n.stale.reasons.Has(reason) != stale
+	if changed {
+		if stale {
+			log.Warningf("The running configuration is stale (%v)", reason)
+			n.stale.reasons.Insert(reason)
+		} else {
+			log.Infof("The running configuration is not stale anymore (%v)", reason)
+			n.stale.reasons.Delete(reason)
+		}
+		n.stale.version++
+	}
+	n.stale.lock.Unlock()
+
+	if changed {
+		n.metricCollector.SetStaleConfiguration(reason, stale)
+	}
+
+	n.sendStaleness()
+}
+
+// sendStaleness sends the reasons to NGINX if it did not receive the
+// current ones. When the request fails they are sent again in the next
+// call, like the periodic check of the API server.
+func (n *NGINXController) sendStaleness() {
+	n.stale.sendLock.Lock()
+	defer n.stale.sendLock.Unlock()
+
+	n.stale.lock.Lock()
+	version := n.stale.version
+	reasons := n.stale.reasons.List()
+	sent := n.stale.sentVersion == version
+	n.stale.lock.Unlock()
+
+	if sent {
+		return
+	}
+
+	err := configureStaleness(reasons)
+	if err != nil {
+		log.Warningf("Unexpected error reporting stale configuration to NGINX: %v", err)
+		return
+	}
+
+	n.stale.lock.Lock()
+	n.stale.sentVersion = version
+	n.stale.lock.Unlock()
+}
+
+// resend forces the next call to sendStaleness to send the reasons
+func (s *staleConfiguration) resend() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.sentVersion = -1
+}
+
+// checkAPIServer verifies the API server is reachable
+func (n *NGINXController) checkAPIServer() {
+	_, err := n.cfg.Client.Discovery().ServerVersion()
+	if err != nil {
+		log.Warningf("Unexpected error contacting the API server: %v", err)
+	}
+
+	n.setStale(staleReasonAPIServer, err != nil)
+}
+
+// configureStaleness POSTs the list of reasons why the configuration
+// is stale to an internal HTTP endpoint handled by Lua.
+func configureStaleness(reasons []string) error {
+	return post("/configuration/stale", reasons)
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
+	"k8s.io/ingress-nginx/internal/ingress/metric"
+)
+
+func TestSetStale(t *testing.T) {
+	n := &NGINXController{
+		cfg: &Configuration{
+			ListenPorts: &ngx_config.ListenPorts{Status: 0},
+		},
+		metricCollector: metric.DummyCollector{},
+		stale: &staleConfiguration{
+			reasons: sets.NewString(),
+		},
+	}
+
+	n.setStale(staleReasonReload, true)
+	n.setStale(staleReasonAPIServer, true)
+	n.setStale(staleReasonAPIServer, true)
+
+	expected := []string{staleReasonAPIServer, staleReasonReload}
+	if !reflect.DeepEqual(n.stale.reasons.List(), expected) {
+		t.Errorf("expected %v but %v was returned", expected, n.stale.reasons.List())
+	}
+
+	n.setStale(staleReasonReload, false)
+	n.setStale(staleReasonDynamic, false)
+
+	expected = []string{staleReasonAPIServer}
+	if !reflect.DeepEqual(n.stale.reasons.List(), expected) {
+		t.Errorf("expected %v but %v was returned", expected, n.stale.reasons.List())
+	}
+}
+
+func TestSendStaleness(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		available bool
+		received  []string
+	)
+
+	stop := newConfigurationServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if !available {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		received = nil
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("unexpected error decoding the reasons: %v", err)
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer stop()
+
+	n := &NGINXController{
+		metricCollector: metric.DummyCollector{},
+		stale: &staleConfiguration{
+			reasons: sets.NewString(),
+		},
+	}
+
+	n.setStale(staleReasonReload, true)
+	if received != nil {
+		t.Errorf("expected no reasons received by NGINX but got %v", received)
+	}
+
+	// the same state is sent again until NGINX receives it
+	mu.Lock()
+	available = true
+	mu.Unlock()
+	n.setStale(staleReasonReload, true)
+
+	expected := []string{staleReasonReload}
+	if !reflect.DeepEqual(received, expected) {
+		t.Errorf("expected %v but %v was received", expected, received)
+	}
+
+	// NGINX was restarted
+	received = nil
+	n.stale.resend()
+	n.sendStaleness()
+	if !reflect.DeepEqual(received, expected) {
+		t.Errorf("expected %v after a restart but %v was received", expected, received)
+	}
+}
@@ -20,7 +20,6 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/golang/glog"
 	"github.com/imdario/mergo"
 
 	apiv1 "k8s.io/api/core/v1"
@@ -30,22 +29,28 @@ import (
 	"k8s.io/ingress-nginx/internal/file"
 	"k8s.io/ingress-nginx/internal/ingress"
 	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/internal/log"
 	"k8s.io/ingress-nginx/internal/net/ssl"
 )
 
 // syncSecret synchronizes the content of a TLS Secret (certificate(s), secret
 // key) with the filesystem. The resulting files can be used by NGINX.
 func (s k8sStore) syncSecret(key string) {
+	// the first read of the secret is done without the lock
+	if s.secrets != nil {
+		s.secrets.Track(key)
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	glog.V(3).Infof("Syncing Secret %q", key)
+	log.V(3).Infof("Syncing Secret %q", key)
 
 	// TODO: getPemCertificate should not write to disk to avoid unnecessary overhead
 	cert, err := s.getPemCertificate(key)
 	if err != nil {
 		if !isErrSecretForAuth(err) {
-			glog.Warningf("Error obtaining X.509 certificate: %v", err)
+			log.Warningf("Error obtaining X.509 certificate: %v", err)
 		}
 		return
 	}
@@ -57,7 +62,7 @@ func (s k8sStore) syncSecret(key string) {
 			// no need to update
 			return
 		}
-		glog.Infof("Updating Secret %q in the local store", key)
+		log.Infof("Updating Secret %q in the local store", key)
 		s.sslStore.Update(key, cert)
 		// this update must trigger an update
 		// (like an update event from a change in Ingress)
@@ -65,7 +70,7 @@ func (s k8sStore) syncSecret(key string) {
 		return
 	}
 
-	glog.Infof("Adding Secret %q to the local store", key)
+	log.Infof("Adding Secret %q to the local store", key)
 	s.sslStore.Add(key, cert)
 	// this update must trigger an update
 	// (like an update event from a change in Ingress)
@@ -75,7 +80,7 @@ func (s k8sStore) syncSecret(key string) {
 // getPemCertificate receives a secret, and creates a ingress.SSLCert as return.
 // It parses the secret and verifies if it's a keypair, or a 'ca.crt' secret only.
 func (s k8sStore) getPemCertificate(secretName string) (*ingress.SSLCert, error) {
-	secret, err := s.listers.Secret.ByKey(secretName)
+	secret, err := s.GetSecret(secretName)
 	if err != nil {
 		return nil, err
 	}
@@ -116,7 +121,7 @@ func (s k8sStore) getPemCertificate(secretName string) (*ingress.SSLCert, error)
 		if ca != nil {
 			msg += " and authentication"
 		}
-		glog.V(3).Info(msg)
+		log.V(3).Info(msg)
 
 	} else if ca != nil {
 		sslCert, err = ssl.AddCertAuth(nsSecName, ca, s.filesystem)
@@ -127,7 +132,7 @@ func (s k8sStore) getPemCertificate(secretName string) (*ingress.SSLCert, error)
 
 		// makes this secret in 'syncSecret' to be used for Certificate Authentication
 		// this does not enable Certificate Authentication
-		glog.V(3).Infof("Configuring Secret %q for TLS authentication", secretName)
+		log.V(3).Infof("Configuring Secret %q for TLS authentication", secretName)
 
 	} else {
 		if auth != nil {
@@ -158,7 +163,7 @@ func (s k8sStore) checkSSLChainIssues() {
 
 		data, err := ssl.FullChainCert(secret.PemFileName, s.filesystem)
 		if err != nil {
-			glog.Errorf("Error generating CA certificate chain for Secret %q: %v", secrKey, err)
+			log.Errorf("Error generating CA certificate chain for Secret %q: %v", secrKey, err)
 			continue
 		}
 
@@ -166,13 +171,13 @@ func (s k8sStore) checkSSLChainIssues() {
 
 		file, err := s.filesystem.Create(fullChainPemFileName)
 		if err != nil {
-			glog.Errorf("Error creating SSL certificate file for Secret %q: %v", secrKey, err)
+			log.Errorf("Error creating SSL certificate file for Secret %q: %v", secrKey, err)
 			continue
 		}
 
 		_, err = file.Write(data)
 		if err != nil {
-			glog.Errorf("Error creating SSL certificate for Secret %q: %v", secrKey, err)
+			log.Errorf("Error creating SSL certificate for Secret %q: %v", secrKey, err)
 			continue
 		}
 
@@ -180,13 +185,13 @@ func (s k8sStore) checkSSLChainIssues() {
 
 		err = mergo.MergeWithOverwrite(dst, secret)
 		if err != nil {
-			glog.Errorf("Error creating SSL certificate for Secret %q: %v", secrKey, err)
+			log.Errorf("Error creating SSL certificate for Secret %q: %v", secrKey, err)
 			continue
 		}
 
 		dst.FullChainPemFileName = fullChainPemFileName
 
-		glog.Infof("Updating local copy of SSL certificate %q with missing intermediate CA certs", secrKey)
+		log.Infof("Updating local copy of SSL certificate %q with missing intermediate CA certs", secrKey)
 		s.sslStore.Update(secrKey, dst)
 		// this update must trigger an update
 		// (like an update event from a change in Ingress)
@@ -0,0 +1,92 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apiruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/informers/internalinterfaces"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/pager"
+)
+
+// pagedListWatch returns a ListWatch that lists the objects in pages of
+// pageSize items using the limit and continue options, to avoid a single
+// large response from the API server in clusters with many objects.
+// The reflectors of the informers list from the cache of the API server
+// (resourceVersion 0), where the limit is ignored, so the pages are read
+// from etcd instead. If the continue token expires the objects are
+// listed in a single request.
+func pagedListWatch(list cache.ListFunc, watch cache.WatchFunc, pageSize int64) *cache.ListWatch {
+	lp := pager.New(pager.SimplePageFunc(list))
+	lp.PageSize = pageSize
+
+	return &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (apiruntime.Object, error) {
+			options.ResourceVersion = ""
+			options.Limit = pageSize
+			return lp.List(context.Background(), options)
+		},
+		WatchFunc:       watch,
+		DisableChunking: true,
+	}
+}
+
+// newPagedInformer returns the function used by the informer factory to
+// create the informer of a resource listed in pages
+func newPagedInformer(obj apiruntime.Object, list cache.ListFunc, watch cache.WatchFunc, pageSize int64) internalinterfaces.NewInformerFunc {
+	return func(_ clientset.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+		return cache.NewSharedIndexInformer(
+			pagedListWatch(list, watch, pageSize),
+			obj,
+			resyncPeriod,
+			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+		)
+	}
+}
+
+// usePagedInformers registers in the informer factory informers that list
+// the resources used by the store in pages of pageSize items. Must be
+// called before the informers are obtained from the factory.
+func usePagedInformers(factory informers.SharedInformerFactory, client clientset.Interface, namespace string, pageSize int64) {
+	ingresses := client.ExtensionsV1beta1().Ingresses(namespace)
+	factory.InformerFor(&extensions.Ingress{}, newPagedInformer(&extensions.Ingress{},
+		func(options metav1.ListOptions) (apiruntime.Object, error) { return ingresses.List(options) },
+		ingresses.Watch, pageSize))
+
+	endpoints := client.CoreV1().Endpoints(namespace)
+	factory.InformerFor(&corev1.Endpoints{}, newPagedInformer(&corev1.Endpoints{},
+		func(options metav1.ListOptions) (apiruntime.Object, error) { return endpoints.List(options) },
+		endpoints.Watch, pageSize))
+
+	services := client.CoreV1().Services(namespace)
+	factory.InformerFor(&corev1.Service{}, newPagedInformer(&corev1.Service{},
+		func(options metav1.ListOptions) (apiruntime.Object, error) { return services.List(options) },
+		services.Watch, pageSize))
+
+	pods := client.CoreV1().Pods(namespace)
+	factory.InformerFor(&corev1.Pod{}, newPagedInformer(&corev1.Pod{},
+		func(options metav1.ListOptions) (apiruntime.Object, error) { return pods.List(options) },
+		pods.Watch, pageSize))
+}
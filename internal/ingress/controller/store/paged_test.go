@@ -0,0 +1,121 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apiruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestPagedListWatch(t *testing.T) {
+	pages := map[string]*corev1.ConfigMapList{
+		"": {
+			ListMeta: metav1.ListMeta{ResourceVersion: "10", Continue: "page-2"},
+			Items:    []corev1.ConfigMap{{ObjectMeta: metav1.ObjectMeta{Name: "a"}}, {ObjectMeta: metav1.ObjectMeta{Name: "b"}}},
+		},
+		"page-2": {
+			ListMeta: metav1.ListMeta{ResourceVersion: "10"},
+			Items:    []corev1.ConfigMap{{ObjectMeta: metav1.ObjectMeta{Name: "c"}}},
+		},
+	}
+
+	requests := 0
+	list := func(options metav1.ListOptions) (apiruntime.Object, error) {
+		requests++
+		if options.Limit != 2 {
+			t.Errorf("expected a limit of 2 but %v used", options.Limit)
+		}
+		if options.ResourceVersion != "" {
+			t.Errorf("expected no resource version but %q used", options.ResourceVersion)
+		}
+
+		page, ok := pages[options.Continue]
+		if !ok {
+			return nil, fmt.Errorf("unexpected continue token %q", options.Continue)
+		}
+		return page, nil
+	}
+	watchFn := func(metav1.ListOptions) (watch.Interface, error) {
+		return watch.NewFake(), nil
+	}
+
+	lw := pagedListWatch(list, watchFn, 2)
+	obj, err := lw.List(metav1.ListOptions{ResourceVersion: "0"})
+	if err != nil {
+		t.Fatalf("unexpected error listing: %v", err)
+	}
+
+	if requests != 2 {
+		t.Errorf("expected 2 requests but %v returned", requests)
+	}
+
+	items, err := meta.ExtractList(obj)
+	if err != nil {
+		t.Fatalf("unexpected error extracting the items: %v", err)
+	}
+	if len(items) != 3 {
+		t.Errorf("expected 3 items but %v returned", len(items))
+	}
+
+	m, err := meta.ListAccessor(obj)
+	if err != nil {
+		t.Fatalf("unexpected error reading the list metadata: %v", err)
+	}
+	if m.GetResourceVersion() != "10" {
+		t.Errorf("expected resource version 10 but %v returned", m.GetResourceVersion())
+	}
+}
+
+func TestUsePagedInformers(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&extensions.Ingress{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default"}},
+		&extensions.Ingress{ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "default"}},
+	)
+
+	factory := informers.NewSharedInformerFactoryWithOptions(client, 0, informers.WithNamespace("default"))
+	usePagedInformers(factory, client, "default", 1)
+
+	informer := factory.Extensions().V1beta1().Ingresses().Informer()
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go informer.Run(stopCh)
+
+	if !cache.WaitForCacheSync(stopCh, informer.HasSynced) {
+		t.Fatalf("timed out waiting for the cache to sync")
+	}
+
+	// fake clients return all the objects ignoring the limit
+	err := wait.Poll(10*time.Millisecond, time.Second, func() (bool, error) {
+		return len(informer.GetStore().List()) == 2, nil
+	})
+	if err != nil {
+		t.Errorf("expected 2 Ingresses in the store but %v returned", len(informer.GetStore().List()))
+	}
+}
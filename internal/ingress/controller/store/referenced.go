@@ -0,0 +1,252 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apiruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	"k8s.io/ingress-nginx/internal/log"
+)
+
+var (
+	// referencedSyncPeriod is the time between the reads of the
+	// referenced objects, to detect their changes
+	referencedSyncPeriod = time.Minute
+
+	// referencedReadTimeout is the maximum time waiting for the first
+	// read of an object started by another caller
+	referencedReadTimeout = 5 * time.Second
+)
+
+// referencedObject is the state of an object read by the controller
+type referencedObject struct {
+	// read is closed once the object is read the first time
+	read chan struct{}
+	// used is true when the object was read by the controller since the
+	// last sync
+	used bool
+}
+
+// referencedObjects keeps the objects of a kind, Secrets or ConfigMaps,
+// referenced by the Ingresses or the configuration of the controller,
+// instead of all the objects of the watched namespaces. Each object is read
+// with a single-item GET the first time the controller reads it, and read
+// again every referencedSyncPeriod to notify its changes to the handler.
+// The objects not referenced by any Ingress nor read since the previous
+// sync are released at the end of each sync.
+type referencedObjects struct {
+	mu sync.Mutex
+
+	// store contains the objects read, used by the listers
+	store cache.Store
+	// objects contains the state of the objects read by key
+	objects map[string]*referencedObject
+	// pinned contains the keys of the objects never released, like the
+	// configuration ConfigMap
+	pinned map[string]bool
+
+	// namespace restricts the objects read, all the namespaces when empty
+	namespace string
+	objType   apiruntime.Object
+	// get reads an object from the API server
+	get func(namespace, name string) (apiruntime.Object, error)
+	// isReferenced returns true if an Ingress references the object matching key
+	isReferenced func(key string) bool
+	handler      cache.ResourceEventHandler
+}
+
+// newReferencedObjects returns the referenced objects of a kind, whose
+// changes are notified to handler
+func newReferencedObjects(objType apiruntime.Object, namespace string,
+	get func(namespace, name string) (apiruntime.Object, error),
+	isReferenced func(key string) bool, handler cache.ResourceEventHandler) *referencedObjects {
+	return &referencedObjects{
+		store:        cache.NewStore(cache.DeletionHandlingMetaNamespaceKeyFunc),
+		objects:      make(map[string]*referencedObject),
+		pinned:       make(map[string]bool),
+		namespace:    namespace,
+		objType:      objType,
+		get:          get,
+		isReferenced: isReferenced,
+		handler:      handler,
+	}
+}
+
+// Track reads the object matching key the first time, and keeps it in sync
+// until it is released. The handler is not notified of the first read.
+func (r *referencedObjects) Track(key string) {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil || name == "" {
+		return
+	}
+	if r.namespace != "" && namespace != r.namespace {
+		// the objects of other namespaces are not available, as before
+		return
+	}
+
+	r.mu.Lock()
+	o, ok := r.objects[key]
+	if !ok {
+		o = &referencedObject{read: make(chan struct{})}
+		r.objects[key] = o
+	}
+	o.used = true
+	r.mu.Unlock()
+
+	if ok {
+		select {
+		case <-o.read:
+		case <-time.After(referencedReadTimeout):
+			log.Warningf("Timed out reading %T %v", r.objType, key)
+		}
+		return
+	}
+
+	log.V(3).Infof("Reading %T %v", r.objType, key)
+	obj, err := r.get(namespace, name)
+	switch {
+	case err == nil:
+		r.store.Add(obj)
+	case !apierrors.IsNotFound(err):
+		// read again in the next sync
+		log.Warningf("Error reading %T %v: %v", r.objType, key, err)
+	}
+
+	close(o.read)
+}
+
+// Pin reads the object matching key, notifying the handler, and never
+// releases it
+func (r *referencedObjects) Pin(key string) {
+	r.mu.Lock()
+	r.pinned[key] = true
+	r.mu.Unlock()
+
+	r.Track(key)
+
+	if obj, exists, _ := r.store.GetByKey(key); exists {
+		r.handler.OnAdd(obj)
+	}
+}
+
+// Release stops tracking the object matching key and removes it from the
+// store, unless it is pinned. The handler is not notified.
+func (r *referencedObjects) Release(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.objects[key]; !ok || r.pinned[key] {
+		return
+	}
+
+	log.V(3).Infof("Releasing %T %v, not referenced anymore", r.objType, key)
+	delete(r.objects, key)
+
+	if obj, exists, _ := r.store.GetByKey(key); exists {
+		r.store.Delete(obj)
+	}
+}
+
+// Run reads the tracked objects every referencedSyncPeriod until stopCh
+// is closed
+func (r *referencedObjects) Run(stopCh chan struct{}) {
+	ticker := time.NewTicker(referencedSyncPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.sync()
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// sync reads again the tracked objects, notifying their changes to the
+// handler, and then releases the ones not referenced anymore
+func (r *referencedObjects) sync() {
+	var keys, unreferenced []string
+
+	r.mu.Lock()
+	for key, o := range r.objects {
+		if !o.used && !r.pinned[key] && !r.isReferenced(key) {
+			unreferenced = append(unreferenced, key)
+			continue
+		}
+
+		o.used = false
+
+		select {
+		case <-o.read:
+			keys = append(keys, key)
+		default:
+			// the first read is in progress
+		}
+	}
+	r.mu.Unlock()
+
+	for _, key := range keys {
+		r.refresh(key)
+	}
+
+	for _, key := range unreferenced {
+		r.Release(key)
+	}
+}
+
+// refresh reads the object matching key and notifies the handler if it was
+// created, updated or deleted
+func (r *referencedObjects) refresh(key string) {
+	namespace, name, _ := cache.SplitMetaNamespaceKey(key)
+
+	obj, err := r.get(namespace, name)
+	if err != nil && !apierrors.IsNotFound(err) {
+		log.Warningf("Error reading %T %v: %v", r.objType, key, err)
+		return
+	}
+
+	old, exists, _ := r.store.GetByKey(key)
+
+	r.mu.Lock()
+	_, tracked := r.objects[key]
+	r.mu.Unlock()
+	if !tracked {
+		// released while it was read
+		return
+	}
+
+	switch {
+	case err != nil && exists:
+		r.store.Delete(old)
+		r.handler.OnDelete(old)
+	case err != nil:
+		// it still does not exist
+	case !exists:
+		r.store.Add(obj)
+		r.handler.OnAdd(obj)
+	case !reflect.DeepEqual(old, obj):
+		r.store.Update(obj)
+		r.handler.OnUpdate(old, obj)
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package store
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apiruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestReferencedObjects(t *testing.T) {
+	clientSet := fake.NewSimpleClientset(
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "a"}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "b"}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "c"}},
+	)
+
+	var add, update, del int
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { add++ },
+		UpdateFunc: func(old, cur interface{}) { update++ },
+		DeleteFunc: func(obj interface{}) { del++ },
+	}
+
+	referenced := map[string]bool{"default/d": true}
+	objects := newReferencedObjects(&corev1.ConfigMap{}, "default",
+		func(ns, name string) (apiruntime.Object, error) {
+			return clientSet.CoreV1().ConfigMaps(ns).Get(name, metav1.GetOptions{})
+		}, func(key string) bool { return referenced[key] }, handler)
+
+	exists := func(key string) bool {
+		_, ok, _ := objects.store.GetByKey(key)
+		return ok
+	}
+
+	objects.Track("default/a")
+	if !exists("default/a") {
+		t.Errorf("expected the ConfigMap default/a in the store")
+	}
+	if exists("default/b") {
+		t.Errorf("unexpected ConfigMap default/b in the store, it is not tracked")
+	}
+	if add != 0 {
+		t.Errorf("expected no events of type Add for the first read but %v occurred", add)
+	}
+
+	objects.Track("other/c")
+	if exists("other/c") {
+		t.Errorf("unexpected ConfigMap other/c in the store, its namespace is not watched")
+	}
+
+	objects.Track("default/d")
+	_, err := clientSet.CoreV1().ConfigMaps("default").Create(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "d"}})
+	if err != nil {
+		t.Fatalf("unexpected error creating the ConfigMap default/d: %v", err)
+	}
+	_, err = clientSet.CoreV1().ConfigMaps("default").Update(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "a"},
+		Data:       map[string]string{"key": "value"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error updating the ConfigMap default/a: %v", err)
+	}
+
+	objects.sync()
+	if !exists("default/d") || add != 1 {
+		t.Errorf("expected the ConfigMap default/d in the store and 1 event of type Add after its creation but %v occurred", add)
+	}
+	if update != 1 {
+		t.Errorf("expected 1 event of type Update but %v occurred", update)
+	}
+
+	objects.Pin("default/b")
+	if add != 2 {
+		t.Errorf("expected 1 event of type Add for the pinned ConfigMap default/b")
+	}
+
+	// the objects not read since the previous sync nor referenced are released
+	objects.sync()
+	if exists("default/a") {
+		t.Errorf("unexpected ConfigMap default/a in the store, it is not referenced")
+	}
+	if !exists("default/b") || !exists("default/d") {
+		t.Errorf("expected the pinned ConfigMap default/b and the referenced ConfigMap default/d in the store")
+	}
+
+	err = clientSet.CoreV1().ConfigMaps("default").Delete("d", &metav1.DeleteOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error deleting the ConfigMap default/d: %v", err)
+	}
+	objects.sync()
+	if exists("default/d") || del != 1 {
+		t.Errorf("expected the ConfigMap default/d deleted from the store and 1 event of type Delete but %v occurred", del)
+	}
+
+	objects.Release("default/d")
+	objects.Release("default/b")
+	if !exists("default/b") {
+		t.Errorf("expected the pinned ConfigMap default/b in the store after its release")
+	}
+
+	for _, action := range clientSet.Actions() {
+		if action.GetResource().Resource == "configmaps" && (action.GetVerb() == "list" || action.GetVerb() == "watch") {
+			t.Errorf("unexpected %v of configmaps, only single-item reads are expected", action.GetVerb())
+		}
+	}
+}
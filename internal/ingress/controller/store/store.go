@@ -25,14 +25,17 @@ import (
 	"time"
 
 	"github.com/eapache/channels"
-	"github.com/golang/glog"
 
 	corev1 "k8s.io/api/core/v1"
 	extensions "k8s.io/api/extensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	apiruntime "k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/informers"
+	coreinformers "k8s.io/client-go/informers/core/v1"
 	clientset "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	clientcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
@@ -50,6 +53,7 @@ import (
 	"k8s.io/ingress-nginx/internal/ingress/errors"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
 	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/internal/log"
 )
 
 // Storer is the interface that wraps the required methods to gather information
@@ -70,12 +74,19 @@ type Storer interface {
 	// GetServiceEndpoints returns the Endpoints of a Service matching key.
 	GetServiceEndpoints(key string) (*corev1.Endpoints, error)
 
+	// GetPod returns the Pod matching key.
+	GetPod(key string) (*corev1.Pod, error)
+
 	// GetIngress returns the Ingress matching key.
 	GetIngress(key string) (*extensions.Ingress, error)
 
 	// ListIngresses returns a list of all Ingresses in the store.
 	ListIngresses() []*extensions.Ingress
 
+	// ListIngressesOfOtherClasses returns a list of the Ingresses in the store
+	// ignored because of the ingress class.
+	ListIngressesOfOtherClasses() []*extensions.Ingress
+
 	// GetIngressAnnotations returns the parsed annotations of an Ingress matching key.
 	GetIngressAnnotations(key string) (*annotations.Ingress, error)
 
@@ -118,12 +129,15 @@ type Event struct {
 }
 
 // Informer defines the required SharedIndexInformers that interact with the API server.
+// The Secrets and ConfigMaps are not listed, only the ones referenced are read.
 type Informer struct {
-	Ingress   cache.SharedIndexInformer
-	Endpoint  cache.SharedIndexInformer
-	Service   cache.SharedIndexInformer
-	Secret    cache.SharedIndexInformer
-	ConfigMap cache.SharedIndexInformer
+	Ingress  cache.SharedIndexInformer
+	Endpoint cache.SharedIndexInformer
+	Service  cache.SharedIndexInformer
+	// Pod is only used to read the weight of the endpoints
+	Pod cache.SharedIndexInformer
+	// Namespace is only used with a namespace selector
+	Namespace cache.SharedIndexInformer
 }
 
 // Lister contains object listers (stores).
@@ -133,6 +147,8 @@ type Lister struct {
 	Endpoint          EndpointLister
 	Secret            SecretLister
 	ConfigMap         ConfigMapLister
+	Pod               PodLister
+	Namespace         NamespaceLister
 	IngressAnnotation IngressAnnotationsLister
 }
 
@@ -146,22 +162,33 @@ func (e NotExistsError) Error() string {
 
 // Run initiates the synchronization of the informers against the API server.
 func (i *Informer) Run(stopCh chan struct{}) {
+	// the namespaces are read before the ingresses they contain
+	if i.Namespace != nil {
+		go i.Namespace.Run(stopCh)
+		if !cache.WaitForCacheSync(stopCh, i.Namespace.HasSynced) {
+			runtime.HandleError(fmt.Errorf("Timed out waiting for caches to sync"))
+		}
+	}
+
 	go i.Endpoint.Run(stopCh)
 	go i.Service.Run(stopCh)
-	go i.Secret.Run(stopCh)
-	go i.ConfigMap.Run(stopCh)
 
 	// wait for all involved caches to be synced before processing items
 	// from the queue
 	if !cache.WaitForCacheSync(stopCh,
 		i.Endpoint.HasSynced,
 		i.Service.HasSynced,
-		i.Secret.HasSynced,
-		i.ConfigMap.HasSynced,
 	) {
 		runtime.HandleError(fmt.Errorf("Timed out waiting for caches to sync"))
 	}
 
+	if i.Pod != nil {
+		go i.Pod.Run(stopCh)
+		if !cache.WaitForCacheSync(stopCh, i.Pod.HasSynced) {
+			runtime.HandleError(fmt.Errorf("Timed out waiting for caches to sync"))
+		}
+	}
+
 	// in big clusters, deltas can keep arriving even after HasSynced
 	// functions have returned 'true'
 	time.Sleep(1 * time.Second)
@@ -203,6 +230,26 @@ type k8sStore struct {
 	// secret in the annotations.
 	secretIngressMap ObjectRefMap
 
+	// configMapIngressMap contains information about which ingress references
+	// a configmap in the annotations.
+	configMapIngressMap ObjectRefMap
+
+	// serviceIngressMap contains information about which ingress references
+	// a service in the annotations.
+	serviceIngressMap ObjectRefMap
+
+	// secrets and configMaps watch the objects referenced by the ingresses
+	// and the configuration, instead of all the objects
+	secrets    *referencedObjects
+	configMaps *referencedObjects
+
+	// configmap is the key of the configuration ConfigMap
+	configmap string
+
+	// namespaceSelector restricts the ingresses to the namespaces matching
+	// it, all the namespaces when nil
+	namespaceSelector labels.Selector
+
 	filesystem file.Filesystem
 
 	// updateCh
@@ -214,16 +261,23 @@ type k8sStore struct {
 	defaultSSLCertificate string
 
 	isDynamicCertificatesEnabled bool
+
+	// profile contains the defaults used when the ConfigMap does not define a value
+	profile string
 }
 
 // New creates a new object store to be used in the ingress controller
 func New(checkOCSP bool,
-	namespace, configmap, defaultSSLCertificate string,
+	namespace string, namespaceSelector labels.Selector,
+	configmap, defaultSSLCertificate string,
 	resyncPeriod time.Duration,
 	client clientset.Interface,
 	fs file.Filesystem,
 	updateCh *channels.RingChannel,
-	isDynamicCertificatesEnabled bool) Storer {
+	isDynamicCertificatesEnabled bool,
+	profile string,
+	endpointWeightAnnotation string,
+	listPageSize int64) Storer {
 
 	store := &k8sStore{
 		isOCSPCheckEnabled:           checkOCSP,
@@ -232,15 +286,20 @@ func New(checkOCSP bool,
 		sslStore:                     NewSSLCertTracker(),
 		filesystem:                   fs,
 		updateCh:                     updateCh,
-		backendConfig:                ngx_config.NewDefault(),
+		backendConfig:                ngx_template.ReadConfig(ngx_config.ApplyProfile(profile, map[string]string{})),
 		mu:                           &sync.Mutex{},
 		secretIngressMap:             NewObjectRefMap(),
+		configMapIngressMap:          NewObjectRefMap(),
+		serviceIngressMap:            NewObjectRefMap(),
+		configmap:                    configmap,
+		namespaceSelector:            namespaceSelector,
 		defaultSSLCertificate:        defaultSSLCertificate,
 		isDynamicCertificatesEnabled: isDynamicCertificatesEnabled,
+		profile:                      profile,
 	}
 
 	eventBroadcaster := record.NewBroadcaster()
-	eventBroadcaster.StartLogging(glog.Infof)
+	eventBroadcaster.StartLogging(log.Infof)
 	eventBroadcaster.StartRecordingToSink(&clientcorev1.EventSinkImpl{
 		Interface: client.CoreV1().Events(namespace),
 	})
@@ -258,18 +317,16 @@ func New(checkOCSP bool,
 		informers.WithNamespace(namespace),
 		informers.WithTweakListOptions(func(*metav1.ListOptions) {}))
 
+	if listPageSize > 0 {
+		usePagedInformers(infFactory, client, namespace, listPageSize)
+	}
+
 	store.informers.Ingress = infFactory.Extensions().V1beta1().Ingresses().Informer()
 	store.listers.Ingress.Store = store.informers.Ingress.GetStore()
 
 	store.informers.Endpoint = infFactory.Core().V1().Endpoints().Informer()
 	store.listers.Endpoint.Store = store.informers.Endpoint.GetStore()
 
-	store.informers.Secret = infFactory.Core().V1().Secrets().Informer()
-	store.listers.Secret.Store = store.informers.Secret.GetStore()
-
-	store.informers.ConfigMap = infFactory.Core().V1().ConfigMaps().Informer()
-	store.listers.ConfigMap.Store = store.informers.ConfigMap.GetStore()
-
 	store.informers.Service = infFactory.Core().V1().Services().Informer()
 	store.listers.Service.Store = store.informers.Service.GetStore()
 
@@ -278,12 +335,18 @@ func New(checkOCSP bool,
 			ing := obj.(*extensions.Ingress)
 			if !class.IsValid(ing) {
 				a, _ := parser.GetStringAnnotation(class.IngressKey, ing)
-				glog.Infof("ignoring add for ingress %v based on annotation %v with value %v", ing.Name, class.IngressKey, a)
+				log.Infof("ignoring add for ingress %v based on annotation %v with value %v", ing.Name, class.IngressKey, a)
+				return
+			}
+			if !store.isNamespaceWatched(ing.Namespace) {
+				log.V(3).Infof("ignoring add for ingress %v, namespace %v does not match the namespace selector", ing.Name, ing.Namespace)
 				return
 			}
 			recorder.Eventf(ing, corev1.EventTypeNormal, "CREATE", fmt.Sprintf("Ingress %s/%s", ing.Namespace, ing.Name))
 
-			store.extractAnnotations(ing)
+			store.updateConfigMapIngressMap(ing)
+			store.updateServiceIngressMap(ing)
+			store.updateIngressAnnotations(ing)
 			store.updateSecretIngressMap(ing)
 			store.syncSecrets(ing)
 
@@ -298,17 +361,17 @@ func New(checkOCSP bool,
 				// If we reached here it means the ingress was deleted but its final state is unrecorded.
 				tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
 				if !ok {
-					glog.Errorf("couldn't get object from tombstone %#v", obj)
+					log.Errorf("couldn't get object from tombstone %#v", obj)
 					return
 				}
 				ing, ok = tombstone.Obj.(*extensions.Ingress)
 				if !ok {
-					glog.Errorf("Tombstone contained object that is not an Ingress: %#v", obj)
+					log.Errorf("Tombstone contained object that is not an Ingress: %#v", obj)
 					return
 				}
 			}
 			if !class.IsValid(ing) {
-				glog.Infof("ignoring delete for ingress %v based on annotation %v", ing.Name, class.IngressKey)
+				log.Infof("ignoring delete for ingress %v based on annotation %v", ing.Name, class.IngressKey)
 				return
 			}
 			recorder.Eventf(ing, corev1.EventTypeNormal, "DELETE", fmt.Sprintf("Ingress %s/%s", ing.Namespace, ing.Name))
@@ -316,7 +379,13 @@ func New(checkOCSP bool,
 			store.listers.IngressAnnotation.Delete(ing)
 
 			key := k8s.MetaNamespaceKey(ing)
+			secrets := store.secretIngressMap.ReferencedBy(key)
+			configMaps := store.configMapIngressMap.ReferencedBy(key)
 			store.secretIngressMap.Delete(key)
+			store.configMapIngressMap.Delete(key)
+			store.serviceIngressMap.Delete(key)
+			releaseUnreferenced(store.secrets, store.secretIngressMap, secrets)
+			releaseUnreferenced(store.configMaps, store.configMapIngressMap, configMaps)
 
 			updateCh.In() <- Event{
 				Type: DeleteEvent,
@@ -326,19 +395,31 @@ func New(checkOCSP bool,
 		UpdateFunc: func(old, cur interface{}) {
 			oldIng := old.(*extensions.Ingress)
 			curIng := cur.(*extensions.Ingress)
+			if !store.isNamespaceWatched(curIng.Namespace) {
+				return
+			}
+
+			if onlyEffectiveConfigChanged(oldIng, curIng) {
+				log.V(3).Infof("ignoring update of the effective configuration of ingress %v/%v", curIng.Namespace, curIng.Name)
+				store.updateAnnotationsVersion(curIng)
+				return
+			}
+
 			validOld := class.IsValid(oldIng)
 			validCur := class.IsValid(curIng)
 			if !validOld && validCur {
-				glog.Infof("creating ingress %v based on annotation %v", curIng.Name, class.IngressKey)
+				log.Infof("creating ingress %v based on annotation %v", curIng.Name, class.IngressKey)
 				recorder.Eventf(curIng, corev1.EventTypeNormal, "CREATE", fmt.Sprintf("Ingress %s/%s", curIng.Namespace, curIng.Name))
 			} else if validOld && !validCur {
-				glog.Infof("removing ingress %v based on annotation %v", curIng.Name, class.IngressKey)
+				log.Infof("removing ingress %v based on annotation %v", curIng.Name, class.IngressKey)
 				recorder.Eventf(curIng, corev1.EventTypeNormal, "DELETE", fmt.Sprintf("Ingress %s/%s", curIng.Namespace, curIng.Name))
 			} else if validCur && !reflect.DeepEqual(old, cur) {
 				recorder.Eventf(curIng, corev1.EventTypeNormal, "UPDATE", fmt.Sprintf("Ingress %s/%s", curIng.Namespace, curIng.Name))
 			}
 
-			store.extractAnnotations(curIng)
+			store.updateConfigMapIngressMap(curIng)
+			store.updateServiceIngressMap(curIng)
+			store.updateIngressAnnotations(curIng)
 			store.updateSecretIngressMap(curIng)
 			store.syncSecrets(curIng)
 
@@ -360,11 +441,11 @@ func New(checkOCSP bool,
 
 			// find references in ingresses and update local ssl certs
 			if ings := store.secretIngressMap.Reference(key); len(ings) > 0 {
-				glog.Infof("secret %v was added and it is used in ingress annotations. Parsing...", key)
+				log.Infof("secret %v was added and it is used in ingress annotations. Parsing...", key)
 				for _, ingKey := range ings {
 					ing, err := store.GetIngress(ingKey)
 					if err != nil {
-						glog.Errorf("could not find Ingress %v in local store", ingKey)
+						log.Errorf("could not find Ingress %v in local store", ingKey)
 						continue
 					}
 					store.extractAnnotations(ing)
@@ -387,11 +468,11 @@ func New(checkOCSP bool,
 
 				// find references in ingresses and update local ssl certs
 				if ings := store.secretIngressMap.Reference(key); len(ings) > 0 {
-					glog.Infof("secret %v was updated and it is used in ingress annotations. Parsing...", key)
+					log.Infof("secret %v was updated and it is used in ingress annotations. Parsing...", key)
 					for _, ingKey := range ings {
 						ing, err := store.GetIngress(ingKey)
 						if err != nil {
-							glog.Errorf("could not find Ingress %v in local store", ingKey)
+							log.Errorf("could not find Ingress %v in local store", ingKey)
 							continue
 						}
 						store.extractAnnotations(ing)
@@ -410,12 +491,12 @@ func New(checkOCSP bool,
 				// If we reached here it means the secret was deleted but its final state is unrecorded.
 				tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
 				if !ok {
-					glog.Errorf("couldn't get object from tombstone %#v", obj)
+					log.Errorf("couldn't get object from tombstone %#v", obj)
 					return
 				}
 				sec, ok = tombstone.Obj.(*corev1.Secret)
 				if !ok {
-					glog.Errorf("Tombstone contained object that is not a Secret: %#v", obj)
+					log.Errorf("Tombstone contained object that is not a Secret: %#v", obj)
 					return
 				}
 			}
@@ -426,11 +507,11 @@ func New(checkOCSP bool,
 
 			// find references in ingresses
 			if ings := store.secretIngressMap.Reference(key); len(ings) > 0 {
-				glog.Infof("secret %v was deleted and it is used in ingress annotations. Parsing...", key)
+				log.Infof("secret %v was deleted and it is used in ingress annotations. Parsing...", key)
 				for _, ingKey := range ings {
 					ing, err := store.GetIngress(ingKey)
 					if err != nil {
-						glog.Errorf("could not find Ingress %v in local store", ingKey)
+						log.Errorf("could not find Ingress %v in local store", ingKey)
 						continue
 					}
 					store.extractAnnotations(ing)
@@ -483,6 +564,16 @@ func New(checkOCSP bool,
 					Obj:  obj,
 				}
 			}
+
+			// find references in ingresses
+			if ings := store.configMapIngressMap.Reference(key); len(ings) > 0 {
+				log.Infof("configmap %v was added and it is used in ingress annotations. Parsing...", key)
+				store.extractReferencedAnnotations(ings)
+				updateCh.In() <- Event{
+					Type: CreateEvent,
+					Obj:  obj,
+				}
+			}
 		},
 		UpdateFunc: func(old, cur interface{}) {
 			if !reflect.DeepEqual(old, cur) {
@@ -500,7 +591,7 @@ func New(checkOCSP bool,
 						key := k8s.MetaNamespaceKey(ingKey)
 						ing, err := store.GetIngress(key)
 						if err != nil {
-							glog.Errorf("could not find Ingress %v in local store: %v", key, err)
+							log.Errorf("could not find Ingress %v in local store: %v", key, err)
 							continue
 						}
 						store.extractAnnotations(ing)
@@ -511,38 +602,338 @@ func New(checkOCSP bool,
 						Obj:  cur,
 					}
 				}
+
+				// find references in ingresses
+				if ings := store.configMapIngressMap.Reference(key); len(ings) > 0 {
+					log.Infof("configmap %v was updated and it is used in ingress annotations. Parsing...", key)
+					store.extractReferencedAnnotations(ings)
+					updateCh.In() <- Event{
+						Type: UpdateEvent,
+						Obj:  cur,
+					}
+				}
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			cm, ok := obj.(*corev1.ConfigMap)
+			if !ok {
+				// If we reached here it means the configmap was deleted but its final state is unrecorded.
+				tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+				if !ok {
+					log.Errorf("couldn't get object from tombstone %#v", obj)
+					return
+				}
+				cm, ok = tombstone.Obj.(*corev1.ConfigMap)
+				if !ok {
+					log.Errorf("Tombstone contained object that is not a ConfigMap: %#v", obj)
+					return
+				}
+			}
+
+			key := k8s.MetaNamespaceKey(cm)
+
+			// find references in ingresses
+			if ings := store.configMapIngressMap.Reference(key); len(ings) > 0 {
+				log.Infof("configmap %v was deleted and it is used in ingress annotations. Parsing...", key)
+				store.extractReferencedAnnotations(ings)
+				updateCh.In() <- Event{
+					Type: DeleteEvent,
+					Obj:  obj,
+				}
 			}
 		},
 	}
 
 	store.informers.Ingress.AddEventHandler(ingEventHandler)
 	store.informers.Endpoint.AddEventHandler(epEventHandler)
-	store.informers.Secret.AddEventHandler(secrEventHandler)
-	store.informers.ConfigMap.AddEventHandler(cmEventHandler)
-	store.informers.Service.AddEventHandler(cache.ResourceEventHandlerFuncs{})
+
+	store.secrets = newReferencedObjects(&corev1.Secret{}, namespace,
+		func(ns, name string) (apiruntime.Object, error) {
+			return client.CoreV1().Secrets(ns).Get(name, metav1.GetOptions{})
+		}, store.secretIngressMap.Has, secrEventHandler)
+	store.listers.Secret.Store = store.secrets.store
+
+	store.configMaps = newReferencedObjects(&corev1.ConfigMap{}, namespace,
+		func(ns, name string) (apiruntime.Object, error) {
+			return client.CoreV1().ConfigMaps(ns).Get(name, metav1.GetOptions{})
+		}, store.configMapIngressMap.Has, cmEventHandler)
+	store.listers.ConfigMap.Store = store.configMaps.store
+
+	// the annotations referencing a service are parsed again when it changes,
+	// the periodic resyncs do not extract the annotations of the ingresses
+	store.informers.Service.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			svc := obj.(*corev1.Service)
+			key := k8s.MetaNamespaceKey(svc)
+			if ings := store.serviceIngressMap.Reference(key); len(ings) > 0 {
+				log.Infof("service %v was added and it is used in ingress annotations. Parsing...", key)
+				store.extractReferencedAnnotations(ings)
+				updateCh.In() <- Event{
+					Type: CreateEvent,
+					Obj:  obj,
+				}
+			}
+		},
+		UpdateFunc: func(old, cur interface{}) {
+			if reflect.DeepEqual(old, cur) {
+				return
+			}
+
+			svc := cur.(*corev1.Service)
+			key := k8s.MetaNamespaceKey(svc)
+			if ings := store.serviceIngressMap.Reference(key); len(ings) > 0 {
+				log.Infof("service %v was updated and it is used in ingress annotations. Parsing...", key)
+				store.extractReferencedAnnotations(ings)
+				updateCh.In() <- Event{
+					Type: UpdateEvent,
+					Obj:  cur,
+				}
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			svc, ok := obj.(*corev1.Service)
+			if !ok {
+				// If we reached here it means the service was deleted but its final state is unrecorded.
+				tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+				if !ok {
+					log.Errorf("couldn't get object from tombstone %#v", obj)
+					return
+				}
+				svc, ok = tombstone.Obj.(*corev1.Service)
+				if !ok {
+					log.Errorf("Tombstone contained object that is not a Service: %#v", obj)
+					return
+				}
+			}
+
+			key := k8s.MetaNamespaceKey(svc)
+			if ings := store.serviceIngressMap.Reference(key); len(ings) > 0 {
+				log.Infof("service %v was deleted and it is used in ingress annotations. Parsing...", key)
+				store.extractReferencedAnnotations(ings)
+				updateCh.In() <- Event{
+					Type: DeleteEvent,
+					Obj:  obj,
+				}
+			}
+		},
+	})
+
+	store.listers.Pod.Store = cache.NewStore(cache.MetaNamespaceKeyFunc)
+	if endpointWeightAnnotation != "" {
+		store.informers.Pod = infFactory.Core().V1().Pods().Informer()
+		store.listers.Pod.Store = store.informers.Pod.GetStore()
+		store.informers.Pod.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			UpdateFunc: func(old, cur interface{}) {
+				oldPod := old.(*corev1.Pod)
+				curPod := cur.(*corev1.Pod)
+				if oldPod.Annotations[endpointWeightAnnotation] != curPod.Annotations[endpointWeightAnnotation] {
+					updateCh.In() <- Event{
+						Type: UpdateEvent,
+						Obj:  cur,
+					}
+				}
+			},
+		})
+	}
+
+	if namespaceSelector != nil {
+		store.informers.Namespace = coreinformers.NewFilteredNamespaceInformer(client, resyncPeriod, cache.Indexers{},
+			func(options *metav1.ListOptions) {
+				options.LabelSelector = namespaceSelector.String()
+				if namespace != "" {
+					options.FieldSelector = fields.OneTermEqualSelector("metadata.name", namespace).String()
+				}
+			})
+		store.listers.Namespace.Store = store.informers.Namespace.GetStore()
+		// the ingresses of a namespace are added when it starts matching
+		// the selector, and deleted when it does not match anymore
+		store.informers.Namespace.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				ns := obj.(*corev1.Namespace)
+				log.Infof("namespace %v matches the namespace selector", ns.Name)
+				for _, ing := range store.namespaceIngresses(ns.Name) {
+					ingEventHandler.OnAdd(ing)
+				}
+			},
+			DeleteFunc: func(obj interface{}) {
+				name, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+				if err != nil {
+					log.Errorf("couldn't get the key of the namespace %#v: %v", obj, err)
+					return
+				}
+				log.Infof("namespace %v does not match the namespace selector anymore", name)
+				for _, ing := range store.namespaceIngresses(name) {
+					ingEventHandler.OnDelete(ing)
+				}
+			},
+		})
+	}
 
 	// do not wait for informers to read the configmap configuration
 	ns, name, _ := k8s.ParseNameNS(configmap)
 	cm, err := client.CoreV1().ConfigMaps(ns).Get(name, metav1.GetOptions{})
 	if err != nil {
-		glog.Warningf("Unexpected error reading configuration configmap: %v", err)
+		log.Warningf("Unexpected error reading configuration configmap: %v", err)
 	}
 
 	store.setConfig(cm)
 	return store
 }
 
+// isNamespaceWatched returns true if the ingresses of a namespace are
+// watched, i.e. it matches the namespace selector
+func (s k8sStore) isNamespaceWatched(namespace string) bool {
+	if s.namespaceSelector == nil {
+		return true
+	}
+
+	_, err := s.listers.Namespace.ByKey(namespace)
+	return err == nil
+}
+
+// namespaceIngresses returns the ingresses of a namespace in the local store
+func (s k8sStore) namespaceIngresses(namespace string) []*extensions.Ingress {
+	items, err := s.informers.Ingress.GetIndexer().ByIndex(cache.NamespaceIndex, namespace)
+	if err != nil {
+		log.Errorf("unexpected error listing the ingresses of namespace %v: %v", namespace, err)
+		return nil
+	}
+
+	ingresses := make([]*extensions.Ingress, 0, len(items))
+	for _, item := range items {
+		ingresses = append(ingresses, item.(*extensions.Ingress))
+	}
+
+	return ingresses
+}
+
 // extractAnnotations parses ingress annotations converting the value of the
 // annotation to a go struct and also information about the referenced secrets
 func (s *k8sStore) extractAnnotations(ing *extensions.Ingress) {
 	key := k8s.MetaNamespaceKey(ing)
-	glog.V(3).Infof("updating annotations information for ingress %v", key)
+	log.V(3).Infof("updating annotations information for ingress %v", key)
 
 	anns := s.annotations.Extract(ing)
 
 	err := s.listers.IngressAnnotation.Update(anns)
 	if err != nil {
-		glog.Error(err)
+		log.Error(err)
+	}
+}
+
+// updateIngressAnnotations extracts the annotations of an Ingress unless
+// they were already extracted from the same version of the Ingress, like in
+// the periodic resyncs. When a secret, configmap or service referenced by
+// the Ingress changes, extractAnnotations parses them again.
+func (s *k8sStore) updateIngressAnnotations(ing *extensions.Ingress) {
+	key := k8s.MetaNamespaceKey(ing)
+
+	cur, err := s.listers.IngressAnnotation.ByKey(key)
+	if err == nil && ing.ResourceVersion != "" &&
+		cur.UID == ing.UID && cur.ResourceVersion == ing.ResourceVersion {
+		log.V(3).Infof("annotations of ingress %v already extracted from resource version %v", key, ing.ResourceVersion)
+		return
+	}
+
+	s.extractAnnotations(ing)
+}
+
+// updateAnnotationsVersion sets the version of an Ingress in its parsed
+// annotations, when the change of version does not require to parse them again
+func (s *k8sStore) updateAnnotationsVersion(ing *extensions.Ingress) {
+	cur, err := s.listers.IngressAnnotation.ByKey(k8s.MetaNamespaceKey(ing))
+	if err != nil || cur.UID != ing.UID {
+		return
+	}
+
+	anns := *cur
+	anns.ObjectMeta = ing.ObjectMeta
+	err = s.listers.IngressAnnotation.Update(&anns)
+	if err != nil {
+		log.Error(err)
+	}
+}
+
+// onlyEffectiveConfigChanged returns true if the only change between two
+// versions of an Ingress is the effective configuration annotation, written
+// by the controller
+func onlyEffectiveConfigChanged(old, cur *extensions.Ingress) bool {
+	key := parser.GetAnnotationWithPrefix(annotations.EffectiveConfigAnnotation)
+	if old.Annotations[key] == cur.Annotations[key] {
+		return false
+	}
+
+	oldCopy := old.DeepCopy()
+	curCopy := cur.DeepCopy()
+	for _, ing := range []*extensions.Ingress{oldCopy, curCopy} {
+		delete(ing.Annotations, key)
+		if len(ing.Annotations) == 0 {
+			ing.Annotations = nil
+		}
+		ing.ResourceVersion = ""
+	}
+
+	return reflect.DeepEqual(oldCopy, curCopy)
+}
+
+// extractReferencedAnnotations parses again the annotations of the
+// Ingresses that reference an object that was created, updated or deleted
+func (s *k8sStore) extractReferencedAnnotations(ingKeys []string) {
+	for _, ingKey := range ingKeys {
+		ing, err := s.GetIngress(ingKey)
+		if err != nil {
+			log.Errorf("could not find Ingress %v in local store", ingKey)
+			continue
+		}
+		s.extractAnnotations(ing)
+	}
+}
+
+// updateConfigMapIngressMap takes an Ingress and updates all ConfigMap objects it
+// references in configMapIngressMap.
+func (s *k8sStore) updateConfigMapIngressMap(ing *extensions.Ingress) {
+	key := k8s.MetaNamespaceKey(ing)
+	log.V(3).Infof("updating references to configmaps for ingress %v", key)
+
+	// delete all existing references first
+	previous := s.configMapIngressMap.ReferencedBy(key)
+	s.configMapIngressMap.Delete(key)
+
+	var refConfigMaps []string
+
+	configMapAnnotations := []string{
+		"whitelist-source-range-configmap",
+		"fastcgi-params-configmap",
+	}
+	for _, ann := range configMapAnnotations {
+		cmKey, err := objectRefAnnotationNsKey(ann, ing)
+		if err != nil && !errors.IsMissingAnnotations(err) {
+			log.Errorf("error reading configmap reference in annotation %q: %s", ann, err)
+			continue
+		}
+		if cmKey != "" {
+			refConfigMaps = append(refConfigMaps, cmKey)
+		}
+	}
+
+	// populate map with all configmap references
+	s.configMapIngressMap.Insert(key, refConfigMaps...)
+	releaseUnreferenced(s.configMaps, s.configMapIngressMap, previous)
+}
+
+// updateServiceIngressMap takes an Ingress and updates the Service objects it
+// references in serviceIngressMap.
+func (s *k8sStore) updateServiceIngressMap(ing *extensions.Ingress) {
+	key := k8s.MetaNamespaceKey(ing)
+	log.V(3).Infof("updating references to services for ingress %v", key)
+
+	// delete all existing references first
+	s.serviceIngressMap.Delete(key)
+
+	// the default backend is always located in the namespace of the ingress
+	name, _ := parser.GetStringAnnotation("default-backend", ing)
+	if name != "" {
+		s.serviceIngressMap.Insert(key, fmt.Sprintf("%v/%v", ing.Namespace, name))
 	}
 }
 
@@ -550,9 +941,10 @@ func (s *k8sStore) extractAnnotations(ing *extensions.Ingress) {
 // references in secretIngressMap.
 func (s *k8sStore) updateSecretIngressMap(ing *extensions.Ingress) {
 	key := k8s.MetaNamespaceKey(ing)
-	glog.V(3).Infof("updating references to secrets for ingress %v", key)
+	log.V(3).Infof("updating references to secrets for ingress %v", key)
 
 	// delete all existing references first
+	previous := s.secretIngressMap.ReferencedBy(key)
 	s.secretIngressMap.Delete(key)
 
 	var refSecrets []string
@@ -571,12 +963,14 @@ func (s *k8sStore) updateSecretIngressMap(ing *extensions.Ingress) {
 	// references it would not trigger a resync of that secret.
 	secretAnnotations := []string{
 		"auth-secret",
+		"auth-oidc-secret",
 		"auth-tls-secret",
+		"proxy-ssl-secret",
 	}
 	for _, ann := range secretAnnotations {
 		secrKey, err := objectRefAnnotationNsKey(ann, ing)
 		if err != nil && !errors.IsMissingAnnotations(err) {
-			glog.Errorf("error reading secret reference in annotation %q: %s", ann, err)
+			log.Errorf("error reading secret reference in annotation %q: %s", ann, err)
 			continue
 		}
 		if secrKey != "" {
@@ -586,6 +980,21 @@ func (s *k8sStore) updateSecretIngressMap(ing *extensions.Ingress) {
 
 	// populate map with all secret references
 	s.secretIngressMap.Insert(key, refSecrets...)
+	releaseUnreferenced(s.secrets, s.secretIngressMap, previous)
+}
+
+// releaseUnreferenced stops tracking the objects matching keys that are
+// not referenced anymore by any Ingress
+func releaseUnreferenced(objects *referencedObjects, refs ObjectRefMap, keys []string) {
+	if objects == nil {
+		return
+	}
+
+	for _, key := range keys {
+		if !refs.Has(key) {
+			objects.Release(key)
+		}
+	}
 }
 
 // objectRefAnnotationNsKey returns an object reference formatted as a
@@ -616,8 +1025,13 @@ func (s k8sStore) syncSecrets(ing *extensions.Ingress) {
 	}
 }
 
-// GetSecret returns the Secret matching key.
+// GetSecret returns the Secret matching key. The Secret is read from the
+// API server the first time, and kept in sync from now on.
 func (s k8sStore) GetSecret(key string) (*corev1.Secret, error) {
+	if s.secrets != nil {
+		s.secrets.Track(key)
+	}
+
 	return s.listers.Secret.ByKey(key)
 }
 
@@ -649,7 +1063,7 @@ func (s k8sStore) ListIngresses() []*extensions.Ingress {
 	var ingresses []*extensions.Ingress
 	for _, item := range s.listers.Ingress.List() {
 		ing := item.(*extensions.Ingress)
-		if !class.IsValid(ing) {
+		if !class.IsValid(ing) || !s.isNamespaceWatched(ing.Namespace) {
 			continue
 		}
 
@@ -671,6 +1085,21 @@ func (s k8sStore) ListIngresses() []*extensions.Ingress {
 	return ingresses
 }
 
+// ListIngressesOfOtherClasses returns the list of Ingresses ignored because of the ingress class
+func (s k8sStore) ListIngressesOfOtherClasses() []*extensions.Ingress {
+	var ingresses []*extensions.Ingress
+	for _, item := range s.listers.Ingress.List() {
+		ing := item.(*extensions.Ingress)
+		if class.IsValid(ing) || !s.isNamespaceWatched(ing.Namespace) {
+			continue
+		}
+
+		ingresses = append(ingresses, ing)
+	}
+
+	return ingresses
+}
+
 // GetIngressAnnotations returns the parsed annotations of an Ingress matching key.
 func (s k8sStore) GetIngressAnnotations(key string) (*annotations.Ingress, error) {
 	ia, err := s.listers.IngressAnnotation.ByKey(key)
@@ -686,8 +1115,13 @@ func (s k8sStore) GetLocalSSLCert(key string) (*ingress.SSLCert, error) {
 	return s.sslStore.ByKey(key)
 }
 
-// GetConfigMap returns the ConfigMap matching key.
+// GetConfigMap returns the ConfigMap matching key. The ConfigMap is read
+// from the API server the first time, and kept in sync from now on.
 func (s k8sStore) GetConfigMap(key string) (*corev1.ConfigMap, error) {
+	if s.configMaps != nil {
+		s.configMaps.Track(key)
+	}
+
 	return s.listers.ConfigMap.ByKey(key)
 }
 
@@ -696,6 +1130,11 @@ func (s k8sStore) GetServiceEndpoints(key string) (*corev1.Endpoints, error) {
 	return s.listers.Endpoint.ByKey(key)
 }
 
+// GetPod returns the Pod matching key
+func (s k8sStore) GetPod(key string) (*corev1.Pod, error) {
+	return s.listers.Pod.ByKey(key)
+}
+
 // GetAuthCertificate is used by the auth-tls annotations to get a cert from a secret
 func (s k8sStore) GetAuthCertificate(name string) (*resolver.AuthSSLCert, error) {
 	if _, err := s.GetLocalSSLCert(name); err != nil {
@@ -723,18 +1162,18 @@ func (s k8sStore) writeSSLSessionTicketKey(cmap *corev1.ConfigMap, fileName stri
 
 		// 81 used instead of 80 because of padding
 		if !(ticketBytes == 48 || ticketBytes == 81) {
-			glog.Warningf("ssl-session-ticket-key must contain either 48 or 80 bytes")
+			log.Warningf("ssl-session-ticket-key must contain either 48 or 80 bytes")
 		}
 
 		decodedTicket, err := base64.StdEncoding.DecodeString(ticketString)
 		if err != nil {
-			glog.Errorf("unexpected error decoding ssl-session-ticket-key: %v", err)
+			log.Errorf("unexpected error decoding ssl-session-ticket-key: %v", err)
 			return
 		}
 
 		err = ioutil.WriteFile(fileName, decodedTicket, file.ReadWriteByUser)
 		if err != nil {
-			glog.Errorf("unexpected error writing ssl-session-ticket-key to %s: %v", fileName, err)
+			log.Errorf("unexpected error writing ssl-session-ticket-key to %s: %v", fileName, err)
 			return
 		}
 
@@ -752,13 +1191,23 @@ func (s k8sStore) GetBackendConfiguration() ngx_config.Configuration {
 }
 
 func (s *k8sStore) setConfig(cmap *corev1.ConfigMap) {
-	s.backendConfig = ngx_template.ReadConfig(cmap.Data)
+	s.backendConfig = ngx_template.ReadConfig(ngx_config.ApplyProfile(s.profile, cmap.Data))
 	s.writeSSLSessionTicketKey(cmap, "/etc/nginx/tickets.key")
 }
 
 // Run initiates the synchronization of the informers and the initial
 // synchronization of the secrets.
 func (s k8sStore) Run(stopCh chan struct{}) {
+	go s.secrets.Run(stopCh)
+	go s.configMaps.Run(stopCh)
+
+	// the configuration and the default certificate are always kept in
+	// sync, and read before the ingresses
+	s.configMaps.Pin(s.configmap)
+	if s.defaultSSLCertificate != "" {
+		s.secrets.Pin(s.defaultSSLCertificate)
+	}
+
 	// start informers
 	s.informers.Run(stopCh)
 
@@ -27,6 +27,7 @@ import (
 	extensions "k8s.io/api/extensions/v1beta1"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/tools/cache"
 
@@ -37,11 +38,17 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
 	"k8s.io/ingress-nginx/internal/file"
+	"k8s.io/ingress-nginx/internal/ingress/annotations"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/parser"
+	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
 	"k8s.io/ingress-nginx/test/e2e/framework"
 )
 
 func TestStore(t *testing.T) {
+	// detect the creation of the referenced secrets in the subtests
+	defer func(period time.Duration) { referencedSyncPeriod = period }(referencedSyncPeriod)
+	referencedSyncPeriod = time.Second
+
 	clientSet := fake.NewSimpleClientset()
 
 	t.Run("should return an error searching for non existing objects", func(t *testing.T) {
@@ -62,13 +69,17 @@ func TestStore(t *testing.T) {
 		fs := newFS(t)
 		storer := New(true,
 			ns,
+			nil,
 			fmt.Sprintf("%v/config", ns),
 			"",
 			10*time.Minute,
 			clientSet,
 			fs,
 			updateCh,
-			false)
+			false,
+			ngx_config.DefaultProfile,
+			"",
+			0)
 
 		storer.Run(stopCh)
 
@@ -148,13 +159,17 @@ func TestStore(t *testing.T) {
 		fs := newFS(t)
 		storer := New(true,
 			ns,
+			nil,
 			fmt.Sprintf("%v/config", ns),
 			"",
 			10*time.Minute,
 			clientSet,
 			fs,
 			updateCh,
-			false)
+			false,
+			ngx_config.DefaultProfile,
+			"",
+			0)
 
 		storer.Run(stopCh)
 
@@ -294,13 +309,17 @@ func TestStore(t *testing.T) {
 		fs := newFS(t)
 		storer := New(true,
 			ns,
+			nil,
 			fmt.Sprintf("%v/config", ns),
 			"",
 			10*time.Minute,
 			clientSet,
 			fs,
 			updateCh,
-			false)
+			false,
+			ngx_config.DefaultProfile,
+			"",
+			0)
 
 		storer.Run(stopCh)
 
@@ -381,13 +400,17 @@ func TestStore(t *testing.T) {
 		fs := newFS(t)
 		storer := New(true,
 			ns,
+			nil,
 			fmt.Sprintf("%v/config", ns),
 			"",
 			10*time.Minute,
 			clientSet,
 			fs,
 			updateCh,
-			false)
+			false,
+			ngx_config.DefaultProfile,
+			"",
+			0)
 
 		storer.Run(stopCh)
 
@@ -491,13 +514,17 @@ func TestStore(t *testing.T) {
 		fs := newFS(t)
 		storer := New(true,
 			ns,
+			nil,
 			fmt.Sprintf("%v/config", ns),
 			"",
 			10*time.Minute,
 			clientSet,
 			fs,
 			updateCh,
-			false)
+			false,
+			ngx_config.DefaultProfile,
+			"",
+			0)
 
 		storer.Run(stopCh)
 
@@ -596,6 +623,94 @@ func TestStore(t *testing.T) {
 		})
 	})
 
+	t.Run("should parse the default backend of an ingress when the service is created after it", func(t *testing.T) {
+		ns := createNamespace(clientSet, t)
+		defer deleteNamespace(ns, clientSet, t)
+		cm := createConfigMap(clientSet, ns, t)
+		defer deleteConfigMap(cm, ns, clientSet, t)
+
+		stopCh := make(chan struct{})
+		updateCh := channels.NewRingChannel(1024)
+
+		go func(ch *channels.RingChannel) {
+			for {
+				<-ch.Out()
+			}
+		}(updateCh)
+
+		fs := newFS(t)
+		storer := New(true,
+			ns,
+			nil,
+			fmt.Sprintf("%v/config", ns),
+			"",
+			10*time.Minute,
+			clientSet,
+			fs,
+			updateCh,
+			false,
+			ngx_config.DefaultProfile,
+			"",
+			0)
+
+		storer.Run(stopCh)
+
+		name := "ingress-with-default-backend"
+		ing := ensureIngress(&extensions.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: ns,
+				SelfLink:  fmt.Sprintf("/apis/extensions/v1beta1/namespaces/%s/ingresses/%s", ns, name),
+				Annotations: map[string]string{
+					parser.GetAnnotationWithPrefix("default-backend"): "custom-errors",
+				},
+			},
+			Spec: extensions.IngressSpec{
+				Backend: &extensions.IngressBackend{
+					ServiceName: "http-svc",
+					ServicePort: intstr.FromInt(80),
+				},
+			},
+		}, clientSet, t)
+		defer deleteIngress(ing, clientSet, t)
+
+		err := framework.WaitForIngressInNamespace(clientSet, ns, name)
+		if err != nil {
+			t.Errorf("error waiting for ingress: %v", err)
+		}
+
+		key := fmt.Sprintf("%v/%v", ns, name)
+		time.Sleep(time.Second)
+
+		anns, err := storer.GetIngressAnnotations(key)
+		if err != nil {
+			t.Fatalf("unexpected error reading the annotations of ingress %v: %v", key, err)
+		}
+		if anns.DefaultBackend != nil {
+			t.Errorf("expected no default backend before the service is created")
+		}
+
+		_, err = clientSet.CoreV1().Services(ns).Create(&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "custom-errors", Namespace: ns},
+			Spec: v1.ServiceSpec{
+				Ports: []v1.ServicePort{{Port: 80}},
+			},
+		})
+		if err != nil {
+			t.Fatalf("error creating service: %v", err)
+		}
+
+		time.Sleep(time.Second)
+
+		anns, err = storer.GetIngressAnnotations(key)
+		if err != nil {
+			t.Fatalf("unexpected error reading the annotations of ingress %v: %v", key, err)
+		}
+		if anns.DefaultBackend == nil || anns.DefaultBackend.Name != "custom-errors" {
+			t.Errorf("expected the default backend custom-errors but %v returned", anns.DefaultBackend)
+		}
+	})
+
 	// test add ingress with secret it doesn't exists and then add secret
 	// check secret is generated on fs
 	// check ocsp
@@ -722,11 +837,13 @@ func newStore(t *testing.T) *k8sStore {
 			// add more listers if needed
 			Ingress: IngressLister{cache.NewStore(cache.MetaNamespaceKeyFunc)},
 		},
-		sslStore:         NewSSLCertTracker(),
-		filesystem:       fs,
-		updateCh:         channels.NewRingChannel(10),
-		mu:               new(sync.Mutex),
-		secretIngressMap: NewObjectRefMap(),
+		sslStore:            NewSSLCertTracker(),
+		filesystem:          fs,
+		updateCh:            channels.NewRingChannel(10),
+		mu:                  new(sync.Mutex),
+		secretIngressMap:    NewObjectRefMap(),
+		configMapIngressMap: NewObjectRefMap(),
+		serviceIngressMap:   NewObjectRefMap(),
 	}
 }
 
@@ -794,6 +911,173 @@ func TestUpdateSecretIngressMap(t *testing.T) {
 	})
 }
 
+func TestUpdateConfigMapIngressMap(t *testing.T) {
+	s := newStore(t)
+
+	ingTpl := &extensions.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "testns",
+		},
+	}
+	s.listers.Ingress.Add(ingTpl)
+
+	t.Run("with annotation in simple name format", func(t *testing.T) {
+		ing := ingTpl.DeepCopy()
+		ing.ObjectMeta.SetAnnotations(map[string]string{
+			parser.GetAnnotationWithPrefix("whitelist-source-range-configmap"): "allowlist",
+		})
+		s.listers.Ingress.Update(ing)
+		s.updateConfigMapIngressMap(ing)
+
+		if l := s.configMapIngressMap.Len(); !(l == 1 && s.configMapIngressMap.Has("testns/allowlist")) {
+			t.Errorf("Expected \"testns/allowlist\" to be the only referenced ConfigMap (got %d)", l)
+		}
+	})
+
+	t.Run("without annotation", func(t *testing.T) {
+		ing := ingTpl.DeepCopy()
+		s.listers.Ingress.Update(ing)
+		s.updateConfigMapIngressMap(ing)
+
+		if l := s.configMapIngressMap.Len(); l != 0 {
+			t.Errorf("Expected 0 referenced ConfigMap (got %d)", l)
+		}
+	})
+}
+
+func TestUpdateServiceIngressMap(t *testing.T) {
+	s := newStore(t)
+
+	ing := &extensions.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "testns",
+			Annotations: map[string]string{
+				parser.GetAnnotationWithPrefix("default-backend"): "custom-errors",
+			},
+		},
+	}
+
+	s.updateServiceIngressMap(ing)
+	if l := s.serviceIngressMap.Len(); !(l == 1 && s.serviceIngressMap.Has("testns/custom-errors")) {
+		t.Errorf("Expected \"testns/custom-errors\" to be the only referenced Service (got %d)", l)
+	}
+
+	ing.SetAnnotations(nil)
+	s.updateServiceIngressMap(ing)
+	if l := s.serviceIngressMap.Len(); l != 0 {
+		t.Errorf("Expected 0 referenced Service (got %d)", l)
+	}
+}
+
+func TestUpdateIngressAnnotations(t *testing.T) {
+	s := newStore(t)
+	s.annotations = annotations.NewAnnotationExtractor(s)
+	s.listers.IngressAnnotation.Store = cache.NewStore(cache.DeletionHandlingMetaNamespaceKeyFunc)
+
+	ing := &extensions.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "test",
+			Namespace:       "default",
+			UID:             "uid",
+			ResourceVersion: "1",
+			Annotations: map[string]string{
+				parser.GetAnnotationWithPrefix("rewrite-target"): "/a",
+			},
+		},
+	}
+
+	s.updateIngressAnnotations(ing)
+	first, err := s.GetIngressAnnotations("default/test")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first.Rewrite.Target != "/a" {
+		t.Errorf("expected the rewrite target /a but %v returned", first.Rewrite.Target)
+	}
+
+	// the same version is not parsed again
+	s.updateIngressAnnotations(ing.DeepCopy())
+	if anns, _ := s.GetIngressAnnotations("default/test"); anns != first {
+		t.Errorf("expected the annotations of the same resource version not extracted again")
+	}
+
+	// a referenced object changed
+	s.extractAnnotations(ing)
+	if anns, _ := s.GetIngressAnnotations("default/test"); anns == first {
+		t.Errorf("expected the annotations extracted again")
+	}
+
+	updated := ing.DeepCopy()
+	updated.ResourceVersion = "2"
+	updated.Annotations[parser.GetAnnotationWithPrefix("rewrite-target")] = "/b"
+	s.updateIngressAnnotations(updated)
+	if anns, _ := s.GetIngressAnnotations("default/test"); anns.Rewrite.Target != "/b" {
+		t.Errorf("expected the rewrite target /b of the new resource version but %v returned", anns.Rewrite.Target)
+	}
+}
+
+func TestOnlyEffectiveConfigChanged(t *testing.T) {
+	key := parser.GetAnnotationWithPrefix(annotations.EffectiveConfigAnnotation)
+
+	old := &extensions.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "test",
+			Namespace:       "default",
+			ResourceVersion: "1",
+		},
+	}
+
+	cur := old.DeepCopy()
+	cur.ResourceVersion = "2"
+	cur.Annotations = map[string]string{key: `{"backendProtocol":"HTTP"}`}
+	if !onlyEffectiveConfigChanged(old, cur) {
+		t.Errorf("expected only the effective configuration changed")
+	}
+
+	// the annotation was not changed
+	if onlyEffectiveConfigChanged(cur, cur.DeepCopy()) {
+		t.Errorf("expected the effective configuration not changed")
+	}
+
+	other := cur.DeepCopy()
+	other.ResourceVersion = "3"
+	other.Annotations[key] = `{"backendProtocol":"HTTPS"}`
+	other.Annotations[parser.GetAnnotationWithPrefix("rewrite-target")] = "/"
+	if onlyEffectiveConfigChanged(cur, other) {
+		t.Errorf("expected other annotations changed")
+	}
+}
+
+func TestUpdateAnnotationsVersion(t *testing.T) {
+	s := newStore(t)
+	s.annotations = annotations.NewAnnotationExtractor(s)
+	s.listers.IngressAnnotation.Store = cache.NewStore(cache.DeletionHandlingMetaNamespaceKeyFunc)
+
+	ing := &extensions.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "test",
+			Namespace:       "default",
+			UID:             "uid",
+			ResourceVersion: "1",
+		},
+	}
+	s.updateIngressAnnotations(ing)
+
+	updated := ing.DeepCopy()
+	updated.ResourceVersion = "2"
+	s.updateAnnotationsVersion(updated)
+
+	anns, err := s.GetIngressAnnotations("default/test")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if anns.ResourceVersion != "2" {
+		t.Errorf("expected the resource version 2 but %v returned", anns.ResourceVersion)
+	}
+}
+
 func TestListIngresses(t *testing.T) {
 	s := newStore(t)
 
@@ -895,6 +1179,51 @@ func TestListIngresses(t *testing.T) {
 	if s := len(ingresses); s != 3 {
 		t.Errorf("Expected 3 Ingresses but got %v", s)
 	}
+
+	ingresses = s.ListIngressesOfOtherClasses()
+	if s := len(ingresses); s != 1 {
+		t.Errorf("Expected 1 Ingress of other classes but got %v", s)
+	}
+}
+
+func TestListIngressesNamespaceSelector(t *testing.T) {
+	s := newStore(t)
+	s.namespaceSelector = labels.SelectorFromSet(labels.Set{"ingress": "public"})
+	s.listers.Namespace.Store = cache.NewStore(cache.MetaNamespaceKeyFunc)
+	s.listers.Namespace.Add(&v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "public",
+			Labels: map[string]string{"ingress": "public"},
+		},
+	})
+
+	for _, ns := range []string{"public", "private"} {
+		s.listers.Ingress.Add(&extensions.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: ns,
+			},
+			Spec: extensions.IngressSpec{
+				Backend: &extensions.IngressBackend{
+					ServiceName: "demo",
+					ServicePort: intstr.FromInt(80),
+				},
+			},
+		})
+	}
+
+	ingresses := s.ListIngresses()
+	if len(ingresses) != 1 {
+		t.Fatalf("Expected 1 Ingress but got %v", len(ingresses))
+	}
+	if ingresses[0].Namespace != "public" {
+		t.Errorf("Expected the Ingress of namespace public but got the one of %v", ingresses[0].Namespace)
+	}
+
+	s.namespaceSelector = nil
+	if ingresses := s.ListIngresses(); len(ingresses) != 2 {
+		t.Errorf("Expected 2 Ingresses without a namespace selector but got %v", len(ingresses))
+	}
 }
 
 func TestWriteSSLSessionTicketKey(t *testing.T) {
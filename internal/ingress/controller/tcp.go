@@ -21,17 +21,19 @@ import (
 	"io"
 	"net"
 
-	"github.com/golang/glog"
-
 	"github.com/paultag/sniff/parser"
+
+	"k8s.io/ingress-nginx/internal/log"
 )
 
 // TCPServer describes a server that works in passthrough mode.
 type TCPServer struct {
-	Hostname      string
-	IP            string
-	Port          int
-	ProxyProtocol bool
+	Hostname string
+	IP       string
+	Port     int
+	// ProxyProtocol is the version of the PROXY protocol, proxyProtocolV1
+	// or proxyProtocolV2, sent to the server. Empty when disabled.
+	ProxyProtocol string
 }
 
 // TCPProxy describes the passthrough servers and a default as catch all.
@@ -63,49 +65,43 @@ func (p *TCPProxy) Handle(conn net.Conn) {
 
 	length, err := conn.Read(data)
 	if err != nil {
-		glog.V(4).Infof("Error reading the first 4k of the connection: %s", err)
+		log.V(4).Infof("Error reading the first 4k of the connection: %s", err)
 		return
 	}
 
 	proxy := p.Default
 	hostname, err := parser.GetHostname(data[:])
 	if err == nil {
-		glog.V(4).Infof("Parsed hostname from TLS Client Hello: %s", hostname)
+		log.V(4).Infof("Parsed hostname from TLS Client Hello: %s", hostname)
 		proxy = p.Get(hostname)
 	}
 
 	if proxy == nil {
-		glog.V(4).Infof("There is no configured proxy for SSL connections.")
+		log.V(4).Infof("There is no configured proxy for SSL connections.")
 		return
 	}
 
-	clientConn, err := net.Dial("tcp", fmt.Sprintf("%s:%d", proxy.IP, proxy.Port))
+	clientConn, err := net.Dial("tcp", net.JoinHostPort(proxy.IP, fmt.Sprintf("%d", proxy.Port)))
 	if err != nil {
 		return
 	}
 	defer clientConn.Close()
 
-	if proxy.ProxyProtocol {
+	if proxy.ProxyProtocol != "" {
 		// write out the Proxy Protocol header
 		localAddr := conn.LocalAddr().(*net.TCPAddr)
 		remoteAddr := conn.RemoteAddr().(*net.TCPAddr)
-		protocol := "UNKNOWN"
-		if remoteAddr.IP.To4() != nil {
-			protocol = "TCP4"
-		} else if remoteAddr.IP.To16() != nil {
-			protocol = "TCP6"
-		}
-		proxyProtocolHeader := fmt.Sprintf("PROXY %s %s %s %d %d\r\n", protocol, remoteAddr.IP.String(), localAddr.IP.String(), remoteAddr.Port, localAddr.Port)
-		glog.V(4).Infof("Writing Proxy Protocol header: %s", proxyProtocolHeader)
-		_, err = fmt.Fprintf(clientConn, proxyProtocolHeader)
+		header := proxyProtocolHeader(proxy.ProxyProtocol, remoteAddr, localAddr)
+		log.V(4).Infof("Writing Proxy Protocol %v header: %q", proxy.ProxyProtocol, header)
+		_, err = clientConn.Write(header)
 	}
 	if err != nil {
-		glog.Errorf("Error writing Proxy Protocol header: %s", err)
+		log.Errorf("Error writing Proxy Protocol header: %s", err)
 		clientConn.Close()
 	} else {
 		_, err = clientConn.Write(data[:length])
 		if err != nil {
-			glog.Errorf("Error writing the first 4k of proxy data: %s", err)
+			log.Errorf("Error writing the first 4k of proxy data: %s", err)
 			clientConn.Close()
 		}
 	}
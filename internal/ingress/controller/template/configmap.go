@@ -19,17 +19,18 @@ package template
 import (
 	"fmt"
 	"net"
+	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/golang/glog"
-
 	"github.com/mitchellh/hashstructure"
 	"github.com/mitchellh/mapstructure"
 
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/ingress-nginx/internal/ingress/controller/config"
+	"k8s.io/ingress-nginx/internal/log"
 	ing_net "k8s.io/ingress-nginx/internal/net"
 	"k8s.io/ingress-nginx/internal/runtime"
 )
@@ -40,6 +41,10 @@ const (
 	whitelistSourceRange     = "whitelist-source-range"
 	proxyRealIPCIDR          = "proxy-real-ip-cidr"
 	bindAddress              = "bind-address"
+	bindAddressIpv4          = "bind-address-ipv4"
+	bindAddressIpv6          = "bind-address-ipv6"
+	listenIPFamily           = "listen-ip-family"
+	statusListenIPFamily     = "status-listen-ip-family"
 	httpRedirectCode         = "http-redirect-code"
 	blockCIDRs               = "block-cidrs"
 	blockUserAgents          = "block-user-agents"
@@ -49,11 +54,25 @@ const (
 	nginxStatusIpv4Whitelist = "nginx-status-ipv4-whitelist"
 	nginxStatusIpv6Whitelist = "nginx-status-ipv6-whitelist"
 	proxyHeaderTimeout       = "proxy-protocol-header-timeout"
+	proxyProtocolListeners   = "proxy-protocol-listeners"
 	workerProcesses          = "worker-processes"
+	proxyCacheZones          = "proxy-cache-zones"
+	logFormatJSON            = "log-format-json"
+	accessLogTarget          = "access-log-target"
+	errorLogTarget           = "error-log-target"
+	httpSnippetFiles         = "http-snippet-files"
+	serviceLabels            = "service-labels"
+	ingressLabels            = "ingress-labels"
 )
 
 var (
 	validRedirectCodes = sets.NewInt([]int{301, 302, 307, 308}...)
+	validCacheZoneName = regexp.MustCompile(`^[a-zA-Z0-9_\-]+$`)
+	validCacheZoneSize = regexp.MustCompile(`^[0-9]+[kKmMgG]?$`)
+	validLogFieldName  = regexp.MustCompile(`^[a-zA-Z0-9_\-.]+$`)
+	validLogFieldVar   = regexp.MustCompile(`^\$[a-zA-Z0-9_]+$`)
+	validLogTarget     = regexp.MustCompile(`^syslog://(\[[0-9a-fA-F:.]+\]|[a-zA-Z0-9\-.]+)(:[0-9]+)?$`)
+	validLabelKey      = regexp.MustCompile(`^([a-zA-Z0-9\-.]+/)?[a-zA-Z0-9_\-.]+$`)
 )
 
 // ReadConfig obtains the configuration defined by the user merged with the defaults.
@@ -83,7 +102,7 @@ func ReadConfig(src map[string]string) config.Configuration {
 		for _, i := range strings.Split(val, ",") {
 			j, err := strconv.Atoi(i)
 			if err != nil {
-				glog.Warningf("%v is not a valid http code: %v", i, err)
+				log.Warningf("%v is not a valid http code: %v", i, err)
 			} else {
 				errors = append(errors, j)
 			}
@@ -105,21 +124,63 @@ func ReadConfig(src map[string]string) config.Configuration {
 		delete(conf, proxyRealIPCIDR)
 		proxyList = append(proxyList, strings.Split(val, ",")...)
 	} else {
-		proxyList = append(proxyList, "0.0.0.0/0")
+		proxyList = append(proxyList, "0.0.0.0/0", "::/0")
 	}
 	if val, ok := conf[bindAddress]; ok {
 		delete(conf, bindAddress)
-		for _, i := range strings.Split(val, ",") {
-			ns := net.ParseIP(i)
-			if ns != nil {
-				if ing_net.IsIPV6(ns) {
-					bindAddressIpv6List = append(bindAddressIpv6List, fmt.Sprintf("[%v]", ns))
-				} else {
-					bindAddressIpv4List = append(bindAddressIpv4List, fmt.Sprintf("%v", ns))
-				}
-			} else {
-				glog.Warningf("%v is not a valid textual representation of an IP address", i)
+		ipv4, ipv6 := parseBindAddresses(val)
+		bindAddressIpv4List = append(bindAddressIpv4List, ipv4...)
+		bindAddressIpv6List = append(bindAddressIpv6List, ipv6...)
+	}
+	if val, ok := conf[bindAddressIpv4]; ok {
+		delete(conf, bindAddressIpv4)
+		ipv4, ipv6 := parseBindAddresses(val)
+		if len(ipv6) > 0 {
+			log.Warningf("Ignoring IPv6 addresses %v in %v", ipv6, bindAddressIpv4)
+		}
+		bindAddressIpv4List = append(bindAddressIpv4List, ipv4...)
+	}
+	if val, ok := conf[bindAddressIpv6]; ok {
+		delete(conf, bindAddressIpv6)
+		ipv4, ipv6 := parseBindAddresses(val)
+		if len(ipv4) > 0 {
+			log.Warningf("Ignoring IPv4 addresses %v in %v", ipv4, bindAddressIpv6)
+		}
+		bindAddressIpv6List = append(bindAddressIpv6List, ipv6...)
+	}
+
+	if val, ok := conf[listenIPFamily]; ok {
+		delete(conf, listenIPFamily)
+		if config.IsValidIPFamily(val) {
+			to.ListenIPFamily = val
+		} else {
+			log.Warningf("%v is not a valid IP family for %v", val, listenIPFamily)
+		}
+	}
+	if val, ok := conf[statusListenIPFamily]; ok {
+		delete(conf, statusListenIPFamily)
+		if config.IsValidIPFamily(val) {
+			to.StatusListenIPFamily = val
+		} else {
+			log.Warningf("%v is not a valid IP family for %v", val, statusListenIPFamily)
+		}
+	}
+
+	if val, ok := conf[proxyProtocolListeners]; ok {
+		delete(conf, proxyProtocolListeners)
+		to.ProxyProtocolListeners = []string{}
+		for _, l := range strings.Split(val, ",") {
+			l = strings.TrimSpace(l)
+			if l == "" {
+				continue
+			}
+
+			if l != config.HTTPListener && l != config.HTTPSListener {
+				log.Warningf("%v is not a valid listener for %v, valid values are %v and %v", l, proxyProtocolListeners, config.HTTPListener, config.HTTPSListener)
+				continue
 			}
+
+			to.ProxyProtocolListeners = append(to.ProxyProtocolListeners, l)
 		}
 	}
 
@@ -136,16 +197,119 @@ func ReadConfig(src map[string]string) config.Configuration {
 		blockRefererList = strings.Split(val, ",")
 	}
 
+	if val, ok := conf[httpSnippetFiles]; ok {
+		delete(conf, httpSnippetFiles)
+		for _, f := range strings.Split(val, ",") {
+			f = strings.TrimSpace(f)
+			if f == "" {
+				continue
+			}
+
+			if !filepath.IsAbs(f) {
+				log.Warningf("%v is not an absolute path of a snippet file", f)
+				continue
+			}
+
+			to.HTTPSnippetFiles = append(to.HTTPSnippetFiles, f)
+		}
+	}
+
+	if val, ok := conf[serviceLabels]; ok {
+		delete(conf, serviceLabels)
+		to.ServiceLabels = parseLabelKeys(val)
+	}
+	if val, ok := conf[ingressLabels]; ok {
+		delete(conf, ingressLabels)
+		to.IngressLabels = parseLabelKeys(val)
+	}
+
+	if val, ok := conf[proxyCacheZones]; ok {
+		delete(conf, proxyCacheZones)
+		for _, zone := range strings.Split(val, ",") {
+			zone = strings.TrimSpace(zone)
+			if zone == "" {
+				continue
+			}
+
+			parts := strings.Split(zone, ":")
+			if len(parts) != 2 || !validCacheZoneName.MatchString(parts[0]) || !validCacheZoneSize.MatchString(parts[1]) {
+				log.Warningf("%v is not a valid cache zone definition (expected name:size)", zone)
+				continue
+			}
+
+			to.ProxyCacheZones = append(to.ProxyCacheZones, config.ProxyCacheZone{
+				Name: parts[0],
+				Size: parts[1],
+			})
+		}
+	}
+
+	if val, ok := conf[logFormatJSON]; ok {
+		delete(conf, logFormatJSON)
+		for _, field := range strings.Split(val, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+
+			parts := strings.SplitN(field, ":", 2)
+			name := strings.TrimSpace(parts[0])
+			if !validLogFieldName.MatchString(name) {
+				log.Warningf("%v is not a valid log field name", name)
+				continue
+			}
+
+			var variable string
+			if len(parts) == 2 {
+				variable = strings.TrimSpace(parts[1])
+				if !validLogFieldVar.MatchString(variable) {
+					log.Warningf("%v is not a valid NGINX variable for the log field %v", variable, name)
+					continue
+				}
+			} else {
+				variable, ok = config.LogFormatJSONFields[name]
+				if !ok {
+					log.Warningf("%v is not a known log field (expected one of the predefined fields or name:$variable)", name)
+					continue
+				}
+			}
+
+			to.LogFormatJSON = append(to.LogFormatJSON, config.LogFormatJSONField{
+				Name:     name,
+				Variable: variable,
+			})
+		}
+	}
+
+	for _, key := range []string{accessLogTarget, errorLogTarget} {
+		val, ok := conf[key]
+		if !ok {
+			continue
+		}
+
+		delete(conf, key)
+		if val != "" && !validLogTarget.MatchString(val) {
+			log.Warningf("%v is not a valid %v (expected %vhost[:port])", val, key, config.SyslogScheme)
+			continue
+		}
+
+		if key == accessLogTarget {
+			to.AccessLogTarget = val
+		} else {
+			to.ErrorLogTarget = val
+		}
+	}
+
 	if val, ok := conf[httpRedirectCode]; ok {
 		delete(conf, httpRedirectCode)
 		j, err := strconv.Atoi(val)
 		if err != nil {
-			glog.Warningf("%v is not a valid HTTP code: %v", val, err)
+			log.Warningf("%v is not a valid HTTP code: %v", val, err)
 		} else {
 			if validRedirectCodes.Has(j) {
 				to.HTTPRedirectCode = j
 			} else {
-				glog.Warningf("The code %v is not a valid as HTTP redirect code. Using the default.", val)
+				log.Warningf("The code %v is not a valid as HTTP redirect code. Using the default.", val)
 			}
 		}
 	}
@@ -155,7 +319,7 @@ func ReadConfig(src map[string]string) config.Configuration {
 		delete(conf, proxyHeaderTimeout)
 		duration, err := time.ParseDuration(val)
 		if err != nil {
-			glog.Warningf("proxy-protocol-header-timeout of %v encountered an error while being parsed %v. Switching to use default value instead.", val, err)
+			log.Warningf("proxy-protocol-header-timeout of %v encountered an error while being parsed %v. Switching to use default value instead.", val, err)
 		} else {
 			to.ProxyProtocolHeaderTimeout = duration
 		}
@@ -166,7 +330,7 @@ func ReadConfig(src map[string]string) config.Configuration {
 		delete(conf, proxyStreamResponses)
 		j, err := strconv.Atoi(val)
 		if err != nil {
-			glog.Warningf("%v is not a valid number: %v", val, err)
+			log.Warningf("%v is not a valid number: %v", val, err)
 		} else {
 			streamResponses = j
 		}
@@ -220,18 +384,18 @@ func ReadConfig(src map[string]string) config.Configuration {
 
 	decoder, err := mapstructure.NewDecoder(config)
 	if err != nil {
-		glog.Warningf("unexpected error merging defaults: %v", err)
+		log.Warningf("unexpected error merging defaults: %v", err)
 	}
 	err = decoder.Decode(conf)
 	if err != nil {
-		glog.Warningf("unexpected error merging defaults: %v", err)
+		log.Warningf("unexpected error merging defaults: %v", err)
 	}
 
 	hash, err := hashstructure.Hash(to, &hashstructure.HashOptions{
 		TagName: "json",
 	})
 	if err != nil {
-		glog.Warningf("unexpected error obtaining hash: %v", err)
+		log.Warningf("unexpected error obtaining hash: %v", err)
 	}
 
 	to.Checksum = fmt.Sprintf("%v", hash)
@@ -245,9 +409,55 @@ func filterErrors(codes []int) []int {
 		if code > 299 && code < 600 {
 			fa = append(fa, code)
 		} else {
-			glog.Warningf("error code %v is not valid for custom error pages", code)
+			log.Warningf("error code %v is not valid for custom error pages", code)
 		}
 	}
 
 	return fa
 }
+
+// parseBindAddresses returns the IPv4 and IPv6 addresses of a comma-separated
+// list, the IPv6 addresses enclosed in brackets to use them in listen directives
+func parseBindAddresses(val string) ([]string, []string) {
+	var ipv4, ipv6 []string
+	for _, i := range strings.Split(val, ",") {
+		i = strings.TrimSpace(i)
+		if i == "" {
+			continue
+		}
+
+		ns := net.ParseIP(i)
+		if ns == nil {
+			log.Warningf("%v is not a valid textual representation of an IP address", i)
+			continue
+		}
+
+		if ing_net.IsIPV6(ns) {
+			ipv6 = append(ipv6, fmt.Sprintf("[%v]", ns))
+		} else {
+			ipv4 = append(ipv4, fmt.Sprintf("%v", ns))
+		}
+	}
+
+	return ipv4, ipv6
+}
+
+// parseLabelKeys returns the valid label keys of a comma-separated list
+func parseLabelKeys(val string) []string {
+	var keys []string
+	for _, key := range strings.Split(val, ",") {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+
+		if !validLabelKey.MatchString(key) {
+			log.Warningf("%v is not a valid label key", key)
+			continue
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys
+}
@@ -159,3 +159,163 @@ func TestDefaultLoadBalance(t *testing.T) {
 		t.Errorf("default load balance algorithm wrong")
 	}
 }
+
+func TestDefaultProxyRealIPCIDR(t *testing.T) {
+	to := ReadConfig(map[string]string{})
+	expected := []string{"0.0.0.0/0", "::/0"}
+	if !reflect.DeepEqual(to.ProxyRealIPCIDR, expected) {
+		t.Errorf("expected %v as default proxy-real-ip-cidr but %v returned", expected, to.ProxyRealIPCIDR)
+	}
+}
+
+func TestProxyCacheZonesParsing(t *testing.T) {
+	to := ReadConfig(map[string]string{
+		"proxy-cache-zones": "static:10m, api:1g,invalid,bad zone:5m,other:abc",
+	})
+
+	expected := []config.ProxyCacheZone{
+		{Name: "static", Size: "10m"},
+		{Name: "api", Size: "1g"},
+	}
+	if !reflect.DeepEqual(to.ProxyCacheZones, expected) {
+		t.Errorf("expected %v but %v was returned", expected, to.ProxyCacheZones)
+	}
+}
+
+func TestHTTPSnippetFilesParsing(t *testing.T) {
+	to := ReadConfig(map[string]string{
+		"http-snippet-files": "/etc/nginx/snippets/maps.conf, relative.conf,,/etc/nginx/snippets/limits.conf",
+		"server-snippet-dir": "/etc/nginx/snippets/server",
+	})
+
+	expected := []string{"/etc/nginx/snippets/maps.conf", "/etc/nginx/snippets/limits.conf"}
+	if !reflect.DeepEqual(to.HTTPSnippetFiles, expected) {
+		t.Errorf("expected %v but %v was returned", expected, to.HTTPSnippetFiles)
+	}
+
+	if to.ServerSnippetDir != "/etc/nginx/snippets/server" {
+		t.Errorf("expected /etc/nginx/snippets/server but %v was returned", to.ServerSnippetDir)
+	}
+}
+
+func TestBindAddressParsing(t *testing.T) {
+	to := ReadConfig(map[string]string{
+		"bind-address":      "10.0.0.1, 2001:db8::1",
+		"bind-address-ipv4": "10.0.0.2,2001:db8::2,invalid",
+		"bind-address-ipv6": "fd00::1,10.0.0.3",
+	})
+
+	expected := []string{"10.0.0.1", "10.0.0.2"}
+	if !reflect.DeepEqual(to.BindAddressIpv4, expected) {
+		t.Errorf("expected %v but %v was returned", expected, to.BindAddressIpv4)
+	}
+
+	expected = []string{"[2001:db8::1]", "[fd00::1]"}
+	if !reflect.DeepEqual(to.BindAddressIpv6, expected) {
+		t.Errorf("expected %v but %v was returned", expected, to.BindAddressIpv6)
+	}
+}
+
+func TestListenIPFamilyParsing(t *testing.T) {
+	to := ReadConfig(map[string]string{
+		"listen-ip-family":        "ipv6",
+		"status-listen-ip-family": "ipv5",
+	})
+
+	if to.ListenIPFamily != config.IPv6IPFamily {
+		t.Errorf("expected %v but %v was returned", config.IPv6IPFamily, to.ListenIPFamily)
+	}
+	if to.StatusListenIPFamily != "" {
+		t.Errorf("expected an invalid IP family to be ignored but %v was returned", to.StatusListenIPFamily)
+	}
+
+	if to = ReadConfig(map[string]string{}); to.ListenIPFamily != config.AutoIPFamily {
+		t.Errorf("expected %v by default but %v was returned", config.AutoIPFamily, to.ListenIPFamily)
+	}
+}
+
+func TestProxyProtocolListenersParsing(t *testing.T) {
+	to := ReadConfig(map[string]string{
+		"proxy-protocol-listeners": " https, tcp",
+	})
+
+	if !reflect.DeepEqual(to.ProxyProtocolListeners, []string{config.HTTPSListener}) {
+		t.Errorf("expected only the listener %v but %v was returned", config.HTTPSListener, to.ProxyProtocolListeners)
+	}
+
+	to = ReadConfig(map[string]string{})
+	if !reflect.DeepEqual(to.ProxyProtocolListeners, []string{config.HTTPListener, config.HTTPSListener}) {
+		t.Errorf("expected all the listeners by default but %v was returned", to.ProxyProtocolListeners)
+	}
+}
+
+func TestLabelsParsing(t *testing.T) {
+	to := ReadConfig(map[string]string{
+		"service-labels": "team, app.kubernetes.io/part-of,,bad label",
+		"ingress-labels": "owner",
+	})
+
+	expected := []string{"team", "app.kubernetes.io/part-of"}
+	if !reflect.DeepEqual(to.ServiceLabels, expected) {
+		t.Errorf("expected %v but %v was returned", expected, to.ServiceLabels)
+	}
+
+	expected = []string{"owner"}
+	if !reflect.DeepEqual(to.IngressLabels, expected) {
+		t.Errorf("expected %v but %v was returned", expected, to.IngressLabels)
+	}
+}
+
+func TestLogFormatJSONParsing(t *testing.T) {
+	to := ReadConfig(map[string]string{
+		"log-format-json": "time, request_id,namespace,ingress,service,latency:$upstream_response_time,unknown,bad name:$host,other:host",
+	})
+
+	expected := []config.LogFormatJSONField{
+		{Name: "time", Variable: "$time_iso8601"},
+		{Name: "request_id", Variable: "$req_id"},
+		{Name: "namespace", Variable: "$namespace"},
+		{Name: "ingress", Variable: "$ingress_name"},
+		{Name: "service", Variable: "$service_name"},
+		{Name: "latency", Variable: "$upstream_response_time"},
+	}
+	if !reflect.DeepEqual(to.LogFormatJSON, expected) {
+		t.Errorf("expected %v but %v was returned", expected, to.LogFormatJSON)
+	}
+}
+
+func TestLogTargetParsing(t *testing.T) {
+	to := ReadConfig(map[string]string{
+		"access-log-target": "syslog://logs.example.com:5140",
+		"error-log-target":  "tcp://logs.example.com:5140",
+	})
+
+	if to.AccessLogTarget != "syslog://logs.example.com:5140" {
+		t.Errorf("expected a syslog access log target but %v was returned", to.AccessLogTarget)
+	}
+	if to.ErrorLogTarget != "" {
+		t.Errorf("expected an invalid error log target to be ignored but %v was returned", to.ErrorLogTarget)
+	}
+}
+
+func TestLowMemoryProfile(t *testing.T) {
+	to := ReadConfig(config.ApplyProfile(config.LowMemoryProfile, map[string]string{
+		"worker-processes": "2",
+	}))
+
+	if to.WorkerProcesses != "2" {
+		t.Errorf("expected worker-processes defined in the ConfigMap but %v was returned", to.WorkerProcesses)
+	}
+	if to.MaxWorkerConnections != 1024 {
+		t.Errorf("expected 1024 worker connections but %v was returned", to.MaxWorkerConnections)
+	}
+	if !to.DisableLuaRestyWAF || to.UseGeoIP || to.UseGeoIP2 {
+		t.Errorf("expected lua-resty-waf and GeoIP to be disabled")
+	}
+
+	def := ReadConfig(config.ApplyProfile(config.DefaultProfile, map[string]string{}))
+	def.Checksum = ""
+	if diff := pretty.Compare(def, config.NewDefault()); diff != "" {
+		t.Errorf("unexpected diff: (-got +want)\n%s", diff)
+	}
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"reflect"
+	"regexp"
+	"sync"
+	text_template "text/template"
+)
+
+var (
+	validFuncName = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
+
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// FuncRegistry contains the functions available in the templates in addition
+// to the built-in ones. The functions cannot replace the built-in functions
+// used by the default template, and a panic in a function only fails the
+// rendering of the template.
+type FuncRegistry struct {
+	mu    sync.Mutex
+	funcs text_template.FuncMap
+}
+
+// NewFuncRegistry returns a registry with the extension functions for
+// CIDR math and base64 encoding
+func NewFuncRegistry() *FuncRegistry {
+	return &FuncRegistry{
+		funcs: text_template.FuncMap{
+			"cidrContains": cidrContains,
+			"cidrNetwork":  cidrNetwork,
+			"cidrMask":     cidrMask,
+			"base64Encode": base64Encode,
+			"base64Decode": base64Decode,
+		},
+	}
+}
+
+// Register adds a function to the registry. Like in text/template, the
+// function must return a single value or a value and an error.
+func (r *FuncRegistry) Register(name string, fn interface{}) error {
+	if !validFuncName.MatchString(name) {
+		return fmt.Errorf("invalid template function name %q", name)
+	}
+
+	if _, ok := funcMap[name]; ok {
+		return fmt.Errorf("template function %q is a built-in function", name)
+	}
+
+	t := reflect.TypeOf(fn)
+	if t == nil || t.Kind() != reflect.Func {
+		return fmt.Errorf("template function %q is not a function", name)
+	}
+
+	switch {
+	case t.NumOut() == 1:
+	case t.NumOut() == 2 && t.Out(1) == errorType:
+	default:
+		return fmt.Errorf("template function %q must return a value or a value and an error", name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.funcs[name]; ok {
+		return fmt.Errorf("template function %q is already registered", name)
+	}
+
+	r.funcs[name] = fn
+	return nil
+}
+
+// funcMap returns the built-in functions and the registered ones
+func (r *FuncRegistry) funcMap() text_template.FuncMap {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fm := make(text_template.FuncMap, len(funcMap)+len(r.funcs))
+	for name, fn := range r.funcs {
+		fm[name] = fn
+	}
+	for name, fn := range funcMap {
+		fm[name] = fn
+	}
+
+	return fm
+}
+
+// cidrContains returns true if a network contains an IP address
+func cidrContains(cidr, ip string) (bool, error) {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return false, err
+	}
+
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false, fmt.Errorf("invalid IP address %q", ip)
+	}
+
+	return network.Contains(addr), nil
+}
+
+// cidrNetwork returns the network of a CIDR, like 10.0.0.0/8 for 10.1.2.3/8
+func cidrNetwork(cidr string) (string, error) {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return "", err
+	}
+
+	return network.String(), nil
+}
+
+// cidrMask returns the netmask of an IPv4 CIDR, like 255.0.0.0 for 10.0.0.0/8
+func cidrMask(cidr string) (string, error) {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return "", err
+	}
+
+	if len(network.Mask) != net.IPv4len {
+		return "", fmt.Errorf("%v is not an IPv4 network", cidr)
+	}
+
+	return net.IP(network.Mask).String(), nil
+}
+
+func base64Encode(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+func base64Decode(s string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
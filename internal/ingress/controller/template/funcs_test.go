@@ -0,0 +1,143 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/ingress-nginx/internal/file"
+	"k8s.io/ingress-nginx/internal/ingress/controller/config"
+)
+
+func TestFuncRegistryRegister(t *testing.T) {
+	r := NewFuncRegistry()
+
+	if err := r.Register("tenant", func(s string) string { return s }); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := r.Register("tenant", func(s string) string { return s }); err == nil {
+		t.Errorf("expected an error registering a function twice")
+	}
+	if err := r.Register("buildProxyPass", func() string { return "" }); err == nil {
+		t.Errorf("expected an error replacing a built-in function")
+	}
+	if err := r.Register("bad name", func() string { return "" }); err == nil {
+		t.Errorf("expected an error with an invalid name")
+	}
+	if err := r.Register("notFunc", "value"); err == nil {
+		t.Errorf("expected an error registering a value that is not a function")
+	}
+	if err := r.Register("noResult", func() {}); err == nil {
+		t.Errorf("expected an error registering a function without result")
+	}
+	if err := r.Register("twoResults", func() (string, string) { return "", "" }); err == nil {
+		t.Errorf("expected an error registering a function with two results")
+	}
+	if err := r.Register("withError", func() (string, error) { return "", nil }); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestExtensionFuncs(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fn       func() (interface{}, error)
+		expected interface{}
+		err      bool
+	}{
+		{"ip in network", func() (interface{}, error) { return cidrContains("10.0.0.0/8", "10.1.2.3") }, true, false},
+		{"ip outside network", func() (interface{}, error) { return cidrContains("10.0.0.0/8", "192.168.0.1") }, false, false},
+		{"invalid ip", func() (interface{}, error) { return cidrContains("10.0.0.0/8", "host") }, false, true},
+		{"network", func() (interface{}, error) { return cidrNetwork("10.1.2.3/16") }, "10.1.0.0/16", false},
+		{"invalid network", func() (interface{}, error) { return cidrNetwork("10.1.2.3") }, "", true},
+		{"mask", func() (interface{}, error) { return cidrMask("172.16.0.0/12") }, "255.240.0.0", false},
+		{"ipv6 mask", func() (interface{}, error) { return cidrMask("2001:db8::/32") }, "", true},
+		{"base64 decode", func() (interface{}, error) { return base64Decode("dXNlcjpwYXNz") }, "user:pass", false},
+		{"invalid base64", func() (interface{}, error) { return base64Decode("%%%") }, "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := tc.fn()
+			if tc.err {
+				if err == nil {
+					t.Errorf("expected an error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if v != tc.expected {
+				t.Errorf("expected %v but got %v", tc.expected, v)
+			}
+		})
+	}
+
+	if encoded := base64Encode("user:pass"); encoded != "dXNlcjpwYXNz" {
+		t.Errorf("expected dXNlcjpwYXNz but got %v", encoded)
+	}
+}
+
+func TestNewTemplateWithFuncs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "template")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "nginx.tmpl")
+	content := `{{ tenant .Profile }} {{ cidrMask "10.0.0.0/8" }}{{ if eq .Profile "panic" }}{{ explode }}{{ end }}`
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fs, err := file.NewLocalFS()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := NewTemplate(path, fs); err == nil {
+		t.Errorf("expected an error parsing a template with unknown functions")
+	}
+
+	funcs := NewFuncRegistry()
+	funcs.Register("tenant", func(s string) string { return "tenant-" + s })
+	funcs.Register("explode", func() string { panic("unexpected") })
+
+	tmpl, err := NewTemplateWithFuncs(path, fs, funcs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	out, err := tmpl.Write(config.TemplateConfig{Profile: "default"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(string(out), "tenant-default 255.0.0.0") {
+		t.Errorf("expected the output of the registered functions but got %q", out)
+	}
+
+	if _, err := tmpl.Write(config.TemplateConfig{Profile: "panic"}); err == nil {
+		t.Errorf("expected an error when a function panics")
+	}
+}
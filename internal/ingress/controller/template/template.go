@@ -21,26 +21,31 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"math/rand"
 	"net"
 	"net/url"
 	"os"
 	"os/exec"
+	"regexp"
 	"strconv"
 	"strings"
 	text_template "text/template"
 	"time"
 
-	"github.com/golang/glog"
 	"github.com/pkg/errors"
 
 	extensions "k8s.io/api/extensions/v1beta1"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/ingress-nginx/internal/file"
 	"k8s.io/ingress-nginx/internal/ingress"
-	"k8s.io/ingress-nginx/internal/ingress/annotations/influxdb"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/compression"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/connectproxy"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/cors"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/proxycache"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/ratelimit"
 	"k8s.io/ingress-nginx/internal/ingress/controller/config"
+	"k8s.io/ingress-nginx/internal/log"
 	ing_net "k8s.io/ingress-nginx/internal/net"
 )
 
@@ -60,12 +65,18 @@ type Template struct {
 //NewTemplate returns a new Template instance or an
 //error if the specified template file contains errors
 func NewTemplate(file string, fs file.Filesystem) (*Template, error) {
+	return NewTemplateWithFuncs(file, fs, NewFuncRegistry())
+}
+
+// NewTemplateWithFuncs returns a new Template instance that can use the
+// functions of a registry in addition to the built-in ones
+func NewTemplateWithFuncs(file string, fs file.Filesystem, funcs *FuncRegistry) (*Template, error) {
 	data, err := fs.ReadFile(file)
 	if err != nil {
 		return nil, errors.Wrapf(err, "unexpected error reading template %v", file)
 	}
 
-	tmpl, err := text_template.New("nginx.tmpl").Funcs(funcMap).Parse(string(data))
+	tmpl, err := text_template.New("nginx.tmpl").Funcs(funcs.funcMap()).Parse(string(data))
 	if err != nil {
 		return nil, err
 	}
@@ -85,12 +96,12 @@ func (t *Template) Write(conf config.TemplateConfig) ([]byte, error) {
 	outCmdBuf := t.bp.Get()
 	defer t.bp.Put(outCmdBuf)
 
-	if glog.V(3) {
+	if log.V(3) {
 		b, err := json.Marshal(conf)
 		if err != nil {
-			glog.Errorf("unexpected error: %v", err)
+			log.Errorf("unexpected error: %v", err)
 		}
-		glog.Infof("NGINX configuration: %v", string(b))
+		log.Infof("NGINX configuration: %v", string(b))
 	}
 
 	err := t.tmpl.Execute(tmplBuf, conf)
@@ -104,7 +115,7 @@ func (t *Template) Write(conf config.TemplateConfig) ([]byte, error) {
 	cmd.Stdin = tmplBuf
 	cmd.Stdout = outCmdBuf
 	if err := cmd.Run(); err != nil {
-		glog.Warningf("unexpected error cleaning template: %v", err)
+		log.Warningf("unexpected error cleaning template: %v", err)
 		return tmplBuf.Bytes(), nil
 	}
 
@@ -125,11 +136,23 @@ var (
 		"buildLuaSharedDictionaries": buildLuaSharedDictionaries,
 		"buildLocation":              buildLocation,
 		"buildAuthLocation":          buildAuthLocation,
+		"buildShadowLocation":        buildShadowLocation,
+		"internalLocationPrefixes":   internalLocationPrefixes,
 		"buildAuthResponseHeaders":   buildAuthResponseHeaders,
+		"buildAuthOIDCPath":          buildAuthOIDCPath,
 		"buildLoadBalancingConfig":   buildLoadBalancingConfig,
 		"buildProxyPass":             buildProxyPass,
+		"buildProxySSL":              buildProxySSL,
+		"isTLSUpstream":              isTLSUpstream,
+		"buildLabelVariables":        buildLabelVariables,
+		"buildInternalListen":        buildInternalListen,
+		"buildRealIP":                buildRealIP,
+		"hasWebSocketHeavyLocations": hasWebSocketHeavyLocations,
+		"hasAuthCacheLocations":      hasAuthCacheLocations,
 		"filterRateLimits":           filterRateLimits,
 		"buildRateLimitZones":        buildRateLimitZones,
+		"buildAccessLogFormats":      buildAccessLogFormats,
+		"buildAccessLogFormatName":   buildAccessLogFormatName,
 		"buildRateLimit":             buildRateLimit,
 		"buildResolversForLua":       buildResolversForLua,
 		"buildResolvers":             buildResolvers,
@@ -137,6 +160,8 @@ var (
 		"isLocationInLocationList":   isLocationInLocationList,
 		"isLocationAllowed":          isLocationAllowed,
 		"buildLogFormatUpstream":     buildLogFormatUpstream,
+		"buildAccessLog":             buildAccessLog,
+		"buildErrorLog":              buildErrorLog,
 		"buildDenyVariable":          buildDenyVariable,
 		"getenv":                     os.Getenv,
 		"contains":                   strings.Contains,
@@ -145,6 +170,7 @@ var (
 		"trimSpace":                  strings.TrimSpace,
 		"toUpper":                    strings.ToUpper,
 		"toLower":                    strings.ToLower,
+		"join":                       strings.Join,
 		"formatIP":                   formatIP,
 		"buildNextUpstream":          buildNextUpstream,
 		"getIngressInformation":      getIngressInformation,
@@ -157,8 +183,13 @@ var (
 		"buildOpentracing":            buildOpentracing,
 		"proxySetHeader":              proxySetHeader,
 		"buildInfluxDB":               buildInfluxDB,
+		"excludeCompressionTypes":     excludeCompressionTypes,
+		"buildProxyCache":             buildProxyCache,
 		"enforceRegexModifier":        enforceRegexModifier,
 		"stripLocationModifer":        stripLocationModifer,
+		"buildCorsOriginMaps":         buildCorsOriginMaps,
+		"buildCorsOriginVariable":     buildCorsOriginVariable,
+		"buildCorsOrigin":             buildCorsOrigin,
 	}
 )
 
@@ -198,18 +229,34 @@ func shouldConfigureLuaRestyWAF(disableLuaRestyWAF bool, mode string) bool {
 	return false
 }
 
-func buildLuaSharedDictionaries(s interface{}, disableLuaRestyWAF bool) string {
+// luaSharedDicts contains the Lua shared dictionaries always defined,
+// with the size used by default and with the low-memory profile
+var luaSharedDicts = []struct {
+	name          string
+	size          string
+	lowMemorySize string
+}{
+	{"configuration_data", "5M", "1M"},
+	{"certificate_data", "16M", "2M"},
+	{"locks", "512k", "128k"},
+	{"sticky_sessions", "1M", "256k"},
+	{"websockets", "1M", "256k"},
+}
+
+func buildLuaSharedDictionaries(s interface{}, disableLuaRestyWAF bool, profile string) string {
 	servers, ok := s.([]*ingress.Server)
 	if !ok {
-		glog.Errorf("expected an '[]*ingress.Server' type but %T was returned", s)
+		log.Errorf("expected an '[]*ingress.Server' type but %T was returned", s)
 		return ""
 	}
 
-	out := []string{
-		"lua_shared_dict configuration_data 5M",
-		"lua_shared_dict certificate_data 16M",
-		"lua_shared_dict locks 512k",
-		"lua_shared_dict sticky_sessions 1M",
+	out := []string{}
+	for _, dict := range luaSharedDicts {
+		size := dict.size
+		if profile == config.LowMemoryProfile {
+			size = dict.lowMemorySize
+		}
+		out = append(out, fmt.Sprintf("lua_shared_dict %v %v", dict.name, size))
 	}
 
 	if !disableLuaRestyWAF {
@@ -228,6 +275,21 @@ func buildLuaSharedDictionaries(s interface{}, disableLuaRestyWAF bool) string {
 		}
 	}
 
+	authOIDCEnabled := func() bool {
+		for _, server := range servers {
+			for _, location := range server.Locations {
+				if location.AuthOIDC.Discovery != "" {
+					return true
+				}
+			}
+		}
+		return false
+	}()
+	if authOIDCEnabled {
+		// names used by lua-resty-openidc to cache the discovery documents and keys
+		out = append(out, "lua_shared_dict discovery 1M", "lua_shared_dict jwks 1M")
+	}
+
 	if len(out) == 0 {
 		return ""
 	}
@@ -237,12 +299,12 @@ func buildLuaSharedDictionaries(s interface{}, disableLuaRestyWAF bool) string {
 func buildResolversForLua(res interface{}, disableIpv6 interface{}) string {
 	nss, ok := res.([]net.IP)
 	if !ok {
-		glog.Errorf("expected a '[]net.IP' type but %T was returned", res)
+		log.Errorf("expected a '[]net.IP' type but %T was returned", res)
 		return ""
 	}
 	no6, ok := disableIpv6.(bool)
 	if !ok {
-		glog.Errorf("expected a 'bool' type but %T was returned", disableIpv6)
+		log.Errorf("expected a 'bool' type but %T was returned", disableIpv6)
 		return ""
 	}
 
@@ -266,12 +328,12 @@ func buildResolvers(res interface{}, disableIpv6 interface{}) string {
 	// NGINX need IPV6 addresses to be surrounded by brackets
 	nss, ok := res.([]net.IP)
 	if !ok {
-		glog.Errorf("expected a '[]net.IP' type but %T was returned", res)
+		log.Errorf("expected a '[]net.IP' type but %T was returned", res)
 		return ""
 	}
 	no6, ok := disableIpv6.(bool)
 	if !ok {
-		glog.Errorf("expected a 'bool' type but %T was returned", disableIpv6)
+		log.Errorf("expected a 'bool' type but %T was returned", disableIpv6)
 		return ""
 	}
 
@@ -315,7 +377,7 @@ func stripLocationModifer(path string) string {
 func enforceRegexModifier(input interface{}) bool {
 	locations, ok := input.([]*ingress.Location)
 	if !ok {
-		glog.Errorf("expected an '[]*ingress.Location' type but %T was returned", input)
+		log.Errorf("expected an '[]*ingress.Location' type but %T was returned", input)
 		return false
 	}
 
@@ -332,7 +394,7 @@ func enforceRegexModifier(input interface{}) bool {
 func buildLocation(input interface{}, enforceRegex bool) string {
 	location, ok := input.(*ingress.Location)
 	if !ok {
-		glog.Errorf("expected an '*ingress.Location' type but %T was returned", input)
+		log.Errorf("expected an '*ingress.Location' type but %T was returned", input)
 		return slash
 	}
 
@@ -359,7 +421,7 @@ func buildLocation(input interface{}, enforceRegex bool) string {
 func buildAuthLocation(input interface{}) string {
 	location, ok := input.(*ingress.Location)
 	if !ok {
-		glog.Errorf("expected an '*ingress.Location' type but %T was returned", input)
+		log.Errorf("expected an '*ingress.Location' type but %T was returned", input)
 		return ""
 	}
 
@@ -370,14 +432,52 @@ func buildAuthLocation(input interface{}) string {
 	str := base64.URLEncoding.EncodeToString([]byte(location.Path))
 	// removes "=" after encoding
 	str = strings.Replace(str, "=", "", -1)
-	return fmt.Sprintf("/_external-auth-%v", str)
+	return fmt.Sprintf("%v%v", ingress.ExternalAuthLocationPrefix, str)
+}
+
+// buildShadowLocation returns the path of the internal location that sends
+// the copies of the requests of a location to its shadow backends
+func buildShadowLocation(input interface{}) string {
+	location, ok := input.(*ingress.Location)
+	if !ok {
+		log.Errorf("expected an '*ingress.Location' type but %T was returned", input)
+		return ""
+	}
+
+	if !location.Shadow {
+		return ""
+	}
+
+	str := base64.URLEncoding.EncodeToString([]byte(location.Path))
+	// removes "=" after encoding
+	str = strings.Replace(str, "=", "", -1)
+	return fmt.Sprintf("%v%v", ingress.ShadowLocationPrefix, str)
+}
+
+// internalLocationPrefixes returns the prefixes of the paths of the internal
+// locations, which must not be reachable by requests from clients
+func internalLocationPrefixes() []string {
+	return ingress.InternalLocationPrefixes
+}
+
+// buildAuthOIDCPath returns the path used by the OpenID Connect flow for
+// the location. Paths are relative to the location path so the requests
+// are handled by the same location that requires authentication.
+func buildAuthOIDCPath(input interface{}, path string) string {
+	location, ok := input.(*ingress.Location)
+	if !ok {
+		log.Errorf("expected an '*ingress.Location' type but %T was returned", input)
+		return path
+	}
+
+	return strings.TrimSuffix(location.Path, slash) + path
 }
 
 func buildAuthResponseHeaders(input interface{}) []string {
 	location, ok := input.(*ingress.Location)
 	res := []string{}
 	if !ok {
-		glog.Errorf("expected an '*ingress.Location' type but %T was returned", input)
+		log.Errorf("expected an '*ingress.Location' type but %T was returned", input)
 		return res
 	}
 
@@ -397,17 +497,40 @@ func buildAuthResponseHeaders(input interface{}) []string {
 func buildLogFormatUpstream(input interface{}) string {
 	cfg, ok := input.(config.Configuration)
 	if !ok {
-		glog.Errorf("expected a 'config.Configuration' type but %T was returned", input)
+		log.Errorf("expected a 'config.Configuration' type but %T was returned", input)
 		return ""
 	}
 
 	return cfg.BuildLogFormatUpstream()
 }
 
+// buildAccessLog returns the parameters of the access_log
+// directive for the log format
+func buildAccessLog(input interface{}, format string) string {
+	cfg, ok := input.(config.Configuration)
+	if !ok {
+		log.Errorf("expected a 'config.Configuration' type but %T was returned", input)
+		return ""
+	}
+
+	return cfg.BuildAccessLog(format)
+}
+
+// buildErrorLog returns the destination of the error logs
+func buildErrorLog(input interface{}) string {
+	cfg, ok := input.(config.Configuration)
+	if !ok {
+		log.Errorf("expected a 'config.Configuration' type but %T was returned", input)
+		return ""
+	}
+
+	return cfg.BuildErrorLogDestination()
+}
+
 func buildLoadBalancingConfig(b interface{}, fallbackLoadBalancing string) string {
 	backend, ok := b.(*ingress.Backend)
 	if !ok {
-		glog.Errorf("expected an '*ingress.Backend' type but %T was returned", b)
+		log.Errorf("expected an '*ingress.Backend' type but %T was returned", b)
 		return ""
 	}
 
@@ -429,6 +552,185 @@ func buildLoadBalancingConfig(b interface{}, fallbackLoadBalancing string) strin
 	return fmt.Sprintf("%s;", fallbackLoadBalancing)
 }
 
+// isTLSUpstream returns true if the location connects to the upstream
+// servers using TLS (HTTPS or GRPCS backend protocol)
+func isTLSUpstream(loc interface{}) bool {
+	location, ok := loc.(*ingress.Location)
+	if !ok {
+		log.Errorf("expected an '*ingress.Location' type but %T was returned", loc)
+		return false
+	}
+
+	return location.BackendProtocol == "HTTPS" || location.BackendProtocol == "GRPCS"
+}
+
+// buildLabelVariables returns the set directives of the NGINX variables with
+// the labels of the Service and the Ingress of a location listed in the keys
+// service-labels and ingress-labels. The variables are defined in all the
+// locations, empty when the label is missing, so the log format can use them.
+func buildLabelVariables(c interface{}, loc interface{}) string {
+	cfg, ok := c.(config.Configuration)
+	if !ok {
+		log.Errorf("expected a 'config.Configuration' type but %T was returned", c)
+		return ""
+	}
+
+	location, ok := loc.(*ingress.Location)
+	if !ok {
+		log.Errorf("expected an '*ingress.Location' type but %T was returned", loc)
+		return ""
+	}
+
+	buf := bytes.NewBufferString("")
+	for _, label := range cfg.ServiceLabels {
+		fmt.Fprintf(buf, "set $%v %q;\n", config.LabelVariable(config.ServiceLabelPrefix, label), location.ServiceLabels[label])
+	}
+	for _, label := range cfg.IngressLabels {
+		fmt.Fprintf(buf, "set $%v %q;\n", config.LabelVariable(config.IngressLabelPrefix, label), location.IngressLabels[label])
+	}
+
+	return buf.String()
+}
+
+// buildInternalListen returns the listen directives of the internal ports
+// for the servers with the annotation internal and for the catch-all server,
+// the default server of the internal ports
+func buildInternalListen(c interface{}, s interface{}) string {
+	all, ok := c.(config.TemplateConfig)
+	if !ok {
+		log.Errorf("expected a 'config.TemplateConfig' type but %T was returned", c)
+		return ""
+	}
+
+	server, ok := s.(*ingress.Server)
+	if !ok {
+		log.Errorf("expected an '*ingress.Server' type but %T was returned", s)
+		return ""
+	}
+
+	ports := all.ListenPorts
+	if !ports.HasInternal() || (!server.Internal && server.Hostname != "_") {
+		return ""
+	}
+
+	var addresses []string
+	if ports.InternalAddress != "" {
+		addresses = append(addresses, formatIP(ports.InternalAddress)+":")
+	} else {
+		if all.HTTPListen.IPv4 {
+			addresses = append(addresses, "")
+		}
+		if all.HTTPListen.IPv6 {
+			addresses = append(addresses, "[::]:")
+		}
+	}
+
+	defaultServer := ""
+	if server.Hostname == "_" {
+		defaultServer = " default_server"
+	}
+
+	http2 := ""
+	if all.Cfg.UseHTTP2 {
+		http2 = " http2"
+	}
+
+	buf := bytes.NewBufferString("")
+	for _, address := range addresses {
+		if ports.Internal > 0 {
+			fmt.Fprintf(buf, "listen %v%v%v;\n", address, ports.Internal, defaultServer)
+		}
+		if ports.InternalHTTPS > 0 && server.SSLCert.PemFileName != "" {
+			fmt.Fprintf(buf, "listen %v%v%v ssl%v;\n", address, ports.InternalHTTPS, defaultServer, http2)
+		}
+	}
+
+	return buf.String()
+}
+
+// buildRealIP returns the directives of the real IP module of a server with
+// the annotations proxy-real-ip-cidr or forwarded-for-header. The values not
+// set in the annotations are the ones of the configuration.
+func buildRealIP(c interface{}, s interface{}) string {
+	cfg, ok := c.(config.Configuration)
+	if !ok {
+		log.Errorf("expected a 'config.Configuration' type but %T was returned", c)
+		return ""
+	}
+
+	server, ok := s.(*ingress.Server)
+	if !ok {
+		log.Errorf("expected an '*ingress.Server' type but %T was returned", s)
+		return ""
+	}
+
+	if server.RealIP.IsEmpty() {
+		return ""
+	}
+
+	header := server.RealIP.Header
+	if header == "" {
+		header = cfg.ForwardedForHeader
+		if cfg.UseProxyProtocol {
+			header = "proxy_protocol"
+		}
+	}
+
+	cidrs := server.RealIP.CIDRs
+	if len(cidrs) == 0 {
+		cidrs = cfg.ProxyRealIPCIDR
+	}
+
+	buf := bytes.NewBufferString("")
+	fmt.Fprintf(buf, "real_ip_header %v;\n", header)
+	buf.WriteString("real_ip_recursive on;\n")
+	for _, cidr := range cidrs {
+		fmt.Fprintf(buf, "set_real_ip_from %v;\n", cidr)
+	}
+
+	return buf.String()
+}
+
+// hasWebSocketHeavyLocations returns true if any location has the
+// annotation websocket-heavy
+func hasWebSocketHeavyLocations(s interface{}) bool {
+	servers, ok := s.([]*ingress.Server)
+	if !ok {
+		log.Errorf("expected an '[]*ingress.Server' type but %T was returned", s)
+		return false
+	}
+
+	for _, server := range servers {
+		for _, location := range server.Locations {
+			if location.WebSocketHeavy {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// hasAuthCacheLocations returns true if any location caches the
+// responses of the external authentication (annotation auth-cache-key)
+func hasAuthCacheLocations(s interface{}) bool {
+	servers, ok := s.([]*ingress.Server)
+	if !ok {
+		log.Errorf("expected an '[]*ingress.Server' type but %T was returned", s)
+		return false
+	}
+
+	for _, server := range servers {
+		for _, location := range server.Locations {
+			if location.ExternalAuth.AuthCacheKey != "" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 // buildProxyPass produces the proxy pass string, if the ingress has redirects
 // (specified through the nginx.ingress.kubernetes.io/rewrite-target annotation)
 // If the annotation nginx.ingress.kubernetes.io/add-base-url:"true" is specified it will
@@ -436,13 +738,13 @@ func buildLoadBalancingConfig(b interface{}, fallbackLoadBalancing string) strin
 func buildProxyPass(host string, b interface{}, loc interface{}) string {
 	backends, ok := b.([]*ingress.Backend)
 	if !ok {
-		glog.Errorf("expected an '[]*ingress.Backend' type but %T was returned", b)
+		log.Errorf("expected an '[]*ingress.Backend' type but %T was returned", b)
 		return ""
 	}
 
 	location, ok := loc.(*ingress.Location)
 	if !ok {
-		glog.Errorf("expected a '*ingress.Location' type but %T was returned", loc)
+		log.Errorf("expected a '*ingress.Location' type but %T was returned", loc)
 		return ""
 	}
 
@@ -463,9 +765,18 @@ func buildProxyPass(host string, b interface{}, loc interface{}) string {
 	case "AJP":
 		proto = ""
 		proxyPass = "ajp_pass"
+	case "FCGI":
+		proto = ""
+		proxyPass = "fastcgi_pass"
 	}
 
 	upstreamName := "upstream_balancer"
+	if location.ConnectProxy.Address != "" {
+		// the controller tunnels the connections through the forward proxy
+		upstreamName = "unix:" + connectproxy.SocketPath(location.Backend, location.ConnectProxy.Address)
+	} else if location.WebSocketHeavy {
+		upstreamName = "upstream_balancer_websocket"
+	}
 
 	for _, backend := range backends {
 		if backend.Name == location.Backend {
@@ -537,6 +848,96 @@ rewrite "(?i)%s$" %s/ break;
 	return defProxyPass
 }
 
+// buildProxySSL returns the directives of the TLS connections to the upstream
+// servers of a location with the backend protocol HTTPS or GRPCS, like the
+// client certificate and the verification of the certificate of the servers.
+// The SNI configured in the backend of the location is sent when the location
+// does not define a server name. The Lua balancer replaces the variable
+// $proxy_ssl_server_name when it selects an alternative backend.
+func buildProxySSL(b interface{}, loc interface{}) string {
+	backends, ok := b.([]*ingress.Backend)
+	if !ok {
+		log.Errorf("expected an '[]*ingress.Backend' type but %T was returned", b)
+		return ""
+	}
+
+	location, ok := loc.(*ingress.Location)
+	if !ok {
+		log.Errorf("expected an '*ingress.Location' type but %T was returned", loc)
+		return ""
+	}
+
+	var prefix string
+	switch location.BackendProtocol {
+	case "HTTPS":
+		prefix = "proxy_ssl"
+	case "GRPCS":
+		prefix = "grpc_ssl"
+	default:
+		return ""
+	}
+
+	cfg := location.ProxySSL
+	buf := bytes.NewBufferString("")
+	if cfg.CAFileName != "" {
+		// the file contains the client certificate and its key before the CA certificates
+		fmt.Fprintf(buf, "# PEM sha: %v\n", cfg.PemSHA)
+		if cfg.ClientCertificate {
+			fmt.Fprintf(buf, "%v_certificate %v;\n", prefix, cfg.CAFileName)
+			fmt.Fprintf(buf, "%v_certificate_key %v;\n", prefix, cfg.CAFileName)
+		}
+		fmt.Fprintf(buf, "%v_trusted_certificate %v;\n", prefix, cfg.CAFileName)
+	}
+	if cfg.Verify {
+		fmt.Fprintf(buf, "%v_verify on;\n", prefix)
+	}
+	if cfg.Name != "" {
+		fmt.Fprintf(buf, "%v_name %v;\n", prefix, cfg.Name)
+		fmt.Fprintf(buf, "%v_server_name on;\n", prefix)
+	} else if serverName, ok := backendSSLServerName(backends, location.Backend); ok {
+		fmt.Fprintf(buf, "set $proxy_ssl_server_name \"%v\";\n", serverName)
+		fmt.Fprintf(buf, "%v_name $proxy_ssl_server_name;\n", prefix)
+		fmt.Fprintf(buf, "%v_server_name on;\n", prefix)
+	}
+	if cfg.Protocols != "" {
+		fmt.Fprintf(buf, "%v_protocols %v;\n", prefix, cfg.Protocols)
+	}
+
+	return buf.String()
+}
+
+// backendSSLServerName returns the server name sent with SNI to a backend,
+// empty when it is disabled, and true if the SNI is enabled in the backend
+// or in any of its alternative backends
+func backendSSLServerName(backends []*ingress.Backend, name string) (string, bool) {
+	byName := make(map[string]*ingress.Backend, len(backends))
+	for _, backend := range backends {
+		byName[backend.Name] = backend
+	}
+
+	backend, ok := byName[name]
+	if !ok {
+		return "", false
+	}
+
+	serverName := ""
+	if backend.SSLServerName.Enabled {
+		serverName = backend.SSLServerName.Name
+		if serverName == "" {
+			serverName = "$proxy_upstream_vhost"
+		}
+	}
+
+	enabled := backend.SSLServerName.Enabled
+	for _, alternative := range backend.AlternativeBackends {
+		if b, ok := byName[alternative]; ok && b.SSLServerName.Enabled {
+			enabled = true
+		}
+	}
+
+	return serverName, enabled
+}
+
 // TODO: Needs Unit Tests
 func filterRateLimits(input interface{}) []ratelimit.Config {
 	ratelimits := []ratelimit.Config{}
@@ -544,7 +945,7 @@ func filterRateLimits(input interface{}) []ratelimit.Config {
 
 	servers, ok := input.([]*ingress.Server)
 	if !ok {
-		glog.Errorf("expected a '[]ratelimit.RateLimit' type but %T was returned", input)
+		log.Errorf("expected a '[]ratelimit.RateLimit' type but %T was returned", input)
 		return ratelimits
 	}
 	for _, server := range servers {
@@ -558,6 +959,123 @@ func filterRateLimits(input interface{}) []ratelimit.Config {
 	return ratelimits
 }
 
+// corsOriginMap is a map of the header Origin of the requests to the value
+// of the header Access-Control-Allow-Origin, empty for origins not allowed
+type corsOriginMap struct {
+	Variable string
+	Origins  []string
+	Regex    string
+}
+
+// corsOrigins returns the origins of a CORS configuration matched exactly
+func corsOrigins(c cors.Config) []string {
+	if len(c.CorsAllowOrigins) > 0 {
+		return c.CorsAllowOrigins
+	}
+	if c.CorsAllowOrigin != "" && c.CorsAllowOrigin != "*" {
+		return []string{c.CorsAllowOrigin}
+	}
+	return nil
+}
+
+// buildCorsOriginVariable returns the variable with the value of the header
+// Access-Control-Allow-Origin for a CORS configuration matching the origin
+// of the requests. Configurations allowing the same origins use the same variable.
+func buildCorsOriginVariable(input interface{}) string {
+	c, ok := input.(cors.Config)
+	if !ok {
+		log.Errorf("expected a 'cors.Config' type but %T was returned", input)
+		return ""
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(strings.Join(corsOrigins(c), ",")))
+	h.Write([]byte{0})
+	h.Write([]byte(c.CorsAllowOriginRegex))
+	return fmt.Sprintf("$cors_origin_%x", h.Sum32())
+}
+
+// buildCorsOrigin returns the value of the header Access-Control-Allow-Origin
+// for a CORS configuration, the variable of its map when it matches the origin
+func buildCorsOrigin(input interface{}) string {
+	c, ok := input.(cors.Config)
+	if !ok {
+		log.Errorf("expected a 'cors.Config' type but %T was returned", input)
+		return ""
+	}
+
+	if c.MatchesOrigin() {
+		return buildCorsOriginVariable(c)
+	}
+
+	return c.CorsAllowOrigin
+}
+
+// buildCorsOriginMaps returns the maps of the origins allowed by the
+// locations with CORS enabled and several origins or a regex
+func buildCorsOriginMaps(input interface{}) []corsOriginMap {
+	maps := []corsOriginMap{}
+	found := sets.String{}
+
+	servers, ok := input.([]*ingress.Server)
+	if !ok {
+		log.Errorf("expected a '[]*ingress.Server' type but %T was returned", input)
+		return maps
+	}
+
+	for _, server := range servers {
+		for _, loc := range server.Locations {
+			if !loc.CorsConfig.CorsEnabled || !loc.CorsConfig.MatchesOrigin() {
+				continue
+			}
+
+			variable := buildCorsOriginVariable(loc.CorsConfig)
+			if found.Has(variable) {
+				continue
+			}
+			found.Insert(variable)
+
+			maps = append(maps, corsOriginMap{
+				Variable: variable,
+				Origins:  corsOrigins(loc.CorsConfig),
+				Regex:    loc.CorsConfig.CorsAllowOriginRegex,
+			})
+		}
+	}
+
+	return maps
+}
+
+// buildAccessLogFormats returns the custom access log formats
+// defined in the locations using the access-log-format annotation
+func buildAccessLogFormats(input interface{}) []string {
+	formats := sets.String{}
+
+	servers, ok := input.([]*ingress.Server)
+	if !ok {
+		log.Errorf("expected a '[]*ingress.Server' type but %T was returned", input)
+		return formats.List()
+	}
+
+	for _, server := range servers {
+		for _, loc := range server.Locations {
+			if loc.Logs.Access && loc.Logs.Format != "" {
+				formats.Insert(loc.Logs.Format)
+			}
+		}
+	}
+
+	return formats.List()
+}
+
+// buildAccessLogFormatName returns the name of the log_format
+// used for a custom access log format
+func buildAccessLogFormatName(format string) string {
+	h := fnv.New32a()
+	h.Write([]byte(format))
+	return fmt.Sprintf("upstreaminfo_%x", h.Sum32())
+}
+
 // TODO: Needs Unit Tests
 // buildRateLimitZones produces an array of limit_conn_zone in order to allow
 // rate limiting of request. Each Ingress rule could have up to three zones, one
@@ -568,7 +1086,7 @@ func buildRateLimitZones(input interface{}) []string {
 
 	servers, ok := input.([]*ingress.Server)
 	if !ok {
-		glog.Errorf("expected a '[]*ingress.Server' type but %T was returned", input)
+		log.Errorf("expected a '[]*ingress.Server' type but %T was returned", input)
 		return zones.List()
 	}
 
@@ -618,7 +1136,7 @@ func buildRateLimit(input interface{}) []string {
 
 	loc, ok := input.(*ingress.Location)
 	if !ok {
-		glog.Errorf("expected an '*ingress.Location' type but %T was returned", input)
+		log.Errorf("expected an '*ingress.Location' type but %T was returned", input)
 		return limits
 	}
 
@@ -658,7 +1176,7 @@ func buildRateLimit(input interface{}) []string {
 func isLocationInLocationList(location interface{}, rawLocationList string) bool {
 	loc, ok := location.(*ingress.Location)
 	if !ok {
-		glog.Errorf("expected an '*ingress.Location' type but %T was returned", location)
+		log.Errorf("expected an '*ingress.Location' type but %T was returned", location)
 		return false
 	}
 
@@ -680,7 +1198,7 @@ func isLocationInLocationList(location interface{}, rawLocationList string) bool
 func isLocationAllowed(input interface{}) bool {
 	loc, ok := input.(*ingress.Location)
 	if !ok {
-		glog.Errorf("expected an '*ingress.Location' type but %T was returned", input)
+		log.Errorf("expected an '*ingress.Location' type but %T was returned", input)
 		return false
 	}
 
@@ -699,7 +1217,7 @@ var (
 func buildDenyVariable(a interface{}) string {
 	l, ok := a.(string)
 	if !ok {
-		glog.Errorf("expected a 'string' type but %T was returned", a)
+		log.Errorf("expected a 'string' type but %T was returned", a)
 		return ""
 	}
 
@@ -713,7 +1231,7 @@ func buildDenyVariable(a interface{}) string {
 func buildUpstreamName(loc interface{}) string {
 	location, ok := loc.(*ingress.Location)
 	if !ok {
-		glog.Errorf("expected a '*ingress.Location' type but %T was returned", loc)
+		log.Errorf("expected a '*ingress.Location' type but %T was returned", loc)
 		return ""
 	}
 
@@ -738,7 +1256,7 @@ func isSticky(host string, loc *ingress.Location, stickyLocations map[string][]s
 func buildNextUpstream(i, r interface{}) string {
 	nextUpstream, ok := i.(string)
 	if !ok {
-		glog.Errorf("expected a 'string' type but %T was returned", i)
+		log.Errorf("expected a 'string' type but %T was returned", i)
 		return ""
 	}
 
@@ -767,7 +1285,7 @@ func buildNextUpstream(i, r interface{}) string {
 func isValidClientBodyBufferSize(input interface{}) bool {
 	s, ok := input.(string)
 	if !ok {
-		glog.Errorf("expected an 'string' type but %T was returned", input)
+		log.Errorf("expected an 'string' type but %T was returned", input)
 		return false
 	}
 
@@ -791,7 +1309,7 @@ func isValidClientBodyBufferSize(input interface{}) bool {
 			return true
 		}
 
-		glog.Errorf("client-body-buffer-size '%v' was provided in an incorrect format, hence it will not be set.", s)
+		log.Errorf("client-body-buffer-size '%v' was provided in an incorrect format, hence it will not be set.", s)
 		return false
 	}
 
@@ -808,13 +1326,13 @@ type ingressInformation struct {
 func getIngressInformation(i, p interface{}) *ingressInformation {
 	ing, ok := i.(*extensions.Ingress)
 	if !ok {
-		glog.Errorf("expected an '*extensions.Ingress' type but %T was returned", i)
+		log.Errorf("expected an '*extensions.Ingress' type but %T was returned", i)
 		return &ingressInformation{}
 	}
 
 	path, ok := p.(string)
 	if !ok {
-		glog.Errorf("expected a 'string' type but %T was returned", p)
+		log.Errorf("expected a 'string' type but %T was returned", p)
 		return &ingressInformation{}
 	}
 
@@ -851,7 +1369,7 @@ func getIngressInformation(i, p interface{}) *ingressInformation {
 func buildForwardedFor(input interface{}) string {
 	s, ok := input.(string)
 	if !ok {
-		glog.Errorf("expected a 'string' type but %T was returned", input)
+		log.Errorf("expected a 'string' type but %T was returned", input)
 		return ""
 	}
 
@@ -863,7 +1381,7 @@ func buildForwardedFor(input interface{}) string {
 func buildAuthSignURL(input interface{}) string {
 	s, ok := input.(string)
 	if !ok {
-		glog.Errorf("expected an 'string' type but %T was returned", input)
+		log.Errorf("expected an 'string' type but %T was returned", input)
 		return ""
 	}
 
@@ -898,7 +1416,7 @@ func randomString() string {
 func buildOpentracing(input interface{}) string {
 	cfg, ok := input.(config.Configuration)
 	if !ok {
-		glog.Errorf("expected a 'config.Configuration' type but %T was returned", input)
+		log.Errorf("expected a 'config.Configuration' type but %T was returned", input)
 		return ""
 	}
 
@@ -918,33 +1436,158 @@ func buildOpentracing(input interface{}) string {
 	return buf.String()
 }
 
-// buildInfluxDB produces the single line configuration
-// needed by the InfluxDB module to send request's metrics
-// for the current resource
+// buildInfluxDB returns the influxdb directive of a location. The variables
+// $namespace, $ingress, $service and $backend in the measurement and the
+// server name are replaced with the values of the location.
 func buildInfluxDB(input interface{}) string {
-	cfg, ok := input.(influxdb.Config)
+	location, ok := input.(*ingress.Location)
 	if !ok {
-		glog.Errorf("expected an 'influxdb.Config' type but %T was returned", input)
+		log.Errorf("expected an '*ingress.Location' type but %T was returned", input)
 		return ""
 	}
 
+	cfg := location.InfluxDB
 	if !cfg.InfluxDBEnabled {
 		return ""
 	}
 
+	namespace, ingressName, service := "", "", ""
+	if location.Ingress != nil {
+		namespace = location.Ingress.Namespace
+		ingressName = location.Ingress.Name
+	}
+	if location.Service != nil {
+		service = location.Service.Name
+	}
+
+	r := strings.NewReplacer(
+		"$namespace", influxDBName(namespace),
+		"$ingress", influxDBName(ingressName),
+		"$service", influxDBName(service),
+		"$backend", influxDBName(location.Backend),
+	)
+
 	return fmt.Sprintf(
 		"influxdb server_name=%s host=%s port=%s measurement=%s enabled=true;",
-		cfg.InfluxDBServerName,
+		r.Replace(cfg.InfluxDBServerName),
 		cfg.InfluxDBHost,
 		cfg.InfluxDBPort,
-		cfg.InfluxDBMeasurement,
+		r.Replace(cfg.InfluxDBMeasurement),
 	)
 }
 
+var invalidInfluxDBNameChars = regexp.MustCompile(`[^a-zA-Z0-9_.\-]`)
+
+// influxDBName returns a value safe to be used as measurement name or tag
+// value in the InfluxDB line protocol and as a parameter of a directive
+func influxDBName(value string) string {
+	if value == "" {
+		return "none"
+	}
+
+	return invalidInfluxDBNameChars.ReplaceAllString(value, "_")
+}
+
+// excludeCompressionTypes removes the MIME types excluded from the compression
+// in a location from a list of types. Exclusions ending with /* remove all
+// the types with the same prefix, i.e. image/*. The text/html type is used
+// when all the types are excluded, since the directives require a value.
+func excludeCompressionTypes(types string, input interface{}) string {
+	cfg, ok := input.(compression.Config)
+	if !ok {
+		log.Errorf("expected a 'compression.Config' type but %T was returned", input)
+		return types
+	}
+
+	if len(cfg.ExcludeTypes) == 0 {
+		return types
+	}
+
+	result := []string{}
+	for _, t := range strings.Fields(types) {
+		excluded := false
+		for _, exclude := range cfg.ExcludeTypes {
+			if t == exclude || (strings.HasSuffix(exclude, "/*") && strings.HasPrefix(t, strings.TrimSuffix(exclude, "*"))) {
+				excluded = true
+				break
+			}
+		}
+
+		if !excluded {
+			result = append(result, t)
+		}
+	}
+
+	if len(result) == 0 {
+		return "text/html"
+	}
+
+	return strings.Join(result, " ")
+}
+
+// buildProxyCache returns the directives required to cache the responses of a
+// location. The zone must be declared in the proxy-cache-zones configmap key
+// or the location will not be cached.
+func buildProxyCache(input interface{}, z interface{}) []string {
+	cfg, ok := input.(proxycache.Config)
+	if !ok {
+		log.Errorf("expected a 'proxycache.Config' type but %T was returned", input)
+		return []string{}
+	}
+
+	zones, ok := z.([]config.ProxyCacheZone)
+	if !ok {
+		log.Errorf("expected a '[]config.ProxyCacheZone' type but %T was returned", z)
+		return []string{}
+	}
+
+	if cfg.Zone == "" {
+		return []string{}
+	}
+
+	found := false
+	for _, zone := range zones {
+		if zone.Name == cfg.Zone {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		log.Warningf("cache zone %v is not defined in the configuration configmap", cfg.Zone)
+		return []string{}
+	}
+
+	key := cfg.Key
+	if cfg.Compressed {
+		// one variant for each compression method requested to the upstream servers
+		key = fmt.Sprintf("%v$proxy_cache_encoding", key)
+	}
+
+	directives := []string{
+		fmt.Sprintf("proxy_cache %v;", cfg.Zone),
+		fmt.Sprintf("proxy_cache_key \"%v\";", key),
+	}
+
+	for _, valid := range cfg.Valid {
+		directives = append(directives, fmt.Sprintf("proxy_cache_valid %v;", valid))
+	}
+
+	if cfg.Bypass != "" {
+		directives = append(directives,
+			fmt.Sprintf("proxy_cache_bypass %v;", cfg.Bypass),
+			fmt.Sprintf("proxy_no_cache %v;", cfg.Bypass))
+	}
+
+	directives = append(directives, "add_header X-Cache-Status $upstream_cache_status;")
+
+	return directives
+}
+
 func proxySetHeader(loc interface{}) string {
 	location, ok := loc.(*ingress.Location)
 	if !ok {
-		glog.Errorf("expected a '*ingress.Location' type but %T was returned", loc)
+		log.Errorf("expected a '*ingress.Location' type but %T was returned", loc)
 		return "proxy_set_header"
 	}
 
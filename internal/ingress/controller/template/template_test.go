@@ -29,12 +29,23 @@ import (
 	"fmt"
 
 	jsoniter "github.com/json-iterator/go"
+	extensions "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/ingress-nginx/internal/file"
 	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/authoidc"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/authreq"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/compression"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/connectproxy"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/cors"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/influxdb"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/luarestywaf"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/proxycache"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/proxyssl"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/realip"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/rewrite"
 	"k8s.io/ingress-nginx/internal/ingress/controller/config"
+	"k8s.io/ingress-nginx/internal/ingress/resolver"
 )
 
 var (
@@ -346,18 +357,52 @@ func TestBuildLuaSharedDictionaries(t *testing.T) {
 		},
 	}
 
-	config := buildLuaSharedDictionaries(servers, false)
-	if !strings.Contains(config, "lua_shared_dict configuration_data") {
-		t.Errorf("expected to include 'configuration_data' but got %s", config)
+	dicts := buildLuaSharedDictionaries(servers, false, config.DefaultProfile)
+	if !strings.Contains(dicts, "lua_shared_dict configuration_data") {
+		t.Errorf("expected to include 'configuration_data' but got %s", dicts)
 	}
-	if strings.Contains(config, "waf_storage") {
-		t.Errorf("expected to not include 'waf_storage' but got %s", config)
+	if strings.Contains(dicts, "waf_storage") {
+		t.Errorf("expected to not include 'waf_storage' but got %s", dicts)
 	}
 
 	servers[1].Locations[0].LuaRestyWAF = luarestywaf.Config{Mode: "ACTIVE"}
-	config = buildLuaSharedDictionaries(servers, false)
-	if !strings.Contains(config, "lua_shared_dict waf_storage") {
-		t.Errorf("expected to configure 'waf_storage', but got %s", config)
+	dicts = buildLuaSharedDictionaries(servers, false, config.DefaultProfile)
+	if !strings.Contains(dicts, "lua_shared_dict waf_storage") {
+		t.Errorf("expected to configure 'waf_storage', but got %s", dicts)
+	}
+
+	if strings.Contains(dicts, "lua_shared_dict discovery") {
+		t.Errorf("expected to not include 'discovery' but got %s", dicts)
+	}
+
+	servers[0].Locations[0].AuthOIDC = authoidc.Config{Discovery: "https://accounts.example.com"}
+	dicts = buildLuaSharedDictionaries(servers, false, config.DefaultProfile)
+	if !strings.Contains(dicts, "lua_shared_dict discovery") || !strings.Contains(dicts, "lua_shared_dict jwks") {
+		t.Errorf("expected to configure 'discovery' and 'jwks', but got %s", dicts)
+	}
+
+	dicts = buildLuaSharedDictionaries(servers, false, config.LowMemoryProfile)
+	if !strings.Contains(dicts, "lua_shared_dict certificate_data 2M") {
+		t.Errorf("expected to reduce the size of 'certificate_data', but got %s", dicts)
+	}
+}
+
+func TestBuildAuthOIDCPath(t *testing.T) {
+	cases := map[string]struct {
+		Location string
+		Path     string
+		Expected string
+	}{
+		"root location":     {"/", "/oauth2/callback", "/oauth2/callback"},
+		"location":          {"/app", "/oauth2/callback", "/app/oauth2/callback"},
+		"location trailing": {"/app/", "/oauth2/logout", "/app/oauth2/logout"},
+	}
+
+	for k, tc := range cases {
+		res := buildAuthOIDCPath(&ingress.Location{Path: tc.Location}, tc.Path)
+		if res != tc.Expected {
+			t.Errorf("%s: expected '%v' but returned '%v'", k, tc.Expected, res)
+		}
 	}
 }
 
@@ -434,6 +479,167 @@ func TestBuildProxyPass(t *testing.T) {
 	}
 }
 
+func TestBuildProxyPassProtocols(t *testing.T) {
+	for protocol, expected := range map[string]string{
+		"HTTP":  "proxy_pass http://upstream_balancer;",
+		"GRPC":  "grpc_pass grpc://upstream_balancer;",
+		"AJP":   "ajp_pass upstream_balancer;",
+		"FCGI":  "fastcgi_pass upstream_balancer;",
+		"HTTPS": "proxy_pass https://upstream_balancer;",
+	} {
+		loc := &ingress.Location{
+			Path:            "/",
+			Backend:         "upstream-name",
+			BackendProtocol: protocol,
+		}
+
+		pp := buildProxyPass("example.com", []*ingress.Backend{{Name: "upstream-name"}}, loc)
+		if pp != expected {
+			t.Errorf("%v: expected \n'%v'\nbut returned \n'%v'", protocol, expected, pp)
+		}
+	}
+}
+
+func TestBuildProxySSL(t *testing.T) {
+	loc := &ingress.Location{
+		BackendProtocol: "HTTPS",
+		ProxySSL: proxyssl.Config{
+			AuthSSLCert: resolver.AuthSSLCert{
+				Secret:     "default/client",
+				CAFileName: "/etc/ingress-controller/ssl/default-client.pem",
+				PemSHA:     "123",
+			},
+			ClientCertificate: true,
+			Verify:            true,
+			Name:              "backend.example.com",
+			Protocols:         "TLSv1.2 TLSv1.3",
+		},
+	}
+
+	expected := `# PEM sha: 123
+proxy_ssl_certificate /etc/ingress-controller/ssl/default-client.pem;
+proxy_ssl_certificate_key /etc/ingress-controller/ssl/default-client.pem;
+proxy_ssl_trusted_certificate /etc/ingress-controller/ssl/default-client.pem;
+proxy_ssl_verify on;
+proxy_ssl_name backend.example.com;
+proxy_ssl_server_name on;
+proxy_ssl_protocols TLSv1.2 TLSv1.3;
+`
+	if actual := buildProxySSL([]*ingress.Backend{}, loc); actual != expected {
+		t.Errorf("expected \n'%v'\nbut returned \n'%v'", expected, actual)
+	}
+
+	loc.BackendProtocol = "GRPCS"
+	loc.ProxySSL = proxyssl.Config{Verify: true}
+	if actual := buildProxySSL([]*ingress.Backend{}, loc); actual != "grpc_ssl_verify on;\n" {
+		t.Errorf("expected grpc_ssl directives but returned '%v'", actual)
+	}
+
+	loc.BackendProtocol = "HTTP"
+	if actual := buildProxySSL([]*ingress.Backend{}, loc); actual != "" {
+		t.Errorf("expected no directives for an HTTP backend but returned '%v'", actual)
+	}
+}
+
+func TestBuildProxySSLServerName(t *testing.T) {
+	loc := &ingress.Location{
+		Backend:         "app",
+		BackendProtocol: "HTTPS",
+	}
+	backends := []*ingress.Backend{
+		{Name: "app", AlternativeBackends: []string{"canary"}},
+		{Name: "canary", NoServer: true},
+	}
+
+	if actual := buildProxySSL(backends, loc); actual != "" {
+		t.Errorf("expected no SNI without server name but returned '%v'", actual)
+	}
+
+	backends[1].SSLServerName = proxyssl.ServerName{Enabled: true, Name: "canary.example.com"}
+	expected := `set $proxy_ssl_server_name "";
+proxy_ssl_name $proxy_ssl_server_name;
+proxy_ssl_server_name on;
+`
+	if actual := buildProxySSL(backends, loc); actual != expected {
+		t.Errorf("expected \n'%v'\nbut returned \n'%v'", expected, actual)
+	}
+
+	backends[0].SSLServerName = proxyssl.ServerName{Enabled: true}
+	expected = `set $proxy_ssl_server_name "$proxy_upstream_vhost";
+proxy_ssl_name $proxy_ssl_server_name;
+proxy_ssl_server_name on;
+`
+	if actual := buildProxySSL(backends, loc); actual != expected {
+		t.Errorf("expected \n'%v'\nbut returned \n'%v'", expected, actual)
+	}
+
+	loc.ProxySSL.Name = "backend.example.com"
+	expected = `proxy_ssl_name backend.example.com;
+proxy_ssl_server_name on;
+`
+	if actual := buildProxySSL(backends, loc); actual != expected {
+		t.Errorf("expected \n'%v'\nbut returned \n'%v'", expected, actual)
+	}
+}
+
+func TestBuildProxyPassWithConnectProxy(t *testing.T) {
+	loc := &ingress.Location{
+		Path:         "/",
+		Backend:      "upstream-name",
+		ConnectProxy: connectproxy.Config{Address: "proxy.corp:3128"},
+	}
+
+	expected := fmt.Sprintf("proxy_pass http://unix:%v;", connectproxy.SocketPath("upstream-name", "proxy.corp:3128"))
+	pp := buildProxyPass("example.com", []*ingress.Backend{{Name: "upstream-name"}}, loc)
+	if pp != expected {
+		t.Errorf("expected \n'%v'\nbut returned \n'%v'", expected, pp)
+	}
+}
+
+func TestBuildProxyPassWebSocketHeavy(t *testing.T) {
+	loc := &ingress.Location{
+		Path:           "/",
+		Backend:        "upstream-name",
+		WebSocketHeavy: true,
+	}
+
+	expected := "proxy_pass http://upstream_balancer_websocket;"
+	pp := buildProxyPass("example.com", []*ingress.Backend{{Name: "upstream-name"}}, loc)
+	if pp != expected {
+		t.Errorf("expected \n'%v'\nbut returned \n'%v'", expected, pp)
+	}
+
+	servers := []*ingress.Server{{Locations: []*ingress.Location{{Path: "/"}, loc}}}
+	if !hasWebSocketHeavyLocations(servers) {
+		t.Errorf("expected a location with the annotation websocket-heavy")
+	}
+
+	loc.WebSocketHeavy = false
+	if hasWebSocketHeavyLocations(servers) {
+		t.Errorf("expected no location with the annotation websocket-heavy")
+	}
+}
+
+func TestHasAuthCacheLocations(t *testing.T) {
+	loc := &ingress.Location{
+		Path: "/",
+		ExternalAuth: authreq.Config{
+			URL:          "foo.com/auth",
+			AuthCacheKey: "$remote_user$http_authorization",
+		},
+	}
+
+	servers := []*ingress.Server{{Locations: []*ingress.Location{{Path: "/api"}, loc}}}
+	if !hasAuthCacheLocations(servers) {
+		t.Errorf("expected a location with the annotation auth-cache-key")
+	}
+
+	loc.ExternalAuth.AuthCacheKey = ""
+	if hasAuthCacheLocations(servers) {
+		t.Errorf("expected no location with the annotation auth-cache-key")
+	}
+}
+
 func TestBuildAuthLocation(t *testing.T) {
 	authURL := "foo.com/auth"
 
@@ -454,6 +660,23 @@ func TestBuildAuthLocation(t *testing.T) {
 	}
 }
 
+func TestBuildShadowLocation(t *testing.T) {
+	loc := &ingress.Location{Path: "/cat"}
+	if str := buildShadowLocation(loc); str != "" {
+		t.Errorf("Expected no shadow location but returned '%v'", str)
+	}
+
+	loc.Shadow = true
+	str := buildShadowLocation(loc)
+
+	encodedPath := strings.Replace(base64.URLEncoding.EncodeToString([]byte(loc.Path)), "=", "", -1)
+	expected := fmt.Sprintf("/_shadow-%v", encodedPath)
+
+	if str != expected {
+		t.Errorf("Expected \n'%v'\nbut returned \n'%v'", expected, str)
+	}
+}
+
 func TestBuildAuthResponseHeaders(t *testing.T) {
 	loc := &ingress.Location{
 		ExternalAuth: authreq.Config{ResponseHeaders: []string{"h1", "H-With-Caps-And-Dashes"}},
@@ -489,6 +712,8 @@ func TestTemplateWithData(t *testing.T) {
 	if dat.ListenPorts == nil {
 		dat.ListenPorts = &config.ListenPorts{}
 	}
+	dat.Cfg.RetryAfterUnavailable = 5
+	dat.ShutdownMarker = "/tmp/nginx-shutdown"
 
 	fs, err := file.NewFakeFS()
 	if err != nil {
@@ -516,6 +741,14 @@ func TestTemplateWithData(t *testing.T) {
 	if !strings.Contains(string(rt), "listen 2.2.2.2") {
 		t.Errorf("invalid NGINX template, expected IPV4 listen address not present")
 	}
+
+	if !strings.Contains(string(rt), "more_set_headers -s 503 'Retry-After: 5';") {
+		t.Errorf("invalid NGINX template, expected Retry-After header not present")
+	}
+
+	if !strings.Contains(string(rt), "if (-f /tmp/nginx-shutdown) {") {
+		t.Errorf("invalid NGINX template, expected shutdown marker check not present")
+	}
 }
 
 func BenchmarkTemplateWithData(b *testing.B) {
@@ -607,6 +840,118 @@ func TestIsLocationAllowed(t *testing.T) {
 	}
 }
 
+func TestIsTLSUpstream(t *testing.T) {
+	for protocol, expected := range map[string]bool{
+		"HTTP":  false,
+		"HTTPS": true,
+		"GRPC":  false,
+		"GRPCS": true,
+		"AJP":   false,
+		"FCGI":  false,
+	} {
+		loc := &ingress.Location{BackendProtocol: protocol}
+		if actual := isTLSUpstream(loc); actual != expected {
+			t.Errorf("%v: expected '%v' but returned '%v'", protocol, expected, actual)
+		}
+	}
+
+	if isTLSUpstream(nil) {
+		t.Errorf("expected false for an invalid location")
+	}
+}
+
+func TestBuildLabelVariables(t *testing.T) {
+	cfg := config.Configuration{
+		ServiceLabels: []string{"team", "app.kubernetes.io/part-of"},
+		IngressLabels: []string{"owner"},
+	}
+	loc := &ingress.Location{
+		ServiceLabels: map[string]string{"team": "payments"},
+	}
+
+	expected := `set $service_label_team "payments";
+set $service_label_app_kubernetes_io_part_of "";
+set $ingress_label_owner "";
+`
+	if actual := buildLabelVariables(cfg, loc); actual != expected {
+		t.Errorf("expected '%v' but returned '%v'", expected, actual)
+	}
+
+	if actual := buildLabelVariables(config.NewDefault(), loc); actual != "" {
+		t.Errorf("expected no variables without labels but returned '%v'", actual)
+	}
+}
+
+func TestBuildInternalListen(t *testing.T) {
+	all := config.TemplateConfig{
+		Cfg:         config.NewDefault(),
+		ListenPorts: &config.ListenPorts{HTTP: 80, HTTPS: 443, Internal: 8080, InternalHTTPS: 8443},
+		HTTPListen:  config.ListenIPFamily{IPv4: true},
+	}
+
+	internal := &ingress.Server{Hostname: "internal.example.com", Internal: true}
+	if actual, expected := buildInternalListen(all, internal), "listen 8080;\n"; actual != expected {
+		t.Errorf("expected '%v' but returned '%v'", expected, actual)
+	}
+
+	internal.SSLCert.PemFileName = "/etc/ingress-controller/ssl/internal.pem"
+	all.HTTPListen.IPv6 = true
+	expected := `listen 8080;
+listen 8443 ssl http2;
+listen [::]:8080;
+listen [::]:8443 ssl http2;
+`
+	if actual := buildInternalListen(all, internal); actual != expected {
+		t.Errorf("expected '%v' but returned '%v'", expected, actual)
+	}
+
+	all.ListenPorts.InternalAddress = "10.0.0.1"
+	catchAll := &ingress.Server{Hostname: "_"}
+	if actual, expected := buildInternalListen(all, catchAll), "listen 10.0.0.1:8080 default_server;\n"; actual != expected {
+		t.Errorf("expected '%v' but returned '%v'", expected, actual)
+	}
+
+	if actual := buildInternalListen(all, &ingress.Server{Hostname: "public.example.com"}); actual != "" {
+		t.Errorf("expected no internal listen directives for a public server but returned '%v'", actual)
+	}
+
+	all.ListenPorts.Internal = 0
+	all.ListenPorts.InternalHTTPS = 0
+	if actual := buildInternalListen(all, catchAll); actual != "" {
+		t.Errorf("expected no internal listen directives without internal ports but returned '%v'", actual)
+	}
+}
+
+func TestBuildRealIP(t *testing.T) {
+	cfg := config.NewDefault()
+	cfg.ProxyRealIPCIDR = []string{"10.0.0.0/8"}
+
+	server := &ingress.Server{Hostname: "example.com"}
+	if actual := buildRealIP(cfg, server); actual != "" {
+		t.Errorf("expected no directives for a server without annotations but returned '%v'", actual)
+	}
+
+	server.RealIP = realip.Config{Header: "CF-Connecting-IP"}
+	expected := `real_ip_header CF-Connecting-IP;
+real_ip_recursive on;
+set_real_ip_from 10.0.0.0/8;
+`
+	if actual := buildRealIP(cfg, server); actual != expected {
+		t.Errorf("expected '%v' but returned '%v'", expected, actual)
+	}
+
+	cfg.UseProxyProtocol = true
+	server.RealIP = realip.Config{CIDRs: []string{"173.245.48.0/20", "2400:cb00::/32"}}
+	expected = `real_ip_header proxy_protocol;
+real_ip_recursive on;
+set_real_ip_from 173.245.48.0/20;
+set_real_ip_from 2400:cb00::/32;
+`
+	if actual := buildRealIP(cfg, server); actual != expected {
+		t.Errorf("expected '%v' but returned '%v'", expected, actual)
+	}
+}
+
 func TestBuildForwardedFor(t *testing.T) {
 	inputStr := "X-Forwarded-For"
 	outputStr := buildForwardedFor(inputStr)
@@ -730,6 +1075,127 @@ func TestBuildRateLimit(t *testing.T) {
 	}
 }
 
+func TestBuildAccessLogFormats(t *testing.T) {
+	format := "$remote_addr $status"
+
+	locs := []*ingress.Location{
+		{Path: "/"},
+		{Path: "/a"},
+		{Path: "/b"},
+		{Path: "/c"},
+	}
+	locs[0].Logs.Access = true
+	locs[1].Logs.Access = true
+	locs[1].Logs.Format = format
+	locs[2].Logs.Access = true
+	locs[2].Logs.Format = format
+	locs[3].Logs.Format = "$status"
+
+	formats := buildAccessLogFormats([]*ingress.Server{{Hostname: "example.com", Locations: locs}})
+	if !reflect.DeepEqual(formats, []string{format}) {
+		t.Errorf("expected %v but returned %v", []string{format}, formats)
+	}
+
+	name := buildAccessLogFormatName(format)
+	if !strings.HasPrefix(name, "upstreaminfo_") {
+		t.Errorf("expected a name with the prefix upstreaminfo_ but returned %v", name)
+	}
+	if name != buildAccessLogFormatName(format) || name == buildAccessLogFormatName("$status") {
+		t.Errorf("expected a stable and distinct name for each format")
+	}
+}
+
+func TestBuildCorsOriginMaps(t *testing.T) {
+	origins := cors.Config{
+		CorsEnabled:      true,
+		CorsAllowOrigin:  "https://a.example.com,https://b.example.com",
+		CorsAllowOrigins: []string{"https://a.example.com", "https://b.example.com"},
+	}
+	regex := cors.Config{
+		CorsEnabled:          true,
+		CorsAllowOrigin:      "https://example.com",
+		CorsAllowOriginRegex: `^https://[a-z]+\.example\.com$`,
+	}
+	single := cors.Config{
+		CorsEnabled:     true,
+		CorsAllowOrigin: "https://example.com",
+	}
+
+	locs := []*ingress.Location{
+		{Path: "/", CorsConfig: origins},
+		{Path: "/a", CorsConfig: origins},
+		{Path: "/b", CorsConfig: regex},
+		{Path: "/c", CorsConfig: single},
+	}
+
+	maps := buildCorsOriginMaps([]*ingress.Server{{Hostname: "example.com", Locations: locs}})
+	expected := []corsOriginMap{
+		{Variable: buildCorsOriginVariable(origins), Origins: origins.CorsAllowOrigins},
+		{Variable: buildCorsOriginVariable(regex), Origins: []string{"https://example.com"}, Regex: regex.CorsAllowOriginRegex},
+	}
+	if !reflect.DeepEqual(maps, expected) {
+		t.Errorf("expected %v but returned %v", expected, maps)
+	}
+
+	if !strings.HasPrefix(expected[0].Variable, "$cors_origin_") || expected[0].Variable == expected[1].Variable {
+		t.Errorf("expected a distinct variable for each map but returned %v and %v", expected[0].Variable, expected[1].Variable)
+	}
+
+	if origin := buildCorsOrigin(regex); origin != expected[1].Variable {
+		t.Errorf("expected %v but returned %v", expected[1].Variable, origin)
+	}
+	if origin := buildCorsOrigin(single); origin != "https://example.com" {
+		t.Errorf("expected https://example.com but returned %v", origin)
+	}
+}
+
+func TestBuildInfluxDB(t *testing.T) {
+	loc := &ingress.Location{
+		Backend: "default-app-80",
+		Ingress: &extensions.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app"},
+		},
+		InfluxDB: influxdb.Config{
+			InfluxDBEnabled:     true,
+			InfluxDBMeasurement: "reqs-$backend",
+			InfluxDBPort:        "8089",
+			InfluxDBHost:        "127.0.0.1",
+			InfluxDBServerName:  "$namespace.$ingress.$service",
+		},
+	}
+
+	expected := "influxdb server_name=default.app.none host=127.0.0.1 port=8089 measurement=reqs-default-app-80 enabled=true;"
+	if directive := buildInfluxDB(loc); directive != expected {
+		t.Errorf("expected %v but returned %v", expected, directive)
+	}
+
+	loc.InfluxDB.InfluxDBEnabled = false
+	if directive := buildInfluxDB(loc); directive != "" {
+		t.Errorf("expected no directive but returned %v", directive)
+	}
+}
+
+func TestExcludeCompressionTypes(t *testing.T) {
+	types := "application/json image/svg+xml image/x-icon text/css"
+
+	testCases := map[string]struct {
+		exclude  []string
+		expected string
+	}{
+		"no exclusions":      {nil, types},
+		"exact type":         {[]string{"text/css"}, "application/json image/svg+xml image/x-icon"},
+		"wildcard":           {[]string{"image/*"}, "application/json text/css"},
+		"all types excluded": {[]string{"image/*", "application/json", "text/css"}, "text/html"},
+	}
+
+	for k, tc := range testCases {
+		result := excludeCompressionTypes(types, compression.Config{ExcludeTypes: tc.exclude})
+		if result != tc.expected {
+			t.Errorf("%v: expected %v but returned %v", k, tc.expected, result)
+		}
+	}
+}
+
 func TestBuildAuthSignURL(t *testing.T) {
 	cases := map[string]struct {
 		Input, Output string
@@ -834,3 +1300,52 @@ func TestEscapeLiteralDollar(t *testing.T) {
 		t.Errorf("Expected %v but returned %v", expected, escapedPath)
 	}
 }
+
+func TestBuildProxyCache(t *testing.T) {
+	zones := []config.ProxyCacheZone{{Name: "static", Size: "10m"}}
+
+	cfg := proxycache.Config{
+		Zone:   "static",
+		Valid:  []string{"200 302 10m", "404 1m"},
+		Key:    "$scheme$host$request_uri",
+		Bypass: "$http_pragma",
+	}
+
+	expected := []string{
+		"proxy_cache static;",
+		"proxy_cache_key \"$scheme$host$request_uri\";",
+		"proxy_cache_valid 200 302 10m;",
+		"proxy_cache_valid 404 1m;",
+		"proxy_cache_bypass $http_pragma;",
+		"proxy_no_cache $http_pragma;",
+		"add_header X-Cache-Status $upstream_cache_status;",
+	}
+
+	directives := buildProxyCache(cfg, zones)
+	if !reflect.DeepEqual(directives, expected) {
+		t.Errorf("Expected '%v' but returned '%v'", expected, directives)
+	}
+
+	cfg.Zone = "undefined"
+	directives = buildProxyCache(cfg, zones)
+	if len(directives) != 0 {
+		t.Errorf("Expected no directives for an undefined zone but returned '%v'", directives)
+	}
+
+	directives = buildProxyCache(proxycache.Config{}, zones)
+	if len(directives) != 0 {
+		t.Errorf("Expected no directives without zone but returned '%v'", directives)
+	}
+
+	cfg = proxycache.Config{Zone: "static", Key: "$host$uri", Compressed: true}
+	expected = []string{
+		"proxy_cache static;",
+		"proxy_cache_key \"$host$uri$proxy_cache_encoding\";",
+		"add_header X-Cache-Status $upstream_cache_status;",
+	}
+
+	directives = buildProxyCache(cfg, zones)
+	if !reflect.DeepEqual(directives, expected) {
+		t.Errorf("Expected '%v' but returned '%v'", expected, directives)
+	}
+}
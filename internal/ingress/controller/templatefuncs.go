@@ -0,0 +1,47 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	ngx_template "k8s.io/ingress-nginx/internal/ingress/controller/template"
+	"k8s.io/ingress-nginx/internal/log"
+)
+
+// newTemplateFuncs returns the functions available in the templates in
+// addition to the built-in ones, including the lookups in the local store
+func (n *NGINXController) newTemplateFuncs() *ngx_template.FuncRegistry {
+	funcs := ngx_template.NewFuncRegistry()
+
+	err := funcs.Register("serviceLabel", n.serviceLabel)
+	if err != nil {
+		log.Fatalf("Error registering template function: %v", err)
+	}
+
+	return funcs
+}
+
+// serviceLabel returns the value of a label of a Service, in the form
+// "namespace/name", or an empty string if the Service or the label do
+// not exist
+func (n *NGINXController) serviceLabel(key, label string) string {
+	svc, err := n.store.GetService(key)
+	if err != nil {
+		return ""
+	}
+
+	return svc.Labels[label]
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"k8s.io/ingress-nginx/internal/file"
+	"k8s.io/ingress-nginx/internal/log"
+)
+
+// configValidator tests NGINX configuration files using a fixed number
+// of workers. Each worker reuses the same temporal file for all the
+// tests and the checksum of the last valid configuration is kept to
+// avoid testing the same content again.
+type configValidator struct {
+	requests chan *validationRequest
+
+	// test runs "nginx -t" with the configuration file
+	test func(cfg string) ([]byte, error)
+
+	mu        sync.Mutex
+	lastValid [sha256.Size]byte
+	files     []string
+}
+
+type validationRequest struct {
+	cfg    []byte
+	result chan error
+}
+
+func newConfigValidator(workers int, test func(cfg string) ([]byte, error)) (*configValidator, error) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	v := &configValidator{
+		requests: make(chan *validationRequest),
+		test:     test,
+	}
+
+	for i := 0; i < workers; i++ {
+		tmpfile, err := ioutil.TempFile("", "nginx-cfg")
+		if err != nil {
+			v.Stop()
+			return nil, err
+		}
+		tmpfile.Close()
+
+		v.files = append(v.files, tmpfile.Name())
+		go v.worker(tmpfile.Name())
+	}
+
+	return v, nil
+}
+
+func (v *configValidator) worker(name string) {
+	for req := range v.requests {
+		req.result <- v.run(name, req.cfg)
+	}
+}
+
+func (v *configValidator) run(name string, cfg []byte) error {
+	err := ioutil.WriteFile(name, cfg, file.ReadWriteByUser)
+	if err != nil {
+		return err
+	}
+
+	out, err := v.test(name)
+	if err != nil {
+		// this error is different from the rest because it must be clear why nginx is not working
+		oe := fmt.Sprintf(`
+-------------------------------------------------------------------------------
+Error: %v
+%v
+-------------------------------------------------------------------------------
+`, err, string(out))
+		return errors.New(oe)
+	}
+
+	return nil
+}
+
+// Validate checks if the NGINX configuration is valid. It blocks
+// until one of the workers is available.
+func (v *configValidator) Validate(cfg []byte) error {
+	if len(cfg) == 0 {
+		return fmt.Errorf("invalid NGINX configuration (empty)")
+	}
+
+	checksum := sha256.Sum256(cfg)
+
+	v.mu.Lock()
+	valid := v.lastValid == checksum
+	v.mu.Unlock()
+	if valid {
+		log.V(3).Infof("Skipping test of the NGINX configuration (already validated)")
+		return nil
+	}
+
+	req := &validationRequest{
+		cfg:    cfg,
+		result: make(chan error, 1),
+	}
+	v.requests <- req
+	err := <-req.result
+	if err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	v.lastValid = checksum
+	v.mu.Unlock()
+
+	return nil
+}
+
+// Stop terminates the workers and removes the temporal files
+func (v *configValidator) Stop() {
+	close(v.requests)
+	for _, name := range v.files {
+		os.Remove(name)
+	}
+}
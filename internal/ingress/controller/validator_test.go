@@ -0,0 +1,68 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestConfigValidator(t *testing.T) {
+	tests := 0
+	v, err := newConfigValidator(2, func(cfg string) ([]byte, error) {
+		tests++
+		content, err := ioutil.ReadFile(cfg)
+		if err != nil {
+			return nil, err
+		}
+		if strings.Contains(string(content), "invalid") {
+			return []byte("unknown directive"), fmt.Errorf("exit status 1")
+		}
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer v.Stop()
+
+	if err := v.Validate([]byte{}); err == nil {
+		t.Errorf("expected an error testing an empty configuration")
+	}
+
+	if err := v.Validate([]byte("events {}")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := v.Validate([]byte("events {}")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if tests != 1 {
+		t.Errorf("expected the same valid configuration to be tested once but it was tested %v times", tests)
+	}
+
+	err = v.Validate([]byte("invalid {}"))
+	if err == nil || !strings.Contains(err.Error(), "unknown directive") {
+		t.Errorf("expected an error with the output of the test but %v was returned", err)
+	}
+	err = v.Validate([]byte("invalid {}"))
+	if err == nil {
+		t.Errorf("expected an error testing an invalid configuration again")
+	}
+	if tests != 3 {
+		t.Errorf("expected invalid configurations to be tested every time but it was tested %v times", tests-1)
+	}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"runtime/pprof"
+	"sync"
+	"time"
+
+	"k8s.io/ingress-nginx/internal/log"
+)
+
+// syncWatchdogCheckPeriod is the maximum time between checks of the
+// sync watchdog. Shorter deadlines are checked more often.
+const syncWatchdogCheckPeriod = 10 * time.Second
+
+// syncWatchdog keeps the start time of the sync in progress
+type syncWatchdog struct {
+	sync.Mutex
+	// started is the start time of the sync in progress, zero when
+	// no sync is running
+	started time.Time
+	// reported indicates the sync in progress was already reported as stuck
+	reported bool
+}
+
+// start records the start of a sync
+func (w *syncWatchdog) start(t time.Time) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.started = t
+	w.reported = false
+}
+
+// done records the end of the sync in progress
+func (w *syncWatchdog) done() {
+	w.Lock()
+	defer w.Unlock()
+
+	w.started = time.Time{}
+	w.reported = false
+}
+
+// expired returns the time the sync in progress is running when it is longer
+// than the deadline. Each sync is returned as expired only once.
+func (w *syncWatchdog) expired(deadline time.Duration, now time.Time) (time.Duration, bool) {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.started.IsZero() || w.reported {
+		return 0, false
+	}
+
+	elapsed := now.Sub(w.started)
+	if elapsed <= deadline {
+		return 0, false
+	}
+
+	w.reported = true
+	return elapsed, true
+}
+
+// syncWatchdogPeriod returns the time between checks of the sync watchdog
+func syncWatchdogPeriod(deadline time.Duration) time.Duration {
+	if deadline/2 < syncWatchdogCheckPeriod {
+		return deadline / 2
+	}
+
+	return syncWatchdogCheckPeriod
+}
+
+// goroutineStacks returns the stack traces of all the goroutines, in the
+// format used by Go when a program panics
+func goroutineStacks() string {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 2); err != nil {
+		return err.Error()
+	}
+
+	return buf.String()
+}
+
+// checkSyncWatchdog reports a sync running for longer than the deadline of
+// the watchdog, which usually means it is blocked, like in a deadlock, a hung
+// test of the configuration or a request to Lua that never completes. No other
+// sync runs in the meantime so the changes in the cluster are not applied.
+func (n *NGINXController) checkSyncWatchdog() {
+	elapsed, stuck := n.syncWatchdog.expired(n.cfg.SyncWatchdogDeadline, time.Now())
+	if !stuck {
+		return
+	}
+
+	n.metricCollector.IncSyncWatchdogTimeouts()
+	log.Errorf("The sync of the configuration is running for %v, longer than the sync watchdog deadline (%v). Goroutines:\n%v",
+		elapsed.Round(time.Second), n.cfg.SyncWatchdogDeadline, goroutineStacks())
+
+	if n.cfg.SyncWatchdogRestart {
+		log.Fatalf("Exiting to restart the controller because the sync of the configuration is stuck (--sync-watchdog-restart)")
+	}
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyncWatchdog(t *testing.T) {
+	now := time.Now()
+	w := &syncWatchdog{}
+
+	if _, stuck := w.expired(time.Minute, now); stuck {
+		t.Errorf("expected no stuck sync without a sync in progress")
+	}
+
+	w.start(now)
+	if _, stuck := w.expired(time.Minute, now.Add(30*time.Second)); stuck {
+		t.Errorf("expected no stuck sync before the deadline")
+	}
+
+	elapsed, stuck := w.expired(time.Minute, now.Add(2*time.Minute))
+	if !stuck {
+		t.Fatalf("expected a stuck sync after the deadline")
+	}
+	if elapsed != 2*time.Minute {
+		t.Errorf("expected the sync to be running for 2m but got %v", elapsed)
+	}
+
+	if _, stuck := w.expired(time.Minute, now.Add(3*time.Minute)); stuck {
+		t.Errorf("expected a stuck sync to be reported only once")
+	}
+
+	w.done()
+	w.start(now.Add(3 * time.Minute))
+	if _, stuck := w.expired(time.Minute, now.Add(5*time.Minute)); !stuck {
+		t.Errorf("expected the next stuck sync to be reported")
+	}
+
+	w.done()
+	if _, stuck := w.expired(time.Minute, now.Add(10*time.Minute)); stuck {
+		t.Errorf("expected no stuck sync after the sync is done")
+	}
+}
+
+func TestSyncWatchdogPeriod(t *testing.T) {
+	if p := syncWatchdogPeriod(10 * time.Minute); p != syncWatchdogCheckPeriod {
+		t.Errorf("expected a period of %v but got %v", syncWatchdogCheckPeriod, p)
+	}
+
+	if p := syncWatchdogPeriod(4 * time.Second); p != 2*time.Second {
+		t.Errorf("expected a period of 2s but got %v", p)
+	}
+}
+
+func TestGoroutineStacks(t *testing.T) {
+	if stacks := goroutineStacks(); !strings.Contains(stacks, "TestGoroutineStacks") {
+		t.Errorf("expected the stack traces to contain the running test but got:\n%v", stacks)
+	}
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/controller/process"
+	"k8s.io/ingress-nginx/internal/log"
+	"k8s.io/ingress-nginx/internal/task"
+)
+
+// websocketRetryPeriod is the time after which a deferred reload is retried
+const websocketRetryPeriod = 30 * time.Second
+
+// activeWebSockets returns the number of active WebSocket connections of the
+// locations with the annotation websocket-heavy, counted by NGINX for each
+// worker process. The connections of the processes not in pids, like the
+// workers killed after worker-shutdown-timeout, are ignored.
+func activeWebSockets(pids sets.Int) (int, error) {
+	client := configurationClient(5 * time.Second)
+	statusCode, body, err := getBody(client, configurationURL("/configuration/websockets"))
+	if err != nil {
+		return 0, err
+	}
+
+	if statusCode != 200 {
+		return 0, fmt.Errorf("unexpected status code %v reading the active WebSocket connections", statusCode)
+	}
+
+	counts := map[string]int{}
+	if err := json.Unmarshal([]byte(body), &counts); err != nil {
+		return 0, fmt.Errorf("invalid active WebSocket connections %q: %v", body, err)
+	}
+
+	active := 0
+	for pid, count := range counts {
+		p, err := strconv.Atoi(pid)
+		if err != nil || !pids.Has(p) {
+			continue
+		}
+
+		active += count
+	}
+
+	return active, nil
+}
+
+// deferReload indicates if a reload of NGINX must be deferred because the
+// active WebSocket connections exceed websocket-reload-threshold. A reload
+// is not deferred longer than websocket-reload-max-delay. A sync is
+// scheduled to retry the deferred reloads.
+func (n *NGINXController) deferReload() bool {
+	if n.cfg.WebSocketReloadThreshold <= 0 || len(n.runningConfig.Servers) == 0 {
+		return false
+	}
+
+	if !n.reloadDeferredSince.IsZero() && time.Since(n.reloadDeferredSince) > n.cfg.WebSocketReloadMaxDelay {
+		log.Warningf("Reloading NGINX after deferring the reload for %v", n.cfg.WebSocketReloadMaxDelay)
+		return false
+	}
+
+	workers, err := process.ListWorkers()
+	if err != nil {
+		log.Warningf("Error reading the NGINX worker processes: %v", err)
+		return false
+	}
+
+	pids := sets.NewInt()
+	for _, w := range workers {
+		pids.Insert(w.PID)
+	}
+
+	active, err := activeWebSockets(pids)
+	if err != nil {
+		log.Warningf("Error reading the active WebSocket connections: %v", err)
+		return false
+	}
+
+	if active <= n.cfg.WebSocketReloadThreshold {
+		return false
+	}
+
+	log.Infof("Deferring the reload of NGINX: %v active WebSocket connections exceed the threshold of %v",
+		active, n.cfg.WebSocketReloadThreshold)
+
+	if n.reloadDeferredSince.IsZero() {
+		n.reloadDeferredSince = time.Now()
+	}
+
+	if n.reloadRetry == nil {
+		n.reloadRetry = time.AfterFunc(websocketRetryPeriod, func() {
+			n.syncQueue.EnqueueTask(task.GetDummyObject("deferred-reload"))
+		})
+	} else {
+		n.reloadRetry.Reset(websocketRetryPeriod)
+	}
+
+	return true
+}
+
+// withRunningBackends returns a copy of a configuration that also contains
+// the backends of the running configuration it does not define. The backends
+// posted while a reload is deferred must include the ones still used by the
+// locations configured in NGINX.
+func withRunningBackends(pcfg, running *ingress.Configuration) *ingress.Configuration {
+	names := sets.NewString()
+	for _, b := range pcfg.Backends {
+		names.Insert(b.Name)
+	}
+
+	cfg := *pcfg
+	cfg.Backends = append([]*ingress.Backend{}, pcfg.Backends...)
+	for _, b := range running.Backends {
+		if !names.Has(b.Name) {
+			cfg.Backends = append(cfg.Backends, b)
+		}
+	}
+
+	return &cfg
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+)
+
+func TestActiveWebSockets(t *testing.T) {
+	stop := newConfigurationServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/configuration/websockets" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, `{"10":5,"20":3,"30":100}`)
+	}))
+	defer stop()
+
+	// the worker 30 does not exist anymore
+	active, err := activeWebSockets(sets.NewInt(10, 20))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if active != 8 {
+		t.Errorf("expected 8 active connections but %v returned", active)
+	}
+}
+
+func TestWithRunningBackends(t *testing.T) {
+	running := &ingress.Configuration{
+		Backends: []*ingress.Backend{{Name: "a"}, {Name: "removed"}},
+	}
+
+	updated := &ingress.Backend{Name: "a", NoServer: true}
+	pcfg := &ingress.Configuration{
+		Backends: []*ingress.Backend{updated, {Name: "new"}},
+	}
+
+	cfg := withRunningBackends(pcfg, running)
+
+	names := sets.NewString()
+	for _, b := range cfg.Backends {
+		names.Insert(b.Name)
+		if b.Name == "a" && b != updated {
+			t.Errorf("expected the backend of the new configuration")
+		}
+	}
+
+	if !names.Equal(sets.NewString("a", "new", "removed")) {
+		t.Errorf("expected the backends a, new and removed but returned %v", names.List())
+	}
+
+	if len(pcfg.Backends) != 2 {
+		t.Errorf("expected the configuration not to be modified")
+	}
+}
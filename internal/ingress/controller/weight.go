@@ -0,0 +1,65 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"fmt"
+	"strconv"
+
+	apiv1 "k8s.io/api/core/v1"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/log"
+)
+
+// endpointWeight returns the weight of an endpoint defined in an annotation
+// of the Pod or nil if the annotation is not present or is invalid
+func endpointWeight(pod *apiv1.Pod, annotation string) *int {
+	val, ok := pod.Annotations[annotation]
+	if !ok {
+		return nil
+	}
+
+	weight, err := strconv.Atoi(val)
+	if err != nil || weight < 0 {
+		log.Warningf("Invalid endpoint weight %q in Pod %v/%v, using the default weight", val, pod.Namespace, pod.Name)
+		return nil
+	}
+
+	return &weight
+}
+
+// setEndpointWeights sets the weight of the endpoints of an upstream using
+// the annotation of the Pods configured in the flag endpoint-weight-annotation
+func (n *NGINXController) setEndpointWeights(upstream *ingress.Backend) {
+	if n.cfg.EndpointWeightAnnotation == "" {
+		return
+	}
+
+	for i, ep := range upstream.Endpoints {
+		if ep.Target == nil || ep.Target.Kind != "Pod" {
+			continue
+		}
+
+		pod, err := n.store.GetPod(fmt.Sprintf("%v/%v", ep.Target.Namespace, ep.Target.Name))
+		if err != nil {
+			log.V(3).Infof("Error obtaining Pod of endpoint %v:%v: %v", ep.Address, ep.Port, err)
+			continue
+		}
+
+		upstream.Endpoints[i].Weight = endpointWeight(pod, n.cfg.EndpointWeightAnnotation)
+	}
+}
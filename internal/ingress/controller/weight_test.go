@@ -0,0 +1,64 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestEndpointWeight(t *testing.T) {
+	annotation := "ingress.kubernetes.io/endpoint-weight"
+
+	testCases := []struct {
+		annotations map[string]string
+		expected    *int
+	}{
+		{nil, nil},
+		{map[string]string{annotation: "10"}, intPtr(10)},
+		{map[string]string{annotation: "0"}, intPtr(0)},
+		{map[string]string{annotation: "-1"}, nil},
+		{map[string]string{annotation: "high"}, nil},
+	}
+
+	for _, tc := range testCases {
+		pod := &apiv1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "app",
+				Namespace:   "default",
+				Annotations: tc.annotations,
+			},
+		}
+
+		weight := endpointWeight(pod, annotation)
+		if tc.expected == nil {
+			if weight != nil {
+				t.Errorf("expected no weight for annotations %v but got %v", tc.annotations, *weight)
+			}
+			continue
+		}
+
+		if weight == nil || *weight != *tc.expected {
+			t.Errorf("expected weight %v for annotations %v but got %v", *tc.expected, tc.annotations, weight)
+		}
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/ingress-nginx/internal/ingress/controller/process"
+	"k8s.io/ingress-nginx/internal/log"
+)
+
+const (
+	workerCheckPeriod = 30 * time.Second
+
+	// maxWorkerReloads is the number of reloads kept to know since when
+	// the workers of previous configurations are shutting down
+	maxWorkerReloads = 100
+)
+
+// reloadHistory contains the time of the last reloads of NGINX
+type reloadHistory struct {
+	sync.Mutex
+	times []time.Time
+}
+
+// add records a reload of NGINX
+func (r *reloadHistory) add(t time.Time) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.times = append(r.times, t)
+	if len(r.times) > maxWorkerReloads {
+		r.times = r.times[len(r.times)-maxWorkerReloads:]
+	}
+}
+
+// list returns the time of the last reloads, oldest first
+func (r *reloadHistory) list() []time.Time {
+	r.Lock()
+	defer r.Unlock()
+
+	return append([]time.Time(nil), r.times...)
+}
+
+// shutdownAges returns, by PID, the time since the workers shutting down
+// received the reload that replaced them. The first reload after the start
+// of a worker is the one that replaced it. If that reload is unknown the
+// age is the time since the worker started.
+func shutdownAges(workers []process.Worker, reloads []time.Time, now time.Time) map[int]time.Duration {
+	ages := make(map[int]time.Duration)
+	for _, w := range workers {
+		if !w.ShuttingDown {
+			continue
+		}
+
+		since := w.StartTime
+		for _, reload := range reloads {
+			if reload.After(w.StartTime) {
+				since = reload
+				break
+			}
+		}
+
+		ages[w.PID] = now.Sub(since)
+	}
+
+	return ages
+}
+
+// parseNGINXTime parses a NGINX time value like 10s or 1m30s. A number
+// without unit is a number of seconds.
+func parseNGINXTime(val string) (time.Duration, error) {
+	if s, err := strconv.Atoi(val); err == nil {
+		return time.Duration(s) * time.Second, nil
+	}
+
+	return time.ParseDuration(val)
+}
+
+// checkLingeringWorkers updates the metrics of the NGINX workers of previous
+// configurations and reports the workers shutting down for longer than
+// worker-shutdown-timeout, usually because long-lived connections like
+// WebSockets keep them running.
+func (n *NGINXController) checkLingeringWorkers() {
+	workers, err := process.ListWorkers()
+	if err != nil {
+		log.Warningf("Error reading the NGINX worker processes: %v", err)
+		return
+	}
+
+	timeout, err := parseNGINXTime(n.store.GetBackendConfiguration().WorkerShutdownTimeout)
+	if err != nil {
+		log.Warningf("Invalid worker-shutdown-timeout: %v", err)
+		timeout = 0
+	}
+
+	ages := shutdownAges(workers, n.reloads.list(), time.Now())
+
+	var oldest time.Duration
+	lingering := sets.NewInt()
+	for pid, age := range ages {
+		if age > oldest {
+			oldest = age
+		}
+
+		if timeout <= 0 || age <= timeout {
+			continue
+		}
+
+		lingering.Insert(pid)
+		if n.lingeringWorkers.Has(pid) {
+			continue
+		}
+
+		msg := fmt.Sprintf("NGINX worker process %v is shutting down for %v, longer than worker-shutdown-timeout (%v)",
+			pid, age.Round(time.Second), timeout)
+		log.Warning(msg)
+		if n.recorder != nil {
+			if pod := n.controllerPod(); pod != nil {
+				n.recorder.Event(pod, apiv1.EventTypeWarning, "LingeringWorker", msg)
+			}
+		}
+	}
+
+	n.lingeringWorkers = lingering
+	n.metricCollector.SetLingeringWorkers(lingering.Len(), oldest)
+}
+
+// controllerPod returns the pod running the controller, used as the
+// object of the events not related to an Ingress
+func (n *NGINXController) controllerPod() *apiv1.Pod {
+	name, ns := os.Getenv("POD_NAME"), os.Getenv("POD_NAMESPACE")
+	if name == "" || ns == "" {
+		return nil
+	}
+
+	pod, err := n.cfg.Client.CoreV1().Pods(ns).Get(name, metav1.GetOptions{})
+	if err != nil {
+		log.Warningf("Error getting the controller pod %v/%v: %v", ns, name, err)
+		return nil
+	}
+
+	return pod
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/ingress-nginx/internal/ingress/controller/process"
+)
+
+func TestShutdownAges(t *testing.T) {
+	now := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+	reloads := []time.Time{
+		now.Add(-10 * time.Minute),
+		now.Add(-5 * time.Minute),
+		now.Add(-1 * time.Minute),
+	}
+
+	workers := []process.Worker{
+		// replaced by the second reload
+		{PID: 1, ShuttingDown: true, StartTime: now.Add(-10*time.Minute + time.Second)},
+		// replaced by the first reload
+		{PID: 2, ShuttingDown: true, StartTime: now.Add(-time.Hour)},
+		// replaced by the last reload
+		{PID: 3, ShuttingDown: true, StartTime: now.Add(-4 * time.Minute)},
+		// started before an unknown reload
+		{PID: 4, ShuttingDown: true, StartTime: now.Add(-30 * time.Second)},
+		{PID: 5, StartTime: now.Add(-30 * time.Second)},
+	}
+
+	expected := map[int]time.Duration{
+		1: 5 * time.Minute,
+		2: 10 * time.Minute,
+		3: time.Minute,
+		4: 30 * time.Second,
+	}
+
+	ages := shutdownAges(workers, reloads, now)
+	if !reflect.DeepEqual(ages, expected) {
+		t.Errorf("expected %v but returned %v", expected, ages)
+	}
+}
+
+func TestReloadHistory(t *testing.T) {
+	r := &reloadHistory{}
+	start := time.Now()
+	for i := 0; i < maxWorkerReloads+10; i++ {
+		r.add(start.Add(time.Duration(i) * time.Second))
+	}
+
+	reloads := r.list()
+	if len(reloads) != maxWorkerReloads {
+		t.Fatalf("expected %v reloads but returned %v", maxWorkerReloads, len(reloads))
+	}
+	if !reloads[0].Equal(start.Add(10 * time.Second)) {
+		t.Errorf("expected the oldest reloads to be removed but the first one is %v", reloads[0])
+	}
+}
+
+func TestParseNGINXTime(t *testing.T) {
+	testCases := map[string]struct {
+		value    string
+		expected time.Duration
+		err      bool
+	}{
+		"seconds":          {"10s", 10 * time.Second, false},
+		"minutes":          {"1m30s", 90 * time.Second, false},
+		"number":           {"240", 240 * time.Second, false},
+		"invalid duration": {"10x", 0, true},
+	}
+
+	for name, tc := range testCases {
+		d, err := parseNGINXTime(tc.value)
+		if (err != nil) != tc.err {
+			t.Errorf("%v: unexpected error %v", name, err)
+		}
+		if d != tc.expected {
+			t.Errorf("%v: expected %v but returned %v", name, tc.expected, d)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"sort"
+	"strings"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	ngx_config "k8s.io/ingress-nginx/internal/ingress/controller/config"
+	"k8s.io/ingress-nginx/internal/log"
+)
+
+// buildRedirectServers returns the servers that redirect to the servers with
+// the annotation from-to-www-redirect, from the same host with or without
+// the prefix www, unless that host is defined by an Ingress too. The
+// redirects use the certificate of the server and the configuration of
+// SSL redirects and ports of its root location.
+func buildRedirectServers(servers []*ingress.Server) []*ngx_config.RedirectServer {
+	hostnames := make(map[string]bool, len(servers))
+	for _, srv := range servers {
+		hostnames[srv.Hostname] = true
+	}
+
+	redirects := make(map[string]*ngx_config.RedirectServer)
+	for _, srv := range servers {
+		if !srv.RedirectFromToWWW {
+			continue
+		}
+
+		var from string
+		if strings.HasPrefix(srv.Hostname, "www.") {
+			from = strings.TrimPrefix(srv.Hostname, "www.")
+		} else {
+			from = "www." + srv.Hostname
+		}
+
+		if hostnames[from] || redirects[from] != nil {
+			continue
+		}
+
+		log.V(3).Infof("Creating redirect from %q to %q", from, srv.Hostname)
+		redirect := &ngx_config.RedirectServer{
+			From:    from,
+			To:      srv.Hostname,
+			SSLCert: srv.SSLCert,
+		}
+
+		for _, loc := range srv.Locations {
+			if loc.Path != rootLocation {
+				continue
+			}
+
+			redirect.SSLRedirect = loc.Rewrite.ForceSSLRedirect || (srv.SSLCert.PemFileName != "" && loc.Rewrite.SSLRedirect)
+			redirect.UsePortInRedirects = loc.UsePortInRedirects
+			break
+		}
+
+		if srv.SSLCert.Certificate != nil {
+			if err := verifyHostname(from, srv.SSLCert.Certificate); err != nil {
+				log.Warningf("The SSL certificate of server %q used to redirect from %q is not valid for it: %v", srv.Hostname, from, err)
+			}
+		}
+
+		redirects[from] = redirect
+	}
+
+	redirectServers := make([]*ngx_config.RedirectServer, 0, len(redirects))
+	for _, redirect := range redirects {
+		redirectServers = append(redirectServers, redirect)
+	}
+	sort.Slice(redirectServers, func(i, j int) bool {
+		return redirectServers[i].From < redirectServers[j].From
+	})
+
+	return redirectServers
+}
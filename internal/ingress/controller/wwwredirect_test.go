@@ -0,0 +1,86 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/rewrite"
+)
+
+func TestBuildRedirectServers(t *testing.T) {
+	servers := []*ingress.Server{
+		{
+			Hostname:          "www.example.com",
+			RedirectFromToWWW: true,
+			SSLCert:           ingress.SSLCert{PemFileName: "/etc/ingress-controller/ssl/example.pem"},
+			Locations: []*ingress.Location{
+				{Path: "/api"},
+				{
+					Path:               "/",
+					Rewrite:            rewrite.Config{SSLRedirect: true},
+					UsePortInRedirects: true,
+				},
+			},
+		},
+		{
+			Hostname:          "foo.bar",
+			RedirectFromToWWW: true,
+			Locations: []*ingress.Location{
+				{Path: "/", Rewrite: rewrite.Config{SSLRedirect: true}},
+			},
+		},
+		{
+			Hostname:          "defined.bar",
+			RedirectFromToWWW: true,
+		},
+		{
+			Hostname: "www.defined.bar",
+		},
+		{
+			Hostname: "other.bar",
+		},
+	}
+
+	redirects := buildRedirectServers(servers)
+	if len(redirects) != 2 {
+		t.Fatalf("expected 2 redirect servers but %v were returned", len(redirects))
+	}
+
+	example := redirects[0]
+	if example.From != "example.com" || example.To != "www.example.com" {
+		t.Errorf("expected a redirect from example.com to www.example.com but it is from %q to %q", example.From, example.To)
+	}
+	if example.SSLCert.PemFileName != "/etc/ingress-controller/ssl/example.pem" {
+		t.Errorf("expected the redirect to use the certificate of www.example.com but it uses %q", example.SSLCert.PemFileName)
+	}
+	if !example.SSLRedirect {
+		t.Errorf("expected the redirect from example.com to redirect to HTTPS")
+	}
+	if !example.UsePortInRedirects {
+		t.Errorf("expected the redirect from example.com to use the port")
+	}
+
+	foo := redirects[1]
+	if foo.From != "www.foo.bar" || foo.To != "foo.bar" {
+		t.Errorf("expected a redirect from www.foo.bar to foo.bar but it is from %q to %q", foo.From, foo.To)
+	}
+	if foo.SSLRedirect {
+		t.Errorf("expected the redirect from www.foo.bar to keep the scheme because foo.bar has no certificate")
+	}
+}
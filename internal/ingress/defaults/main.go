@@ -73,6 +73,15 @@ type Backend struct {
 	// https://nginx.org/en/docs/http/ngx_http_proxy_module.html#proxy_next_upstream_tries
 	ProxyNextUpstreamTries int `json:"proxy-next-upstream-tries"`
 
+	// Limits the time in seconds during which a request can be passed to the next server.
+	// A value of 0 turns off this limitation.
+	// https://nginx.org/en/docs/http/ngx_http_proxy_module.html#proxy_next_upstream_timeout
+	ProxyNextUpstreamTimeout int `json:"proxy-next-upstream-timeout"`
+
+	// Maximum percentage of the requests to a backend that can be passed to the next server
+	// by each NGINX worker. A value of 0 turns off this limitation.
+	ProxyNextUpstreamBudget int `json:"proxy-next-upstream-budget"`
+
 	// Sets the original text that should be changed in the "Location" and "Refresh" header fields of a proxied server response.
 	// http://nginx.org/en/docs/http/ngx_http_proxy_module.html#proxy_redirect
 	// Default: off
@@ -107,6 +116,11 @@ type Backend struct {
 	// Default: false
 	UsePortInRedirects bool `json:"use-port-in-redirects"`
 
+	// Allows the use of annotations that inject custom configuration
+	// snippets (configuration-snippet, server-snippet and auth-snippet)
+	// Default: true
+	AllowSnippetAnnotations bool `json:"allow-snippet-annotations"`
+
 	// Enable stickiness by client-server mapping based on a NGINX variable, text or a combination of both.
 	// A consistent hashing method will be used which ensures only a few keys would be remapped to different
 	// servers on upstream group changes
@@ -131,7 +145,50 @@ type Backend struct {
 	// http://nginx.org/en/docs/http/ngx_http_core_module.html#limit_rate_after
 	LimitRateAfter int `json:"limit-rate-after"`
 
+	// Size in MB of the shared memory zones used to limit the connections and
+	// requests of the Ingresses with rate limit annotations.
+	// http://nginx.org/en/docs/http/ngx_http_limit_conn_module.html#limit_conn_zone
+	LimitConnZoneSize int `json:"limit-conn-zone-size"`
+
 	// Enables or disables buffering of responses from the proxied server.
 	// http://nginx.org/en/docs/http/ngx_http_proxy_module.html#proxy_buffering
 	ProxyBuffering string `json:"proxy-buffering"`
+
+	// Sets the local IP address used in the connections to the upstream servers.
+	// Use the transparent parameter to connect using a non-local IP address,
+	// like the address of the client ($remote_addr).
+	// http://nginx.org/en/docs/http/ngx_http_proxy_module.html#proxy_bind
+	ProxyBind string `json:"proxy-bind"`
+
+	// Enables or disables the reuse of TLS sessions when connecting to HTTPS
+	// upstream servers, avoiding a full handshake for every new connection.
+	// http://nginx.org/en/docs/http/ngx_http_proxy_module.html#proxy_ssl_session_reuse
+	ProxySSLSessionReuse bool `json:"proxy-ssl-session-reuse"`
+
+	// Enables or disables the use of the NGINX Brotli Module for compression
+	// https://github.com/google/ngx_brotli
+	EnableBrotli bool `json:"enable-brotli,omitempty"`
+
+	// Brotli Compression Level that will be used
+	BrotliLevel int `json:"brotli-level,omitempty"`
+
+	// MIME Types that will be compressed on-the-fly using Brotli module
+	BrotliTypes string `json:"brotli-types,omitempty"`
+
+	// Enables or disables the header HSTS in servers running SSL
+	HSTS bool `json:"hsts,omitempty"`
+
+	// Enables or disables the use of HSTS in all the subdomains of the servername
+	// Default: true
+	HSTSIncludeSubdomains bool `json:"hsts-include-subdomains,omitempty"`
+
+	// HTTP Strict Transport Security (often abbreviated as HSTS) is a security feature (HTTP header)
+	// that tell browsers that it should only be communicated with using HTTPS, instead of using HTTP.
+	// https://developer.mozilla.org/en-US/docs/Web/Security/HTTP_strict_transport_security
+	// max-age is the time, in seconds, that the browser should remember that this site is only to be
+	// accessed using HTTPS.
+	HSTSMaxAge string `json:"hsts-max-age,omitempty"`
+
+	// Enables or disables the preload attribute in HSTS feature
+	HSTSPreload bool `json:"hsts-preload,omitempty"`
 }
@@ -20,16 +20,18 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/golang/glog"
 	"github.com/prometheus/client_golang/prometheus"
 
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/log"
 )
 
 var (
-	operation    = []string{"controller_namespace", "controller_class", "controller_pod"}
-	sslLabelHost = []string{"namespace", "class", "host"}
+	operation     = []string{"controller_namespace", "controller_class", "controller_pod"}
+	staleReason   = []string{"controller_namespace", "controller_class", "controller_pod", "reason"}
+	sslLabelHost  = []string{"namespace", "class", "host"}
+	syncOperation = []string{"controller_namespace", "controller_class", "controller_pod", "operation"}
 )
 
 // Controller defines base metrics about the ingress controller
@@ -39,10 +41,27 @@ type Controller struct {
 	configHash        prometheus.Gauge
 	configSuccess     prometheus.Gauge
 	configSuccessTime prometheus.Gauge
+	configFailureTime prometheus.Gauge
 
 	reloadOperation       *prometheus.CounterVec
 	reloadOperationErrors *prometheus.CounterVec
 	sslExpireTime         *prometheus.GaugeVec
+	sslStartTime          *prometheus.GaugeVec
+	configStale           *prometheus.GaugeVec
+	leaderElection        prometheus.Gauge
+	leaderChanges         prometheus.Counter
+	leaderIdentity        *prometheus.GaugeVec
+	nginxRestarts         prometheus.Counter
+	lingeringWorkers      prometheus.Gauge
+	lingeringWorkerAge    prometheus.Gauge
+	classConflicts        prometheus.Gauge
+	syncQueueDepth        prometheus.Gauge
+	syncQueueWait         prometheus.Histogram
+	syncQueueSkipped      prometheus.Counter
+	syncQueueDropped      prometheus.Counter
+	syncRateLimiterWait   prometheus.Histogram
+	syncDuration          *prometheus.HistogramVec
+	syncWatchdogTimeouts  prometheus.Counter
 
 	constLabels prometheus.Labels
 	labels      prometheus.Labels
@@ -87,6 +106,13 @@ func NewController(pod, namespace, class string) *Controller {
 				Help:        "Timestamp of the last successful configuration reload.",
 				ConstLabels: constLabels,
 			}),
+		configFailureTime: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace:   PrometheusNamespace,
+				Name:        "last_config_failure_timestamp_seconds",
+				Help:        "Timestamp of the last configuration that could not be rendered, tested or reloaded.",
+				ConstLabels: constLabels,
+			}),
 		reloadOperation: prometheus.NewCounterVec(
 			prometheus.CounterOpts{
 				Namespace: PrometheusNamespace,
@@ -112,6 +138,127 @@ func NewController(pod, namespace, class string) *Controller {
 			},
 			sslLabelHost,
 		),
+		sslStartTime: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: PrometheusNamespace,
+				Name:      "ssl_start_time_seconds",
+				Help: `Number of seconds since 1970 to the beginning of the validity of the SSL Certificate.
+			An example to check if a certificate is not valid yet is: "nginx_ingress_controller_ssl_start_time_seconds > time()"`,
+			},
+			sslLabelHost,
+		),
+		configStale: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: PrometheusNamespace,
+				Name:      "config_stale",
+				Help:      `Whether the running configuration is stale for a reason (reload-failed, dynamic-configuration-failed or apiserver-unreachable)`,
+			},
+			staleReason,
+		),
+		leaderElection: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace:   PrometheusNamespace,
+				Name:        "leader_election_status",
+				Help:        "Whether this instance of the Ingress controller is the leader",
+				ConstLabels: constLabels,
+			}),
+		leaderChanges: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace:   PrometheusNamespace,
+				Name:        "leader_election_changes",
+				Help:        "Cumulative number of leaders elected observed by this instance of the Ingress controller",
+				ConstLabels: constLabels,
+			}),
+		leaderIdentity: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace:   PrometheusNamespace,
+				Name:        "leader_election_leader",
+				Help:        "Identity of the current leader of the status updates observed by this instance of the Ingress controller",
+				ConstLabels: constLabels,
+			},
+			[]string{"leader"},
+		),
+		nginxRestarts: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace:   PrometheusNamespace,
+				Name:        "nginx_restarts",
+				Help:        "Cumulative number of restarts of the NGINX master process after it died",
+				ConstLabels: constLabels,
+			}),
+		lingeringWorkers: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace:   PrometheusNamespace,
+				Name:        "nginx_lingering_workers",
+				Help:        "Number of NGINX worker processes shutting down for longer than worker-shutdown-timeout",
+				ConstLabels: constLabels,
+			}),
+		lingeringWorkerAge: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace:   PrometheusNamespace,
+				Name:        "nginx_shutting_down_worker_age_seconds",
+				Help:        "Time in seconds since the reload that started the shutdown of the oldest NGINX worker process shutting down",
+				ConstLabels: constLabels,
+			}),
+		classConflicts: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace:   PrometheusNamespace,
+				Name:        "ingress_class_conflicts",
+				Help:        "Number of Ingresses with a host and path also defined in an Ingress of other class",
+				ConstLabels: constLabels,
+			}),
+		syncQueueDepth: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace:   PrometheusNamespace,
+				Name:        "sync_queue_depth",
+				Help:        "Number of tasks waiting in the sync queue",
+				ConstLabels: constLabels,
+			}),
+		syncQueueWait: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Namespace:   PrometheusNamespace,
+				Name:        "sync_queue_wait_seconds",
+				Help:        "Time the tasks wait in the sync queue before their sync starts",
+				Buckets:     prometheus.ExponentialBuckets(0.01, 2, 12),
+				ConstLabels: constLabels,
+			}),
+		syncQueueSkipped: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace:   PrometheusNamespace,
+				Name:        "sync_queue_skipped",
+				Help:        "Cumulative number of tasks of the sync queue skipped because a newer sync already happened",
+				ConstLabels: constLabels,
+			}),
+		syncQueueDropped: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace:   PrometheusNamespace,
+				Name:        "sync_queue_dropped",
+				Help:        "Cumulative number of tasks that could not be added to the sync queue",
+				ConstLabels: constLabels,
+			}),
+		syncRateLimiterWait: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Namespace:   PrometheusNamespace,
+				Name:        "sync_rate_limiter_wait_seconds",
+				Help:        "Time the syncs wait for the sync rate limiter (--sync-rate-limit)",
+				Buckets:     prometheus.ExponentialBuckets(0.01, 2, 12),
+				ConstLabels: constLabels,
+			}),
+		syncDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: PrometheusNamespace,
+				Name:      "sync_duration_seconds",
+				Help:      `Time spent in each operation of a sync (build of the configuration, reload or dynamic-configuration)`,
+				Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12),
+			},
+			syncOperation,
+		),
+		syncWatchdogTimeouts: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace:   PrometheusNamespace,
+				Name:        "sync_watchdog_timeouts",
+				Help:        "Cumulative number of syncs that did not complete within the deadline of the sync watchdog",
+				ConstLabels: constLabels,
+			}),
 	}
 
 	return cm
@@ -138,18 +285,133 @@ func (cm *Controller) ConfigSuccess(hash uint64, success bool) {
 		return
 	}
 
+	cm.configFailureTime.Set(float64(time.Now().Unix()))
 	cm.configSuccess.Set(0)
 	cm.configHash.Set(0)
 }
 
+// SetStaleConfiguration sets a flag indicating if the running configuration is stale for a reason
+func (cm *Controller) SetStaleConfiguration(reason string, stale bool) {
+	labels := make(prometheus.Labels, len(cm.constLabels)+1)
+	for k, v := range cm.constLabels {
+		labels[k] = v
+	}
+	labels["reason"] = reason
+
+	if stale {
+		cm.configStale.With(labels).Set(1)
+		return
+	}
+
+	cm.configStale.With(labels).Set(0)
+}
+
+// SetLeader sets a flag indicating if this instance is the leader
+func (cm *Controller) SetLeader(leader bool) {
+	if leader {
+		cm.leaderElection.Set(1)
+		return
+	}
+
+	cm.leaderElection.Set(0)
+}
+
+// IncLeaderChanges increment the counter of leaders elected
+func (cm *Controller) IncLeaderChanges() {
+	cm.leaderChanges.Inc()
+}
+
+// SetLeaderIdentity sets the identity of the current leader, removing
+// the one of the previous leader
+func (cm *Controller) SetLeaderIdentity(identity string) {
+	cm.leaderIdentity.Reset()
+	cm.leaderIdentity.WithLabelValues(identity).Set(1)
+}
+
+// IncNGINXRestartCount increment the counter of restarts of the NGINX master process
+func (cm *Controller) IncNGINXRestartCount() {
+	cm.nginxRestarts.Inc()
+}
+
+// SetLingeringWorkers sets the number of NGINX workers shutting down for
+// longer than worker-shutdown-timeout and the shutdown age of the oldest one
+func (cm *Controller) SetLingeringWorkers(count int, oldest time.Duration) {
+	cm.lingeringWorkers.Set(float64(count))
+	cm.lingeringWorkerAge.Set(oldest.Seconds())
+}
+
+// SetClassConflicts sets the number of Ingresses with class conflicts
+func (cm *Controller) SetClassConflicts(count int) {
+	cm.classConflicts.Set(float64(count))
+}
+
+// SetSyncQueueDepth sets the number of tasks waiting in the sync queue
+func (cm *Controller) SetSyncQueueDepth(depth int) {
+	cm.syncQueueDepth.Set(float64(depth))
+}
+
+// ObserveSyncQueueWait observes the time a task waited in the sync queue
+func (cm *Controller) ObserveSyncQueueWait(d time.Duration) {
+	cm.syncQueueWait.Observe(d.Seconds())
+}
+
+// IncSyncQueueSkipped increment the counter of skipped tasks of the sync queue
+func (cm *Controller) IncSyncQueueSkipped() {
+	cm.syncQueueSkipped.Inc()
+}
+
+// IncSyncQueueDropped increment the counter of tasks that could not be added to the sync queue
+func (cm *Controller) IncSyncQueueDropped() {
+	cm.syncQueueDropped.Inc()
+}
+
+// ObserveSyncRateLimiterWait observes the time a sync waited for the sync rate limiter
+func (cm *Controller) ObserveSyncRateLimiterWait(d time.Duration) {
+	cm.syncRateLimiterWait.Observe(d.Seconds())
+}
+
+// ObserveSyncDuration observes the time spent in an operation of a sync
+func (cm *Controller) ObserveSyncDuration(operation string, d time.Duration) {
+	labels := make(prometheus.Labels, len(cm.constLabels)+1)
+	for k, v := range cm.constLabels {
+		labels[k] = v
+	}
+	labels["operation"] = operation
+
+	cm.syncDuration.With(labels).Observe(d.Seconds())
+}
+
+// IncSyncWatchdogTimeouts increment the counter of syncs that did not
+// complete within the deadline of the sync watchdog
+func (cm *Controller) IncSyncWatchdogTimeouts() {
+	cm.syncWatchdogTimeouts.Inc()
+}
+
 // Describe implements prometheus.Collector
 func (cm Controller) Describe(ch chan<- *prometheus.Desc) {
 	cm.configHash.Describe(ch)
 	cm.configSuccess.Describe(ch)
 	cm.configSuccessTime.Describe(ch)
+	cm.configFailureTime.Describe(ch)
 	cm.reloadOperation.Describe(ch)
 	cm.reloadOperationErrors.Describe(ch)
 	cm.sslExpireTime.Describe(ch)
+	cm.sslStartTime.Describe(ch)
+	cm.configStale.Describe(ch)
+	cm.leaderElection.Describe(ch)
+	cm.leaderChanges.Describe(ch)
+	cm.leaderIdentity.Describe(ch)
+	cm.nginxRestarts.Describe(ch)
+	cm.lingeringWorkers.Describe(ch)
+	cm.lingeringWorkerAge.Describe(ch)
+	cm.classConflicts.Describe(ch)
+	cm.syncQueueDepth.Describe(ch)
+	cm.syncQueueWait.Describe(ch)
+	cm.syncQueueSkipped.Describe(ch)
+	cm.syncQueueDropped.Describe(ch)
+	cm.syncRateLimiterWait.Describe(ch)
+	cm.syncDuration.Describe(ch)
+	cm.syncWatchdogTimeouts.Describe(ch)
 }
 
 // Collect implements the prometheus.Collector interface.
@@ -157,9 +419,26 @@ func (cm Controller) Collect(ch chan<- prometheus.Metric) {
 	cm.configHash.Collect(ch)
 	cm.configSuccess.Collect(ch)
 	cm.configSuccessTime.Collect(ch)
+	cm.configFailureTime.Collect(ch)
 	cm.reloadOperation.Collect(ch)
 	cm.reloadOperationErrors.Collect(ch)
 	cm.sslExpireTime.Collect(ch)
+	cm.sslStartTime.Collect(ch)
+	cm.configStale.Collect(ch)
+	cm.leaderElection.Collect(ch)
+	cm.leaderChanges.Collect(ch)
+	cm.leaderIdentity.Collect(ch)
+	cm.nginxRestarts.Collect(ch)
+	cm.lingeringWorkers.Collect(ch)
+	cm.lingeringWorkerAge.Collect(ch)
+	cm.classConflicts.Collect(ch)
+	cm.syncQueueDepth.Collect(ch)
+	cm.syncQueueWait.Collect(ch)
+	cm.syncQueueSkipped.Collect(ch)
+	cm.syncQueueDropped.Collect(ch)
+	cm.syncRateLimiterWait.Collect(ch)
+	cm.syncDuration.Collect(ch)
+	cm.syncWatchdogTimeouts.Collect(ch)
 }
 
 // SetSSLExpireTime sets the expiration time of SSL Certificates
@@ -173,6 +452,10 @@ func (cm *Controller) SetSSLExpireTime(servers []*ingress.Server) {
 			labels["host"] = s.Hostname
 
 			cm.sslExpireTime.With(labels).Set(float64(s.SSLCert.ExpireTime.Unix()))
+
+			if s.SSLCert.Certificate != nil {
+				cm.sslStartTime.With(labels).Set(float64(s.SSLCert.Certificate.NotBefore.Unix()))
+			}
 		}
 	}
 }
@@ -181,16 +464,23 @@ func (cm *Controller) SetSSLExpireTime(servers []*ingress.Server) {
 func (cm *Controller) RemoveMetrics(hosts []string, registry prometheus.Gatherer) {
 	mfs, err := registry.Gather()
 	if err != nil {
-		glog.Errorf("Error gathering metrics: %v", err)
+		log.Errorf("Error gathering metrics: %v", err)
 		return
 	}
 
-	glog.V(2).Infof("removing SSL certificate metrics for %v hosts", hosts)
+	log.V(2).Infof("removing SSL certificate metrics for %v hosts", hosts)
 	toRemove := sets.NewString(hosts...)
 
 	for _, mf := range mfs {
 		metricName := mf.GetName()
-		if fmt.Sprintf("%v_ssl_expire_time_seconds", PrometheusNamespace) != metricName {
+
+		var gauge *prometheus.GaugeVec
+		switch metricName {
+		case fmt.Sprintf("%v_ssl_expire_time_seconds", PrometheusNamespace):
+			gauge = cm.sslExpireTime
+		case fmt.Sprintf("%v_ssl_start_time_seconds", PrometheusNamespace):
+			gauge = cm.sslStartTime
+		default:
 			continue
 		}
 
@@ -212,10 +502,10 @@ func (cm *Controller) RemoveMetrics(hosts []string, registry prometheus.Gatherer
 				continue
 			}
 
-			glog.V(2).Infof("Removing prometheus metric from gauge %v for host %v", metricName, host)
-			removed := cm.sslExpireTime.Delete(labels)
+			log.V(2).Infof("Removing prometheus metric from gauge %v for host %v", metricName, host)
+			removed := gauge.Delete(labels)
 			if !removed {
-				glog.V(2).Infof("metric %v for host %v with labels not removed: %v", metricName, host, labels)
+				log.V(2).Infof("metric %v for host %v with labels not removed: %v", metricName, host, labels)
 			}
 		}
 	}
@@ -17,6 +17,7 @@ limitations under the License.
 package collectors
 
 import (
+	"crypto/x509"
 	"testing"
 	"time"
 
@@ -100,6 +101,151 @@ func TestControllerCounters(t *testing.T) {
 			`,
 			metrics: []string{"nginx_ingress_controller_ssl_expire_time_seconds"},
 		},
+		{
+			name: "should set SSL certificates start time metrics",
+			test: func(cm *Controller) {
+				t1, _ := time.Parse(
+					time.RFC3339,
+					"2012-11-01T22:08:41+00:00")
+
+				servers := []*ingress.Server{
+					{
+						Hostname: "demo",
+						SSLCert: ingress.SSLCert{
+							Certificate: &x509.Certificate{NotBefore: t1},
+							ExpireTime:  t1.Add(24 * time.Hour),
+						},
+					},
+				}
+				cm.SetSSLExpireTime(servers)
+			},
+			want: `
+				# HELP nginx_ingress_controller_ssl_start_time_seconds Number of seconds since 1970 to the beginning of the validity of the SSL Certificate.\n			An example to check if a certificate is not valid yet is: "nginx_ingress_controller_ssl_start_time_seconds > time()"
+				# TYPE nginx_ingress_controller_ssl_start_time_seconds gauge
+				nginx_ingress_controller_ssl_start_time_seconds{class="nginx",host="demo",namespace="default"} 1.351807721e+09
+			`,
+			metrics: []string{"nginx_ingress_controller_ssl_start_time_seconds"},
+		},
+		{
+			name: "should set stale configuration metrics",
+			test: func(cm *Controller) {
+				cm.SetStaleConfiguration("reload-failed", true)
+				cm.SetStaleConfiguration("apiserver-unreachable", false)
+			},
+			want: `
+				# HELP nginx_ingress_controller_config_stale Whether the running configuration is stale for a reason (reload-failed, dynamic-configuration-failed or apiserver-unreachable)
+				# TYPE nginx_ingress_controller_config_stale gauge
+				nginx_ingress_controller_config_stale{controller_class="nginx",controller_namespace="default",controller_pod="pod",reason="apiserver-unreachable"} 0
+				nginx_ingress_controller_config_stale{controller_class="nginx",controller_namespace="default",controller_pod="pod",reason="reload-failed"} 1
+			`,
+			metrics: []string{"nginx_ingress_controller_config_stale"},
+		},
+		{
+			name: "should set leader election metrics",
+			test: func(cm *Controller) {
+				cm.SetLeader(true)
+				cm.IncLeaderChanges()
+				cm.IncLeaderChanges()
+			},
+			want: `
+				# HELP nginx_ingress_controller_leader_election_changes Cumulative number of leaders elected observed by this instance of the Ingress controller
+				# TYPE nginx_ingress_controller_leader_election_changes counter
+				nginx_ingress_controller_leader_election_changes{controller_class="nginx",controller_namespace="default",controller_pod="pod"} 2
+				# HELP nginx_ingress_controller_leader_election_status Whether this instance of the Ingress controller is the leader
+				# TYPE nginx_ingress_controller_leader_election_status gauge
+				nginx_ingress_controller_leader_election_status{controller_class="nginx",controller_namespace="default",controller_pod="pod"} 1
+			`,
+			metrics: []string{"nginx_ingress_controller_leader_election_changes", "nginx_ingress_controller_leader_election_status"},
+		},
+		{
+			name: "should set the identity of the leader",
+			test: func(cm *Controller) {
+				cm.SetLeaderIdentity("pod-a")
+				cm.SetLeaderIdentity("pod-b")
+			},
+			want: `
+				# HELP nginx_ingress_controller_leader_election_leader Identity of the current leader of the status updates observed by this instance of the Ingress controller
+				# TYPE nginx_ingress_controller_leader_election_leader gauge
+				nginx_ingress_controller_leader_election_leader{controller_class="nginx",controller_namespace="default",controller_pod="pod",leader="pod-b"} 1
+			`,
+			metrics: []string{"nginx_ingress_controller_leader_election_leader"},
+		},
+		{
+			name: "should count the restarts of NGINX",
+			test: func(cm *Controller) {
+				cm.IncNGINXRestartCount()
+			},
+			want: `
+				# HELP nginx_ingress_controller_nginx_restarts Cumulative number of restarts of the NGINX master process after it died
+				# TYPE nginx_ingress_controller_nginx_restarts counter
+				nginx_ingress_controller_nginx_restarts{controller_class="nginx",controller_namespace="default",controller_pod="pod"} 1
+			`,
+			metrics: []string{"nginx_ingress_controller_nginx_restarts"},
+		},
+		{
+			name: "should set the lingering workers",
+			test: func(cm *Controller) {
+				cm.SetLingeringWorkers(2, 90*time.Second)
+			},
+			want: `
+				# HELP nginx_ingress_controller_nginx_lingering_workers Number of NGINX worker processes shutting down for longer than worker-shutdown-timeout
+				# TYPE nginx_ingress_controller_nginx_lingering_workers gauge
+				nginx_ingress_controller_nginx_lingering_workers{controller_class="nginx",controller_namespace="default",controller_pod="pod"} 2
+				# HELP nginx_ingress_controller_nginx_shutting_down_worker_age_seconds Time in seconds since the reload that started the shutdown of the oldest NGINX worker process shutting down
+				# TYPE nginx_ingress_controller_nginx_shutting_down_worker_age_seconds gauge
+				nginx_ingress_controller_nginx_shutting_down_worker_age_seconds{controller_class="nginx",controller_namespace="default",controller_pod="pod"} 90
+			`,
+			metrics: []string{"nginx_ingress_controller_nginx_lingering_workers", "nginx_ingress_controller_nginx_shutting_down_worker_age_seconds"},
+		},
+		{
+			name: "should set the number of class conflicts",
+			test: func(cm *Controller) {
+				cm.SetClassConflicts(3)
+			},
+			want: `
+				# HELP nginx_ingress_controller_ingress_class_conflicts Number of Ingresses with a host and path also defined in an Ingress of other class
+				# TYPE nginx_ingress_controller_ingress_class_conflicts gauge
+				nginx_ingress_controller_ingress_class_conflicts{controller_class="nginx",controller_namespace="default",controller_pod="pod"} 3
+			`,
+			metrics: []string{"nginx_ingress_controller_ingress_class_conflicts"},
+		},
+		{
+			name: "should set the sync queue metrics",
+			test: func(cm *Controller) {
+				cm.SetSyncQueueDepth(4)
+				cm.IncSyncQueueSkipped()
+				cm.IncSyncQueueSkipped()
+				cm.IncSyncQueueDropped()
+			},
+			want: `
+				# HELP nginx_ingress_controller_sync_queue_depth Number of tasks waiting in the sync queue
+				# TYPE nginx_ingress_controller_sync_queue_depth gauge
+				nginx_ingress_controller_sync_queue_depth{controller_class="nginx",controller_namespace="default",controller_pod="pod"} 4
+				# HELP nginx_ingress_controller_sync_queue_dropped Cumulative number of tasks that could not be added to the sync queue
+				# TYPE nginx_ingress_controller_sync_queue_dropped counter
+				nginx_ingress_controller_sync_queue_dropped{controller_class="nginx",controller_namespace="default",controller_pod="pod"} 1
+				# HELP nginx_ingress_controller_sync_queue_skipped Cumulative number of tasks of the sync queue skipped because a newer sync already happened
+				# TYPE nginx_ingress_controller_sync_queue_skipped counter
+				nginx_ingress_controller_sync_queue_skipped{controller_class="nginx",controller_namespace="default",controller_pod="pod"} 2
+			`,
+			metrics: []string{
+				"nginx_ingress_controller_sync_queue_depth",
+				"nginx_ingress_controller_sync_queue_dropped",
+				"nginx_ingress_controller_sync_queue_skipped",
+			},
+		},
+		{
+			name: "should count the sync watchdog timeouts",
+			test: func(cm *Controller) {
+				cm.IncSyncWatchdogTimeouts()
+			},
+			want: `
+				# HELP nginx_ingress_controller_sync_watchdog_timeouts Cumulative number of syncs that did not complete within the deadline of the sync watchdog
+				# TYPE nginx_ingress_controller_sync_watchdog_timeouts counter
+				nginx_ingress_controller_sync_watchdog_timeouts{controller_class="nginx",controller_namespace="default",controller_pod="pod"} 1
+			`,
+			metrics: []string{"nginx_ingress_controller_sync_watchdog_timeouts"},
+		},
 	}
 
 	for _, c := range cases {
@@ -19,11 +19,11 @@ package collectors
 import (
 	"path/filepath"
 
-	"github.com/golang/glog"
-
 	common "github.com/ncabatoff/process-exporter"
 	"github.com/ncabatoff/process-exporter/proc"
 	"github.com/prometheus/client_golang/prometheus"
+
+	"k8s.io/ingress-nginx/internal/log"
 )
 
 type scrapeRequest struct {
@@ -186,7 +186,7 @@ func (p namedProcess) Stop() {
 func (p namedProcess) scrape(ch chan<- prometheus.Metric) {
 	_, err := p.Update(p.fs.AllProcs())
 	if err != nil {
-		glog.Warningf("unexpected error obtaining nginx process info: %v", err)
+		log.Warningf("unexpected error obtaining nginx process info: %v", err)
 		return
 	}
 
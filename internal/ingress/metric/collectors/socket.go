@@ -22,11 +22,13 @@ import (
 	"io/ioutil"
 	"net"
 	"os"
+	"sync"
 
-	"github.com/golang/glog"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/prometheus/client_golang/prometheus"
 	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/ingress-nginx/internal/log"
 )
 
 type upstream struct {
@@ -35,6 +37,7 @@ type upstream struct {
 	ResponseLength float64 `json:"upstreamResponseLength"`
 	ResponseTime   float64 `json:"upstreamResponseTime"`
 	Status         string  `json:"upstreamStatus"`
+	TLS            bool    `json:"upstreamTLS"`
 }
 
 type socketData struct {
@@ -48,8 +51,14 @@ type socketData struct {
 	RequestLength float64 `json:"requestLength"`
 	RequestTime   float64 `json:"requestTime"`
 
+	SizeMetrics      bool    `json:"sizeMetrics"`
+	RequestBodySize  float64 `json:"requestBodySize"`
+	ResponseBodySize float64 `json:"responseBodySize"`
+
 	upstream
 
+	AlternativeBackend string `json:"alternativeBackend"`
+
 	Namespace string `json:"namespace"`
 	Ingress   string `json:"ingress"`
 	Service   string `json:"service"`
@@ -68,15 +77,31 @@ type SocketCollector struct {
 
 	upstreamLatency *prometheus.SummaryVec
 
+	upstreamTLSHandshake *prometheus.HistogramVec
+	upstreamTLSFailures  *prometheus.CounterVec
+
 	bytesSent *prometheus.HistogramVec
 
+	requestBodySize  *prometheus.HistogramVec
+	responseBodySize *prometheus.HistogramVec
+
 	requests *prometheus.CounterVec
 
+	alternativeBackendRequests *prometheus.CounterVec
+
+	chargebackRequestBytes  *prometheus.CounterVec
+	chargebackResponseBytes *prometheus.CounterVec
+
 	listener net.Listener
 
 	metricMapping map[string]interface{}
 
 	hosts sets.String
+
+	chargebackLock sync.RWMutex
+	// chargebackIDs maps Ingresses (<namespace>/<name>) to the
+	// value used to aggregate the bytes transferred
+	chargebackIDs map[string]string
 }
 
 var (
@@ -168,6 +193,16 @@ func NewSocketCollector(pod, namespace, class string) (*SocketCollector, error)
 			[]string{"ingress", "namespace", "status"},
 		),
 
+		alternativeBackendRequests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "alternative_backend_requests",
+				Help:        "The total number of client requests served by an alternative backend, like a canary",
+				Namespace:   PrometheusNamespace,
+				ConstLabels: constLabels,
+			},
+			[]string{"ingress", "namespace", "service", "alternative_backend"},
+		),
+
 		bytesSent: prometheus.NewHistogramVec(
 			prometheus.HistogramOpts{
 				Name:        "bytes_sent",
@@ -179,6 +214,46 @@ func NewSocketCollector(pod, namespace, class string) (*SocketCollector, error)
 			requestTags,
 		),
 
+		requestBodySize: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:        "request_body_size",
+				Help:        "The size of the request bodies, only for the Ingresses with the size metrics enabled",
+				Namespace:   PrometheusNamespace,
+				Buckets:     prometheus.ExponentialBuckets(64, 4, 8), // 8 buckets, from 64B to 1MB.
+				ConstLabels: constLabels,
+			},
+			requestTags,
+		),
+		responseBodySize: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:        "response_body_size",
+				Help:        "The size of the response bodies, only for the Ingresses with the size metrics enabled",
+				Namespace:   PrometheusNamespace,
+				Buckets:     prometheus.ExponentialBuckets(64, 4, 8), // 8 buckets, from 64B to 1MB.
+				ConstLabels: constLabels,
+			},
+			requestTags,
+		),
+
+		chargebackRequestBytes: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "chargeback_request_bytes",
+				Help:        "The total number of bytes received from clients aggregated by chargeback label",
+				Namespace:   PrometheusNamespace,
+				ConstLabels: constLabels,
+			},
+			[]string{"chargeback"},
+		),
+		chargebackResponseBytes: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "chargeback_response_bytes",
+				Help:        "The total number of bytes sent to clients aggregated by chargeback label",
+				Namespace:   PrometheusNamespace,
+				ConstLabels: constLabels,
+			},
+			[]string{"chargeback"},
+		),
+
 		upstreamLatency: prometheus.NewSummaryVec(
 			prometheus.SummaryOpts{
 				Name:        "ingress_upstream_latency_seconds",
@@ -188,6 +263,26 @@ func NewSocketCollector(pod, namespace, class string) (*SocketCollector, error)
 			},
 			[]string{"ingress", "namespace", "service"},
 		),
+
+		upstreamTLSHandshake: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:        "upstream_tls_handshake_seconds",
+				Help:        "The time spent establishing connections, including the TLS handshake, with HTTPS upstream servers",
+				Namespace:   PrometheusNamespace,
+				Buckets:     prometheus.ExponentialBuckets(0.001, 2, 12), // 12 buckets, from 1ms to ~2s.
+				ConstLabels: constLabels,
+			},
+			[]string{"ingress", "namespace", "service"},
+		),
+		upstreamTLSFailures: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "upstream_tls_connect_failures",
+				Help:        "The total number of requests that failed to establish a connection with HTTPS upstream servers",
+				Namespace:   PrometheusNamespace,
+				ConstLabels: constLabels,
+			},
+			[]string{"ingress", "namespace", "service"},
+		),
 	}
 
 	sc.metricMapping = map[string]interface{}{
@@ -199,26 +294,34 @@ func NewSocketCollector(pod, namespace, class string) (*SocketCollector, error)
 
 		prometheus.BuildFQName(PrometheusNamespace, "", "bytes_sent"): sc.bytesSent,
 
+		prometheus.BuildFQName(PrometheusNamespace, "", "request_body_size"):  sc.requestBodySize,
+		prometheus.BuildFQName(PrometheusNamespace, "", "response_body_size"): sc.responseBodySize,
+
 		prometheus.BuildFQName(PrometheusNamespace, "", "ingress_upstream_latency_seconds"): sc.upstreamLatency,
+
+		prometheus.BuildFQName(PrometheusNamespace, "", "alternative_backend_requests"): sc.alternativeBackendRequests,
+
+		prometheus.BuildFQName(PrometheusNamespace, "", "upstream_tls_handshake_seconds"): sc.upstreamTLSHandshake,
+		prometheus.BuildFQName(PrometheusNamespace, "", "upstream_tls_connect_failures"):  sc.upstreamTLSFailures,
 	}
 
 	return sc, nil
 }
 
 func (sc *SocketCollector) handleMessage(msg []byte) {
-	glog.V(5).Infof("msg: %v", string(msg))
+	log.V(5).Infof("msg: %v", string(msg))
 
 	// Unmarshall bytes
 	var statsBatch []socketData
 	err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(msg, &statsBatch)
 	if err != nil {
-		glog.Errorf("Unexpected error deserializing JSON paylod: %v. Payload:\n%v", err, string(msg))
+		log.Errorf("Unexpected error deserializing JSON paylod: %v. Payload:\n%v", err, string(msg))
 		return
 	}
 
 	for _, stats := range statsBatch {
 		if !sc.hosts.Has(stats.Host) {
-			glog.V(3).Infof("skiping metric for host %v that is not being served", stats.Host)
+			log.V(3).Infof("skiping metric for host %v that is not being served", stats.Host)
 			continue
 		}
 
@@ -247,24 +350,34 @@ func (sc *SocketCollector) handleMessage(msg []byte) {
 
 		requestsMetric, err := sc.requests.GetMetricWith(collectorLabels)
 		if err != nil {
-			glog.Errorf("Error fetching requests metric: %v", err)
+			log.Errorf("Error fetching requests metric: %v", err)
 		} else {
 			requestsMetric.Inc()
 		}
 
+		sc.observeChargeback(stats)
+
+		if stats.AlternativeBackend != "" {
+			sc.observeAlternativeBackend(stats)
+		}
+
 		if stats.Latency != -1 {
 			latencyMetric, err := sc.upstreamLatency.GetMetricWith(latencyLabels)
 			if err != nil {
-				glog.Errorf("Error fetching latency metric: %v", err)
+				log.Errorf("Error fetching latency metric: %v", err)
 			} else {
 				latencyMetric.Observe(stats.Latency)
 			}
 		}
 
+		if stats.TLS {
+			sc.observeTLSHandshake(stats, latencyLabels)
+		}
+
 		if stats.RequestTime != -1 {
 			requestTimeMetric, err := sc.requestTime.GetMetricWith(requestLabels)
 			if err != nil {
-				glog.Errorf("Error fetching request duration metric: %v", err)
+				log.Errorf("Error fetching request duration metric: %v", err)
 			} else {
 				requestTimeMetric.Observe(stats.RequestTime)
 			}
@@ -273,7 +386,7 @@ func (sc *SocketCollector) handleMessage(msg []byte) {
 		if stats.RequestLength != -1 {
 			requestLengthMetric, err := sc.requestLength.GetMetricWith(requestLabels)
 			if err != nil {
-				glog.Errorf("Error fetching request length metric: %v", err)
+				log.Errorf("Error fetching request length metric: %v", err)
 			} else {
 				requestLengthMetric.Observe(stats.RequestLength)
 			}
@@ -282,7 +395,7 @@ func (sc *SocketCollector) handleMessage(msg []byte) {
 		if stats.ResponseTime != -1 {
 			responseTimeMetric, err := sc.responseTime.GetMetricWith(requestLabels)
 			if err != nil {
-				glog.Errorf("Error fetching upstream response time metric: %v", err)
+				log.Errorf("Error fetching upstream response time metric: %v", err)
 			} else {
 				responseTimeMetric.Observe(stats.ResponseTime)
 			}
@@ -291,18 +404,113 @@ func (sc *SocketCollector) handleMessage(msg []byte) {
 		if stats.ResponseLength != -1 {
 			bytesSentMetric, err := sc.bytesSent.GetMetricWith(requestLabels)
 			if err != nil {
-				glog.Errorf("Error fetching bytes sent metric: %v", err)
+				log.Errorf("Error fetching bytes sent metric: %v", err)
 			} else {
 				bytesSentMetric.Observe(stats.ResponseLength)
 			}
 
 			responseSizeMetric, err := sc.responseLength.GetMetricWith(requestLabels)
 			if err != nil {
-				glog.Errorf("Error fetching bytes sent metric: %v", err)
+				log.Errorf("Error fetching bytes sent metric: %v", err)
 			} else {
 				responseSizeMetric.Observe(stats.ResponseLength)
 			}
 		}
+
+		if stats.SizeMetrics {
+			sc.observeBodySizes(stats, requestLabels)
+		}
+	}
+}
+
+// observeBodySizes records the size of the request and response bodies
+// of the locations with the size metrics enabled
+func (sc *SocketCollector) observeBodySizes(stats socketData, labels prometheus.Labels) {
+	if stats.RequestBodySize != -1 {
+		requestBodyMetric, err := sc.requestBodySize.GetMetricWith(labels)
+		if err != nil {
+			log.Errorf("Error fetching request body size metric: %v", err)
+		} else {
+			requestBodyMetric.Observe(stats.RequestBodySize)
+		}
+	}
+
+	if stats.ResponseBodySize != -1 {
+		responseBodyMetric, err := sc.responseBodySize.GetMetricWith(labels)
+		if err != nil {
+			log.Errorf("Error fetching response body size metric: %v", err)
+		} else {
+			responseBodyMetric.Observe(stats.ResponseBodySize)
+		}
+	}
+}
+
+// observeTLSHandshake records the connection time of requests proxied to
+// HTTPS upstream servers. NGINX does not expose the duration of the TLS
+// handshake alone, so the connection time (TCP and TLS) is used instead.
+// Requests sent to an upstream server without a connection time failed
+// to establish the connection.
+func (sc *SocketCollector) observeTLSHandshake(stats socketData, labels prometheus.Labels) {
+	if stats.Endpoint == "" || stats.Endpoint == "-" {
+		return
+	}
+
+	if stats.Latency == -1 {
+		failuresMetric, err := sc.upstreamTLSFailures.GetMetricWith(labels)
+		if err != nil {
+			log.Errorf("Error fetching upstream TLS failures metric: %v", err)
+			return
+		}
+
+		failuresMetric.Inc()
+		return
+	}
+
+	handshakeMetric, err := sc.upstreamTLSHandshake.GetMetricWith(labels)
+	if err != nil {
+		log.Errorf("Error fetching upstream TLS handshake metric: %v", err)
+		return
+	}
+
+	handshakeMetric.Observe(stats.Latency)
+}
+
+// observeAlternativeBackend counts a request served by an alternative
+// backend of the location instead of its backend
+func (sc *SocketCollector) observeAlternativeBackend(stats socketData) {
+	alternativeMetric, err := sc.alternativeBackendRequests.GetMetricWith(prometheus.Labels{
+		"namespace":           stats.Namespace,
+		"ingress":             stats.Ingress,
+		"service":             stats.Service,
+		"alternative_backend": stats.AlternativeBackend,
+	})
+	if err != nil {
+		log.Errorf("Error fetching alternative backend requests metric: %v", err)
+		return
+	}
+
+	alternativeMetric.Inc()
+}
+
+// observeChargeback adds the bytes transferred in a request to the
+// counters of the chargeback label of the Ingress that served it
+func (sc *SocketCollector) observeChargeback(stats socketData) {
+	sc.chargebackLock.RLock()
+	id, ok := sc.chargebackIDs[fmt.Sprintf("%v/%v", stats.Namespace, stats.Ingress)]
+	sc.chargebackLock.RUnlock()
+
+	if !ok || id == "" {
+		return
+	}
+
+	labels := prometheus.Labels{"chargeback": id}
+
+	if stats.RequestLength > 0 {
+		sc.chargebackRequestBytes.With(labels).Add(stats.RequestLength)
+	}
+
+	if stats.ResponseLength > 0 {
+		sc.chargebackResponseBytes.With(labels).Add(stats.ResponseLength)
 	}
 }
 
@@ -329,12 +537,12 @@ func (sc *SocketCollector) Stop() {
 func (sc *SocketCollector) RemoveMetrics(ingresses []string, registry prometheus.Gatherer) {
 	mfs, err := registry.Gather()
 	if err != nil {
-		glog.Errorf("Error gathering metrics: %v", err)
+		log.Errorf("Error gathering metrics: %v", err)
 		return
 	}
 
 	// 1. remove metrics of removed ingresses
-	glog.V(2).Infof("removing ingresses %v from metrics", ingresses)
+	log.V(2).Infof("removing ingresses %v from metrics", ingresses)
 	for _, mf := range mfs {
 		metricName := mf.GetName()
 		metric, ok := sc.metricMapping[metricName]
@@ -366,13 +574,13 @@ func (sc *SocketCollector) RemoveMetrics(ingresses []string, registry prometheus
 				continue
 			}
 
-			glog.V(2).Infof("Removing prometheus metric from histogram %v for ingress %v", metricName, ingKey)
+			log.V(2).Infof("Removing prometheus metric from histogram %v for ingress %v", metricName, ingKey)
 
 			h, ok := metric.(*prometheus.HistogramVec)
 			if ok {
 				removed := h.Delete(labels)
 				if !removed {
-					glog.V(2).Infof("metric %v for ingress %v with labels not removed: %v", metricName, ingKey, labels)
+					log.V(2).Infof("metric %v for ingress %v with labels not removed: %v", metricName, ingKey, labels)
 				}
 			}
 
@@ -380,7 +588,15 @@ func (sc *SocketCollector) RemoveMetrics(ingresses []string, registry prometheus
 			if ok {
 				removed := s.Delete(labels)
 				if !removed {
-					glog.V(2).Infof("metric %v for ingress %v with labels not removed: %v", metricName, ingKey, labels)
+					log.V(2).Infof("metric %v for ingress %v with labels not removed: %v", metricName, ingKey, labels)
+				}
+			}
+
+			c, ok := metric.(*prometheus.CounterVec)
+			if ok {
+				removed := c.Delete(labels)
+				if !removed {
+					log.V(2).Infof("metric %v for ingress %v with labels not removed: %v", metricName, ingKey, labels)
 				}
 			}
 		}
@@ -389,33 +605,55 @@ func (sc *SocketCollector) RemoveMetrics(ingresses []string, registry prometheus
 }
 
 // Describe implements prometheus.Collector
-func (sc SocketCollector) Describe(ch chan<- *prometheus.Desc) {
+func (sc *SocketCollector) Describe(ch chan<- *prometheus.Desc) {
 	sc.requestTime.Describe(ch)
 	sc.requestLength.Describe(ch)
 
 	sc.requests.Describe(ch)
 
+	sc.chargebackRequestBytes.Describe(ch)
+	sc.chargebackResponseBytes.Describe(ch)
+
 	sc.upstreamLatency.Describe(ch)
 
+	sc.upstreamTLSHandshake.Describe(ch)
+	sc.upstreamTLSFailures.Describe(ch)
+
+	sc.alternativeBackendRequests.Describe(ch)
+
 	sc.responseTime.Describe(ch)
 	sc.responseLength.Describe(ch)
 
 	sc.bytesSent.Describe(ch)
+
+	sc.requestBodySize.Describe(ch)
+	sc.responseBodySize.Describe(ch)
 }
 
 // Collect implements the prometheus.Collector interface.
-func (sc SocketCollector) Collect(ch chan<- prometheus.Metric) {
+func (sc *SocketCollector) Collect(ch chan<- prometheus.Metric) {
 	sc.requestTime.Collect(ch)
 	sc.requestLength.Collect(ch)
 
 	sc.requests.Collect(ch)
 
+	sc.chargebackRequestBytes.Collect(ch)
+	sc.chargebackResponseBytes.Collect(ch)
+
 	sc.upstreamLatency.Collect(ch)
 
+	sc.upstreamTLSHandshake.Collect(ch)
+	sc.upstreamTLSFailures.Collect(ch)
+
+	sc.alternativeBackendRequests.Collect(ch)
+
 	sc.responseTime.Collect(ch)
 	sc.responseLength.Collect(ch)
 
 	sc.bytesSent.Collect(ch)
+
+	sc.requestBodySize.Collect(ch)
+	sc.responseBodySize.Collect(ch)
 }
 
 // SetHosts sets the hostnames that are being served by the ingress controller
@@ -424,6 +662,16 @@ func (sc *SocketCollector) SetHosts(hosts sets.String) {
 	sc.hosts = hosts
 }
 
+// SetChargebackIDs sets the value of the chargeback label of each Ingress
+// (<namespace>/<name>). Requests served by Ingresses without a value are
+// not added to the chargeback metrics
+func (sc *SocketCollector) SetChargebackIDs(ids map[string]string) {
+	sc.chargebackLock.Lock()
+	defer sc.chargebackLock.Unlock()
+
+	sc.chargebackIDs = ids
+}
+
 // handleMessages process the content received in a network connection
 func handleMessages(conn io.ReadCloser, fn func([]byte)) {
 	defer conn.Close()
@@ -322,3 +322,289 @@ func TestCollector(t *testing.T) {
 		})
 	}
 }
+
+func TestChargeback(t *testing.T) {
+	registry := prometheus.NewPedanticRegistry()
+
+	sc, err := NewSocketCollector("pod", "default", "ingress")
+	if err != nil {
+		t.Fatalf("unexpected error creating new SocketCollector: %v", err)
+	}
+	defer sc.Stop()
+
+	if err := registry.Register(sc); err != nil {
+		t.Errorf("registering collector failed: %s", err)
+	}
+	defer registry.Unregister(sc)
+
+	sc.SetHosts(sets.NewString("testshop.com"))
+	sc.SetChargebackIDs(map[string]string{
+		"test-app-production/web-yml": "team-a",
+		"test-app-production/api":     "team-a",
+	})
+
+	sc.handleMessage([]byte(`[{
+		"host":"testshop.com",
+		"status":"200",
+		"method":"GET",
+		"path":"/",
+		"requestLength":300.0,
+		"responseLength":1000.0,
+		"namespace":"test-app-production",
+		"ingress":"web-yml",
+		"service":"test-app"
+	},{
+		"host":"testshop.com",
+		"status":"200",
+		"method":"POST",
+		"path":"/api",
+		"requestLength":200.0,
+		"responseLength":50.0,
+		"namespace":"test-app-production",
+		"ingress":"api",
+		"service":"test-api"
+	},{
+		"host":"testshop.com",
+		"status":"200",
+		"method":"GET",
+		"path":"/other",
+		"requestLength":100.0,
+		"responseLength":100.0,
+		"namespace":"test-app-production",
+		"ingress":"other",
+		"service":"test-other"
+	}]`))
+
+	want := `
+		# HELP nginx_ingress_controller_chargeback_request_bytes The total number of bytes received from clients aggregated by chargeback label
+		# TYPE nginx_ingress_controller_chargeback_request_bytes counter
+		nginx_ingress_controller_chargeback_request_bytes{chargeback="team-a",controller_class="ingress",controller_namespace="default",controller_pod="pod"} 500
+		# HELP nginx_ingress_controller_chargeback_response_bytes The total number of bytes sent to clients aggregated by chargeback label
+		# TYPE nginx_ingress_controller_chargeback_response_bytes counter
+		nginx_ingress_controller_chargeback_response_bytes{chargeback="team-a",controller_class="ingress",controller_namespace="default",controller_pod="pod"} 1050
+	`
+
+	metrics := []string{"nginx_ingress_controller_chargeback_request_bytes", "nginx_ingress_controller_chargeback_response_bytes"}
+	if err := GatherAndCompare(sc, want, metrics, registry); err != nil {
+		t.Errorf("unexpected collecting result:\n%s", err)
+	}
+}
+
+func TestUpstreamTLSMetrics(t *testing.T) {
+	registry := prometheus.NewPedanticRegistry()
+
+	sc, err := NewSocketCollector("pod", "default", "ingress")
+	if err != nil {
+		t.Fatalf("unexpected error creating new SocketCollector: %v", err)
+	}
+	defer sc.Stop()
+
+	if err := registry.Register(sc); err != nil {
+		t.Errorf("registering collector failed: %s", err)
+	}
+	defer registry.Unregister(sc)
+
+	sc.SetHosts(sets.NewString("testshop.com"))
+
+	sc.handleMessage([]byte(`[{
+		"host":"testshop.com",
+		"status":"200",
+		"method":"GET",
+		"path":"/api",
+		"endpoint":"10.0.0.1:443",
+		"upstreamLatency":0.01,
+		"upstreamTLS":true,
+		"namespace":"test-app-production",
+		"ingress":"api",
+		"service":"test-api"
+	},{
+		"host":"testshop.com",
+		"status":"502",
+		"method":"GET",
+		"path":"/api",
+		"endpoint":"10.0.0.1:443",
+		"upstreamLatency":-1,
+		"upstreamTLS":true,
+		"namespace":"test-app-production",
+		"ingress":"api",
+		"service":"test-api"
+	},{
+		"host":"testshop.com",
+		"status":"503",
+		"method":"GET",
+		"path":"/api",
+		"endpoint":"-",
+		"upstreamLatency":-1,
+		"upstreamTLS":true,
+		"namespace":"test-app-production",
+		"ingress":"api",
+		"service":"test-api"
+	},{
+		"host":"testshop.com",
+		"status":"200",
+		"method":"GET",
+		"path":"/",
+		"endpoint":"10.0.0.2:80",
+		"upstreamLatency":0.002,
+		"namespace":"test-app-production",
+		"ingress":"web-yml",
+		"service":"test-app"
+	}]`))
+
+	want := `
+		# HELP nginx_ingress_controller_upstream_tls_connect_failures The total number of requests that failed to establish a connection with HTTPS upstream servers
+		# TYPE nginx_ingress_controller_upstream_tls_connect_failures counter
+		nginx_ingress_controller_upstream_tls_connect_failures{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="api",namespace="test-app-production",service="test-api"} 1
+		# HELP nginx_ingress_controller_upstream_tls_handshake_seconds The time spent establishing connections, including the TLS handshake, with HTTPS upstream servers
+		# TYPE nginx_ingress_controller_upstream_tls_handshake_seconds histogram
+		nginx_ingress_controller_upstream_tls_handshake_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="api",namespace="test-app-production",service="test-api",le="0.001"} 0
+		nginx_ingress_controller_upstream_tls_handshake_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="api",namespace="test-app-production",service="test-api",le="0.002"} 0
+		nginx_ingress_controller_upstream_tls_handshake_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="api",namespace="test-app-production",service="test-api",le="0.004"} 0
+		nginx_ingress_controller_upstream_tls_handshake_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="api",namespace="test-app-production",service="test-api",le="0.008"} 0
+		nginx_ingress_controller_upstream_tls_handshake_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="api",namespace="test-app-production",service="test-api",le="0.016"} 1
+		nginx_ingress_controller_upstream_tls_handshake_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="api",namespace="test-app-production",service="test-api",le="0.032"} 1
+		nginx_ingress_controller_upstream_tls_handshake_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="api",namespace="test-app-production",service="test-api",le="0.064"} 1
+		nginx_ingress_controller_upstream_tls_handshake_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="api",namespace="test-app-production",service="test-api",le="0.128"} 1
+		nginx_ingress_controller_upstream_tls_handshake_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="api",namespace="test-app-production",service="test-api",le="0.256"} 1
+		nginx_ingress_controller_upstream_tls_handshake_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="api",namespace="test-app-production",service="test-api",le="0.512"} 1
+		nginx_ingress_controller_upstream_tls_handshake_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="api",namespace="test-app-production",service="test-api",le="1.024"} 1
+		nginx_ingress_controller_upstream_tls_handshake_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="api",namespace="test-app-production",service="test-api",le="2.048"} 1
+		nginx_ingress_controller_upstream_tls_handshake_seconds_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="api",namespace="test-app-production",service="test-api",le="+Inf"} 1
+		nginx_ingress_controller_upstream_tls_handshake_seconds_sum{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="api",namespace="test-app-production",service="test-api"} 0.01
+		nginx_ingress_controller_upstream_tls_handshake_seconds_count{controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="api",namespace="test-app-production",service="test-api"} 1
+	`
+
+	metrics := []string{"nginx_ingress_controller_upstream_tls_handshake_seconds", "nginx_ingress_controller_upstream_tls_connect_failures"}
+	if err := GatherAndCompare(sc, want, metrics, registry); err != nil {
+		t.Errorf("unexpected collecting result:\n%s", err)
+	}
+}
+
+func TestBodySizeMetrics(t *testing.T) {
+	registry := prometheus.NewPedanticRegistry()
+
+	sc, err := NewSocketCollector("pod", "default", "ingress")
+	if err != nil {
+		t.Fatalf("unexpected error creating new SocketCollector: %v", err)
+	}
+	defer sc.Stop()
+
+	if err := registry.Register(sc); err != nil {
+		t.Errorf("registering collector failed: %s", err)
+	}
+	defer registry.Unregister(sc)
+
+	sc.SetHosts(sets.NewString("testshop.com"))
+
+	sc.handleMessage([]byte(`[{
+		"host":"testshop.com",
+		"status":"200",
+		"method":"POST",
+		"path":"/api",
+		"sizeMetrics":true,
+		"requestBodySize":300,
+		"responseBodySize":5000,
+		"namespace":"test-app-production",
+		"ingress":"api",
+		"service":"test-api"
+	},{
+		"host":"testshop.com",
+		"status":"200",
+		"method":"GET",
+		"path":"/",
+		"requestLength":100,
+		"namespace":"test-app-production",
+		"ingress":"web-yml",
+		"service":"test-app"
+	}]`))
+
+	want := `
+		# HELP nginx_ingress_controller_request_body_size The size of the request bodies, only for the Ingresses with the size metrics enabled
+		# TYPE nginx_ingress_controller_request_body_size histogram
+		nginx_ingress_controller_request_body_size_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",host="testshop.com",ingress="api",method="POST",namespace="test-app-production",path="/api",service="test-api",status="200",le="64"} 0
+		nginx_ingress_controller_request_body_size_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",host="testshop.com",ingress="api",method="POST",namespace="test-app-production",path="/api",service="test-api",status="200",le="256"} 0
+		nginx_ingress_controller_request_body_size_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",host="testshop.com",ingress="api",method="POST",namespace="test-app-production",path="/api",service="test-api",status="200",le="1024"} 1
+		nginx_ingress_controller_request_body_size_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",host="testshop.com",ingress="api",method="POST",namespace="test-app-production",path="/api",service="test-api",status="200",le="4096"} 1
+		nginx_ingress_controller_request_body_size_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",host="testshop.com",ingress="api",method="POST",namespace="test-app-production",path="/api",service="test-api",status="200",le="16384"} 1
+		nginx_ingress_controller_request_body_size_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",host="testshop.com",ingress="api",method="POST",namespace="test-app-production",path="/api",service="test-api",status="200",le="65536"} 1
+		nginx_ingress_controller_request_body_size_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",host="testshop.com",ingress="api",method="POST",namespace="test-app-production",path="/api",service="test-api",status="200",le="262144"} 1
+		nginx_ingress_controller_request_body_size_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",host="testshop.com",ingress="api",method="POST",namespace="test-app-production",path="/api",service="test-api",status="200",le="1048576"} 1
+		nginx_ingress_controller_request_body_size_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",host="testshop.com",ingress="api",method="POST",namespace="test-app-production",path="/api",service="test-api",status="200",le="+Inf"} 1
+		nginx_ingress_controller_request_body_size_sum{controller_class="ingress",controller_namespace="default",controller_pod="pod",host="testshop.com",ingress="api",method="POST",namespace="test-app-production",path="/api",service="test-api",status="200"} 300
+		nginx_ingress_controller_request_body_size_count{controller_class="ingress",controller_namespace="default",controller_pod="pod",host="testshop.com",ingress="api",method="POST",namespace="test-app-production",path="/api",service="test-api",status="200"} 1
+		# HELP nginx_ingress_controller_response_body_size The size of the response bodies, only for the Ingresses with the size metrics enabled
+		# TYPE nginx_ingress_controller_response_body_size histogram
+		nginx_ingress_controller_response_body_size_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",host="testshop.com",ingress="api",method="POST",namespace="test-app-production",path="/api",service="test-api",status="200",le="64"} 0
+		nginx_ingress_controller_response_body_size_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",host="testshop.com",ingress="api",method="POST",namespace="test-app-production",path="/api",service="test-api",status="200",le="256"} 0
+		nginx_ingress_controller_response_body_size_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",host="testshop.com",ingress="api",method="POST",namespace="test-app-production",path="/api",service="test-api",status="200",le="1024"} 0
+		nginx_ingress_controller_response_body_size_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",host="testshop.com",ingress="api",method="POST",namespace="test-app-production",path="/api",service="test-api",status="200",le="4096"} 0
+		nginx_ingress_controller_response_body_size_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",host="testshop.com",ingress="api",method="POST",namespace="test-app-production",path="/api",service="test-api",status="200",le="16384"} 1
+		nginx_ingress_controller_response_body_size_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",host="testshop.com",ingress="api",method="POST",namespace="test-app-production",path="/api",service="test-api",status="200",le="65536"} 1
+		nginx_ingress_controller_response_body_size_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",host="testshop.com",ingress="api",method="POST",namespace="test-app-production",path="/api",service="test-api",status="200",le="262144"} 1
+		nginx_ingress_controller_response_body_size_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",host="testshop.com",ingress="api",method="POST",namespace="test-app-production",path="/api",service="test-api",status="200",le="1048576"} 1
+		nginx_ingress_controller_response_body_size_bucket{controller_class="ingress",controller_namespace="default",controller_pod="pod",host="testshop.com",ingress="api",method="POST",namespace="test-app-production",path="/api",service="test-api",status="200",le="+Inf"} 1
+		nginx_ingress_controller_response_body_size_sum{controller_class="ingress",controller_namespace="default",controller_pod="pod",host="testshop.com",ingress="api",method="POST",namespace="test-app-production",path="/api",service="test-api",status="200"} 5000
+		nginx_ingress_controller_response_body_size_count{controller_class="ingress",controller_namespace="default",controller_pod="pod",host="testshop.com",ingress="api",method="POST",namespace="test-app-production",path="/api",service="test-api",status="200"} 1
+	`
+
+	metrics := []string{"nginx_ingress_controller_request_body_size", "nginx_ingress_controller_response_body_size"}
+	if err := GatherAndCompare(sc, want, metrics, registry); err != nil {
+		t.Errorf("unexpected collecting result:\n%s", err)
+	}
+}
+
+func TestAlternativeBackendMetrics(t *testing.T) {
+	registry := prometheus.NewPedanticRegistry()
+
+	sc, err := NewSocketCollector("pod", "default", "ingress")
+	if err != nil {
+		t.Fatalf("unexpected error creating new SocketCollector: %v", err)
+	}
+	defer sc.Stop()
+
+	if err := registry.Register(sc); err != nil {
+		t.Errorf("registering collector failed: %s", err)
+	}
+	defer registry.Unregister(sc)
+
+	sc.SetHosts(sets.NewString("testshop.com"))
+
+	sc.handleMessage([]byte(`[{
+		"host":"testshop.com",
+		"status":"200",
+		"method":"GET",
+		"path":"/",
+		"alternativeBackend":"test-app-production-test-app-canary-80",
+		"namespace":"test-app-production",
+		"ingress":"web-yml",
+		"service":"test-app"
+	},{
+		"host":"testshop.com",
+		"status":"200",
+		"method":"GET",
+		"path":"/",
+		"alternativeBackend":"test-app-production-test-app-canary-80",
+		"namespace":"test-app-production",
+		"ingress":"web-yml",
+		"service":"test-app"
+	},{
+		"host":"testshop.com",
+		"status":"200",
+		"method":"GET",
+		"path":"/",
+		"namespace":"test-app-production",
+		"ingress":"web-yml",
+		"service":"test-app"
+	}]`))
+
+	want := `
+		# HELP nginx_ingress_controller_alternative_backend_requests The total number of client requests served by an alternative backend, like a canary
+		# TYPE nginx_ingress_controller_alternative_backend_requests counter
+		nginx_ingress_controller_alternative_backend_requests{alternative_backend="test-app-production-test-app-canary-80",controller_class="ingress",controller_namespace="default",controller_pod="pod",ingress="web-yml",namespace="test-app-production",service="test-app"} 2
+	`
+
+	metrics := []string{"nginx_ingress_controller_alternative_backend_requests"}
+	if err := GatherAndCompare(sc, want, metrics, registry); err != nil {
+		t.Errorf("unexpected collecting result:\n%s", err)
+	}
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collectors
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"k8s.io/ingress-nginx/internal/ingress/controller/process"
+	"k8s.io/ingress-nginx/internal/log"
+)
+
+const workerSubSystem = "nginx_worker"
+
+type nginxWorkersData struct {
+	processes        *prometheus.Desc
+	cpuSecs          *prometheus.Desc
+	memResidentbytes *prometheus.Desc
+	openSockets      *prometheus.Desc
+	startTime        *prometheus.Desc
+}
+
+type nginxWorkers struct {
+	scrapeChan chan scrapeRequest
+	data       nginxWorkersData
+}
+
+// NewNGINXWorkers returns a new prometheus collector of the metrics of
+// each NGINX worker process, useful to detect the workers of previous
+// configurations that do not terminate after a reload
+func NewNGINXWorkers(pod, namespace, ingressClass string) (NGINXProcessCollector, error) {
+	constLabels := prometheus.Labels{
+		"controller_namespace": namespace,
+		"controller_class":     ingressClass,
+		"controller_pod":       pod,
+	}
+
+	p := nginxWorkers{
+		scrapeChan: make(chan scrapeRequest),
+		data: nginxWorkersData{
+			processes: prometheus.NewDesc(
+				prometheus.BuildFQName(PrometheusNamespace, workerSubSystem, "processes"),
+				"number of worker processes by state (active or shutting_down)",
+				[]string{"state"}, constLabels),
+
+			cpuSecs: prometheus.NewDesc(
+				prometheus.BuildFQName(PrometheusNamespace, workerSubSystem, "cpu_seconds_total"),
+				"Cpu usage in seconds of a worker process",
+				[]string{"pid"}, constLabels),
+
+			memResidentbytes: prometheus.NewDesc(
+				prometheus.BuildFQName(PrometheusNamespace, workerSubSystem, "resident_memory_bytes"),
+				"number of bytes of memory in use by a worker process",
+				[]string{"pid"}, constLabels),
+
+			openSockets: prometheus.NewDesc(
+				prometheus.BuildFQName(PrometheusNamespace, workerSubSystem, "open_sockets"),
+				"number of sockets open by a worker process, including the listening sockets",
+				[]string{"pid"}, constLabels),
+
+			startTime: prometheus.NewDesc(
+				prometheus.BuildFQName(PrometheusNamespace, workerSubSystem, "start_time_seconds"),
+				"start time in seconds since 1970/01/01 of a worker process",
+				[]string{"pid"}, constLabels),
+		},
+	}
+
+	return p, nil
+}
+
+// Describe implements prometheus.Collector.
+func (p nginxWorkers) Describe(ch chan<- *prometheus.Desc) {
+	ch <- p.data.processes
+	ch <- p.data.cpuSecs
+	ch <- p.data.memResidentbytes
+	ch <- p.data.openSockets
+	ch <- p.data.startTime
+}
+
+// Collect implements prometheus.Collector.
+func (p nginxWorkers) Collect(ch chan<- prometheus.Metric) {
+	req := scrapeRequest{results: ch, done: make(chan struct{})}
+	p.scrapeChan <- req
+	<-req.done
+}
+
+func (p nginxWorkers) Start() {
+	for req := range p.scrapeChan {
+		ch := req.results
+		p.scrape(ch)
+		req.done <- struct{}{}
+	}
+}
+
+func (p nginxWorkers) Stop() {
+	close(p.scrapeChan)
+}
+
+func (p nginxWorkers) scrape(ch chan<- prometheus.Metric) {
+	workers, err := process.ListWorkers()
+	if err != nil {
+		log.Warningf("unexpected error obtaining nginx worker process info: %v", err)
+		return
+	}
+
+	active, shuttingDown := 0, 0
+	for _, w := range workers {
+		if w.ShuttingDown {
+			shuttingDown++
+		} else {
+			active++
+		}
+
+		pid := strconv.Itoa(w.PID)
+		ch <- prometheus.MustNewConstMetric(p.data.cpuSecs,
+			prometheus.CounterValue, w.CPUSeconds, pid)
+		ch <- prometheus.MustNewConstMetric(p.data.memResidentbytes,
+			prometheus.GaugeValue, float64(w.ResidentMemory), pid)
+		ch <- prometheus.MustNewConstMetric(p.data.openSockets,
+			prometheus.GaugeValue, float64(w.OpenSockets), pid)
+		ch <- prometheus.MustNewConstMetric(p.data.startTime,
+			prometheus.GaugeValue, float64(w.StartTime.UnixNano())/float64(time.Second), pid)
+	}
+
+	ch <- prometheus.MustNewConstMetric(p.data.processes,
+		prometheus.GaugeValue, float64(active), "active")
+	ch <- prometheus.MustNewConstMetric(p.data.processes,
+		prometheus.GaugeValue, float64(shuttingDown), "shutting_down")
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collectors
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestNGINXWorkersCollector(t *testing.T) {
+	active := &exec.Cmd{Path: "/bin/sleep", Args: []string{"nginx: worker process", "1000000"}}
+	shuttingDown := &exec.Cmd{Path: "/bin/sleep", Args: []string{"nginx: worker process is shutting down", "1000000"}}
+
+	for _, cmd := range []*exec.Cmd{active, shuttingDown} {
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("unexpected error creating dummy process: %v", err)
+		}
+		go cmd.Wait()
+		defer cmd.Process.Kill()
+	}
+
+	cm, err := NewNGINXWorkers("pod", "default", "nginx")
+	if err != nil {
+		t.Fatalf("unexpected error creating nginx workers collector: %v", err)
+	}
+
+	go cm.Start()
+	defer cm.Stop()
+
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(cm); err != nil {
+		t.Errorf("registering collector failed: %s", err)
+	}
+	defer reg.Unregister(cm)
+
+	metrics, err := reg.Gather()
+	if err != nil {
+		t.Errorf("gathering metrics failed: %s", err)
+	}
+
+	m := filterMetrics(metrics, []string{"nginx_ingress_controller_nginx_worker_processes"})
+	if len(m) != 1 || len(m[0].GetMetric()) != 2 {
+		t.Fatalf("expected the number of processes by state but got %v", m)
+	}
+
+	for _, metric := range m[0].GetMetric() {
+		if *metric.Gauge.Value < 1 {
+			t.Errorf("expected at least one worker process with labels %v", metric.GetLabel())
+		}
+	}
+}
@@ -16,7 +16,13 @@ limitations under the License.
 
 package metric
 
-import "k8s.io/ingress-nginx/internal/ingress"
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"k8s.io/ingress-nginx/internal/ingress"
+)
 
 // DummyCollector dummy implementation for mocks in tests
 type DummyCollector struct{}
@@ -41,3 +47,51 @@ func (dc DummyCollector) Stop() {}
 
 // SetSSLExpireTime ...
 func (dc DummyCollector) SetSSLExpireTime([]*ingress.Server) {}
+
+// SetHosts ...
+func (dc DummyCollector) SetHosts(hosts sets.String) {}
+
+// SetChargebackIDs ...
+func (dc DummyCollector) SetChargebackIDs(map[string]string) {}
+
+// SetStaleConfiguration ...
+func (dc DummyCollector) SetStaleConfiguration(string, bool) {}
+
+// SetLeader ...
+func (dc DummyCollector) SetLeader(bool) {}
+
+// IncLeaderChanges ...
+func (dc DummyCollector) IncLeaderChanges() {}
+
+// SetLeaderIdentity ...
+func (dc DummyCollector) SetLeaderIdentity(string) {}
+
+// IncNGINXRestartCount ...
+func (dc DummyCollector) IncNGINXRestartCount() {}
+
+// SetLingeringWorkers ...
+func (dc DummyCollector) SetLingeringWorkers(int, time.Duration) {}
+
+// SetClassConflicts ...
+func (dc DummyCollector) SetClassConflicts(int) {}
+
+// SetSyncQueueDepth ...
+func (dc DummyCollector) SetSyncQueueDepth(int) {}
+
+// ObserveSyncQueueWait ...
+func (dc DummyCollector) ObserveSyncQueueWait(time.Duration) {}
+
+// IncSyncQueueSkipped ...
+func (dc DummyCollector) IncSyncQueueSkipped() {}
+
+// IncSyncQueueDropped ...
+func (dc DummyCollector) IncSyncQueueDropped() {}
+
+// ObserveSyncRateLimiterWait ...
+func (dc DummyCollector) ObserveSyncRateLimiterWait(time.Duration) {}
+
+// ObserveSyncDuration ...
+func (dc DummyCollector) ObserveSyncDuration(string, time.Duration) {}
+
+// IncSyncWatchdogTimeouts ...
+func (dc DummyCollector) IncSyncWatchdogTimeouts() {}
@@ -43,6 +43,46 @@ type Collector interface {
 	// SetHosts sets the hostnames that are being served by the ingress controller
 	SetHosts(sets.String)
 
+	// SetChargebackIDs sets the chargeback label of each Ingress
+	SetChargebackIDs(map[string]string)
+
+	// SetStaleConfiguration indicates if the running configuration is stale for a reason
+	SetStaleConfiguration(string, bool)
+
+	// SetLeader indicates if this instance is the leader of the status updates
+	SetLeader(bool)
+	// IncLeaderChanges counts the leaders elected
+	IncLeaderChanges()
+	// SetLeaderIdentity sets the identity of the current leader
+	SetLeaderIdentity(string)
+
+	// IncNGINXRestartCount counts the restarts of the NGINX master process
+	IncNGINXRestartCount()
+	// SetLingeringWorkers sets the number of NGINX workers shutting down for
+	// longer than worker-shutdown-timeout and the shutdown age of the oldest one
+	SetLingeringWorkers(int, time.Duration)
+
+	// SetClassConflicts sets the number of Ingresses with a host and path
+	// also defined in an Ingress of other class
+	SetClassConflicts(int)
+
+	// SetSyncQueueDepth sets the number of tasks waiting in the sync queue
+	SetSyncQueueDepth(int)
+	// ObserveSyncQueueWait observes the time a task waited in the sync queue
+	ObserveSyncQueueWait(time.Duration)
+	// IncSyncQueueSkipped counts the tasks of the sync queue skipped because
+	// a newer sync already happened
+	IncSyncQueueSkipped()
+	// IncSyncQueueDropped counts the tasks that could not be added to the sync queue
+	IncSyncQueueDropped()
+	// ObserveSyncRateLimiterWait observes the time a sync waited for the sync rate limiter
+	ObserveSyncRateLimiterWait(time.Duration)
+	// ObserveSyncDuration observes the time spent in an operation of a sync
+	ObserveSyncDuration(string, time.Duration)
+	// IncSyncWatchdogTimeouts counts the syncs that did not complete
+	// within the deadline of the sync watchdog
+	IncSyncWatchdogTimeouts()
+
 	Start()
 	Stop()
 }
@@ -50,6 +90,7 @@ type Collector interface {
 type collector struct {
 	nginxStatus  collectors.NGINXStatusCollector
 	nginxProcess collectors.NGINXProcessCollector
+	nginxWorkers collectors.NGINXProcessCollector
 
 	ingressController *collectors.Controller
 
@@ -77,6 +118,11 @@ func NewCollector(statusPort int, registry *prometheus.Registry) (Collector, err
 		return nil, err
 	}
 
+	wc, err := collectors.NewNGINXWorkers(podName, podNamespace, class.IngressClass)
+	if err != nil {
+		return nil, err
+	}
+
 	s, err := collectors.NewSocketCollector(podName, podNamespace, class.IngressClass)
 	if err != nil {
 		return nil, err
@@ -87,6 +133,7 @@ func NewCollector(statusPort int, registry *prometheus.Registry) (Collector, err
 	return Collector(&collector{
 		nginxStatus:  nc,
 		nginxProcess: pc,
+		nginxWorkers: wc,
 
 		ingressController: ic,
 
@@ -113,9 +160,70 @@ func (c *collector) RemoveMetrics(ingresses, hosts []string) {
 	c.ingressController.RemoveMetrics(hosts, c.registry)
 }
 
+func (c *collector) SetChargebackIDs(ids map[string]string) {
+	c.socket.SetChargebackIDs(ids)
+}
+
+func (c *collector) SetStaleConfiguration(reason string, stale bool) {
+	c.ingressController.SetStaleConfiguration(reason, stale)
+}
+
+func (c *collector) SetLeader(leader bool) {
+	c.ingressController.SetLeader(leader)
+}
+
+func (c *collector) IncLeaderChanges() {
+	c.ingressController.IncLeaderChanges()
+}
+
+func (c *collector) SetLeaderIdentity(identity string) {
+	c.ingressController.SetLeaderIdentity(identity)
+}
+
+func (c *collector) IncNGINXRestartCount() {
+	c.ingressController.IncNGINXRestartCount()
+}
+
+func (c *collector) SetLingeringWorkers(count int, oldest time.Duration) {
+	c.ingressController.SetLingeringWorkers(count, oldest)
+}
+
+func (c *collector) SetClassConflicts(count int) {
+	c.ingressController.SetClassConflicts(count)
+}
+
+func (c *collector) SetSyncQueueDepth(depth int) {
+	c.ingressController.SetSyncQueueDepth(depth)
+}
+
+func (c *collector) ObserveSyncQueueWait(d time.Duration) {
+	c.ingressController.ObserveSyncQueueWait(d)
+}
+
+func (c *collector) IncSyncQueueSkipped() {
+	c.ingressController.IncSyncQueueSkipped()
+}
+
+func (c *collector) IncSyncQueueDropped() {
+	c.ingressController.IncSyncQueueDropped()
+}
+
+func (c *collector) ObserveSyncRateLimiterWait(d time.Duration) {
+	c.ingressController.ObserveSyncRateLimiterWait(d)
+}
+
+func (c *collector) ObserveSyncDuration(operation string, d time.Duration) {
+	c.ingressController.ObserveSyncDuration(operation, d)
+}
+
+func (c *collector) IncSyncWatchdogTimeouts() {
+	c.ingressController.IncSyncWatchdogTimeouts()
+}
+
 func (c *collector) Start() {
 	c.registry.MustRegister(c.nginxStatus)
 	c.registry.MustRegister(c.nginxProcess)
+	c.registry.MustRegister(c.nginxWorkers)
 	c.registry.MustRegister(c.ingressController)
 	c.registry.MustRegister(c.socket)
 
@@ -126,17 +234,20 @@ func (c *collector) Start() {
 		c.nginxStatus.Start()
 	}()
 	go c.nginxProcess.Start()
+	go c.nginxWorkers.Start()
 	go c.socket.Start()
 }
 
 func (c *collector) Stop() {
 	c.registry.Unregister(c.nginxStatus)
 	c.registry.Unregister(c.nginxProcess)
+	c.registry.Unregister(c.nginxWorkers)
 	c.registry.Unregister(c.ingressController)
 	c.registry.Unregister(c.socket)
 
 	c.nginxStatus.Stop()
 	c.nginxProcess.Stop()
+	c.nginxWorkers.Stop()
 	c.socket.Stop()
 }
 
@@ -0,0 +1,146 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metric
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/internal/log"
+)
+
+// NamespaceMetricsPath is the prefix of the URL used to expose the metrics of a namespace
+const NamespaceMetricsPath = "/metrics/namespaces/"
+
+type namespaceHandler struct {
+	gatherer prometheus.Gatherer
+	client   kubernetes.Interface
+}
+
+// NewNamespaceHandler returns an HTTP handler that exposes only the metrics
+// of the Ingresses located in the namespace indicated in the URL, i.e.
+// /metrics/namespaces/<namespace>. Requests must contain a bearer token of a
+// user or service account allowed to get Ingresses in that namespace.
+func NewNamespaceHandler(gatherer prometheus.Gatherer, client kubernetes.Interface) http.Handler {
+	return namespaceHandler{
+		gatherer: gatherer,
+		client:   client,
+	}
+}
+
+func (h namespaceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	namespace := strings.TrimPrefix(r.URL.Path, NamespaceMetricsPath)
+	if namespace == "" || strings.Contains(namespace, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	token := k8s.BearerToken(r)
+	if token == "" {
+		http.Error(w, "a bearer token is required", http.StatusUnauthorized)
+		return
+	}
+
+	allowed, err := h.authorize(token, namespace)
+	if err != nil {
+		log.Errorf("Unexpected error authorizing access to metrics of namespace %v: %v", namespace, err)
+		http.Error(w, "unexpected error authorizing the request", http.StatusInternalServerError)
+		return
+	}
+
+	if !allowed {
+		http.Error(w, "access denied", http.StatusForbidden)
+		return
+	}
+
+	mfs, err := h.gatherer.Gather()
+	if err != nil {
+		log.Errorf("Error gathering metrics: %v", err)
+		http.Error(w, "unexpected error gathering metrics", http.StatusInternalServerError)
+		return
+	}
+
+	contentType := expfmt.Negotiate(r.Header)
+	w.Header().Set("Content-Type", string(contentType))
+
+	enc := expfmt.NewEncoder(w, contentType)
+	for _, mf := range filterByNamespace(mfs, namespace) {
+		if err := enc.Encode(mf); err != nil {
+			log.Errorf("Error encoding metric family %v: %v", mf.GetName(), err)
+			return
+		}
+	}
+}
+
+// authorize checks the token is valid and the user it belongs to
+// is allowed to get Ingresses in the namespace
+func (h namespaceHandler) authorize(token, namespace string) (bool, error) {
+	return k8s.Authorize(h.client, token, authorizationv1.ResourceAttributes{
+		Namespace: namespace,
+		Verb:      "get",
+		Group:     "extensions",
+		Resource:  "ingresses",
+	})
+}
+
+// filterByNamespace returns the metrics that belong to Ingresses located in
+// the namespace. Only metrics with the labels namespace and ingress are
+// considered, which excludes the metrics about the ingress controller itself.
+func filterByNamespace(mfs []*dto.MetricFamily, namespace string) []*dto.MetricFamily {
+	var filtered []*dto.MetricFamily
+
+	for _, mf := range mfs {
+		var metrics []*dto.Metric
+		for _, m := range mf.GetMetric() {
+			hasIngress := false
+			inNamespace := false
+			for _, l := range m.GetLabel() {
+				switch l.GetName() {
+				case "ingress":
+					hasIngress = true
+				case "namespace":
+					inNamespace = l.GetValue() == namespace
+				}
+			}
+
+			if hasIngress && inNamespace {
+				metrics = append(metrics, m)
+			}
+		}
+
+		if len(metrics) == 0 {
+			continue
+		}
+
+		filtered = append(filtered, &dto.MetricFamily{
+			Name:   mf.Name,
+			Help:   mf.Help,
+			Type:   mf.Type,
+			Metric: metrics,
+		})
+	}
+
+	return filtered
+}
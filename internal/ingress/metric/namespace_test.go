@@ -0,0 +1,117 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metric
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newFakeClient(validToken, allowedNamespace string) *fake.Clientset {
+	client := fake.NewSimpleClientset()
+
+	client.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		tr := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		if tr.Spec.Token == validToken {
+			tr.Status.Authenticated = true
+			tr.Status.User.Username = "system:serviceaccount:team:metrics"
+		}
+		return true, tr, nil
+	})
+
+	client.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		sar := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		sar.Status.Allowed = sar.Spec.ResourceAttributes.Namespace == allowedNamespace
+		return true, sar, nil
+	})
+
+	return client
+}
+
+func TestNamespaceHandler(t *testing.T) {
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "requests",
+		Help: "requests",
+	}, []string{"namespace", "ingress"})
+	requests.WithLabelValues("team", "app").Inc()
+	requests.WithLabelValues("other", "app").Inc()
+
+	certificates := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ssl_expire_time_seconds",
+		Help: "ssl_expire_time_seconds",
+	}, []string{"namespace", "host"})
+	certificates.WithLabelValues("team", "example.com").Set(1)
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(requests, certificates)
+
+	handler := NewNamespaceHandler(reg, newFakeClient("valid", "team"))
+
+	testCases := []struct {
+		name     string
+		path     string
+		token    string
+		code     int
+		contains string
+		excludes []string
+	}{
+		{"without token", "/metrics/namespaces/team", "", http.StatusUnauthorized, "", nil},
+		{"invalid token", "/metrics/namespaces/team", "invalid", http.StatusForbidden, "", nil},
+		{"namespace not allowed", "/metrics/namespaces/other", "valid", http.StatusForbidden, "", nil},
+		{"invalid path", "/metrics/namespaces/", "valid", http.StatusNotFound, "", nil},
+		{"allowed namespace", "/metrics/namespaces/team", "valid", http.StatusOK,
+			`requests{ingress="app",namespace="team"} 1`,
+			[]string{`namespace="other"`, "ssl_expire_time_seconds"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tc.path, nil)
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.code {
+				t.Fatalf("expected status code %v but %v was returned", tc.code, rec.Code)
+			}
+
+			body := rec.Body.String()
+			if !strings.Contains(body, tc.contains) {
+				t.Errorf("expected body to contain %q but returned %q", tc.contains, body)
+			}
+
+			for _, e := range tc.excludes {
+				if strings.Contains(body, e) {
+					t.Errorf("expected body to not contain %q but returned %q", e, body)
+				}
+			}
+		})
+	}
+}
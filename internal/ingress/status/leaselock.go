@@ -0,0 +1,151 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"errors"
+	"fmt"
+
+	coordination "k8s.io/api/coordination/v1beta1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coordinationclient "k8s.io/client-go/kubernetes/typed/coordination/v1beta1"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+const (
+	// ElectionLockConfigMaps stores the leader election record in an
+	// annotation of a ConfigMap
+	ElectionLockConfigMaps = "configmaps"
+	// ElectionLockLeases stores the leader election record in a
+	// coordination.k8s.io Lease object
+	ElectionLockLeases = "leases"
+)
+
+// IsValidElectionLockType checks the type of the leader election lock is supported
+func IsValidElectionLockType(lockType string) bool {
+	return lockType == ElectionLockConfigMaps || lockType == ElectionLockLeases
+}
+
+// leaseLock implements resourcelock.Interface using a Lease object,
+// which is lighter than updating the annotation of a ConfigMap and
+// does not trigger the watchers of ConfigMaps in every renewal
+type leaseLock struct {
+	leaseMeta  metav1.ObjectMeta
+	client     coordinationclient.LeasesGetter
+	lockConfig resourcelock.ResourceLockConfig
+	lease      *coordination.Lease
+}
+
+// Get returns the election record from the spec of the Lease
+func (ll *leaseLock) Get() (*resourcelock.LeaderElectionRecord, error) {
+	lease, err := ll.client.Leases(ll.leaseMeta.Namespace).Get(ll.leaseMeta.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	ll.lease = lease
+	return leaseSpecToRecord(&lease.Spec), nil
+}
+
+// Create attempts to create a Lease with the election record
+func (ll *leaseLock) Create(ler resourcelock.LeaderElectionRecord) error {
+	lease, err := ll.client.Leases(ll.leaseMeta.Namespace).Create(&coordination.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ll.leaseMeta.Name,
+			Namespace: ll.leaseMeta.Namespace,
+		},
+		Spec: recordToLeaseSpec(&ler),
+	})
+	if err != nil {
+		return err
+	}
+
+	ll.lease = lease
+	return nil
+}
+
+// Update replaces the election record in the spec of the Lease
+func (ll *leaseLock) Update(ler resourcelock.LeaderElectionRecord) error {
+	if ll.lease == nil {
+		return errors.New("lease not initialized, call get or create first")
+	}
+
+	ll.lease.Spec = recordToLeaseSpec(&ler)
+	lease, err := ll.client.Leases(ll.leaseMeta.Namespace).Update(ll.lease)
+	if err != nil {
+		return err
+	}
+
+	ll.lease = lease
+	return nil
+}
+
+// RecordEvent records an event about the leader election in the Lease
+func (ll *leaseLock) RecordEvent(s string) {
+	if ll.lockConfig.EventRecorder == nil || ll.lease == nil {
+		return
+	}
+
+	ll.lockConfig.EventRecorder.Eventf(&coordination.Lease{ObjectMeta: ll.lease.ObjectMeta}, apiv1.EventTypeNormal, "LeaderElection", "%v %v", ll.lockConfig.Identity, s)
+}
+
+// Describe returns the namespace and name of the Lease
+func (ll *leaseLock) Describe() string {
+	return fmt.Sprintf("%v/%v", ll.leaseMeta.Namespace, ll.leaseMeta.Name)
+}
+
+// Identity returns the identity of the lock
+func (ll *leaseLock) Identity() string {
+	return ll.lockConfig.Identity
+}
+
+func leaseSpecToRecord(spec *coordination.LeaseSpec) *resourcelock.LeaderElectionRecord {
+	record := &resourcelock.LeaderElectionRecord{}
+
+	if spec.HolderIdentity != nil {
+		record.HolderIdentity = *spec.HolderIdentity
+	}
+	if spec.LeaseDurationSeconds != nil {
+		record.LeaseDurationSeconds = int(*spec.LeaseDurationSeconds)
+	}
+	if spec.LeaseTransitions != nil {
+		record.LeaderTransitions = int(*spec.LeaseTransitions)
+	}
+	if spec.AcquireTime != nil {
+		record.AcquireTime = metav1.Time{Time: spec.AcquireTime.Time}
+	}
+	if spec.RenewTime != nil {
+		record.RenewTime = metav1.Time{Time: spec.RenewTime.Time}
+	}
+
+	return record
+}
+
+func recordToLeaseSpec(ler *resourcelock.LeaderElectionRecord) coordination.LeaseSpec {
+	holder := ler.HolderIdentity
+	duration := int32(ler.LeaseDurationSeconds)
+	transitions := int32(ler.LeaderTransitions)
+
+	return coordination.LeaseSpec{
+		HolderIdentity:       &holder,
+		LeaseDurationSeconds: &duration,
+		AcquireTime:          &metav1.MicroTime{Time: ler.AcquireTime.Time},
+		RenewTime:            &metav1.MicroTime{Time: ler.RenewTime.Time},
+		LeaseTransitions:     &transitions,
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"testing"
+	"time"
+
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	"k8s.io/ingress-nginx/internal/k8s"
+)
+
+func TestLeaseLock(t *testing.T) {
+	client := testclient.NewSimpleClientset()
+
+	lock := &leaseLock{
+		leaseMeta:  metav1.ObjectMeta{Namespace: "default", Name: "ingress-controller-leader-nginx"},
+		client:     client.CoordinationV1beta1(),
+		lockConfig: resourcelock.ResourceLockConfig{Identity: "pod-a"},
+	}
+
+	if _, err := lock.Get(); !k8sErrors.IsNotFound(err) {
+		t.Fatalf("expected a not found error but %v returned", err)
+	}
+
+	if err := lock.Update(resourcelock.LeaderElectionRecord{}); err == nil {
+		t.Fatalf("expected an error updating a lease not initialized")
+	}
+
+	now := metav1.NewTime(time.Now().Truncate(time.Second))
+	record := resourcelock.LeaderElectionRecord{
+		HolderIdentity:       "pod-a",
+		LeaseDurationSeconds: 30,
+		AcquireTime:          now,
+		RenewTime:            now,
+	}
+
+	if err := lock.Create(record); err != nil {
+		t.Fatalf("unexpected error creating the lease: %v", err)
+	}
+
+	record.HolderIdentity = "pod-b"
+	record.LeaderTransitions = 1
+	if err := lock.Update(record); err != nil {
+		t.Fatalf("unexpected error updating the lease: %v", err)
+	}
+
+	actual, err := lock.Get()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if actual.HolderIdentity != "pod-b" || actual.LeaseDurationSeconds != 30 || actual.LeaderTransitions != 1 {
+		t.Errorf("unexpected election record: %+v", actual)
+	}
+	if !actual.RenewTime.Equal(&now) {
+		t.Errorf("expected renew time %v but returned %v", now, actual.RenewTime)
+	}
+
+	if lock.Describe() != "default/ingress-controller-leader-nginx" {
+		t.Errorf("unexpected description %v", lock.Describe())
+	}
+	if lock.Identity() != "pod-a" {
+		t.Errorf("unexpected identity %v", lock.Identity())
+	}
+}
+
+func TestElectionLock(t *testing.T) {
+	s := statusSync{
+		pod:    &k8s.PodInfo{Name: "pod-a", Namespace: "default"},
+		Config: Config{Client: testclient.NewSimpleClientset()},
+	}
+
+	if _, ok := s.electionLock("leader", resourcelock.ResourceLockConfig{}).(*resourcelock.ConfigMapLock); !ok {
+		t.Errorf("expected a ConfigMap lock by default")
+	}
+
+	s.ElectionLockType = ElectionLockLeases
+	if _, ok := s.electionLock("leader", resourcelock.ResourceLockConfig{}).(*leaseLock); !ok {
+		t.Errorf("expected a Lease lock")
+	}
+}
@@ -25,7 +25,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/golang/glog"
 	"github.com/pkg/errors"
 
 	pool "gopkg.in/go-playground/pool.v3"
@@ -42,11 +41,22 @@ import (
 	"k8s.io/kubernetes/pkg/kubelet/util/sliceutils"
 
 	"k8s.io/ingress-nginx/internal/k8s"
+	"k8s.io/ingress-nginx/internal/log"
 	"k8s.io/ingress-nginx/internal/task"
 )
 
 const (
 	updateInterval = 60 * time.Second
+
+	// DefaultLeaseDuration is the time non-leader candidates wait
+	// before trying to acquire the leadership
+	DefaultLeaseDuration = 30 * time.Second
+	// DefaultRenewDeadline is the time the leader retries refreshing
+	// the leadership before giving up
+	DefaultRenewDeadline = 15 * time.Second
+	// DefaultRetryPeriod is the time between attempts to acquire
+	// or renew the leadership
+	DefaultRetryPeriod = 7500 * time.Millisecond
 )
 
 // Sync ...
@@ -64,12 +74,33 @@ type ingressLister interface {
 type Config struct {
 	Client clientset.Interface
 
+	// PublishService is a comma separated list of Services (namespace/name)
+	// whose addresses are set in the status of the Ingresses
 	PublishService string
 
+	// PublishStatusAddress is a comma separated list of IP addresses or
+	// hostnames set in the status of the Ingresses
 	PublishStatusAddress string
 
 	ElectionID string
 
+	// ElectionLockType is the kind of object used to store the leader
+	// election record (configmaps or leases)
+	ElectionLockType string
+
+	// LeaseDuration, RenewDeadline and RetryPeriod configure how long it
+	// takes to detect the leader is gone and elect a new one
+	LeaseDuration time.Duration
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+
+	// OnLeaderChange is invoked when this instance starts or stops being the leader
+	OnLeaderChange func(bool)
+	// OnNewLeader is invoked when a new leader is observed
+	OnNewLeader func(string)
+	// OnSync is invoked by the leader after each update of the status
+	OnSync func()
+
 	UpdateStatusOnShutdown bool
 
 	UseNodeInternalIP bool
@@ -116,7 +147,10 @@ func (s statusSync) Run() {
 	var stopCh chan struct{}
 	callbacks := leaderelection.LeaderCallbacks{
 		OnStartedLeading: func(ctx context.Context) {
-			glog.V(2).Infof("I am the new status update leader")
+			log.V(2).Infof("I am the new status update leader")
+			if s.OnLeaderChange != nil {
+				s.OnLeaderChange(true)
+			}
 			stopCh = make(chan struct{})
 			go s.syncQueue.Run(time.Second, stopCh)
 			// trigger initial sync
@@ -129,7 +163,10 @@ func (s statusSync) Run() {
 			}, stopCh)
 		},
 		OnStoppedLeading: func() {
-			glog.V(2).Infof("I am not status update leader anymore")
+			log.V(2).Infof("I am not status update leader anymore")
+			if s.OnLeaderChange != nil {
+				s.OnLeaderChange(false)
+			}
 			close(stopCh)
 
 			// cancel the context
@@ -140,7 +177,10 @@ func (s statusSync) Run() {
 			go s.elector.Run(leaderCtx)
 		},
 		OnNewLeader: func(identity string) {
-			glog.Infof("new leader elected: %v", identity)
+			log.Infof("new leader elected: %v", identity)
+			if s.OnNewLeader != nil {
+				s.OnNewLeader(identity)
+			}
 		},
 	}
 
@@ -152,31 +192,76 @@ func (s statusSync) Run() {
 		Host:      hostname,
 	})
 
-	lock := resourcelock.ConfigMapLock{
-		ConfigMapMeta: metav1.ObjectMeta{Namespace: s.pod.Namespace, Name: electionID},
-		Client:        s.Config.Client.CoreV1(),
-		LockConfig: resourcelock.ResourceLockConfig{
-			Identity:      s.pod.Name,
-			EventRecorder: recorder,
-		},
-	}
+	lock := s.electionLock(electionID, resourcelock.ResourceLockConfig{
+		Identity:      s.pod.Name,
+		EventRecorder: recorder,
+	})
 
-	ttl := 30 * time.Second
+	lease, renew, retry := s.electionDurations()
 	le, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
-		Lock:          &lock,
-		LeaseDuration: ttl,
-		RenewDeadline: ttl / 2,
-		RetryPeriod:   ttl / 4,
+		Lock:          lock,
+		LeaseDuration: lease,
+		RenewDeadline: renew,
+		RetryPeriod:   retry,
 		Callbacks:     callbacks,
 	})
 	if err != nil {
-		glog.Fatalf("unexpected error starting leader election: %v", err)
+		log.Fatalf("unexpected error starting leader election: %v", err)
 	}
 	s.elector = le
 
 	go le.Run(leaderCtx)
 }
 
+// electionLock returns the lock used by the leader election
+func (s statusSync) electionLock(electionID string, lockConfig resourcelock.ResourceLockConfig) resourcelock.Interface {
+	meta := metav1.ObjectMeta{Namespace: s.pod.Namespace, Name: electionID}
+
+	if s.ElectionLockType == ElectionLockLeases {
+		return &leaseLock{
+			leaseMeta:  meta,
+			client:     s.Config.Client.CoordinationV1beta1(),
+			lockConfig: lockConfig,
+		}
+	}
+
+	return &resourcelock.ConfigMapLock{
+		ConfigMapMeta: meta,
+		Client:        s.Config.Client.CoreV1(),
+		LockConfig:    lockConfig,
+	}
+}
+
+// electionDurations returns the durations used by the leader election,
+// falling back to the defaults for the values that are not set
+func (s statusSync) electionDurations() (lease, renew, retry time.Duration) {
+	lease, renew, retry = DefaultLeaseDuration, DefaultRenewDeadline, DefaultRetryPeriod
+	if s.LeaseDuration > 0 {
+		lease = s.LeaseDuration
+	}
+	if s.RenewDeadline > 0 {
+		renew = s.RenewDeadline
+	}
+	if s.RetryPeriod > 0 {
+		retry = s.RetryPeriod
+	}
+
+	return
+}
+
+// ValidateElectionDurations checks the leader election durations are coherent
+func ValidateElectionDurations(lease, renew, retry time.Duration) error {
+	if lease <= renew {
+		return fmt.Errorf("the lease duration (%v) must be greater than the renew deadline (%v)", lease, renew)
+	}
+
+	if renew <= time.Duration(leaderelection.JitterFactor*float64(retry)) {
+		return fmt.Errorf("the renew deadline (%v) must be greater than %v times the retry period (%v)", renew, leaderelection.JitterFactor, retry)
+	}
+
+	return nil
+}
+
 // Shutdown stop the sync. In case the instance is the leader it will remove the current IP
 // if there is no other instances running.
 func (s statusSync) Shutdown() {
@@ -188,36 +273,36 @@ func (s statusSync) Shutdown() {
 	}
 
 	if !s.UpdateStatusOnShutdown {
-		glog.Warningf("skipping update of status of Ingress rules")
+		log.Warningf("skipping update of status of Ingress rules")
 		return
 	}
 
-	glog.Infof("updating status of Ingress rules (remove)")
+	log.Infof("updating status of Ingress rules (remove)")
 
 	addrs, err := s.runningAddresses()
 	if err != nil {
-		glog.Errorf("error obtaining running IPs: %v", addrs)
+		log.Errorf("error obtaining running IPs: %v", addrs)
 		return
 	}
 
 	if len(addrs) > 1 {
 		// leave the job to the next leader
-		glog.Infof("leaving status update for next leader (%v)", len(addrs))
+		log.Infof("leaving status update for next leader (%v)", len(addrs))
 		return
 	}
 
 	if s.isRunningMultiplePods() {
-		glog.V(2).Infof("skipping Ingress status update (multiple pods running - another one will be elected as master)")
+		log.V(2).Infof("skipping Ingress status update (multiple pods running - another one will be elected as master)")
 		return
 	}
 
-	glog.Infof("removing address from ingress status (%v)", addrs)
+	log.Infof("removing address from ingress status (%v)", addrs)
 	s.updateStatus([]apiv1.LoadBalancerIngress{})
 }
 
 func (s *statusSync) sync(key interface{}) error {
 	if s.syncQueue.IsShuttingDown() {
-		glog.V(2).Infof("skipping Ingress status update (shutting down in progress)")
+		log.V(2).Infof("skipping Ingress status update (shutting down in progress)")
 		return nil
 	}
 
@@ -231,6 +316,10 @@ func (s *statusSync) sync(key interface{}) error {
 	}
 	s.updateStatus(sliceToStatus(addrs))
 
+	if s.OnSync != nil {
+		s.OnSync()
+	}
+
 	return nil
 }
 
@@ -242,7 +331,7 @@ func (s statusSync) keyfunc(input interface{}) (interface{}, error) {
 func NewStatusSyncer(config Config) Sync {
 	pod, err := k8s.GetPodDetails(config.Client)
 	if err != nil {
-		glog.Fatalf("unexpected error obtaining pod information: %v", err)
+		log.Fatalf("unexpected error obtaining pod information: %v", err)
 	}
 
 	st := statusSync{
@@ -261,31 +350,20 @@ func (s *statusSync) runningAddresses() ([]string, error) {
 	addrs := []string{}
 
 	if s.PublishService != "" {
-		ns, name, _ := k8s.ParseNameNS(s.PublishService)
-		svc, err := s.Client.CoreV1().Services(ns).Get(name, metav1.GetOptions{})
-		if err != nil {
-			return nil, err
-		}
-
-		if svc.Spec.Type == apiv1.ServiceTypeExternalName {
-			addrs = append(addrs, svc.Spec.ExternalName)
-			return addrs, nil
-		}
-
-		for _, ip := range svc.Status.LoadBalancer.Ingress {
-			if ip.IP == "" {
-				addrs = append(addrs, ip.Hostname)
-			} else {
-				addrs = append(addrs, ip.IP)
+		for _, svcName := range splitList(s.PublishService) {
+			svcAddrs, err := s.serviceAddresses(svcName)
+			if err != nil {
+				return nil, err
 			}
+
+			addrs = appendAddresses(addrs, svcAddrs...)
 		}
 
-		addrs = append(addrs, svc.Spec.ExternalIPs...)
 		return addrs, nil
 	}
 
 	if s.PublishStatusAddress != "" {
-		addrs = append(addrs, s.PublishStatusAddress)
+		addrs = appendAddresses(addrs, splitList(s.PublishStatusAddress)...)
 		return addrs, nil
 	}
 
@@ -312,6 +390,60 @@ func (s *statusSync) runningAddresses() ([]string, error) {
 	return addrs, nil
 }
 
+// serviceAddresses returns the addresses of a Service (namespace/name)
+// that fronts the ingress controller
+func (s *statusSync) serviceAddresses(svcName string) ([]string, error) {
+	ns, name, err := k8s.ParseNameNS(svcName)
+	if err != nil {
+		return nil, err
+	}
+
+	svc, err := s.Client.CoreV1().Services(ns).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	if svc.Spec.Type == apiv1.ServiceTypeExternalName {
+		return []string{svc.Spec.ExternalName}, nil
+	}
+
+	addrs := []string{}
+	for _, ip := range svc.Status.LoadBalancer.Ingress {
+		if ip.IP == "" {
+			addrs = append(addrs, ip.Hostname)
+		} else {
+			addrs = append(addrs, ip.IP)
+		}
+	}
+
+	addrs = append(addrs, svc.Spec.ExternalIPs...)
+	return addrs, nil
+}
+
+// splitList returns the non-empty elements of a comma separated list
+func splitList(list string) []string {
+	items := []string{}
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// appendAddresses appends the addresses not already present in the list
+func appendAddresses(addrs []string, newAddrs ...string) []string {
+	for _, addr := range newAddrs {
+		if !sliceutils.StringInSlice(addr, addrs) {
+			addrs = append(addrs, addr)
+		}
+	}
+
+	return addrs
+}
+
 func (s *statusSync) isRunningMultiplePods() bool {
 	pods, err := s.Client.CoreV1().Pods(s.pod.Namespace).List(metav1.ListOptions{
 		LabelSelector: labels.SelectorFromSet(s.pod.Labels).String(),
@@ -355,7 +487,7 @@ func (s *statusSync) updateStatus(newIngressPoint []apiv1.LoadBalancerIngress) {
 		curIPs := ing.Status.LoadBalancer.Ingress
 		sort.SliceStable(curIPs, lessLoadBalancerIngress(curIPs))
 		if ingressSliceEqual(curIPs, newIngressPoint) {
-			glog.V(3).Infof("skipping update of Ingress %v/%v (no change)", ing.Namespace, ing.Name)
+			log.V(3).Infof("skipping update of Ingress %v/%v (no change)", ing.Namespace, ing.Name)
 			continue
 		}
 
@@ -380,11 +512,11 @@ func runUpdate(ing *extensions.Ingress, status []apiv1.LoadBalancerIngress,
 			return nil, errors.Wrap(err, fmt.Sprintf("unexpected error searching Ingress %v/%v", ing.Namespace, ing.Name))
 		}
 
-		glog.Infof("updating Ingress %v/%v status to %v", currIng.Namespace, currIng.Name, status)
+		log.Infof("updating Ingress %v/%v status to %v", currIng.Namespace, currIng.Name, status)
 		currIng.Status.LoadBalancer.Ingress = status
 		_, err = ingClient.UpdateStatus(currIng)
 		if err != nil {
-			glog.Warningf("error updating ingress rule: %v", err)
+			log.Warningf("error updating ingress rule: %v", err)
 		}
 
 		return true, nil
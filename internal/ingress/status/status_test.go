@@ -18,6 +18,7 @@ package status
 
 import (
 	"os"
+	"reflect"
 	"testing"
 	"time"
 
@@ -371,6 +372,41 @@ func TestRunningAddresessWithPublishService(t *testing.T) {
 	}
 }
 
+func TestRunningAddresessWithMultiplePublishServices(t *testing.T) {
+	fk := buildStatusSync()
+	fk.PublishService = apiv1.NamespaceDefault + "/foo, " + apiv1.NamespaceDefault + "/internal"
+
+	_, err := fk.Client.CoreV1().Services(apiv1.NamespaceDefault).Create(&apiv1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "internal",
+			Namespace: apiv1.NamespaceDefault,
+		},
+		Status: apiv1.ServiceStatus{
+			LoadBalancer: apiv1.LoadBalancerStatus{
+				Ingress: []apiv1.LoadBalancerIngress{{IP: "10.0.0.1"}, {IP: "172.16.0.1"}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating service: %v", err)
+	}
+
+	r, err := fk.runningAddresses()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "foo4", "172.16.0.1"}
+	if !reflect.DeepEqual(r, expected) {
+		t.Errorf("returned %v but expected %v", r, expected)
+	}
+
+	fk.PublishService = apiv1.NamespaceDefault + "/foo,invalid"
+	if _, err := fk.runningAddresses(); err == nil {
+		t.Errorf("expected an error with an invalid service reference")
+	}
+}
+
 func TestRunningAddresessWithPods(t *testing.T) {
 	fk := buildStatusSync()
 	fk.PublishService = ""
@@ -408,6 +444,19 @@ func TestRunningAddresessWithPublishStatusAddress(t *testing.T) {
 	}
 }
 
+func TestRunningAddresessWithMultiplePublishStatusAddresses(t *testing.T) {
+	fk := buildStatusSync()
+	fk.PublishService = ""
+	fk.PublishStatusAddress = "127.0.0.1, lb.example.com,,127.0.0.1"
+
+	r, _ := fk.runningAddresses()
+
+	expected := []string{"127.0.0.1", "lb.example.com"}
+	if !reflect.DeepEqual(r, expected) {
+		t.Errorf("returned %v but expected %v", r, expected)
+	}
+}
+
 /*
 TODO: this test requires a refactoring
 func TestUpdateStatus(t *testing.T) {
@@ -496,3 +545,22 @@ func TestIngressSliceEqual(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateElectionDurations(t *testing.T) {
+	testCases := []struct {
+		lease, renew, retry time.Duration
+		valid               bool
+	}{
+		{DefaultLeaseDuration, DefaultRenewDeadline, DefaultRetryPeriod, true},
+		{8 * time.Second, 5 * time.Second, 2 * time.Second, true},
+		{5 * time.Second, 5 * time.Second, 2 * time.Second, false},
+		{8 * time.Second, 5 * time.Second, 5 * time.Second, false},
+	}
+
+	for _, tc := range testCases {
+		err := ValidateElectionDurations(tc.lease, tc.renew, tc.retry)
+		if (err == nil) != tc.valid {
+			t.Errorf("expected valid=%v for %v/%v/%v but returned %v", tc.valid, tc.lease, tc.renew, tc.retry, err)
+		}
+	}
+}
@@ -17,21 +17,36 @@ limitations under the License.
 package ingress
 
 import (
+	"strings"
+
 	apiv1 "k8s.io/api/core/v1"
 	extensions "k8s.io/api/extensions/v1beta1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 
+	"k8s.io/ingress-nginx/internal/ingress/annotations/allowedmethods"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/auth"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/authoidc"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/authreq"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/authtls"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/blocking"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/brotli"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/compression"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/connection"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/connectproxy"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/cors"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/fastcgi"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/geoipfilter"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/hsts"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/influxdb"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/ipwhitelist"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/log"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/luarestywaf"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/opentracing"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/proxy"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/proxycache"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/proxyssl"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/ratelimit"
+	"k8s.io/ingress-nginx/internal/ingress/annotations/realip"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/redirect"
 	"k8s.io/ingress-nginx/internal/ingress/annotations/rewrite"
 	"k8s.io/ingress-nginx/internal/ingress/resolver"
@@ -43,8 +58,34 @@ var (
 	// The name of each file is <namespace>-<secret name>.pem. The content is the concatenated
 	// certificate and key.
 	DefaultSSLDirectory = "/ingress-controller/ssl"
+
+	// InternalLocationPrefixes contains the prefixes of the paths used by the
+	// locations that only process subrequests generated by NGINX, like the
+	// ones of the external authentication. Requests from clients to these
+	// paths are denied and Ingress rules cannot use them.
+	InternalLocationPrefixes = []string{ExternalAuthLocationPrefix, ShadowLocationPrefix}
 )
 
+// ExternalAuthLocationPrefix is the prefix of the path of the internal
+// locations used to send the subrequests of the external authentication
+const ExternalAuthLocationPrefix = "/_external-auth-"
+
+// ShadowLocationPrefix is the prefix of the path of the internal locations
+// used to send the copies of the requests to the shadow backends
+const ShadowLocationPrefix = "/_shadow-"
+
+// IsInternalPath returns true if the path is reserved for the internal
+// locations generated by the controller
+func IsInternalPath(path string) bool {
+	for _, prefix := range InternalLocationPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // Configuration holds the definition of all the parts required to describe all
 // ingresses reachable by the ingress controller (using a filter by namespace)
 type Configuration struct {
@@ -61,6 +102,11 @@ type Configuration struct {
 	// BackendConfigChecksum contains the particular checksum of a Configuration object
 	BackendConfigChecksum string `json:"BackendConfigChecksum,omitempty"`
 
+	// RealIPRanges are the IP ranges published by CDNs, trusted to send the
+	// client address in addition to the ones of the configuration
+	// +optional
+	RealIPRanges []string `json:"realIPRanges,omitempty"`
+
 	// ConfigurationChecksum contains the particular checksum of a Configuration object
 	ConfigurationChecksum string `json:"configurationChecksum,omitempty"`
 }
@@ -85,6 +131,10 @@ type Backend struct {
 	UpstreamHashBy string `json:"upstream-hash-by,omitempty"`
 	// LB algorithm configuration per ingress
 	LoadBalancing string `json:"load-balance,omitempty"`
+	// Maximum percentage of the requests that can be passed to the next endpoint
+	NextUpstreamBudget int `json:"next-upstream-budget,omitempty"`
+	// SSLServerName is the server name sent with SNI to the endpoints using HTTPS or GRPCS
+	SSLServerName proxyssl.ServerName `json:"sslServerName"`
 	// Denotes if a backend has no server. The backend instead shares a server with another backend and acts as an
 	// alternative backend.
 	// This can be used to share multiple upstreams in the sam nginx server block.
@@ -95,6 +145,10 @@ type Backend struct {
 	// Contains a list of backends without servers that are associated with this backend.
 	// +optional
 	AlternativeBackends []string `json:"alternativeBackends,omitempty"`
+	// Policies to route requests to other backends depending on the value of a query parameter,
+	// indexed by the key (namespace/name) of the Ingress that defines them.
+	// +optional
+	QueryRoutingPolicies map[string]QueryRoutingPolicy `json:"queryRoutingPolicies,omitempty"`
 }
 
 // TrafficShapingPolicy describes the policies to put in place when a backend has no server and is used as an
@@ -104,11 +158,36 @@ type TrafficShapingPolicy struct {
 	// Weight (0-100) of traffic to redirect to the backend.
 	// e.g. Weight 20 means 20% of traffic will be redirected to the backend and 80% will remain
 	// with the other backend. 0 weight will not send any traffic to this backend
-	Weight int `json:"weight"`
+	Weight float64 `json:"weight"`
+	// WeightTotal is the total of the weights, 100 when it is zero, to route
+	// fractions of a percent of the traffic, e.g. weight 1 of 10000 is 0.01%
+	WeightTotal int `json:"weightTotal,omitempty"`
 	// Header on which to redirect requests to this backend
 	Header string `json:"header"`
 	// Cookie on which to redirect requests to this backend
 	Cookie string `json:"cookie"`
+	// HeaderValue is the exact value the Header must contain to redirect requests to this backend
+	HeaderValue string `json:"headerValue,omitempty"`
+	// HeaderPattern is a regular expression the Header must match to redirect requests to this backend
+	HeaderPattern string `json:"headerPattern,omitempty"`
+	// UpstreamVhost is the Host header sent to the backend when it is selected,
+	// instead of the one of the location
+	UpstreamVhost string `json:"upstreamVhost,omitempty"`
+	// Shadow indicates the backend receives a copy of the requests selected
+	// by the policy, discarding its responses, instead of serving them
+	Shadow bool `json:"shadow,omitempty"`
+	// Host restricts the policy to the requests of the server with this
+	// hostname, when the backend is shared with the locations of other servers
+	Host string `json:"host,omitempty"`
+}
+
+// QueryRoutingPolicy describes how requests are routed to other backends
+// depending on the value of a query parameter
+type QueryRoutingPolicy struct {
+	// Param is the name of the query parameter
+	Param string `json:"param"`
+	// Backends maps the values of the query parameter to the name of a backend
+	Backends map[string]string `json:"backends"`
 }
 
 // HashInclude defines if a field should be used or not to calculate the hash
@@ -145,6 +224,13 @@ type Endpoint struct {
 	Port string `json:"port"`
 	// Target returns a reference to the object providing the endpoint
 	Target *apiv1.ObjectReference `json:"target,omitempty"`
+	// Weight of the endpoint in the load balancing. Nil means the default weight
+	// +optional
+	Weight *int `json:"weight,omitempty"`
+	// Draining indicates the endpoint was removed from the backend and only
+	// receives requests of existing sessions until the end of the drain period
+	// +optional
+	Draining bool `json:"draining,omitempty"`
 }
 
 // Server describes a website
@@ -154,6 +240,10 @@ type Server struct {
 	// SSLPassthrough indicates if the TLS termination is realized in
 	// the server or in the remote endpoint
 	SSLPassthrough bool `json:"sslPassthrough"`
+	// ProxyProtocol is the version of the PROXY protocol, v1 or v2, sent
+	// to the endpoints of a SSL passthrough server
+	// +optional
+	ProxyProtocol string `json:"proxyProtocol,omitempty"`
 	// SSLCert describes the certificate that will be used on the server
 	SSLCert SSLCert `json:"sslCert"`
 	// Locations list of URIs configured in the server.
@@ -168,10 +258,23 @@ type Server struct {
 	// ServerSnippet returns the snippet of server
 	// +optional
 	ServerSnippet string `json:"serverSnippet"`
+	// Blocking contains the rules used to deny requests to the server
+	// +optional
+	Blocking blocking.Config `json:"blocking"`
+	// RealIP overrides the trusted proxies and the header with the client
+	// address of the configuration
+	// +optional
+	RealIP realip.Config `json:"realIP"`
+	// HSTS contains the configuration of the header Strict-Transport-Security
+	// +optional
+	HSTS hsts.Config `json:"hsts"`
 	// SSLCiphers returns list of ciphers to be enabled
 	SSLCiphers string `json:"sslCiphers,omitempty"`
 	// AuthTLSError contains the reason why the access to a server should be denied
 	AuthTLSError string `json:"authTLSError,omitempty"`
+	// Internal indicates the server only listens on the internal ports
+	// +optional
+	Internal bool `json:"internal,omitempty"`
 }
 
 // Location describes an URI inside a server.
@@ -243,6 +346,10 @@ type Location struct {
 	// to be used in connections against endpoints
 	// +optional
 	Proxy proxy.Config `json:"proxy,omitempty"`
+	// ProxyCache allows to cache the responses from the upstream servers
+	// using one of the zones defined in the configuration configmap
+	// +optional
+	ProxyCache proxycache.Config `json:"proxyCache,omitempty"`
 	// UsePortInRedirects indicates if redirects must specify the port
 	// +optional
 	UsePortInRedirects bool `json:"usePortInRedirects"`
@@ -275,6 +382,58 @@ type Location struct {
 	// BackendProtocol indicates which protocol should be used to communicate with the service
 	// By default this is HTTP
 	BackendProtocol string `json:"backend-protocol"`
+	// Brotli allows to configure the brotli compression of the location
+	Brotli brotli.Config `json:"brotli"`
+	// Satisfy indicates if the location requires all the access restrictions
+	// (whitelist and authentication) or only one of them (any)
+	Satisfy string `json:"satisfy"`
+	// Priority defines the precedence of the location when the paths
+	// of different Ingresses overlap. Higher values are rendered first.
+	Priority int `json:"location-priority"`
+	// AuthOIDC indicates the access to this location requires
+	// authentication using an OpenID Connect provider
+	AuthOIDC authoidc.Config `json:"auth-oidc"`
+	// AllowedMethods restricts the HTTP methods accepted in the location
+	// +optional
+	AllowedMethods allowedmethods.Config `json:"allowedMethods"`
+	// GeoIPFilter filters the access to the location by the country or
+	// autonomous system of the client using the GeoIP2 databases
+	// +optional
+	GeoIPFilter geoipfilter.Config `json:"geoipFilter"`
+	// ConnectProxy indicates the upstream servers are reached through
+	// a forward proxy using the HTTP CONNECT method
+	// +optional
+	ConnectProxy connectproxy.Config `json:"connectProxy"`
+	// Compression contains the content types excluded from the compression
+	// and the minimum length of the compressed responses
+	Compression compression.Config `json:"compression"`
+	// FastCGI contains the index and the parameters of the locations
+	// using the FCGI backend protocol
+	// +optional
+	FastCGI fastcgi.Config `json:"fastcgi"`
+	// ProxySSL contains the client certificate, the trusted CA certificates
+	// and the TLS settings of the connections to HTTPS and GRPCS upstreams
+	// +optional
+	ProxySSL proxyssl.Config `json:"proxySSL"`
+	// Opentracing indicates if the requests of the location are not traced
+	Opentracing opentracing.Config `json:"opentracing"`
+	// SizeMetrics indicates if the size of the request and response
+	// bodies of the location is reported in the metrics
+	SizeMetrics bool `json:"sizeMetrics"`
+	// WebSocketHeavy indicates the location serves many long-lived
+	// WebSocket connections, counted to defer the reloads of NGINX
+	WebSocketHeavy bool `json:"websocketHeavy"`
+	// Shadow indicates the backend of the location has shadow alternative
+	// backends, which receive a copy of some of the requests
+	Shadow bool `json:"shadow,omitempty"`
+	// ServiceLabels are the labels of the Service listed in the
+	// service-labels setting, exposed in NGINX variables
+	// +optional
+	ServiceLabels map[string]string `json:"serviceLabels,omitempty"`
+	// IngressLabels are the labels of the Ingress listed in the
+	// ingress-labels setting, exposed in NGINX variables
+	// +optional
+	IngressLabels map[string]string `json:"ingressLabels,omitempty"`
 }
 
 // SSLPassthroughBackend describes a SSL upstream server configured
@@ -288,6 +447,9 @@ type SSLPassthroughBackend struct {
 	Backend string `json:"namespace,omitempty"`
 	// Hostname returns the FQDN of the server
 	Hostname string `json:"hostname"`
+	// ProxyProtocol is the version of the PROXY protocol, v1 or v2, sent
+	// to the endpoints. Empty when disabled.
+	ProxyProtocol string `json:"proxyProtocol,omitempty"`
 }
 
 // L4Service describes a L4 Ingress service.
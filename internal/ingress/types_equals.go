@@ -70,6 +70,15 @@ func (c1 *Configuration) Equal(c2 *Configuration) bool {
 		return false
 	}
 
+	if len(c1.RealIPRanges) != len(c2.RealIPRanges) {
+		return false
+	}
+	for i := range c1.RealIPRanges {
+		if c1.RealIPRanges[i] != c2.RealIPRanges[i] {
+			return false
+		}
+	}
+
 	return true
 }
 
@@ -118,6 +127,12 @@ func (b1 *Backend) Equal(b2 *Backend) bool {
 	if b1.LoadBalancing != b2.LoadBalancing {
 		return false
 	}
+	if b1.NextUpstreamBudget != b2.NextUpstreamBudget {
+		return false
+	}
+	if !(&b1.SSLServerName).Equal(&b2.SSLServerName) {
+		return false
+	}
 
 	if len(b1.Endpoints) != len(b2.Endpoints) {
 		return false
@@ -140,6 +155,16 @@ func (b1 *Backend) Equal(b2 *Backend) bool {
 		return false
 	}
 
+	if len(b1.QueryRoutingPolicies) != len(b2.QueryRoutingPolicies) {
+		return false
+	}
+	for key, qrp1 := range b1.QueryRoutingPolicies {
+		qrp2, ok := b2.QueryRoutingPolicies[key]
+		if !ok || !qrp1.Equal(qrp2) {
+			return false
+		}
+	}
+
 	for _, vb1 := range b1.AlternativeBackends {
 		found := false
 		for _, vb2 := range b2.AlternativeBackends {
@@ -206,6 +231,18 @@ func (e1 *Endpoint) Equal(e2 *Endpoint) bool {
 	if e1.Port != e2.Port {
 		return false
 	}
+	if e1.Draining != e2.Draining {
+		return false
+	}
+
+	if e1.Weight != e2.Weight {
+		if e1.Weight == nil || e2.Weight == nil {
+			return false
+		}
+		if *e1.Weight != *e2.Weight {
+			return false
+		}
+	}
 
 	if e1.Target != e2.Target {
 		if e1.Target == nil || e2.Target == nil {
@@ -227,12 +264,47 @@ func (tsp1 TrafficShapingPolicy) Equal(tsp2 TrafficShapingPolicy) bool {
 	if tsp1.Weight != tsp2.Weight {
 		return false
 	}
+	if tsp1.WeightTotal != tsp2.WeightTotal {
+		return false
+	}
 	if tsp1.Header != tsp2.Header {
 		return false
 	}
 	if tsp1.Cookie != tsp2.Cookie {
 		return false
 	}
+	if tsp1.HeaderValue != tsp2.HeaderValue {
+		return false
+	}
+	if tsp1.HeaderPattern != tsp2.HeaderPattern {
+		return false
+	}
+	if tsp1.UpstreamVhost != tsp2.UpstreamVhost {
+		return false
+	}
+	if tsp1.Shadow != tsp2.Shadow {
+		return false
+	}
+	if tsp1.Host != tsp2.Host {
+		return false
+	}
+
+	return true
+}
+
+// Equal checks for equality between two QueryRoutingPolicies
+func (qrp1 QueryRoutingPolicy) Equal(qrp2 QueryRoutingPolicy) bool {
+	if qrp1.Param != qrp2.Param {
+		return false
+	}
+	if len(qrp1.Backends) != len(qrp2.Backends) {
+		return false
+	}
+	for value, backend := range qrp1.Backends {
+		if qrp2.Backends[value] != backend {
+			return false
+		}
+	}
 
 	return true
 }
@@ -251,6 +323,12 @@ func (s1 *Server) Equal(s2 *Server) bool {
 	if s1.SSLPassthrough != s2.SSLPassthrough {
 		return false
 	}
+	if s1.Internal != s2.Internal {
+		return false
+	}
+	if s1.ProxyProtocol != s2.ProxyProtocol {
+		return false
+	}
 	if !(&s1.SSLCert).Equal(&s2.SSLCert) {
 		return false
 	}
@@ -266,6 +344,15 @@ func (s1 *Server) Equal(s2 *Server) bool {
 	if s1.ServerSnippet != s2.ServerSnippet {
 		return false
 	}
+	if !(&s1.Blocking).Equal(&s2.Blocking) {
+		return false
+	}
+	if !(&s1.RealIP).Equal(&s2.RealIP) {
+		return false
+	}
+	if !(&s1.HSTS).Equal(&s2.HSTS) {
+		return false
+	}
 	if s1.SSLCiphers != s2.SSLCiphers {
 		return false
 	}
@@ -347,6 +434,9 @@ func (l1 *Location) Equal(l2 *Location) bool {
 	if !(&l1.Proxy).Equal(&l2.Proxy) {
 		return false
 	}
+	if !(&l1.ProxyCache).Equal(&l2.ProxyCache) {
+		return false
+	}
 	if l1.UsePortInRedirects != l2.UsePortInRedirects {
 		return false
 	}
@@ -380,6 +470,85 @@ func (l1 *Location) Equal(l2 *Location) bool {
 		return false
 	}
 
+	if !(&l1.Brotli).Equal(&l2.Brotli) {
+		return false
+	}
+
+	if l1.Satisfy != l2.Satisfy {
+		return false
+	}
+
+	if l1.Priority != l2.Priority {
+		return false
+	}
+
+	if !(&l1.AuthOIDC).Equal(&l2.AuthOIDC) {
+		return false
+	}
+
+	if !(&l1.AllowedMethods).Equal(&l2.AllowedMethods) {
+		return false
+	}
+
+	if !(&l1.GeoIPFilter).Equal(&l2.GeoIPFilter) {
+		return false
+	}
+
+	if !(&l1.ConnectProxy).Equal(&l2.ConnectProxy) {
+		return false
+	}
+
+	if !(&l1.Compression).Equal(&l2.Compression) {
+		return false
+	}
+
+	if !(&l1.FastCGI).Equal(&l2.FastCGI) {
+		return false
+	}
+
+	if !(&l1.ProxySSL).Equal(&l2.ProxySSL) {
+		return false
+	}
+
+	if !(&l1.Opentracing).Equal(&l2.Opentracing) {
+		return false
+	}
+
+	if l1.SizeMetrics != l2.SizeMetrics {
+		return false
+	}
+
+	if l1.WebSocketHeavy != l2.WebSocketHeavy {
+		return false
+	}
+
+	if l1.Shadow != l2.Shadow {
+		return false
+	}
+
+	if !stringMapEqual(l1.ServiceLabels, l2.ServiceLabels) {
+		return false
+	}
+
+	if !stringMapEqual(l1.IngressLabels, l2.IngressLabels) {
+		return false
+	}
+
+	return true
+}
+
+// stringMapEqual tests for equality between two maps of strings
+func stringMapEqual(m1, m2 map[string]string) bool {
+	if len(m1) != len(m2) {
+		return false
+	}
+
+	for k, v1 := range m1 {
+		if v2, ok := m2[k]; !ok || v1 != v2 {
+			return false
+		}
+	}
+
 	return true
 }
 
@@ -400,6 +569,9 @@ func (ptb1 *SSLPassthroughBackend) Equal(ptb2 *SSLPassthroughBackend) bool {
 	if ptb1.Port != ptb2.Port {
 		return false
 	}
+	if ptb1.ProxyProtocol != ptb2.ProxyProtocol {
+		return false
+	}
 
 	if ptb1.Service != ptb2.Service {
 		if ptb1.Service == nil || ptb2.Service == nil {
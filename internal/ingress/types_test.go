@@ -0,0 +1,37 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingress
+
+import "testing"
+
+func TestIsInternalPath(t *testing.T) {
+	testCases := map[string]bool{
+		"/":                            false,
+		"/app":                         false,
+		"/external-auth":               false,
+		"/_external-auth":              false,
+		"/_external-auth-":             true,
+		"/_external-auth-L2FwcA":       true,
+		"/_external-auth-L2FwcA/other": true,
+	}
+
+	for path, expected := range testCases {
+		if actual := IsInternalPath(path); actual != expected {
+			t.Errorf("%v: expected %v but returned %v", path, expected, actual)
+		}
+	}
+}
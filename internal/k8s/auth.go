@@ -0,0 +1,75 @@
+/*
+Copyright 2015 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8s
+
+import (
+	"net/http"
+	"strings"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	clientset "k8s.io/client-go/kubernetes"
+)
+
+// BearerToken returns the bearer token of the Authorization header of
+// a request, or an empty string
+func BearerToken(r *http.Request) string {
+	auth := strings.TrimSpace(r.Header.Get("Authorization"))
+	parts := strings.SplitN(auth, " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "bearer") {
+		return ""
+	}
+
+	return strings.TrimSpace(parts[1])
+}
+
+// Authorize checks the token is valid, using a TokenReview, and the user it
+// belongs to is allowed to access a resource, using a SubjectAccessReview
+func Authorize(kubeClient clientset.Interface, token string, attrs authorizationv1.ResourceAttributes) (bool, error) {
+	tr, err := kubeClient.AuthenticationV1().TokenReviews().Create(&authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{
+			Token: token,
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if !tr.Status.Authenticated {
+		return false, nil
+	}
+
+	extra := make(map[string]authorizationv1.ExtraValue, len(tr.Status.User.Extra))
+	for k, v := range tr.Status.User.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+
+	sar, err := kubeClient.AuthorizationV1().SubjectAccessReviews().Create(&authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:               tr.Status.User.Username,
+			UID:                tr.Status.User.UID,
+			Groups:             tr.Status.User.Groups,
+			Extra:              extra,
+			ResourceAttributes: &attrs,
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return sar.Status.Allowed, nil
+}
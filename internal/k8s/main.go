@@ -21,11 +21,12 @@ import (
 	"os"
 	"strings"
 
-	"github.com/golang/glog"
 	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clientset "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
+
+	"k8s.io/ingress-nginx/internal/log"
 )
 
 // ParseNameNS parses a string searching a namespace and name
@@ -42,7 +43,7 @@ func ParseNameNS(input string) (string, string, error) {
 func GetNodeIPOrName(kubeClient clientset.Interface, name string, useInternalIP bool) string {
 	node, err := kubeClient.CoreV1().Nodes().Get(name, metav1.GetOptions{})
 	if err != nil {
-		glog.Errorf("Error getting node %v: %v", name, err)
+		log.Errorf("Error getting node %v: %v", name, err)
 		return ""
 	}
 
@@ -104,7 +105,7 @@ func GetPodDetails(kubeClient clientset.Interface) (*PodInfo, error) {
 func MetaNamespaceKey(obj interface{}) string {
 	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
 	if err != nil {
-		glog.Warning(err)
+		log.Warning(err)
 	}
 
 	return key
@@ -0,0 +1,216 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package log writes the logs of the ingress controller to a set of sinks,
+// like stderr in text or JSON format or a remote syslog server. The functions
+// follow the API of glog, used before by the controller.
+package log
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Severity is the severity of a log entry
+type Severity int
+
+const (
+	// InfoSeverity is the severity of informational messages
+	InfoSeverity Severity = iota
+	// WarningSeverity is the severity of warnings
+	WarningSeverity
+	// ErrorSeverity is the severity of errors
+	ErrorSeverity
+	// FatalSeverity is the severity of errors that terminate the controller
+	FatalSeverity
+)
+
+func (s Severity) String() string {
+	switch s {
+	case InfoSeverity:
+		return "info"
+	case WarningSeverity:
+		return "warning"
+	case ErrorSeverity:
+		return "error"
+	default:
+		return "fatal"
+	}
+}
+
+// Entry is a message logged by the controller
+type Entry struct {
+	Time     time.Time
+	Severity Severity
+	// File and Line are the location of the call that logged the entry
+	File    string
+	Line    int
+	Message string
+}
+
+// Sink writes log entries to a destination
+type Sink interface {
+	Write(e *Entry) error
+}
+
+// flusher is implemented by the sinks that write the entries in the
+// background, to wait for them before the controller terminates
+type flusher interface {
+	Flush(timeout time.Duration)
+}
+
+// flushTimeout is the maximum time to wait for the sinks after a fatal error
+const flushTimeout = 5 * time.Second
+
+var (
+	mu    sync.Mutex
+	sinks = []Sink{NewTextSink(os.Stderr)}
+
+	verbosity int32
+
+	// exit terminates the process after a fatal error
+	exit = os.Exit
+)
+
+// SetSinks replaces the sinks where the log entries are written
+func SetSinks(s ...Sink) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	sinks = s
+}
+
+// SetVerbosity sets the level of the messages logged with V
+func SetVerbosity(level int) {
+	atomic.StoreInt32(&verbosity, int32(level))
+}
+
+// Verbosity returns the level of the messages logged with V
+func Verbosity() int {
+	return int(atomic.LoadInt32(&verbosity))
+}
+
+// Verbose is returned by V to log a message only when the verbosity
+// is at least the requested level
+type Verbose bool
+
+// V returns true when the verbosity is at least level. The messages
+// logged with the returned value are discarded otherwise:
+//
+//	log.V(2).Infof("Posting to %s", url)
+func V(level int) Verbose {
+	return Verbose(Verbosity() >= level)
+}
+
+// Info logs a message when v is true, like Info
+func (v Verbose) Info(args ...interface{}) {
+	if v {
+		output(InfoSeverity, fmt.Sprint(args...))
+	}
+}
+
+// Infof logs a message when v is true, like Infof
+func (v Verbose) Infof(format string, args ...interface{}) {
+	if v {
+		output(InfoSeverity, fmt.Sprintf(format, args...))
+	}
+}
+
+// Info logs an informational message
+func Info(args ...interface{}) {
+	output(InfoSeverity, fmt.Sprint(args...))
+}
+
+// Infof logs an informational message
+func Infof(format string, args ...interface{}) {
+	output(InfoSeverity, fmt.Sprintf(format, args...))
+}
+
+// Warning logs a warning
+func Warning(args ...interface{}) {
+	output(WarningSeverity, fmt.Sprint(args...))
+}
+
+// Warningf logs a warning
+func Warningf(format string, args ...interface{}) {
+	output(WarningSeverity, fmt.Sprintf(format, args...))
+}
+
+// Error logs an error
+func Error(args ...interface{}) {
+	output(ErrorSeverity, fmt.Sprint(args...))
+}
+
+// Errorf logs an error
+func Errorf(format string, args ...interface{}) {
+	output(ErrorSeverity, fmt.Sprintf(format, args...))
+}
+
+// Fatal logs an error and terminates the controller with exit code 255
+func Fatal(args ...interface{}) {
+	output(FatalSeverity, fmt.Sprint(args...))
+	flush()
+	exit(255)
+}
+
+// Fatalf logs an error and terminates the controller with exit code 255
+func Fatalf(format string, args ...interface{}) {
+	output(FatalSeverity, fmt.Sprintf(format, args...))
+	flush()
+	exit(255)
+}
+
+// flush waits for the sinks that write the entries in the background
+func flush() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, s := range sinks {
+		if f, ok := s.(flusher); ok {
+			f.Flush(flushTimeout)
+		}
+	}
+}
+
+// output writes an entry to all the sinks. It must be called directly
+// by the exported functions to report the location of their caller.
+func output(severity Severity, msg string) {
+	e := &Entry{
+		Time:     time.Now(),
+		Severity: severity,
+		File:     "???",
+		Message:  msg,
+	}
+
+	if _, file, line, ok := runtime.Caller(2); ok {
+		e.File = filepath.Base(file)
+		e.Line = line
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, s := range sinks {
+		if err := s.Write(e); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing log entry: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package log
+
+import (
+	"os"
+	"testing"
+)
+
+type fakeSink struct {
+	entries []*Entry
+}
+
+func (s *fakeSink) Write(e *Entry) error {
+	s.entries = append(s.entries, e)
+	return nil
+}
+
+func TestLog(t *testing.T) {
+	sink := &fakeSink{}
+	SetSinks(sink)
+	defer SetSinks(NewTextSink(os.Stderr))
+
+	SetVerbosity(2)
+	defer SetVerbosity(0)
+
+	exitCode := 0
+	exit = func(code int) { exitCode = code }
+
+	Infof("info %v", 1)
+	V(2).Infof("verbose %v", 2)
+	V(3).Info("discarded")
+	Warning("warning")
+	Errorf("error %v", 3)
+	Fatal("fatal")
+
+	expected := []struct {
+		severity Severity
+		message  string
+	}{
+		{InfoSeverity, "info 1"},
+		{InfoSeverity, "verbose 2"},
+		{WarningSeverity, "warning"},
+		{ErrorSeverity, "error 3"},
+		{FatalSeverity, "fatal"},
+	}
+
+	if len(sink.entries) != len(expected) {
+		t.Fatalf("expected %v entries but %v returned", len(expected), len(sink.entries))
+	}
+
+	for i, e := range sink.entries {
+		if e.Severity != expected[i].severity || e.Message != expected[i].message {
+			t.Errorf("expected %v %q but returned %v %q", expected[i].severity, expected[i].message, e.Severity, e.Message)
+		}
+		if e.File != "log_test.go" || e.Line == 0 {
+			t.Errorf("expected the location of the caller but returned %v:%v", e.File, e.Line)
+		}
+	}
+
+	if exitCode != 255 {
+		t.Errorf("expected exit code 255 after a fatal error but %v returned", exitCode)
+	}
+
+	if V(3) {
+		t.Errorf("expected verbosity 3 to be disabled")
+	}
+}
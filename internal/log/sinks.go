@@ -0,0 +1,242 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/syslog"
+	"net"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// TextFormat writes the log entries in the format of glog
+	TextFormat = "text"
+	// JSONFormat writes each log entry as a JSON object in a line
+	JSONFormat = "json"
+)
+
+// NewSink returns a sink that writes to w the log entries in a format,
+// TextFormat or JSONFormat
+func NewSink(format string, w io.Writer) (Sink, error) {
+	switch format {
+	case TextFormat:
+		return NewTextSink(w), nil
+	case JSONFormat:
+		return NewJSONSink(w), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q, valid values are %q and %q", format, TextFormat, JSONFormat)
+	}
+}
+
+type textSink struct {
+	w   io.Writer
+	pid int
+}
+
+// NewTextSink returns a sink that writes the log entries in the format of
+// glog, like `I1001 12:00:00.000000    1 nginx.go:42] message`
+func NewTextSink(w io.Writer) Sink {
+	return &textSink{w: w, pid: os.Getpid()}
+}
+
+func (s *textSink) Write(e *Entry) error {
+	msg := e.Message
+	if !strings.HasSuffix(msg, "\n") {
+		msg += "\n"
+	}
+
+	_, err := fmt.Fprintf(s.w, "%c%v %7d %v:%d] %v",
+		strings.ToUpper(e.Severity.String())[0], e.Time.Format("0102 15:04:05.000000"), s.pid, e.File, e.Line, msg)
+	return err
+}
+
+type jsonSink struct {
+	enc *json.Encoder
+}
+
+// NewJSONSink returns a sink that writes each log entry as a JSON object in
+// a line, with the keys time, severity, file, line and message
+func NewJSONSink(w io.Writer) Sink {
+	return &jsonSink{enc: json.NewEncoder(w)}
+}
+
+type jsonEntry struct {
+	Time     string `json:"time"`
+	Severity string `json:"severity"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Message  string `json:"message"`
+}
+
+func (s *jsonSink) Write(e *Entry) error {
+	return s.enc.Encode(jsonEntry{
+		Time:     e.Time.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		Severity: e.Severity.String(),
+		File:     e.File,
+		Line:     e.Line,
+		Message:  strings.TrimSuffix(e.Message, "\n"),
+	})
+}
+
+const (
+	// syslogQueueSize is the number of log entries waiting to be sent to the
+	// syslog server. The entries are discarded when the queue is full.
+	syslogQueueSize = 1000
+	// syslogTimeout is the maximum time to connect to the syslog server and
+	// to send a log entry
+	syslogTimeout = 5 * time.Second
+)
+
+type syslogSink struct {
+	network  string
+	address  string
+	tag      string
+	hostname string
+	pid      int
+
+	entries chan *Entry
+	// pending is the number of queued entries not sent yet
+	pending int64
+	// dropped is the number of entries discarded since the last one sent
+	dropped uint64
+
+	// conn is only used by the goroutine sending the entries
+	conn net.Conn
+}
+
+// NewSyslogSink returns a sink that sends the log entries to a remote
+// syslog server with an address like udp://10.0.0.1:514 or tcp://logs:601.
+// The entries are sent in the background and never block the logging: they
+// are discarded when the server is unavailable or too slow. The connection
+// is established when the first entry is sent and after each error.
+func NewSyslogSink(address, tag string) (Sink, error) {
+	parts := strings.SplitN(address, "://", 2)
+	if len(parts) != 2 || (parts[0] != "udp" && parts[0] != "tcp") || parts[1] == "" {
+		return nil, fmt.Errorf("invalid syslog address %q, the format is udp://host:port or tcp://host:port", address)
+	}
+
+	hostname, _ := os.Hostname()
+	s := &syslogSink{
+		network:  parts[0],
+		address:  parts[1],
+		tag:      tag,
+		hostname: hostname,
+		pid:      os.Getpid(),
+		entries:  make(chan *Entry, syslogQueueSize),
+	}
+
+	go s.run()
+
+	return s, nil
+}
+
+// Write queues an entry, discarding it if the queue is full
+func (s *syslogSink) Write(e *Entry) error {
+	atomic.AddInt64(&s.pending, 1)
+	select {
+	case s.entries <- e:
+	default:
+		atomic.AddInt64(&s.pending, -1)
+		atomic.AddUint64(&s.dropped, 1)
+	}
+
+	return nil
+}
+
+// Flush waits until the queued entries are sent, up to a timeout
+func (s *syslogSink) Flush(timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for atomic.LoadInt64(&s.pending) > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// run sends the queued entries to the syslog server. Errors are written to
+// stderr only when the server becomes unavailable, to avoid an error for
+// each entry.
+func (s *syslogSink) run() {
+	available := true
+	for e := range s.entries {
+		err := s.send(e)
+		if err != nil {
+			atomic.AddUint64(&s.dropped, 1)
+			if available {
+				fmt.Fprintf(os.Stderr, "Error sending log entries to syslog server %v, discarding them until it is available: %v\n", s.address, err)
+			}
+		}
+
+		available = err == nil
+		atomic.AddInt64(&s.pending, -1)
+	}
+}
+
+// send writes an entry to the syslog server, connecting to it if necessary,
+// preceded by the number of entries discarded before it
+func (s *syslogSink) send(e *Entry) error {
+	if s.conn == nil {
+		conn, err := net.DialTimeout(s.network, s.address, syslogTimeout)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+
+	msg := s.format(e)
+	if dropped := atomic.SwapUint64(&s.dropped, 0); dropped > 0 {
+		msg = s.format(&Entry{
+			Time:     time.Now(),
+			Severity: WarningSeverity,
+			File:     "sinks.go",
+			Message:  fmt.Sprintf("%v log entries were discarded", dropped),
+		}) + msg
+	}
+
+	s.conn.SetWriteDeadline(time.Now().Add(syslogTimeout))
+	_, err := io.WriteString(s.conn, msg)
+	if err != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+
+	return err
+}
+
+// format returns an entry in the format of the syslog package, with the
+// facility LOG_DAEMON
+func (s *syslogSink) format(e *Entry) string {
+	var priority syslog.Priority
+	switch e.Severity {
+	case InfoSeverity:
+		priority = syslog.LOG_INFO
+	case WarningSeverity:
+		priority = syslog.LOG_WARNING
+	case ErrorSeverity:
+		priority = syslog.LOG_ERR
+	default:
+		priority = syslog.LOG_CRIT
+	}
+
+	msg := strings.TrimSuffix(e.Message, "\n")
+	return fmt.Sprintf("<%d>%s %s %s[%d]: %v:%d] %s\n",
+		priority|syslog.LOG_DAEMON, e.Time.Format(time.RFC3339), s.hostname, s.tag, s.pid, e.File, e.Line, msg)
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestEntry() *Entry {
+	return &Entry{
+		Time:     time.Date(2018, 10, 1, 12, 30, 15, 123456000, time.UTC),
+		Severity: WarningSeverity,
+		File:     "nginx.go",
+		Line:     42,
+		Message:  "reload failed",
+	}
+}
+
+func TestNewSink(t *testing.T) {
+	testCases := map[string]struct {
+		format   string
+		expected string
+		err      bool
+	}{
+		"text": {
+			format:   TextFormat,
+			expected: fmt.Sprintf("W1001 12:30:15.123456 %7d nginx.go:42] reload failed\n", os.Getpid()),
+		},
+		"json": {
+			format:   JSONFormat,
+			expected: `{"time":"2018-10-01T12:30:15.123456Z","severity":"warning","file":"nginx.go","line":42,"message":"reload failed"}` + "\n",
+		},
+		"invalid format": {
+			format: "xml",
+			err:    true,
+		},
+	}
+
+	for name, tc := range testCases {
+		buf := &bytes.Buffer{}
+		sink, err := NewSink(tc.format, buf)
+		if (err != nil) != tc.err {
+			t.Errorf("%v: unexpected error %v", name, err)
+		}
+		if err != nil {
+			continue
+		}
+
+		if err := sink.Write(newTestEntry()); err != nil {
+			t.Errorf("%v: unexpected error %v", name, err)
+		}
+		if buf.String() != tc.expected {
+			t.Errorf("%v: expected %q but returned %q", name, tc.expected, buf.String())
+		}
+	}
+}
+
+func TestSyslogSink(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	sink, err := NewSyslogSink("udp://"+conn.LocalAddr().String(), "nginx-ingress-controller")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := sink.Write(newTestEntry()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 1024)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	msg := string(buf[:n])
+	// LOG_DAEMON|LOG_WARNING
+	if !strings.HasPrefix(msg, "<28>") || !strings.Contains(msg, "nginx-ingress-controller") ||
+		!strings.HasSuffix(strings.TrimSpace(msg), "nginx.go:42] reload failed") {
+		t.Errorf("unexpected syslog message %q", msg)
+	}
+
+	for _, address := range []string{"10.0.0.1:514", "http://10.0.0.1", "udp://"} {
+		if _, err := NewSyslogSink(address, "test"); err == nil {
+			t.Errorf("expected an error with the address %q", address)
+		}
+	}
+}
+
+func TestSyslogSinkReconnect(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	address := l.Addr().String()
+	l.Close()
+
+	// the server is not available
+	sink, err := NewSyslogSink("tcp://"+address, "nginx-ingress-controller")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sink.Write(newTestEntry())
+	sink.(*syslogSink).Flush(5 * time.Second)
+
+	l, err = net.Listen("tcp", address)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer l.Close()
+
+	sink.Write(newTestEntry())
+
+	l.(*net.TCPListener).SetDeadline(time.Now().Add(5 * time.Second))
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+	for _, expected := range []string{"1 log entries were discarded", "nginx.go:42] reload failed"} {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.HasSuffix(strings.TrimSpace(line), expected) {
+			t.Errorf("expected a syslog message ending with %q but got %q", expected, line)
+		}
+	}
+}
+
+func TestSyslogSinkQueueFull(t *testing.T) {
+	// without the goroutine sending the entries
+	sink := &syslogSink{entries: make(chan *Entry, 1)}
+
+	done := make(chan struct{})
+	go func() {
+		sink.Write(newTestEntry())
+		sink.Write(newTestEntry())
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the entries discarded when the queue is full")
+	}
+
+	if sink.dropped != 1 {
+		t.Errorf("expected 1 entry discarded but got %v", sink.dropped)
+	}
+}
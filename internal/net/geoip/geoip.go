@@ -0,0 +1,130 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package geoip
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"k8s.io/ingress-nginx/internal/file"
+	"k8s.io/ingress-nginx/internal/net/proxy"
+)
+
+var (
+	// Path is the directory that contains the GeoIP2 databases used by NGINX
+	Path = "/etc/nginx/geoip"
+
+	// Editions contains the names of the GeoIP2 databases used by NGINX
+	Editions = []string{"GeoLite2-City", "GeoLite2-ASN"}
+
+	// downloadURL is the URL of the MaxMind service used to download the databases
+	downloadURL = "https://download.maxmind.com/app/geoip_download?license_key=%v&edition_id=%v&suffix=tar.gz"
+
+	client = proxy.NewClient(5 * time.Minute)
+)
+
+// DownloadDatabases downloads the latest version of the GeoIP2 databases
+// using a MaxMind license key. Returns true if any of the databases changed.
+func DownloadDatabases(licenseKey string) (bool, error) {
+	changed := false
+	for _, edition := range Editions {
+		updated, err := downloadDatabase(licenseKey, edition)
+		if err != nil {
+			return changed, fmt.Errorf("downloading GeoIP2 database %v: %v", edition, err)
+		}
+
+		changed = changed || updated
+	}
+
+	return changed, nil
+}
+
+func downloadDatabase(licenseKey, edition string) (bool, error) {
+	resp, err := client.Get(fmt.Sprintf(downloadURL, licenseKey, edition))
+	if err != nil {
+		// the URL contains the license key
+		if uerr, ok := err.(*url.Error); ok {
+			return false, uerr.Err
+		}
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("unexpected status code %v", resp.StatusCode)
+	}
+
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return false, err
+	}
+	defer gz.Close()
+
+	mmdb := edition + ".mmdb"
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return false, fmt.Errorf("the archive does not contain the file %v", mmdb)
+		}
+		if err != nil {
+			return false, err
+		}
+
+		if hdr.Typeflag != tar.TypeReg || path.Base(hdr.Name) != mmdb {
+			continue
+		}
+
+		return writeDatabase(tr, filepath.Join(Path, mmdb))
+	}
+}
+
+// writeDatabase replaces the content of a database only if it changed.
+// The file is written in a temporal location and then renamed to avoid
+// NGINX reading a partial database.
+func writeDatabase(r io.Reader, dst string) (bool, error) {
+	tmp, err := ioutil.TempFile(filepath.Dir(dst), filepath.Base(dst))
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, r)
+	tmp.Close()
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := os.Stat(dst); err == nil && file.SHA1(tmp.Name()) == file.SHA1(dst) {
+		return false, nil
+	}
+
+	err = os.Chmod(tmp.Name(), 0644)
+	if err != nil {
+		return false, err
+	}
+
+	return true, os.Rename(tmp.Name(), dst)
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package geoip
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func buildArchive(t *testing.T, edition, content string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	name := fmt.Sprintf("%v_20181001/%v.mmdb", edition, edition)
+	err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+	if err != nil {
+		t.Fatalf("unexpected error writing archive: %v", err)
+	}
+	if _, err := tw.Write([]byte(content)); err != nil {
+		t.Fatalf("unexpected error writing archive: %v", err)
+	}
+
+	tw.Close()
+	gz.Close()
+
+	return buf.Bytes()
+}
+
+func TestDownloadDatabases(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geoip")
+	if err != nil {
+		t.Fatalf("unexpected error creating temporal directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	content := "v1"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("license_key") != "valid" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Write(buildArchive(t, r.URL.Query().Get("edition_id"), content))
+	}))
+	defer server.Close()
+
+	oldPath, oldURL := Path, downloadURL
+	defer func() { Path, downloadURL = oldPath, oldURL }()
+	Path = dir
+	downloadURL = server.URL + "?license_key=%v&edition_id=%v"
+
+	if _, err := DownloadDatabases("invalid"); err == nil {
+		t.Errorf("expected an error using an invalid license key")
+	}
+
+	changed, err := DownloadDatabases("valid")
+	if err != nil || !changed {
+		t.Fatalf("expected the databases to change but returned %v (error: %v)", changed, err)
+	}
+
+	for _, edition := range Editions {
+		b, err := ioutil.ReadFile(filepath.Join(dir, edition+".mmdb"))
+		if err != nil || string(b) != content {
+			t.Errorf("expected database %v with content %q but returned %q (error: %v)", edition, content, b, err)
+		}
+	}
+
+	changed, err = DownloadDatabases("valid")
+	if err != nil || changed {
+		t.Errorf("expected the databases to not change but returned %v (error: %v)", changed, err)
+	}
+
+	content = "v2"
+	changed, err = DownloadDatabases("valid")
+	if err != nil || !changed {
+		t.Errorf("expected the databases to change but returned %v (error: %v)", changed, err)
+	}
+}
+
+func TestDownloadDatabasesError(t *testing.T) {
+	oldURL := downloadURL
+	defer func() { downloadURL = oldURL }()
+	// nothing listens on the port 1
+	downloadURL = "http://127.0.0.1:1/?license_key=%v&edition_id=%v"
+
+	_, err := DownloadDatabases("secret-license-key")
+	if err == nil {
+		t.Fatalf("expected an error downloading the databases")
+	}
+
+	if strings.Contains(err.Error(), "secret-license-key") {
+		t.Errorf("expected the error to not contain the license key but returned %v", err)
+	}
+}
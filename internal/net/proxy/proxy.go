@@ -0,0 +1,104 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package proxy contains the HTTP clients used by the controller to reach
+// servers outside the cluster, like the CA servers of the SSL chain
+// completion, through an optional HTTP proxy. Requests sent by NGINX and
+// DNS resolutions do not use the proxy.
+package proxy
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	mu sync.RWMutex
+	// proxy returns the proxy of a request, by default the one defined
+	// in the environment variables HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	proxy = http.ProxyFromEnvironment
+)
+
+// Set configures the HTTP proxy used by the clients returned by NewClient.
+// Requests to hosts, domains or CIDRs contained in noProxy do not use the proxy.
+func Set(proxyURL *url.URL, noProxy []string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	proxy = Func(proxyURL, noProxy)
+}
+
+// NewClient returns an HTTP client that uses the proxy configured with Set,
+// also when Set is called after creating the client
+func NewClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy: func(req *http.Request) (*url.URL, error) {
+				mu.RLock()
+				defer mu.RUnlock()
+
+				return proxy(req)
+			},
+			DialContext: (&net.Dialer{
+				Timeout:   30 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+		},
+	}
+}
+
+// Func returns a function that returns the proxy to use for a request,
+// unless the host matches one of the entries of noProxy
+func Func(proxyURL *url.URL, noProxy []string) func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		host := req.URL.Hostname()
+		ip := net.ParseIP(host)
+
+		for _, entry := range noProxy {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+
+			if entry == "*" {
+				return nil, nil
+			}
+
+			if _, cidr, err := net.ParseCIDR(entry); err == nil {
+				if ip != nil && cidr.Contains(ip) {
+					return nil, nil
+				}
+				continue
+			}
+
+			domain := strings.TrimPrefix(entry, ".")
+			if host == domain || strings.HasSuffix(host, "."+domain) {
+				return nil, nil
+			}
+		}
+
+		return proxyURL, nil
+	}
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestFunc(t *testing.T) {
+	proxy, _ := url.Parse("http://proxy.corp:3128")
+	fn := Func(proxy, []string{"internal.corp", ".example.com", "10.0.0.0/8", " "})
+
+	testCases := []struct {
+		url      string
+		expected *url.URL
+	}{
+		{"http://crt.sectigo.com/ca.crt", proxy},
+		{"http://internal.corp/ca.crt", nil},
+		{"http://pki.internal.corp/ca.crt", nil},
+		{"http://notinternal.corp/ca.crt", proxy},
+		{"http://example.com/ca.crt", nil},
+		{"http://ca.example.com:8080/ca.crt", nil},
+		{"http://10.1.2.3/ca.crt", nil},
+		{"http://192.168.1.1/ca.crt", proxy},
+	}
+
+	for _, tc := range testCases {
+		req, _ := http.NewRequest("GET", tc.url, nil)
+		p, err := fn(req)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tc.url, err)
+		}
+		if p != tc.expected {
+			t.Errorf("%v: expected proxy %v but %v was returned", tc.url, tc.expected, p)
+		}
+	}
+
+	fn = Func(proxy, []string{"*"})
+	req, _ := http.NewRequest("GET", "http://crt.sectigo.com/ca.crt", nil)
+	if p, _ := fn(req); p != nil {
+		t.Errorf("expected no proxy but %v was returned", p)
+	}
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssl
+
+import (
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/zakjan/cert-chain-resolver/certUtil"
+
+	"k8s.io/ingress-nginx/internal/net/proxy"
+)
+
+// chainCompletionClient is the HTTP client used to download the
+// intermediate CA certificates during the SSL chain completion
+var chainCompletionClient = proxy.NewClient(30 * time.Second)
+
+// fetchCertificateChain downloads the chain of intermediate CA certificates
+// using the Authority Information Access extension of the certificates
+func fetchCertificateChain(cert *x509.Certificate) ([]*x509.Certificate, error) {
+	certs := []*x509.Certificate{cert}
+
+	for certs[len(certs)-1].IssuingCertificateURL != nil {
+		parentURL := certs[len(certs)-1].IssuingCertificateURL[0]
+
+		data, err := download(parentURL)
+		if err != nil {
+			return nil, err
+		}
+
+		parent, err := certUtil.DecodeCertificate(data)
+		if err != nil {
+			return nil, err
+		}
+
+		// stop once the root CA is reached
+		if parent.CheckSignatureFrom(parent) == nil {
+			break
+		}
+
+		certs = append(certs, parent)
+	}
+
+	return certs, nil
+}
+
+func download(url string) ([]byte, error) {
+	resp, err := chainCompletionClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %v downloading %v", resp.StatusCode, url)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
@@ -32,13 +32,13 @@ import (
 	"strconv"
 	"time"
 
-	"github.com/golang/glog"
 	"github.com/zakjan/cert-chain-resolver/certUtil"
 
 	"k8s.io/apimachinery/pkg/util/sets"
 
 	"k8s.io/ingress-nginx/internal/file"
 	"k8s.io/ingress-nginx/internal/ingress"
+	"k8s.io/ingress-nginx/internal/log"
 )
 
 var (
@@ -56,7 +56,7 @@ func AddOrUpdateCertAndKey(name string, cert, key, ca []byte,
 	if err != nil {
 		return nil, fmt.Errorf("could not create temp pem file %v: %v", pemFileName, err)
 	}
-	glog.V(3).Infof("Creating temp file %v for Keypair: %v", tempPemFile.Name(), pemName)
+	log.V(3).Infof("Creating temp file %v for Keypair: %v", tempPemFile.Name(), pemName)
 
 	_, err = tempPemFile.Write(cert)
 	if err != nil {
@@ -110,11 +110,11 @@ func AddOrUpdateCertAndKey(name string, cert, key, ca []byte,
 	}
 
 	if len(pemCert.Extensions) > 0 {
-		glog.V(3).Info("parsing ssl certificate extensions")
+		log.V(3).Info("parsing ssl certificate extensions")
 		for _, ext := range getExtension(pemCert, oidExtensionSubjectAltName) {
 			dns, _, _, err := parseSANExtension(ext.Value)
 			if err != nil {
-				glog.Warningf("unexpected error parsing certificate extensions: %v", err)
+				log.Warningf("unexpected error parsing certificate extensions: %v", err)
 				continue
 			}
 
@@ -135,7 +135,8 @@ func AddOrUpdateCertAndKey(name string, cert, key, ca []byte,
 		bundle := x509.NewCertPool()
 		bundle.AppendCertsFromPEM(ca)
 		opts := x509.VerifyOptions{
-			Roots: bundle,
+			Roots:       bundle,
+			CurrentTime: verificationTime(pemCert, time.Now()),
 		}
 
 		_, err := pemCert.Verify(opts)
@@ -224,11 +225,11 @@ func CreateSSLCert(name string, cert, key, ca []byte) (*ingress.SSLCert, error)
 	}
 
 	if len(pemCert.Extensions) > 0 {
-		glog.V(3).Info("parsing ssl certificate extensions")
+		log.V(3).Info("parsing ssl certificate extensions")
 		for _, ext := range getExtension(pemCert, oidExtensionSubjectAltName) {
 			dns, _, _, err := parseSANExtension(ext.Value)
 			if err != nil {
-				glog.Warningf("unexpected error parsing certificate extensions: %v", err)
+				log.Warningf("unexpected error parsing certificate extensions: %v", err)
 				continue
 			}
 
@@ -244,7 +245,8 @@ func CreateSSLCert(name string, cert, key, ca []byte) (*ingress.SSLCert, error)
 		bundle := x509.NewCertPool()
 		bundle.AppendCertsFromPEM(ca)
 		opts := x509.VerifyOptions{
-			Roots: bundle,
+			Roots:       bundle,
+			CurrentTime: verificationTime(pemCert, time.Now()),
 		}
 
 		_, err := pemCert.Verify(opts)
@@ -366,7 +368,7 @@ func AddCertAuth(name string, ca []byte, fs file.Filesystem) (*ingress.SSLCert,
 		return nil, fmt.Errorf("could not write CA file %v: %v", caFileName, err)
 	}
 
-	glog.V(3).Infof("Created CA Certificate for Authentication: %v", caFileName)
+	log.V(3).Infof("Created CA Certificate for Authentication: %v", caFileName)
 	return &ingress.SSLCert{
 		Certificate: pemCert,
 		CAFileName:  caFileName,
@@ -382,7 +384,7 @@ func AddOrUpdateDHParam(name string, dh []byte, fs file.Filesystem) (string, err
 
 	tempPemFile, err := fs.TempFile(file.DefaultSSLDirectory, pemName)
 
-	glog.V(3).Infof("Creating temp file %v for DH param: %v", tempPemFile.Name(), pemName)
+	log.V(3).Infof("Creating temp file %v for DH param: %v", tempPemFile.Name(), pemName)
 	if err != nil {
 		return "", fmt.Errorf("could not create temp pem file %v: %v", pemFileName, err)
 	}
@@ -432,7 +434,7 @@ func GetFakeSSLCert() ([]byte, []byte) {
 	priv, err = rsa.GenerateKey(rand.Reader, 2048)
 
 	if err != nil {
-		glog.Fatalf("failed to generate fake private key: %s", err)
+		log.Fatalf("failed to generate fake private key: %s", err)
 	}
 
 	notBefore := time.Now()
@@ -443,7 +445,7 @@ func GetFakeSSLCert() ([]byte, []byte) {
 	serialNumber, err := rand.Int(rand.Reader, serialNumberLimit)
 
 	if err != nil {
-		glog.Fatalf("failed to generate fake serial number: %s", err)
+		log.Fatalf("failed to generate fake serial number: %s", err)
 	}
 
 	template := x509.Certificate{
@@ -462,7 +464,7 @@ func GetFakeSSLCert() ([]byte, []byte) {
 	}
 	derBytes, err := x509.CreateCertificate(rand.Reader, &template, &template, &priv.(*rsa.PrivateKey).PublicKey, priv)
 	if err != nil {
-		glog.Fatalf("Failed to create fake certificate: %s", err)
+		log.Fatalf("Failed to create fake certificate: %s", err)
 	}
 
 	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: derBytes})
@@ -496,7 +498,7 @@ func FullChainCert(in string, fs file.Filesystem) ([]byte, error) {
 		return nil, nil
 	}
 
-	certs, err := certUtil.FetchCertificateChain(cert)
+	certs, err := fetchCertificateChain(cert)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,55 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssl
+
+import (
+	"crypto/x509"
+	"time"
+)
+
+// clockSkewLeeway is the time a certificate can be used before the
+// beginning of its validity period. Certificates are usually used right
+// after they are issued, and the clocks of the nodes of the cluster and
+// the certificate authority can differ.
+var clockSkewLeeway = 5 * time.Minute
+
+// SetClockSkewLeeway configures the time a certificate is considered
+// valid before the beginning of its validity period
+func SetClockSkewLeeway(leeway time.Duration) {
+	clockSkewLeeway = leeway
+}
+
+// IsNotYetValid checks if the validity period of a certificate starts
+// in the future, after the clock skew leeway
+func IsNotYetValid(cert *x509.Certificate, now time.Time) bool {
+	if cert == nil {
+		return false
+	}
+
+	return cert.NotBefore.After(now.Add(clockSkewLeeway))
+}
+
+// verificationTime returns the time used to verify the chain of a
+// certificate. The beginning of the validity period is used when it is
+// in the future but inside the clock skew leeway.
+func verificationTime(cert *x509.Certificate, now time.Time) time.Time {
+	if cert.NotBefore.After(now) && !IsNotYetValid(cert, now) {
+		return cert.NotBefore
+	}
+
+	return now
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssl
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+)
+
+func TestCertificateValidity(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name            string
+		notBefore       time.Time
+		notYetValid     bool
+		verificationNow bool
+	}{
+		{"already valid", now.Add(-time.Hour), false, true},
+		{"valid inside the leeway", now.Add(time.Minute), false, false},
+		{"not yet valid", now.Add(time.Hour), true, true},
+	}
+
+	for _, test := range tests {
+		cert := &x509.Certificate{NotBefore: test.notBefore}
+
+		if IsNotYetValid(cert, now) != test.notYetValid {
+			t.Errorf("%v: expected not yet valid to be %v", test.name, test.notYetValid)
+		}
+
+		vt := verificationTime(cert, now)
+		if vt.Equal(now) != test.verificationNow {
+			t.Errorf("%v: unexpected verification time %v", test.name, vt)
+		}
+	}
+
+	if IsNotYetValid(nil, now) {
+		t.Errorf("expected a missing certificate to not be reported as not yet valid")
+	}
+}
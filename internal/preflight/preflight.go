@@ -0,0 +1,330 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package preflight checks the node and the container are able to run the
+// ingress controller before it starts serving traffic.
+package preflight
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	ing_net "k8s.io/ingress-nginx/internal/net"
+)
+
+// Status of the result of a check
+type Status string
+
+const (
+	// StatusOK indicates the check passed
+	StatusOK Status = "ok"
+	// StatusWarning indicates a setting that can degrade the service
+	StatusWarning Status = "warning"
+	// StatusBlocker indicates the controller cannot work
+	StatusBlocker Status = "blocker"
+)
+
+const (
+	defNginxBinary = "/usr/sbin/nginx"
+
+	// minimum number of file descriptors NGINX requires to start
+	minOpenFiles = 1024
+	// number of file descriptors recommended to handle many connections
+	recommendedOpenFiles = 65536
+	// the controller uses 511 as backlog for lower values
+	recommendedSomaxconn = 512
+	// number of ephemeral ports recommended for the connections to upstreams
+	recommendedLocalPorts = 10000
+)
+
+// requiredModules contains the modules the NGINX template depends on,
+// as they appear in the output of "nginx -V"
+var requiredModules = []string{
+	"http_ssl_module",
+	"http_v2_module",
+	"http_realip_module",
+	"with-stream",
+	"lua-nginx-module",
+}
+
+// Result of a check
+type Result struct {
+	Name    string `json:"name"`
+	Status  Status `json:"status"`
+	Message string `json:"message"`
+}
+
+// Report contains the results of all the checks
+type Report struct {
+	Results  []Result `json:"checks"`
+	Warnings int      `json:"warnings"`
+	Blockers int      `json:"blockers"`
+}
+
+// Config defines what is checked
+type Config struct {
+	// Ports contains the ports the controller listens on indexed by
+	// the name of the flag that configures each one
+	Ports map[string]int
+	// WritableDirs contains the directories the controller writes to
+	WritableDirs []string
+}
+
+// checker contains the functions used to inspect the system,
+// replaced in the tests
+type checker struct {
+	readFile        func(string) ([]byte, error)
+	openFilesLimit  func() (uint64, error)
+	nginxVersion    func() (string, error)
+	isPortAvailable func(int) bool
+	isWritable      func(string) error
+}
+
+// Run executes all the checks
+func Run(cfg Config) *Report {
+	c := &checker{
+		readFile:        ioutil.ReadFile,
+		openFilesLimit:  openFilesLimit,
+		nginxVersion:    nginxVersion,
+		isPortAvailable: ing_net.IsPortAvailable,
+		isWritable:      isWritable,
+	}
+
+	return c.run(cfg)
+}
+
+func (c *checker) run(cfg Config) *Report {
+	results := []Result{
+		c.checkNginx(),
+		c.checkOpenFiles(),
+		c.checkSomaxconn(),
+		c.checkLocalPortRange(),
+	}
+
+	flags := []string{}
+	for flag := range cfg.Ports {
+		flags = append(flags, flag)
+	}
+	sort.Strings(flags)
+
+	for _, flag := range flags {
+		results = append(results, c.checkPort(flag, cfg.Ports[flag]))
+	}
+
+	for _, dir := range cfg.WritableDirs {
+		results = append(results, c.checkWritable(dir))
+	}
+
+	report := &Report{Results: results}
+	for _, r := range results {
+		switch r.Status {
+		case StatusWarning:
+			report.Warnings++
+		case StatusBlocker:
+			report.Blockers++
+		}
+	}
+
+	return report
+}
+
+func (c *checker) checkNginx() Result {
+	r := Result{Name: "nginx-modules"}
+
+	out, err := c.nginxVersion()
+	if err != nil {
+		r.Status = StatusBlocker
+		r.Message = fmt.Sprintf("error running nginx -V: %v", err)
+		return r
+	}
+
+	missing := []string{}
+	for _, module := range requiredModules {
+		if !strings.Contains(out, module) {
+			missing = append(missing, module)
+		}
+	}
+
+	if len(missing) > 0 {
+		r.Status = StatusBlocker
+		r.Message = fmt.Sprintf("missing required modules: %v", strings.Join(missing, ", "))
+		return r
+	}
+
+	r.Status = StatusOK
+	r.Message = "all the required modules are present"
+	return r
+}
+
+func (c *checker) checkOpenFiles() Result {
+	r := Result{Name: "rlimit-nofile"}
+
+	limit, err := c.openFilesLimit()
+	if err != nil {
+		r.Status = StatusWarning
+		r.Message = fmt.Sprintf("error reading the maximum number of open files: %v", err)
+		return r
+	}
+
+	r.Message = fmt.Sprintf("maximum number of open files is %v", limit)
+	switch {
+	case limit < minOpenFiles:
+		r.Status = StatusBlocker
+		r.Message += fmt.Sprintf(", at least %v are required", minOpenFiles)
+	case limit < recommendedOpenFiles:
+		r.Status = StatusWarning
+		r.Message += fmt.Sprintf(", %v or more are recommended", recommendedOpenFiles)
+	default:
+		r.Status = StatusOK
+	}
+
+	return r
+}
+
+func (c *checker) checkSomaxconn() Result {
+	r := Result{Name: "sysctl-net.core.somaxconn"}
+
+	value, err := c.readSysctl("net/core/somaxconn")
+	if err != nil {
+		r.Status = StatusWarning
+		r.Message = err.Error()
+		return r
+	}
+
+	r.Message = fmt.Sprintf("net.core.somaxconn is %v", value)
+	if value < recommendedSomaxconn {
+		r.Status = StatusWarning
+		r.Message += fmt.Sprintf(", %v or more are recommended to avoid dropping connections", recommendedSomaxconn)
+		return r
+	}
+
+	r.Status = StatusOK
+	return r
+}
+
+func (c *checker) checkLocalPortRange() Result {
+	r := Result{Name: "sysctl-net.ipv4.ip_local_port_range"}
+
+	content, err := c.readFile("/proc/sys/net/ipv4/ip_local_port_range")
+	if err != nil {
+		r.Status = StatusWarning
+		r.Message = fmt.Sprintf("error reading net.ipv4.ip_local_port_range: %v", err)
+		return r
+	}
+
+	fields := strings.Fields(string(content))
+	if len(fields) != 2 {
+		r.Status = StatusWarning
+		r.Message = fmt.Sprintf("unexpected value of net.ipv4.ip_local_port_range: %q", string(content))
+		return r
+	}
+
+	low, errLow := strconv.Atoi(fields[0])
+	high, errHigh := strconv.Atoi(fields[1])
+	if errLow != nil || errHigh != nil {
+		r.Status = StatusWarning
+		r.Message = fmt.Sprintf("unexpected value of net.ipv4.ip_local_port_range: %q", string(content))
+		return r
+	}
+
+	r.Message = fmt.Sprintf("net.ipv4.ip_local_port_range is %v %v", low, high)
+	if high-low < recommendedLocalPorts {
+		r.Status = StatusWarning
+		r.Message += fmt.Sprintf(", a range of %v or more ports is recommended for the connections to the upstreams", recommendedLocalPorts)
+		return r
+	}
+
+	r.Status = StatusOK
+	return r
+}
+
+func (c *checker) checkPort(flag string, port int) Result {
+	r := Result{Name: fmt.Sprintf("port-%v", port)}
+
+	if !c.isPortAvailable(port) {
+		r.Status = StatusBlocker
+		r.Message = fmt.Sprintf("port %v is already in use, please check the flag --%v", port, flag)
+		return r
+	}
+
+	r.Status = StatusOK
+	r.Message = fmt.Sprintf("port %v (--%v) is available", port, flag)
+	return r
+}
+
+func (c *checker) checkWritable(dir string) Result {
+	r := Result{Name: fmt.Sprintf("writable-%v", dir)}
+
+	if err := c.isWritable(dir); err != nil {
+		r.Status = StatusBlocker
+		r.Message = fmt.Sprintf("directory %v is not writable: %v", dir, err)
+		return r
+	}
+
+	r.Status = StatusOK
+	r.Message = fmt.Sprintf("directory %v is writable", dir)
+	return r
+}
+
+func (c *checker) readSysctl(name string) (int, error) {
+	content, err := c.readFile("/proc/sys/" + name)
+	if err != nil {
+		return 0, fmt.Errorf("error reading %v: %v", strings.Replace(name, "/", ".", -1), err)
+	}
+
+	value, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		return 0, fmt.Errorf("unexpected value of %v: %v", strings.Replace(name, "/", ".", -1), err)
+	}
+
+	return value, nil
+}
+
+func openFilesLimit() (uint64, error) {
+	var rLimit syscall.Rlimit
+	err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rLimit)
+	if err != nil {
+		return 0, err
+	}
+
+	return rLimit.Max, nil
+}
+
+func nginxVersion() (string, error) {
+	ngx := os.Getenv("NGINX_BINARY")
+	if ngx == "" {
+		ngx = defNginxBinary
+	}
+
+	out, err := exec.Command(ngx, "-V").CombinedOutput()
+	return string(out), err
+}
+
+func isWritable(dir string) error {
+	f, err := ioutil.TempFile(dir, ".preflight")
+	if err != nil {
+		return err
+	}
+
+	f.Close()
+	return os.Remove(f.Name())
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preflight
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+const nginxV = `nginx version: openresty/1.13.6.2
+built with OpenSSL 1.1.0h  27 Mar 2018
+configure arguments: --with-http_ssl_module --with-http_v2_module --with-http_realip_module --with-stream --add-module=/tmp/build/lua-nginx-module`
+
+func newTestChecker() *checker {
+	return &checker{
+		readFile: func(path string) ([]byte, error) {
+			switch path {
+			case "/proc/sys/net/core/somaxconn":
+				return []byte("32768\n"), nil
+			case "/proc/sys/net/ipv4/ip_local_port_range":
+				return []byte("1024\t65000\n"), nil
+			}
+			return nil, fmt.Errorf("unexpected file %v", path)
+		},
+		openFilesLimit:  func() (uint64, error) { return 1048576, nil },
+		nginxVersion:    func() (string, error) { return nginxV, nil },
+		isPortAvailable: func(int) bool { return true },
+		isWritable:      func(string) error { return nil },
+	}
+}
+
+func findResult(report *Report, name string) *Result {
+	for _, r := range report.Results {
+		if r.Name == name {
+			return &r
+		}
+	}
+
+	return nil
+}
+
+func TestRunOK(t *testing.T) {
+	report := newTestChecker().run(Config{
+		Ports:        map[string]int{"http-port": 80, "https-port": 443},
+		WritableDirs: []string{"/etc/nginx"},
+	})
+
+	if report.Blockers != 0 || report.Warnings != 0 {
+		t.Errorf("expected no blockers or warnings but returned %+v", report)
+	}
+
+	if len(report.Results) != 7 {
+		t.Errorf("expected 7 checks but returned %v", len(report.Results))
+	}
+
+	if report.Results[4].Name != "port-80" || report.Results[5].Name != "port-443" {
+		t.Errorf("expected the ports to be sorted by flag but returned %+v", report.Results[4:6])
+	}
+}
+
+func TestRunProblems(t *testing.T) {
+	c := newTestChecker()
+	c.readFile = func(path string) ([]byte, error) {
+		if path == "/proc/sys/net/core/somaxconn" {
+			return []byte("128"), nil
+		}
+		return []byte("32768 33768"), nil
+	}
+	c.openFilesLimit = func() (uint64, error) { return 512, nil }
+	c.nginxVersion = func() (string, error) { return "--with-http_ssl_module --with-stream", nil }
+	c.isPortAvailable = func(port int) bool { return port != 443 }
+	c.isWritable = func(dir string) error { return fmt.Errorf("permission denied") }
+
+	report := c.run(Config{
+		Ports:        map[string]int{"http-port": 80, "https-port": 443},
+		WritableDirs: []string{"/etc/nginx"},
+	})
+
+	testCases := map[string]Status{
+		"nginx-modules":                       StatusBlocker,
+		"rlimit-nofile":                       StatusBlocker,
+		"sysctl-net.core.somaxconn":           StatusWarning,
+		"sysctl-net.ipv4.ip_local_port_range": StatusWarning,
+		"port-80":                             StatusOK,
+		"port-443":                            StatusBlocker,
+		"writable-/etc/nginx":                 StatusBlocker,
+	}
+
+	for name, expected := range testCases {
+		r := findResult(report, name)
+		if r == nil {
+			t.Errorf("expected a result for %v", name)
+			continue
+		}
+
+		if r.Status != expected {
+			t.Errorf("%v: expected status %v but returned %v (%v)", name, expected, r.Status, r.Message)
+		}
+	}
+
+	if report.Blockers != 4 || report.Warnings != 2 {
+		t.Errorf("expected 4 blockers and 2 warnings but returned %v and %v", report.Blockers, report.Warnings)
+	}
+
+	r := findResult(report, "nginx-modules")
+	if r.Message != "missing required modules: http_v2_module, http_realip_module, lua-nginx-module" {
+		t.Errorf("unexpected message: %v", r.Message)
+	}
+}
+
+func TestOpenFilesWarning(t *testing.T) {
+	c := newTestChecker()
+	c.openFilesLimit = func() (uint64, error) { return 4096, nil }
+
+	if r := c.checkOpenFiles(); r.Status != StatusWarning {
+		t.Errorf("expected a warning but returned %v", r.Status)
+	}
+}
+
+func TestIsWritable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "preflight")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := isWritable(dir); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 0 {
+		t.Errorf("expected the temporal file to be removed")
+	}
+
+	if err := isWritable(dir + "/missing"); err == nil {
+		t.Errorf("expected an error with a missing directory")
+	}
+}
@@ -20,12 +20,12 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/golang/glog"
-
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
+
+	"k8s.io/ingress-nginx/internal/log"
 )
 
 var (
@@ -47,13 +47,46 @@ type Queue struct {
 	fn func(obj interface{}) (interface{}, error)
 	// lastSync is the Unix epoch time of the last execution of 'sync'
 	lastSync int64
+	// observer receives the measures of the activity of the queue
+	observer Observer
+}
+
+// Observer receives the measures of the activity of a Queue
+type Observer interface {
+	// SetSyncQueueDepth sets the number of elements waiting in the queue
+	SetSyncQueueDepth(int)
+	// ObserveSyncQueueWait observes the time an element waited in the
+	// queue before its sync
+	ObserveSyncQueueWait(time.Duration)
+	// IncSyncQueueSkipped counts the elements not synced because a newer
+	// sync already happened
+	IncSyncQueueSkipped()
+	// IncSyncQueueDropped counts the elements that could not be queued
+	IncSyncQueueDropped()
 }
 
+type noopObserver struct{}
+
+func (noopObserver) SetSyncQueueDepth(int)              {}
+func (noopObserver) ObserveSyncQueueWait(time.Duration) {}
+func (noopObserver) IncSyncQueueSkipped()               {}
+func (noopObserver) IncSyncQueueDropped()               {}
+
 // Element represents one item of the queue
 type Element struct {
 	Key         interface{}
 	Timestamp   int64
 	IsSkippable bool
+	// Queued is the Unix epoch time when the element was added to the queue
+	Queued int64
+}
+
+// SetObserver sets the observer of the activity of the queue
+func (t *Queue) SetObserver(o Observer) {
+	if o == nil {
+		o = noopObserver{}
+	}
+	t.observer = o
 }
 
 // Run starts processing elements in the queue
@@ -75,7 +108,8 @@ func (t *Queue) EnqueueSkippableTask(obj interface{}) {
 // enqueue enqueues ns/name of the given api object in the task queue.
 func (t *Queue) enqueue(obj interface{}, skippable bool) {
 	if t.IsShuttingDown() {
-		glog.Errorf("queue has been shutdown, failed to enqueue: %v", obj)
+		log.Errorf("queue has been shutdown, failed to enqueue: %v", obj)
+		t.observer.IncSyncQueueDropped()
 		return
 	}
 
@@ -84,16 +118,19 @@ func (t *Queue) enqueue(obj interface{}, skippable bool) {
 		// make sure the timestamp is bigger than lastSync
 		ts = time.Now().Add(24 * time.Hour).UnixNano()
 	}
-	glog.V(3).Infof("queuing item %v", obj)
+	log.V(3).Infof("queuing item %v", obj)
 	key, err := t.fn(obj)
 	if err != nil {
-		glog.Errorf("%v", err)
+		log.Errorf("%v", err)
+		t.observer.IncSyncQueueDropped()
 		return
 	}
 	t.queue.Add(Element{
 		Key:       key,
 		Timestamp: ts,
+		Queued:    time.Now().UnixNano(),
 	})
+	t.observer.SetSyncQueueDepth(t.queue.Len())
 }
 
 func (t *Queue) defaultKeyFunc(obj interface{}) (interface{}, error) {
@@ -116,21 +153,28 @@ func (t *Queue) worker() {
 			return
 		}
 		ts := time.Now().UnixNano()
+		t.observer.SetSyncQueueDepth(t.queue.Len())
 
 		item := key.(Element)
 		if t.lastSync > item.Timestamp {
-			glog.V(3).Infof("skipping %v sync (%v > %v)", item.Key, t.lastSync, item.Timestamp)
+			log.V(3).Infof("skipping %v sync (%v > %v)", item.Key, t.lastSync, item.Timestamp)
+			t.observer.IncSyncQueueSkipped()
 			t.queue.Forget(key)
 			t.queue.Done(key)
 			continue
 		}
 
-		glog.V(3).Infof("syncing %v", item.Key)
+		if item.Queued > 0 {
+			t.observer.ObserveSyncQueueWait(time.Duration(ts - item.Queued))
+		}
+
+		log.V(3).Infof("syncing %v", item.Key)
 		if err := t.sync(key); err != nil {
-			glog.Warningf("requeuing %v, err %v", item.Key, err)
+			log.Warningf("requeuing %v, err %v", item.Key, err)
 			t.queue.AddRateLimited(Element{
 				Key:       item.Key,
 				Timestamp: time.Now().UnixNano(),
+				Queued:    time.Now().UnixNano(),
 			})
 		} else {
 			t.queue.Forget(key)
@@ -175,6 +219,7 @@ func NewCustomTaskQueue(syncFn func(interface{}) error, fn func(interface{}) (in
 		sync:       syncFn,
 		workerDone: make(chan bool),
 		fn:         fn,
+		observer:   noopObserver{},
 	}
 
 	if fn == nil {
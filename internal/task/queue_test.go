@@ -157,3 +157,62 @@ func TestSkipEnqueue(t *testing.T) {
 	// shutdown queue before exit
 	q.Shutdown()
 }
+
+type mockObserver struct {
+	depth   int32
+	waits   int32
+	skipped int32
+	dropped int32
+}
+
+func (o *mockObserver) SetSyncQueueDepth(depth int) {
+	atomic.StoreInt32(&o.depth, int32(depth))
+}
+
+func (o *mockObserver) ObserveSyncQueueWait(time.Duration) {
+	atomic.AddInt32(&o.waits, 1)
+}
+
+func (o *mockObserver) IncSyncQueueSkipped() {
+	atomic.AddInt32(&o.skipped, 1)
+}
+
+func (o *mockObserver) IncSyncQueueDropped() {
+	atomic.AddInt32(&o.dropped, 1)
+}
+
+func TestObserver(t *testing.T) {
+	o := &mockObserver{}
+	q := NewCustomTaskQueue(mockSynFn, mockKeyFn)
+	q.SetObserver(o)
+	stopCh := make(chan struct{})
+	mo := mockEnqueueObj{
+		k: "testKey",
+		v: "testValue",
+	}
+	q.EnqueueTask(mo)
+	q.EnqueueSkippableTask(mo)
+	if atomic.LoadInt32(&o.depth) != 2 {
+		t.Errorf("depth should be 2, but is %d", o.depth)
+	}
+
+	// run queue
+	go q.Run(time.Second, stopCh)
+	// wait for 'mockSynFn'
+	time.Sleep(time.Millisecond * 10)
+	if atomic.LoadInt32(&o.depth) != 0 {
+		t.Errorf("depth should be 0, but is %d", o.depth)
+	}
+	if atomic.LoadInt32(&o.waits) != 1 {
+		t.Errorf("waits should be 1, but is %d", o.waits)
+	}
+	if atomic.LoadInt32(&o.skipped) != 1 {
+		t.Errorf("skipped should be 1, but is %d", o.skipped)
+	}
+
+	q.Shutdown()
+	q.EnqueueTask(mo)
+	if atomic.LoadInt32(&o.dropped) != 1 {
+		t.Errorf("dropped should be 1, but is %d", o.dropped)
+	}
+}
@@ -13,6 +13,15 @@ local ewma = require("balancer.ewma")
 -- it will take <the delay until controller POSTed the backend object to the Nginx endpoint> + BACKENDS_SYNC_INTERVAL
 local BACKENDS_SYNC_INTERVAL = 1
 
+-- measured in seconds
+-- length of the period in which the retries to a backend are compared
+-- with its requests to enforce the next upstream budget
+local NEXT_UPSTREAM_BUDGET_WINDOW = 10
+
+-- total of the weights of the traffic shaping policies without weightTotal,
+-- so the weight is a percentage of the requests
+local DEFAULT_WEIGHT_TOTAL = 100
+
 local DEFAULT_LB_ALG = "round_robin"
 local IMPLEMENTATIONS = {
   round_robin = round_robin,
@@ -23,6 +32,8 @@ local IMPLEMENTATIONS = {
 
 local _M = {}
 local balancers = {}
+local next_upstream_budgets = {}
+local next_upstream_counters = {}
 
 local function get_implementation(backend)
   local name = backend["load-balance"] or DEFAULT_LB_ALG
@@ -71,6 +82,15 @@ local function sync_backend(backend)
   local implementation = get_implementation(backend)
   local balancer = balancers[backend.name]
 
+  local service_type = backend.service and backend.service.spec and backend.service.spec["type"]
+  if service_type == "ExternalName" then
+    backend = resolve_external_names(backend)
+  end
+
+  backend.endpoints = format_ipv6_endpoints(backend.endpoints)
+
+  next_upstream_budgets[backend.name] = backend["next-upstream-budget"]
+
   if not balancer then
     balancers[backend.name] = implementation:new(backend)
     return
@@ -86,13 +106,6 @@ local function sync_backend(backend)
     return
   end
 
-  local service_type = backend.service and backend.service.spec and backend.service.spec["type"]
-  if service_type == "ExternalName" then
-    backend = resolve_external_names(backend)
-  end
-
-  backend.endpoints = format_ipv6_endpoints(backend.endpoints)
-
   balancer:sync(backend)
 end
 
@@ -100,6 +113,8 @@ local function sync_backends()
   local backends_data = configuration.get_backends_data()
   if not backends_data then
     balancers = {}
+    next_upstream_budgets = {}
+    next_upstream_counters = {}
     return
   end
 
@@ -118,21 +133,54 @@ local function sync_backends()
   for backend_name, _ in pairs(balancers) do
     if not balancers_to_keep[backend_name] then
       balancers[backend_name] = nil
+      next_upstream_budgets[backend_name] = nil
+      next_upstream_counters[backend_name] = nil
     end
   end
 end
 
-local function route_to_alternative_balancer(balancer)
-  if not balancer.alternative_backends then
-    return false
+-- returns the percentage of the requests routed to a backend by the
+-- weight of its traffic shaping policy, relative to the total weight
+local function weight_percentage(traffic_shaping_policy)
+  local weight = traffic_shaping_policy.weight or 0
+  if weight <= 0 then
+    return 0
   end
 
-  -- TODO: support traffic shaping for n > 1 alternative backends
-  local alternative_balancer = balancers[balancer.alternative_backends[1]]
+  local total = traffic_shaping_policy.weightTotal
+  if not total or total <= 0 then
+    total = DEFAULT_WEIGHT_TOTAL
+  end
 
-  local clean_target_header = util.replace_special_char(alternative_balancer.traffic_shaping_policy.header, "-", "_")
+  return weight * 100 / total
+end
+
+-- returns whether the header or cookie of the traffic shaping policy routes
+-- the request to the backend, nil when it depends on the weight
+local function matches_traffic_shaping_policy(traffic_shaping_policy)
+  local clean_target_header = util.replace_special_char(traffic_shaping_policy.header, "-", "_")
 
   local header = ngx.var["http_" .. clean_target_header]
+
+  -- alternative backends defined using the header-routing-map annotation
+  if not util.is_blank(traffic_shaping_policy.headerValue) then
+    return header == traffic_shaping_policy.headerValue
+  end
+
+  if not util.is_blank(traffic_shaping_policy.headerPattern) then
+    if not header then
+      return false
+    end
+
+    local from, _, err = ngx.re.find(header, traffic_shaping_policy.headerPattern, "jo")
+    if err then
+      ngx.log(ngx.ERR, string.format("error matching header pattern %s: %s",
+        traffic_shaping_policy.headerPattern, tostring(err)))
+    end
+
+    return from ~= nil
+  end
+
   if header then
     if header == "always" then
       return true
@@ -141,7 +189,7 @@ local function route_to_alternative_balancer(balancer)
     end
   end
 
-  local clean_target_cookie = util.replace_special_char(alternative_balancer.traffic_shaping_policy.cookie, "-", "_")
+  local clean_target_cookie = util.replace_special_char(traffic_shaping_policy.cookie, "-", "_")
 
   local cookie = ngx.var["cookie_" .. clean_target_cookie]
   if cookie then
@@ -152,14 +200,119 @@ local function route_to_alternative_balancer(balancer)
     end
   end
 
-  if math.random(100) <= alternative_balancer.traffic_shaping_policy.weight then
+  return nil
+end
+
+-- returns whether the traffic shaping policy applies to the server of the
+-- request, the policies of the routing overrides are restricted to a host
+local function matches_host(traffic_shaping_policy)
+  if util.is_blank(traffic_shaping_policy.host) then
     return true
   end
 
-  return false
+  return ngx.var.server_name == traffic_shaping_policy.host
 end
 
-local function get_balancer()
+-- returns the first alternative balancer whose header or cookie matches
+-- the request or else one of the alternative balancers chosen by weight,
+-- if any, and the name of its backend. A single random draw is shared by
+-- all the weighted alternatives so each one receives its own percentage of
+-- the requests. Only the shadow alternative balancers are considered when
+-- shadow is true, and only the other ones otherwise.
+local function route_to_alternative_balancer(balancer, shadow)
+  if not balancer.alternative_backends then
+    return nil
+  end
+
+  local weighted = {}
+  for _, backend_name in ipairs(balancer.alternative_backends) do
+    local alternative_balancer = balancers[backend_name]
+    local policy = alternative_balancer and alternative_balancer.traffic_shaping_policy
+    if policy and (policy.shadow or false) == (shadow or false) and matches_host(policy) then
+      local matches = matches_traffic_shaping_policy(policy)
+      if matches then
+        return alternative_balancer, backend_name
+      elseif matches == nil then
+        table.insert(weighted, backend_name)
+      end
+    end
+  end
+
+  local draw = math.random() * 100
+  local upper = 0
+  for _, backend_name in ipairs(weighted) do
+    local alternative_balancer = balancers[backend_name]
+    local percentage = weight_percentage(alternative_balancer.traffic_shaping_policy)
+    upper = upper + percentage
+    if percentage > 0 and draw < upper then
+      return alternative_balancer, backend_name
+    end
+  end
+
+  return nil
+end
+
+-- returns the balancer of the backend configured for the value
+-- of the query parameter defined in the query routing policy
+-- of the Ingress of the location serving the request
+local function route_by_query_parameter(balancer)
+  local policies = balancer.query_routing_policies
+  if not policies then
+    return nil
+  end
+
+  local ingress_key = (ngx.var.namespace or "") .. "/" .. (ngx.var.ingress_name or "")
+  local policy = policies[ingress_key]
+  if not policy or util.is_blank(policy.param) or not policy.backends then
+    return nil
+  end
+
+  local value = ngx.var["arg_" .. policy.param]
+  if not value then
+    return nil
+  end
+
+  local backend_name = policy.backends[value]
+  if not backend_name then
+    return nil
+  end
+
+  return balancers[backend_name]
+end
+
+-- returns the requests and retries to a backend in the current window
+local function get_next_upstream_counter(backend_name)
+  local now = ngx.now()
+  local counter = next_upstream_counters[backend_name]
+  if not counter or now - counter.window_start >= NEXT_UPSTREAM_BUDGET_WINDOW then
+    counter = { window_start = now, requests = 0, retries = 0 }
+    next_upstream_counters[backend_name] = counter
+  end
+
+  return counter
+end
+
+-- counts an attempt to send a request to a backend and returns whether
+-- the request can be passed to the next endpoint if the attempt fails,
+-- which happens while the retries stay below the percentage of the
+-- requests configured in the next upstream budget of the backend
+local function next_upstream_allowed(backend_name, is_retry)
+  local budget = next_upstream_budgets[backend_name]
+  if not budget or budget <= 0 then
+    return true
+  end
+
+  local counter = get_next_upstream_counter(backend_name)
+  if is_retry then
+    counter.retries = counter.retries + 1
+  else
+    counter.requests = counter.requests + 1
+  end
+
+  return counter.retries < counter.requests * budget / 100
+end
+
+local function select_balancer()
   local backend_name = ngx.var.proxy_upstream_name
 
   local balancer = balancers[backend_name]
@@ -167,14 +320,62 @@ local function get_balancer()
     return
   end
 
-  if route_to_alternative_balancer(balancer) then
-    local alternative_balancer = balancers[balancer.alternative_backends[1]]
-    return alternative_balancer
+  local query_balancer = route_by_query_parameter(balancer)
+  if query_balancer then
+    return query_balancer
+  end
+
+  local alternative_balancer, alternative_name = route_to_alternative_balancer(balancer)
+  if alternative_balancer then
+    return alternative_balancer, alternative_name
   end
 
   return balancer
 end
 
+-- returns the balancer selected for the request, the same in all the
+-- phases even when the traffic shaping policy of a canary is random
+-- and the name of the alternative backend, if any, in ngx.ctx
+local function get_balancer()
+  local balancer = ngx.ctx.balancer
+  if not balancer then
+    balancer, ngx.ctx.alternative_backend = select_balancer()
+    ngx.ctx.balancer = balancer
+  end
+
+  return balancer
+end
+
+-- returns the Host header of a balancer selected by its traffic shaping
+-- policy, nil to keep the upstream vhost of the location
+local function get_upstream_vhost(balancer)
+  local policy = balancer.traffic_shaping_policy
+  if not policy or util.is_blank(policy.upstreamVhost) then
+    return nil
+  end
+
+  return policy.upstreamVhost
+end
+
+-- returns the server name sent with SNI to the backend of a balancer, empty
+-- when SNI is disabled, nil when the backend does not configure it
+local function get_ssl_server_name(balancer)
+  local ssl_server_name = balancer.ssl_server_name
+  if not ssl_server_name then
+    return nil
+  end
+
+  if not ssl_server_name.enabled then
+    return ""
+  end
+
+  if util.is_blank(ssl_server_name.name) then
+    return ngx.var.proxy_upstream_vhost
+  end
+
+  return ssl_server_name.name
+end
+
 function _M.init_worker()
   sync_backends() -- when worker starts, sync backends without delay
   local _, err = ngx.timer.every(BACKENDS_SYNC_INTERVAL, sync_backends)
@@ -189,6 +390,46 @@ function _M.rewrite()
     ngx.status = ngx.HTTP_SERVICE_UNAVAILABLE
     return ngx.exit(ngx.status)
   end
+
+  -- reported in the metrics of the request
+  if ngx.ctx.alternative_backend then
+    ngx.var.proxy_alternative_upstream_name = ngx.ctx.alternative_backend
+  end
+
+  local upstream_vhost = get_upstream_vhost(balancer)
+  if upstream_vhost then
+    ngx.var.proxy_upstream_vhost = upstream_vhost
+  end
+
+  -- only defined in the locations sending a server name with SNI
+  if ngx.var.proxy_ssl_server_name then
+    local ssl_server_name = get_ssl_server_name(balancer)
+    if ssl_server_name then
+      ngx.var.proxy_ssl_server_name = ssl_server_name
+    end
+  end
+end
+
+-- selects the shadow backend of the copy of a request sent by the
+-- mirror of NGINX, or discards the copy when no shadow backend matches
+function _M.shadow()
+  local balancer = balancers[ngx.var.proxy_upstream_name]
+  local shadow_balancer
+  if balancer then
+    shadow_balancer = route_to_alternative_balancer(balancer, true)
+  end
+
+  if not shadow_balancer then
+    return ngx.exit(ngx.HTTP_NO_CONTENT)
+  end
+
+  ngx.ctx.balancer = shadow_balancer
+  ngx.ctx.shadow = true
+
+  local upstream_vhost = get_upstream_vhost(shadow_balancer)
+  if upstream_vhost then
+    ngx.var.proxy_shadow_vhost = upstream_vhost
+  end
 end
 
 function _M.balance()
@@ -203,7 +444,11 @@ function _M.balance()
     return
   end
 
-  ngx_balancer.set_more_tries(1)
+  -- the copies of the requests sent to a shadow backend are not retried
+  local is_retry = ngx_balancer.get_last_failure() ~= nil
+  if not ngx.ctx.shadow and next_upstream_allowed(ngx.var.proxy_upstream_name, is_retry) then
+    ngx_balancer.set_more_tries(1)
+  end
 
   local ok, err = ngx_balancer.set_current_peer(peer)
   if not ok then
@@ -227,6 +472,12 @@ end
 if _TEST then
   _M.get_implementation = get_implementation
   _M.sync_backend = sync_backend
+  _M.route_by_query_parameter = route_by_query_parameter
+  _M.route_to_alternative_balancer = route_to_alternative_balancer
+  _M.get_upstream_vhost = get_upstream_vhost
+  _M.get_ssl_server_name = get_ssl_server_name
+  _M.weight_percentage = weight_percentage
+  _M.next_upstream_allowed = next_upstream_allowed
 end
 
 return _M
@@ -45,23 +45,57 @@ end
 local function score(upstream)
   -- Original implementation used names
   -- Endpoints don't have names, so passing in IP:Port as key instead
-  local upstream_name = upstream.address .. ":" .. upstream.port
+  local upstream_name = util.endpoint_string(upstream)
   return get_or_update_ewma(upstream_name, 0, false)
 end
 
 -- implementation similar to https://en.wikipedia.org/wiki/Fisher%E2%80%93Yates_shuffle
 -- or https://en.wikipedia.org/wiki/Random_permutation
 -- loop from 1 .. k
--- pick a random value r from the remaining set of unpicked values (i .. n)
+-- pick a random value r from the remaining set of unpicked values (i .. n),
+-- with a probability proportional to the weight of the peer
 -- swap the value at position i with the value at position r
 local function shuffle_peers(peers, k)
   for i=1, k do
+    local total = 0
+    for j=i, #peers do
+      total = total + util.get_weight(peers[j])
+    end
+
     local rand_index = math.random(i,#peers)
+    if total > 0 then
+      local r = math.random() * total
+      for j=i, #peers do
+        r = r - util.get_weight(peers[j])
+        if r < 0 then
+          rand_index = j
+          break
+        end
+      end
+    end
+
     peers[i], peers[rand_index] = peers[rand_index], peers[i]
   end
   -- peers[1 .. k] will now contain a randomly selected k from #peers
 end
 
+-- returns the peers with a weight greater than 0
+-- or all of them if every peer has weight 0
+local function active_peers(endpoints)
+  local peers = {}
+  for _, endpoint in ipairs(endpoints) do
+    if util.get_weight(endpoint) > 0 then
+      table.insert(peers, endpoint)
+    end
+  end
+
+  if #peers == 0 then
+    return endpoints
+  end
+
+  return peers
+end
+
 local function pick_and_score(peers, k)
   shuffle_peers(peers, k)
   local lowest_score_index = 1
@@ -86,7 +120,7 @@ function _M.balance(self)
   end
 
   -- TODO(elvinefendi) move this processing to _M.sync
-  return endpoint.address .. ":" .. endpoint.port
+  return util.endpoint_string(endpoint)
 end
 
 function _M.after_balance(_)
@@ -104,13 +138,15 @@ end
 function _M.sync(self, backend)
   self.traffic_shaping_policy = backend.trafficShapingPolicy
   self.alternative_backends = backend.alternativeBackends
+  self.query_routing_policies = backend.queryRoutingPolicies
+  self.ssl_server_name = backend.sslServerName
 
-  local changed = not util.deep_compare(self.peers, backend.endpoints)
+  local changed = not util.deep_compare(self.peers, active_peers(backend.endpoints))
   if not changed then
     return
   end
 
-  self.peers = backend.endpoints
+  self.peers = active_peers(backend.endpoints)
 
   -- TODO: Reset state of EWMA per backend
   balancer_ewma = {}
@@ -119,7 +155,7 @@ end
 
 function _M.new(self, backend)
   local o = {
-    peers = backend.endpoints,
+    peers = active_peers(backend.endpoints),
   }
   setmetatable(o, self)
   self.__index = self
@@ -12,6 +12,8 @@ end
 function _M.sync(self, backend)
   self.traffic_shaping_policy = backend.trafficShapingPolicy
   self.alternative_backends = backend.alternativeBackends
+  self.query_routing_policies = backend.queryRoutingPolicies
+  self.ssl_server_name = backend.sslServerName
 
   local nodes = util.get_nodes(backend.endpoints)
   local changed = not util.deep_compare(self.instance.nodes, nodes)
@@ -5,6 +5,21 @@ local ck = require("resty.cookie")
 
 local _M = balancer_resty:new({ factory = resty_chash, name = "sticky" })
 
+-- number of cookies generated for a new session to avoid draining endpoints
+local MAX_NEW_SESSION_ATTEMPTS = 10
+
+-- returns the draining endpoints indexed by address:port
+local function get_draining(endpoints)
+  local draining = {}
+  for _, endpoint in pairs(endpoints) do
+    if endpoint.draining then
+      draining[util.endpoint_string(endpoint)] = true
+    end
+  end
+
+  return draining
+end
+
 function _M.new(self, backend)
   local nodes = util.get_nodes(backend.endpoints)
   local digest_func = util.md5_digest
@@ -16,6 +31,7 @@ function _M.new(self, backend)
     instance = self.factory:new(nodes),
     cookie_name = backend["sessionAffinityConfig"]["cookieSessionAffinity"]["name"] or "route",
     digest_func = digest_func,
+    draining = get_draining(backend.endpoints),
   }
   setmetatable(o, self)
   self.__index = self
@@ -58,13 +74,32 @@ function _M.balance(self)
   end
 
   local key = cookie:get(self.cookie_name)
-  if not key then
+  if key then
+    return self.instance:find(key)
+  end
+
+  -- new sessions are not assigned to draining endpoints
+  local endpoint
+  for attempt = 1, MAX_NEW_SESSION_ATTEMPTS do
     local random_str = string.format("%s.%s", ngx.now(), ngx.worker.pid())
+    if attempt > 1 then
+      random_str = string.format("%s.%s", random_str, attempt)
+    end
+
     key = encrypted_endpoint_string(self, random_str)
-    set_cookie(self, key)
+    endpoint = self.instance:find(key)
+    if not self.draining[endpoint] then
+      break
+    end
   end
 
-  return self.instance:find(key)
+  set_cookie(self, key)
+  return endpoint
+end
+
+function _M.sync(self, backend)
+  self.draining = get_draining(backend.endpoints)
+  balancer_resty.sync(self, backend)
 end
 
 return _M
@@ -0,0 +1,49 @@
+-- removes entries from the cache zones defined in the proxy-cache-zones
+-- configmap key. The cache uses levels=1:2 so the file that contains the
+-- response is located in <path>/<zone>/<last char of md5>/<previous two chars>/<md5>
+
+local _M = {}
+
+local function cache_file(path, zone, key)
+  local hash = ngx.md5(key)
+  return string.format("%s/%s/%s/%s/%s", path, zone, hash:sub(-1), hash:sub(-3, -2), hash)
+end
+
+function _M.call(path)
+  if ngx.var.request_method ~= "DELETE" then
+    ngx.status = ngx.HTTP_BAD_REQUEST
+    ngx.print("Only DELETE requests are allowed!")
+    return
+  end
+
+  local args = ngx.req.get_uri_args()
+  local zone = args.zone
+  local key = args.key
+
+  if type(zone) ~= "string" or type(key) ~= "string" then
+    ngx.status = ngx.HTTP_BAD_REQUEST
+    ngx.print("zone and key arguments are required")
+    return
+  end
+
+  if not zone:match("^[%w_%-]+$") then
+    ngx.status = ngx.HTTP_BAD_REQUEST
+    ngx.print("invalid zone name")
+    return
+  end
+
+  local ok, err = os.remove(cache_file(path, zone, key))
+  if not ok then
+    ngx.log(ngx.INFO, "cache-purge: unable to remove entry: " .. tostring(err))
+    ngx.status = ngx.HTTP_NOT_FOUND
+    return
+  end
+
+  ngx.status = ngx.HTTP_OK
+end
+
+if _TEST then
+  _M.cache_file = cache_file
+end
+
+return _M
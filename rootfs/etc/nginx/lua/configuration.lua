@@ -12,6 +12,33 @@ function _M.get_backends_data()
   return configuration_data:get("backends")
 end
 
+-- decompresses a body compressed with gzip by the controller
+local function gunzip(data)
+  local zlib = require("ffi-zlib")
+
+  local chunks = {}
+  local position = 1
+  local input = function(size)
+    if position > #data then
+      return nil
+    end
+
+    local chunk = data:sub(position, position + size - 1)
+    position = position + size
+    return chunk
+  end
+  local output = function(chunk)
+    table.insert(chunks, chunk)
+  end
+
+  local ok, err = zlib.inflateGzip(input, output, 16384)
+  if not ok then
+    return nil, err
+  end
+
+  return table.concat(chunks)
+end
+
 local function fetch_request_body()
   ngx.req.read_body()
   local body = ngx.req.get_body_data()
@@ -29,9 +56,50 @@ local function fetch_request_body()
     file:close()
   end
 
+  if body and ngx.var.http_content_encoding == "gzip" then
+    local err
+    body, err = gunzip(body)
+    if not body then
+      ngx.log(ngx.ERR, "could not decompress request body: " .. tostring(err))
+    end
+  end
+
   return body
 end
 
+-- returns the MD5 checksum of the last backends posted by the controller
+function _M.get_backends_checksum()
+  return configuration_data:get("backends_checksum")
+end
+
+function _M.get_stale_reasons()
+  return configuration_data:get("stale")
+end
+
+-- adds the header with the reasons why the configuration is stale, if any
+function _M.set_stale_header(name)
+  local reasons = _M.get_stale_reasons()
+  if reasons and reasons ~= "" then
+    ngx.header[name] = reasons
+  end
+end
+
+-- returns the JSON encoded list of networks blocked in all the servers
+function _M.get_denylist()
+  return configuration_data:get("denylist")
+end
+
+-- returns the JSON encoded map of hosts redirected to other hosts
+function _M.get_host_redirects()
+  return configuration_data:get("host_redirects")
+end
+
+-- returns the JSON encoded map of the credentials of the OpenID Connect
+-- clients, indexed by secret
+function _M.get_oidc_credentials()
+  return configuration_data:get("oidc_credentials")
+end
+
 function _M.get_pem_cert_key(hostname)
   return certificate_data:get(hostname)
 end
@@ -81,6 +149,241 @@ local function handle_servers()
   ngx.status = ngx.HTTP_CREATED
 end
 
+local function handle_checksum()
+  if ngx.var.request_method ~= "GET" then
+    ngx.status = ngx.HTTP_BAD_REQUEST
+    ngx.print("Only GET requests are allowed!")
+    return
+  end
+
+  ngx.status = ngx.HTTP_OK
+  ngx.print(_M.get_backends_checksum() or "")
+end
+
+local function handle_websockets()
+  if ngx.var.request_method ~= "GET" then
+    ngx.status = ngx.HTTP_BAD_REQUEST
+    ngx.print("Only GET requests are allowed!")
+    return
+  end
+
+  ngx.status = ngx.HTTP_OK
+  ngx.print(require("websocket").get_active())
+end
+
+local function handle_stale()
+  if ngx.var.request_method ~= "POST" then
+    ngx.status = ngx.HTTP_BAD_REQUEST
+    ngx.print("Only POST requests are allowed!")
+    return
+  end
+
+  local ok, reasons = pcall(json.decode, fetch_request_body())
+  if not ok or type(reasons) ~= "table" then
+    ngx.log(ngx.ERR, "could not parse stale reasons: " .. tostring(reasons))
+    ngx.status = ngx.HTTP_BAD_REQUEST
+    return
+  end
+
+  local success, err = configuration_data:set("stale", table.concat(reasons, ","))
+  if not success then
+    ngx.log(ngx.ERR, "error updating stale reasons: " .. tostring(err))
+    ngx.status = ngx.HTTP_INTERNAL_SERVER_ERROR
+    return
+  end
+
+  ngx.status = ngx.HTTP_CREATED
+end
+
+-- returns an error if a network of the denylist is not a valid IPv4
+-- or IPv6 address or network
+local function validate_denylist(networks)
+  local iputils = require("resty.iputils")
+  local ipv6 = require("util.ipv6")
+
+  for _, network in ipairs(networks) do
+    if type(network) ~= "string" then
+      return "invalid network " .. tostring(network)
+    end
+
+    local valid, err
+    if network:find(":", 1, true) then
+      valid, err = ipv6.parse_cidr(network)
+    else
+      valid, err = iputils.parse_cidr(network)
+    end
+
+    if not valid then
+      return "invalid network " .. network .. ": " .. tostring(err)
+    end
+  end
+
+  return nil
+end
+
+local function handle_denylist()
+  if ngx.var.request_method == "GET" then
+    local denylist = _M.get_denylist()
+    if not denylist then
+      ngx.status = ngx.HTTP_NOT_FOUND
+      return
+    end
+
+    ngx.status = ngx.HTTP_OK
+    ngx.print(denylist)
+    return
+  end
+
+  local denylist = fetch_request_body()
+
+  local ok, networks = pcall(json.decode, denylist)
+  if not ok or type(networks) ~= "table" then
+    ngx.log(ngx.ERR, "could not parse denylist: " .. tostring(networks))
+    ngx.status = ngx.HTTP_BAD_REQUEST
+    return
+  end
+
+  local err = validate_denylist(networks)
+  if err then
+    ngx.log(ngx.ERR, "rejecting denylist: " .. err)
+    ngx.status = ngx.HTTP_BAD_REQUEST
+    ngx.print(err)
+    return
+  end
+
+  local success, err = configuration_data:set("denylist", denylist)
+  if not success then
+    ngx.log(ngx.ERR, "error updating denylist: " .. tostring(err))
+    ngx.status = ngx.HTTP_INTERNAL_SERVER_ERROR
+    return
+  end
+
+  ngx.status = ngx.HTTP_CREATED
+end
+
+local function handle_host_redirects()
+  if ngx.var.request_method ~= "POST" then
+    ngx.status = ngx.HTTP_BAD_REQUEST
+    ngx.print("Only POST requests are allowed!")
+    return
+  end
+
+  local host_redirects = fetch_request_body()
+
+  local ok, redirects = pcall(json.decode, host_redirects)
+  if not ok or type(redirects) ~= "table" then
+    ngx.log(ngx.ERR, "could not parse host redirects: " .. tostring(redirects))
+    ngx.status = ngx.HTTP_BAD_REQUEST
+    return
+  end
+
+  local success, err = configuration_data:set("host_redirects", host_redirects)
+  if not success then
+    ngx.log(ngx.ERR, "error updating host redirects: " .. tostring(err))
+    ngx.status = ngx.HTTP_INTERNAL_SERVER_ERROR
+    return
+  end
+
+  ngx.status = ngx.HTTP_CREATED
+end
+
+local function handle_oidc()
+  if ngx.var.request_method ~= "POST" then
+    ngx.status = ngx.HTTP_BAD_REQUEST
+    ngx.print("Only POST requests are allowed!")
+    return
+  end
+
+  local oidc_credentials = fetch_request_body()
+
+  local ok, credentials = pcall(json.decode, oidc_credentials)
+  if not ok or type(credentials) ~= "table" then
+    -- the body contains secrets, it is never logged
+    ngx.log(ngx.ERR, "could not parse OpenID Connect credentials")
+    ngx.status = ngx.HTTP_BAD_REQUEST
+    return
+  end
+
+  local success, err = configuration_data:set("oidc_credentials", oidc_credentials)
+  if not success then
+    ngx.log(ngx.ERR, "error updating OpenID Connect credentials: " .. tostring(err))
+    ngx.status = ngx.HTTP_INTERNAL_SERVER_ERROR
+    return
+  end
+
+  ngx.status = ngx.HTTP_CREATED
+end
+
+-- applies the backends added, changed and removed since the last POST of the
+-- controller. The delta is rejected with 409 when the stored backends are not
+-- the ones it is based on, like after a restart of NGINX, and the controller
+-- posts all the backends again.
+local function handle_backends_delta()
+  if ngx.var.request_method ~= "POST" then
+    ngx.status = ngx.HTTP_BAD_REQUEST
+    ngx.print("Only POST requests are allowed!")
+    return
+  end
+
+  local ok, delta = pcall(json.decode, fetch_request_body())
+  if not ok or type(delta) ~= "table" or not delta.checksum then
+    ngx.log(ngx.ERR, "could not parse backends delta: " .. tostring(delta))
+    ngx.status = ngx.HTTP_BAD_REQUEST
+    return
+  end
+
+  local backends_data = _M.get_backends_data()
+  if not backends_data or _M.get_backends_checksum() ~= delta.base then
+    ngx.status = ngx.HTTP_CONFLICT
+    ngx.print("The backends changed, all the backends must be posted")
+    return
+  end
+
+  local backends
+  ok, backends = pcall(json.decode, backends_data)
+  if not ok then
+    ngx.log(ngx.ERR, "could not parse backends data: " .. tostring(backends))
+    ngx.status = ngx.HTTP_CONFLICT
+    return
+  end
+
+  local changed = {}
+  for _, name in ipairs(delta.remove or {}) do
+    changed[name] = true
+  end
+  for _, backend in ipairs(delta.update or {}) do
+    changed[backend.name] = true
+  end
+
+  local new_backends = {}
+  for _, backend in ipairs(backends) do
+    if not changed[backend.name] then
+      table.insert(new_backends, backend)
+    end
+  end
+  for _, backend in ipairs(delta.update or {}) do
+    table.insert(new_backends, backend)
+  end
+  table.sort(new_backends, function(a, b) return a.name < b.name end)
+
+  local success, err = configuration_data:set("backends", json.encode(new_backends))
+  if not success then
+    ngx.log(ngx.ERR, "dynamic-configuration: error updating configuration: " .. tostring(err))
+    ngx.status = ngx.HTTP_INTERNAL_SERVER_ERROR
+    return
+  end
+
+  -- the checksum of all the backends computed by the controller
+  success, err = configuration_data:set("backends_checksum", delta.checksum)
+  if not success then
+    ngx.log(ngx.ERR, "dynamic-configuration: error updating checksum: " .. tostring(err))
+    ngx.status = ngx.HTTP_INTERNAL_SERVER_ERROR
+    return
+  end
+
+  ngx.status = ngx.HTTP_CREATED
+end
+
 function _M.call()
   if ngx.var.request_method ~= "POST" and ngx.var.request_method ~= "GET" then
     ngx.status = ngx.HTTP_BAD_REQUEST
@@ -93,6 +396,41 @@ function _M.call()
     return
   end
 
+  if ngx.var.request_uri == "/configuration/backends/delta" then
+    handle_backends_delta()
+    return
+  end
+
+  if ngx.var.request_uri == "/configuration/checksum" then
+    handle_checksum()
+    return
+  end
+
+  if ngx.var.request_uri == "/configuration/websockets" then
+    handle_websockets()
+    return
+  end
+
+  if ngx.var.request_uri == "/configuration/stale" then
+    handle_stale()
+    return
+  end
+
+  if ngx.var.request_uri == "/configuration/denylist" then
+    handle_denylist()
+    return
+  end
+
+  if ngx.var.request_uri == "/configuration/host-redirects" then
+    handle_host_redirects()
+    return
+  end
+
+  if ngx.var.request_uri == "/configuration/oidc" then
+    handle_oidc()
+    return
+  end
+
   if ngx.var.request_uri ~= "/configuration/backends" then
     ngx.status = ngx.HTTP_NOT_FOUND
     ngx.print("Not found!")
@@ -119,11 +457,26 @@ function _M.call()
     return
   end
 
+  success, err = configuration_data:set("backends_checksum", ngx.md5(backends))
+  if not success then
+    ngx.log(ngx.ERR, "dynamic-configuration: error updating checksum: " .. tostring(err))
+    ngx.status = ngx.HTTP_BAD_REQUEST
+    return
+  end
+
   ngx.status = ngx.HTTP_CREATED
 end
 
 if _TEST then
+  _M.fetch_request_body = fetch_request_body
   _M.handle_servers = handle_servers
+  _M.handle_stale = handle_stale
+  _M.handle_checksum = handle_checksum
+  _M.handle_backends_delta = handle_backends_delta
+  _M.handle_websockets = handle_websockets
+  _M.handle_denylist = handle_denylist
+  _M.handle_host_redirects = handle_host_redirects
+  _M.handle_oidc = handle_oidc
 end
 
 return _M
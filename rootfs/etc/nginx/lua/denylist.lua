@@ -0,0 +1,93 @@
+local json = require("cjson")
+local iputils = require("resty.iputils")
+local ipv6 = require("util.ipv6")
+local configuration = require("configuration")
+
+-- blocks the requests sent from the networks defined in the global denylist.
+-- The list is parsed only when the content of the shared dictionary changes.
+-- The IPv4 networks are matched by resty.iputils and the IPv6 ones by util.ipv6.
+local _M = {}
+
+local denylist_data
+local networks = {}
+local ipv6_networks = {}
+
+local function get_networks()
+  local data = configuration.get_denylist()
+  if data == denylist_data then
+    return networks, ipv6_networks
+  end
+
+  denylist_data = data
+  networks = {}
+  ipv6_networks = {}
+
+  if not data then
+    return networks, ipv6_networks
+  end
+
+  local ok, cidrs = pcall(json.decode, data)
+  if not ok or type(cidrs) ~= "table" then
+    ngx.log(ngx.ERR, "denylist: could not parse networks: " .. tostring(cidrs))
+    return networks, ipv6_networks
+  end
+
+  local ipv4_cidrs = {}
+  for _, cidr in ipairs(cidrs) do
+    if type(cidr) == "string" and cidr:find(":", 1, true) then
+      local network, err = ipv6.parse_cidr(cidr)
+      if network then
+        table.insert(ipv6_networks, network)
+      else
+        ngx.log(ngx.ERR, "denylist: " .. tostring(err))
+      end
+    else
+      table.insert(ipv4_cidrs, cidr)
+    end
+  end
+
+  networks = iputils.parse_cidrs(ipv4_cidrs)
+  return networks, ipv6_networks
+end
+
+-- returns whether an IPv6 address is in one of the networks
+local function ipv6_denied(address, denied)
+  local groups = ipv6.parse_address(address)
+  if not groups then
+    return false
+  end
+
+  for _, network in ipairs(denied) do
+    if ipv6.contains(network, groups) then
+      return true
+    end
+  end
+
+  return false
+end
+
+function _M.call()
+  local denied, denied_ipv6 = get_networks()
+  if #denied == 0 and #denied_ipv6 == 0 then
+    return
+  end
+
+  local address = ngx.var.the_real_ip or ngx.var.remote_addr
+  local blocked
+  if address and address:find(":", 1, true) then
+    blocked = ipv6_denied(address, denied_ipv6)
+  else
+    blocked = #denied > 0 and iputils.ip_in_cidrs(address, denied)
+  end
+
+  if blocked then
+    ngx.log(ngx.INFO, "denylist: access denied to " .. tostring(address))
+    return ngx.exit(ngx.HTTP_FORBIDDEN)
+  end
+end
+
+if _TEST then
+  _M.get_networks = get_networks
+end
+
+return _M
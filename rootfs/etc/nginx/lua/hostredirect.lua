@@ -0,0 +1,53 @@
+local json = require("cjson")
+local configuration = require("configuration")
+
+-- redirects the requests sent to the hosts defined in the host redirect map.
+-- The map is parsed only when the content of the shared dictionary changes.
+local _M = {}
+
+local redirects_data
+local redirects = {}
+
+local function get_redirects()
+  local data = configuration.get_host_redirects()
+  if data == redirects_data then
+    return redirects
+  end
+
+  redirects_data = data
+  redirects = {}
+
+  if not data then
+    return redirects
+  end
+
+  local ok, res = pcall(json.decode, data)
+  if not ok or type(res) ~= "table" then
+    ngx.log(ngx.ERR, "hostredirect: could not parse host redirects: " .. tostring(res))
+    return redirects
+  end
+
+  redirects = res
+  return redirects
+end
+
+function _M.call()
+  local redirect = get_redirects()[ngx.var.host]
+  if not redirect then
+    return
+  end
+
+  local uri = "/"
+  if redirect.preservePath then
+    uri = ngx.var.request_uri
+  end
+
+  local scheme = ngx.var.pass_access_scheme or ngx.var.scheme
+  return ngx.redirect(scheme .. "://" .. redirect.host .. uri, redirect.code)
+end
+
+if _TEST then
+  _M.get_redirects = get_redirects
+end
+
+return _M
@@ -17,7 +17,7 @@ local function send(payload)
 end
 
 local function metrics()
-  return {
+  local m = {
     host = ngx.var.host or "-",
     namespace = ngx.var.namespace or "-",
     ingress = ngx.var.ingress_name or "-",
@@ -36,6 +36,26 @@ local function metrics()
     upstreamResponseLength = tonumber(ngx.var.upstream_response_length) or -1,
     upstreamStatus = ngx.var.upstream_status or "-",
   }
+
+  -- only sent for HTTPS upstreams to keep the payload small
+  if ngx.var.upstream_tls == "on" then
+    m.upstreamTLS = true
+  end
+
+  -- only sent for the requests served by an alternative backend, like a canary
+  local alternative_backend = ngx.var.proxy_alternative_upstream_name
+  if alternative_backend and alternative_backend ~= "" then
+    m.alternativeBackend = alternative_backend
+  end
+
+  -- only sent for the locations with the size metrics enabled
+  if ngx.var.size_metrics == "on" then
+    m.sizeMetrics = true
+    m.requestBodySize = tonumber(ngx.var.content_length) or 0
+    m.responseBodySize = tonumber(ngx.var.body_bytes_sent) or -1
+  end
+
+  return m
 end
 
 local function flush(premature)
@@ -0,0 +1,89 @@
+-- authenticates requests using the authorization code flow of an OpenID
+-- Connect provider. The session, including the tokens, is stored in an
+-- encrypted cookie, which means the session secret must be the same in
+-- all the replicas of the ingress controller.
+
+local json = require("cjson")
+local openidc = require("resty.openidc")
+local configuration = require("configuration")
+
+local _M = {}
+
+-- credentials decoded by this worker and the JSON they were decoded from
+local raw_credentials
+local credentials = {}
+
+-- headers sent to the upstream with information about the authenticated user
+local USER_HEADER = "X-Auth-Request-User"
+local EMAIL_HEADER = "X-Auth-Request-Email"
+local ACCESS_TOKEN_HEADER = "X-Auth-Request-Access-Token"
+
+local function set_headers(res)
+  -- headers sent by the client must never reach the upstream
+  ngx.req.clear_header(USER_HEADER)
+  ngx.req.clear_header(EMAIL_HEADER)
+  ngx.req.clear_header(ACCESS_TOKEN_HEADER)
+
+  if res.id_token then
+    if res.id_token.sub then
+      ngx.req.set_header(USER_HEADER, res.id_token.sub)
+    end
+    if res.id_token.email then
+      ngx.req.set_header(EMAIL_HEADER, res.id_token.email)
+    end
+  end
+
+  if res.access_token then
+    ngx.req.set_header(ACCESS_TOKEN_HEADER, res.access_token)
+  end
+end
+
+-- returns the credentials of the OpenID Connect client stored in a secret,
+-- posted by the controller with the dynamic configuration
+local function get_credentials(secret)
+  local raw = configuration.get_oidc_credentials()
+  if not raw then
+    return nil
+  end
+
+  if raw ~= raw_credentials then
+    local ok, decoded = pcall(json.decode, raw)
+    if not ok or type(decoded) ~= "table" then
+      return nil
+    end
+
+    raw_credentials = raw
+    credentials = decoded
+  end
+
+  return credentials[secret]
+end
+
+function _M.authenticate(opts, secret)
+  local creds = get_credentials(secret)
+  if not creds then
+    ngx.log(ngx.ERR, "oidc: no credentials found for secret ", secret)
+    return ngx.exit(ngx.HTTP_SERVICE_UNAVAILABLE)
+  end
+
+  opts.client_id = creds.client_id
+  opts.client_secret = creds.client_secret
+
+  -- refresh the access token (if possible) before it expires
+  opts.renew_access_token_on_expiry = true
+  opts.access_token_expires_leeway = 30
+
+  local res, err = openidc.authenticate(opts, nil, nil, { secret = creds.session_secret })
+  if err then
+    ngx.log(ngx.ERR, "oidc: error authenticating request: ", tostring(err))
+    return ngx.exit(ngx.HTTP_INTERNAL_SERVER_ERROR)
+  end
+
+  set_headers(res)
+end
+
+if _TEST then
+  _M.set_headers = set_headers
+end
+
+return _M
@@ -30,6 +30,21 @@ describe("Balancer ewma", function()
       local peer = instance:balance()
       assert.equal("10.184.97.100:8080", peer)
     end)
+
+    it("does not pick endpoints with weight 0", function()
+      local backend = {
+        name = "my-dummy-backend", ["load-balance"] = "ewma",
+        endpoints = {
+          { address = "10.184.7.40", port = "8080", maxFails = 0, failTimeout = 0, weight = 0 },
+          { address = "10.184.97.100", port = "8080", maxFails = 0, failTimeout = 0 },
+        }
+      }
+      local instance = balancer_ewma:new(backend)
+
+      for _ = 1, 10 do
+        assert.equal("10.184.97.100:8080", instance:balance())
+      end
+    end)
   end)
 
   describe("sync()", function()
@@ -132,6 +132,16 @@ describe("Sticky", function()
         assert.has_no.errors(function() sticky_balancer_instance:balance() end)
         assert.spy(s).was_called()
       end)
+
+      it("does not pick a draining endpoint", function()
+        local backend = get_test_backend()
+        backend.endpoints[1].draining = true
+        table.insert(backend.endpoints, { address = "10.184.7.41", port = "8080", maxFails = 0, failTimeout = 0 })
+
+        local sticky_balancer_instance = sticky:new(backend)
+        local peer = sticky_balancer_instance:balance()
+        assert.equal("10.184.7.41:8080", peer)
+      end)
     end)
 
     context("when client has a cookie set", function()
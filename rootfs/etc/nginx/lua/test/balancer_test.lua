@@ -1,5 +1,7 @@
 _G._TEST = true
 
+local util = require("util")
+
 local balancer, expected_implementations, backends
 
 local function reset_balancer()
@@ -115,6 +117,34 @@ describe("Balancer", function()
       assert.stub(mock_instance.sync).was_called_with(mock_instance, expected_backend)
     end)
 
+    it("resolves external name to endpoints when initializing the balancer", function()
+      backend = {
+        name = "exmaple-com", service = { spec = { ["type"] = "ExternalName" } },
+        endpoints = {
+          { address = "example.com", port = "80", maxFails = 0, failTimeout = 0 }
+        }
+      }
+
+      local dns_helper = require("test/dns_helper")
+      dns_helper.mock_dns_query({
+        {
+          name = "example.com",
+          address = "192.168.1.1",
+          ttl = 3600,
+        },
+      })
+      expected_backend = {
+        name = "exmaple-com", service = { spec = { ["type"] = "ExternalName" } },
+        endpoints = {
+          { address = "192.168.1.1", port = "80" },
+        }
+      }
+
+      local s = spy.on(implementation, "new")
+      assert.has_no.errors(function() balancer.sync_backend(backend) end)
+      assert.spy(s).was_called_with(implementation, expected_backend)
+    end)
+
     it("wraps IPv6 addresses into square brackets", function()
       local backend = {
         name = "exmaple-com",
@@ -171,4 +201,299 @@ describe("Balancer", function()
       assert.stub(mock_instance.sync).was_called_with(mock_instance, backend)
     end)
   end)
+
+  describe("route_by_query_parameter()", function()
+    local original_ngx = ngx
+    local query_balancer
+
+    before_each(function()
+      balancer.sync_backend(backends[1])
+      query_balancer = {
+        query_routing_policies = {
+          ["default/api"] = { param = "version", backends = { v2 = "access-router-production-web-80" } },
+        },
+      }
+    end)
+
+    after_each(function()
+      _G.ngx = original_ngx
+    end)
+
+    local function mock_ngx_var(var)
+      local _ngx = { var = var }
+      setmetatable(_ngx, { __index = original_ngx })
+      _G.ngx = _ngx
+    end
+
+    it("returns the balancer of the backend mapped to the value of the parameter", function()
+      mock_ngx_var({ namespace = "default", ingress_name = "api", arg_version = "v2" })
+      local b = balancer.route_by_query_parameter(query_balancer)
+      assert.are.equal(package.loaded["balancer.round_robin"], getmetatable(b))
+    end)
+
+    it("returns nil when the value is not mapped", function()
+      mock_ngx_var({ namespace = "default", ingress_name = "api", arg_version = "v3" })
+      assert.is_nil(balancer.route_by_query_parameter(query_balancer))
+    end)
+
+    it("returns nil when the request does not contain the parameter", function()
+      mock_ngx_var({ namespace = "default", ingress_name = "api" })
+      assert.is_nil(balancer.route_by_query_parameter(query_balancer))
+    end)
+
+    it("returns nil when the policy belongs to another Ingress", function()
+      mock_ngx_var({ namespace = "default", ingress_name = "web", arg_version = "v2" })
+      assert.is_nil(balancer.route_by_query_parameter(query_balancer))
+    end)
+
+    it("returns nil when there is no policy", function()
+      mock_ngx_var({ namespace = "default", ingress_name = "api", arg_version = "v2" })
+      assert.is_nil(balancer.route_by_query_parameter({}))
+    end)
+  end)
+
+  describe("route_to_alternative_balancer()", function()
+    local original_ngx = ngx
+    local primary
+
+    local function mock_ngx_var(var)
+      local _ngx = { var = var }
+      setmetatable(_ngx, { __index = original_ngx })
+      _G.ngx = _ngx
+    end
+
+    before_each(function()
+      local tenant = util.deepcopy(backends[1])
+      tenant.name = "default-app-header-0-tenant-acme-80"
+      tenant.trafficShapingPolicy = { weight = 0, header = "X-Tenant", cookie = "", headerValue = "acme" }
+
+      local beta = util.deepcopy(backends[1])
+      beta.name = "default-app-header-1-tenant-beta-80"
+      beta.trafficShapingPolicy = { weight = 0, header = "X-Tenant", cookie = "", headerPattern = "^beta-" }
+
+      for _, backend in ipairs({ tenant, beta }) do
+        -- the traffic shaping policy is configured in the first sync after the creation
+        balancer.sync_backend(backend)
+        balancer.sync_backend(backend)
+      end
+
+      primary = { alternative_backends = { tenant.name, beta.name } }
+    end)
+
+    after_each(function()
+      _G.ngx = original_ngx
+    end)
+
+    it("returns the alternative balancer with the exact header value", function()
+      mock_ngx_var({ http_x_tenant = "acme" })
+      local b = balancer.route_to_alternative_balancer(primary)
+      assert.are.equal("acme", b.traffic_shaping_policy.headerValue)
+    end)
+
+    it("returns the alternative balancer matching the header pattern", function()
+      mock_ngx_var({ http_x_tenant = "beta-corp" })
+      local b = balancer.route_to_alternative_balancer(primary)
+      assert.are.equal("^beta-", b.traffic_shaping_policy.headerPattern)
+    end)
+
+    it("returns nil when no alternative balancer matches", function()
+      mock_ngx_var({ http_x_tenant = "other" })
+      assert.is_nil(balancer.route_to_alternative_balancer(primary))
+
+      mock_ngx_var({})
+      assert.is_nil(balancer.route_to_alternative_balancer(primary))
+    end)
+  end)
+
+  describe("weight_percentage()", function()
+    it("returns the percentage of the weight relative to the total", function()
+      assert.are.equal(0.01, balancer.weight_percentage({ weight = 1, weightTotal = 10000 }))
+      assert.are.equal(50, balancer.weight_percentage({ weight = 50 }))
+      assert.are.equal(25, balancer.weight_percentage({ weight = 1, weightTotal = 4 }))
+    end)
+
+    it("returns 0 for the policies without weight", function()
+      assert.are.equal(0, balancer.weight_percentage({ weight = 0 }))
+      assert.are.equal(0, balancer.weight_percentage({}))
+    end)
+  end)
+
+  describe("route_to_alternative_balancer() with several canaries", function()
+    local original_ngx = ngx
+    local original_random = math.random
+    local primary
+
+    before_each(function()
+      local weights = { 20, 30, 0 }
+      local names = {}
+      for i, weight in ipairs(weights) do
+        local canary = util.deepcopy(backends[1])
+        canary.name = "default-canary-" .. i .. "-80"
+        canary.trafficShapingPolicy = { weight = weight, header = "X-Canary", cookie = "" }
+
+        -- the traffic shaping policy is configured in the first sync after the creation
+        balancer.sync_backend(canary)
+        balancer.sync_backend(canary)
+        table.insert(names, canary.name)
+      end
+
+      primary = { alternative_backends = names }
+
+      local _ngx = { var = {} }
+      setmetatable(_ngx, { __index = original_ngx })
+      _G.ngx = _ngx
+    end)
+
+    after_each(function()
+      _G.ngx = original_ngx
+      math.random = original_random
+    end)
+
+    it("routes each canary its own percentage of the requests", function()
+      math.random = function() return 0.1 end
+      local _, name = balancer.route_to_alternative_balancer(primary)
+      assert.are.equal("default-canary-1-80", name)
+
+      math.random = function() return 0.3 end
+      _, name = balancer.route_to_alternative_balancer(primary)
+      assert.are.equal("default-canary-2-80", name)
+
+      math.random = function() return 0.5 end
+      assert.is_nil(balancer.route_to_alternative_balancer(primary))
+    end)
+
+    it("routes the requests with the header to the first canary", function()
+      math.random = function() return 0.99 end
+      ngx.var.http_x_canary = "always"
+      local _, name = balancer.route_to_alternative_balancer(primary)
+      assert.are.equal("default-canary-1-80", name)
+    end)
+
+    it("routes the requests to the shadow canaries only when shadow is requested", function()
+      local shadow = util.deepcopy(backends[1])
+      shadow.name = "default-canary-shadow-80"
+      shadow.trafficShapingPolicy = { weight = 10, header = "", cookie = "", shadow = true }
+      balancer.sync_backend(shadow)
+      balancer.sync_backend(shadow)
+      table.insert(primary.alternative_backends, 1, shadow.name)
+
+      math.random = function() return 0.05 end
+      local _, name = balancer.route_to_alternative_balancer(primary)
+      assert.are.equal("default-canary-1-80", name)
+
+      _, name = balancer.route_to_alternative_balancer(primary, true)
+      assert.are.equal("default-canary-shadow-80", name)
+
+      math.random = function() return 0.15 end
+      assert.is_nil(balancer.route_to_alternative_balancer(primary, true))
+    end)
+
+    it("does not route the requests with the header to the canaries", function()
+      math.random = function() return 0 end
+      ngx.var.http_x_canary = "never"
+      assert.is_nil(balancer.route_to_alternative_balancer(primary))
+    end)
+
+    it("routes to the alternatives restricted to a host only the requests of the host", function()
+      local override = util.deepcopy(backends[1])
+      override.name = "override-foo.bar-default/experiment"
+      override.trafficShapingPolicy = { weight = 100, header = "", cookie = "", host = "foo.bar" }
+      balancer.sync_backend(override)
+      balancer.sync_backend(override)
+      primary = { alternative_backends = { override.name } }
+
+      math.random = function() return 0.5 end
+      ngx.var.server_name = "foo.bar"
+      local _, name = balancer.route_to_alternative_balancer(primary)
+      assert.are.equal(override.name, name)
+
+      ngx.var.server_name = "other.bar"
+      assert.is_nil(balancer.route_to_alternative_balancer(primary))
+    end)
+  end)
+
+  describe("get_upstream_vhost()", function()
+    it("returns the upstream vhost of the traffic shaping policy", function()
+      local canary = { traffic_shaping_policy = { weight = 20, upstreamVhost = "app.canary.svc" } }
+      assert.are.equal("app.canary.svc", balancer.get_upstream_vhost(canary))
+    end)
+
+    it("returns nil when the balancer has no upstream vhost", function()
+      assert.is_nil(balancer.get_upstream_vhost({ traffic_shaping_policy = { weight = 20 } }))
+      assert.is_nil(balancer.get_upstream_vhost({}))
+    end)
+  end)
+
+  describe("get_ssl_server_name()", function()
+    local original_ngx = ngx
+
+    before_each(function()
+      local _ngx = { var = { proxy_upstream_vhost = "app.example.com" } }
+      setmetatable(_ngx, { __index = original_ngx })
+      _G.ngx = _ngx
+    end)
+
+    after_each(function()
+      _G.ngx = original_ngx
+    end)
+
+    it("returns the server name of the backend", function()
+      local canary = { ssl_server_name = { enabled = true, name = "lb.example.com" } }
+      assert.are.equal("lb.example.com", balancer.get_ssl_server_name(canary))
+    end)
+
+    it("returns the upstream vhost when the backend has no server name", function()
+      assert.are.equal("app.example.com", balancer.get_ssl_server_name({ ssl_server_name = { enabled = true } }))
+    end)
+
+    it("returns an empty name when SNI is disabled", function()
+      assert.are.equal("", balancer.get_ssl_server_name({ ssl_server_name = { enabled = false } }))
+    end)
+
+    it("returns nil when the backend does not configure SNI", function()
+      assert.is_nil(balancer.get_ssl_server_name({}))
+    end)
+  end)
+
+  describe("next_upstream_allowed()", function()
+    it("allows retries when the backend has no budget", function()
+      balancer.sync_backend(backends[1])
+
+      for _ = 1, 10 do
+        assert.is_true(balancer.next_upstream_allowed(backends[1].name, true))
+      end
+    end)
+
+    it("limits retries to the budget of the backend", function()
+      local backend = backends[1]
+      backend["next-upstream-budget"] = 20
+      balancer.sync_backend(backend)
+
+      for _ = 1, 10 do
+        balancer.next_upstream_allowed(backend.name, false)
+      end
+
+      assert.is_true(balancer.next_upstream_allowed(backend.name, true))
+      assert.is_false(balancer.next_upstream_allowed(backend.name, true))
+    end)
+
+    it("resets the counters when the window expires", function()
+      local backend = backends[1]
+      backend["next-upstream-budget"] = 20
+      balancer.sync_backend(backend)
+
+      local now = ngx.now()
+      local s = stub(ngx, "now", function() return now end)
+
+      for _ = 1, 5 do
+        balancer.next_upstream_allowed(backend.name, false)
+      end
+      assert.is_false(balancer.next_upstream_allowed(backend.name, true))
+
+      now = now + 10
+      assert.is_true(balancer.next_upstream_allowed(backend.name, false))
+
+      s:revert()
+    end)
+  end)
 end)
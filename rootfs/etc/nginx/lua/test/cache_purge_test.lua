@@ -0,0 +1,13 @@
+_G._TEST = true
+local cache_purge = require("cache_purge")
+
+describe("CachePurge", function()
+  describe("cache_file", function()
+    it("returns the path of the entry using levels=1:2", function()
+      local hash = ngx.md5("httpexample.com/index.html")
+      local expected = "/tmp/nginx-cache/static/" .. hash:sub(-1) .. "/" .. hash:sub(-3, -2) .. "/" .. hash
+
+      assert.are.equal(expected, cache_purge.cache_file("/tmp/nginx-cache", "static", "httpexample.com/index.html"))
+    end)
+  end)
+end)
@@ -104,6 +104,11 @@ describe("Configuration", function()
                 assert.equal(ngx.shared.configuration_data:get("backends"), cjson.encode(get_backends()))
             end)
 
+            it("stores the checksum of the posted backends", function()
+                assert.has_no.errors(configuration.call)
+                assert.equal(configuration.get_backends_checksum(), ngx.md5(cjson.encode(get_backends())))
+            end)
+
             context("Failed to read request body", function()
                 local mocked_get_body_data = ngx.req.get_body_data
                 before_each(function()
@@ -259,4 +264,241 @@ describe("Configuration", function()
             assert.same(ngx.status, ngx.HTTP_INTERNAL_SERVER_ERROR)
         end)
     end)
+
+    describe("handle_checksum()", function()
+        it("should not accept non GET methods", function()
+            ngx.var.request_method = "POST"
+            local s = spy.on(ngx, "print")
+            assert.has_no.errors(configuration.handle_checksum)
+            assert.spy(s).was_called_with("Only GET requests are allowed!")
+            assert.same(ngx.status, ngx.HTTP_BAD_REQUEST)
+        end)
+
+        it("should return the checksum of the backends", function()
+            ngx.var.request_method = "GET"
+            ngx.shared.configuration_data:set("backends_checksum", "abc")
+            local s = spy.on(ngx, "print")
+            assert.has_no.errors(configuration.handle_checksum)
+            assert.spy(s).was_called_with("abc")
+            assert.same(ngx.status, ngx.HTTP_OK)
+        end)
+    end)
+
+    describe("fetch_request_body()", function()
+        it("should decompress a body compressed with gzip", function()
+            local zlib = require("ffi-zlib")
+            local body = cjson.encode(get_backends())
+            local compressed = {}
+            local sent = false
+            zlib.deflateGzip(function()
+                if sent then return nil end
+                sent = true
+                return body
+            end, function(chunk) table.insert(compressed, chunk) end)
+
+            ngx.var.http_content_encoding = "gzip"
+            ngx.req.get_body_data = function() return table.concat(compressed) end
+
+            assert.same(configuration.fetch_request_body(), body)
+        end)
+
+        it("should return nil when the body is not compressed with gzip", function()
+            ngx.var.http_content_encoding = "gzip"
+            assert.is_nil(configuration.fetch_request_body())
+        end)
+    end)
+
+    describe("handle_backends_delta()", function()
+        before_each(function()
+            ngx.var.request_method = "POST"
+            ngx.shared.configuration_data:set("backends", cjson.encode(get_backends()))
+            ngx.shared.configuration_data:set("backends_checksum", "base")
+        end)
+
+        it("should not accept non POST methods", function()
+            ngx.var.request_method = "GET"
+            local s = spy.on(ngx, "print")
+            assert.has_no.errors(configuration.handle_backends_delta)
+            assert.spy(s).was_called_with("Only POST requests are allowed!")
+            assert.same(ngx.status, ngx.HTTP_BAD_REQUEST)
+        end)
+
+        it("should add, update and remove backends", function()
+            local updated = { name = "my-dummy-backend-2", ["load-balance"] = "round_robin", endpoints = {} }
+            local added = { name = "my-dummy-backend-0", ["load-balance"] = "ewma", endpoints = {} }
+            ngx.req.get_body_data = function()
+                return cjson.encode({ base = "base", checksum = "new", update = { updated, added }, remove = { "my-dummy-backend-3" } })
+            end
+
+            assert.has_no.errors(configuration.handle_backends_delta)
+            assert.same(ngx.status, ngx.HTTP_CREATED)
+            assert.same(configuration.get_backends_checksum(), "new")
+
+            local backends = cjson.decode(configuration.get_backends_data())
+            assert.same(#backends, 3)
+            assert.same(backends[1].name, "my-dummy-backend-0")
+            assert.same(backends[2].name, "my-dummy-backend-1")
+            assert.same(backends[3]["load-balance"], "round_robin")
+        end)
+
+        it("should reject a delta based on other backends", function()
+            ngx.req.get_body_data = function()
+                return cjson.encode({ base = "other", checksum = "new", remove = { "my-dummy-backend-3" } })
+            end
+
+            assert.has_no.errors(configuration.handle_backends_delta)
+            assert.same(ngx.status, ngx.HTTP_CONFLICT)
+            assert.same(configuration.get_backends_checksum(), "base")
+            assert.same(#cjson.decode(configuration.get_backends_data()), 3)
+        end)
+
+        it("should reject an invalid delta", function()
+            ngx.req.get_body_data = function() return "my-dummy-backend-3" end
+
+            assert.has_no.errors(configuration.handle_backends_delta)
+            assert.same(ngx.status, ngx.HTTP_BAD_REQUEST)
+        end)
+    end)
+
+    describe("handle_stale()", function()
+        it("should not accept non POST methods", function()
+            ngx.var.request_method = "GET"
+
+            local s = spy.on(ngx, "print")
+            assert.has_no.errors(configuration.handle_stale)
+            assert.spy(s).was_called_with("Only POST requests are allowed!")
+            assert.same(ngx.status, ngx.HTTP_BAD_REQUEST)
+        end)
+
+        it("should store the reasons why the configuration is stale", function()
+            ngx.var.request_method = "POST"
+            ngx.req.get_body_data = function() return cjson.encode({ "reload-failed", "apiserver-unreachable" }) end
+
+            assert.has_no.errors(configuration.handle_stale)
+            assert.same(configuration.get_stale_reasons(), "reload-failed,apiserver-unreachable")
+            assert.same(ngx.status, ngx.HTTP_CREATED)
+        end)
+
+        it("should clear the reasons when the configuration is not stale", function()
+            ngx.var.request_method = "POST"
+            ngx.req.get_body_data = function() return cjson.encode({}) end
+
+            assert.has_no.errors(configuration.handle_stale)
+            assert.same(configuration.get_stale_reasons(), "")
+            assert.same(ngx.status, ngx.HTTP_CREATED)
+        end)
+    end)
+
+    describe("handle_denylist()", function()
+        it("should return the stored denylist", function()
+            ngx.var.request_method = "GET"
+            local denylist = cjson.encode({ "10.0.0.0/8" })
+            ngx.shared.configuration_data:set("denylist", denylist)
+
+            local s = spy.on(ngx, "print")
+            assert.has_no.errors(configuration.handle_denylist)
+            assert.spy(s).was_called_with(denylist)
+            assert.same(ngx.status, ngx.HTTP_OK)
+        end)
+
+        it("should return not found when there is no denylist", function()
+            ngx.var.request_method = "GET"
+            ngx.shared.configuration_data:delete("denylist")
+
+            assert.has_no.errors(configuration.handle_denylist)
+            assert.same(ngx.status, ngx.HTTP_NOT_FOUND)
+        end)
+
+        it("should store the denylist", function()
+            ngx.var.request_method = "POST"
+            local denylist = cjson.encode({ "10.0.0.0/8", "192.168.1.1" })
+            ngx.req.get_body_data = function() return denylist end
+
+            assert.has_no.errors(configuration.handle_denylist)
+            assert.same(configuration.get_denylist(), denylist)
+            assert.same(ngx.status, ngx.HTTP_CREATED)
+        end)
+
+        it("should reject an invalid denylist", function()
+            ngx.var.request_method = "POST"
+            ngx.req.get_body_data = function() return "10.0.0.0/8" end
+
+            assert.has_no.errors(configuration.handle_denylist)
+            assert.same(ngx.status, ngx.HTTP_BAD_REQUEST)
+        end)
+
+        it("should store a denylist with IPv6 networks", function()
+            ngx.var.request_method = "POST"
+            local denylist = cjson.encode({ "10.0.0.0/8", "2001:db8::/32", "::1" })
+            ngx.req.get_body_data = function() return denylist end
+
+            assert.has_no.errors(configuration.handle_denylist)
+            assert.same(configuration.get_denylist(), denylist)
+            assert.same(ngx.status, ngx.HTTP_CREATED)
+        end)
+
+        it("should reject a denylist with invalid networks", function()
+            ngx.var.request_method = "POST"
+            local denylist = cjson.encode({ "10.0.0.0/8", "2001:db8::1::/32" })
+            ngx.req.get_body_data = function() return denylist end
+            ngx.shared.configuration_data:delete("denylist")
+
+            local s = spy.on(ngx, "print")
+            assert.has_no.errors(configuration.handle_denylist)
+            assert.spy(s).was_called_with("invalid network 2001:db8::1::/32: invalid IPv6 address 2001:db8::1::")
+            assert.same(ngx.status, ngx.HTTP_BAD_REQUEST)
+            assert.is_nil(configuration.get_denylist())
+        end)
+    end)
+
+    describe("handle_host_redirects()", function()
+        it("should not accept non POST methods", function()
+            ngx.var.request_method = "GET"
+
+            local s = spy.on(ngx, "print")
+            assert.has_no.errors(configuration.handle_host_redirects)
+            assert.spy(s).was_called_with("Only POST requests are allowed!")
+            assert.same(ngx.status, ngx.HTTP_BAD_REQUEST)
+        end)
+
+        it("should store the host redirects", function()
+            ngx.var.request_method = "POST"
+            local redirects = cjson.encode({ ["old.example.com"] = { host = "new.example.com", code = 301, preservePath = true } })
+            ngx.req.get_body_data = function() return redirects end
+
+            assert.has_no.errors(configuration.handle_host_redirects)
+            assert.same(configuration.get_host_redirects(), redirects)
+            assert.same(ngx.status, ngx.HTTP_CREATED)
+        end)
+
+        it("should reject invalid host redirects", function()
+            ngx.var.request_method = "POST"
+            ngx.req.get_body_data = function() return "old.example.com" end
+
+            assert.has_no.errors(configuration.handle_host_redirects)
+            assert.same(ngx.status, ngx.HTTP_BAD_REQUEST)
+        end)
+    end)
+
+    describe("handle_oidc()", function()
+        it("should store the credentials", function()
+            ngx.var.request_method = "POST"
+            local credentials = cjson.encode({ ["default/oidc"] = { client_id = "app", client_secret = "s3cr3t", session_secret = "0123456789" } })
+            ngx.req.get_body_data = function() return credentials end
+
+            assert.has_no.errors(configuration.handle_oidc)
+            assert.same(configuration.get_oidc_credentials(), credentials)
+            assert.same(ngx.status, ngx.HTTP_CREATED)
+        end)
+
+        it("should reject invalid credentials without logging them", function()
+            ngx.var.request_method = "POST"
+            ngx.req.get_body_data = function() return "s3cr3t" end
+
+            local s = spy.on(ngx, "log")
+            assert.has_no.errors(configuration.handle_oidc)
+            assert.spy(s).was_not_called_with(ngx.ERR, match.matches("s3cr3t"))
+            assert.same(ngx.status, ngx.HTTP_BAD_REQUEST)
+        end)
+    end)
 end)
@@ -0,0 +1,65 @@
+_G._TEST = true
+local cjson = require("cjson")
+local configuration = require("configuration")
+local denylist = require("denylist")
+
+local unmocked_ngx = _G.ngx
+
+local function mock_ngx(address)
+  local _ngx = {
+    var = { the_real_ip = address },
+    exit = function(status) end,
+    log = function(...) end,
+  }
+  setmetatable(_ngx, { __index = unmocked_ngx })
+  _G.ngx = _ngx
+end
+
+describe("Denylist", function()
+  before_each(function()
+    ngx.shared.configuration_data:set("denylist", cjson.encode({ "10.0.0.0/8", "192.168.1.1", "2001:db8::/32" }))
+  end)
+
+  after_each(function()
+    _G.ngx = unmocked_ngx
+    ngx.shared.configuration_data:delete("denylist")
+  end)
+
+  it("denies requests from addresses in the denylist", function()
+    mock_ngx("10.1.2.3")
+    local s = spy.on(ngx, "exit")
+    assert.has_no.errors(denylist.call)
+    assert.spy(s).was_called_with(ngx.HTTP_FORBIDDEN)
+  end)
+
+  it("allows requests from other addresses", function()
+    mock_ngx("172.16.0.1")
+    local s = spy.on(ngx, "exit")
+    assert.has_no.errors(denylist.call)
+    assert.spy(s).was_not_called()
+  end)
+
+  it("denies requests from IPv6 addresses in the denylist", function()
+    mock_ngx("2001:db8:1::1")
+    local s = spy.on(ngx, "exit")
+    assert.has_no.errors(denylist.call)
+    assert.spy(s).was_called_with(ngx.HTTP_FORBIDDEN)
+  end)
+
+  it("allows requests from other IPv6 addresses", function()
+    mock_ngx("2001:db9::1")
+    local s = spy.on(ngx, "exit")
+    assert.has_no.errors(denylist.call)
+    assert.spy(s).was_not_called()
+  end)
+
+  it("parses the networks again when the denylist changes", function()
+    mock_ngx("172.16.0.1")
+    assert.equal(2, #denylist.get_networks())
+
+    ngx.shared.configuration_data:set("denylist", cjson.encode({ "172.16.0.0/12" }))
+    local s = spy.on(ngx, "exit")
+    assert.has_no.errors(denylist.call)
+    assert.spy(s).was_called_with(ngx.HTTP_FORBIDDEN)
+  end)
+end)
@@ -0,0 +1,62 @@
+_G._TEST = true
+local cjson = require("cjson")
+local hostredirect = require("hostredirect")
+
+local unmocked_ngx = _G.ngx
+
+local function mock_ngx(host, request_uri)
+  local _ngx = {
+    var = { host = host, request_uri = request_uri, pass_access_scheme = "https" },
+    redirect = function(uri, status) end,
+    log = function(...) end,
+  }
+  setmetatable(_ngx, { __index = unmocked_ngx })
+  _G.ngx = _ngx
+end
+
+describe("Host redirect", function()
+  before_each(function()
+    ngx.shared.configuration_data:set("host_redirects", cjson.encode({
+      ["old.example.com"] = { host = "new.example.com", code = 308, preservePath = true },
+      ["legacy.example.com"] = { host = "www.example.com", code = 301, preservePath = false },
+    }))
+  end)
+
+  after_each(function()
+    _G.ngx = unmocked_ngx
+    ngx.shared.configuration_data:delete("host_redirects")
+  end)
+
+  it("redirects the requests keeping the path", function()
+    mock_ngx("old.example.com", "/docs?page=2")
+    local s = spy.on(ngx, "redirect")
+    assert.has_no.errors(hostredirect.call)
+    assert.spy(s).was_called_with("https://new.example.com/docs?page=2", 308)
+  end)
+
+  it("redirects the requests to the root path", function()
+    mock_ngx("legacy.example.com", "/docs")
+    local s = spy.on(ngx, "redirect")
+    assert.has_no.errors(hostredirect.call)
+    assert.spy(s).was_called_with("https://www.example.com/", 301)
+  end)
+
+  it("does not redirect other hosts", function()
+    mock_ngx("new.example.com", "/")
+    local s = spy.on(ngx, "redirect")
+    assert.has_no.errors(hostredirect.call)
+    assert.spy(s).was_not_called()
+  end)
+
+  it("parses the redirects again when the map changes", function()
+    mock_ngx("other.example.com", "/")
+    assert.is_nil(hostredirect.get_redirects()["other.example.com"])
+
+    ngx.shared.configuration_data:set("host_redirects", cjson.encode({
+      ["other.example.com"] = { host = "new.example.com", code = 301, preservePath = true },
+    }))
+    local s = spy.on(ngx, "redirect")
+    assert.has_no.errors(hostredirect.call)
+    assert.spy(s).was_called_with("https://new.example.com/", 301)
+  end)
+end)
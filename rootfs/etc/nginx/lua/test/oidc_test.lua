@@ -0,0 +1,86 @@
+_G._TEST = true
+
+local cjson = require("cjson")
+
+local openidc_mock = {}
+package.loaded["resty.openidc"] = openidc_mock
+
+local credentials = cjson.encode({
+  ["default/oidc"] = { client_id = "app", client_secret = "s3cr3t", session_secret = "0123456789" },
+})
+package.loaded["configuration"] = {
+  get_oidc_credentials = function() return credentials end,
+}
+
+local oidc = require("oidc")
+
+describe("OIDC", function()
+  local headers
+
+  before_each(function()
+    headers = { ["X-Auth-Request-User"] = "spoofed" }
+    stub(ngx.req, "clear_header", function(name) headers[name] = nil end)
+    stub(ngx.req, "set_header", function(name, value) headers[name] = value end)
+  end)
+
+  after_each(function()
+    ngx.req.clear_header:revert()
+    ngx.req.set_header:revert()
+  end)
+
+  describe("set_headers()", function()
+    it("sets the information of the user", function()
+      oidc.set_headers({ id_token = { sub = "1234", email = "user@example.com" }, access_token = "token" })
+
+      assert.are.same({
+        ["X-Auth-Request-User"] = "1234",
+        ["X-Auth-Request-Email"] = "user@example.com",
+        ["X-Auth-Request-Access-Token"] = "token",
+      }, headers)
+    end)
+
+    it("removes the headers sent by the client", function()
+      oidc.set_headers({})
+      assert.are.same({}, headers)
+    end)
+  end)
+
+  describe("authenticate()", function()
+    it("uses the credentials of the secret", function()
+      local client_opts, session_opts
+      openidc_mock.authenticate = function(opts, _, _, s)
+        client_opts = opts
+        session_opts = s
+        return { id_token = { sub = "1234" } }, nil
+      end
+
+      oidc.authenticate({ discovery = "https://accounts.example.com" }, "default/oidc")
+
+      assert.are.equal("app", client_opts.client_id)
+      assert.are.equal("s3cr3t", client_opts.client_secret)
+      assert.are.same({ secret = "0123456789" }, session_opts)
+      assert.are.equal("1234", headers["X-Auth-Request-User"])
+    end)
+
+    it("returns an error when the credentials are unknown", function()
+      stub(ngx, "exit")
+
+      oidc.authenticate({ discovery = "https://accounts.example.com" }, "default/missing")
+
+      assert.stub(ngx.exit).was_called_with(ngx.HTTP_SERVICE_UNAVAILABLE)
+      ngx.exit:revert()
+    end)
+
+    it("returns an error when the authentication fails", function()
+      openidc_mock.authenticate = function()
+        return nil, "invalid state"
+      end
+      stub(ngx, "exit")
+
+      oidc.authenticate({ discovery = "https://accounts.example.com" }, "default/oidc")
+
+      assert.stub(ngx.exit).was_called_with(ngx.HTTP_INTERNAL_SERVER_ERROR)
+      ngx.exit:revert()
+    end)
+  end)
+end)
@@ -0,0 +1,41 @@
+local ipv6 = require("util.ipv6")
+
+describe("util.ipv6", function()
+  describe("parse_address()", function()
+    it("expands the compressed groups", function()
+      assert.are.same({ 0x2001, 0xdb8, 0, 0, 0, 0, 0, 1 }, ipv6.parse_address("2001:db8::1"))
+      assert.are.same({ 0, 0, 0, 0, 0, 0, 0, 0 }, ipv6.parse_address("::"))
+      assert.are.same({ 0xfe80, 0, 0, 0, 0, 0, 0, 1 }, ipv6.parse_address("fe80::1%eth0"))
+    end)
+
+    it("parses an IPv4 address in the last groups", function()
+      assert.are.same({ 0, 0, 0, 0, 0, 0xffff, 0xc000, 0x0201 }, ipv6.parse_address("::ffff:192.0.2.1"))
+    end)
+
+    it("returns an error for invalid addresses", function()
+      for _, address in ipairs({ "10.0.0.1", "2001:db8::1::", "2001:db8:1", "2001:db8::g", "1:2:3:4:5:6:7:8:9" }) do
+        local groups, err = ipv6.parse_address(address)
+        assert.is_nil(groups)
+        assert.is_not_nil(err)
+      end
+    end)
+  end)
+
+  describe("contains()", function()
+    it("matches the addresses of a network", function()
+      local network = ipv6.parse_cidr("2001:db8:8000::/33")
+      assert.is_true(ipv6.contains(network, ipv6.parse_address("2001:db8:ffff::1")))
+      assert.is_false(ipv6.contains(network, ipv6.parse_address("2001:db8:7fff::1")))
+    end)
+
+    it("matches a single address", function()
+      local network = ipv6.parse_cidr("2001:db8::1")
+      assert.is_true(ipv6.contains(network, ipv6.parse_address("2001:db8:0::1")))
+      assert.is_false(ipv6.contains(network, ipv6.parse_address("2001:db8::2")))
+    end)
+
+    it("rejects invalid prefix lengths", function()
+      assert.is_nil(ipv6.parse_cidr("2001:db8::/129"))
+    end)
+  end)
+end)
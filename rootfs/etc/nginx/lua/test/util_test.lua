@@ -33,3 +33,38 @@ describe("lua_ngx_var", function()
     assert.equal(nil, util.lua_ngx_var("$foo_bar"))
   end)
 end)
+
+describe("get_nodes", function()
+  local util = require("util")
+
+  it("uses the weight of the endpoints", function()
+    local endpoints = {
+      { address = "10.184.7.40", port = "8080" },
+      { address = "10.184.7.41", port = "8080", weight = 5 },
+      { address = "10.184.7.42", port = "8080", weight = 0 },
+    }
+
+    local expected = { ["10.184.7.40:8080"] = 1, ["10.184.7.41:8080"] = 5 }
+    assert.are.same(expected, util.get_nodes(endpoints))
+  end)
+
+  it("uses all the endpoints when all of them have weight 0", function()
+    local endpoints = {
+      { address = "10.184.7.40", port = "8080", weight = 0 },
+      { address = "10.184.7.41", port = "8080", weight = 0 },
+    }
+
+    local expected = { ["10.184.7.40:8080"] = 1, ["10.184.7.41:8080"] = 1 }
+    assert.are.same(expected, util.get_nodes(endpoints))
+  end)
+
+  it("formats IPv6 endpoints between brackets", function()
+    local endpoints = {
+      { address = "fd00::1", port = "8080" },
+      { address = "10.184.7.41", port = "8080" },
+    }
+
+    local expected = { ["[fd00::1]:8080"] = 1, ["10.184.7.41:8080"] = 1 }
+    assert.are.same(expected, util.get_nodes(endpoints))
+  end)
+end)
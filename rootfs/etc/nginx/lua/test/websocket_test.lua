@@ -0,0 +1,48 @@
+_G._TEST = true
+local cjson = require("cjson")
+local websocket = require("websocket")
+
+local unmocked_ngx = _G.ngx
+
+local function mock_ngx(status, pid)
+  local _ngx = {
+    status = status,
+    worker = { pid = function() return pid end },
+    log = function(...) end,
+  }
+  setmetatable(_ngx, { __index = unmocked_ngx })
+  _G.ngx = _ngx
+end
+
+describe("WebSocket", function()
+  after_each(function()
+    _G.ngx = unmocked_ngx
+    ngx.shared.websockets:flush_all()
+  end)
+
+  it("counts the upgraded connections by worker", function()
+    mock_ngx(ngx.HTTP_SWITCHING_PROTOCOLS, 10)
+    websocket.header_filter()
+    websocket.header_filter()
+    mock_ngx(ngx.HTTP_SWITCHING_PROTOCOLS, 20)
+    websocket.header_filter()
+
+    assert.are.same({ ["10"] = 2, ["20"] = 1 }, cjson.decode(websocket.get_active()))
+  end)
+
+  it("discounts the closed connections", function()
+    mock_ngx(ngx.HTTP_SWITCHING_PROTOCOLS, 10)
+    websocket.header_filter()
+    websocket.log()
+
+    assert.are.equal("{}", websocket.get_active())
+  end)
+
+  it("does not count the requests that are not upgraded", function()
+    mock_ngx(ngx.HTTP_OK, 10)
+    websocket.header_filter()
+    websocket.log()
+
+    assert.are.equal("{}", websocket.get_active())
+  end)
+end)
@@ -6,13 +6,43 @@ local resty_md5 = require("resty.md5")
 
 local _M = {}
 
+-- returns the weight of an endpoint, 1 when it is not defined
+function _M.get_weight(endpoint)
+  return endpoint.weight or 1
+end
+
+-- returns the address:port of an endpoint, with IPv6 addresses between
+-- brackets like NGINX formats them in $upstream_addr
+function _M.endpoint_string(endpoint)
+  if string.find(endpoint.address, ":", 1, true) then
+    return "[" .. endpoint.address .. "]:" .. endpoint.port
+  end
+
+  return endpoint.address .. ":" .. endpoint.port
+end
+
+-- returns a table with the weight of each endpoint indexed by address:port
+-- endpoints with weight 0 are excluded unless all of them have weight 0
 function _M.get_nodes(endpoints)
   local nodes = {}
-  local weight = 1
+  local all_drained = true
 
   for _, endpoint in pairs(endpoints) do
-    local endpoint_string = endpoint.address .. ":" .. endpoint.port
-    nodes[endpoint_string] = weight
+    if _M.get_weight(endpoint) > 0 then
+      all_drained = false
+      break
+    end
+  end
+
+  for _, endpoint in pairs(endpoints) do
+    local endpoint_string = _M.endpoint_string(endpoint)
+    local weight = _M.get_weight(endpoint)
+
+    if all_drained then
+      nodes[endpoint_string] = 1
+    elseif weight > 0 then
+      nodes[endpoint_string] = weight
+    end
   end
 
   return nodes
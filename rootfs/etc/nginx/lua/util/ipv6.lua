@@ -0,0 +1,125 @@
+local bit = require("bit")
+
+-- parses IPv6 addresses and networks, not supported by resty.iputils
+local _M = {}
+
+-- appends to groups the 16 bits groups of a part of an IPv6 address
+-- separated by colons. The last group can be an IPv4 address.
+local function parse_groups(part, groups)
+  if part == "" then
+    return true
+  end
+
+  for field in (part .. ":"):gmatch("([^:]*):") do
+    if field:find(".", 1, true) then
+      local a, b, c, d = field:match("^(%d+)%.(%d+)%.(%d+)%.(%d+)$")
+      a, b, c, d = tonumber(a), tonumber(b), tonumber(c), tonumber(d)
+      if not a or a > 255 or b > 255 or c > 255 or d > 255 then
+        return false
+      end
+
+      table.insert(groups, a * 256 + b)
+      table.insert(groups, c * 256 + d)
+    else
+      if not field:match("^%x%x?%x?%x?$") then
+        return false
+      end
+
+      table.insert(groups, tonumber(field, 16))
+    end
+  end
+
+  return true
+end
+
+-- returns the 8 groups of 16 bits of an IPv6 address
+function _M.parse_address(address)
+  if type(address) ~= "string" or not address:find(":", 1, true) then
+    return nil, "invalid IPv6 address " .. tostring(address)
+  end
+
+  -- the zone of the link-local addresses is not part of the address
+  local value = address:gsub("%%.*$", "")
+
+  local head, tail = value, ""
+  local compressed = value:find("::", 1, true)
+  if compressed then
+    head = value:sub(1, compressed - 1)
+    tail = value:sub(compressed + 2)
+    if tail:find("::", 1, true) then
+      return nil, "invalid IPv6 address " .. address
+    end
+  end
+
+  local first, last = {}, {}
+  if not parse_groups(head, first) or not parse_groups(tail, last) then
+    return nil, "invalid IPv6 address " .. address
+  end
+
+  local missing = 8 - #first - #last
+  if (compressed and missing < 1) or (not compressed and missing ~= 0) then
+    return nil, "invalid IPv6 address " .. address
+  end
+
+  local groups = first
+  for _ = 1, missing do
+    table.insert(groups, 0)
+  end
+  for _, group in ipairs(last) do
+    table.insert(groups, group)
+  end
+
+  return groups
+end
+
+-- returns the groups and the prefix length of an IPv6 network,
+-- an address is a network with a prefix length of 128
+function _M.parse_cidr(cidr)
+  if type(cidr) ~= "string" then
+    return nil, "invalid IPv6 network " .. tostring(cidr)
+  end
+
+  local address, prefix = cidr:match("^([^/]+)/(%d+)$")
+  if not address then
+    address, prefix = cidr, "128"
+  end
+
+  prefix = tonumber(prefix)
+  if prefix > 128 then
+    return nil, "invalid prefix length in IPv6 network " .. cidr
+  end
+
+  local groups, err = _M.parse_address(address)
+  if not groups then
+    return nil, err
+  end
+
+  return { groups = groups, prefix = prefix }
+end
+
+-- returns whether the groups of an address are in a network
+function _M.contains(network, groups)
+  local remaining = network.prefix
+  for i = 1, 8 do
+    if remaining <= 0 then
+      return true
+    end
+
+    if remaining >= 16 then
+      if network.groups[i] ~= groups[i] then
+        return false
+      end
+    else
+      local shift = 16 - remaining
+      if bit.rshift(network.groups[i], shift) ~= bit.rshift(groups[i], shift) then
+        return false
+      end
+    end
+
+    remaining = remaining - 16
+  end
+
+  return true
+end
+
+return _M
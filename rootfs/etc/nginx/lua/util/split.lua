@@ -1,8 +1,11 @@
 local _M = {}
 
--- splits strings into host and port
+-- splits strings into host and port, IPv6 addresses are between brackets
 local function parse_addr(addr)
-  local _, _, host, port = addr:find("([^:]+):([^:]+)")
+  local _, _, host, port = addr:find("^%[([^%]]+)%]:([^:]+)$")
+  if not host then
+    _, _, host, port = addr:find("([^:]+):([^:]+)")
+  end
   if host and port then
     return {host=host, port=port}
   else
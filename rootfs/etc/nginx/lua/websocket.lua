@@ -0,0 +1,49 @@
+local json = require("cjson")
+
+-- counts the active WebSocket connections of the locations with the
+-- annotation websocket-heavy, by worker process. The shared dictionary is
+-- kept after a reload, so the connections of the workers shutting down are
+-- counted until they are closed.
+local websockets = ngx.shared.websockets
+
+local _M = {}
+
+local function is_upgraded()
+  return ngx.status == ngx.HTTP_SWITCHING_PROTOCOLS
+end
+
+local function add(value)
+  local _, err = websockets:incr(tostring(ngx.worker.pid()), value, 0)
+  if err then
+    ngx.log(ngx.ERR, "websocket: error counting the active connections: " .. tostring(err))
+  end
+end
+
+-- counts a connection when the upstream accepts the upgrade to WebSocket
+function _M.header_filter()
+  if is_upgraded() then
+    add(1)
+  end
+end
+
+-- discounts the connection once it is closed
+function _M.log()
+  if is_upgraded() then
+    add(-1)
+  end
+end
+
+-- returns the JSON encoded number of active connections by worker PID
+function _M.get_active()
+  local active = {}
+  for _, pid in ipairs(websockets:get_keys(0)) do
+    local count = websockets:get(pid)
+    if count and count > 0 then
+      active[pid] = count
+    end
+  end
+
+  return json.encode(active)
+end
+
+return _M